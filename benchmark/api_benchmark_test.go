@@ -1,6 +1,7 @@
 package benchmark
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -35,7 +36,7 @@ func (m *MockLogManager) GetLogPaths() []string {
 }
 
 // ReadLogFileFromTail implements interfaces.LogManager.
-func (m *MockLogManager) ReadLogFileFromTail(path string, lines int) (*types.LogContent, error) {
+func (m *MockLogManager) ReadLogFileFromTail(ctx context.Context, path string, lines int) (*types.LogContent, error) {
 	panic("unimplemented")
 }
 
@@ -46,7 +47,7 @@ func (m *MockLogManager) GetLogFiles() ([]types.LogFile, error) {
 	}, nil
 }
 
-func (m *MockLogManager) ReadLogFile(path string, offset int64, limit int) (*types.LogContent, error) {
+func (m *MockLogManager) ReadLogFile(ctx context.Context, path string, offset int64, limit int) (*types.LogContent, error) {
 	entries := make([]types.LogEntry, limit)
 	for i := 0; i < limit; i++ {
 		entries[i] = types.LogEntry{
@@ -63,7 +64,7 @@ func (m *MockLogManager) ReadLogFile(path string, offset int64, limit int) (*typ
 	}, nil
 }
 
-func (m *MockLogManager) SearchLogs(query types.SearchQuery) (*types.SearchResult, error) {
+func (m *MockLogManager) SearchLogs(ctx context.Context, query types.SearchQuery) (*types.SearchResult, error) {
 	entries := make([]types.LogEntry, 10)
 	for i := 0; i < 10; i++ {
 		entries[i] = types.LogEntry{
@@ -93,12 +94,14 @@ func (m *MockLogManager) Stop() error  { return nil }
 // MockWebSocketHub 模拟WebSocket中心
 type MockWebSocketHub struct{}
 
-func (m *MockWebSocketHub) Run()                                               {}
-func (m *MockWebSocketHub) BroadcastLogUpdate(update types.LogUpdate)          {}
-func (m *MockWebSocketHub) RegisterClient(client interfaces.WebSocketClient)   {}
-func (m *MockWebSocketHub) UnregisterClient(client interfaces.WebSocketClient) {}
-func (m *MockWebSocketHub) Start() error                                       { return nil }
-func (m *MockWebSocketHub) Stop() error                                        { return nil }
+func (m *MockWebSocketHub) Run()                                                        {}
+func (m *MockWebSocketHub) BroadcastLogUpdate(update types.LogUpdate)                   {}
+func (m *MockWebSocketHub) Broadcast(message types.WSMessage)                           {}
+func (m *MockWebSocketHub) BroadcastToSubscribers(path string, message types.WSMessage) {}
+func (m *MockWebSocketHub) RegisterClient(client interfaces.WebSocketClient)            {}
+func (m *MockWebSocketHub) UnregisterClient(client interfaces.WebSocketClient)          {}
+func (m *MockWebSocketHub) Start() error                                                { return nil }
+func (m *MockWebSocketHub) Stop() error                                                 { return nil }
 
 // BenchmarkAPIEndpoints 测试各个 API 端点的性能
 func BenchmarkAPIEndpoints(b *testing.B) {
@@ -0,0 +1,376 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// evaluationInterval 定期评估间隔，保证没有新日志时窗口也能滑动、告警能够恢复
+	evaluationInterval = time.Second
+	// watchRetryInterval 监控文件失败（例如文件还不存在）后的重试间隔
+	watchRetryInterval = 30 * time.Second
+	// maxSamples 每个规则保留的最近匹配条目数
+	maxSamples = 5
+	// defaultSeverity 默认告警级别
+	defaultSeverity = "warning"
+	// hitBuckets 每个规则的窗口划分的计数桶数，匹配按桶计数，占用的内存与匹配次数无关；
+	// 窗口的起点按桶对齐，最早的匹配最多提前窗口的 1/hitBuckets 移出窗口
+	hitBuckets = 60
+)
+
+// Source 提供文件的实时日志更新流
+type Source interface {
+	WatchFile(path string) (<-chan types.LogUpdate, error)
+	UnwatchFile(path string, updates <-chan types.LogUpdate) error
+}
+
+// Engine 告警规则引擎，在实时日志流上持续评估规则
+type Engine struct {
+	source    Source
+	rules     []*rule
+	listeners []func(types.AlertEvent)
+
+	// 已成功监控的文件的更新流，以及上次尝试监控的时间
+	watched          map[string]<-chan types.LogUpdate
+	lastWatchAttempt time.Time
+
+	now     func() time.Time
+	running bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// rule 单个告警规则的运行状态
+type rule struct {
+	config    config.AlertRuleConfig
+	matcher   func(entry *types.LogEntry) bool
+	buckets   [hitBuckets]hitBucket // 按到达时间分桶的匹配次数，循环使用
+	width     time.Duration         // 每个桶的时长
+	count     int                   // 上次评估时窗口内的匹配次数
+	samples   []types.LogEntry
+	state     types.AlertState
+	since     time.Time
+	lastFired time.Time
+}
+
+// hitBucket 一个时间段内的匹配次数，slot 为时间段的序号（时间除以桶的时长）
+type hitBucket struct {
+	slot  int64
+	count int
+}
+
+// NewEngine 创建告警规则引擎
+func NewEngine(rules []config.AlertRuleConfig, source Source) (*Engine, error) {
+	e := &Engine{
+		source:  source,
+		watched: make(map[string]<-chan types.LogUpdate),
+		now:     time.Now,
+	}
+
+	now := e.now()
+	for _, cfg := range rules {
+		matcher, err := search.NewMatcher(types.SearchQuery{
+			Query:   cfg.Query,
+			IsRegex: cfg.IsRegex,
+			Levels:  cfg.Levels,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("告警规则 %s 无效: %w", cfg.Name, err)
+		}
+		if cfg.Severity == "" {
+			cfg.Severity = defaultSeverity
+		}
+
+		e.rules = append(e.rules, &rule{
+			config:  cfg,
+			matcher: matcher,
+			width:   max(cfg.Window/hitBuckets, 1),
+			state:   types.AlertInactive,
+			since:   now,
+		})
+	}
+
+	return e, nil
+}
+
+// OnEvent 注册告警状态变化的监听函数，监听函数不应阻塞
+func (e *Engine) OnEvent(listener func(types.AlertEvent)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.listeners = append(e.listeners, listener)
+}
+
+// Start 开始监控规则涉及的文件并定期评估
+func (e *Engine) Start() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.running {
+		return fmt.Errorf("告警引擎已经在运行")
+	}
+	e.running = true
+	e.stopCh = make(chan struct{})
+
+	if len(e.rules) == 0 {
+		return nil
+	}
+
+	e.watchLocked(e.now())
+
+	e.wg.Add(1)
+	go e.loop()
+
+	logger.Info("alert engine started", zap.Int("rules", len(e.rules)))
+	return nil
+}
+
+// Stop 停止告警引擎，取消对文件的订阅
+func (e *Engine) Stop() error {
+	e.mutex.Lock()
+	if !e.running {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.running = false
+	close(e.stopCh)
+	watched := e.watched
+	e.watched = make(map[string]<-chan types.LogUpdate)
+	e.mutex.Unlock()
+
+	e.wg.Wait()
+
+	for path, updates := range watched {
+		if err := e.source.UnwatchFile(path, updates); err != nil {
+			logger.Debug("failed to unwatch file for alert rule", zap.String("path", path), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Alerts 返回所有规则的当前状态
+func (e *Engine) Alerts() []types.AlertStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	statuses := make([]types.AlertStatus, 0, len(e.rules))
+	for _, r := range e.rules {
+		statuses = append(statuses, r.status())
+	}
+	return statuses
+}
+
+// loop 定期评估规则，并重试监控失败的文件
+func (e *Engine) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(evaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := e.now()
+			e.mutex.Lock()
+			if now.Sub(e.lastWatchAttempt) >= watchRetryInterval {
+				e.watchLocked(now)
+			}
+			events := e.evaluateLocked(now)
+			listeners := e.listeners
+			e.mutex.Unlock()
+			emit(listeners, events)
+
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// watchLocked 监控规则涉及的、尚未监控的文件（调用方持有锁），引擎停止后不再建立订阅
+func (e *Engine) watchLocked(now time.Time) {
+	e.lastWatchAttempt = now
+	if !e.running {
+		return
+	}
+
+	for _, r := range e.rules {
+		path := r.config.Path
+		if _, exists := e.watched[path]; exists {
+			continue
+		}
+
+		updates, err := e.source.WatchFile(path)
+		if err != nil {
+			logger.Warn("failed to watch file for alert rule",
+				zap.String("rule", r.config.Name),
+				zap.String("path", path),
+				zap.Error(err))
+			continue
+		}
+
+		e.watched[path] = updates
+		e.wg.Add(1)
+		go e.consume(path, updates)
+	}
+}
+
+// consume 读取文件的更新流
+func (e *Engine) consume(path string, updates <-chan types.LogUpdate) {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				// 更新流被关闭，稍后重新监控
+				e.mutex.Lock()
+				delete(e.watched, path)
+				e.mutex.Unlock()
+				return
+			}
+			e.observe(update, e.now())
+
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// observe 将日志更新计入相关规则并立即评估
+func (e *Engine) observe(update types.LogUpdate, now time.Time) {
+	e.mutex.Lock()
+	for _, r := range e.rules {
+		if r.config.Path != update.Path {
+			continue
+		}
+		for i := range update.Entries {
+			if r.matcher(&update.Entries[i]) {
+				r.hit(update.Entries[i], now)
+			}
+		}
+	}
+	events := e.evaluateLocked(now)
+	listeners := e.listeners
+	e.mutex.Unlock()
+
+	emit(listeners, events)
+}
+
+// evaluateLocked 评估所有规则，返回状态变化事件（调用方持有锁）
+func (e *Engine) evaluateLocked(now time.Time) []types.AlertEvent {
+	var events []types.AlertEvent
+	for _, r := range e.rules {
+		events = append(events, r.evaluate(now)...)
+	}
+	return events
+}
+
+// emit 在锁外通知监听函数
+func emit(listeners []func(types.AlertEvent), events []types.AlertEvent) {
+	for _, event := range events {
+		logger.Info("alert state changed",
+			zap.String("rule", event.Rule),
+			zap.String("from", string(event.Previous)),
+			zap.String("to", string(event.State)),
+			zap.Int("count", event.Count))
+
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// hit 记录一次匹配，计入到达时间所在的桶；桶中是之前一轮的计数时先清零
+func (r *rule) hit(entry types.LogEntry, now time.Time) {
+	slot := now.UnixNano() / int64(r.width)
+	bucket := &r.buckets[slot%hitBuckets]
+	if bucket.slot != slot {
+		*bucket = hitBucket{slot: slot}
+	}
+	bucket.count++
+
+	r.samples = append(r.samples, entry)
+	if len(r.samples) > maxSamples {
+		r.samples = r.samples[len(r.samples)-maxSamples:]
+	}
+}
+
+// evaluate 滑动窗口并推进状态机:
+// inactive/resolved -> pending（超过阈值）-> firing（持续 for 且已过冷却时间）-> resolved（回到阈值以内）
+func (r *rule) evaluate(now time.Time) []types.AlertEvent {
+	// 窗口内是当前桶和之前的 hitBuckets-1 个桶
+	current := now.UnixNano() / int64(r.width)
+	r.count = 0
+	for _, bucket := range r.buckets {
+		if bucket.slot > current-hitBuckets && bucket.slot <= current {
+			r.count += bucket.count
+		}
+	}
+
+	var events []types.AlertEvent
+	breached := r.count > r.config.Threshold
+
+	switch {
+	case breached && (r.state == types.AlertInactive || r.state == types.AlertResolved):
+		events = append(events, r.transition(types.AlertPending, now))
+	case !breached && r.state == types.AlertPending:
+		events = append(events, r.transition(types.AlertInactive, now))
+	case !breached && r.state == types.AlertFiring:
+		events = append(events, r.transition(types.AlertResolved, now))
+	}
+
+	if r.state == types.AlertPending && now.Sub(r.since) >= r.config.For && r.cooledDown(now) {
+		r.lastFired = now
+		events = append(events, r.transition(types.AlertFiring, now))
+	}
+
+	return events
+}
+
+// cooledDown 距离上次触发是否已超过冷却时间
+func (r *rule) cooledDown(now time.Time) bool {
+	return r.lastFired.IsZero() || now.Sub(r.lastFired) >= r.config.Cooldown
+}
+
+// transition 切换状态并生成事件
+func (r *rule) transition(state types.AlertState, now time.Time) types.AlertEvent {
+	previous := r.state
+	r.state = state
+	r.since = now
+
+	return types.AlertEvent{
+		AlertStatus: r.status(),
+		Previous:    previous,
+		Time:        now,
+	}
+}
+
+// status 生成规则状态快照
+func (r *rule) status() types.AlertStatus {
+	status := types.AlertStatus{
+		Rule:      r.config.Name,
+		Path:      r.config.Path,
+		Severity:  r.config.Severity,
+		State:     r.state,
+		Count:     r.count,
+		Threshold: r.config.Threshold,
+		Window:    r.config.Window.String(),
+		Since:     r.since,
+	}
+	if !r.lastFired.IsZero() {
+		lastFired := r.lastFired
+		status.LastFired = &lastFired
+	}
+	if len(r.samples) > 0 {
+		status.Samples = append([]types.LogEntry(nil), r.samples...)
+	}
+	return status
+}
@@ -0,0 +1,309 @@
+package alert
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// fakeSource 测试用的日志更新源
+type fakeSource struct {
+	mutex   sync.Mutex
+	streams map[string]chan types.LogUpdate
+	fail    map[string]bool
+	unwatch []string
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		streams: make(map[string]chan types.LogUpdate),
+		fail:    make(map[string]bool),
+	}
+}
+
+func (s *fakeSource) WatchFile(path string) (<-chan types.LogUpdate, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.fail[path] {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	ch := make(chan types.LogUpdate, 10)
+	s.streams[path] = ch
+	return ch, nil
+}
+
+func (s *fakeSource) UnwatchFile(path string, updates <-chan types.LogUpdate) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if ch, ok := s.streams[path]; !ok || (<-chan types.LogUpdate)(ch) != updates {
+		return fmt.Errorf("not watched: %s", path)
+	}
+	delete(s.streams, path)
+	s.unwatch = append(s.unwatch, path)
+	return nil
+}
+
+func (s *fakeSource) stream(path string) chan types.LogUpdate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.streams[path]
+}
+
+func timeoutRule() config.AlertRuleConfig {
+	return config.AlertRuleConfig{
+		Name:      "api-timeouts",
+		Path:      "/logs/api.log",
+		Query:     "timeout",
+		Levels:    []string{"ERROR"},
+		Window:    time.Minute,
+		Threshold: 2,
+	}
+}
+
+func entries(level, message string, n int) types.LogUpdate {
+	update := types.LogUpdate{Path: "/logs/api.log", Type: "append"}
+	for i := 0; i < n; i++ {
+		update.Entries = append(update.Entries, types.LogEntry{
+			Level:   level,
+			Message: message,
+			Raw:     level + " " + message,
+		})
+	}
+	return update
+}
+
+func newTestEngine(t *testing.T, rules ...config.AlertRuleConfig) (*Engine, *[]types.AlertEvent) {
+	t.Helper()
+	engine, err := NewEngine(rules, newFakeSource())
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	var events []types.AlertEvent
+	engine.OnEvent(func(event types.AlertEvent) {
+		events = append(events, event)
+	})
+	return engine, &events
+}
+
+func states(events []types.AlertEvent) []types.AlertState {
+	var result []types.AlertState
+	for _, event := range events {
+		result = append(result, event.State)
+	}
+	return result
+}
+
+func assertStates(t *testing.T, events []types.AlertEvent, expected ...types.AlertState) {
+	t.Helper()
+	got := states(events)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected transitions %v, got %v", expected, got)
+	}
+}
+
+func TestEngine_FiresAndResolves(t *testing.T) {
+	engine, events := newTestEngine(t, timeoutRule())
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 不匹配的行不计数
+	engine.observe(entries("ERROR", "connection refused", 5), start)
+	engine.observe(entries("INFO", "request timeout retried", 5), start)
+	engine.observe(entries("ERROR", "upstream timeout", 2), start)
+	if len(*events) != 0 {
+		t.Fatalf("Expected no transitions at threshold, got %v", states(*events))
+	}
+
+	engine.observe(entries("ERROR", "upstream timeout", 1), start.Add(10*time.Second))
+	assertStates(t, *events, types.AlertPending, types.AlertFiring)
+
+	alerts := engine.Alerts()
+	if len(alerts) != 1 || alerts[0].State != types.AlertFiring || alerts[0].Count != 3 {
+		t.Fatalf("Unexpected alert status: %+v", alerts)
+	}
+	if alerts[0].LastFired == nil || len(alerts[0].Samples) != 3 || alerts[0].Severity != defaultSeverity {
+		t.Errorf("Expected last fired time, samples and default severity: %+v", alerts[0])
+	}
+
+	// 窗口滑过后恢复
+	*events = nil
+	engine.mutex.Lock()
+	resolved := engine.evaluateLocked(start.Add(65 * time.Second))
+	engine.mutex.Unlock()
+	assertStates(t, resolved, types.AlertResolved)
+	if status := engine.Alerts()[0]; status.Count != 1 {
+		t.Errorf("Expected 1 hit left in window, got %d", status.Count)
+	}
+}
+
+func TestEngine_ForDuration(t *testing.T) {
+	rule := timeoutRule()
+	rule.For = 30 * time.Second
+	engine, events := newTestEngine(t, rule)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	engine.observe(entries("ERROR", "timeout", 3), start)
+	assertStates(t, *events, types.AlertPending)
+
+	engine.observe(entries("ERROR", "timeout", 1), start.Add(20*time.Second))
+	assertStates(t, *events, types.AlertPending)
+
+	engine.observe(entries("ERROR", "timeout", 1), start.Add(30*time.Second))
+	assertStates(t, *events, types.AlertPending, types.AlertFiring)
+}
+
+func TestEngine_PendingReturnsToInactive(t *testing.T) {
+	rule := timeoutRule()
+	rule.For = time.Minute
+	engine, events := newTestEngine(t, rule)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	engine.observe(entries("ERROR", "timeout", 3), start)
+
+	engine.mutex.Lock()
+	engine.evaluateLocked(start.Add(61 * time.Second))
+	engine.mutex.Unlock()
+
+	if status := engine.Alerts()[0]; status.State != types.AlertInactive {
+		t.Errorf("Expected inactive after window expired, got %s", status.State)
+	}
+	assertStates(t, *events, types.AlertPending)
+}
+
+func TestEngine_Cooldown(t *testing.T) {
+	rule := timeoutRule()
+	rule.Window = 10 * time.Second
+	rule.Cooldown = time.Minute
+	engine, events := newTestEngine(t, rule)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	engine.observe(entries("ERROR", "timeout", 3), start)
+	engine.observe(entries("ERROR", "timeout", 0), start.Add(15*time.Second))
+	assertStates(t, *events, types.AlertPending, types.AlertFiring, types.AlertResolved)
+
+	// 冷却期内再次超过阈值只进入 pending
+	*events = nil
+	engine.observe(entries("ERROR", "timeout", 3), start.Add(20*time.Second))
+	assertStates(t, *events, types.AlertPending)
+
+	engine.observe(entries("ERROR", "timeout", 3), start.Add(60*time.Second))
+	assertStates(t, *events, types.AlertPending, types.AlertFiring)
+	if (*events)[1].Previous != types.AlertPending {
+		t.Errorf("Expected previous state pending, got %s", (*events)[1].Previous)
+	}
+}
+
+func TestEngine_ConsumesWatchedStreams(t *testing.T) {
+	source := newFakeSource()
+	source.fail["/logs/missing.log"] = true
+
+	missing := timeoutRule()
+	missing.Name = "missing"
+	missing.Path = "/logs/missing.log"
+
+	engine, err := NewEngine([]config.AlertRuleConfig{timeoutRule(), missing}, source)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	received := make(chan types.AlertEvent, 10)
+	engine.OnEvent(func(event types.AlertEvent) { received <- event })
+
+	// 监控失败的文件不影响其他规则
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer engine.Stop()
+
+	source.stream("/logs/api.log") <- entries("ERROR", "timeout", 3)
+
+	for _, expected := range []types.AlertState{types.AlertPending, types.AlertFiring} {
+		select {
+		case event := <-received:
+			if event.State != expected || event.Rule != "api-timeouts" {
+				t.Errorf("Expected %s for api-timeouts, got %+v", expected, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s event", expected)
+		}
+	}
+}
+
+func TestEngine_CountsHitsInBuckets(t *testing.T) {
+	rule := timeoutRule()
+	rule.Threshold = 1000
+	engine, events := newTestEngine(t, rule)
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 每秒 100 次匹配，持续一分钟：计数不保存每次匹配的时间
+	for i := 0; i < 60; i++ {
+		engine.observe(entries("ERROR", "timeout", 100), start.Add(time.Duration(i)*time.Second))
+	}
+	if status := engine.Alerts()[0]; status.Count != 6000 || status.State != types.AlertFiring {
+		t.Fatalf("Expected 6000 hits firing, got %d %s", status.Count, status.State)
+	}
+	assertStates(t, *events, types.AlertPending, types.AlertFiring)
+
+	// 窗口滑过 30 秒后只剩后 30 秒的匹配
+	engine.mutex.Lock()
+	engine.evaluateLocked(start.Add(89 * time.Second))
+	engine.mutex.Unlock()
+	if status := engine.Alerts()[0]; status.Count != 3000 {
+		t.Errorf("Expected 3000 hits left in window, got %d", status.Count)
+	}
+
+	// 过了一整个窗口后，旧的桶不再计数，新的匹配重新开始计数
+	engine.observe(entries("ERROR", "timeout", 1), start.Add(10*time.Minute))
+	if status := engine.Alerts()[0]; status.Count != 1 || status.State != types.AlertResolved {
+		t.Errorf("Expected 1 hit after the window passed, got %d %s", status.Count, status.State)
+	}
+}
+
+func TestEngine_StopUnwatchesFiles(t *testing.T) {
+	source := newFakeSource()
+	other := timeoutRule()
+	other.Name = "other"
+	other.Path = "/logs/worker.log"
+
+	engine, err := NewEngine([]config.AlertRuleConfig{timeoutRule(), other}, source)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	source.mutex.Lock()
+	unwatched, remaining := len(source.unwatch), len(source.streams)
+	source.mutex.Unlock()
+	if unwatched != 2 || remaining != 0 {
+		t.Errorf("Expected both subscriptions released on stop, unwatched %d, remaining %d", unwatched, remaining)
+	}
+
+	// 重新启动后重新订阅
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	defer engine.Stop()
+	if source.stream("/logs/api.log") == nil {
+		t.Error("Expected file to be watched again after restart")
+	}
+}
+
+func TestNewEngine_InvalidRegex(t *testing.T) {
+	rule := timeoutRule()
+	rule.Query = "("
+	rule.IsRegex = true
+
+	if _, err := NewEngine([]config.AlertRuleConfig{rule}, newFakeSource()); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// ExecNotifier 运行本地命令发送告警
+// 事件 JSON 通过标准输入传入，常用字段同时通过 ALERT_* 环境变量传入
+type ExecNotifier struct {
+	name    string
+	command string
+	args    []*template.Template
+}
+
+// NewExecNotifier 创建命令通知渠道
+func NewExecNotifier(cfg config.NotifierConfig) (*ExecNotifier, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	n := &ExecNotifier{
+		name:    cfg.Name,
+		command: cfg.Command,
+	}
+	for i, arg := range cfg.Args {
+		tmpl, err := parseTemplate(fmt.Sprintf("%s-arg%d", cfg.Name, i), arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument template %q: %w", arg, err)
+		}
+		n.args = append(n.args, tmpl)
+	}
+
+	return n, nil
+}
+
+// Name 返回通知渠道名称
+func (n *ExecNotifier) Name() string {
+	return n.name
+}
+
+// Notify 运行命令，非零退出码视为可重试的失败
+func (n *ExecNotifier) Notify(ctx context.Context, event types.AlertEvent) error {
+	args := make([]string, 0, len(n.args))
+	for _, tmpl := range n.args {
+		arg, err := renderTemplate(tmpl, event)
+		if err != nil {
+			return Permanent(err)
+		}
+		args = append(args, arg)
+	}
+
+	input, err := json.Marshal(event)
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to marshal event: %w", err))
+	}
+
+	cmd := exec.CommandContext(ctx, n.command, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+event.Rule,
+		"ALERT_STATE="+string(event.State),
+		"ALERT_PREVIOUS="+string(event.Previous),
+		"ALERT_SEVERITY="+event.Severity,
+		"ALERT_PATH="+event.Path,
+		"ALERT_COUNT="+strconv.Itoa(event.Count),
+		"ALERT_THRESHOLD="+strconv.Itoa(event.Threshold),
+		"ALERT_TIME="+event.Time.Format("2006-01-02T15:04:05Z07:00"),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) && ctx.Err() == nil {
+			// 命令不存在或不可执行，重试没有意义
+			return Permanent(fmt.Errorf("failed to run %s: %w", n.command, err))
+		}
+		return fmt.Errorf("command %s failed: %w: %s", n.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,451 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultMaxAttempts 默认最大投递次数
+	defaultMaxAttempts = 10
+	// defaultNotifyTimeout 默认单次投递超时
+	defaultNotifyTimeout = 10 * time.Second
+	// retryBaseDelay 首次重试的等待时间，之后每次翻倍
+	retryBaseDelay = 5 * time.Second
+	// retryMaxDelay 重试等待时间上限
+	retryMaxDelay = 10 * time.Minute
+	// queueCheckInterval 检查到期投递的间隔
+	queueCheckInterval = time.Second
+	// queueFileName 投递队列在数据目录中的文件名
+	queueFileName = "notification-queue.json"
+)
+
+// Notifier 告警通知渠道
+type Notifier interface {
+	// Name 通知渠道名称（对应配置中的 name）
+	Name() string
+
+	// Notify 投递告警事件，返回 Permanent 包装的错误时不再重试
+	Notify(ctx context.Context, event types.AlertEvent) error
+}
+
+// permanentError 不可重试的投递错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 将错误标记为不可重试（例如 webhook 返回 4xx）
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否不可重试
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// NewNotifier 根据配置创建通知渠道
+func NewNotifier(cfg config.NotifierConfig) (Notifier, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "webhook":
+		return NewWebhookNotifier(cfg)
+	case "smtp":
+		return NewSMTPNotifier(cfg)
+	case "exec":
+		return NewExecNotifier(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的通知类型: %s", cfg.Type)
+	}
+}
+
+// delivery 待投递的通知
+type delivery struct {
+	ID          string           `json:"id"`
+	Notifier    string           `json:"notifier"`
+	Event       types.AlertEvent `json:"event"`
+	Attempts    int              `json:"attempts"`
+	NextAttempt time.Time        `json:"nextAttempt"`
+	LastError   string           `json:"lastError,omitempty"`
+}
+
+// route 通知渠道及其过滤条件
+type route struct {
+	notifier    Notifier
+	rules       map[string]bool
+	states      map[types.AlertState]bool
+	maxAttempts int
+	timeout     time.Duration
+}
+
+// matches 判断事件是否需要通过该渠道通知
+func (r *route) matches(event types.AlertEvent) bool {
+	if len(r.rules) > 0 && !r.rules[event.Rule] {
+		return false
+	}
+	return r.states[event.State]
+}
+
+// Dispatcher 将告警事件分发到通知渠道
+// 所有投递都先进入队列，失败后按指数退避重试；配置了数据目录时队列持久化，重启后继续投递
+type Dispatcher struct {
+	routes    map[string]*route
+	queue     []*delivery
+	queuePath string
+	sequence  int64
+
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	now       func() time.Time
+
+	running bool
+	wakeCh  chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// NewDispatcher 创建通知分发器，dataDir 为空时队列只保存在内存中
+func NewDispatcher(configs []config.NotifierConfig, dataDir string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		routes:    make(map[string]*route),
+		baseDelay: retryBaseDelay,
+		maxDelay:  retryMaxDelay,
+		now:       time.Now,
+		wakeCh:    make(chan struct{}, 1),
+	}
+	if dataDir != "" {
+		d.queuePath = filepath.Join(dataDir, queueFileName)
+	}
+
+	for _, cfg := range configs {
+		notifier, err := NewNotifier(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("通知 %s 配置无效: %w", cfg.Name, err)
+		}
+		d.AddNotifier(notifier, cfg)
+	}
+
+	if err := d.loadQueue(); err != nil {
+		logger.Warn("加载通知队列失败", zap.String("path", d.queuePath), zap.Error(err))
+	}
+
+	return d, nil
+}
+
+// AddNotifier 添加通知渠道，cfg 中的 rules、states、maxAttempts、timeout 用于过滤和重试
+func (d *Dispatcher) AddNotifier(notifier Notifier, cfg config.NotifierConfig) {
+	r := &route{
+		notifier:    notifier,
+		rules:       make(map[string]bool),
+		states:      make(map[types.AlertState]bool),
+		maxAttempts: cfg.MaxAttempts,
+		timeout:     cfg.Timeout,
+	}
+	for _, rule := range cfg.Rules {
+		r.rules[rule] = true
+	}
+	states := cfg.States
+	if len(states) == 0 {
+		states = []string{string(types.AlertFiring), string(types.AlertResolved)}
+	}
+	for _, state := range states {
+		r.states[types.AlertState(strings.ToLower(state))] = true
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultMaxAttempts
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultNotifyTimeout
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.routes[notifier.Name()] = r
+}
+
+// Handle 将告警事件加入投递队列（用作告警引擎的监听函数，不会阻塞）
+func (d *Dispatcher) Handle(event types.AlertEvent) {
+	d.mutex.Lock()
+	now := d.now()
+	added := 0
+	for name, r := range d.routes {
+		if !r.matches(event) {
+			continue
+		}
+		d.sequence++
+		d.queue = append(d.queue, &delivery{
+			ID:          fmt.Sprintf("%d-%d", now.UnixNano(), d.sequence),
+			Notifier:    name,
+			Event:       event,
+			NextAttempt: now,
+		})
+		added++
+	}
+	if added > 0 {
+		d.saveQueueLocked()
+	}
+	d.mutex.Unlock()
+
+	if added > 0 {
+		d.wake()
+	}
+}
+
+// Pending 返回队列中等待投递的通知数
+func (d *Dispatcher) Pending() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.queue)
+}
+
+// Start 启动投递协程
+func (d *Dispatcher) Start() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.running {
+		return fmt.Errorf("通知分发器已经在运行")
+	}
+	d.running = true
+	d.stopCh = make(chan struct{})
+
+	d.wg.Add(1)
+	go d.loop()
+	return nil
+}
+
+// Stop 停止投递协程，未投递的通知保留在队列中
+func (d *Dispatcher) Stop() error {
+	d.mutex.Lock()
+	if !d.running {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.running = false
+	close(d.stopCh)
+	d.mutex.Unlock()
+
+	d.wg.Wait()
+	return nil
+}
+
+// wake 通知投递协程检查队列
+func (d *Dispatcher) wake() {
+	select {
+	case d.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// loop 投递到期的通知
+func (d *Dispatcher) loop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(queueCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue()
+
+		select {
+		case <-ticker.C:
+		case <-d.wakeCh:
+		case <-d.stopCh:
+			return
+		}
+	}
+}
+
+// deliverDue 依次投递所有到期的通知
+func (d *Dispatcher) deliverDue() {
+	d.mutex.Lock()
+	now := d.now()
+	var due []*delivery
+	for _, item := range d.queue {
+		if !item.NextAttempt.After(now) {
+			due = append(due, item)
+		}
+	}
+	d.mutex.Unlock()
+
+	for _, item := range due {
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+		d.attempt(item)
+	}
+}
+
+// attempt 投递一次，根据结果移出队列或安排重试
+func (d *Dispatcher) attempt(item *delivery) {
+	d.mutex.Lock()
+	r, exists := d.routes[item.Notifier]
+	d.mutex.Unlock()
+
+	var err error
+	if !exists {
+		err = Permanent(fmt.Errorf("notifier %s is not configured", item.Notifier))
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		err = r.notifier.Notify(ctx, item.Event)
+		cancel()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	item.Attempts++
+	switch {
+	case err == nil:
+		logger.Info("alert notification delivered",
+			zap.String("notifier", item.Notifier),
+			zap.String("rule", item.Event.Rule),
+			zap.String("state", string(item.Event.State)),
+			zap.Int("attempts", item.Attempts))
+		d.removeLocked(item)
+
+	case IsPermanent(err) || item.Attempts >= r.maxAttempts:
+		logger.Error("alert notification dropped",
+			zap.String("notifier", item.Notifier),
+			zap.String("rule", item.Event.Rule),
+			zap.Int("attempts", item.Attempts),
+			zap.Error(err))
+		d.removeLocked(item)
+
+	default:
+		item.LastError = err.Error()
+		item.NextAttempt = d.now().Add(d.backoff(item.Attempts))
+		logger.Warn("alert notification failed, will retry",
+			zap.String("notifier", item.Notifier),
+			zap.String("rule", item.Event.Rule),
+			zap.Int("attempts", item.Attempts),
+			zap.Time("next_attempt", item.NextAttempt),
+			zap.Error(err))
+	}
+
+	d.saveQueueLocked()
+}
+
+// backoff 第 attempts 次失败后的等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempts && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.maxDelay {
+		delay = d.maxDelay
+	}
+	return delay
+}
+
+// removeLocked 将通知移出队列（调用方持有锁）
+func (d *Dispatcher) removeLocked(item *delivery) {
+	for i, queued := range d.queue {
+		if queued == item {
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// loadQueue 加载上次运行时未完成的投递
+func (d *Dispatcher) loadQueue() error {
+	if d.queuePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(d.queuePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var queue []*delivery
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, item := range queue {
+		if _, exists := d.routes[item.Notifier]; !exists {
+			logger.Warn("丢弃未配置通知渠道的投递", zap.String("notifier", item.Notifier))
+			continue
+		}
+		d.queue = append(d.queue, item)
+	}
+	if len(d.queue) > 0 {
+		logger.Info("恢复未完成的告警通知", zap.Int("count", len(d.queue)))
+	}
+	return nil
+}
+
+// saveQueueLocked 保存投递队列（调用方持有锁），写入失败只记录日志
+func (d *Dispatcher) saveQueueLocked() {
+	if d.queuePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(d.queue)
+	if err != nil {
+		logger.Warn("序列化通知队列失败", zap.Error(err))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.queuePath), 0755); err != nil {
+		logger.Warn("创建数据目录失败", zap.String("path", d.queuePath), zap.Error(err))
+		return
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下不完整的队列
+	tmp := d.queuePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		logger.Warn("写入通知队列失败", zap.String("path", d.queuePath), zap.Error(err))
+		return
+	}
+	if err := os.Rename(tmp, d.queuePath); err != nil {
+		logger.Warn("保存通知队列失败", zap.String("path", d.queuePath), zap.Error(err))
+	}
+}
+
+// parseTemplate 解析通知模板，模板数据为告警事件
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"upper": strings.ToUpper,
+	}).Parse(text)
+}
+
+// renderTemplate 使用告警事件渲染模板
+func renderTemplate(tmpl *template.Template, event types.AlertEvent) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
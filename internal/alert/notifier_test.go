@@ -0,0 +1,438 @@
+package alert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func firingEvent() types.AlertEvent {
+	return types.AlertEvent{
+		AlertStatus: types.AlertStatus{
+			Rule:      "api-timeouts",
+			Path:      "/logs/api.log",
+			Severity:  "critical",
+			State:     types.AlertFiring,
+			Count:     23,
+			Threshold: 20,
+			Window:    "1m0s",
+			Samples:   []types.LogEntry{{Level: "ERROR", Message: "upstream timeout", Raw: "ERROR upstream timeout"}},
+		},
+		Previous: types.AlertPending,
+		Time:     time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+// webhookRecorder 记录收到的请求，并按顺序返回预设的状态码
+type webhookRecorder struct {
+	mutex    sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.bodies = append(r.bodies, string(body))
+	r.headers = append(r.headers, req.Header.Clone())
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *webhookRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.bodies)
+}
+
+func TestWebhookNotifier_TemplateAndSignature(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(config.NotifierConfig{
+		Name:    "chat",
+		URL:     server.URL,
+		Body:    `{"text":"{{.Rule}} is {{.State}} ({{.Count}}/{{.Threshold}})","sample":{{json (index .Samples 0).Message}}}`,
+		Secret:  "s3cret",
+		Headers: map[string]string{"X-Team": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier failed: %v", err)
+	}
+
+	if err := notifier.Notify(context.Background(), firingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	expected := `{"text":"api-timeouts is firing (23/20)","sample":"upstream timeout"}`
+	if recorder.bodies[0] != expected {
+		t.Errorf("Unexpected body:\n%s\nexpected:\n%s", recorder.bodies[0], expected)
+	}
+	headers := recorder.headers[0]
+	if headers.Get(SignatureHeader) != Sign([]byte("s3cret"), []byte(expected)) {
+		t.Errorf("Unexpected signature: %s", headers.Get(SignatureHeader))
+	}
+	if headers.Get("X-Team") != "ops" || headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+}
+
+func TestWebhookNotifier_DefaultBodyAndErrors(t *testing.T) {
+	recorder := &webhookRecorder{statuses: []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusBadRequest}}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(config.NotifierConfig{Name: "hook", URL: server.URL})
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier failed: %v", err)
+	}
+
+	if err := notifier.Notify(context.Background(), firingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	var decoded types.AlertEvent
+	if err := json.Unmarshal([]byte(recorder.bodies[0]), &decoded); err != nil || decoded.Rule != "api-timeouts" {
+		t.Errorf("Expected event JSON body, got %s (%v)", recorder.bodies[0], err)
+	}
+
+	if err := notifier.Notify(context.Background(), firingEvent()); err == nil || IsPermanent(err) {
+		t.Errorf("Expected retryable error for 503, got %v", err)
+	}
+	if err := notifier.Notify(context.Background(), firingEvent()); !IsPermanent(err) {
+		t.Errorf("Expected permanent error for 400, got %v", err)
+	}
+}
+
+func newTestDispatcher(t *testing.T, dataDir string, configs ...config.NotifierConfig) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(configs, dataDir)
+	if err != nil {
+		t.Fatalf("NewDispatcher failed: %v", err)
+	}
+	return d
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	recorder := &webhookRecorder{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	d := newTestDispatcher(t, "", config.NotifierConfig{Name: "hook", Type: "webhook", URL: server.URL})
+	d.baseDelay = 0
+
+	d.Handle(firingEvent())
+	for i := 0; i < 3; i++ {
+		d.deliverDue()
+	}
+
+	if recorder.count() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", recorder.count())
+	}
+	if d.Pending() != 0 {
+		t.Errorf("Expected queue to be empty after delivery, got %d", d.Pending())
+	}
+}
+
+func TestDispatcher_DropsAfterMaxAttemptsOrPermanentError(t *testing.T) {
+	recorder := &webhookRecorder{statuses: []int{500, 500, 404}}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	d := newTestDispatcher(t, "",
+		config.NotifierConfig{Name: "limited", Type: "webhook", URL: server.URL, MaxAttempts: 2},
+	)
+	d.baseDelay = 0
+
+	d.Handle(firingEvent())
+	d.deliverDue()
+	d.deliverDue()
+	if d.Pending() != 0 {
+		t.Errorf("Expected delivery to be dropped after max attempts, got %d pending", d.Pending())
+	}
+
+	d.Handle(firingEvent())
+	d.deliverDue()
+	if d.Pending() != 0 || recorder.count() != 3 {
+		t.Errorf("Expected 404 to be dropped without retry, pending=%d attempts=%d", d.Pending(), recorder.count())
+	}
+}
+
+func TestDispatcher_Filters(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	d := newTestDispatcher(t, "",
+		config.NotifierConfig{Name: "all", Type: "webhook", URL: server.URL},
+		config.NotifierConfig{Name: "db-only", Type: "webhook", URL: server.URL, Rules: []string{"db-errors"}},
+		config.NotifierConfig{Name: "pending", Type: "webhook", URL: server.URL, States: []string{"pending"}},
+	)
+
+	d.Handle(firingEvent())
+	if d.Pending() != 1 {
+		t.Errorf("Expected only the default notifier to match, got %d", d.Pending())
+	}
+
+	pending := firingEvent()
+	pending.State = types.AlertPending
+	d.Handle(pending)
+	if d.Pending() != 2 {
+		t.Errorf("Expected pending event to match the pending notifier, got %d", d.Pending())
+	}
+}
+
+func TestDispatcher_QueueSurvivesRestart(t *testing.T) {
+	recorder := &webhookRecorder{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	dataDir := t.TempDir()
+	cfg := config.NotifierConfig{Name: "hook", Type: "webhook", URL: server.URL}
+
+	first := newTestDispatcher(t, dataDir, cfg)
+	first.Handle(firingEvent())
+	first.deliverDue()
+	if first.Pending() != 1 {
+		t.Fatalf("Expected failed delivery to stay queued, got %d", first.Pending())
+	}
+
+	// 重启后从数据目录恢复队列，并在重试时间到达后投递
+	second := newTestDispatcher(t, dataDir, cfg)
+	if second.Pending() != 1 {
+		t.Fatalf("Expected queue to be restored, got %d", second.Pending())
+	}
+	second.deliverDue()
+	if recorder.count() != 1 {
+		t.Errorf("Expected retry to wait for backoff, got %d attempts", recorder.count())
+	}
+
+	second.now = func() time.Time { return time.Now().Add(time.Hour) }
+	second.deliverDue()
+	if recorder.count() != 2 || second.Pending() != 0 {
+		t.Errorf("Expected restored delivery to succeed, attempts=%d pending=%d", recorder.count(), second.Pending())
+	}
+
+	third := newTestDispatcher(t, dataDir, cfg)
+	if third.Pending() != 0 {
+		t.Errorf("Expected persisted queue to be empty, got %d", third.Pending())
+	}
+}
+
+func TestDispatcher_StartDeliversInBackground(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	d := newTestDispatcher(t, "", config.NotifierConfig{Name: "hook", Type: "webhook", URL: server.URL})
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer d.Stop()
+
+	d.Handle(firingEvent())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for recorder.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if recorder.count() != 1 {
+		t.Errorf("Expected background delivery, got %d", recorder.count())
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := newTestDispatcher(t, "")
+
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second}
+	for i, want := range expected {
+		if got := d.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+	if got := d.backoff(50); got != retryMaxDelay {
+		t.Errorf("Expected backoff to be capped at %v, got %v", retryMaxDelay, got)
+	}
+}
+
+// smtpStub 进程内的最小 SMTP 服务器，记录收到的邮件
+type smtpStub struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stub := &smtpStub{listener: listener, done: make(chan struct{})}
+	go stub.serve()
+	t.Cleanup(func() { listener.Close() })
+	return stub
+}
+
+func (s *smtpStub) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	defer close(s.done)
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 stub ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 stub")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.mutex.Lock()
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			s.mutex.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.mutex.Lock()
+			s.to = append(s.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			s.mutex.Unlock()
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mutex.Lock()
+			s.data = data.String()
+			s.mutex.Unlock()
+			reply("250 OK queued")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifier_SendsMail(t *testing.T) {
+	stub := newSMTPStub(t)
+	host, portStr, _ := net.SplitHostPort(stub.listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	notifier, err := NewSMTPNotifier(config.NotifierConfig{
+		Name: "mail",
+		Host: host,
+		Port: port,
+		From: "logviewer@example.com",
+		To:   []string{"ops@example.com", "dev@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPNotifier failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, firingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	<-stub.done
+
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	if stub.from != "logviewer@example.com" || len(stub.to) != 2 {
+		t.Errorf("Unexpected envelope: from=%s to=%v", stub.from, stub.to)
+	}
+	if !strings.Contains(stub.data, "Subject: [FIRING] api-timeouts") {
+		t.Errorf("Expected subject in message, got:\n%s", stub.data)
+	}
+	if !strings.Contains(stub.data, "ERROR upstream timeout") || !strings.Contains(stub.data, "pending -> firing") {
+		t.Errorf("Expected event details in body, got:\n%s", stub.data)
+	}
+}
+
+func TestExecNotifier_RunsCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	output := filepath.Join(t.TempDir(), "alert.txt")
+	notifier, err := NewExecNotifier(config.NotifierConfig{
+		Name:    "script",
+		Command: "sh",
+		Args:    []string{"-c", `echo "$1 $ALERT_STATE $ALERT_COUNT" > ` + output + `; cat >> ` + output, "notify", "{{.Rule}}"},
+	})
+	if err != nil {
+		t.Fatalf("NewExecNotifier failed: %v", err)
+	}
+
+	if err := notifier.Notify(context.Background(), firingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 2)
+	if lines[0] != "api-timeouts firing 23" {
+		t.Errorf("Unexpected command output: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"rule":"api-timeouts"`) {
+		t.Errorf("Expected event JSON on stdin, got %q", lines[1])
+	}
+
+	failing, _ := NewExecNotifier(config.NotifierConfig{Name: "fail", Command: "sh", Args: []string{"-c", "exit 3"}})
+	if err := failing.Notify(context.Background(), firingEvent()); err == nil || IsPermanent(err) {
+		t.Errorf("Expected retryable error for non-zero exit, got %v", err)
+	}
+
+	missing, _ := NewExecNotifier(config.NotifierConfig{Name: "missing", Command: "/nonexistent/notify"})
+	if err := missing.Notify(context.Background(), firingEvent()); !IsPermanent(err) {
+		t.Errorf("Expected permanent error for missing command, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package alert
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	defaultSMTPPort = 25
+
+	defaultSubjectTemplate = `[{{upper (printf "%s" .State)}}] {{.Rule}}`
+	defaultMailTemplate    = `告警规则: {{.Rule}}
+状态: {{.Previous}} -> {{.State}}
+级别: {{.Severity}}
+文件: {{.Path}}
+窗口内匹配数: {{.Count}}（阈值 {{.Threshold}}，窗口 {{.Window}}）
+时间: {{.Time.Format "2006-01-02 15:04:05 MST"}}
+{{if .Samples}}
+最近匹配的日志:
+{{range .Samples}}{{.Raw}}
+{{end}}{{end}}`
+)
+
+// SMTPNotifier 通过邮件发送告警
+type SMTPNotifier struct {
+	name     string
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+	subject  *template.Template
+	body     *template.Template
+}
+
+// NewSMTPNotifier 创建邮件通知渠道
+func NewSMTPNotifier(cfg config.NotifierConfig) (*SMTPNotifier, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("smtp host, from and to are required")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+
+	subjectText := cfg.Subject
+	if subjectText == "" {
+		subjectText = defaultSubjectTemplate
+	}
+	subject, err := parseTemplate(cfg.Name+"-subject", subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	bodyText := cfg.Body
+	if bodyText == "" {
+		bodyText = defaultMailTemplate
+	}
+	body, err := parseTemplate(cfg.Name+"-body", bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	return &SMTPNotifier{
+		name:     cfg.Name,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		host:     cfg.Host,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+		to:       cfg.To,
+		subject:  subject,
+		body:     body,
+	}, nil
+}
+
+// Name 返回通知渠道名称
+func (n *SMTPNotifier) Name() string {
+	return n.name
+}
+
+// Notify 发送邮件，服务器支持时使用 STARTTLS；5xx 响应不重试
+func (n *SMTPNotifier) Notify(ctx context.Context, event types.AlertEvent) error {
+	message, err := n.buildMessage(event)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server %s: %w", n.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return classifySMTPError(err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return classifySMTPError(err)
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return classifySMTPError(err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return classifySMTPError(err)
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return classifySMTPError(err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return classifySMTPError(err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return classifySMTPError(err)
+	}
+	if err := writer.Close(); err != nil {
+		return classifySMTPError(err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage 生成邮件内容
+func (n *SMTPNotifier) buildMessage(event types.AlertEvent) ([]byte, error) {
+	subject, err := renderTemplate(n.subject, event)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderTemplate(n.body, event)
+	if err != nil {
+		return nil, err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("From: " + n.from + "\r\n")
+	builder.WriteString("To: " + strings.Join(n.to, ", ") + "\r\n")
+	builder.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)) + "\r\n")
+	builder.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(builder.String()), nil
+}
+
+// classifySMTPError 服务器返回 5xx 时为永久错误，其他错误可重试
+func classifySMTPError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(fmt.Errorf("smtp server rejected message: %w", err))
+	}
+	return fmt.Errorf("smtp delivery failed: %w", err)
+}
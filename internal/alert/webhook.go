@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// SignatureHeader webhook 请求体的 HMAC-SHA256 签名头，格式为 sha256=<hex>
+const SignatureHeader = "X-LogViewer-Signature"
+
+// WebhookNotifier 通过 HTTP 请求发送告警
+type WebhookNotifier struct {
+	name    string
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+	secret  []byte
+	client  *http.Client
+}
+
+// NewWebhookNotifier 创建 webhook 通知渠道
+func NewWebhookNotifier(cfg config.NotifierConfig) (*WebhookNotifier, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+
+	n := &WebhookNotifier{
+		name:    cfg.Name,
+		url:     cfg.URL,
+		method:  strings.ToUpper(cfg.Method),
+		headers: cfg.Headers,
+		secret:  []byte(cfg.Secret),
+		client:  &http.Client{},
+	}
+	if n.method == "" {
+		n.method = http.MethodPost
+	}
+
+	if cfg.Body != "" {
+		body, err := parseTemplate(cfg.Name, cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+		n.body = body
+	}
+
+	return n, nil
+}
+
+// Name 返回通知渠道名称
+func (n *WebhookNotifier) Name() string {
+	return n.name
+}
+
+// Notify 发送请求，网络错误、5xx 和 429 可重试，其他 4xx 不重试
+func (n *WebhookNotifier) Notify(ctx context.Context, event types.AlertEvent) error {
+	var payload []byte
+	if n.body != nil {
+		rendered, err := renderTemplate(n.body, event)
+		if err != nil {
+			return Permanent(err)
+		}
+		payload = []byte(rendered)
+	} else {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return Permanent(fmt.Errorf("failed to marshal event: %w", err))
+		}
+		payload = data
+	}
+
+	req, err := http.NewRequestWithContext(ctx, n.method, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.headers {
+		req.Header.Set(key, value)
+	}
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return Permanent(err)
+}
+
+// Sign 计算请求体的签名，接收方用相同的密钥验证
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,487 @@
+package annotation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// storeFileName 标注在数据目录中的文件名
+	storeFileName = "annotations.json"
+	// headSize 用于识别文件的文件头长度，文件被替换或截断后标注不再显示
+	headSize = 1024
+	// maxLineSnapshot 保存的行内容最大长度
+	maxLineSnapshot = 256
+	// maxTags 单个标注的最大标签数
+	maxTags = 20
+	// idLength 标注ID长度
+	idLength = 10
+	// idAlphabet 标注ID字符集
+	idAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// readTimeout 读取日志文件（可能是远程日志源）的超时时间
+	readTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotFound 标注不存在
+	ErrNotFound = errors.New("annotation not found")
+	// ErrForbidden 只有作者可以修改或删除
+	ErrForbidden = errors.New("only the author can modify an annotation")
+	// ErrInvalid 标注内容或位置无效
+	ErrInvalid = errors.New("invalid annotation")
+)
+
+// record 持久化的标注，附带创建时的文件头哈希用于识别文件
+type record struct {
+	types.Annotation
+	HeadLen  int    `json:"headLen"`
+	HeadHash string `json:"headHash"`
+}
+
+// Resolver 返回文件所属的日志源和日志源中的规范路径，标注按规范路径保存
+type Resolver func(path string) (source.LogSource, string, error)
+
+// Store 日志行标注存储，持久化为数据目录下的JSON文件，dataDir 为空时只保存在内存中
+type Store struct {
+	path    string
+	records map[string]*record
+	resolve Resolver
+	now     func() time.Time
+	mutex   sync.RWMutex
+}
+
+// NewStore 创建存储并加载已保存的标注，默认只能标注本地文件，见 SetResolver
+func NewStore(dataDir string) (*Store, error) {
+	local, _ := source.NewLocalSource("", nil)
+	s := &Store{
+		records: make(map[string]*record),
+		resolve: func(path string) (source.LogSource, string, error) {
+			resolved, ok := local.Resolve(path)
+			if !ok {
+				return nil, "", fmt.Errorf("not a local file: %s", path)
+			}
+			return local, resolved, nil
+		},
+		now: time.Now,
+	}
+	if dataDir == "" {
+		return s, nil
+	}
+
+	s.path = filepath.Join(dataDir, storeFileName)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	var records []*record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return s, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+
+	return s, nil
+}
+
+// SetResolver 设置文件所属日志源的查找方式，使标注可以用于远程日志源和归档中的文件
+func (s *Store) SetResolver(resolve Resolver) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resolve = resolve
+}
+
+// ForFile 返回文件当前内容上的标注，按位置排序
+// 文件被替换或截断后，之前的标注不再返回
+func (s *Store) ForFile(path string) ([]types.Annotation, error) {
+	src, path, err := s.resolveFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	var candidates []*record
+	for _, r := range s.records {
+		if r.Path == path {
+			copied := *r
+			candidates = append(candidates, &copied)
+		}
+	}
+	s.mutex.RUnlock()
+
+	result := make([]types.Annotation, 0, len(candidates))
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+	info, err := src.Stat(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+
+	hashes := make(map[int]string)
+	for _, r := range candidates {
+		// 大小未知（例如还没有完整读取过的 .gz 对象）时只按文件开头的内容判断
+		if !info.SizeUnknown && (r.Offset >= info.Size || int64(r.HeadLen) > info.Size) {
+			continue
+		}
+		hash, ok := hashes[r.HeadLen]
+		if !ok {
+			if hash, err = headHash(ctx, src, path, r.HeadLen); err != nil {
+				return nil, err
+			}
+			hashes[r.HeadLen] = hash
+		}
+		if hash == r.HeadHash {
+			result = append(result, r.Annotation)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Offset != result[j].Offset {
+			return result[i].Offset < result[j].Offset
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// Get 获取标注
+func (s *Store) Get(id string) (*types.Annotation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	r, exists := s.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	copied := r.Annotation
+	return &copied, nil
+}
+
+// Create 在文件指定位置的行上创建标注，位置必须是行的起始位置
+func (s *Store) Create(user string, annotation types.Annotation) (*types.Annotation, error) {
+	if err := validate(annotation); err != nil {
+		return nil, err
+	}
+
+	src, path, err := s.resolveFile(annotation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+	info, err := src.Stat(ctx, path)
+	if err != nil || info.IsDir {
+		return nil, fmt.Errorf("%w: failed to open file %s: %v", ErrInvalid, annotation.Path, err)
+	}
+
+	line, err := lineAt(ctx, src, path, annotation.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	headLen := headSize
+	if info.SizeUnknown {
+		// 大小未知时按实际读取到的开头内容计算
+		head, err := readRange(ctx, src, path, 0, headSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file head: %w", err)
+		}
+		headLen = len(head)
+	} else if info.Size < int64(headLen) {
+		headLen = int(info.Size)
+	}
+	hash, err := headHash(ctx, src, path, headLen)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id, err := s.newIDLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	r := &record{
+		Annotation: types.Annotation{
+			ID:        id,
+			Path:      path,
+			Offset:    annotation.Offset,
+			Line:      line,
+			Pinned:    annotation.Pinned,
+			Tags:      normalizeTags(annotation.Tags),
+			Comment:   strings.TrimSpace(annotation.Comment),
+			Author:    user,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		HeadLen:  headLen,
+		HeadHash: hash,
+	}
+	s.records[id] = r
+
+	if err := s.saveLocked(); err != nil {
+		delete(s.records, id)
+		return nil, err
+	}
+
+	copied := r.Annotation
+	return &copied, nil
+}
+
+// Update 修改标注的书签状态、标签和评论，只有作者可以修改
+func (s *Store) Update(id, user string, annotation types.Annotation) (*types.Annotation, error) {
+	if err := validate(annotation); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, err := s.ownedLocked(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := r.Annotation
+	r.Pinned = annotation.Pinned
+	r.Tags = normalizeTags(annotation.Tags)
+	r.Comment = strings.TrimSpace(annotation.Comment)
+	r.UpdatedAt = s.now()
+
+	if err := s.saveLocked(); err != nil {
+		r.Annotation = previous
+		return nil, err
+	}
+
+	copied := r.Annotation
+	return &copied, nil
+}
+
+// Delete 删除标注并返回被删除的标注，只有作者可以删除
+func (s *Store) Delete(id, user string) (*types.Annotation, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, err := s.ownedLocked(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(s.records, id)
+	if err := s.saveLocked(); err != nil {
+		s.records[id] = r
+		return nil, err
+	}
+
+	copied := r.Annotation
+	return &copied, nil
+}
+
+// Attach 将标注附加到对应位置的日志条目上
+func Attach(entries []types.LogEntry, annotations []types.Annotation) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	byOffset := make(map[int64][]types.Annotation, len(annotations))
+	for _, annotation := range annotations {
+		byOffset[annotation.Offset] = append(byOffset[annotation.Offset], annotation)
+	}
+	for i := range entries {
+		if found := byOffset[entries[i].ByteOffset]; len(found) > 0 {
+			entries[i].Annotations = found
+		}
+	}
+}
+
+// validate 检查标注内容
+func validate(annotation types.Annotation) error {
+	if annotation.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalid)
+	}
+	if len(annotation.Tags) > maxTags {
+		return fmt.Errorf("%w: at most %d tags are allowed", ErrInvalid, maxTags)
+	}
+	if !annotation.Pinned && len(normalizeTags(annotation.Tags)) == 0 && strings.TrimSpace(annotation.Comment) == "" {
+		return fmt.Errorf("%w: an annotation needs a comment, a tag or pinned", ErrInvalid)
+	}
+	return nil
+}
+
+// normalizeTags 去除空白和重复的标签
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// resolveFile 查找文件所属的日志源，标注按日志源中的规范路径（本地文件为绝对路径）保存
+func (s *Store) resolveFile(path string) (source.LogSource, string, error) {
+	if path == "" {
+		return nil, "", fmt.Errorf("%w: path is required", ErrInvalid)
+	}
+	s.mutex.RLock()
+	resolve := s.resolve
+	s.mutex.RUnlock()
+
+	src, resolved, err := resolve(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: invalid path %s: %v", ErrInvalid, path, err)
+	}
+	return src, resolved, nil
+}
+
+// readRange 读取文件 [offset, offset+length) 范围内的内容，文件较短时返回的内容也较短
+func readRange(ctx context.Context, src source.LogSource, path string, offset, length int64) ([]byte, error) {
+	r, err := src.Open(ctx, path, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, length))
+}
+
+// lineAt 读取从 offset 开始的一行（最多 maxLineSnapshot 字节），offset 必须是行的起始位置
+func lineAt(ctx context.Context, src source.LogSource, path string, offset int64) (string, error) {
+	if offset > 0 {
+		prev, err := readRange(ctx, src, path, offset-1, 1)
+		if err != nil {
+			return "", fmt.Errorf("failed to read line: %w", err)
+		}
+		if len(prev) == 0 {
+			return "", fmt.Errorf("%w: offset %d is beyond the end of file", ErrInvalid, offset)
+		}
+		if prev[0] != '\n' {
+			return "", fmt.Errorf("%w: offset %d is not the start of a line", ErrInvalid, offset)
+		}
+	}
+
+	data, err := readRange(ctx, src, path, offset, maxLineSnapshot+2)
+	if err != nil {
+		return "", fmt.Errorf("failed to read line: %w", err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("%w: offset %d is beyond the end of file", ErrInvalid, offset)
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	line := strings.TrimRight(string(data), "\r\n")
+	if len(line) > maxLineSnapshot {
+		line = line[:maxLineSnapshot]
+	}
+	return line, nil
+}
+
+// headHash 计算文件前 n 个字节的哈希
+func headHash(ctx context.Context, src source.LogSource, path string, n int) (string, error) {
+	buf := make([]byte, n)
+	if n > 0 {
+		head, err := readRange(ctx, src, path, 0, int64(n))
+		if err != nil {
+			return "", fmt.Errorf("failed to read file head: %w", err)
+		}
+		copy(buf, head)
+	}
+	sum := sha1.Sum(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ownedLocked 获取属于用户的标注（调用方持有锁）
+func (s *Store) ownedLocked(id, user string) (*record, error) {
+	r, exists := s.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if r.Author != user {
+		return nil, ErrForbidden
+	}
+	return r, nil
+}
+
+// newIDLocked 生成未使用的ID（调用方持有锁）
+func (s *Store) newIDLocked() (string, error) {
+	max := big.NewInt(int64(len(idAlphabet)))
+	for {
+		var builder strings.Builder
+		for i := 0; i < idLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate id: %w", err)
+			}
+			builder.WriteByte(idAlphabet[n.Int64()])
+		}
+		if id := builder.String(); s.records[id] == nil {
+			return id, nil
+		}
+	}
+}
+
+// saveLocked 写入磁盘（调用方持有锁）
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	records := make([]*record, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下不完整的文件
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save annotations: %w", err)
+	}
+	return nil
+}
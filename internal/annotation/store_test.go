@@ -0,0 +1,194 @@
+package annotation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/source/sourcetest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+const testContent = "first line\nsecond line\r\nthird line\n"
+
+func writeLog(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func appendLog(t *testing.T, path, content string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+}
+
+func TestStore_CreateAndForFile(t *testing.T) {
+	path := writeLog(t, testContent)
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	created, err := store.Create("alice", types.Annotation{
+		Path:    path,
+		Offset:  11,
+		Tags:    []string{"root-cause", " ", "root-cause"},
+		Comment: " root cause here ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "second line", created.Line)
+	assert.Equal(t, []string{"root-cause"}, created.Tags)
+	assert.Equal(t, "root cause here", created.Comment)
+	assert.Equal(t, "alice", created.Author)
+
+	// 追加内容后标注仍然有效
+	appendLog(t, path, "fourth line\n")
+	annotations, err := store.ForFile(path)
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, created.ID, annotations[0].ID)
+	assert.Equal(t, int64(11), annotations[0].Offset)
+}
+
+func TestStore_FileReplaced(t *testing.T) {
+	path := writeLog(t, testContent)
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	_, err = store.Create("alice", types.Annotation{Path: path, Offset: 0, Pinned: true})
+	require.NoError(t, err)
+
+	// 文件被替换（例如轮转）后，旧的标注不应出现在新内容上
+	require.NoError(t, os.WriteFile(path, []byte("rotated line\nanother line\n"), 0644))
+	annotations, err := store.ForFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, annotations)
+}
+
+func TestStore_InvalidOffset(t *testing.T) {
+	path := writeLog(t, testContent)
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		annotation types.Annotation
+	}{
+		{"middle of line", types.Annotation{Path: path, Offset: 3, Pinned: true}},
+		{"beyond end", types.Annotation{Path: path, Offset: int64(len(testContent)), Pinned: true}},
+		{"negative", types.Annotation{Path: path, Offset: -1, Pinned: true}},
+		{"empty", types.Annotation{Path: path, Offset: 0}},
+		{"missing file", types.Annotation{Path: path + ".missing", Offset: 0, Pinned: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Create("alice", tt.annotation)
+			assert.True(t, errors.Is(err, ErrInvalid), "expected ErrInvalid, got %v", err)
+		})
+	}
+}
+
+func TestStore_SourceFiles(t *testing.T) {
+	fixtures := sourcetest.NewMemory("mem://fixtures")
+	fixtures.Write("app.log", testContent)
+	store, err := NewStore("")
+	require.NoError(t, err)
+	store.SetResolver(func(path string) (source.LogSource, string, error) {
+		if resolved, ok := fixtures.Resolve(path); ok {
+			return fixtures, resolved, nil
+		}
+		return nil, "", errors.New("unknown source")
+	})
+
+	// 标注按日志源中的规范路径保存
+	_, err = store.Create("alice", types.Annotation{Path: "mem://fixtures/logs/../app.log", Offset: 11, Pinned: true})
+	require.Error(t, err, "paths with .. must be rejected")
+	created, err := store.Create("alice", types.Annotation{Path: "mem://fixtures//app.log", Offset: 11, Pinned: true})
+	require.NoError(t, err)
+	assert.Equal(t, "mem://fixtures/app.log", created.Path)
+	assert.Equal(t, "second line", created.Line)
+
+	fixtures.Append("app.log", "fourth line\n")
+	annotations, err := store.ForFile("mem://fixtures/app.log")
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, created.ID, annotations[0].ID)
+
+	_, err = store.Create("alice", types.Annotation{Path: "mem://fixtures/app.log", Offset: 3, Pinned: true})
+	assert.True(t, errors.Is(err, ErrInvalid), "expected ErrInvalid, got %v", err)
+	_, err = store.Create("alice", types.Annotation{Path: "/var/log/app.log", Offset: 0, Pinned: true})
+	assert.True(t, errors.Is(err, ErrInvalid), "expected ErrInvalid, got %v", err)
+
+	// 文件被替换后不再显示
+	fixtures.Write("app.log", "rotated line\n")
+	annotations, err = store.ForFile("mem://fixtures/app.log")
+	require.NoError(t, err)
+	assert.Empty(t, annotations)
+}
+
+func TestStore_UpdateDeleteOwnership(t *testing.T) {
+	path := writeLog(t, testContent)
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	created, err := store.Create("alice", types.Annotation{Path: path, Offset: 24, Comment: "suspicious"})
+	require.NoError(t, err)
+
+	_, err = store.Update(created.ID, "bob", types.Annotation{Comment: "mine"})
+	assert.True(t, errors.Is(err, ErrForbidden))
+	_, err = store.Delete(created.ID, "bob")
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	// 位置不能通过修改变更
+	updated, err := store.Update(created.ID, "alice", types.Annotation{Offset: 0, Pinned: true, Tags: []string{"confirmed"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(24), updated.Offset)
+	assert.True(t, updated.Pinned)
+	assert.Empty(t, updated.Comment)
+
+	deleted, err := store.Delete(created.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, path, deleted.Path)
+
+	_, err = store.Get(created.ID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestStore_Persistence(t *testing.T) {
+	path := writeLog(t, testContent)
+	dir := t.TempDir()
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	created, err := store.Create("alice", types.Annotation{Path: path, Offset: 11, Comment: "persisted"})
+	require.NoError(t, err)
+
+	reloaded, err := NewStore(dir)
+	require.NoError(t, err)
+	annotations, err := reloaded.ForFile(path)
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, created.ID, annotations[0].ID)
+	assert.Equal(t, "persisted", annotations[0].Comment)
+}
+
+func TestAttach(t *testing.T) {
+	entries := []types.LogEntry{{ByteOffset: 0}, {ByteOffset: 11}, {ByteOffset: 24}}
+	Attach(entries, []types.Annotation{
+		{ID: "a", Offset: 11},
+		{ID: "b", Offset: 11},
+		{ID: "c", Offset: 100},
+	})
+
+	assert.Empty(t, entries[0].Annotations)
+	require.Len(t, entries[1].Annotations, 2)
+	assert.Equal(t, "a", entries[1].Annotations[0].ID)
+	assert.Empty(t, entries[2].Annotations)
+}
@@ -78,6 +78,7 @@ func (c *MemoryCache) Get(key string) (interface{}, bool) {
 	item, exists := c.items[key]
 	if !exists {
 		atomic.AddInt64(&c.misses, 1)
+		cacheMisses.Inc()
 		return nil, false
 	}
 
@@ -88,6 +89,7 @@ func (c *MemoryCache) Get(key string) (interface{}, bool) {
 		c.currentSize -= item.size
 		delete(c.items, key)
 		atomic.AddInt64(&c.misses, 1)
+		cacheMisses.Inc()
 		return nil, false
 	}
 
@@ -96,6 +98,7 @@ func (c *MemoryCache) Get(key string) (interface{}, bool) {
 	atomic.AddInt64(&item.accessCount, 1)
 	c.items[key] = item
 	atomic.AddInt64(&c.hits, 1)
+	cacheHits.Inc()
 
 	return item.value, true
 }
@@ -181,6 +184,7 @@ func (c *MemoryCache) evictLRU() {
 			c.currentSize -= item.size
 			delete(c.items, lruKey)
 			atomic.AddInt64(&c.evictions, 1)
+			cacheEvictions.Inc()
 		}
 	}
 }
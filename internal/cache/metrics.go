@@ -0,0 +1,13 @@
+package cache
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	cacheRequests = metrics.NewCounterVec("logviewer_cache_requests_total",
+		"Cache lookups by result (hit or miss).", "result")
+	cacheEvictions = metrics.NewCounter("logviewer_cache_evictions_total",
+		"Cache items evicted to stay within the size or memory limit.")
+
+	cacheHits   = cacheRequests.WithLabelValues("hit")
+	cacheMisses = cacheRequests.WithLabelValues("miss")
+)
@@ -72,6 +72,39 @@ func (sc *SearchCache) InvalidateFile(filePath string) {
 	sc.cache.Clear() // 简化实现：清空所有缓存
 }
 
+// GetFacets 获取字段聚合结果，version 标识文件版本（如大小和修改时间）
+func (sc *SearchCache) GetFacets(query types.FacetQuery, version string) (*types.FacetResult, bool) {
+	key := sc.generateFacetKey(query, version)
+
+	if cached, found := sc.cache.Get(key); found {
+		if result, ok := cached.(*CachedFacetResult); ok {
+			if time.Now().Before(result.ExpiresAt) {
+				return result.Result, true
+			}
+			sc.cache.Delete(key)
+		}
+	}
+
+	return nil, false
+}
+
+// SetFacets 设置字段聚合结果
+func (sc *SearchCache) SetFacets(query types.FacetQuery, version string, result *types.FacetResult) {
+	key := sc.generateFacetKey(query, version)
+	sc.cache.Set(key, &CachedFacetResult{
+		Result:    result,
+		ExpiresAt: time.Now().Add(sc.ttl),
+	})
+}
+
+// generateFacetKey 生成字段聚合缓存键
+func (sc *SearchCache) generateFacetKey(query types.FacetQuery, version string) string {
+	data := fmt.Sprintf("%s|%s|%d|%s", sc.generateKey(query.SearchQuery), query.Field, query.TopN, version)
+
+	hash := md5.Sum([]byte(data))
+	return fmt.Sprintf("facets:%x", hash)
+}
+
 // generateKey 生成缓存键
 func (sc *SearchCache) generateKey(query types.SearchQuery) string {
 	// 创建查询的唯一标识
@@ -97,6 +130,12 @@ type CachedSearchResult struct {
 	Query     types.SearchQuery   `json:"query"`
 }
 
+// CachedFacetResult 缓存的字段聚合结果
+type CachedFacetResult struct {
+	Result    *types.FacetResult `json:"result"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+}
+
 // FileContentCache 文件内容缓存
 type FileContentCache struct {
 	cache     interfaces.LogCache
@@ -3,20 +3,40 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/local-log-viewer/internal/types"
 )
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Logging  LogConfig      `yaml:"logging"`
-	Security SecurityConfig `yaml:"security"`
+	Server      ServerConfig      `yaml:"server"`
+	Logging     LogConfig         `yaml:"logging"`
+	Security    SecurityConfig    `yaml:"security"`
+	Parsers     []ParserConfig    `yaml:"parsers,omitempty"`
+	Formats     []FormatConfig    `yaml:"formats,omitempty"`
+	Alerts      []AlertRuleConfig `yaml:"alerts,omitempty"`
+	Notifiers   []NotifierConfig  `yaml:"notifiers,omitempty"`
+	Streaming   StreamingConfig   `yaml:"streaming"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	LogMetrics  LogMetricsConfig  `yaml:"logMetrics"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Correlation CorrelationConfig `yaml:"correlation"`
+	SSH         SSHConfig         `yaml:"ssh"`
+	Docker      DockerConfig      `yaml:"docker"`
+	Journal     JournalConfig     `yaml:"journal"`
+	S3          S3Config          `yaml:"s3"`
+
+	// ConfigPath 实际加载的配置文件路径（未加载文件时为空）
+	ConfigPath string `yaml:"-"`
 }
 
 // ServerConfig 服务器配置
@@ -26,6 +46,109 @@ type ServerConfig struct {
 	LogPaths    []string `yaml:"logPaths"`
 	MaxFileSize int64    `yaml:"maxFileSize"`
 	CacheSize   int      `yaml:"cacheSize"`
+	DataDir     string   `yaml:"dataDir"` // 持久化数据目录（文件摘要等），为空时只保存在内存中
+}
+
+// 慢速客户端处理策略
+const (
+	SlowConsumerDropOldest = "drop_oldest" // 丢弃最早积压的更新，并通知客户端缺失的范围
+	SlowConsumerCoalesce   = "coalesce"    // 合并积压的更新，超出上限的最早条目丢弃并通知
+	SlowConsumerDisconnect = "disconnect"  // 断开连接
+)
+
+// StreamingConfig 实时日志推送配置
+type StreamingConfig struct {
+	SlowConsumerPolicy  string `yaml:"slowConsumerPolicy"`  // drop_oldest（默认）、coalesce 或 disconnect
+	MaxPendingUpdates   int    `yaml:"maxPendingUpdates"`   // 每个订阅最多积压的更新数，默认 100
+	MaxCoalescedEntries int    `yaml:"maxCoalescedEntries"` // coalesce 合并后最多保留的条目数，默认 1000
+	MaxResyncBytes      int64  `yaml:"maxResyncBytes"`      // 单次 resync 最多读取的字节数，默认 1MB
+	MaxReplayBytes      int64  `yaml:"maxReplayBytes"`      // 断线重连续传时最多回放的字节数，默认 8MB
+}
+
+// MetricsConfig Prometheus 指标配置，访问控制独立于界面的认证
+type MetricsConfig struct {
+	Enabled    bool     `yaml:"enabled"`    // 是否提供指标端点，默认启用
+	Path       string   `yaml:"path"`       // 指标端点路径，默认 /metrics
+	Token      string   `yaml:"token"`      // 抓取时需要提供的 Bearer 令牌
+	AllowedIPs []string `yaml:"allowedIPs"` // 允许抓取的IP或CIDR；未设置令牌和允许列表时只允许本机访问
+}
+
+// 链路追踪导出方式
+const (
+	TracingExporterOTLP   = "otlp"   // OTLP/HTTP
+	TracingExporterStdout = "stdout" // 输出到标准输出，用于调试
+)
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`     // 是否启用，默认关闭
+	Exporter    string            `yaml:"exporter"`    // otlp 或 stdout，默认 otlp
+	Endpoint    string            `yaml:"endpoint"`    // OTLP/HTTP 地址，例如 localhost:4318；为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool              `yaml:"insecure"`    // 使用 HTTP 而不是 HTTPS 连接 OTLP 地址
+	Headers     map[string]string `yaml:"headers"`     // OTLP 请求头，例如认证令牌
+	ServiceName string            `yaml:"serviceName"` // 服务名，默认 local-log-viewer
+	SampleRatio float64           `yaml:"sampleRatio"` // 采样比例 0~1，默认 1；请求带有 traceparent 时跟随上游的采样决定
+}
+
+// CorrelationConfig 按 trace ID 或 request ID 跨文件关联日志的配置
+type CorrelationConfig struct {
+	IDFields       []string `yaml:"idFields"`       // 保存关联 ID 的字段，默认 trace_id、request_id、traceId、requestId
+	SpanIDFields   []string `yaml:"spanIdFields"`   // span ID 字段，默认 span_id、spanId
+	ParentIDFields []string `yaml:"parentIdFields"` // 父 span ID 字段，默认 parent_span_id、parent_id、parentSpanId
+	MaxResults     int      `yaml:"maxResults"`     // 单次查询最多返回的条目数，默认 1000
+	MaxFiles       int      `yaml:"maxFiles"`       // 单次查询最多扫描的文件数，默认 200
+}
+
+// SSHPathPrefix 远程日志路径的前缀，例如 ssh://user@host:22/var/log/app
+const SSHPathPrefix = "ssh://"
+
+// SSHConfig 远程日志路径 (ssh://) 的连接配置，认证方式与 OpenSSH 相同
+type SSHConfig struct {
+	KnownHostsFiles []string      `yaml:"knownHostsFiles"` // 校验主机密钥的 known_hosts 文件，默认 ~/.ssh/known_hosts
+	IdentityFiles   []string      `yaml:"identityFiles"`   // 私钥文件，默认使用 ~/.ssh 下存在的 id_ed25519、id_ecdsa、id_rsa
+	DisableAgent    bool          `yaml:"disableAgent"`    // 不使用 SSH_AUTH_SOCK 指向的 ssh-agent
+	Timeout         time.Duration `yaml:"timeout"`         // 连接超时，默认 10s
+	PollInterval    time.Duration `yaml:"pollInterval"`    // 实时跟踪远程文件时检查变化的间隔，默认 2s
+	MaxSessions     int           `yaml:"maxSessions"`     // 执行远程命令时每个主机同时打开的会话数，默认 8（OpenSSH 默认允许 10 个）
+	Exec            bool          `yaml:"exec"`            // 在远程主机上执行 find、tail 等 GNU 命令代替 SFTP，默认只在远程主机不支持 SFTP 时使用
+}
+
+// DockerPathPrefix 容器日志路径的前缀，后面是 Docker Engine API 的 Unix socket，例如 docker:///var/run/docker.sock
+const DockerPathPrefix = "docker://"
+
+// DockerConfig 容器日志路径 (docker://) 的配置
+type DockerConfig struct {
+	Tail         int           `yaml:"tail"`         // 第一次读取容器日志时读取的最近行数，默认 1000，-1 读取全部
+	Timeout      time.Duration `yaml:"timeout"`      // 连接 Docker Engine API 的超时，默认 10s
+	PollInterval time.Duration `yaml:"pollInterval"` // 实时跟踪时容器停止或不存在后重新检查的间隔，默认 2s
+	MaxBytes     int64         `yaml:"maxBytes"`     // 每个容器在内存中保留的日志字节数，超过时丢弃最早的行，默认 32MB
+}
+
+// JournalPathPrefix systemd journal 路径的前缀，后面是 journal 文件所在的目录，例如 journal:///var/log/journal
+const JournalPathPrefix = "journal://"
+
+// JournalConfig systemd journal 路径 (journal://) 的配置
+type JournalConfig struct {
+	Command      string        `yaml:"command"`      // journalctl 命令的路径，默认 journalctl
+	Tail         int           `yaml:"tail"`         // 第一次读取单元日志时读取的最近条数，默认 1000，-1 读取全部
+	PollInterval time.Duration `yaml:"pollInterval"` // 直接读取 journal 文件时检查新记录的间隔，以及 journalctl 跟踪中断后重新检查的间隔，默认 2s
+	MaxBytes     int64         `yaml:"maxBytes"`     // 每个单元在内存中保留的日志字节数，超过时丢弃最早的记录，默认 32MB
+	Exec         bool          `yaml:"exec"`         // 总是通过 journalctl 读取，默认直接读取 journal 文件，只在记录的字段被压缩或文件格式不支持时使用 journalctl
+}
+
+// S3PathPrefix 对象存储路径的前缀，后面是桶和可选的前缀，例如 s3://app-logs/archive/2024
+const S3PathPrefix = "s3://"
+
+// S3Config S3 兼容对象存储路径 (s3://) 的配置，未配置的区域、地址和凭证从 AWS_* 环境变量读取
+type S3Config struct {
+	Endpoint        string        `yaml:"endpoint"`        // 服务地址，例如 http://minio.internal:9000，默认 AWS_ENDPOINT_URL 或 https://s3.<region>.amazonaws.com
+	Region          string        `yaml:"region"`          // 签名使用的区域，默认 AWS_REGION、AWS_DEFAULT_REGION 或 us-east-1
+	AccessKeyID     string        `yaml:"accessKeyId"`     // 默认 AWS_ACCESS_KEY_ID，没有凭证时发送匿名请求
+	SecretAccessKey string        `yaml:"secretAccessKey"` // 默认 AWS_SECRET_ACCESS_KEY
+	SessionToken    string        `yaml:"sessionToken"`    // 临时凭证的令牌，默认 AWS_SESSION_TOKEN
+	PathStyle       bool          `yaml:"pathStyle"`       // 使用 <endpoint>/<bucket> 形式的地址（MinIO 等通常需要），否则使用 <bucket>.<endpoint>
+	Timeout         time.Duration `yaml:"timeout"`         // 列出对象、获取对象信息的超时，默认 30s
+	PollInterval    time.Duration `yaml:"pollInterval"`    // 实时跟踪时检查对象变化的间隔，默认 30s
 }
 
 // LogConfig 日志配置
@@ -37,11 +160,13 @@ type LogConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	EnableAuth bool      `yaml:"enableAuth"`
-	Username   string    `yaml:"username"`
-	Password   string    `yaml:"password"`
-	AllowedIPs []string  `yaml:"allowedIPs"`
-	TLS        TLSConfig `yaml:"tls"`
+	EnableAuth bool     `yaml:"enableAuth"`
+	Username   string   `yaml:"username"`
+	Password   string   `yaml:"password"`
+	AllowedIPs []string `yaml:"allowedIPs"`
+	// TrustedProxies 前置认证代理的IP或CIDR，未启用内置认证时只接受来自这些地址的 X-Remote-User 请求头
+	TrustedProxies []string  `yaml:"trustedProxies"`
+	TLS            TLSConfig `yaml:"tls"`
 }
 
 // TLSConfig TLS配置
@@ -52,6 +177,103 @@ type TLSConfig struct {
 	AutoCert bool   `yaml:"autoCert"`
 }
 
+// ParserConfig 自定义解析器配置
+type ParserConfig struct {
+	Name            string            `yaml:"name"`
+	Type            string            `yaml:"type"`            // regex, grok
+	Pattern         string            `yaml:"pattern"`         // 命名分组正则或grok模式
+	TimestampField  string            `yaml:"timestampField"`  // 时间戳字段名
+	TimestampLayout string            `yaml:"timestampLayout"` // Go时间布局，或 unix、unix_ms
+	LevelField      string            `yaml:"levelField"`      // 日志级别字段名
+	MessageField    string            `yaml:"messageField"`    // 消息字段名
+	FieldTypes      map[string]string `yaml:"fieldTypes"`      // 字段类型转换: int, float, bool, duration(秒), string
+	Files           []string          `yaml:"files"`           // 绑定到该解析器的文件glob
+}
+
+// FormatConfig 为指定文件固定日志格式，跳过自动检测
+type FormatConfig struct {
+	Format string   `yaml:"format"` // 内置格式(json, logfmt, syslog, common)或自定义解析器名称
+	Files  []string `yaml:"files"`  // 文件glob
+}
+
+// AlertRuleConfig 告警规则配置
+// 例如 "api.log 中 1 分钟内超过 20 条匹配 timeout 的 ERROR 日志"
+type AlertRuleConfig struct {
+	Name      string        `yaml:"name"`
+	Path      string        `yaml:"path"`      // 日志文件路径，相对路径在日志目录中查找
+	Query     string        `yaml:"query"`     // 关键词或正则表达式，为空时匹配所有行
+	IsRegex   bool          `yaml:"isRegex"`   // Query 是否为正则表达式
+	Levels    []string      `yaml:"levels"`    // 日志级别过滤
+	Window    time.Duration `yaml:"window"`    // 统计窗口
+	Threshold int           `yaml:"threshold"` // 窗口内匹配数超过该值时触发
+	For       time.Duration `yaml:"for"`       // 持续超过阈值多久后才进入 firing，为 0 时立即触发
+	Cooldown  time.Duration `yaml:"cooldown"`  // 两次触发之间的最短间隔
+	Severity  string        `yaml:"severity"`  // 告警级别，默认 warning
+}
+
+// 从日志中提取的指标类型
+const (
+	LogMetricCounter = "counter" // 累加匹配条数，或设置 value 时累加字段值
+	LogMetricGauge   = "gauge"   // 记录字段值的观测值
+)
+
+// LogMetricsConfig 从日志中提取的指标（log-to-metric），保存在内存中的环形时间序列里
+type LogMetricsConfig struct {
+	Retention        time.Duration         `yaml:"retention"`        // 保留时长，默认 6h
+	Resolution       time.Duration         `yaml:"resolution"`       // 时间分辨率，默认 10s
+	MaxBackfillBytes int64                 `yaml:"maxBackfillBytes"` // 启动时从每个文件末尾回填的最大字节数，默认 64MB，为负数时不回填
+	MaxSeries        int                   `yaml:"maxSeries"`        // 每个指标最多的分组数，默认 100
+	Rules            []LogMetricRuleConfig `yaml:"rules"`
+}
+
+// LogMetricRuleConfig 单个日志指标的定义，过滤条件与实时订阅相同
+type LogMetricRuleConfig struct {
+	Name    string                 `yaml:"name"`
+	Type    string                 `yaml:"type"`    // counter 或 gauge
+	Path    string                 `yaml:"path"`    // 日志文件路径，相对路径在日志目录中查找
+	Query   string                 `yaml:"query"`   // 关键词或正则表达式，为空时匹配所有行
+	IsRegex bool                   `yaml:"isRegex"` // Query 是否为正则表达式
+	Levels  []string               `yaml:"levels"`  // 日志级别过滤
+	Fields  []types.FieldPredicate `yaml:"fields"`  // 字段条件，全部满足才匹配
+	Value   string                 `yaml:"value"`   // 数值字段，例如 latency_ms；gauge 必须设置
+	GroupBy string                 `yaml:"groupBy"` // 分组字段，例如 status
+}
+
+// NotifierConfig 告警通知配置，不同类型使用各自的字段
+type NotifierConfig struct {
+	Name   string   `yaml:"name"`
+	Type   string   `yaml:"type"`   // webhook, smtp, exec
+	Rules  []string `yaml:"rules"`  // 只通知这些规则，为空时通知所有规则
+	States []string `yaml:"states"` // 通知的状态，默认 firing 和 resolved
+
+	// webhook
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`  // 默认 POST
+	Headers map[string]string `yaml:"headers"` // 额外的请求头
+	Body    string            `yaml:"body"`    // 请求体或邮件正文模板（Go text/template），webhook 为空时发送事件JSON
+	Secret  string            `yaml:"secret"`  // HMAC-SHA256 签名密钥
+	Timeout time.Duration     `yaml:"timeout"` // 单次投递超时，默认 10s
+
+	// smtp
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"` // 默认 25
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	Subject  string   `yaml:"subject"` // 邮件主题模板
+
+	// exec
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"` // 参数模板
+
+	// 投递失败后的最大尝试次数，默认 10
+	MaxAttempts int `yaml:"maxAttempts"`
+}
+
+// BuiltinFormats 内置的日志格式名称
+var BuiltinFormats = []string{"json", "docker", "journal", "logfmt", "syslog", "common"}
+
 // CommandLineOptions 命令行选项
 type CommandLineOptions struct {
 	ConfigPath  string
@@ -69,6 +291,7 @@ type CommandLineOptions struct {
 	CertFile    string
 	KeyFile     string
 	AutoCert    bool
+	DataDir     string
 }
 
 // DefaultConfig 返回默认配置
@@ -98,6 +321,10 @@ func DefaultConfig() *Config {
 				AutoCert: false,
 			},
 		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
 	}
 }
 
@@ -135,6 +362,7 @@ func LoadWithOptions(options *CommandLineOptions) (*Config, error) {
 		if err := loadFromFile(cfg, configPath); err != nil {
 			return nil, fmt.Errorf("加载配置文件失败: %w", err)
 		}
+		cfg.ConfigPath = configPath
 	}
 
 	// 命令行参数覆盖配置文件
@@ -181,6 +409,10 @@ func applyCommandLineOptions(cfg *Config, options *CommandLineOptions) error {
 		cfg.Server.CacheSize = options.CacheSize
 	}
 
+	if options.DataDir != "" {
+		cfg.Server.DataDir = options.DataDir
+	}
+
 	// 安全配置
 	if options.EnableAuth {
 		cfg.Security.EnableAuth = true
@@ -296,6 +528,71 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("安全配置错误: %w", err)
 	}
 
+	// 验证自定义解析器配置
+	if err := ValidateParserConfigs(c.Parsers); err != nil {
+		return fmt.Errorf("解析器配置错误: %w", err)
+	}
+
+	// 验证文件格式配置
+	if err := ValidateFormatConfigs(c.Formats, c.Parsers); err != nil {
+		return fmt.Errorf("日志格式配置错误: %w", err)
+	}
+
+	// 验证告警规则配置
+	if err := ValidateAlertConfigs(c.Alerts); err != nil {
+		return fmt.Errorf("告警规则配置错误: %w", err)
+	}
+
+	// 验证告警通知配置
+	if err := ValidateNotifierConfigs(c.Notifiers); err != nil {
+		return fmt.Errorf("告警通知配置错误: %w", err)
+	}
+
+	// 验证实时推送配置
+	if err := c.Streaming.Validate(); err != nil {
+		return fmt.Errorf("实时推送配置错误: %w", err)
+	}
+
+	// 验证指标配置
+	if err := c.Metrics.Validate(); err != nil {
+		return fmt.Errorf("指标配置错误: %w", err)
+	}
+
+	// 验证日志指标配置
+	if err := c.LogMetrics.Validate(); err != nil {
+		return fmt.Errorf("日志指标配置错误: %w", err)
+	}
+
+	// 验证链路追踪配置
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("链路追踪配置错误: %w", err)
+	}
+
+	// 验证日志关联配置
+	if err := c.Correlation.Validate(); err != nil {
+		return fmt.Errorf("日志关联配置错误: %w", err)
+	}
+
+	// 验证 SSH 配置
+	if err := c.SSH.Validate(); err != nil {
+		return fmt.Errorf("SSH 配置错误: %w", err)
+	}
+
+	// 验证 Docker 配置
+	if err := c.Docker.Validate(); err != nil {
+		return fmt.Errorf("Docker 配置错误: %w", err)
+	}
+
+	// 验证 journal 配置
+	if err := c.Journal.Validate(); err != nil {
+		return fmt.Errorf("journal 配置错误: %w", err)
+	}
+
+	// 验证对象存储配置
+	if err := c.S3.Validate(); err != nil {
+		return fmt.Errorf("S3 配置错误: %w", err)
+	}
+
 	return nil
 }
 
@@ -324,6 +621,32 @@ func (c *Config) validateServerConfig() error {
 			return fmt.Errorf("日志路径不能为空")
 		}
 
+		// 远程路径在连接时检查
+		if strings.HasPrefix(path, SSHPathPrefix) {
+			if err := validateSSHPath(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(path, DockerPathPrefix) {
+			if err := validateDockerPath(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(path, JournalPathPrefix) {
+			if err := validateJournalPath(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(path, S3PathPrefix) {
+			if err := validateS3Path(path); err != nil {
+				return err
+			}
+			continue
+		}
+
 		absPath, err := filepath.Abs(path)
 		if err != nil {
 			return fmt.Errorf("无效的日志路径: %s", path)
@@ -347,6 +670,75 @@ func (c *Config) validateServerConfig() error {
 	return nil
 }
 
+// validateSSHPath 验证远程日志路径，例如 ssh://user@host:22/var/log/app
+func validateSSHPath(path string) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("无效的远程日志路径 %s: %w", path, err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return fmt.Errorf("远程日志路径缺少用户名: %s", path)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return fmt.Errorf("远程日志路径不支持密码认证，请使用私钥或 ssh-agent: %s", path)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("远程日志路径缺少主机: %s", path)
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return fmt.Errorf("远程日志路径必须是绝对路径: %s", path)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("远程日志路径不能包含查询参数: %s", path)
+	}
+	return nil
+}
+
+// validateDockerPath 验证容器日志路径，例如 docker:///var/run/docker.sock
+func validateDockerPath(path string) error {
+	socket := strings.TrimPrefix(path, DockerPathPrefix)
+	if !strings.HasPrefix(socket, "/") {
+		return fmt.Errorf("容器日志路径必须是 Docker socket 的绝对路径，例如 docker:///var/run/docker.sock: %s", path)
+	}
+	if strings.ContainsAny(socket, "?#") {
+		return fmt.Errorf("容器日志路径不能包含查询参数: %s", path)
+	}
+	return nil
+}
+
+// validateJournalPath 验证 systemd journal 路径，例如 journal:///var/log/journal
+func validateJournalPath(path string) error {
+	dir := strings.TrimPrefix(path, JournalPathPrefix)
+	if !strings.HasPrefix(dir, "/") {
+		return fmt.Errorf("journal 路径必须是 journal 目录的绝对路径，例如 journal:///var/log/journal: %s", path)
+	}
+	if strings.ContainsAny(dir, "?#") {
+		return fmt.Errorf("journal 路径不能包含查询参数: %s", path)
+	}
+	return nil
+}
+
+// s3BucketPattern S3 桶名称的规则：3~63 个小写字母、数字、点和连字符，以字母或数字开头和结尾
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// validateS3Path 验证对象存储路径，例如 s3://app-logs/archive/2024
+func validateS3Path(path string) error {
+	rest := strings.TrimPrefix(path, S3PathPrefix)
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if !s3BucketPattern.MatchString(bucket) {
+		return fmt.Errorf("对象存储路径的桶名称无效，例如 s3://app-logs/archive: %s", path)
+	}
+	if strings.ContainsAny(prefix, "?#") {
+		return fmt.Errorf("对象存储路径不能包含查询参数: %s", path)
+	}
+	for _, part := range strings.Split(strings.Trim(prefix, "/"), "/") {
+		if part == "." || part == ".." {
+			return fmt.Errorf("对象存储路径不能包含 . 或 ..: %s", path)
+		}
+	}
+	return nil
+}
+
 // validateLoggingConfig 验证日志配置
 func (c *Config) validateLoggingConfig() error {
 	// 验证日志级别
@@ -399,7 +791,227 @@ func (c *Config) validateSecurityConfig() error {
 	}
 
 	// 验证IP白名单
-	for _, ip := range c.Security.AllowedIPs {
+	if err := validateAllowedIPs(c.Security.AllowedIPs); err != nil {
+		return err
+	}
+	if err := validateAllowedIPs(c.Security.TrustedProxies); err != nil {
+		return fmt.Errorf("trustedProxies: %w", err)
+	}
+
+	// 验证TLS配置
+	if c.Security.TLS.Enabled {
+		if !c.Security.TLS.AutoCert {
+			if c.Security.TLS.CertFile == "" {
+				return fmt.Errorf("启用TLS时必须设置证书文件路径或启用自动证书生成")
+			}
+			if c.Security.TLS.KeyFile == "" {
+				return fmt.Errorf("启用TLS时必须设置私钥文件路径或启用自动证书生成")
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateParserConfigs 验证自定义解析器配置
+func ValidateParserConfigs(parsers []ParserConfig) error {
+	validTypes := map[string]bool{
+		"regex": true,
+		"grok":  true,
+	}
+	validFieldTypes := map[string]bool{
+		"int":      true,
+		"float":    true,
+		"bool":     true,
+		"duration": true,
+		"string":   true,
+	}
+
+	names := make(map[string]bool)
+	for i, p := range parsers {
+		if p.Name == "" {
+			return fmt.Errorf("第%d个解析器缺少名称", i+1)
+		}
+
+		name := strings.ToLower(p.Name)
+		if names[name] {
+			return fmt.Errorf("解析器名称重复: %s", p.Name)
+		}
+		names[name] = true
+
+		if !validTypes[strings.ToLower(p.Type)] {
+			return fmt.Errorf("解析器 %s 的类型无效: %s，支持的类型: regex, grok", p.Name, p.Type)
+		}
+
+		if p.Pattern == "" {
+			return fmt.Errorf("解析器 %s 缺少匹配模式", p.Name)
+		}
+
+		for field, fieldType := range p.FieldTypes {
+			if !validFieldTypes[strings.ToLower(fieldType)] {
+				return fmt.Errorf("解析器 %s 的字段 %s 类型无效: %s", p.Name, field, fieldType)
+			}
+		}
+
+		for _, glob := range p.Files {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return fmt.Errorf("解析器 %s 的文件模式无效: %s", p.Name, glob)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateFormatConfigs 验证文件格式配置，格式必须是内置格式或已定义的自定义解析器
+func ValidateFormatConfigs(formats []FormatConfig, parsers []ParserConfig) error {
+	validFormats := make(map[string]bool)
+	for _, name := range BuiltinFormats {
+		validFormats[name] = true
+	}
+	for _, p := range parsers {
+		validFormats[strings.ToLower(p.Name)] = true
+	}
+
+	for i, f := range formats {
+		if !validFormats[strings.ToLower(f.Format)] {
+			return fmt.Errorf("第%d个格式配置的格式无效: %s", i+1, f.Format)
+		}
+
+		if len(f.Files) == 0 {
+			return fmt.Errorf("格式 %s 缺少文件模式", f.Format)
+		}
+
+		for _, glob := range f.Files {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return fmt.Errorf("格式 %s 的文件模式无效: %s", f.Format, glob)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateAlertConfigs 验证告警规则配置
+func ValidateAlertConfigs(rules []AlertRuleConfig) error {
+	names := make(map[string]bool)
+	for i, r := range rules {
+		if r.Name == "" {
+			return fmt.Errorf("第%d个告警规则缺少名称", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("告警规则名称重复: %s", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Path == "" {
+			return fmt.Errorf("告警规则 %s 缺少文件路径", r.Name)
+		}
+		if r.Window <= 0 {
+			return fmt.Errorf("告警规则 %s 的统计窗口必须大于0", r.Name)
+		}
+		if r.Threshold < 0 {
+			return fmt.Errorf("告警规则 %s 的阈值不能为负数: %d", r.Name, r.Threshold)
+		}
+		if r.For < 0 || r.Cooldown < 0 {
+			return fmt.Errorf("告警规则 %s 的 for 和 cooldown 不能为负数", r.Name)
+		}
+		if r.IsRegex {
+			if _, err := regexp.Compile(r.Query); err != nil {
+				return fmt.Errorf("告警规则 %s 的正则表达式无效: %w", r.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateNotifierConfigs 验证告警通知配置
+func ValidateNotifierConfigs(notifiers []NotifierConfig) error {
+	validStates := map[string]bool{
+		"pending":  true,
+		"firing":   true,
+		"resolved": true,
+		"inactive": true,
+	}
+
+	names := make(map[string]bool)
+	for i, n := range notifiers {
+		if n.Name == "" {
+			return fmt.Errorf("第%d个通知配置缺少名称", i+1)
+		}
+		if names[n.Name] {
+			return fmt.Errorf("通知名称重复: %s", n.Name)
+		}
+		names[n.Name] = true
+
+		switch strings.ToLower(n.Type) {
+		case "webhook":
+			if n.URL == "" {
+				return fmt.Errorf("通知 %s 缺少 url", n.Name)
+			}
+		case "smtp":
+			if n.Host == "" || n.From == "" || len(n.To) == 0 {
+				return fmt.Errorf("通知 %s 缺少 host、from 或 to", n.Name)
+			}
+		case "exec":
+			if n.Command == "" {
+				return fmt.Errorf("通知 %s 缺少 command", n.Name)
+			}
+		default:
+			return fmt.Errorf("通知 %s 的类型无效: %s，支持的类型: webhook, smtp, exec", n.Name, n.Type)
+		}
+
+		for _, state := range n.States {
+			if !validStates[strings.ToLower(state)] {
+				return fmt.Errorf("通知 %s 的状态无效: %s", n.Name, state)
+			}
+		}
+		if n.MaxAttempts < 0 || n.Timeout < 0 {
+			return fmt.Errorf("通知 %s 的 maxAttempts 和 timeout 不能为负数", n.Name)
+		}
+	}
+
+	return nil
+}
+
+// Validate 验证实时推送配置，未设置的值使用默认值
+func (s StreamingConfig) Validate() error {
+	switch s.SlowConsumerPolicy {
+	case "", SlowConsumerDropOldest, SlowConsumerCoalesce, SlowConsumerDisconnect:
+	default:
+		return fmt.Errorf("无效的慢速客户端处理策略: %s，支持 %s、%s、%s",
+			s.SlowConsumerPolicy, SlowConsumerDropOldest, SlowConsumerCoalesce, SlowConsumerDisconnect)
+	}
+	if s.MaxPendingUpdates < 0 || s.MaxCoalescedEntries < 0 || s.MaxResyncBytes < 0 || s.MaxReplayBytes < 0 {
+		return fmt.Errorf("积压和读取上限不能为负数")
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置
+func (s StreamingConfig) WithDefaults() StreamingConfig {
+	if s.SlowConsumerPolicy == "" {
+		s.SlowConsumerPolicy = SlowConsumerDropOldest
+	}
+	if s.MaxPendingUpdates == 0 {
+		s.MaxPendingUpdates = 100
+	}
+	if s.MaxCoalescedEntries == 0 {
+		s.MaxCoalescedEntries = 1000
+	}
+	if s.MaxResyncBytes == 0 {
+		s.MaxResyncBytes = 1024 * 1024
+	}
+	if s.MaxReplayBytes == 0 {
+		s.MaxReplayBytes = 8 * 1024 * 1024
+	}
+	return s
+}
+
+// validateAllowedIPs 验证IP允许列表，支持IP地址和CIDR格式
+func validateAllowedIPs(ips []string) error {
+	for _, ip := range ips {
 		if ip == "" {
 			continue
 		}
@@ -416,22 +1028,287 @@ func (c *Config) validateSecurityConfig() error {
 			}
 		}
 	}
+	return nil
+}
 
-	// 验证TLS配置
-	if c.Security.TLS.Enabled {
-		if !c.Security.TLS.AutoCert {
-			if c.Security.TLS.CertFile == "" {
-				return fmt.Errorf("启用TLS时必须设置证书文件路径或启用自动证书生成")
-			}
-			if c.Security.TLS.KeyFile == "" {
-				return fmt.Errorf("启用TLS时必须设置私钥文件路径或启用自动证书生成")
+// Validate 验证指标配置
+func (m MetricsConfig) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+	if m.Path != "" && (!strings.HasPrefix(m.Path, "/") || strings.HasPrefix(m.Path, "/api") || strings.HasPrefix(m.Path, "/ws")) {
+		return fmt.Errorf("指标端点路径必须以 / 开头，且不能位于 /api 或 /ws 下: %s", m.Path)
+	}
+	return validateAllowedIPs(m.AllowedIPs)
+}
+
+// Validate 验证链路追踪配置
+func (t TracingConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	switch t.Exporter {
+	case "", TracingExporterOTLP, TracingExporterStdout:
+	default:
+		return fmt.Errorf("导出方式无效: %s，支持 %s、%s", t.Exporter, TracingExporterOTLP, TracingExporterStdout)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("采样比例必须在 0 到 1 之间: %v", t.SampleRatio)
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置
+func (t TracingConfig) WithDefaults() TracingConfig {
+	if t.Exporter == "" {
+		t.Exporter = TracingExporterOTLP
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = "local-log-viewer"
+	}
+	if t.SampleRatio == 0 {
+		t.SampleRatio = 1
+	}
+	return t
+}
+
+// Validate 验证 SSH 配置
+func (s SSHConfig) Validate() error {
+	if s.Timeout < 0 || s.PollInterval < 0 || s.MaxSessions < 0 {
+		return fmt.Errorf("超时、检查间隔和会话数不能为负数")
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置
+func (s SSHConfig) WithDefaults() SSHConfig {
+	if s.Timeout == 0 {
+		s.Timeout = 10 * time.Second
+	}
+	if s.PollInterval == 0 {
+		s.PollInterval = 2 * time.Second
+	}
+	if s.MaxSessions == 0 {
+		s.MaxSessions = 8
+	}
+	return s
+}
+
+// Validate 验证 Docker 配置
+func (d DockerConfig) Validate() error {
+	if d.Tail < -1 {
+		return fmt.Errorf("读取的行数必须大于等于 -1")
+	}
+	if d.Timeout < 0 || d.PollInterval < 0 {
+		return fmt.Errorf("超时和检查间隔不能为负数")
+	}
+	if d.MaxBytes < 0 {
+		return fmt.Errorf("保留的字节数不能为负数")
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置
+func (d DockerConfig) WithDefaults() DockerConfig {
+	if d.Tail == 0 {
+		d.Tail = 1000
+	}
+	if d.Timeout == 0 {
+		d.Timeout = 10 * time.Second
+	}
+	if d.PollInterval == 0 {
+		d.PollInterval = 2 * time.Second
+	}
+	if d.MaxBytes == 0 {
+		d.MaxBytes = 32 * 1024 * 1024
+	}
+	return d
+}
+
+// Validate 验证 journal 配置
+func (j JournalConfig) Validate() error {
+	if j.Tail < -1 {
+		return fmt.Errorf("读取的条数必须大于等于 -1")
+	}
+	if j.PollInterval < 0 {
+		return fmt.Errorf("检查间隔不能为负数")
+	}
+	if j.MaxBytes < 0 {
+		return fmt.Errorf("保留的字节数不能为负数")
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置
+func (j JournalConfig) WithDefaults() JournalConfig {
+	if j.Command == "" {
+		j.Command = "journalctl"
+	}
+	if j.Tail == 0 {
+		j.Tail = 1000
+	}
+	if j.PollInterval == 0 {
+		j.PollInterval = 2 * time.Second
+	}
+	if j.MaxBytes == 0 {
+		j.MaxBytes = 32 * 1024 * 1024
+	}
+	return j
+}
+
+// Validate 验证对象存储配置
+func (s S3Config) Validate() error {
+	if s.Endpoint != "" {
+		u, err := url.Parse(s.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("服务地址必须是 http:// 或 https:// 开头的 URL: %s", s.Endpoint)
+		}
+	}
+	if (s.AccessKeyID == "") != (s.SecretAccessKey == "") {
+		return fmt.Errorf("accessKeyId 和 secretAccessKey 需要同时配置")
+	}
+	if s.Timeout < 0 || s.PollInterval < 0 {
+		return fmt.Errorf("超时和检查间隔不能为负数")
+	}
+	return nil
+}
+
+// WithDefaults 返回填充了默认值的配置，区域、地址和凭证的默认值由日志源从环境变量读取
+func (s S3Config) WithDefaults() S3Config {
+	if s.Timeout == 0 {
+		s.Timeout = 30 * time.Second
+	}
+	if s.PollInterval == 0 {
+		s.PollInterval = 30 * time.Second
+	}
+	return s
+}
+
+// Validate 验证日志关联配置
+func (c CorrelationConfig) Validate() error {
+	if c.MaxResults < 0 || c.MaxFiles < 0 {
+		return fmt.Errorf("最大条目数和最大文件数不能为负数")
+	}
+	for _, fields := range [][]string{c.IDFields, c.SpanIDFields, c.ParentIDFields} {
+		for _, field := range fields {
+			if strings.TrimSpace(field) == "" {
+				return fmt.Errorf("字段名不能为空")
 			}
 		}
 	}
+	return nil
+}
 
+// WithDefaults 返回填充了默认值的配置
+func (c CorrelationConfig) WithDefaults() CorrelationConfig {
+	if len(c.IDFields) == 0 {
+		c.IDFields = []string{"trace_id", "request_id", "traceId", "requestId"}
+	}
+	if len(c.SpanIDFields) == 0 {
+		c.SpanIDFields = []string{"span_id", "spanId"}
+	}
+	if len(c.ParentIDFields) == 0 {
+		c.ParentIDFields = []string{"parent_span_id", "parent_id", "parentSpanId"}
+	}
+	if c.MaxResults == 0 {
+		c.MaxResults = 1000
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = 200
+	}
+	return c
+}
+
+// Validate 验证日志指标配置
+func (l LogMetricsConfig) Validate() error {
+	if l.Retention < 0 || l.Resolution < 0 || l.MaxSeries < 0 {
+		return fmt.Errorf("保留时长、分辨率和分组数不能为负数")
+	}
+	defaults := l.WithDefaults()
+	if defaults.Resolution > defaults.Retention {
+		return fmt.Errorf("分辨率 %s 不能大于保留时长 %s", defaults.Resolution, defaults.Retention)
+	}
+	if defaults.Retention/defaults.Resolution > maxLogMetricSlots {
+		return fmt.Errorf("保留时长 %s 按分辨率 %s 超过 %d 个时间点", defaults.Retention, defaults.Resolution, maxLogMetricSlots)
+	}
+
+	names := make(map[string]bool)
+	for i, r := range l.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("第%d个日志指标缺少名称", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("日志指标名称重复: %s", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Path == "" {
+			return fmt.Errorf("日志指标 %s 缺少文件路径", r.Name)
+		}
+		switch r.Type {
+		case LogMetricCounter:
+		case LogMetricGauge:
+			if r.Value == "" {
+				return fmt.Errorf("日志指标 %s 为 gauge 时必须设置数值字段 value", r.Name)
+			}
+		default:
+			return fmt.Errorf("日志指标 %s 的类型无效: %s，支持 %s、%s", r.Name, r.Type, LogMetricCounter, LogMetricGauge)
+		}
+		if r.IsRegex {
+			if _, err := regexp.Compile(r.Query); err != nil {
+				return fmt.Errorf("日志指标 %s 的正则表达式无效: %w", r.Name, err)
+			}
+		}
+		for _, f := range r.Fields {
+			if f.Field == "" || f.Op == "" {
+				return fmt.Errorf("日志指标 %s 的字段条件缺少字段名或比较方式", r.Name)
+			}
+		}
+	}
 	return nil
 }
 
+// maxLogMetricSlots 每个时间序列最多保留的时间点数
+const maxLogMetricSlots = 100000
+
+// WithDefaults 返回填充了默认值的配置
+func (l LogMetricsConfig) WithDefaults() LogMetricsConfig {
+	if l.Retention == 0 {
+		l.Retention = 6 * time.Hour
+	}
+	if l.Resolution == 0 {
+		l.Resolution = 10 * time.Second
+	}
+	if l.MaxBackfillBytes == 0 {
+		l.MaxBackfillBytes = 64 * 1024 * 1024
+	}
+	if l.MaxSeries == 0 {
+		l.MaxSeries = 100
+	}
+	return l
+}
+
+// LoadParserConfigs 从配置文件中只加载自定义解析器配置（用于热加载）
+func LoadParserConfigs(configPath string) ([]ParserConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var partial struct {
+		Parsers []ParserConfig `yaml:"parsers"`
+	}
+	if err := yaml.Unmarshal(data, &partial); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	if err := ValidateParserConfigs(partial.Parsers); err != nil {
+		return nil, err
+	}
+
+	return partial.Parsers, nil
+}
+
 // Save 保存配置到文件
 func (c *Config) Save(configPath string) error {
 	data, err := yaml.Marshal(c)
@@ -38,6 +38,9 @@ const (
 	ErrorTypeSearchTimeout ErrorType = "SEARCH_TIMEOUT"
 	ErrorTypeInvalidQuery  ErrorType = "INVALID_QUERY"
 
+	// 资源错误
+	ErrorTypeNotFound ErrorType = "NOT_FOUND"
+
 	// 系统错误
 	ErrorTypeInternalError      ErrorType = "INTERNAL_ERROR"
 	ErrorTypeServiceUnavailable ErrorType = "SERVICE_UNAVAILABLE"
@@ -74,7 +77,7 @@ func (e *AppError) ToHTTPStatus() int {
 
 	// 根据错误类型返回默认状态码
 	switch e.Type {
-	case ErrorTypeFileNotFound:
+	case ErrorTypeFileNotFound, ErrorTypeNotFound:
 		return http.StatusNotFound
 	case ErrorTypeFilePermission, ErrorTypeAccessDenied:
 		return http.StatusForbidden
@@ -131,6 +134,8 @@ func (e *AppError) GetUserMessage() string {
 		return "用户名或密码错误"
 	case ErrorTypeAccessDenied:
 		return "访问被拒绝"
+	case ErrorTypeNotFound:
+		return "请求的资源不存在"
 	case ErrorTypeSearchTimeout:
 		return "搜索超时，请简化搜索条件"
 	case ErrorTypeInvalidQuery:
@@ -77,6 +77,7 @@ func (h *HealthService) CheckAll(ctx context.Context) map[string]CheckResult {
 	h.mu.RUnlock()
 
 	results := make(map[string]CheckResult)
+	var resultsMu sync.Mutex
 	var wg sync.WaitGroup
 
 	for name, check := range checks {
@@ -93,7 +94,9 @@ func (h *HealthService) CheckAll(ctx context.Context) map[string]CheckResult {
 			result.Duration = time.Since(start)
 			result.Timestamp = time.Now()
 
+			resultsMu.Lock()
 			results[name] = result
+			resultsMu.Unlock()
 		}(name, check)
 	}
 
@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/local-log-viewer/internal/types"
 )
 
@@ -12,14 +14,14 @@ type LogManager interface {
 	// GetDirectoryFiles 获取指定目录的直接子节点(用于懒加载)
 	GetDirectoryFiles(dirPath string) ([]types.LogFile, error)
 
-	// ReadLogFile 读取日志文件内容
-	ReadLogFile(path string, offset int64, limit int) (*types.LogContent, error)
+	// ReadLogFile 读取日志文件内容，ctx 用于链路追踪
+	ReadLogFile(ctx context.Context, path string, offset int64, limit int) (*types.LogContent, error)
 
 	// ReadLogFileFromTail 从文件尾部读取日志内容
-	ReadLogFileFromTail(path string, lines int) (*types.LogContent, error)
+	ReadLogFileFromTail(ctx context.Context, path string, lines int) (*types.LogContent, error)
 
 	// SearchLogs 搜索日志内容
-	SearchLogs(query types.SearchQuery) (*types.SearchResult, error)
+	SearchLogs(ctx context.Context, query types.SearchQuery) (*types.SearchResult, error)
 
 	// WatchFile 监控文件变化
 	WatchFile(path string) (<-chan types.LogUpdate, error)
@@ -34,6 +36,93 @@ type LogManager interface {
 	Stop() error
 }
 
+// FileFormatSetter 支持手动指定文件日志格式的日志管理器
+type FileFormatSetter interface {
+	// SetFileFormat 指定文件的日志格式，format 为 "auto" 时恢复自动检测
+	SetFileFormat(path, format string) error
+}
+
+// FormatReader 支持按请求指定解析格式的日志管理器
+type FormatReader interface {
+	// WithFormat 返回只对本次请求生效的上下文，用它调用 ReadLogFile、ReadLogFileFromTail 时按 format 解析，
+	// 不修改文件保存的格式；format 为空或 "auto" 时不改变，未知的格式返回错误
+	WithFormat(ctx context.Context, format string) (context.Context, error)
+}
+
+// FacetProvider 支持字段聚合统计的日志管理器
+type FacetProvider interface {
+	// GetFacets 统计文件中字段的取值分布
+	GetFacets(ctx context.Context, query types.FacetQuery) (*types.FacetResult, error)
+}
+
+// HistogramProvider 支持日志量直方图的日志管理器
+type HistogramProvider interface {
+	// GetHistogram 按时间分桶统计文件中的日志条目数量
+	GetHistogram(ctx context.Context, query types.HistogramQuery) (*types.HistogramResult, error)
+}
+
+// CorrelationProvider 支持跨文件关联日志的日志管理器
+type CorrelationProvider interface {
+	// Correlate 查找所有日志路径中带有同一 trace ID 或 request ID 的条目
+	Correlate(ctx context.Context, query types.CorrelateQuery) (*types.CorrelateResult, error)
+}
+
+// AlertProvider 支持告警规则的日志管理器
+type AlertProvider interface {
+	// GetAlerts 返回所有告警规则的当前状态
+	GetAlerts() []types.AlertStatus
+
+	// OnAlert 注册告警状态变化的监听函数
+	OnAlert(listener func(types.AlertEvent))
+}
+
+// LogMetricProvider 支持从日志中提取指标的日志管理器
+type LogMetricProvider interface {
+	// GetLogMetrics 返回所有日志指标的定义和当前状态
+	GetLogMetrics() []types.LogMetricInfo
+
+	// QueryLogMetric 按时间范围查询日志指标
+	QueryLogMetric(query types.LogMetricQuery) (*types.LogMetricResult, error)
+}
+
+// AnnotationProvider 支持日志行标注的日志管理器
+type AnnotationProvider interface {
+	// GetAnnotations 返回文件当前内容上的标注
+	GetAnnotations(path string) ([]types.Annotation, error)
+
+	// CreateAnnotation 在指定字节位置的行上创建标注
+	CreateAnnotation(user string, annotation types.Annotation) (*types.Annotation, error)
+
+	// UpdateAnnotation 修改标注，只有作者可以修改
+	UpdateAnnotation(id, user string, annotation types.Annotation) (*types.Annotation, error)
+
+	// DeleteAnnotation 删除标注并返回被删除的标注，只有作者可以删除
+	DeleteAnnotation(id, user string) (*types.Annotation, error)
+}
+
+// RangeReader 支持按字节范围重新读取日志的日志管理器，用于补齐实时推送中缺失的内容
+type RangeReader interface {
+	// ReadRange 读取 [offset, 实时推送位置) 范围内的完整行，最多读取 maxBytes 字节
+	// 返回的更新 EndOffset 小于 liveOffset 时说明还有未读取的内容
+	ReadRange(path string, offset, maxBytes int64) (update *types.LogUpdate, liveOffset int64, err error)
+
+	// OffsetForSeq 返回指定序号的更新结束的字节位置，序号太旧或不存在时返回 false
+	OffsetForSeq(path string, seq uint64) (int64, bool)
+}
+
+// WatchBroker 支持多个订阅者共享文件监控的日志管理器
+// 每个订阅者有独立的更新通道，最后一个订阅者释放后停止监控文件
+type WatchBroker interface {
+	// WatchFileAs 以指定名称订阅文件更新，名称用于统计
+	WatchFileAs(path, subscriber string) (<-chan types.LogUpdate, error)
+
+	// UnwatchFile 释放 WatchFile 或 WatchFileAs 返回的更新通道
+	UnwatchFile(path string, updates <-chan types.LogUpdate) error
+
+	// GetWatchStats 返回正在监控的文件及每个订阅者的统计
+	GetWatchStats() []types.WatchStats
+}
+
 // FileWatcher 文件监控器接口
 type FileWatcher interface {
 	// WatchFile 监控文件
@@ -61,6 +150,13 @@ type LogParser interface {
 	GetFormat() string
 }
 
+// ParserFingerprinter 规则可以修改的解析器（例如自定义解析器），规则变化时指纹随之变化，
+// 用于让按解析结果缓存的数据（如文件摘要）失效
+type ParserFingerprinter interface {
+	// Fingerprint 返回解析规则的哈希
+	Fingerprint() string
+}
+
 // WebSocketHub WebSocket中心接口
 type WebSocketHub interface {
 	// Run 运行WebSocket中心
@@ -69,6 +165,12 @@ type WebSocketHub interface {
 	// BroadcastLogUpdate 广播日志更新
 	BroadcastLogUpdate(update types.LogUpdate)
 
+	// Broadcast 向所有客户端广播消息
+	Broadcast(message types.WSMessage)
+
+	// BroadcastToSubscribers 向订阅了指定文件的客户端广播消息
+	BroadcastToSubscribers(path string, message types.WSMessage)
+
 	// RegisterClient 注册客户端
 	RegisterClient(client WebSocketClient)
 
@@ -92,6 +194,9 @@ type WebSocketClient interface {
 
 	// GetID 获取客户端ID
 	GetID() string
+
+	// IsSubscribed 是否订阅了指定文件
+	IsSubscribed(path string) bool
 }
 
 // SearchEngine 搜索引擎接口
@@ -0,0 +1,426 @@
+package logmetric
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// watchRetryInterval 监控文件失败（例如文件还不存在）后的重试间隔
+	watchRetryInterval = 30 * time.Second
+	// backfillChunkBytes 回填时每次读取的字节数
+	backfillChunkBytes = 4 * 1024 * 1024
+	// maxQueryPoints 单次查询每条序列最多的数据点数
+	maxQueryPoints = 11000
+	// defaultQueryRange 未指定开始时间时查询的时长
+	defaultQueryRange = time.Hour
+	// defaultQueryPoints 未指定步长时每条序列的目标数据点数
+	defaultQueryPoints = 120
+)
+
+var (
+	// ErrNotFound 指标不存在
+	ErrNotFound = errors.New("log metric not found")
+	// ErrInvalid 查询参数无效
+	ErrInvalid = errors.New("invalid log metric query")
+)
+
+// Source 提供文件的实时日志更新流
+type Source interface {
+	WatchFile(path string) (<-chan types.LogUpdate, error)
+}
+
+// RangeSource 支持按字节范围读取文件已有内容的 Source，用于启动时回填
+type RangeSource interface {
+	ReadRange(path string, offset, maxBytes int64) (*types.LogUpdate, int64, error)
+}
+
+// Engine 在实时日志流和文件已有内容上计算日志指标
+type Engine struct {
+	source Source
+	config config.LogMetricsConfig
+	rules  []*rule
+	store  *Store
+
+	// 已成功监控的文件、已完成回填的文件，以及上次尝试监控的时间
+	watched          map[string]bool
+	backfilled       map[string]bool
+	lastWatchAttempt time.Time
+
+	now     func() time.Time
+	running bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// rule 单个日志指标
+type rule struct {
+	config  config.LogMetricRuleConfig
+	matcher func(entry *types.LogEntry) bool
+	// 分组数超过上限后只记录一次警告
+	seriesLimitWarned bool
+}
+
+// NewEngine 创建日志指标引擎
+func NewEngine(cfg config.LogMetricsConfig, source Source) (*Engine, error) {
+	cfg = cfg.WithDefaults()
+	e := &Engine{
+		source:     source,
+		config:     cfg,
+		store:      NewStore(cfg.Retention, cfg.Resolution, cfg.MaxSeries),
+		watched:    make(map[string]bool),
+		backfilled: make(map[string]bool),
+		now:        time.Now,
+	}
+
+	for _, ruleCfg := range cfg.Rules {
+		matcher, err := search.NewStreamMatcher(types.StreamFilter{
+			Query:   ruleCfg.Query,
+			IsRegex: ruleCfg.IsRegex,
+			Levels:  ruleCfg.Levels,
+			Fields:  ruleCfg.Fields,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("日志指标 %s 无效: %w", ruleCfg.Name, err)
+		}
+		e.rules = append(e.rules, &rule{config: ruleCfg, matcher: matcher})
+	}
+
+	return e, nil
+}
+
+// Start 开始监控指标涉及的文件
+func (e *Engine) Start() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.running {
+		return fmt.Errorf("日志指标引擎已经在运行")
+	}
+	e.running = true
+	e.stopCh = make(chan struct{})
+
+	if len(e.rules) == 0 {
+		return nil
+	}
+
+	e.watchLocked(e.now())
+
+	e.wg.Add(1)
+	go e.loop()
+
+	logger.Info("log metric engine started", zap.Int("metrics", len(e.rules)))
+	return nil
+}
+
+// Stop 停止日志指标引擎，已有的数据保留在内存中
+func (e *Engine) Stop() error {
+	e.mutex.Lock()
+	if !e.running {
+		e.mutex.Unlock()
+		return nil
+	}
+	e.running = false
+	close(e.stopCh)
+	e.mutex.Unlock()
+
+	e.wg.Wait()
+	return nil
+}
+
+// Metrics 返回所有日志指标的定义和当前状态
+func (e *Engine) Metrics() []types.LogMetricInfo {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	infos := make([]types.LogMetricInfo, 0, len(e.rules))
+	for _, r := range e.rules {
+		infos = append(infos, types.LogMetricInfo{
+			Name:       r.config.Name,
+			Type:       r.config.Type,
+			Path:       r.config.Path,
+			Value:      r.config.Value,
+			GroupBy:    r.config.GroupBy,
+			Groups:     e.store.Groups(r.config.Name),
+			Retention:  e.config.Retention.String(),
+			Resolution: e.config.Resolution.String(),
+			Backfilled: e.backfilled[r.config.Path],
+		})
+	}
+	return infos
+}
+
+// Query 查询日志指标，未指定的时间范围和步长使用默认值
+func (e *Engine) Query(query types.LogMetricQuery) (*types.LogMetricResult, error) {
+	if !e.hasMetric(query.Name) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, query.Name)
+	}
+
+	switch query.Func {
+	case "":
+		query.Func = types.LogMetricRate
+	case types.LogMetricRate, types.LogMetricSum, types.LogMetricCount,
+		types.LogMetricAvg, types.LogMetricMin, types.LogMetricMax:
+	case types.LogMetricQuantile:
+		if query.Quantile <= 0 || query.Quantile > 1 {
+			return nil, fmt.Errorf("%w: quantile must be in (0, 1]", ErrInvalid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown function %s", ErrInvalid, query.Func)
+	}
+
+	if query.End.IsZero() {
+		query.End = e.now()
+	}
+	if query.Start.IsZero() {
+		query.Start = query.End.Add(-defaultQueryRange)
+	}
+	if !query.Start.Before(query.End) {
+		return nil, fmt.Errorf("%w: start must be before end", ErrInvalid)
+	}
+
+	// 步长向上取整为分辨率的整数倍
+	resolution := e.config.Resolution
+	if query.Step <= 0 {
+		query.Step = query.End.Sub(query.Start) / defaultQueryPoints
+	}
+	if query.Step < resolution {
+		query.Step = resolution
+	}
+	query.Step = (query.Step + resolution - 1) / resolution * resolution
+	if query.End.Sub(query.Start)/query.Step > maxQueryPoints {
+		return nil, fmt.Errorf("%w: too many points, increase step", ErrInvalid)
+	}
+
+	return &types.LogMetricResult{
+		Name:   query.Name,
+		Func:   query.Func,
+		Step:   query.Step.String(),
+		Series: e.store.Query(query.Name, query),
+	}, nil
+}
+
+// hasMetric 指标是否已定义
+func (e *Engine) hasMetric(name string) bool {
+	for _, r := range e.rules {
+		if r.config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loop 定期重试监控失败的文件
+func (e *Engine) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(watchRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.mutex.Lock()
+			e.watchLocked(e.now())
+			e.mutex.Unlock()
+
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// watchLocked 监控指标涉及的、尚未监控的文件（调用方持有锁）
+func (e *Engine) watchLocked(now time.Time) {
+	e.lastWatchAttempt = now
+
+	for _, r := range e.rules {
+		path := r.config.Path
+		if e.watched[path] {
+			continue
+		}
+
+		updates, err := e.source.WatchFile(path)
+		if err != nil {
+			logger.Warn("failed to watch file for log metric",
+				zap.String("metric", r.config.Name),
+				zap.String("path", path),
+				zap.Error(err))
+			continue
+		}
+
+		e.watched[path] = true
+		e.wg.Add(1)
+		go e.consume(path, updates, !e.backfilled[path])
+	}
+}
+
+// consume 先回填文件已有的内容，再读取文件的更新流
+// 回填到实时推送的位置为止，之前的实时更新已包含在回填中，跳过以免重复计数
+func (e *Engine) consume(path string, updates <-chan types.LogUpdate, backfill bool) {
+	defer e.wg.Done()
+
+	backfillEnd := int64(-1)
+	if backfill {
+		backfillEnd = e.backfill(path)
+	}
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				// 更新流被关闭，稍后重新监控
+				e.mutex.Lock()
+				delete(e.watched, path)
+				e.mutex.Unlock()
+				return
+			}
+			if update.Type == "gap" || update.Type == "resync" {
+				continue
+			}
+			if update.EndOffset > 0 && update.EndOffset <= backfillEnd {
+				continue
+			}
+			e.observe(path, update.Entries, e.now(), false)
+
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// backfill 读取文件末尾最多 MaxBackfillBytes 字节已有的内容，返回回填结束的位置，未回填时返回 -1
+func (e *Engine) backfill(path string) int64 {
+	reader, ok := e.source.(RangeSource)
+	if !ok || e.config.MaxBackfillBytes < 0 {
+		return -1
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		logger.Warn("failed to backfill log metrics", zap.String("path", path), zap.Error(err))
+		return -1
+	}
+	offset := info.Size() - e.config.MaxBackfillBytes
+	if offset < 0 {
+		offset = 0
+	}
+
+	// 第一次读取时确定回填的结束位置
+	end := int64(-1)
+	entries := 0
+	for end < 0 || offset < end {
+		select {
+		case <-e.stopCh:
+			return end
+		default:
+		}
+
+		maxBytes := int64(backfillChunkBytes)
+		if end >= 0 && end-offset < maxBytes {
+			maxBytes = end - offset
+		}
+		update, liveOffset, err := reader.ReadRange(path, offset, maxBytes)
+		if err != nil {
+			logger.Warn("failed to backfill log metrics", zap.String("path", path), zap.Error(err))
+			break
+		}
+		if end < 0 {
+			end = liveOffset
+		}
+
+		e.observe(path, update.Entries, e.now(), true)
+		entries += len(update.Entries)
+		if update.EndOffset <= offset {
+			break
+		}
+		offset = update.EndOffset
+	}
+
+	e.mutex.Lock()
+	e.backfilled[path] = true
+	e.mutex.Unlock()
+
+	logger.Info("log metrics backfilled",
+		zap.String("path", path),
+		zap.Int("entries", entries),
+		zap.Int64("end_offset", end))
+	return end
+}
+
+// observe 将日志条目计入相关指标
+// 条目有时间戳时按时间戳计入，否则按到达时间计入；回填时没有时间戳的条目无法确定时间，不计入
+func (e *Engine) observe(path string, entries []types.LogEntry, now time.Time, backfill bool) {
+	for _, r := range e.rules {
+		if r.config.Path != path {
+			continue
+		}
+		for i := range entries {
+			entry := &entries[i]
+			if !r.matcher(entry) {
+				continue
+			}
+
+			t := entry.Timestamp
+			if t.IsZero() {
+				if backfill {
+					continue
+				}
+				t = now
+			}
+
+			value, ok := r.value(entry)
+			if !ok {
+				continue
+			}
+
+			if !e.store.Add(r.config.Name, r.group(entry), t, value) {
+				r.warnSeriesLimit(e.store, e.config.MaxSeries)
+			}
+		}
+	}
+}
+
+// value 返回条目的数值，未设置数值字段时每条计 1，字段不存在或不是数值时不计入
+func (r *rule) value(entry *types.LogEntry) (float64, bool) {
+	if r.config.Value == "" {
+		return 1, true
+	}
+	raw, ok := search.FieldValue(entry, r.config.Value)
+	if !ok {
+		return 0, false
+	}
+	return search.NumericValue(raw)
+}
+
+// group 返回条目所属的分组，未设置分组字段或字段不存在时为空
+func (r *rule) group(entry *types.LogEntry) string {
+	if r.config.GroupBy == "" {
+		return ""
+	}
+	if value, ok := search.FieldValue(entry, r.config.GroupBy); ok {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// warnSeriesLimit 分组数达到上限时记录一次警告，超出时间范围的观测值不警告
+func (r *rule) warnSeriesLimit(store *Store, maxSeries int) {
+	if r.seriesLimitWarned || len(store.Groups(r.config.Name)) < maxSeries {
+		return
+	}
+	r.seriesLimitWarned = true
+	logger.Warn("log metric series limit reached, new groups are dropped",
+		zap.String("metric", r.config.Name),
+		zap.Int("max_series", maxSeries))
+}
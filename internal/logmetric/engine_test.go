@@ -0,0 +1,226 @@
+package logmetric
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// fakeSource 测试用的日志更新源，ReadRange 返回预先设置的已有内容
+type fakeSource struct {
+	mutex    sync.Mutex
+	streams  map[string]chan types.LogUpdate
+	existing types.LogUpdate
+	reads    int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{streams: make(map[string]chan types.LogUpdate)}
+}
+
+func (s *fakeSource) WatchFile(path string) (<-chan types.LogUpdate, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ch := make(chan types.LogUpdate, 10)
+	s.streams[path] = ch
+	return ch, nil
+}
+
+func (s *fakeSource) ReadRange(path string, offset, maxBytes int64) (*types.LogUpdate, int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.reads++
+	update := s.existing
+	update.StartOffset = offset
+	return &update, s.existing.EndOffset, nil
+}
+
+func (s *fakeSource) stream(path string) chan types.LogUpdate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.streams[path]
+}
+
+func request(t time.Time, method string, duration float64) types.LogEntry {
+	return types.LogEntry{
+		Timestamp: t,
+		Level:     "INFO",
+		Message:   "request completed",
+		Fields:    map[string]interface{}{"method": method, "duration_ms": duration},
+	}
+}
+
+func newTestEngine(t *testing.T, source Source, rules ...config.LogMetricRuleConfig) (*Engine, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "api.log")
+	if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := range rules {
+		rules[i].Path = path
+	}
+
+	engine, err := NewEngine(config.LogMetricsConfig{
+		Retention:  time.Hour,
+		Resolution: 10 * time.Second,
+		Rules:      rules,
+	}, source)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	engine.now = func() time.Time { return storeNow }
+	engine.store.now = engine.now
+	return engine, path
+}
+
+func latencyRule() config.LogMetricRuleConfig {
+	return config.LogMetricRuleConfig{
+		Name:    "latency",
+		Type:    config.LogMetricGauge,
+		Query:   "request completed",
+		Value:   "duration_ms",
+		GroupBy: "method",
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngine_BackfillAndLive(t *testing.T) {
+	source := newFakeSource()
+	source.existing = types.LogUpdate{
+		Entries: []types.LogEntry{
+			request(storeNow.Add(-5*time.Minute), "GET", 100),
+			request(storeNow.Add(-5*time.Minute), "GET", 300),
+			// 没有时间戳的已有条目无法确定时间，不计入
+			request(time.Time{}, "GET", 1000),
+		},
+		EndOffset: 100,
+	}
+
+	engine, path := newTestEngine(t, source, latencyRule())
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer engine.Stop()
+
+	waitFor(t, func() bool { return engine.Metrics()[0].Backfilled })
+	if source.reads != 1 {
+		t.Errorf("expected 1 backfill read, got %d", source.reads)
+	}
+
+	// 已包含在回填中的更新被跳过
+	source.stream(path) <- types.LogUpdate{
+		Type:      "append",
+		Entries:   []types.LogEntry{request(storeNow.Add(-5*time.Minute), "GET", 300)},
+		EndOffset: 100,
+	}
+	source.stream(path) <- types.LogUpdate{
+		Type: "append",
+		Entries: []types.LogEntry{
+			request(time.Time{}, "POST", 50),
+			{Timestamp: storeNow, Level: "INFO", Message: "health check"},
+		},
+		EndOffset: 150,
+	}
+	waitFor(t, func() bool { return len(engine.Metrics()[0].Groups) == 2 })
+
+	result, err := engine.Query(types.LogMetricQuery{
+		Name:  "latency",
+		Func:  types.LogMetricAvg,
+		Start: storeNow.Add(-10 * time.Minute),
+		End:   storeNow.Add(10 * time.Second),
+		Step:  10*time.Minute + 10*time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Series) != 2 {
+		t.Fatalf("expected 2 series, got %+v", result.Series)
+	}
+	if got := result.Series[0]; got.Group != "GET" || got.Points[0].Value != 200 {
+		t.Errorf("unexpected GET series: %+v", got)
+	}
+	if got := result.Series[1]; got.Group != "POST" || got.Points[0].Value != 50 {
+		t.Errorf("unexpected POST series: %+v", got)
+	}
+}
+
+func TestEngine_QueryDefaults(t *testing.T) {
+	engine, _ := newTestEngine(t, newFakeSource(), config.LogMetricRuleConfig{
+		Name:   "errors",
+		Type:   config.LogMetricCounter,
+		Levels: []string{"ERROR"},
+	})
+	engine.observe(engine.rules[0].config.Path, []types.LogEntry{
+		{Timestamp: storeNow.Add(-time.Minute), Level: "ERROR"},
+		{Timestamp: storeNow.Add(-time.Minute), Level: "ERROR"},
+		{Timestamp: storeNow.Add(-time.Minute), Level: "INFO"},
+	}, storeNow, false)
+
+	result, err := engine.Query(types.LogMetricQuery{Name: "errors"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Func != types.LogMetricRate || result.Step != "30s" {
+		t.Errorf("unexpected defaults: func=%s step=%s", result.Func, result.Step)
+	}
+	points := result.Series[0].Points
+	if len(points) != 120 {
+		t.Fatalf("expected 120 points, got %d", len(points))
+	}
+	var total float64
+	for _, p := range points {
+		total += p.Value * 30
+	}
+	if total != 2 {
+		t.Errorf("expected 2 errors in total, got %v", total)
+	}
+}
+
+func TestEngine_QueryErrors(t *testing.T) {
+	engine, _ := newTestEngine(t, newFakeSource(), latencyRule())
+
+	tests := []struct {
+		name  string
+		query types.LogMetricQuery
+		want  error
+	}{
+		{"unknown metric", types.LogMetricQuery{Name: "missing"}, ErrNotFound},
+		{"unknown func", types.LogMetricQuery{Name: "latency", Func: "median"}, ErrInvalid},
+		{"bad quantile", types.LogMetricQuery{Name: "latency", Func: types.LogMetricQuantile, Quantile: 1.5}, ErrInvalid},
+		{"reversed range", types.LogMetricQuery{Name: "latency", Start: storeNow, End: storeNow.Add(-time.Hour)}, ErrInvalid},
+		{"too many points", types.LogMetricQuery{Name: "latency", Start: storeNow.Add(-48 * time.Hour), Step: 10 * time.Second}, ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := engine.Query(tt.query); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestNewEngine_InvalidRule(t *testing.T) {
+	_, err := NewEngine(config.LogMetricsConfig{Rules: []config.LogMetricRuleConfig{
+		{Name: "bad", Path: "/logs/api.log", Query: "(", IsRegex: true},
+	}}, newFakeSource())
+	if err == nil {
+		t.Fatal("expected invalid regex to be rejected")
+	}
+}
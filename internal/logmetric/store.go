@@ -0,0 +1,243 @@
+package logmetric
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// maxSlotSamples 每个时间点保留的最大样本数，超过后使用蓄水池抽样，用于计算分位数
+const maxSlotSamples = 128
+
+// slot 一个分辨率时间段内的聚合值
+type slot struct {
+	start   int64 // 时间段开始（UnixNano），0 表示空
+	count   int64
+	sum     float64
+	min     float64
+	max     float64
+	samples []float64
+}
+
+// add 记录一个观测值
+func (s *slot) add(value float64, rng *rand.Rand) {
+	s.count++
+	s.sum += value
+	if s.count == 1 || value < s.min {
+		s.min = value
+	}
+	if s.count == 1 || value > s.max {
+		s.max = value
+	}
+
+	if len(s.samples) < maxSlotSamples {
+		s.samples = append(s.samples, value)
+	} else if i := rng.Int63n(s.count); i < maxSlotSamples {
+		s.samples[i] = value
+	}
+}
+
+// series 单个分组的环形时间序列，时间段按开始时间映射到固定位置，旧数据被新数据覆盖
+type series struct {
+	slots []slot
+}
+
+// Store 内存中的环形时间序列存储，每个指标的每个分组一条序列
+type Store struct {
+	retention  time.Duration
+	resolution time.Duration
+	maxSeries  int
+
+	mutex  sync.RWMutex
+	series map[string]map[string]*series // 指标名 -> 分组 -> 序列
+	rng    *rand.Rand
+	now    func() time.Time
+}
+
+// NewStore 创建时间序列存储，每条序列占用 retention/resolution 个时间点
+func NewStore(retention, resolution time.Duration, maxSeries int) *Store {
+	return &Store{
+		retention:  retention,
+		resolution: resolution,
+		maxSeries:  maxSeries,
+		series:     make(map[string]map[string]*series),
+		// 固定种子，保证相同内容的统计结果一致
+		rng: rand.New(rand.NewSource(1)),
+		now: time.Now,
+	}
+}
+
+// Add 记录指标在 t 时刻的观测值，超出保留时长、晚于当前时间或分组数超过上限时返回 false
+func (s *Store) Add(metric, group string, t time.Time, value float64) bool {
+	now := s.now()
+	if t.Before(now.Add(-s.retention)) || t.After(now.Add(s.resolution)) {
+		return false
+	}
+
+	res := int64(s.resolution)
+	start := t.UnixNano() / res * res
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	groups, exists := s.series[metric]
+	if !exists {
+		groups = make(map[string]*series)
+		s.series[metric] = groups
+	}
+	ser, exists := groups[group]
+	if !exists {
+		if len(groups) >= s.maxSeries {
+			return false
+		}
+		ser = &series{slots: make([]slot, s.retention/s.resolution)}
+		groups[group] = ser
+	}
+
+	sl := &ser.slots[(start/res)%int64(len(ser.slots))]
+	switch {
+	case sl.start == start:
+	case sl.start < start:
+		// 时间段已过期，重用该位置
+		*sl = slot{start: start, samples: sl.samples[:0]}
+	default:
+		// 该位置已被更新的时间段占用
+		return false
+	}
+	sl.add(value, s.rng)
+	return true
+}
+
+// Groups 返回指标已有数据的分组
+func (s *Store) Groups(metric string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	groups := make([]string, 0, len(s.series[metric]))
+	for group := range s.series[metric] {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Query 按步长聚合 [start, end) 内的数据，start 按分辨率对齐，step 为分辨率的整数倍
+func (s *Store) Query(metric string, query types.LogMetricQuery) []types.LogMetricSeries {
+	res := int64(s.resolution)
+	step := int64(query.Step)
+	from := query.Start.UnixNano() / res * res
+	to := query.End.UnixNano()
+	oldest := s.now().Add(-s.retention).UnixNano()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	groups := make([]string, 0, len(s.series[metric]))
+	for group := range s.series[metric] {
+		if query.Group == "" || group == query.Group {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+
+	result := make([]types.LogMetricSeries, 0, len(groups))
+	for _, group := range groups {
+		ser := s.series[metric][group]
+		points := []types.LogMetricPoint{}
+		for window := from; window < to; window += step {
+			var slots []*slot
+			for start := window; start < window+step && start < to; start += res {
+				if start < oldest-res {
+					continue
+				}
+				sl := &ser.slots[(start/res)%int64(len(ser.slots))]
+				if sl.start == start {
+					slots = append(slots, sl)
+				}
+			}
+			if value, ok := aggregate(slots, query, time.Duration(step)); ok {
+				points = append(points, types.LogMetricPoint{Time: time.Unix(0, window).UTC(), Value: value})
+			}
+		}
+		result = append(result, types.LogMetricSeries{Group: group, Points: points})
+	}
+	return result
+}
+
+// aggregate 对一个步长内的时间段求值，没有数据时 rate、sum、count 为 0，其他函数不产生数据点
+func aggregate(slots []*slot, query types.LogMetricQuery, step time.Duration) (float64, bool) {
+	var count int64
+	var sum float64
+	for _, sl := range slots {
+		count += sl.count
+		sum += sl.sum
+	}
+
+	switch query.Func {
+	case types.LogMetricRate:
+		return sum / step.Seconds(), true
+	case types.LogMetricSum:
+		return sum, true
+	case types.LogMetricCount:
+		return float64(count), true
+	}
+	if count == 0 {
+		return 0, false
+	}
+
+	switch query.Func {
+	case types.LogMetricAvg:
+		return sum / float64(count), true
+	case types.LogMetricMin, types.LogMetricMax:
+		value := slots[0].min
+		if query.Func == types.LogMetricMax {
+			value = slots[0].max
+		}
+		for _, sl := range slots[1:] {
+			if query.Func == types.LogMetricMin && sl.min < value {
+				value = sl.min
+			}
+			if query.Func == types.LogMetricMax && sl.max > value {
+				value = sl.max
+			}
+		}
+		return value, true
+	case types.LogMetricQuantile:
+		return weightedQuantile(slots, query.Quantile), true
+	}
+	return 0, false
+}
+
+// weightedQuantile 合并多个时间段的样本计算分位数，每个样本按所在时间段的观测数加权
+func weightedQuantile(slots []*slot, q float64) float64 {
+	type sample struct {
+		value  float64
+		weight float64
+	}
+	var samples []sample
+	var total float64
+	for _, sl := range slots {
+		if len(sl.samples) == 0 {
+			continue
+		}
+		weight := float64(sl.count) / float64(len(sl.samples))
+		for _, value := range sl.samples {
+			samples = append(samples, sample{value, weight})
+		}
+		total += float64(sl.count)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	// 最近秩法，与字段聚合的分位数一致
+	var cumulative float64
+	for _, s := range samples {
+		cumulative += s.weight
+		if cumulative >= q*total {
+			return s.value
+		}
+	}
+	return samples[len(samples)-1].value
+}
@@ -0,0 +1,139 @@
+package logmetric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+var storeNow = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+
+func newTestStore(maxSeries int) *Store {
+	store := NewStore(10*time.Minute, 10*time.Second, maxSeries)
+	store.now = func() time.Time { return storeNow }
+	return store
+}
+
+func query(fn string, start time.Time, step time.Duration) types.LogMetricQuery {
+	return types.LogMetricQuery{
+		Name:  "latency",
+		Func:  fn,
+		Start: start,
+		End:   start.Add(2 * step),
+		Step:  step,
+	}
+}
+
+func pointValues(t *testing.T, series []types.LogMetricSeries) []float64 {
+	t.Helper()
+	if len(series) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(series))
+	}
+	values := make([]float64, 0, len(series[0].Points))
+	for _, p := range series[0].Points {
+		values = append(values, p.Value)
+	}
+	return values
+}
+
+func equalValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStore_Aggregations(t *testing.T) {
+	store := newTestStore(10)
+	start := storeNow.Add(-2 * time.Minute)
+
+	// 第一个步长内 4 个值分布在两个时间段，第二个步长没有数据
+	for i, value := range []float64{100, 200, 300, 400} {
+		if !store.Add("latency", "", start.Add(time.Duration(i)*10*time.Second), value) {
+			t.Fatalf("Add(%v) rejected", value)
+		}
+	}
+
+	tests := []struct {
+		fn       string
+		quantile float64
+		want     []float64
+	}{
+		{types.LogMetricRate, 0, []float64{1000.0 / 60, 0}},
+		{types.LogMetricSum, 0, []float64{1000, 0}},
+		{types.LogMetricCount, 0, []float64{4, 0}},
+		{types.LogMetricAvg, 0, []float64{250}},
+		{types.LogMetricMin, 0, []float64{100}},
+		{types.LogMetricMax, 0, []float64{400}},
+		{types.LogMetricQuantile, 0.5, []float64{200}},
+		{types.LogMetricQuantile, 0.99, []float64{400}},
+	}
+	for _, tt := range tests {
+		q := query(tt.fn, start, time.Minute)
+		q.Quantile = tt.quantile
+		if got := pointValues(t, store.Query("latency", q)); !equalValues(got, tt.want) {
+			t.Errorf("%s(%v) = %v, want %v", tt.fn, tt.quantile, got, tt.want)
+		}
+	}
+}
+
+func TestStore_RejectsOutOfRange(t *testing.T) {
+	store := newTestStore(10)
+
+	if store.Add("latency", "", storeNow.Add(-11*time.Minute), 1) {
+		t.Error("expected sample older than retention to be rejected")
+	}
+	if store.Add("latency", "", storeNow.Add(time.Minute), 1) {
+		t.Error("expected sample in the future to be rejected")
+	}
+	if !store.Add("latency", "", storeNow, 1) {
+		t.Error("expected current sample to be accepted")
+	}
+}
+
+func TestStore_RingReuse(t *testing.T) {
+	store := newTestStore(10)
+	old := storeNow.Add(-10*time.Minute + 5*time.Second)
+
+	store.Add("latency", "", old, 7)
+	// 新的时间段映射到同一位置，覆盖过期的数据
+	store.now = func() time.Time { return storeNow.Add(10 * time.Minute) }
+	store.Add("latency", "", old.Add(10*time.Minute), 3)
+
+	if store.Add("latency", "", old, 5) {
+		t.Error("expected sample for an overwritten slot to be rejected")
+	}
+
+	q := query(types.LogMetricSum, old.Truncate(10*time.Second).Add(10*time.Minute), 10*time.Second)
+	if got := pointValues(t, store.Query("latency", q)); !equalValues(got, []float64{3, 0}) {
+		t.Errorf("sum after reuse = %v, want [3 0]", got)
+	}
+}
+
+func TestStore_Groups(t *testing.T) {
+	store := newTestStore(2)
+
+	store.Add("latency", "GET", storeNow, 1)
+	store.Add("latency", "POST", storeNow, 2)
+	if store.Add("latency", "PUT", storeNow, 3) {
+		t.Error("expected new group beyond maxSeries to be rejected")
+	}
+
+	groups := store.Groups("latency")
+	if len(groups) != 2 || groups[0] != "GET" || groups[1] != "POST" {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+
+	q := query(types.LogMetricSum, storeNow.Truncate(time.Minute), time.Minute)
+	q.Group = "POST"
+	series := store.Query("latency", q)
+	if len(series) != 1 || series[0].Group != "POST" || series[0].Points[0].Value != 2 {
+		t.Errorf("unexpected series for group POST: %+v", series)
+	}
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/local-log-viewer/internal/alert"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+// initializeAlerting 创建告警引擎和通知分发器，配置无效时只记录日志，不影响日志查看
+func (lm *LogManager) initializeAlerting() {
+	engine, err := alert.NewEngine(resolveAlertRules(lm.config.Alerts, lm.mountList()), lm)
+	if err != nil {
+		logger.Error("创建告警引擎失败", zap.Error(err))
+		return
+	}
+	lm.alertEngine = engine
+
+	dispatcher, err := alert.NewDispatcher(lm.config.Notifiers, lm.config.Server.DataDir)
+	if err != nil {
+		logger.Error("创建告警通知失败", zap.Error(err))
+		return
+	}
+	lm.alertDispatcher = dispatcher
+	engine.OnEvent(dispatcher.Handle)
+}
+
+// startAlerting 启动告警引擎和通知分发器
+func (lm *LogManager) startAlerting() error {
+	if lm.alertDispatcher != nil {
+		if err := lm.alertDispatcher.Start(); err != nil {
+			return fmt.Errorf("启动告警通知失败: %w", err)
+		}
+	}
+	if lm.alertEngine != nil {
+		if err := lm.alertEngine.Start(); err != nil {
+			return fmt.Errorf("启动告警引擎失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// stopAlerting 停止告警引擎和通知分发器，未投递的通知保留在队列中
+func (lm *LogManager) stopAlerting() error {
+	if lm.alertEngine != nil {
+		if err := lm.alertEngine.Stop(); err != nil {
+			return fmt.Errorf("停止告警引擎失败: %w", err)
+		}
+	}
+	if lm.alertDispatcher != nil {
+		if err := lm.alertDispatcher.Stop(); err != nil {
+			return fmt.Errorf("停止告警通知失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetAlerts 返回所有告警规则的当前状态
+func (lm *LogManager) GetAlerts() []types.AlertStatus {
+	if lm.alertEngine == nil {
+		return []types.AlertStatus{}
+	}
+	return lm.alertEngine.Alerts()
+}
+
+// OnAlert 注册告警状态变化的监听函数
+func (lm *LogManager) OnAlert(listener func(types.AlertEvent)) {
+	if lm.alertEngine != nil {
+		lm.alertEngine.OnEvent(listener)
+	}
+}
+
+// resolveAlertRules 将规则中的相对路径解析为日志源下的完整路径
+func resolveAlertRules(rules []config.AlertRuleConfig, mounts []sourceMount) []config.AlertRuleConfig {
+	resolved := make([]config.AlertRuleConfig, len(rules))
+	for i, r := range rules {
+		resolved[i] = r
+		resolved[i].Path = resolveLogPath(r.Path, mounts)
+	}
+	return resolved
+}
+
+// resolveLogPath 将相对路径解析为日志源下的完整路径，按配置的顺序使用第一个存在该文件的日志源；
+// 文件还不存在时使用第一个能包含该路径的日志源，引擎会在文件出现后开始监控
+func resolveLogPath(path string, mounts []sourceMount) string {
+	if filepath.IsAbs(path) || source.IsRemote(path) || len(mounts) == 0 {
+		return path
+	}
+
+	ctx, cancel := sourceContext()
+	defer cancel()
+
+	var fallback string
+	for _, m := range mounts {
+		candidate, ok := m.src.Resolve(mountPath(m.src.Root(), path))
+		if !ok {
+			continue
+		}
+		if fallback == "" {
+			fallback = candidate
+		}
+		if _, err := m.src.Stat(ctx, candidate); err == nil {
+			return candidate
+		}
+	}
+	if fallback == "" {
+		return path
+	}
+	return fallback
+}
+
+// mountPath 将相对路径拼接到日志源的根路径下，本地路径使用 filepath.Join，
+// 其他日志源（例如 ssh://、s3://、docker://）的路径使用 / 分隔，不能用 filepath.Join 合并 scheme 后的 //
+func mountPath(root, rel string) string {
+	if !source.IsRemote(root) {
+		return filepath.Join(root, rel)
+	}
+	return strings.TrimSuffix(root, "/") + "/" + filepath.ToSlash(rel)
+}
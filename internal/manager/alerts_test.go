@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/source/sourcetest"
+)
+
+func TestResolveAlertRules(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	if err := os.WriteFile(filepath.Join(second, "api.log"), []byte("x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	mounts := newSourceMounts(createTestConfig([]string{first, second}), nil)
+
+	rules := resolveAlertRules([]config.AlertRuleConfig{
+		{Name: "existing", Path: "api.log"},
+		{Name: "missing", Path: "later.log"},
+		{Name: "absolute", Path: "/var/log/app.log"},
+	}, mounts)
+
+	expected := []string{
+		filepath.Join(second, "api.log"),
+		filepath.Join(first, "later.log"),
+		"/var/log/app.log",
+	}
+	for i, rule := range rules {
+		if rule.Path != expected[i] {
+			t.Errorf("Rule %s: expected path %s, got %s", rule.Name, expected[i], rule.Path)
+		}
+	}
+}
+
+func TestResolveAlertRules_RemoteRoots(t *testing.T) {
+	local := t.TempDir()
+	remote := sourcetest.NewMemory("mem://fixtures/var/log")
+	remote.Write("app/api.log", "x\n")
+	mounts := []sourceMount{{name: "remote", src: remote}}
+	mounts = append(mounts, newSourceMounts(createTestConfig([]string{local}), nil)...)
+
+	rules := resolveAlertRules([]config.AlertRuleConfig{
+		{Name: "remote", Path: "app/api.log"},
+		{Name: "missing", Path: "later.log"},
+		{Name: "escape", Path: "../../etc/passwd"},
+	}, mounts)
+
+	// 远程日志源的路径按 / 拼接，不会被 filepath.Join 合并成 mem:/fixtures
+	expected := []string{
+		"mem://fixtures/var/log/app/api.log",
+		"mem://fixtures/var/log/later.log",
+		"../../etc/passwd",
+	}
+	for i, rule := range rules {
+		if rule.Path != expected[i] {
+			t.Errorf("Rule %s: expected path %s, got %s", rule.Name, expected[i], rule.Path)
+		}
+	}
+}
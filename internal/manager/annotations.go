@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"github.com/local-log-viewer/internal/annotation"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+// GetAnnotations 返回文件当前内容上的标注
+func (lm *LogManager) GetAnnotations(path string) ([]types.Annotation, error) {
+	return lm.annotations.ForFile(path)
+}
+
+// CreateAnnotation 在指定字节位置的行上创建标注
+func (lm *LogManager) CreateAnnotation(user string, a types.Annotation) (*types.Annotation, error) {
+	return lm.annotations.Create(user, a)
+}
+
+// UpdateAnnotation 修改标注，只有作者可以修改
+func (lm *LogManager) UpdateAnnotation(id, user string, a types.Annotation) (*types.Annotation, error) {
+	return lm.annotations.Update(id, user, a)
+}
+
+// DeleteAnnotation 删除标注，只有作者可以删除
+func (lm *LogManager) DeleteAnnotation(id, user string) (*types.Annotation, error) {
+	return lm.annotations.Delete(id, user)
+}
+
+// annotateContent 返回附加了标注的内容副本，缓存中的内容保持不变
+func (lm *LogManager) annotateContent(path string, content *types.LogContent) *types.LogContent {
+	entries, ok := lm.annotateEntries(path, content.Entries)
+	if !ok {
+		return content
+	}
+	copied := *content
+	copied.Entries = entries
+	return &copied
+}
+
+// annotateSearchResult 返回附加了标注的搜索结果副本，缓存中的结果保持不变
+func (lm *LogManager) annotateSearchResult(path string, result *types.SearchResult) *types.SearchResult {
+	entries, ok := lm.annotateEntries(path, result.Entries)
+	if !ok {
+		return result
+	}
+	copied := *result
+	copied.Entries = entries
+	return &copied
+}
+
+// annotateEntries 文件上有标注时返回附加了标注的条目副本
+func (lm *LogManager) annotateEntries(path string, entries []types.LogEntry) ([]types.LogEntry, bool) {
+	if len(entries) == 0 {
+		return nil, false
+	}
+
+	annotations, err := lm.annotations.ForFile(path)
+	if err != nil {
+		logger.Warn("读取日志标注失败", zap.String("path", path), zap.Error(err))
+		return nil, false
+	}
+	if len(annotations) == 0 {
+		return nil, false
+	}
+
+	copied := make([]types.LogEntry, len(entries))
+	copy(copied, entries)
+	annotation.Attach(copied, annotations)
+	return copied, true
+}
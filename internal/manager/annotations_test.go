@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/local-log-viewer/internal/source/sourcetest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestLogManager_AnnotationsAttachedToEntries(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+	content := "2023-01-01 10:00:00 INFO started\n2023-01-01 10:00:01 ERROR database timeout\n2023-01-01 10:00:02 INFO retrying\n"
+	if err := os.WriteFile(logFilePath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newFormatTestManager(t, createTestConfig([]string{tempDir}))
+
+	result, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	target := result.Entries[1]
+	if target.ByteOffset != 33 {
+		t.Fatalf("期望第二行字节位置为 33，得到 %d", target.ByteOffset)
+	}
+
+	created, err := manager.CreateAnnotation("alice", types.Annotation{
+		Path:    logFilePath,
+		Offset:  target.ByteOffset,
+		Tags:    []string{"root-cause"},
+		Comment: "root cause here",
+	})
+	if err != nil {
+		t.Fatalf("创建标注失败: %v", err)
+	}
+
+	// 追加内容后重新读取，标注仍附加在同一行上
+	file, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("打开测试文件失败: %v", err)
+	}
+	file.WriteString("2023-01-01 10:00:03 INFO recovered\n")
+	file.Close()
+
+	assertAnnotated := func(source string, entries []types.LogEntry) {
+		t.Helper()
+		found := false
+		for _, entry := range entries {
+			if entry.ByteOffset == target.ByteOffset {
+				found = true
+				if len(entry.Annotations) != 1 || entry.Annotations[0].ID != created.ID {
+					t.Errorf("%s: 期望第二行附加标注 %s，得到 %+v", source, created.ID, entry.Annotations)
+				}
+			} else if len(entry.Annotations) != 0 {
+				t.Errorf("%s: 第 %d 行不应有标注", source, entry.LineNum)
+			}
+		}
+		if !found {
+			t.Errorf("%s: 结果中没有被标注的行", source)
+		}
+	}
+
+	content2, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	assertAnnotated("ReadLogFile", content2.Entries)
+
+	tail, err := manager.ReadLogFileFromTail(context.Background(), logFilePath, 3)
+	if err != nil {
+		t.Fatalf("读取文件尾部失败: %v", err)
+	}
+	assertAnnotated("ReadLogFileFromTail", tail.Entries)
+
+	searchResult := manager.annotateSearchResult(logFilePath, &types.SearchResult{
+		Entries: []types.LogEntry{{LineNum: 1, ByteOffset: target.ByteOffset}},
+	})
+	assertAnnotated("SearchLogs", searchResult.Entries)
+
+	// 删除后不再附加
+	if _, err := manager.DeleteAnnotation(created.ID, "alice"); err != nil {
+		t.Fatalf("删除标注失败: %v", err)
+	}
+	content3, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	for _, entry := range content3.Entries {
+		if len(entry.Annotations) != 0 {
+			t.Errorf("删除后第 %d 行仍有标注", entry.LineNum)
+		}
+	}
+}
+
+func TestLogManager_AnnotationsOnMountedSource(t *testing.T) {
+	manager := newStreamTestManager(t, t.TempDir())
+	fixtures := sourcetest.NewMemory("mem://fixtures")
+	fixtures.Write("app.log", "INFO started\nERROR database timeout\n")
+	if err := manager.Mount("fixtures", fixtures); err != nil {
+		t.Fatalf("挂载日志源失败: %v", err)
+	}
+
+	// 标注按日志源的规范路径保存，读取时附加到远程文件的条目上
+	created, err := manager.CreateAnnotation("alice", types.Annotation{Path: "mem://fixtures/./app.log", Offset: 13, Pinned: true})
+	if err != nil {
+		t.Fatalf("创建标注失败: %v", err)
+	}
+	if created.Path != "mem://fixtures/app.log" || created.Line != "ERROR database timeout" {
+		t.Errorf("标注的路径或内容不正确: %+v", created)
+	}
+
+	content, err := manager.ReadLogFile(context.Background(), "mem://fixtures/app.log", 0, 10)
+	if err != nil {
+		t.Fatalf("读取挂载的文件失败: %v", err)
+	}
+	if len(content.Entries) != 2 || len(content.Entries[1].Annotations) != 1 || len(content.Entries[0].Annotations) != 0 {
+		t.Errorf("标注没有附加到远程文件的条目上: %+v", content.Entries)
+	}
+
+	// 远程文件被替换后不再显示
+	fixtures.Write("app.log", "WARN rotated\nINFO fresh start\n")
+	if annotations, err := manager.GetAnnotations("mem://fixtures/app.log"); err != nil || len(annotations) != 0 {
+		t.Errorf("文件替换后不应返回标注: %+v, %v", annotations, err)
+	}
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"path/filepath"
+
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+// archiveSource 路径位于本地的归档文件中（或就是归档文件本身）时返回该归档的日志源，
+// 每个归档第一次访问时创建，之后复用其中的成员索引和保留的解压器
+func (lm *LogManager) archiveSource(path string) (*source.ArchiveSource, bool) {
+	archive, _, ok := source.SplitArchivePath(path)
+	if !ok {
+		return nil, false
+	}
+
+	lm.archiveMutex.Lock()
+	defer lm.archiveMutex.Unlock()
+
+	if src, exists := lm.archives[archive]; exists {
+		return src, true
+	}
+	src, err := source.NewArchiveSource(archive)
+	if err != nil {
+		logger.Warn("打开归档失败", zap.String("archive", archive), zap.Error(err))
+		return nil, false
+	}
+	lm.archives[archive] = src
+	return src, true
+}
+
+// archiveLogFiles 列出归档中的日志文件，规则与本地文件相同（大小限制按解压后的大小）
+func (lm *LogManager) archiveLogFiles(path string) []types.LogFile {
+	src, ok := lm.archiveSource(path)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := sourceContext()
+	defer cancel()
+	infos, err := src.Walk(ctx)
+	if err != nil {
+		logger.Warn("读取归档失败", zap.String("archive", path), zap.Error(err))
+		return nil
+	}
+
+	var files []types.LogFile
+	for _, info := range infos {
+		if lm.acceptSourceFile(info) {
+			files = append(files, lm.sourceLogFile(info))
+		}
+	}
+	return files
+}
+
+// archiveDirectory 归档或归档中的目录在文件树中的节点
+func (lm *LogManager) archiveDirectory(path string) (types.LogFile, bool) {
+	src, ok := lm.archiveSource(path)
+	if !ok {
+		return types.LogFile{}, false
+	}
+
+	ctx, cancel := sourceContext()
+	defer cancel()
+	info, err := src.Stat(ctx, path)
+	if err != nil || !info.IsDir {
+		return types.LogFile{}, false
+	}
+	return types.LogFile{
+		Path:        filepath.Clean(path),
+		Name:        info.Name,
+		ModTime:     info.ModTime,
+		IsDirectory: true,
+	}, true
+}
+
+// closeArchives 关闭打开过的归档，释放保留的解压器
+func (lm *LogManager) closeArchives() {
+	lm.archiveMutex.Lock()
+	defer lm.archiveMutex.Unlock()
+
+	for _, src := range lm.archives {
+		src.Close()
+	}
+	lm.archives = make(map[string]*source.ArchiveSource)
+}
@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// writeTestBundle 在 dir 下创建 tar.gz 支持包
+func writeTestBundle(t *testing.T, dir string, members map[string]string) string {
+	t.Helper()
+	bundle := filepath.Join(dir, "case-1234.tar.gz")
+	file, err := os.Create(bundle)
+	if err != nil {
+		t.Fatalf("创建支持包失败: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range members {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return bundle
+}
+
+func findLogFile(files []types.LogFile, path string) *types.LogFile {
+	for i := range files {
+		if files[i].Path == path {
+			return &files[i]
+		}
+		if found := findLogFile(files[i].Children, path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestLogManager_ArchiveBundle(t *testing.T) {
+	logDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(logDir, "local.log"), []byte("local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var app strings.Builder
+	for i := 0; i < 500; i++ {
+		level := "info"
+		if i == 321 {
+			level = "error"
+		}
+		fmt.Fprintf(&app, `{"time":"2024-03-01T12:00:00Z","level":"%s","msg":"request %d"}`+"\n", level, i)
+	}
+	bundle := writeTestBundle(t, logDir, map[string]string{
+		"node1/var/log/app.log":   app.String(),
+		"node1/var/log/syslog.md": "not a log\n",
+		"node2/kern.log":          "kernel: oops\n",
+	})
+
+	manager := NewLogManager(createTestConfig([]string{logDir}), manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+
+	// 归档是本地目录下的目录节点，成员按归档中的目录结构挂在归档节点下
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	archiveNode := findLogFile(files, bundle)
+	if archiveNode == nil || !archiveNode.IsDirectory || findLogFile(files, logDir) == nil || findLogFile(files, logDir).Children == nil {
+		t.Fatalf("文件树中没有归档目录: %+v", files)
+	}
+	appPath := filepath.Join(bundle, "node1", "var", "log", "app.log")
+	if member := findLogFile(archiveNode.Children, appPath); member == nil || member.Format != "JSON" {
+		t.Errorf("归档中的日志文件不正确: %+v", archiveNode.Children)
+	}
+	if findLogFile(archiveNode.Children, filepath.Join(bundle, "node2", "kern.log")) == nil ||
+		findLogFile(files, filepath.Join(bundle, "node1", "var", "log", "syslog.md")) != nil {
+		t.Errorf("归档中的文件过滤不正确: %+v", archiveNode.Children)
+	}
+
+	// 懒加载：本地目录中的归档作为目录，归档中的目录可以继续展开
+	entries, err := manager.GetDirectoryFiles(logDir)
+	if err != nil || findLogFile(entries, bundle) == nil || !findLogFile(entries, bundle).IsDirectory {
+		t.Errorf("目录列表中没有归档: %+v, %v", entries, err)
+	}
+	entries, err = manager.GetDirectoryFiles(bundle)
+	if err != nil || len(entries) != 2 || entries[0].Name != "node1" {
+		t.Errorf("归档的目录列表不正确: %+v, %v", entries, err)
+	}
+
+	ctx := context.Background()
+	page, err := manager.ReadLogFile(ctx, appPath, 300, 10)
+	if err != nil {
+		t.Fatalf("读取归档中的文件失败: %v", err)
+	}
+	if len(page.Entries) != 10 || page.Entries[0].Message != "request 300" || page.TotalLines != 500 {
+		t.Errorf("分页内容不正确: %+v", page)
+	}
+
+	tail, err := manager.ReadLogFileFromTail(ctx, appPath, 2)
+	if err != nil || len(tail.Entries) != 2 || tail.Entries[1].Message != "request 499" {
+		t.Errorf("尾部内容不正确: %+v, %v", tail, err)
+	}
+
+	result, err := manager.SearchLogs(ctx, types.SearchQuery{Path: appPath, Query: "request 321", Limit: 10})
+	if err != nil {
+		t.Fatalf("搜索归档中的文件失败: %v", err)
+	}
+	if result.TotalCount != 1 || result.Entries[0].Level != "ERROR" {
+		t.Errorf("搜索结果不正确: %+v", result)
+	}
+
+	if _, err := manager.ReadLogFile(ctx, bundle+"/../../etc/passwd", 0, 10); err == nil {
+		t.Error("期望读取归档之外的路径失败")
+	}
+}
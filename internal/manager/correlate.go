@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// Correlate 在所有配置的日志路径中查找带有同一 trace ID 或 request ID 的日志，返回合并后的时间线
+func (lm *LogManager) Correlate(ctx context.Context, query types.CorrelateQuery) (*types.CorrelateResult, error) {
+	cfg := lm.config.Correlation.WithDefaults()
+	if query.Limit <= 0 || query.Limit > cfg.MaxResults {
+		query.Limit = cfg.MaxResults
+	}
+
+	paths := lm.correlationFiles(cfg.MaxFiles)
+	return lm.searchEngine.Correlate(ctx, paths, query, search.CorrelationFields{
+		ID:       cfg.IDFields,
+		SpanID:   cfg.SpanIDFields,
+		ParentID: cfg.ParentIDFields,
+	})
+}
+
+// correlationFiles 列出所有配置路径下的日志文件，按最近修改时间优先，最多 maxFiles 个
+func (lm *LogManager) correlationFiles(maxFiles int) []string {
+	lm.mutex.RLock()
+	seen := make(map[string]bool)
+	var files []types.LogFile
+	for _, m := range lm.mountList() {
+		if !isLocalSource(m.src) {
+			continue
+		}
+		absPath := m.src.Root()
+		info, err := os.Stat(absPath)
+		if err != nil {
+			continue
+		}
+
+		var found []types.LogFile
+		if info.IsDir() {
+			if found, err = lm.scanDirectory(absPath); err != nil {
+				continue
+			}
+		} else if source.IsArchive(absPath) {
+			found = lm.archiveLogFiles(absPath)
+		} else {
+			found = []types.LogFile{lm.createLogFile(absPath, info)}
+		}
+
+		// 配置的路径可能互相包含
+		for _, file := range found {
+			if !seen[file.Path] {
+				seen[file.Path] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	lm.mutex.RUnlock()
+
+	// 列出非本地日志源需要网络往返，不持有 lm.mutex，慢速的日志源不会阻塞其他操作
+	ctx, cancel := sourceContext()
+	files = append(files, lm.sourceLogFiles(ctx)...)
+	cancel()
+
+	// 文件过多时优先扫描最近写入的文件
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	if maxFiles > 0 && len(files) > maxFiles {
+		files = files[:maxFiles]
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	return paths
+}
@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/source/sourcetest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestLogManager_Correlate(t *testing.T) {
+	tempDir := t.TempDir()
+	otherDir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(tempDir, "gateway.log"): `{"time":"2023-01-01T10:00:00Z","level":"info","msg":"accepted","requestId":"req-42"}` + "\n",
+		filepath.Join(tempDir, "svc", "orders.log"): `{"time":"2023-01-01T10:00:02Z","level":"error","msg":"stock check failed","request_id":"req-42"}` + "\n" +
+			`{"time":"2023-01-01T10:00:03Z","level":"info","msg":"unrelated","request_id":"req-7"}` + "\n",
+		filepath.Join(otherDir, "billing.log"): "time=2023-01-01T10:00:01Z level=info msg=charge request_id=req-42 amount=10\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	// 同一个文件被两个配置路径包含时只扫描一次
+	cfg := createTestConfig([]string{tempDir, otherDir, filepath.Join(tempDir, "gateway.log")})
+	manager := newFormatTestManager(t, cfg)
+
+	result, err := manager.Correlate(context.Background(), types.CorrelateQuery{ID: "req-42"})
+	if err != nil {
+		t.Fatalf("关联查询失败: %v", err)
+	}
+	if result.FilesScanned != 3 {
+		t.Errorf("期望扫描 3 个文件，得到 %d", result.FilesScanned)
+	}
+
+	var messages []string
+	for _, entry := range result.Entries {
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 3 || messages[0] != "accepted" || messages[2] != "stock check failed" {
+		t.Fatalf("时间线不正确: %v", messages)
+	}
+	if result.Entries[1].Path != filepath.Join(otherDir, "billing.log") || result.Entries[1].MatchedField != "request_id" {
+		t.Errorf("logfmt 条目不正确: %+v", result.Entries[1])
+	}
+
+	// 文件数上限
+	cfg.Correlation.MaxFiles = 1
+	result, err = manager.Correlate(context.Background(), types.CorrelateQuery{ID: "req-42"})
+	if err != nil {
+		t.Fatalf("关联查询失败: %v", err)
+	}
+	if result.FilesScanned != 1 {
+		t.Errorf("期望最多扫描 1 个文件，得到 %d", result.FilesScanned)
+	}
+}
+
+func TestLogManager_CorrelationFilesReleasesLock(t *testing.T) {
+	manager := newStreamTestManager(t, t.TempDir())
+	src := &blockingSource{
+		Memory:  sourcetest.NewMemory("mem://slow"),
+		blocked: "mem://slow",
+		opened:  make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	src.Write("app.log", "INFO started\n")
+	if err := manager.Mount("slow", src); err != nil {
+		t.Fatalf("挂载日志源失败: %v", err)
+	}
+
+	done := make(chan []string)
+	go func() { done <- manager.correlationFiles(0) }()
+	select {
+	case <-src.opened:
+	case <-time.After(2 * time.Second):
+		t.Fatal("没有列出日志源中的文件")
+	}
+
+	// 列出远程日志源期间不持有 lm.mutex
+	locked := make(chan struct{})
+	go func() {
+		manager.mutex.Lock()
+		manager.mutex.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(2 * time.Second):
+		t.Error("列出远程日志源时仍持有 lm.mutex")
+	}
+
+	close(src.release)
+	if paths := <-done; len(paths) != 1 || paths[0] != "mem://slow/app.log" {
+		t.Errorf("期望列出远程文件，得到 %v", paths)
+	}
+}
@@ -0,0 +1,396 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/parser"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// formatSampleLines 检测文件格式时读取的最大非空行数
+	formatSampleLines = 20
+	// formatSampleBytes 检测文件格式时读取的最大字节数
+	formatSampleBytes = 64 * 1024
+	// formatAuto 表示清除手动指定的格式，恢复自动检测
+	formatAuto = "auto"
+)
+
+// detectedFormat 文件格式检测结果，按路径缓存，文件被替换（inode变化）后失效
+type detectedFormat struct {
+	identity fileIdentity
+	parser   interfaces.LogParser
+}
+
+// fileIdentity 判断文件是否被替换：本地文件比较 inode，其他日志源比较返回的文件标识
+type fileIdentity struct {
+	info os.FileInfo
+	id   string
+}
+
+func (f fileIdentity) same(other fileIdentity) bool {
+	if f.info != nil && other.info != nil {
+		return os.SameFile(f.info, other.info)
+	}
+	return f.id != "" && f.id == other.id
+}
+
+// cleanPath 规范化文件路径，非本地路径按所属的日志源规范化（filepath.Clean 会合并 scheme 后的 //）
+func (lm *LogManager) cleanPath(path string) string {
+	if !source.IsRemote(path) {
+		return filepath.Clean(path)
+	}
+	if _, resolved, err := lm.sourceFor(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// parserForFile 获取文件使用的解析器，同一文件的所有行使用同一个解析器
+// 优先级：API指定的格式 > 配置的格式 > 通过文件glob绑定的自定义解析器 > 按文件样本自动检测
+func (lm *LogManager) parserForFile(path string) interfaces.LogParser {
+	return lm.parserForIdentity(path, nil)
+}
+
+// parserForIdentity 与 parserForFile 相同，identity 为已知的文件标识（例如列出远程目录时得到的），为 nil 时重新获取
+func (lm *LogManager) parserForIdentity(path string, identity *fileIdentity) interfaces.LogParser {
+	path = lm.cleanPath(path)
+
+	lm.formatMutex.RLock()
+	override := lm.formatOverrides[path]
+	cached, hasCached := lm.formatCache[path]
+	lm.formatMutex.RUnlock()
+
+	if override != "" {
+		if p := lm.lookupParser(override); p != nil {
+			return p
+		}
+	}
+
+	if p := lm.configuredParser(path); p != nil {
+		return p
+	}
+
+	lm.parserMutex.RLock()
+	bound := lm.parserFactory.GetParserForFile(path)
+	lm.parserMutex.RUnlock()
+	if bound != nil {
+		return bound
+	}
+
+	if identity == nil {
+		current, err := lm.fileIdentity(path)
+		if err != nil {
+			return lm.defaultParser()
+		}
+		identity = &current
+	}
+
+	// 路径相同且仍是同一个文件时直接使用缓存的检测结果
+	if hasCached && cached.identity.same(*identity) {
+		return cached.parser
+	}
+
+	sample, err := lm.readFormatSample(path)
+	if err != nil || len(sample) == 0 {
+		// 空文件暂不缓存，等有内容后再检测
+		return lm.defaultParser()
+	}
+
+	detected := lm.newFormatDetector().DetectFormat(strings.Join(sample, "\n"))
+
+	lm.formatMutex.Lock()
+	lm.formatCache[path] = detectedFormat{identity: *identity, parser: detected}
+	lm.formatMutex.Unlock()
+
+	logger.Debug("检测到日志文件格式",
+		zap.String("path", path),
+		zap.String("format", detected.GetFormat()))
+
+	return detected
+}
+
+// GetFileFormat 获取文件当前使用的日志格式名称
+func (lm *LogManager) GetFileFormat(path string) string {
+	return lm.parserForFile(path).GetFormat()
+}
+
+// SetFileFormat 手动指定文件的日志格式，format 为 "auto" 或空时恢复自动检测。
+// 文件必须位于配置的日志路径或挂载的日志源中（否则返回的错误满足 errors.Is(err, fs.ErrPermission)）
+// 并且存在（否则满足 errors.Is(err, fs.ErrNotExist)）；清除已指定的格式时不要求文件仍然存在
+func (lm *LogManager) SetFileFormat(path, format string) error {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == formatAuto {
+		format = ""
+	}
+	if format != "" && lm.lookupParser(format) == nil {
+		return fmt.Errorf("未知的日志格式: %s", format)
+	}
+
+	if !lm.mounted(path) {
+		return fmt.Errorf("路径不在配置的日志路径中: %s: %w", path, fs.ErrPermission)
+	}
+	path = lm.cleanPath(path)
+
+	lm.formatMutex.RLock()
+	_, overridden := lm.formatOverrides[path]
+	lm.formatMutex.RUnlock()
+	if format != "" || !overridden {
+		if err := lm.statFile(path); err != nil {
+			return err
+		}
+	}
+
+	// 缓存键包含文件指定的格式（见 formatSuffix），修改后该文件之前缓存的内容不再命中，不影响其他文件
+	lm.formatMutex.Lock()
+	if format == "" {
+		delete(lm.formatOverrides, path)
+	} else {
+		lm.formatOverrides[path] = format
+	}
+	lm.formatMutex.Unlock()
+
+	return nil
+}
+
+// mounted 路径是否位于配置的日志路径或挂载的日志源中
+func (lm *LogManager) mounted(path string) bool {
+	for _, m := range lm.mountList() {
+		if _, ok := m.src.Resolve(path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// statFile 检查文件存在并且不是目录
+func (lm *LogManager) statFile(path string) error {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, fs.ErrPermission)
+	}
+	ctx, cancel := sourceContext()
+	defer cancel()
+	info, err := src.Stat(ctx, resolved)
+	if err != nil {
+		return fmt.Errorf("文件不存在: %w", err)
+	}
+	if info.IsDir {
+		return fmt.Errorf("不能为目录指定格式: %s", path)
+	}
+	return nil
+}
+
+// requestFormatKey 请求上下文中临时指定的解析器
+type requestFormatKey struct{}
+
+// WithFormat 返回只对本次请求生效的上下文，读取时使用 format 对应的解析器，不修改文件保存的格式和检测缓存
+func (lm *LogManager) WithFormat(ctx context.Context, format string) (context.Context, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || format == formatAuto {
+		return ctx, nil
+	}
+	p := lm.lookupParser(format)
+	if p == nil {
+		return ctx, fmt.Errorf("未知的日志格式: %s", format)
+	}
+	return context.WithValue(ctx, requestFormatKey{}, p), nil
+}
+
+// requestParser 获取本次读取使用的解析器，请求指定的格式优先于文件的格式
+func (lm *LogManager) requestParser(ctx context.Context, path string) interfaces.LogParser {
+	if p, ok := ctx.Value(requestFormatKey{}).(interfaces.LogParser); ok {
+		return p
+	}
+	return lm.parserForFile(path)
+}
+
+// formatSuffix 加到缓存键后面，区分请求临时指定的格式和文件指定的格式，避免和按其他格式解析的结果混用
+func (lm *LogManager) formatSuffix(ctx context.Context, path string) string {
+	if p, ok := ctx.Value(requestFormatKey{}).(interfaces.LogParser); ok {
+		return ":" + p.GetFormat()
+	}
+	path = lm.cleanPath(path)
+	lm.formatMutex.RLock()
+	override := lm.formatOverrides[path]
+	lm.formatMutex.RUnlock()
+	if override != "" {
+		if p := lm.lookupParser(override); p != nil {
+			return ":" + p.GetFormat()
+		}
+	}
+	return ""
+}
+
+// resetFormatCache 清空格式检测缓存（解析器变化后需要重新检测）
+func (lm *LogManager) resetFormatCache() {
+	lm.formatMutex.Lock()
+	lm.formatCache = make(map[string]detectedFormat)
+	lm.formatMutex.Unlock()
+}
+
+// configuredParser 查找配置文件中为该文件固定的格式
+func (lm *LogManager) configuredParser(path string) interfaces.LogParser {
+	base := filepath.Base(path)
+	for _, f := range lm.config.Formats {
+		for _, glob := range f.Files {
+			if matched, _ := filepath.Match(glob, path); matched {
+				return lm.lookupParser(f.Format)
+			}
+			if matched, _ := filepath.Match(glob, base); matched {
+				return lm.lookupParser(f.Format)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupParser 按名称查找解析器，支持内置解析器和自定义解析器（不区分大小写）
+func (lm *LogManager) lookupParser(name string) interfaces.LogParser {
+	name = strings.ToLower(name)
+
+	lm.parserMutex.RLock()
+	defer lm.parserMutex.RUnlock()
+
+	if p, exists := lm.parsers[name]; exists {
+		return p
+	}
+	for _, p := range lm.customParsers {
+		if strings.ToLower(p.GetFormat()) == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// defaultParser 无法检测格式时使用的通用解析器
+func (lm *LogManager) defaultParser() interfaces.LogParser {
+	if p := lm.lookupParser("common"); p != nil {
+		return p
+	}
+	return parser.NewCommonLogParser()
+}
+
+// newFormatDetector 按固定顺序构建候选解析器：自定义解析器、内置解析器、其他注册的解析器，通用解析器兜底
+func (lm *LogManager) newFormatDetector() *parser.AutoDetector {
+	lm.parserMutex.RLock()
+	defer lm.parserMutex.RUnlock()
+
+	candidates := make([]interfaces.LogParser, 0, len(lm.customParsers)+len(lm.parsers))
+	candidates = append(candidates, lm.customParsers...)
+
+	// json-file 和 journal 记录也是合法的 JSON，Docker、Journal 排在 JSON 之前
+	for _, name := range []string{"docker", "journal", "json", "syslog", "logfmt"} {
+		if p, exists := lm.parsers[name]; exists {
+			candidates = append(candidates, p)
+		}
+	}
+
+	var extra []string
+	for name := range lm.parsers {
+		switch name {
+		case "docker", "journal", "json", "syslog", "logfmt", "common":
+		default:
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		candidates = append(candidates, lm.parsers[name])
+	}
+
+	if p, exists := lm.parsers["common"]; exists {
+		candidates = append(candidates, p)
+	}
+
+	return parser.NewAutoDetectorWithParsers(candidates, formatSampleLines)
+}
+
+// parseLine 使用文件的解析器解析一行，解析失败时退回通用解析器
+func (lm *LogManager) parseLine(p interfaces.LogParser, line string, lineNum int64) types.LogEntry {
+	entry := types.LogEntry{
+		Raw:     line,
+		LineNum: lineNum,
+	}
+
+	if p != nil {
+		if parsed, err := p.Parse(line); err == nil {
+			entry = *parsed
+			entry.LineNum = lineNum
+			return entry
+		}
+	}
+
+	// 例如JSON日志中夹杂的堆栈行
+	if fallback := lm.defaultParser(); fallback != p {
+		if parsed, err := fallback.Parse(line); err == nil {
+			entry = *parsed
+			entry.LineNum = lineNum
+		}
+	}
+
+	return entry
+}
+
+// fileIdentity 通过文件所属的日志源获取文件标识
+func (lm *LogManager) fileIdentity(path string) (fileIdentity, error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	ctx, cancel := sourceContext()
+	defer cancel()
+	info, err := src.Stat(ctx, resolved)
+	return fileIdentity{info: info.OSInfo, id: info.ID}, err
+}
+
+// readFormatSample 读取文件开头的非空行作为格式检测样本，只读取开头的 formatSampleBytes 字节
+func (lm *LogManager) readFormatSample(path string) ([]string, error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := sourceContext()
+	defer cancel()
+	r, err := src.Open(ctx, resolved, 0, formatSampleBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scanFormatSample(r)
+}
+
+// scanFormatSample 从读取器中读取格式检测样本
+func scanFormatSample(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), formatSampleBytes)
+
+	var sample []string
+	var total int
+	for len(sample) < formatSampleLines && total < formatSampleBytes && scanner.Scan() {
+		line := scanner.Text()
+		total += len(line) + 1
+		if strings.TrimSpace(line) != "" {
+			sample = append(sample, line)
+		}
+	}
+
+	// 超长的行会导致扫描失败，已读取的样本仍然可用
+	if err := scanner.Err(); err != nil && len(sample) == 0 {
+		return nil, err
+	}
+
+	return sample, nil
+}
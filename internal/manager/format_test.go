@@ -0,0 +1,237 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+	"github.com/local-log-viewer/internal/watcher"
+)
+
+func newFormatTestManager(t *testing.T, cfg *config.Config) *LogManager {
+	fileWatcher, err := watcher.NewFileWatcher()
+	if err != nil {
+		t.Fatalf("创建文件监控器失败: %v", err)
+	}
+	t.Cleanup(func() { fileWatcher.Stop() })
+
+	manager := NewLogManager(cfg, fileWatcher, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+	return manager
+}
+
+func TestLogManager_PerFileFormatDetection(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "service.log")
+	content := `{"time":"2023-01-01T10:00:00Z","level":"info","msg":"started"}
+{"time":"2023-01-01T10:00:01Z","level":"error","msg":"panic recovered"}
+{"time":"2023-01-01T10:00:02Z","level":"info","msg":"restarted"}
+goroutine 1 [running]:
+level=warn msg="looks like logfmt"
+`
+	if err := os.WriteFile(logFilePath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newFormatTestManager(t, createTestConfig([]string{tempDir}))
+
+	// 多次读取结果必须一致，且所有行都按同一格式处理
+	for i := 0; i < 5; i++ {
+		result, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+		if err != nil {
+			t.Fatalf("读取日志文件失败: %v", err)
+		}
+		if result.Format != "JSON" {
+			t.Fatalf("期望检测为 JSON，实际为 %s", result.Format)
+		}
+		if len(result.Entries) != 5 {
+			t.Fatalf("期望 5 个日志条目，得到 %d", len(result.Entries))
+		}
+		if result.Entries[1].Level != "ERROR" || result.Entries[1].Message != "panic recovered" {
+			t.Errorf("JSON 行解析不正确: %+v", result.Entries[1])
+		}
+		// 无法按JSON解析的行退回通用解析器，而不是被猜测为其他格式
+		if result.Entries[4].LogType == "Logfmt" {
+			t.Error("同一文件中不应混用 logfmt 解析器")
+		}
+		manager.cache.Clear()
+	}
+
+	// 文件被替换（inode变化）后重新检测
+	replacement := filepath.Join(tempDir, "service.log.new")
+	if err := os.WriteFile(replacement, []byte("level=info msg=rotated port=8080\nlevel=debug msg=tick\n"), 0644); err != nil {
+		t.Fatalf("创建替换文件失败: %v", err)
+	}
+	if err := os.Rename(replacement, logFilePath); err != nil {
+		t.Fatalf("替换文件失败: %v", err)
+	}
+	if format := manager.GetFileFormat(logFilePath); format != "Logfmt" {
+		t.Errorf("文件替换后期望重新检测为 Logfmt，实际为 %s", format)
+	}
+
+	// 目录列表中包含检测到的格式
+	files, err := manager.GetDirectoryFiles(tempDir)
+	if err != nil {
+		t.Fatalf("获取目录文件失败: %v", err)
+	}
+	var listed *types.LogFile
+	for i := range files {
+		if files[i].Name == "service.log" {
+			listed = &files[i]
+		}
+	}
+	if listed == nil || listed.Format != "Logfmt" {
+		t.Errorf("期望文件列表包含格式 Logfmt，实际为 %+v", listed)
+	}
+}
+
+func TestLogManager_FormatOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "kv.log")
+	if err := os.WriteFile(logFilePath, []byte("level=info msg=hello\nlevel=warn msg=slow\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	cfg := createTestConfig([]string{tempDir})
+	cfg.Formats = []config.FormatConfig{{Format: "common", Files: []string{"kv*.log"}}}
+	manager := newFormatTestManager(t, cfg)
+
+	if format := manager.GetFileFormat(logFilePath); format != "Common" {
+		t.Errorf("期望配置固定为 Common，实际为 %s", format)
+	}
+
+	// API指定的格式优先于配置
+	if err := manager.SetFileFormat(logFilePath, "logfmt"); err != nil {
+		t.Fatalf("指定文件格式失败: %v", err)
+	}
+	result, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	if result.Format != "Logfmt" || result.Entries[1].Level != "WARN" {
+		t.Errorf("期望按 logfmt 解析，实际格式为 %s", result.Format)
+	}
+
+	if err := manager.SetFileFormat(logFilePath, "nope"); err == nil {
+		t.Error("期望未知格式返回错误")
+	}
+
+	// 恢复自动检测后回到配置的格式
+	if err := manager.SetFileFormat(logFilePath, "auto"); err != nil {
+		t.Fatalf("恢复自动检测失败: %v", err)
+	}
+	if format := manager.GetFileFormat(logFilePath); format != "Common" {
+		t.Errorf("恢复自动检测后期望为 Common，实际为 %s", format)
+	}
+}
+
+func TestLogManager_FormatOverrideValidation(t *testing.T) {
+	tempDir := t.TempDir()
+	kvPath := filepath.Join(tempDir, "kv.log")
+	otherPath := filepath.Join(tempDir, "other.log")
+	for _, p := range []string{kvPath, otherPath} {
+		if err := os.WriteFile(p, []byte("level=info msg=hello\nlevel=warn msg=slow\n"), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "outside.log")
+	if err := os.WriteFile(outside, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newFormatTestManager(t, createTestConfig([]string{tempDir}))
+
+	// 不在日志路径中的文件、不存在的文件都不会保存格式
+	if err := manager.SetFileFormat(outside, "logfmt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("日志路径之外的文件期望权限错误，得到 %v", err)
+	}
+	if err := manager.SetFileFormat(filepath.Join(tempDir, "missing.log"), "logfmt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("不存在的文件期望文件不存在错误，得到 %v", err)
+	}
+	if err := manager.SetFileFormat(tempDir, "logfmt"); err == nil {
+		t.Error("期望不能为目录指定格式")
+	}
+	if len(manager.formatOverrides) != 0 {
+		t.Errorf("无效的路径不应保存格式: %v", manager.formatOverrides)
+	}
+
+	// 指定格式只让该文件的缓存失效
+	ctx := context.Background()
+	for _, p := range []string{kvPath, otherPath} {
+		if _, err := manager.ReadLogFile(ctx, p, 0, 10); err != nil {
+			t.Fatalf("读取日志文件失败: %v", err)
+		}
+	}
+	info, _ := os.Stat(otherPath)
+	otherKey := fmt.Sprintf("file:%s:%d:%d:%d", otherPath, 0, 10, info.ModTime().Unix())
+	if err := manager.SetFileFormat(kvPath, "common"); err != nil {
+		t.Fatalf("指定文件格式失败: %v", err)
+	}
+	if _, found := manager.cache.Get(otherKey); !found {
+		t.Error("指定格式不应清除其他文件的缓存")
+	}
+	if result, err := manager.ReadLogFile(ctx, kvPath, 0, 10); err != nil || result.Format != "Common" {
+		t.Errorf("期望按新指定的 Common 解析: %+v, %v", result, err)
+	}
+
+	// 文件删除后仍然可以清除指定的格式
+	os.Remove(kvPath)
+	if err := manager.SetFileFormat(kvPath, "auto"); err != nil {
+		t.Errorf("清除已删除文件的格式失败: %v", err)
+	}
+	if len(manager.formatOverrides) != 0 {
+		t.Errorf("期望清除指定的格式: %v", manager.formatOverrides)
+	}
+}
+
+func TestLogManager_RequestFormat(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "kv.log")
+	if err := os.WriteFile(logFilePath, []byte("level=info msg=hello\nlevel=warn msg=slow\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	cfg := createTestConfig([]string{tempDir})
+	cfg.Formats = []config.FormatConfig{{Format: "common", Files: []string{"kv*.log"}}}
+	manager := newFormatTestManager(t, cfg)
+
+	// 先按文件格式读取一次，结果进入缓存
+	if result, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10); err != nil || result.Format != "Common" {
+		t.Fatalf("期望按配置的 Common 解析: %+v, %v", result, err)
+	}
+
+	ctx, err := manager.WithFormat(context.Background(), "logfmt")
+	if err != nil {
+		t.Fatalf("指定请求格式失败: %v", err)
+	}
+	result, err := manager.ReadLogFile(ctx, logFilePath, 0, 10)
+	if err != nil || result.Format != "Logfmt" || result.Entries[1].Level != "WARN" {
+		t.Errorf("期望本次请求按 logfmt 解析: %+v, %v", result, err)
+	}
+	if tail, err := manager.ReadLogFileFromTail(ctx, logFilePath, 1); err != nil || tail.Format != "Logfmt" {
+		t.Errorf("期望尾部读取按 logfmt 解析: %+v, %v", tail, err)
+	}
+
+	// 请求指定的格式不保存，之后的读取仍使用文件格式
+	if format := manager.GetFileFormat(logFilePath); format != "Common" {
+		t.Errorf("请求指定的格式不应保存，实际为 %s", format)
+	}
+	if result, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10); err != nil || result.Format != "Common" {
+		t.Errorf("期望之后的读取仍为 Common: %+v, %v", result, err)
+	}
+
+	if _, err := manager.WithFormat(context.Background(), "nope"); err == nil {
+		t.Error("期望未知格式返回错误")
+	}
+}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -107,7 +108,7 @@ func TestLogManager_Integration(t *testing.T) {
 
 	// 测试读取日志文件内容
 	t.Run("ReadLogFile", func(t *testing.T) {
-		content, err := manager.ReadLogFile(appLogPath, 0, 3)
+		content, err := manager.ReadLogFile(context.Background(), appLogPath, 0, 3)
 		if err != nil {
 			t.Fatalf("读取日志文件失败: %v", err)
 		}
@@ -139,13 +140,13 @@ func TestLogManager_Integration(t *testing.T) {
 	// 测试分页读取
 	t.Run("ReadLogFile_Pagination", func(t *testing.T) {
 		// 读取第一页
-		page1, err := manager.ReadLogFile(appLogPath, 0, 2)
+		page1, err := manager.ReadLogFile(context.Background(), appLogPath, 0, 2)
 		if err != nil {
 			t.Fatalf("读取第一页失败: %v", err)
 		}
 
 		// 读取第二页
-		page2, err := manager.ReadLogFile(appLogPath, 2, 2)
+		page2, err := manager.ReadLogFile(context.Background(), appLogPath, 2, 2)
 		if err != nil {
 			t.Fatalf("读取第二页失败: %v", err)
 		}
@@ -211,7 +212,7 @@ func TestLogManager_Integration(t *testing.T) {
 	t.Run("Cache", func(t *testing.T) {
 		// 第一次读取
 		start := time.Now()
-		content1, err := manager.ReadLogFile(accessLogPath, 0, 5)
+		content1, err := manager.ReadLogFile(context.Background(), accessLogPath, 0, 5)
 		if err != nil {
 			t.Fatalf("第一次读取失败: %v", err)
 		}
@@ -219,7 +220,7 @@ func TestLogManager_Integration(t *testing.T) {
 
 		// 第二次读取（应该从缓存获取）
 		start = time.Now()
-		content2, err := manager.ReadLogFile(accessLogPath, 0, 5)
+		content2, err := manager.ReadLogFile(context.Background(), accessLogPath, 0, 5)
 		if err != nil {
 			t.Fatalf("第二次读取失败: %v", err)
 		}
@@ -246,7 +247,7 @@ func TestLogManager_Integration(t *testing.T) {
 	// 测试错误处理
 	t.Run("ErrorHandling", func(t *testing.T) {
 		// 测试读取不存在的文件
-		_, err := manager.ReadLogFile("/nonexistent/file.log", 0, 10)
+		_, err := manager.ReadLogFile(context.Background(), "/nonexistent/file.log", 0, 10)
 		if err == nil {
 			t.Error("读取不存在的文件应该返回错误")
 		}
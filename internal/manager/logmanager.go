@@ -12,14 +12,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/local-log-viewer/internal/alert"
+	"github.com/local-log-viewer/internal/annotation"
 	"github.com/local-log-viewer/internal/cache"
 	"github.com/local-log-viewer/internal/config"
 	"github.com/local-log-viewer/internal/interfaces"
 	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/logmetric"
 	"github.com/local-log-viewer/internal/monitor"
 	"github.com/local-log-viewer/internal/parser"
 	"github.com/local-log-viewer/internal/pool"
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/tracing"
 	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -30,17 +37,58 @@ type LogManager struct {
 	parsers     map[string]interfaces.LogParser
 	cache       interfaces.LogCache
 
+	// 自定义解析器（由配置定义，通过解析器工厂注册，支持热加载）
+	parserFactory *parser.ParserFactory
+	customParsers []interfaces.LogParser
+	parserDefs    []config.ParserConfig
+	parserMutex   sync.RWMutex
+
+	// 按文件检测的日志格式（按路径缓存，文件被替换后重新检测）和API指定的格式
+	formatCache     map[string]detectedFormat
+	formatOverrides map[string]string
+	formatMutex     sync.RWMutex
+
 	// 性能优化组件
 	filePool      *pool.FilePool
 	searchCache   *cache.SearchCache
 	contentCache  *cache.FileContentCache
 	memoryMonitor *monitor.MemoryMonitor
+	searchEngine  *search.SearchEngine
+
+	// 告警规则引擎（评估 WatchFile 产生的日志更新流）和通知分发器
+	alertEngine     *alert.Engine
+	alertDispatcher *alert.Dispatcher
+
+	// 日志指标引擎（从日志更新流和已有内容中提取时间序列）
+	logMetrics *logmetric.Engine
 
-	// 文件监控相关
+	// 日志行标注
+	annotations *annotation.Store
+
+	// 挂载的日志源：配置的每个日志路径（本地目录或 ssh:// 等远程路径）和通过 Mount 挂载的日志源，
+	// 非本地日志源的路径带有源的前缀；不在任何挂载点下的本地路径由 localFS 处理
+	mounts     []sourceMount
+	mountMutex sync.RWMutex
+	localFS    *source.LocalSource
+
+	// 本地日志路径中的 .zip、.tar、.tar.gz 归档，以归档路径为键，归档中的文件路径为 <归档路径>/<成员路径>
+	archives     map[string]*source.ArchiveSource
+	archiveMutex sync.Mutex
+
+	// 文件监控相关，每个文件一个更新源，由 fileWatches 分发给所有订阅者
 	watchedFiles  map[string]chan types.LogUpdate
-	filePositions map[string]int64 // 记录每个文件的读取位置(字节偏移量)
+	fileWatches   map[string]*fileWatch
+	subscriberSeq uint64
+	filePositions map[string]int64  // 记录每个文件的读取位置(字节偏移量)
+	fileSeqs      map[string]uint64 // 每个文件最近一次发送的更新序号
+	seqHistory    map[string][]seqOffset
+	retryPending  map[string]bool        // 更新通道已满、等待重新读取的文件
+	readLocks     map[string]*sync.Mutex // 串行化同一文件的增量读取，读取期间不持有 watchMutex
 	watchMutex    sync.RWMutex
 
+	// 串行化文件监控的建立和停止，不在文件事件回调中持有
+	watchLifecycle sync.Mutex
+
 	// 运行状态
 	running bool
 	stopCh  chan struct{}
@@ -86,34 +134,63 @@ func NewLogManager(cfg *config.Config, fileWatcher interfaces.FileWatcher, logCa
 	}
 	memoryMonitor := monitor.NewMemoryMonitor(memoryConfig)
 
-	return &LogManager{
-		config:        cfg,
-		fileWatcher:   fileWatcher,
-		parsers:       make(map[string]interfaces.LogParser),
-		cache:         logCache,
-		filePool:      filePool,
-		searchCache:   searchCache,
-		contentCache:  contentCache,
-		memoryMonitor: memoryMonitor,
-		watchedFiles:  make(map[string]chan types.LogUpdate),
-		filePositions: make(map[string]int64),
-		stopCh:        make(chan struct{}),
+	lm := &LogManager{
+		config:          cfg,
+		fileWatcher:     fileWatcher,
+		parsers:         make(map[string]interfaces.LogParser),
+		cache:           logCache,
+		parserFactory:   parser.NewParserFactory(),
+		formatCache:     make(map[string]detectedFormat),
+		formatOverrides: make(map[string]string),
+		filePool:        filePool,
+		searchCache:     searchCache,
+		contentCache:    contentCache,
+		memoryMonitor:   memoryMonitor,
+		watchedFiles:    make(map[string]chan types.LogUpdate),
+		fileWatches:     make(map[string]*fileWatch),
+		filePositions:   make(map[string]int64),
+		fileSeqs:        make(map[string]uint64),
+		seqHistory:      make(map[string][]seqOffset),
+		retryPending:    make(map[string]bool),
+		readLocks:       make(map[string]*sync.Mutex),
+		stopCh:          make(chan struct{}),
+		mounts:          newSourceMounts(cfg, fileWatcher),
+		archives:        make(map[string]*source.ArchiveSource),
+	}
+	lm.localFS, _ = source.NewLocalSource("", fileWatcher) // 根路径为空时不会失败
+
+	// 搜索引擎与日志查看使用同一套按文件检测的解析器
+	lm.searchEngine = search.NewSearchEngine(nil, logCache)
+	lm.searchEngine.SetParserResolver(lm.parserForFile)
+	lm.searchEngine.SetSummaryStore(search.NewSummaryStore(cfg.Server.DataDir))
+	lm.searchEngine.SetRemoteFiles(sourceSearchFiles{lm: lm})
+
+	lm.initializeAlerting()
+	lm.initializeLogMetrics()
+
+	annotations, err := annotation.NewStore(cfg.Server.DataDir)
+	if err != nil {
+		logger.Error("加载日志标注失败", zap.Error(err))
 	}
+	// 标注与日志查看使用同一套日志源，远程文件和归档中的文件也可以标注
+	annotations.SetResolver(lm.sourceFor)
+	lm.annotations = annotations
+
+	return lm
 }
 
 // GetLogFiles 获取日志文件列表
 func (lm *LogManager) GetLogFiles() ([]types.LogFile, error) {
 	lm.mutex.RLock()
-	defer lm.mutex.RUnlock()
-
 	var allFiles []types.LogFile
 
-	// 遍历所有配置的日志路径
-	for _, rootPath := range lm.config.Server.LogPaths {
-		absPath, err := filepath.Abs(rootPath)
-		if err != nil {
-			continue // 跳过无效路径
+	// 遍历所有本地日志源（配置的日志路径）
+	for _, m := range lm.mountList() {
+		// 非本地日志源单独构建文件树
+		if !isLocalSource(m.src) {
+			continue
 		}
+		absPath := m.src.Root()
 
 		// 检查路径是否存在
 		info, err := os.Stat(absPath)
@@ -128,6 +205,9 @@ func (lm *LogManager) GetLogFiles() ([]types.LogFile, error) {
 				continue // 跳过扫描失败的目录
 			}
 			allFiles = append(allFiles, files...)
+		} else if source.IsArchive(absPath) {
+			// 如果是归档，添加其中的日志文件
+			allFiles = append(allFiles, lm.archiveLogFiles(absPath)...)
 		} else {
 			// 如果是文件，直接添加
 			logFile := lm.createLogFile(absPath, info)
@@ -135,20 +215,37 @@ func (lm *LogManager) GetLogFiles() ([]types.LogFile, error) {
 		}
 	}
 
-	// 构建树形结构
-	return lm.buildFileTree(allFiles), nil
+	lm.mutex.RUnlock()
+
+	// 构建树形结构，每个非本地日志源是一棵单独的树
+	// 列出非本地日志源需要网络往返，不持有 lm.mutex，慢速的日志源不会阻塞其他操作
+	tree := lm.buildFileTree(allFiles)
+	ctx, cancel := sourceContext()
+	defer cancel()
+	return append(tree, lm.sourceFileTrees(ctx)...), nil
 }
 
 // GetDirectoryFiles 获取指定目录的直接子节点(用于懒加载)
 func (lm *LogManager) GetDirectoryFiles(dirPath string) ([]types.LogFile, error) {
-	lm.mutex.RLock()
-	defer lm.mutex.RUnlock()
+	// 非本地日志源需要网络往返，不持有 lm.mutex 列出，慢速的日志源不会阻塞其他操作
+	ctx, cancel := sourceContext()
+	defer cancel()
 
 	// 如果目录路径为空,返回根目录列表
 	if dirPath == "" {
-		return lm.getRootDirectories()
+		lm.mutex.RLock()
+		roots := lm.getRootDirectories()
+		lm.mutex.RUnlock()
+		return append(roots, lm.sourceRootDirectories(ctx)...), nil
+	}
+
+	if _, local := lm.localPath(dirPath); !local {
+		return lm.sourceDirectoryFiles(ctx, dirPath)
 	}
 
+	lm.mutex.RLock()
+	defer lm.mutex.RUnlock()
+
 	// 检查目录是否存在
 	info, err := os.Stat(dirPath)
 	if err != nil {
@@ -188,41 +285,34 @@ func (lm *LogManager) GetDirectoryFiles(dirPath string) ([]types.LogFile, error)
 				ModTime:     info.ModTime(),
 				IsDirectory: true,
 			})
+		} else if source.IsArchive(fullPath) {
+			// 归档作为目录显示
+			if dir, ok := lm.archiveDirectory(fullPath); ok {
+				files = append(files, dir)
+			}
 		} else if lm.isLogFile(fullPath) {
 			// 如果是日志文件,检查大小限制
 			if info.Size() <= lm.config.Server.MaxFileSize {
-				files = append(files, types.LogFile{
-					Path:        fullPath,
-					Name:        entry.Name(),
-					Size:        info.Size(),
-					ModTime:     info.ModTime(),
-					IsDirectory: false,
-				})
+				files = append(files, lm.createLogFile(fullPath, info))
 			}
 		}
 	}
 
-	// 按名称排序
-	sort.Slice(files, func(i, j int) bool {
-		// 目录排在文件前面
-		if files[i].IsDirectory != files[j].IsDirectory {
-			return files[i].IsDirectory
-		}
-		return files[i].Name < files[j].Name
-	})
+	// 按名称排序，目录排在文件前面
+	sortDirectoryEntries(files)
 
 	return files, nil
 }
 
-// getRootDirectories 获取根目录列表
-func (lm *LogManager) getRootDirectories() ([]types.LogFile, error) {
+// getRootDirectories 获取本地日志源的根目录列表
+func (lm *LogManager) getRootDirectories() []types.LogFile {
 	var roots []types.LogFile
 
-	for _, rootPath := range lm.config.Server.LogPaths {
-		absPath, err := filepath.Abs(rootPath)
-		if err != nil {
+	for _, m := range lm.mountList() {
+		if !isLocalSource(m.src) {
 			continue
 		}
+		absPath := m.src.Root()
 
 		info, err := os.Stat(absPath)
 		if err != nil {
@@ -238,21 +328,20 @@ func (lm *LogManager) getRootDirectories() ([]types.LogFile, error) {
 				ModTime:     info.ModTime(),
 				IsDirectory: true,
 			})
+		} else if source.IsArchive(absPath) {
+			// 归档作为根目录节点
+			if dir, ok := lm.archiveDirectory(absPath); ok {
+				roots = append(roots, dir)
+			}
 		} else if lm.isLogFile(absPath) {
 			// 作为根文件
 			if info.Size() <= lm.config.Server.MaxFileSize {
-				roots = append(roots, types.LogFile{
-					Path:        absPath,
-					Name:        info.Name(),
-					Size:        info.Size(),
-					ModTime:     info.ModTime(),
-					IsDirectory: false,
-				})
+				roots = append(roots, lm.createLogFile(absPath, info))
 			}
 		}
 	}
 
-	return roots, nil
+	return roots
 }
 
 // scanDirectory 递归扫描目录
@@ -272,6 +361,12 @@ func (lm *LogManager) scanDirectory(dirPath string) ([]types.LogFile, error) {
 			return nil
 		}
 
+		// 归档中的日志文件作为归档目录下的文件
+		if !info.IsDir() && source.IsArchive(path) {
+			files = append(files, lm.archiveLogFiles(path)...)
+			return nil
+		}
+
 		// 只处理常见的日志文件扩展名
 		if !info.IsDir() && lm.isLogFile(path) {
 			// 检查文件大小限制
@@ -314,6 +409,14 @@ func (lm *LogManager) isLogFile(path string) bool {
 
 	// 对于没有扩展名的文件，检查是否包含日志关键词
 	if ext == "" {
+		// 常见的syslog文件名，例如 /var/log/messages
+		syslogNames := []string{"messages", "secure", "kern", "daemon", "maillog", "cron"}
+		for _, syslogName := range syslogNames {
+			if name == syslogName {
+				return true
+			}
+		}
+
 		logKeywords := []string{"log", "access", "error", "debug", "info", "warn"}
 		for _, keyword := range logKeywords {
 			if strings.Contains(name, keyword) {
@@ -327,13 +430,17 @@ func (lm *LogManager) isLogFile(path string) bool {
 
 // createLogFile 创建日志文件对象
 func (lm *LogManager) createLogFile(path string, info os.FileInfo) types.LogFile {
-	return types.LogFile{
+	logFile := types.LogFile{
 		Path:        path,
 		Name:        info.Name(),
 		Size:        info.Size(),
 		ModTime:     info.ModTime(),
 		IsDirectory: info.IsDir(),
 	}
+	if !info.IsDir() {
+		logFile.Format = lm.GetFileFormat(path)
+	}
+	return logFile
 }
 
 // buildFileTree 构建文件树形结构
@@ -355,20 +462,7 @@ func (lm *LogManager) buildFileTree(files []types.LogFile) []types.LogFile {
 		dir := filepath.Dir(file.Path)
 
 		// 创建目录节点（如果不存在）
-		if _, exists := dirMap[dir]; !exists {
-			dirInfo, err := os.Stat(dir)
-			if err == nil {
-				dirFile := types.LogFile{
-					Path:        dir,
-					Name:        filepath.Base(dir),
-					Size:        0,
-					ModTime:     dirInfo.ModTime(),
-					IsDirectory: true,
-					Children:    []types.LogFile{},
-				}
-				dirMap[dir] = &dirFile
-			}
-		}
+		lm.addTreeDirectory(dirMap, dir)
 
 		// 将文件添加到对应目录
 		if dirNode, exists := dirMap[dir]; exists {
@@ -376,8 +470,15 @@ func (lm *LogManager) buildFileTree(files []types.LogFile) []types.LogFile {
 		}
 	}
 
-	// 构建根节点列表
+	// 构建根节点列表，子目录先于上级目录处理，复制到上级目录时子目录的内容已经完整
+	dirs := make([]*types.LogFile, 0, len(dirMap))
 	for _, dirFile := range dirMap {
+		dirs = append(dirs, dirFile)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].Path) > len(dirs[j].Path)
+	})
+	for _, dirFile := range dirs {
 		parentDir := filepath.Dir(dirFile.Path)
 		if parentNode, exists := dirMap[parentDir]; exists && parentDir != dirFile.Path {
 			// 这是子目录，添加到父目录
@@ -393,17 +494,63 @@ func (lm *LogManager) buildFileTree(files []types.LogFile) []types.LogFile {
 		return files
 	}
 
+	sort.Slice(roots, func(i, j int) bool {
+		return roots[i].Path < roots[j].Path
+	})
 	return roots
 }
 
+// addTreeDirectory 创建目录节点（如果不存在）；归档和归档中的目录同样是目录节点，
+// 并逐级创建上级目录直到归档所在的本地目录，使归档中的文件挂在归档节点下
+func (lm *LogManager) addTreeDirectory(dirMap map[string]*types.LogFile, dir string) {
+	if _, exists := dirMap[dir]; exists {
+		return
+	}
+
+	if dirFile, ok := lm.archiveDirectory(dir); ok {
+		dirFile.Children = []types.LogFile{}
+		dirMap[dir] = &dirFile
+		lm.addTreeDirectory(dirMap, filepath.Dir(dir))
+		return
+	}
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return
+	}
+	dirMap[dir] = &types.LogFile{
+		Path:        dir,
+		Name:        filepath.Base(dir),
+		Size:        0,
+		ModTime:     dirInfo.ModTime(),
+		IsDirectory: true,
+		Children:    []types.LogFile{},
+	}
+}
+
 // ReadLogFile 读取日志文件内容
-func (lm *LogManager) ReadLogFile(path string, offset int64, limit int) (*types.LogContent, error) {
+func (lm *LogManager) ReadLogFile(ctx context.Context, path string, offset int64, limit int) (content *types.LogContent, err error) {
+	ctx, span := tracing.Start(ctx, "manager.ReadLogFile",
+		attribute.String("file.path", path),
+		attribute.Int64("read.offset", offset),
+		attribute.Int("read.limit", limit))
+	defer func() { tracing.End(span, err) }()
+
 	// 检查内存压力
 	if lm.memoryMonitor.IsMemoryPressure() {
 		// 内存压力大时，清理部分缓存
 		lm.cache.Clear()
 	}
 
+	// 非本地文件通过所属的日志源读取，本地文件使用文件池
+	if _, local := lm.localPath(path); !local {
+		content, err = lm.readSourceLogFile(ctx, path, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		return lm.annotateContent(path, content), nil
+	}
+
 	// 检查文件是否存在
 	info, err := os.Stat(path)
 	if err != nil {
@@ -416,15 +563,17 @@ func (lm *LogManager) ReadLogFile(path string, offset int64, limit int) (*types.
 	}
 
 	// 尝试从缓存获取，包含文件修改时间以确保缓存失效
-	cacheKey := fmt.Sprintf("file:%s:%d:%d:%d", path, offset, limit, info.ModTime().Unix())
+	cacheKey := fmt.Sprintf("file:%s:%d:%d:%d", path, offset, limit, info.ModTime().Unix()) + lm.formatSuffix(ctx, path)
 	if cached, found := lm.cache.Get(cacheKey); found {
 		if content, ok := cached.(*types.LogContent); ok {
-			return content, nil
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return lm.annotateContent(path, content), nil
 		}
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
 	// 使用文件池获取文件资源
-	fileResource, err := lm.filePool.GetFileResource(path)
+	fileResource, err := lm.getFileResource(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取文件资源失败: %w", err)
 	}
@@ -436,7 +585,7 @@ func (lm *LogManager) ReadLogFile(path string, offset int64, limit int) (*types.
 	}
 
 	// 流式读取文件内容
-	content, err := lm.readFileContentOptimized(fileResource, offset, limit)
+	content, err = lm.readFileContentOptimized(ctx, path, fileResource, offset, limit)
 	if err != nil {
 		return nil, fmt.Errorf("读取文件内容失败: %w", err)
 	}
@@ -446,7 +595,15 @@ func (lm *LogManager) ReadLogFile(path string, offset int64, limit int) (*types.
 		lm.cache.Set(cacheKey, content)
 	}
 
-	return content, nil
+	return lm.annotateContent(path, content), nil
+}
+
+// getFileResource 从文件池获取文件资源，等待时间记录在单独的 span 中
+func (lm *LogManager) getFileResource(ctx context.Context, path string) (*pool.FileResource, error) {
+	_, span := tracing.Start(ctx, "pool.GetFileResource", attribute.String("file.path", path))
+	fileResource, err := lm.filePool.GetFileResource(path)
+	tracing.End(span, err)
+	return fileResource, err
 }
 
 // readFileContent 流式读取文件内容
@@ -464,7 +621,7 @@ func (lm *LogManager) readFileContent(file *os.File, offset int64, limit int) (*
 	}
 
 	// 创建新的扫描器
-	scanner := bufio.NewScanner(file)
+	scanner := search.NewLineScanner(file, 4096, bufio.MaxScanTokenSize)
 
 	// 跳过指定行数
 	for i := int64(0); i < offset && scanner.Scan(); i++ {
@@ -474,27 +631,16 @@ func (lm *LogManager) readFileContent(file *os.File, offset int64, limit int) (*
 		return nil, err
 	}
 
+	// 整个文件使用同一个解析器
+	fileParser := lm.parserForFile(file.Name())
+
 	// 读取指定数量的行
 	var entries []types.LogEntry
 	lineNum := offset
 
 	for i := 0; i < limit && scanner.Scan(); i++ {
-		line := scanner.Text()
-
-		// 创建日志条目
-		entry := types.LogEntry{
-			Raw:     line,
-			LineNum: lineNum,
-		}
-
-		// 尝试解析日志行
-		if parser := lm.findParser(line); parser != nil {
-			if parsed, err := parser.Parse(line); err == nil {
-				entry = *parsed
-				entry.LineNum = lineNum
-			}
-		}
-
+		entry := lm.parseLine(fileParser, scanner.Text(), lineNum)
+		entry.ByteOffset = scanner.Offset()
 		entries = append(entries, entry)
 		lineNum++
 	}
@@ -511,6 +657,7 @@ func (lm *LogManager) readFileContent(file *os.File, offset int64, limit int) (*
 		TotalLines: totalLines,
 		HasMore:    hasMore,
 		Offset:     offset,
+		Format:     fileParser.GetFormat(),
 	}, nil
 }
 
@@ -545,12 +692,15 @@ func (lm *LogManager) countLines(file *os.File) (int64, error) {
 }
 
 // readFileContentOptimized 优化的流式读取文件内容
-func (lm *LogManager) readFileContentOptimized(fileResource *pool.FileResource, offset int64, limit int) (*types.LogContent, error) {
+func (lm *LogManager) readFileContentOptimized(ctx context.Context, path string, fileResource *pool.FileResource, offset int64, limit int) (*types.LogContent, error) {
 	file := fileResource.GetFile()
 	reader := fileResource.GetReader()
 
 	// 计算总行数（估算）
+	_, countSpan := tracing.Start(ctx, "manager.countLines", attribute.String("file.path", path))
 	totalLines, err := lm.countLines(file)
+	countSpan.SetAttributes(attribute.Int64("file.lines", totalLines))
+	tracing.End(countSpan, err)
 	if err != nil {
 		return nil, err
 	}
@@ -563,9 +713,7 @@ func (lm *LogManager) readFileContentOptimized(fileResource *pool.FileResource,
 	reader.Reset(file)
 
 	// 创建新的扫描器，使用更大的缓冲区
-	scanner := bufio.NewScanner(reader)
-	buf := make([]byte, 0, 64*1024) // 64KB 缓冲区
-	scanner.Buffer(buf, 1024*1024)  // 最大1MB行长度
+	scanner := search.NewLineScanner(reader, 64*1024, 1024*1024) // 64KB 缓冲区，最大1MB行长度
 
 	// 跳过指定行数
 	for i := int64(0); i < offset && scanner.Scan(); i++ {
@@ -575,30 +723,24 @@ func (lm *LogManager) readFileContentOptimized(fileResource *pool.FileResource,
 		return nil, err
 	}
 
-	// 读取指定数量的行
+	// 整个文件使用同一个解析器
+	fileParser := lm.requestParser(ctx, path)
+
+	// 读取并解析指定数量的行
+	_, parseSpan := tracing.Start(ctx, "parser.Parse",
+		attribute.String("file.path", path),
+		attribute.String("parser.format", fileParser.GetFormat()))
 	var entries []types.LogEntry
 	lineNum := offset
 
 	for i := 0; i < limit && scanner.Scan(); i++ {
-		line := scanner.Text()
-
-		// 创建日志条目
-		entry := types.LogEntry{
-			Raw:     line,
-			LineNum: lineNum,
-		}
-
-		// 尝试解析日志行
-		if parser := lm.findParser(line); parser != nil {
-			if parsed, err := parser.Parse(line); err == nil {
-				entry = *parsed
-				entry.LineNum = lineNum
-			}
-		}
-
+		entry := lm.parseLine(fileParser, scanner.Text(), lineNum)
+		entry.ByteOffset = scanner.Offset()
 		entries = append(entries, entry)
 		lineNum++
 	}
+	parseSpan.SetAttributes(attribute.Int("parser.lines", len(entries)))
+	tracing.End(parseSpan, scanner.Err())
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
@@ -612,25 +754,21 @@ func (lm *LogManager) readFileContentOptimized(fileResource *pool.FileResource,
 		TotalLines: totalLines,
 		HasMore:    hasMore,
 		Offset:     offset,
+		Format:     fileParser.GetFormat(),
 	}, nil
 }
 
-// findParser 查找合适的解析器
-func (lm *LogManager) findParser(line string) interfaces.LogParser {
-	for _, parser := range lm.parsers {
-		if parser.CanParse(line) {
-			return parser
-		}
-	}
-	return nil
-}
-
 // SearchLogs 搜索日志内容
-func (lm *LogManager) SearchLogs(query types.SearchQuery) (*types.SearchResult, error) {
+func (lm *LogManager) SearchLogs(ctx context.Context, query types.SearchQuery) (*types.SearchResult, error) {
+	_, span := tracing.Start(ctx, "manager.SearchLogs", attribute.String("file.path", query.Path))
+	defer span.End()
+
 	// 尝试从搜索缓存获取结果
 	if result, found := lm.searchCache.Get(query); found {
-		return result, nil
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return lm.annotateSearchResult(query.Path, result), nil
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
 	// 检查内存压力
 	if lm.memoryMonitor.IsMemoryPressure() {
@@ -640,6 +778,23 @@ func (lm *LogManager) SearchLogs(query types.SearchQuery) (*types.SearchResult,
 		}
 	}
 
+	// 非本地文件由搜索引擎通过所属的日志源扫描
+	if _, local := lm.localPath(query.Path); !local {
+		// 日志源中的目录（例如对象存储的前缀）同时搜索其下的所有文件
+		paths, isDir, err := lm.sourceSearchPaths(ctx, query.Path)
+		if err != nil {
+			return nil, err
+		}
+		if isDir {
+			return lm.searchEngine.SearchFiles(ctx, paths, query, sourceSearchConcurrency)
+		}
+		result, err := lm.searchEngine.Search(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return lm.annotateSearchResult(query.Path, result), nil
+	}
+
 	// 执行搜索（这里是基本实现，实际搜索逻辑在搜索引擎中）
 	result := &types.SearchResult{
 		Entries:    []types.LogEntry{},
@@ -653,62 +808,17 @@ func (lm *LogManager) SearchLogs(query types.SearchQuery) (*types.SearchResult,
 		lm.searchCache.Set(query, result)
 	}
 
-	return result, nil
+	return lm.annotateSearchResult(query.Path, result), nil
 }
 
-// WatchFile 监控文件变化
-func (lm *LogManager) WatchFile(path string) (<-chan types.LogUpdate, error) {
-	lm.watchMutex.Lock()
-	defer lm.watchMutex.Unlock()
-
-	// 使用传入的路径作为完整路径（不再重复拼接）
-	fullPath := path
-	logger.Debug("WatchFile called",
-		zap.String("requested_path", path),
-		zap.String("full_path", fullPath))
-
-	// 检查是否已经在监控
-	if ch, exists := lm.watchedFiles[fullPath]; exists {
-		logger.Debug("File already being watched", zap.String("path", fullPath))
-		return ch, nil
-	}
-
-	// 初始化文件位置为文件末尾，只读取新增内容
-	if _, exists := lm.filePositions[fullPath]; !exists {
-		if fileInfo, err := os.Stat(fullPath); err == nil {
-			lm.filePositions[fullPath] = fileInfo.Size()
-			logger.Debug("初始化文件读取位置",
-				zap.String("path", fullPath),
-				zap.Int64("position", fileInfo.Size()))
-		} else {
-			// 如果获取文件信息失败，设置为 0
-			lm.filePositions[fullPath] = 0
-		}
-	}
-
-	// 创建更新通道
-	updateCh := make(chan types.LogUpdate, 100)
-	lm.watchedFiles[fullPath] = updateCh
-
-	// 设置文件监控回调
-	err := lm.fileWatcher.WatchFile(fullPath, func(event types.FileEvent) {
-		logger.Debug("File event received",
-			zap.String("path", fullPath),
-			zap.String("event_type", event.Type))
-		lm.handleFileEvent(fullPath, event, updateCh)
-	})
-
-	if err != nil {
-		delete(lm.watchedFiles, fullPath)
-		close(updateCh)
-		logger.Error("Failed to watch file",
-			zap.String("path", fullPath),
-			zap.Error(err))
-		return nil, fmt.Errorf("监控文件失败: %w", err)
-	}
+// GetFacets 统计文件中字段的取值分布
+func (lm *LogManager) GetFacets(ctx context.Context, query types.FacetQuery) (*types.FacetResult, error) {
+	return lm.searchEngine.Facets(ctx, query)
+}
 
-	logger.Info("Started watching file", zap.String("path", fullPath))
-	return updateCh, nil
+// GetHistogram 按时间分桶统计日志量
+func (lm *LogManager) GetHistogram(ctx context.Context, query types.HistogramQuery) (*types.HistogramResult, error) {
+	return lm.searchEngine.Histogram(ctx, query)
 }
 
 // handleFileEvent 处理文件事件
@@ -719,16 +829,16 @@ func (lm *LogManager) handleFileEvent(path string, event types.FileEvent, update
 		lm.handleFileModify(path, updateCh)
 	case "delete":
 		// 文件被删除
-		update := types.LogUpdate{
-			Path:    path,
-			Entries: []types.LogEntry{},
-			Type:    "delete",
-		}
-		select {
-		case updateCh <- update:
-		default:
-			// 通道已满，跳过
-		}
+		lm.watchMutex.Lock()
+		position := lm.filePositions[path]
+		lm.sendUpdateLocked(updateCh, types.LogUpdate{
+			Path:        path,
+			Entries:     []types.LogEntry{},
+			Type:        "delete",
+			StartOffset: position,
+			EndOffset:   position,
+		})
+		lm.watchMutex.Unlock()
 	case "create":
 		// 文件被创建，读取全部内容
 		lm.handleFileCreate(path, updateCh)
@@ -736,32 +846,66 @@ func (lm *LogManager) handleFileEvent(path string, event types.FileEvent, update
 }
 
 // handleFileModify 处理文件修改事件
+// 同一文件的读取由 readLocks 串行化；watchMutex 只在读取位置快照和提交更新时持有，
+// 远程日志源的往返和解析不会阻塞其他文件的事件
 func (lm *LogManager) handleFileModify(path string, updateCh chan types.LogUpdate) {
-	// 使用互斥锁保护整个读取-更新过程,保证原子性
-	// 这是最简单可靠的方案,类似 tail -f 的串行处理
 	lm.watchMutex.Lock()
-	defer lm.watchMutex.Unlock()
+	// 管理器已停止或通道已被替换，通道可能已经关闭
+	if lm.watchedFiles[path] != updateCh {
+		lm.watchMutex.Unlock()
+		return
+	}
+	readLock, exists := lm.readLocks[path]
+	if !exists {
+		readLock = &sync.Mutex{}
+		lm.readLocks[path] = readLock
+	}
+	lm.watchMutex.Unlock()
+
+	readLock.Lock()
+	defer readLock.Unlock()
+
+	lm.watchMutex.Lock()
+	if lm.watchedFiles[path] != updateCh {
+		lm.watchMutex.Unlock()
+		return
+	}
+	// 通道已满时不读取，保留读取位置，稍后从同一位置重新读取，新增内容不会丢失
+	if len(updateCh) == cap(updateCh) {
+		lm.scheduleUpdateRetryLocked(path, updateCh)
+		lm.watchMutex.Unlock()
+		return
+	}
+	// 获取上次读取的位置，readLock 保证读取期间不会被其他读取修改
+	lastPosition := lm.filePositions[path]
+	lm.watchMutex.Unlock()
 
 	// 获取文件信息
-	fileInfo, err := os.Stat(path)
+	currentSize, err := lm.fileSize(path)
 	if err != nil {
 		logger.Error("获取文件信息失败", zap.String("path", path), zap.Error(err))
 		return
 	}
 
-	currentSize := fileInfo.Size()
-
-	// 获取上次读取的位置
-	lastPosition := lm.filePositions[path]
-
-	// 处理文件截断或轮转的情况
+	// 处理文件截断或轮转的情况，通知客户端字节位置从头开始
 	if currentSize < lastPosition {
 		logger.Info("检测到文件被截断或轮转",
 			zap.String("path", path),
 			zap.Int64("lastPosition", lastPosition),
 			zap.Int64("currentSize", currentSize))
-		lastPosition = 0
+		lm.watchMutex.Lock()
+		if !lm.sendUpdateLocked(updateCh, types.LogUpdate{
+			Path:    path,
+			Entries: []types.LogEntry{},
+			Type:    "truncate",
+		}) {
+			lm.scheduleUpdateRetryLocked(path, updateCh)
+			lm.watchMutex.Unlock()
+			return
+		}
 		lm.filePositions[path] = 0
+		lm.watchMutex.Unlock()
+		lastPosition = 0
 	}
 
 	// 如果没有新内容，直接返回
@@ -773,57 +917,22 @@ func (lm *LogManager) handleFileModify(path string, updateCh chan types.LogUpdat
 		return
 	}
 
-	// 打开文件
-	file, err := os.Open(path)
-	if err != nil {
-		logger.Error("打开文件失败", zap.String("path", path), zap.Error(err))
-		return
-	}
-	defer file.Close()
-
-	// 定位到上次读取的位置
-	_, err = file.Seek(lastPosition, io.SeekStart)
+	// 从上次读取的位置打开文件
+	file, err := lm.openFrom(path, lastPosition)
 	if err != nil {
-		logger.Error("定位文件位置失败",
+		logger.Error("打开文件失败",
 			zap.String("path", path),
 			zap.Int64("position", lastPosition),
 			zap.Error(err))
 		return
 	}
-
-	// 使用缓冲读取器读取所有新增内容
-	reader := bufio.NewReader(file)
-	scanner := bufio.NewScanner(reader)
-
-	// 设置较大的缓冲区
-	buf := make([]byte, 0, 64*1024) // 64KB
-	scanner.Buffer(buf, 1024*1024)  // 最大 1MB 行长度
-
-	var entries []types.LogEntry
+	defer file.Close()
 
 	// 读取所有新增的行,不设置行数限制
 	// 这是 tail -f 的核心行为:每次读取从 lastPosition 到 EOF 的所有内容
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 创建日志条目
-		entry := types.LogEntry{
-			Raw:     line,
-			LineNum: -1, // 实时更新不需要行号
-		}
-
-		// 尝试解析日志行
-		if parser := lm.findParser(line); parser != nil {
-			if parsed, err := parser.Parse(line); err == nil {
-				entry = *parsed
-				entry.LineNum = -1
-			}
-		}
-
-		entries = append(entries, entry)
-	}
-
-	if err := scanner.Err(); err != nil {
+	counter := &countingReader{r: file}
+	entries, err := lm.parseLines(path, bufio.NewReader(counter), lastPosition)
+	if err != nil {
 		logger.Error("读取文件内容失败", zap.String("path", path), zap.Error(err))
 		return
 	}
@@ -834,21 +943,16 @@ func (lm *LogManager) handleFileModify(path string, updateCh chan types.LogUpdat
 		return
 	}
 
-	// 更新文件读取位置
-	newPosition, err := file.Seek(0, io.SeekCurrent)
-	if err != nil {
-		logger.Error("获取文件当前位置失败", zap.String("path", path), zap.Error(err))
-		// 即使失败也继续，使用文件大小作为新位置
-		newPosition = currentSize
-	}
-
-	lm.filePositions[path] = newPosition
+	// 新的文件读取位置
+	newPosition := lastPosition + counter.n
 
 	// 构造更新消息
 	update := types.LogUpdate{
-		Path:    path,
-		Entries: entries,
-		Type:    "append",
+		Path:        path,
+		Entries:     entries,
+		Type:        "append",
+		StartOffset: lastPosition,
+		EndOffset:   newPosition,
 	}
 
 	logger.Debug("发送文件更新",
@@ -858,17 +962,19 @@ func (lm *LogManager) handleFileModify(path string, updateCh chan types.LogUpdat
 		zap.Int64("newPosition", newPosition))
 
 	// 发送更新(使用非阻塞 select,避免在锁内长时间等待)
-	select {
-	case updateCh <- update:
-		logger.Debug("文件更新已发送", zap.String("path", path))
-	default:
-		logger.Warn("更新通道已满，跳过更新", zap.String("path", path))
+	// 发送失败时不更新读取位置，稍后重新读取；读取期间监控已停止时 sendUpdateLocked 不会发送
+	lm.watchMutex.Lock()
+	defer lm.watchMutex.Unlock()
+	if !lm.sendUpdateLocked(updateCh, update) {
+		lm.scheduleUpdateRetryLocked(path, updateCh)
+		return
 	}
+	lm.filePositions[path] = newPosition
 }
 
 // handleFileCreate 处理文件创建事件
 func (lm *LogManager) handleFileCreate(path string, updateCh chan types.LogUpdate) {
-	content, err := lm.ReadLogFile(path, 0, 100)
+	content, err := lm.ReadLogFile(context.Background(), path, 0, 100)
 	if err != nil {
 		return
 	}
@@ -878,14 +984,120 @@ func (lm *LogManager) handleFileCreate(path string, updateCh chan types.LogUpdat
 		Entries: content.Entries,
 		Type:    "create",
 	}
+	if n := len(content.Entries); n > 0 {
+		last := content.Entries[n-1]
+		update.EndOffset = last.ByteOffset + int64(len(last.Raw)) + 1
+	}
 
+	lm.watchMutex.Lock()
+	defer lm.watchMutex.Unlock()
+	lm.sendUpdateLocked(updateCh, update)
+}
+
+// parseLines 解析读取器中的所有行，base 为读取器起始位置在文件中的字节偏移量
+func (lm *LogManager) parseLines(path string, r io.Reader, base int64) ([]types.LogEntry, error) {
+	scanner := search.NewLineScanner(r, 64*1024, 1024*1024) // 64KB 缓冲区，最大 1MB 行长度
+	fileParser := lm.parserForFile(path)
+
+	var entries []types.LogEntry
+	for scanner.Scan() {
+		// 实时更新不需要行号
+		entry := lm.parseLine(fileParser, scanner.Text(), -1)
+		entry.ByteOffset = base + scanner.Offset()
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// fileSize 通过文件所属的日志源获取文件当前大小
+func (lm *LogManager) fileSize(path string) (int64, error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := sourceContext()
+	defer cancel()
+	info, err := src.Stat(ctx, resolved)
+	if err != nil {
+		return 0, err
+	}
+	return sourceSize(ctx, src, info)
+}
+
+// openFrom 通过文件所属的日志源从指定的字节位置打开文件，读取到文件末尾
+func (lm *LogManager) openFrom(path string, offset int64) (io.ReadCloser, error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := sourceContext()
+	r, err := src.Open(ctx, resolved, offset, -1)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelReadCloser{ReadCloser: r, cancel: cancel}, nil
+}
+
+// countingReader 记录已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// cancelReadCloser 关闭时同时取消读取使用的上下文
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// sendUpdateLocked 为更新分配序号并以非阻塞方式发送，调用方需持有 watchMutex
+// 通道已满时返回 false，序号不会被占用
+func (lm *LogManager) sendUpdateLocked(updateCh chan types.LogUpdate, update types.LogUpdate) bool {
+	if lm.watchedFiles[update.Path] != updateCh {
+		return false
+	}
+	update.Seq = lm.fileSeqs[update.Path] + 1
 	select {
 	case updateCh <- update:
+		lm.fileSeqs[update.Path] = update.Seq
+		lm.recordSeqLocked(update)
+		logger.Debug("文件更新已发送", zap.String("path", update.Path), zap.Uint64("seq", update.Seq))
+		return true
 	default:
-		// 通道已满，跳过
+		logger.Warn("更新通道已满，稍后重试",
+			zap.String("path", update.Path),
+			zap.String("type", update.Type))
+		return false
 	}
 }
 
+// scheduleUpdateRetryLocked 在更新通道有空位后重新读取新增内容，调用方需持有 watchMutex
+func (lm *LogManager) scheduleUpdateRetryLocked(path string, updateCh chan types.LogUpdate) {
+	if lm.retryPending[path] {
+		return
+	}
+	lm.retryPending[path] = true
+	time.AfterFunc(updateRetryDelay, func() {
+		lm.watchMutex.Lock()
+		delete(lm.retryPending, path)
+		lm.watchMutex.Unlock()
+		lm.handleFileModify(path, updateCh)
+	})
+}
+
 // Start 启动日志管理器
 func (lm *LogManager) Start() error {
 	lm.mutex.Lock()
@@ -902,7 +1114,30 @@ func (lm *LogManager) Start() error {
 	}
 
 	// 初始化日志解析器
-	lm.initializeParsers()
+	if err := lm.initializeParsers(); err != nil {
+		lm.memoryMonitor.Stop()
+		return fmt.Errorf("初始化日志解析器失败: %w", err)
+	}
+
+	// 监控配置文件中的解析器定义，支持热加载
+	if lm.config.ConfigPath != "" {
+		go lm.watchParserConfig(lm.config.ConfigPath, parserConfigCheckInterval)
+	}
+
+	// 启动告警引擎和通知分发器
+	if err := lm.startAlerting(); err != nil {
+		lm.memoryMonitor.Stop()
+		return err
+	}
+
+	// 启动日志指标引擎
+	if lm.logMetrics != nil {
+		if err := lm.logMetrics.Start(); err != nil {
+			lm.stopAlerting()
+			lm.memoryMonitor.Stop()
+			return fmt.Errorf("启动日志指标引擎失败: %w", err)
+		}
+	}
 
 	lm.running = true
 	return nil
@@ -917,6 +1152,18 @@ func (lm *LogManager) Stop() error {
 		return nil
 	}
 
+	// 停止告警引擎和通知分发器
+	if err := lm.stopAlerting(); err != nil {
+		return err
+	}
+
+	// 停止日志指标引擎
+	if lm.logMetrics != nil {
+		if err := lm.logMetrics.Stop(); err != nil {
+			return fmt.Errorf("停止日志指标引擎失败: %w", err)
+		}
+	}
+
 	// 停止内存监控器
 	if err := lm.memoryMonitor.Stop(); err != nil {
 		return fmt.Errorf("停止内存监控器失败: %w", err)
@@ -926,15 +1173,24 @@ func (lm *LogManager) Stop() error {
 	if err := lm.filePool.Close(); err != nil {
 		return fmt.Errorf("关闭文件池失败: %w", err)
 	}
+	if err := lm.searchEngine.Close(); err != nil {
+		return fmt.Errorf("关闭搜索引擎失败: %w", err)
+	}
 
-	// 关闭所有监控通道
+	// 关闭所有监控通道，分发 goroutine 随后关闭所有订阅者的通道
 	lm.watchMutex.Lock()
 	for path, ch := range lm.watchedFiles {
 		close(ch)
 		delete(lm.watchedFiles, path)
+		delete(lm.fileWatches, path)
+		delete(lm.readLocks, path)
 	}
 	lm.watchMutex.Unlock()
 
+	// 关闭远程日志源，停止远程文件的跟踪
+	lm.closeSources()
+	lm.closeArchives()
+
 	// 清空缓存
 	lm.cache.Clear()
 
@@ -945,26 +1201,45 @@ func (lm *LogManager) Stop() error {
 }
 
 // initializeParsers 初始化日志解析器
-func (lm *LogManager) initializeParsers() {
+func (lm *LogManager) initializeParsers() error {
+	lm.parserMutex.Lock()
 	// 添加通用日志解析器
 	lm.parsers["common"] = parser.NewCommonLogParser()
 
 	// 添加JSON日志解析器
 	lm.parsers["json"] = parser.NewJSONLogParser()
+
+	// 添加Docker json-file日志解析器
+	lm.parsers["docker"] = parser.NewDockerLogParser()
+
+	// 添加systemd journal日志解析器
+	lm.parsers["journal"] = parser.NewJournalLogParser()
+
+	// 添加logfmt日志解析器
+	lm.parsers["logfmt"] = parser.NewLogfmtLogParser()
+
+	// 添加Syslog日志解析器
+	lm.parsers["syslog"] = parser.NewSyslogParser()
+	lm.parserMutex.Unlock()
+
+	// 注册配置定义的自定义解析器
+	return lm.ReloadParsers(lm.config.Parsers)
 }
 
 // AddParser 添加日志解析器
 func (lm *LogManager) AddParser(name string, parser interfaces.LogParser) {
-	lm.mutex.Lock()
-	defer lm.mutex.Unlock()
+	lm.parserMutex.Lock()
 	lm.parsers[name] = parser
+	lm.parserMutex.Unlock()
+	lm.resetFormatCache()
 }
 
 // RemoveParser 移除日志解析器
 func (lm *LogManager) RemoveParser(name string) {
-	lm.mutex.Lock()
-	defer lm.mutex.Unlock()
+	lm.parserMutex.Lock()
 	delete(lm.parsers, name)
+	lm.parserMutex.Unlock()
+	lm.resetFormatCache()
 }
 
 // GetPerformanceStats 获取性能统计信息
@@ -1007,12 +1282,26 @@ func (lm *LogManager) OptimizePerformance() error {
 }
 
 // ReadLogFileFromTail 从文件尾部读取日志内容
-func (lm *LogManager) ReadLogFileFromTail(path string, lines int) (*types.LogContent, error) {
+func (lm *LogManager) ReadLogFileFromTail(ctx context.Context, path string, lines int) (content *types.LogContent, err error) {
+	ctx, span := tracing.Start(ctx, "manager.ReadLogFileFromTail",
+		attribute.String("file.path", path),
+		attribute.Int("read.lines", lines))
+	defer func() { tracing.End(span, err) }()
+
 	// 检查内存压力
 	if lm.memoryMonitor.IsMemoryPressure() {
 		lm.cache.Clear()
 	}
 
+	// 非本地文件通过所属的日志源读取，本地文件使用文件池
+	if _, local := lm.localPath(path); !local {
+		content, err = lm.readSourceTail(ctx, path, lines)
+		if err != nil {
+			return nil, err
+		}
+		return lm.annotateContent(path, content), nil
+	}
+
 	// 检查文件是否存在
 	info, err := os.Stat(path)
 	if err != nil {
@@ -1025,22 +1314,24 @@ func (lm *LogManager) ReadLogFileFromTail(path string, lines int) (*types.LogCon
 	}
 
 	// 检查缓存
-	cacheKey := fmt.Sprintf("tail:%s:%d:%d", path, lines, info.ModTime().Unix())
+	cacheKey := fmt.Sprintf("tail:%s:%d:%d", path, lines, info.ModTime().Unix()) + lm.formatSuffix(ctx, path)
 	if cached, found := lm.cache.Get(cacheKey); found {
 		if content, ok := cached.(*types.LogContent); ok {
-			return content, nil
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return lm.annotateContent(path, content), nil
 		}
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
 	// 使用文件池获取文件资源
-	fileResource, err := lm.filePool.GetFileResource(path)
+	fileResource, err := lm.getFileResource(ctx, path)
 	if err != nil {
 		return nil, fmt.Errorf("获取文件资源失败: %w", err)
 	}
 	defer lm.filePool.PutFileResource(path, fileResource)
 
 	// 从文件尾部读取内容
-	content, err := lm.readFromTailOptimized(fileResource, lines)
+	content, err = lm.readFromTailOptimized(ctx, path, fileResource, lines)
 	if err != nil {
 		return nil, fmt.Errorf("读取文件尾部内容失败: %w", err)
 	}
@@ -1050,11 +1341,11 @@ func (lm *LogManager) ReadLogFileFromTail(path string, lines int) (*types.LogCon
 		lm.cache.Set(cacheKey, content)
 	}
 
-	return content, nil
+	return lm.annotateContent(path, content), nil
 }
 
 // readFromTailOptimized 优化的从文件尾部读取
-func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lines int) (*types.LogContent, error) {
+func (lm *LogManager) readFromTailOptimized(ctx context.Context, path string, fileResource *pool.FileResource, lines int) (*types.LogContent, error) {
 	file := fileResource.GetFile()
 	reader := fileResource.GetReader()
 
@@ -1095,12 +1386,11 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 	}
 	reader.Reset(file)
 
-	// 创建扫描器
-	scanner := bufio.NewScanner(reader)
-	buf := make([]byte, 0, 64*1024) // 64KB缓冲区
-	scanner.Buffer(buf, 1024*1024)  // 最大1MB行长度
+	// 创建扫描器，同时记录每行相对于读取位置的字节偏移
+	scanner := search.NewLineScanner(reader, 64*1024, 1024*1024) // 64KB缓冲区，最大1MB行长度
 
 	var allLines []string
+	var allOffsets []int64
 	lineNum := int64(0)
 
 	// 如果不是从文件开头开始读取，跳过第一行（可能是不完整的）
@@ -1111,6 +1401,7 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 	// 读取所有行
 	for scanner.Scan() {
 		allLines = append(allLines, scanner.Text())
+		allOffsets = append(allOffsets, startPos+scanner.Offset())
 		lineNum++
 	}
 
@@ -1138,10 +1429,10 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 				return nil, err
 			}
 			reader.Reset(file)
-			scanner = bufio.NewScanner(reader)
-			scanner.Buffer(buf, 1024*1024)
+			scanner = search.NewLineScanner(reader, 64*1024, 1024*1024)
 
 			allLines = []string{}
+			allOffsets = nil
 
 			// 如果不是从文件开头开始，跳过第一行
 			if newStartPos > 0 && scanner.Scan() {
@@ -1150,6 +1441,7 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 
 			for scanner.Scan() {
 				allLines = append(allLines, scanner.Text())
+				allOffsets = append(allOffsets, newStartPos+scanner.Offset())
 			}
 
 			if err := scanner.Err(); err != nil {
@@ -1172,6 +1464,7 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 	}
 
 	resultLines := allLines[startIndex:]
+	resultOffsets := allOffsets[startIndex:]
 	entries := make([]types.LogEntry, 0, len(resultLines))
 
 	// 计算起始行号
@@ -1180,29 +1473,25 @@ func (lm *LogManager) readFromTailOptimized(fileResource *pool.FileResource, lin
 		startLineNum = 0
 	}
 
-	// 创建日志条目
+	// 创建日志条目，整个文件使用同一个解析器
+	fileParser := lm.requestParser(ctx, path)
+	_, parseSpan := tracing.Start(ctx, "parser.Parse",
+		attribute.String("file.path", path),
+		attribute.String("parser.format", fileParser.GetFormat()),
+		attribute.Int("parser.lines", len(resultLines)))
 	for i, line := range resultLines {
-		entry := types.LogEntry{
-			Raw:     line,
-			LineNum: startLineNum + int64(i),
-		}
-
-		// 尝试解析日志行
-		if parser := lm.findParser(line); parser != nil {
-			if parsed, err := parser.Parse(line); err == nil {
-				entry = *parsed
-				entry.LineNum = startLineNum + int64(i)
-			}
-		}
-
+		entry := lm.parseLine(fileParser, line, startLineNum+int64(i))
+		entry.ByteOffset = resultOffsets[i]
 		entries = append(entries, entry)
 	}
+	parseSpan.End()
 
 	return &types.LogContent{
 		Entries:    entries,
 		TotalLines: totalLines,
 		HasMore:    startLineNum > 0, // 如果起始行号大于0，说明还有更多内容
 		Offset:     startLineNum,
+		Format:     fileParser.GetFormat(),
 	}, nil
 }
 
@@ -1213,4 +1502,3 @@ func (lm *LogManager) GetLogPaths() []string {
 
 	return lm.config.Server.LogPaths
 }
-
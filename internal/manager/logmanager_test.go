@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -154,7 +155,7 @@ func TestLogManager_ReadLogFile(t *testing.T) {
 
 	// 测试读取文件内容
 	logFilePath := filepath.Join(tempDir, "access.log") // 使用根目录下的文件
-	content, err := manager.ReadLogFile(logFilePath, 0, 10)
+	content, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
 	if err != nil {
 		t.Fatalf("读取日志文件失败: %v", err)
 	}
@@ -197,12 +198,12 @@ func TestLogManager_ReadLogFile_Pagination(t *testing.T) {
 	logFilePath := filepath.Join(tempDir, "access.log")
 
 	// 测试分页读取
-	page1, err := manager.ReadLogFile(logFilePath, 0, 2)
+	page1, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 2)
 	if err != nil {
 		t.Fatalf("读取第一页失败: %v", err)
 	}
 
-	page2, err := manager.ReadLogFile(logFilePath, 2, 2)
+	page2, err := manager.ReadLogFile(context.Background(), logFilePath, 2, 2)
 	if err != nil {
 		t.Fatalf("读取第二页失败: %v", err)
 	}
@@ -241,7 +242,7 @@ func TestLogManager_ReadLogFile_NonExistent(t *testing.T) {
 	manager := NewLogManager(cfg, fileWatcher, cache)
 
 	// 测试读取不存在的文件
-	_, err = manager.ReadLogFile("/nonexistent/file.log", 0, 10)
+	_, err = manager.ReadLogFile(context.Background(), "/nonexistent/file.log", 0, 10)
 	if err == nil {
 		t.Error("读取不存在的文件应该返回错误")
 	}
@@ -370,6 +371,9 @@ func TestLogManager_isLogFile(t *testing.T) {
 		{"access_info.out", true},
 		{"not_a_log.dat", false},
 		{"debug.txt", true},
+		{"syslog", true},
+		{"messages", true},
+		{"messages.dat", false},
 	}
 
 	for _, tc := range testCases {
@@ -398,7 +402,7 @@ func TestLogManager_Cache(t *testing.T) {
 
 	// 第一次读取
 	start := time.Now()
-	content1, err := manager.ReadLogFile(logFilePath, 0, 10)
+	content1, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
 	if err != nil {
 		t.Fatalf("第一次读取失败: %v", err)
 	}
@@ -406,7 +410,7 @@ func TestLogManager_Cache(t *testing.T) {
 
 	// 第二次读取（应该从缓存获取）
 	start = time.Now()
-	content2, err := manager.ReadLogFile(logFilePath, 0, 10)
+	content2, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
 	if err != nil {
 		t.Fatalf("第二次读取失败: %v", err)
 	}
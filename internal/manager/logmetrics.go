@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/logmetric"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+// initializeLogMetrics 创建日志指标引擎，配置无效时只记录日志，不影响日志查看
+func (lm *LogManager) initializeLogMetrics() {
+	cfg := lm.config.LogMetrics
+	cfg.Rules = make([]config.LogMetricRuleConfig, len(lm.config.LogMetrics.Rules))
+	mounts := lm.mountList()
+	for i, r := range lm.config.LogMetrics.Rules {
+		cfg.Rules[i] = r
+		cfg.Rules[i].Path = resolveLogPath(r.Path, mounts)
+	}
+
+	engine, err := logmetric.NewEngine(cfg, lm)
+	if err != nil {
+		logger.Error("创建日志指标引擎失败", zap.Error(err))
+		return
+	}
+	lm.logMetrics = engine
+}
+
+// GetLogMetrics 返回所有日志指标的定义和当前状态
+func (lm *LogManager) GetLogMetrics() []types.LogMetricInfo {
+	if lm.logMetrics == nil {
+		return []types.LogMetricInfo{}
+	}
+	return lm.logMetrics.Metrics()
+}
+
+// QueryLogMetric 按时间范围和步长查询日志指标
+func (lm *LogManager) QueryLogMetric(query types.LogMetricQuery) (*types.LogMetricResult, error) {
+	if lm.logMetrics == nil {
+		return nil, logmetric.ErrNotFound
+	}
+	return lm.logMetrics.Query(query)
+}
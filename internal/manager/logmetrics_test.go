@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestLogManager_LogMetricsBackfill(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+
+	now := time.Now().Truncate(time.Second)
+	var content strings.Builder
+	for i, level := range []string{"INFO", "ERROR", "ERROR", "INFO", "ERROR"} {
+		ts := now.Add(time.Duration(i-10) * time.Minute).Format("2006-01-02 15:04:05")
+		fmt.Fprintf(&content, "%s %s request %d\n", ts, level, i)
+	}
+	if err := os.WriteFile(logFilePath, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	cfg := createTestConfig([]string{tempDir})
+	cfg.LogMetrics.Rules = []config.LogMetricRuleConfig{
+		{Name: "errors", Type: config.LogMetricCounter, Path: "app.log", Levels: []string{"ERROR"}},
+	}
+	manager := newFormatTestManager(t, cfg)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !manager.GetLogMetrics()[0].Backfilled {
+		if time.Now().After(deadline) {
+			t.Fatal("等待日志指标回填超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metric := manager.GetLogMetrics()[0]
+	if metric.Path != logFilePath {
+		t.Errorf("期望指标路径为 %s，得到 %s", logFilePath, metric.Path)
+	}
+
+	result, err := manager.QueryLogMetric(types.LogMetricQuery{
+		Name:  "errors",
+		Func:  types.LogMetricCount,
+		Start: now.Add(-time.Hour),
+		End:   now.Add(time.Minute),
+		Step:  time.Hour + time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("查询日志指标失败: %v", err)
+	}
+	if len(result.Series) != 1 || result.Series[0].Points[0].Value != 3 {
+		t.Errorf("期望回填 3 条错误，得到 %+v", result.Series)
+	}
+}
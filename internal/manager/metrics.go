@@ -0,0 +1,10 @@
+package manager
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	watchSubscribers = metrics.NewGauge("logviewer_watch_subscribers",
+		"Subscribers to watched files across all files.")
+	watchUpdatesDropped = metrics.NewCounter("logviewer_watch_updates_dropped_total",
+		"Updates dropped because a subscriber's backlog was full; reported to the subscriber as a gap.")
+)
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logger"
+	"go.uber.org/zap"
+)
+
+// parserConfigCheckInterval 检查配置文件中解析器定义变化的间隔
+const parserConfigCheckInterval = 5 * time.Second
+
+// ReloadParsers 重新加载配置定义的自定义解析器
+// 任意一个定义无效时返回错误，并继续使用之前的解析器
+func (lm *LogManager) ReloadParsers(defs []config.ParserConfig) error {
+	if err := config.ValidateParserConfigs(defs); err != nil {
+		return err
+	}
+
+	lm.parserMutex.Lock()
+	defer lm.parserMutex.Unlock()
+
+	built, err := lm.parserFactory.RegisterPatternParsers(defs)
+	if err != nil {
+		return err
+	}
+
+	customParsers := make([]interfaces.LogParser, 0, len(built))
+	for _, p := range built {
+		customParsers = append(customParsers, p)
+	}
+	lm.customParsers = customParsers
+	lm.parserDefs = defs
+
+	// 已缓存的内容和格式检测结果基于旧解析器，需要失效
+	lm.resetFormatCache()
+	lm.cache.Clear()
+
+	logger.Info("自定义解析器已加载", zap.Int("count", len(built)))
+	return nil
+}
+
+// GetParserConfigs 获取当前生效的自定义解析器配置
+func (lm *LogManager) GetParserConfigs() []config.ParserConfig {
+	lm.parserMutex.RLock()
+	defer lm.parserMutex.RUnlock()
+	return lm.parserDefs
+}
+
+// watchParserConfig 定期检查配置文件，解析器定义变化时热加载
+func (lm *LogManager) watchParserConfig(configPath string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastModTime time.Time
+	if info, err := os.Stat(configPath); err == nil {
+		lastModTime = info.ModTime()
+	}
+
+	for {
+		select {
+		case <-lm.stopCh:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(configPath)
+			if err != nil || !info.ModTime().After(lastModTime) {
+				continue
+			}
+			lastModTime = info.ModTime()
+
+			if err := lm.reloadParsersFromFile(configPath); err != nil {
+				logger.Error("热加载自定义解析器失败",
+					zap.String("config", configPath),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// reloadParsersFromFile 从配置文件加载解析器定义，有变化时重新加载
+func (lm *LogManager) reloadParsersFromFile(configPath string) error {
+	defs, err := config.LoadParserConfigs(configPath)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(defs, lm.GetParserConfigs()) {
+		return nil
+	}
+
+	logger.Info("检测到解析器配置变化，重新加载", zap.String("config", configPath))
+	return lm.ReloadParsers(defs)
+}
@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/watcher"
+)
+
+func TestLogManager_CustomParsers(t *testing.T) {
+	tempDir := setupTestFiles(t)
+	defer cleanupTestFiles(tempDir)
+
+	logFilePath := filepath.Join(tempDir, "orders.log")
+	err := os.WriteFile(logFilePath, []byte("2023-01-01T10:00:00Z|warn|order=42|payment retried\n"), 0644)
+	if err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	cfg := createTestConfig([]string{tempDir})
+	cfg.Parsers = []config.ParserConfig{
+		{
+			Name:           "orders",
+			Type:           "regex",
+			Pattern:        `^(?P<ts>[^|]+)\|(?P<lvl>\w+)\|order=(?P<order>\d+)\|(?P<msg>.*)$`,
+			TimestampField: "ts",
+			LevelField:     "lvl",
+			MessageField:   "msg",
+			FieldTypes:     map[string]string{"order": "int"},
+			Files:          []string{"orders*.log"},
+		},
+	}
+
+	fileWatcher, err := watcher.NewFileWatcher()
+	if err != nil {
+		t.Fatalf("创建文件监控器失败: %v", err)
+	}
+	defer fileWatcher.Stop()
+
+	logCache := cache.NewMemoryCache(10, time.Minute)
+	manager := NewLogManager(cfg, fileWatcher, logCache).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	defer manager.Stop()
+
+	content, err := manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	if len(content.Entries) != 1 {
+		t.Fatalf("期望 1 个日志条目，得到 %d", len(content.Entries))
+	}
+
+	entry := content.Entries[0]
+	if entry.LogType != "orders" {
+		t.Errorf("期望使用 orders 解析器，实际为 %s", entry.LogType)
+	}
+	if entry.Level != "WARN" || entry.Message != "payment retried" {
+		t.Errorf("解析结果不正确: level=%s message=%s", entry.Level, entry.Message)
+	}
+	if order, ok := entry.Fields["order"].(int64); !ok || order != 42 {
+		t.Errorf("期望 order 字段为 int64(42)，实际为 %#v", entry.Fields["order"])
+	}
+
+	// 无效的定义不会替换当前的解析器
+	err = manager.ReloadParsers([]config.ParserConfig{{Name: "bad", Type: "grok", Pattern: "%{NOPE:x}"}})
+	if err == nil {
+		t.Fatal("期望加载无效解析器失败")
+	}
+	if len(manager.GetParserConfigs()) != 1 {
+		t.Error("加载失败后应保留之前的解析器配置")
+	}
+
+	// 移除自定义解析器后回退到内置解析器
+	if err := manager.ReloadParsers(nil); err != nil {
+		t.Fatalf("重新加载解析器失败: %v", err)
+	}
+	content, err = manager.ReadLogFile(context.Background(), logFilePath, 0, 10)
+	if err != nil {
+		t.Fatalf("读取日志文件失败: %v", err)
+	}
+	if content.Entries[0].LogType == "orders" {
+		t.Error("移除自定义解析器后不应继续使用它")
+	}
+}
+
+func TestLogManager_ReloadParsersFromFile(t *testing.T) {
+	tempDir := setupTestFiles(t)
+	defer cleanupTestFiles(tempDir)
+
+	configPath := filepath.Join(tempDir, "config.yaml")
+	configContent := `
+parsers:
+  - name: gateway
+    type: grok
+    pattern: "%{LOGLEVEL:level} %{GREEDYDATA:message}"
+    levelField: level
+    messageField: message
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	cfg := createTestConfig([]string{tempDir})
+	fileWatcher, err := watcher.NewFileWatcher()
+	if err != nil {
+		t.Fatalf("创建文件监控器失败: %v", err)
+	}
+	defer fileWatcher.Stop()
+
+	manager := NewLogManager(cfg, fileWatcher, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	defer manager.Stop()
+
+	if err := manager.reloadParsersFromFile(configPath); err != nil {
+		t.Fatalf("从配置文件加载解析器失败: %v", err)
+	}
+
+	defs := manager.GetParserConfigs()
+	if len(defs) != 1 || defs[0].Name != "gateway" {
+		t.Fatalf("期望加载 gateway 解析器，实际为 %+v", defs)
+	}
+
+	logFilePath := filepath.Join(tempDir, "gateway.log")
+	if err := os.WriteFile(logFilePath, []byte("ERROR upstream timeout\nINFO retry ok\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	if format := manager.GetFileFormat(logFilePath); format != "gateway" {
+		t.Errorf("期望自定义解析器优先于内置解析器，实际为 %s", format)
+	}
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// updateRetryDelay 更新通道已满时重新读取新增内容的间隔
+	updateRetryDelay = 200 * time.Millisecond
+
+	// maxSeqHistory 每个文件保留的序号和字节位置对应关系的数量，用于断线重连续传
+	maxSeqHistory = 1024
+)
+
+// seqOffset 已发送更新的序号和结束的字节位置
+type seqOffset struct {
+	seq uint64
+	end int64
+}
+
+// recordSeqLocked 记录已发送更新结束的字节位置，调用方需持有 watchMutex
+func (lm *LogManager) recordSeqLocked(update types.LogUpdate) {
+	history := append(lm.seqHistory[update.Path], seqOffset{seq: update.Seq, end: update.EndOffset})
+	if len(history) > maxSeqHistory {
+		history = history[len(history)-maxSeqHistory:]
+	}
+	lm.seqHistory[update.Path] = history
+}
+
+// OffsetForSeq 返回指定序号的更新结束的字节位置，序号太旧或不存在时返回 false
+func (lm *LogManager) OffsetForSeq(path string, seq uint64) (int64, bool) {
+	lm.watchMutex.RLock()
+	defer lm.watchMutex.RUnlock()
+
+	history := lm.seqHistory[path]
+	if len(history) == 0 || seq < history[0].seq || seq > history[len(history)-1].seq {
+		return 0, false
+	}
+	// 序号连续递增，直接按下标定位
+	entry := history[seq-history[0].seq]
+	return entry.end, entry.seq == seq
+}
+
+// ReadRange 读取 [offset, 实时推送位置) 范围内的完整行，最多读取 maxBytes 字节
+// 返回的更新 EndOffset 小于 liveOffset 时说明还有未读取的内容
+func (lm *LogManager) ReadRange(path string, offset, maxBytes int64) (*types.LogUpdate, int64, error) {
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("无效的字节位置: %d", offset)
+	}
+
+	file, size, err := lm.openRange(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	// 正在监控的文件读取到实时推送的位置，之后的内容由实时更新送达
+	lm.watchMutex.RLock()
+	liveOffset, watched := lm.filePositions[path]
+	seq := lm.fileSeqs[path]
+	lm.watchMutex.RUnlock()
+	if !watched || liveOffset > size {
+		liveOffset = size
+	}
+	if offset > liveOffset {
+		return nil, 0, fmt.Errorf("字节位置 %d 超出文件范围 %d", offset, liveOffset)
+	}
+
+	// 位置不在行首时从下一行开始
+	start := offset
+	if start > 0 {
+		prev := make([]byte, 1)
+		if _, err := file.ReadAt(prev, start-1); err != nil {
+			return nil, 0, fmt.Errorf("读取文件内容失败: %w", err)
+		}
+		if prev[0] != '\n' {
+			rest := io.NewSectionReader(file, start, liveOffset-start)
+			buf := make([]byte, 4096)
+			for skipped := false; !skipped; {
+				n, err := rest.Read(buf)
+				if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+					start += int64(i + 1)
+					skipped = true
+				} else {
+					start += int64(n)
+				}
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return nil, 0, fmt.Errorf("读取文件内容失败: %w", err)
+				}
+			}
+		}
+	}
+
+	end := liveOffset
+	if maxBytes > 0 && end-start > maxBytes {
+		end = start + maxBytes
+	}
+	data := make([]byte, end-start)
+	if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+	// 受 maxBytes 限制时只保留完整的行，超长的单行整体返回
+	if end < liveOffset {
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+
+	entries, err := lm.parseLines(path, bytes.NewReader(data), start)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+
+	return &types.LogUpdate{
+		Path:        path,
+		Entries:     entries,
+		Type:        "resync",
+		Seq:         seq,
+		StartOffset: start,
+		EndOffset:   start + int64(len(data)),
+	}, liveOffset, nil
+}
+
+// rangeFile 可以按位置读取的文件
+type rangeFile interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// openRange 打开文件用于按位置读取，返回文件当前大小；非本地文件每次通过日志源读取一个范围
+func (lm *LogManager) openRange(path string) (rangeFile, int64, error) {
+	if localPath, local := lm.localPath(path); local {
+		file, err := os.Open(localPath)
+		if err != nil {
+			return nil, 0, fmt.Errorf("打开文件失败: %w", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("获取文件信息失败: %w", err)
+		}
+		return file, info.Size(), nil
+	}
+
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("打开文件失败: %w", err)
+	}
+	ctx, cancel := sourceContext()
+	defer cancel()
+	info, err := src.Stat(ctx, resolved)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	size, err := sourceSize(ctx, src, info)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	return sourceReaderAt{src: src, path: resolved}, size, nil
+}
@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// manualWatcher 不产生文件事件的监控器，测试中手动触发事件
+type manualWatcher struct{}
+
+func (manualWatcher) WatchFile(path string, callback func(types.FileEvent)) error { return nil }
+func (manualWatcher) UnwatchFile(path string) error                               { return nil }
+func (manualWatcher) Start() error                                                { return nil }
+func (manualWatcher) Stop() error                                                 { return nil }
+
+func newStreamTestManager(t *testing.T, dir string) *LogManager {
+	manager := NewLogManager(createTestConfig([]string{dir}), manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+	return manager
+}
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("打开测试文件失败: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+}
+
+func TestLogManager_UpdateSeqAndOffsets(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+	if err := os.WriteFile(logFilePath, []byte("first\nsecond\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newStreamTestManager(t, tempDir)
+	updates, err := manager.WatchFile(logFilePath)
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	updateCh := manager.watchedFiles[logFilePath]
+
+	appendToFile(t, logFilePath, "third\nfourth\n")
+	manager.handleFileModify(logFilePath, updateCh)
+	appendToFile(t, logFilePath, "fifth\n")
+	manager.handleFileModify(logFilePath, updateCh)
+
+	first := <-updates
+	if first.Seq != 1 || first.StartOffset != 13 || first.EndOffset != 26 {
+		t.Errorf("第一个更新的序号或范围不正确: seq=%d range=[%d,%d)", first.Seq, first.StartOffset, first.EndOffset)
+	}
+	if len(first.Entries) != 2 || first.Entries[0].ByteOffset != 13 || first.Entries[1].ByteOffset != 19 {
+		t.Errorf("第一个更新的条目位置不正确: %+v", first.Entries)
+	}
+
+	second := <-updates
+	if second.Seq != 2 || second.StartOffset != 26 || second.EndOffset != 32 {
+		t.Errorf("第二个更新的序号或范围不正确: seq=%d range=[%d,%d)", second.Seq, second.StartOffset, second.EndOffset)
+	}
+
+	// 断线重连时按序号找到续传位置
+	if offset, ok := manager.OffsetForSeq(logFilePath, 1); !ok || offset != 26 {
+		t.Errorf("期望序号 1 结束于 26，得到 %d %v", offset, ok)
+	}
+	if _, ok := manager.OffsetForSeq(logFilePath, 3); ok {
+		t.Error("尚未发送的序号不应可用")
+	}
+
+	// 截断后先通知客户端，字节位置从头开始
+	if err := os.WriteFile(logFilePath, []byte("new\n"), 0644); err != nil {
+		t.Fatalf("截断测试文件失败: %v", err)
+	}
+	manager.handleFileModify(logFilePath, updateCh)
+	if truncate := <-updates; truncate.Type != "truncate" || truncate.Seq != 3 {
+		t.Errorf("期望序号为 3 的截断通知，得到 %s seq=%d", truncate.Type, truncate.Seq)
+	}
+	if appended := <-updates; appended.Seq != 4 || appended.StartOffset != 0 || appended.EndOffset != 4 {
+		t.Errorf("截断后的更新不正确: seq=%d range=[%d,%d)", appended.Seq, appended.StartOffset, appended.EndOffset)
+	}
+}
+
+func TestLogManager_FullChannelDefersUpdate(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+	if err := os.WriteFile(logFilePath, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newStreamTestManager(t, tempDir)
+
+	// 直接使用没有分发 goroutine 的更新源，模拟订阅者全部跟不上的情况
+	updateCh := make(chan types.LogUpdate, 1)
+	manager.watchMutex.Lock()
+	manager.watchedFiles[logFilePath] = updateCh
+	manager.filePositions[logFilePath] = 0
+	manager.watchMutex.Unlock()
+	updateCh <- types.LogUpdate{Path: logFilePath, Type: "append"}
+
+	appendToFile(t, logFilePath, "line 1\nline 2\n")
+	manager.handleFileModify(logFilePath, updateCh)
+
+	manager.watchMutex.RLock()
+	position := manager.filePositions[logFilePath]
+	manager.watchMutex.RUnlock()
+	if position != 0 {
+		t.Fatalf("通道已满时不应推进读取位置，得到 %d", position)
+	}
+
+	// 消费积压的更新后，新增内容在重试时送达而不是丢失
+	<-updateCh
+	select {
+	case update := <-updateCh:
+		if update.Seq != 1 || len(update.Entries) != 2 || update.StartOffset != 0 || update.EndOffset != 14 {
+			t.Errorf("重试送达的更新不正确: seq=%d entries=%d range=[%d,%d)",
+				update.Seq, len(update.Entries), update.StartOffset, update.EndOffset)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("通道空出后没有收到延迟的更新")
+	}
+}
+
+func TestLogManager_ReadRange(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+	content := "line one\nline two\nline three\n"
+	if err := os.WriteFile(logFilePath, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	manager := newStreamTestManager(t, tempDir)
+
+	// 不在行首的位置从下一行开始
+	update, liveOffset, err := manager.ReadRange(logFilePath, 3, 0)
+	if err != nil {
+		t.Fatalf("读取范围失败: %v", err)
+	}
+	if liveOffset != int64(len(content)) || update.Type != "resync" {
+		t.Errorf("期望 liveOffset=%d 类型 resync，得到 %d %s", len(content), liveOffset, update.Type)
+	}
+	if len(update.Entries) != 2 || update.StartOffset != 9 || update.Entries[0].ByteOffset != 9 {
+		t.Errorf("读取结果不正确: start=%d entries=%+v", update.StartOffset, update.Entries)
+	}
+
+	// 超出字节上限时只返回完整的行
+	update, _, err = manager.ReadRange(logFilePath, 0, 12)
+	if err != nil {
+		t.Fatalf("读取范围失败: %v", err)
+	}
+	if len(update.Entries) != 1 || update.EndOffset != 9 {
+		t.Errorf("期望只返回第一行，得到 end=%d entries=%d", update.EndOffset, len(update.Entries))
+	}
+
+	// 正在监控的文件只读取到实时推送的位置
+	if _, err := manager.WatchFile(logFilePath); err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	appendToFile(t, logFilePath, "line four\n")
+	update, liveOffset, err = manager.ReadRange(logFilePath, 0, 0)
+	if err != nil {
+		t.Fatalf("读取范围失败: %v", err)
+	}
+	if liveOffset != int64(len(content)) || len(update.Entries) != 3 {
+		t.Errorf("期望读取到实时推送位置 %d，得到 %d (%d 条)", len(content), liveOffset, len(update.Entries))
+	}
+
+	if _, _, err := manager.ReadRange(logFilePath, 1000, 0); err == nil {
+		t.Error("超出文件范围的位置应返回错误")
+	}
+}
@@ -0,0 +1,638 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/tracing"
+	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// sourceOpTimeout 没有请求上下文的日志源操作（列出目录、检测格式、读取新增内容）的超时时间
+	sourceOpTimeout = 30 * time.Second
+	// tailLineSize 从日志源的文件尾部读取时估算的每行字节数
+	tailLineSize = 200
+	// sourceSearchConcurrency 搜索日志源中的目录时同时读取的文件数
+	sourceSearchConcurrency = 8
+)
+
+// sourceMount 挂载在文件树中的日志源，name 为挂载名称，作为文件树根节点的名称
+type sourceMount struct {
+	name string
+	src  source.LogSource
+}
+
+// newSourceMounts 为配置中的每个日志路径创建日志源：本地路径使用 LocalSource，
+// 其他路径（例如 ssh://user@host/var/log/app）按前缀创建对应的日志源。
+// 创建失败的路径记录错误后跳过，不影响其他日志路径
+func newSourceMounts(cfg *config.Config, fileWatcher interfaces.FileWatcher) []sourceMount {
+	var mounts []sourceMount
+	for _, root := range cfg.Server.LogPaths {
+		var src source.LogSource
+		var err error
+		if source.IsRemote(root) {
+			src, err = source.New(root, cfg)
+		} else {
+			src, err = source.NewLocalSource(root, fileWatcher)
+		}
+		if err != nil {
+			logger.Error("创建日志源失败", zap.String("root", root), zap.Error(err))
+			continue
+		}
+		mounts = append(mounts, sourceMount{name: src.Root(), src: src})
+	}
+	return mounts
+}
+
+// Mount 将日志源挂载到文件树中，name 为根节点显示的名称，不能与已挂载的日志源重复
+func (lm *LogManager) Mount(name string, src source.LogSource) error {
+	if name == "" {
+		return fmt.Errorf("挂载名称不能为空")
+	}
+
+	lm.mountMutex.Lock()
+	defer lm.mountMutex.Unlock()
+
+	for _, m := range lm.mounts {
+		if m.name == name {
+			return fmt.Errorf("挂载名称已存在: %s", name)
+		}
+		if m.src.Root() == src.Root() {
+			return fmt.Errorf("日志源已挂载: %s", src.Root())
+		}
+	}
+	lm.mounts = append(lm.mounts, sourceMount{name: name, src: src})
+	logger.Info("挂载日志源", zap.String("name", name), zap.String("root", src.Root()))
+	return nil
+}
+
+// Unmount 卸载并关闭日志源
+func (lm *LogManager) Unmount(name string) error {
+	lm.mountMutex.Lock()
+	var removed source.LogSource
+	for i, m := range lm.mounts {
+		if m.name == name {
+			removed = m.src
+			lm.mounts = append(lm.mounts[:i:i], lm.mounts[i+1:]...)
+			break
+		}
+	}
+	lm.mountMutex.Unlock()
+
+	if removed == nil {
+		return fmt.Errorf("挂载的日志源不存在: %s", name)
+	}
+	logger.Info("卸载日志源", zap.String("name", name), zap.String("root", removed.Root()))
+	return removed.Close()
+}
+
+// mountList 返回当前挂载的日志源的快照
+func (lm *LogManager) mountList() []sourceMount {
+	lm.mountMutex.RLock()
+	defer lm.mountMutex.RUnlock()
+	return append([]sourceMount(nil), lm.mounts...)
+}
+
+// closeSources 关闭所有挂载的日志源，停止文件的跟踪
+func (lm *LogManager) closeSources() {
+	for _, m := range lm.mountList() {
+		if err := m.src.Close(); err != nil {
+			logger.Warn("关闭日志源失败", zap.String("root", m.src.Root()), zap.Error(err))
+		}
+	}
+}
+
+// sourceFor 查找路径所属的日志源，返回规范化后的路径；
+// 本地归档中的路径由归档的日志源处理，不在任何挂载的日志源下的本地路径由 localFS 处理
+func (lm *LogManager) sourceFor(path string) (source.LogSource, string, error) {
+	// 本地归档中的文件由归档的日志源处理，先于包含归档的本地目录
+	if src, ok := lm.archiveSource(path); ok {
+		if resolved, ok := src.Resolve(path); ok {
+			return src, resolved, nil
+		}
+	}
+	for _, m := range lm.mountList() {
+		if resolved, ok := m.src.Resolve(path); ok {
+			return m.src, resolved, nil
+		}
+	}
+	if resolved, ok := lm.localFS.Resolve(path); ok {
+		return lm.localFS, resolved, nil
+	}
+	return nil, "", fmt.Errorf("路径不属于任何日志源: %s", path)
+}
+
+// localPath 路径属于本地文件系统时返回本地路径，此时使用文件池等本地的快速路径读取
+func (lm *LogManager) localPath(path string) (string, bool) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return "", false
+	}
+	if local, ok := src.(source.LocalFiles); ok {
+		return local.LocalPath(resolved)
+	}
+	return "", false
+}
+
+// sourceContext 为没有请求上下文的日志源操作创建带超时的上下文
+func sourceContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), sourceOpTimeout)
+}
+
+// sourceParent 返回日志源中路径的上级目录，path.Dir 会把 scheme 后的 // 合并，不能使用
+func sourceParent(p string) string {
+	if i := strings.LastIndex(p, "/"); i > 0 {
+		return p[:i]
+	}
+	return p
+}
+
+// sourceLogFile 将日志源返回的文件信息转换为日志文件对象
+func (lm *LogManager) sourceLogFile(info source.FileInfo) types.LogFile {
+	logFile := types.LogFile{
+		Path:        info.Path,
+		Name:        info.Name,
+		Size:        info.Size,
+		ModTime:     info.ModTime,
+		IsDirectory: info.IsDir,
+	}
+	if !info.IsDir {
+		logFile.Format = lm.parserForIdentity(info.Path, &fileIdentity{id: info.ID}).GetFormat()
+	}
+	return logFile
+}
+
+// acceptSourceFile 判断日志源中的文件是否作为日志文件显示，规则与本地文件相同；
+// 读取时解压的文件（例如 app.log.gz）按去掉压缩扩展名的名称判断
+func (lm *LogManager) acceptSourceFile(info source.FileInfo) bool {
+	name := info.Path
+	if info.Decompressed {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return !info.IsDir && lm.isLogFile(name) && info.Size <= lm.config.Server.MaxFileSize
+}
+
+// isLocalSource 本地文件系统的日志源，由本地的快速路径列出和读取
+func isLocalSource(src source.LogSource) bool {
+	_, ok := src.(source.LocalFiles)
+	return ok
+}
+
+// sourceLogFiles 列出所有非本地日志源中的日志文件
+func (lm *LogManager) sourceLogFiles(ctx context.Context) []types.LogFile {
+	var files []types.LogFile
+	for _, m := range lm.mountList() {
+		if isLocalSource(m.src) {
+			continue
+		}
+		infos, err := m.src.Walk(ctx)
+		if err != nil {
+			logger.Warn("列出日志源中的文件失败", zap.String("root", m.src.Root()), zap.Error(err))
+			continue
+		}
+		for _, info := range infos {
+			if lm.acceptSourceFile(info) {
+				files = append(files, lm.sourceLogFile(info))
+			}
+		}
+	}
+	return files
+}
+
+// sourceFileTrees 为每个非本地日志源构建以挂载点为根节点的文件树，不包含日志文件的目录不显示
+func (lm *LogManager) sourceFileTrees(ctx context.Context) []types.LogFile {
+	var trees []types.LogFile
+	for _, m := range lm.mountList() {
+		if isLocalSource(m.src) {
+			continue
+		}
+		infos, err := m.src.Walk(ctx)
+		if err != nil {
+			logger.Warn("列出日志源中的文件失败", zap.String("root", m.src.Root()), zap.Error(err))
+			continue
+		}
+
+		root := &sourceNode{file: types.LogFile{Path: m.src.Root(), Name: m.name, IsDirectory: true}}
+		nodes := map[string]*sourceNode{m.src.Root(): root}
+		for _, info := range infos {
+			if info.IsDir {
+				nodes[info.Path] = &sourceNode{file: lm.sourceLogFile(info)}
+			}
+		}
+		for _, info := range infos {
+			if !lm.acceptSourceFile(info) {
+				continue
+			}
+			// 逐级挂到上级目录，直到已挂载的目录或根节点
+			child := &sourceNode{file: lm.sourceLogFile(info)}
+			for p := sourceParent(info.Path); ; p = sourceParent(p) {
+				parent, ok := nodes[p]
+				if !ok {
+					break
+				}
+				parent.children = append(parent.children, child)
+				if parent.attached || parent == root {
+					break
+				}
+				parent.attached = true
+				child = parent
+			}
+		}
+		trees = append(trees, root.toLogFile())
+	}
+	return trees
+}
+
+// sourceNode 构建日志源的文件树时的节点
+type sourceNode struct {
+	file     types.LogFile
+	children []*sourceNode
+	attached bool
+}
+
+func (n *sourceNode) toLogFile() types.LogFile {
+	file := n.file
+	if file.IsDirectory {
+		file.Children = make([]types.LogFile, 0, len(n.children))
+		for _, child := range n.children {
+			file.Children = append(file.Children, child.toLogFile())
+		}
+		sortDirectoryEntries(file.Children)
+	}
+	return file
+}
+
+// sourceRootDirectories 非本地日志源的挂载点节点，无法访问的源记录警告后跳过
+func (lm *LogManager) sourceRootDirectories(ctx context.Context) []types.LogFile {
+	var roots []types.LogFile
+	for _, m := range lm.mountList() {
+		if isLocalSource(m.src) {
+			continue
+		}
+		info, err := m.src.Stat(ctx, m.src.Root())
+		if err != nil {
+			logger.Warn("无法访问日志源", zap.String("root", m.src.Root()), zap.Error(err))
+			continue
+		}
+		roots = append(roots, types.LogFile{
+			Path:        m.src.Root(),
+			Name:        m.name,
+			ModTime:     info.ModTime,
+			IsDirectory: true,
+		})
+	}
+	return roots
+}
+
+// sourceDirectoryFiles 列出日志源中目录的直接子节点
+func (lm *LogManager) sourceDirectoryFiles(ctx context.Context, dirPath string) ([]types.LogFile, error) {
+	src, resolved, err := lm.sourceFor(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := src.List(ctx, resolved)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+
+	var files []types.LogFile
+	for _, info := range infos {
+		// 跳过隐藏文件
+		if strings.HasPrefix(info.Name, ".") {
+			continue
+		}
+		if info.IsDir || lm.acceptSourceFile(info) {
+			files = append(files, lm.sourceLogFile(info))
+		}
+	}
+	sortDirectoryEntries(files)
+	return files, nil
+}
+
+// statSource 获取日志源中的文件信息并检查大小限制
+func (lm *LogManager) statSource(ctx context.Context, path string) (source.LogSource, source.FileInfo, error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return nil, source.FileInfo{}, err
+	}
+	info, err := src.Stat(ctx, resolved)
+	if err != nil {
+		return nil, source.FileInfo{}, fmt.Errorf("文件不存在: %w", err)
+	}
+	if info.Size > lm.config.Server.MaxFileSize {
+		return nil, source.FileInfo{}, fmt.Errorf("文件过大，超过限制 %d 字节", lm.config.Server.MaxFileSize)
+	}
+	return src, info, nil
+}
+
+// sourceSize 返回文件内容的实际大小；日志源还不知道大小时（SizeUnknown，例如还没有完整读取过的 .gz 对象）
+// 完整读取一遍，日志源随之记录大小
+func sourceSize(ctx context.Context, src source.LogSource, info source.FileInfo) (int64, error) {
+	if !info.SizeUnknown {
+		return info.Size, nil
+	}
+	r, err := src.Open(ctx, info.Path, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return io.Copy(io.Discard, r)
+}
+
+// countSourceLines 统计文件的行数，日志源不支持时读取整个文件统计
+func countSourceLines(ctx context.Context, src source.LogSource, path string) (int64, error) {
+	if counter, ok := src.(source.LineCounter); ok {
+		return counter.CountLines(ctx, path)
+	}
+
+	r, err := src.Open(ctx, path, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var lines int64
+	scanner := search.NewLineScanner(r, 64*1024, 1024*1024)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines, scanner.Err()
+}
+
+// readSourceLogFile 通过日志源从文件的 offset 行开始读取 limit 行
+func (lm *LogManager) readSourceLogFile(ctx context.Context, path string, offset int64, limit int) (*types.LogContent, error) {
+	src, info, err := lm.statSource(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("file:%s:%d:%d:%s:%d", info.Path, offset, limit, info.ID, info.ModTime.UnixNano()) + lm.formatSuffix(ctx, info.Path)
+	if cached, found := lm.cache.Get(cacheKey); found {
+		if content, ok := cached.(*types.LogContent); ok {
+			return content, nil
+		}
+	}
+
+	_, countSpan := tracing.Start(ctx, "manager.countLines", attribute.String("file.path", info.Path))
+	totalLines, err := countSourceLines(ctx, src, info.Path)
+	countSpan.SetAttributes(attribute.Int64("file.lines", totalLines))
+	tracing.End(countSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+
+	r, err := src.Open(ctx, info.Path, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+	// 读取到需要的行后关闭，远程命令等随之结束
+	defer r.Close()
+
+	scanner := search.NewLineScanner(r, 64*1024, 1024*1024)
+	for i := int64(0); i < offset && scanner.Scan(); i++ {
+		// 跳过行
+	}
+
+	fileParser := lm.requestParser(ctx, info.Path)
+	var entries []types.LogEntry
+	lineNum := offset
+	for i := 0; i < limit && scanner.Scan(); i++ {
+		entry := lm.parseLine(fileParser, scanner.Text(), lineNum)
+		entry.ByteOffset = scanner.Offset()
+		entries = append(entries, entry)
+		lineNum++
+	}
+	hasMore := scanner.Scan()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+
+	content := &types.LogContent{
+		Entries:    entries,
+		TotalLines: totalLines,
+		HasMore:    hasMore,
+		Offset:     offset,
+		Format:     fileParser.GetFormat(),
+	}
+	if !lm.memoryMonitor.IsMemoryPressure() {
+		lm.cache.Set(cacheKey, content)
+	}
+	return content, nil
+}
+
+// readSourceTail 通过日志源读取文件的最后 lines 行，从尾部读取一段字节，行数不够时扩大范围重新读取
+func (lm *LogManager) readSourceTail(ctx context.Context, path string, lines int) (*types.LogContent, error) {
+	src, info, err := lm.statSource(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("tail:%s:%d:%s:%d", info.Path, lines, info.ID, info.ModTime.UnixNano()) + lm.formatSuffix(ctx, info.Path)
+	if cached, found := lm.cache.Get(cacheKey); found {
+		if content, ok := cached.(*types.LogContent); ok {
+			return content, nil
+		}
+	}
+
+	fileParser := lm.requestParser(ctx, info.Path)
+	if info.Size == 0 || lines <= 0 {
+		return &types.LogContent{Entries: []types.LogEntry{}, Format: fileParser.GetFormat()}, nil
+	}
+
+	// 大小未知时先统计行数（完整读取一遍），之后日志源知道大小，不必再读取一遍
+	totalLines := int64(-1)
+	size := info.Size
+	if info.SizeUnknown {
+		if totalLines, err = countSourceLines(ctx, src, info.Path); err != nil {
+			return nil, fmt.Errorf("读取文件尾部内容失败: %w", err)
+		}
+		if info, err = src.Stat(ctx, info.Path); err == nil {
+			size, err = sourceSize(ctx, src, info)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取文件尾部内容失败: %w", err)
+		}
+	}
+
+	var allLines []string
+	var allOffsets []int64
+	window := int64(lines) * tailLineSize
+	for {
+		start := size - window
+		if start < 0 {
+			start = 0
+		}
+		allLines, allOffsets, err = readSourceLines(ctx, src, info.Path, start, size-start)
+		if err != nil {
+			return nil, fmt.Errorf("读取文件尾部内容失败: %w", err)
+		}
+		if len(allLines) >= lines || start == 0 {
+			break
+		}
+		window *= 4
+	}
+
+	if totalLines < 0 {
+		if totalLines, err = countSourceLines(ctx, src, info.Path); err != nil {
+			totalLines = int64(len(allLines))
+		}
+	}
+
+	startIndex := 0
+	if len(allLines) > lines {
+		startIndex = len(allLines) - lines
+	}
+	resultLines := allLines[startIndex:]
+	resultOffsets := allOffsets[startIndex:]
+
+	startLineNum := totalLines - int64(len(resultLines))
+	if startLineNum < 0 {
+		startLineNum = 0
+	}
+
+	entries := make([]types.LogEntry, 0, len(resultLines))
+	for i, line := range resultLines {
+		entry := lm.parseLine(fileParser, line, startLineNum+int64(i))
+		entry.ByteOffset = resultOffsets[i]
+		entries = append(entries, entry)
+	}
+
+	content := &types.LogContent{
+		Entries:    entries,
+		TotalLines: totalLines,
+		HasMore:    startLineNum > 0,
+		Offset:     startLineNum,
+		Format:     fileParser.GetFormat(),
+	}
+	if !lm.memoryMonitor.IsMemoryPressure() {
+		lm.cache.Set(cacheKey, content)
+	}
+	return content, nil
+}
+
+// readSourceLines 读取 [start, start+length) 范围内的行，start 不在文件开头时跳过第一行（可能不完整）
+func readSourceLines(ctx context.Context, src source.LogSource, path string, start, length int64) ([]string, []int64, error) {
+	r, err := src.Open(ctx, path, start, length)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	scanner := search.NewLineScanner(r, 64*1024, 1024*1024)
+	if start > 0 && scanner.Scan() {
+		// 跳过可能不完整的第一行
+	}
+
+	var lines []string
+	var offsets []int64
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		offsets = append(offsets, start+scanner.Offset())
+	}
+	return lines, offsets, scanner.Err()
+}
+
+// sourceReaderAt 按需通过日志源读取文件的指定范围，每次读取打开一次（远程源执行一次远程命令）
+type sourceReaderAt struct {
+	src  source.LogSource
+	path string
+}
+
+func (r sourceReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	ctx, cancel := sourceContext()
+	defer cancel()
+
+	rc, err := r.src.Open(ctx, r.path, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	n, err := io.ReadFull(rc, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r sourceReaderAt) Close() error {
+	return nil
+}
+
+// sourceSearchFiles 让搜索引擎通过日志源读取非本地文件
+type sourceSearchFiles struct {
+	lm *LogManager
+}
+
+func (r sourceSearchFiles) Handles(path string) bool {
+	_, local := r.lm.localPath(path)
+	return !local
+}
+
+func (r sourceSearchFiles) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	src, resolved, err := r.lm.sourceFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return src.Open(ctx, resolved, 0, -1)
+}
+
+func (r sourceSearchFiles) Version(ctx context.Context, path string) (string, error) {
+	src, resolved, err := r.lm.sourceFor(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := src.Stat(ctx, resolved)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", info.ID, info.Size, info.ModTime.UnixNano()), nil
+}
+
+// sourceSearchPaths 路径是日志源中的目录时返回其下所有的日志文件，用于搜索整个目录
+func (lm *LogManager) sourceSearchPaths(ctx context.Context, dirPath string) ([]string, bool, error) {
+	src, resolved, err := lm.sourceFor(dirPath)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := src.Stat(ctx, resolved)
+	if err != nil || !info.IsDir {
+		return nil, false, nil
+	}
+
+	infos, err := src.Walk(ctx)
+	if err != nil {
+		return nil, true, fmt.Errorf("列出目录中的文件失败: %w", err)
+	}
+	var paths []string
+	for _, file := range infos {
+		if lm.acceptSourceFile(file) && (resolved == src.Root() || strings.HasPrefix(file.Path, resolved+"/")) {
+			paths = append(paths, file.Path)
+		}
+	}
+	return paths, true, nil
+}
+
+// sortDirectoryEntries 目录排在文件前面，同类按名称排序
+func sortDirectoryEntries(files []types.LogFile) {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].IsDirectory != files[j].IsDirectory {
+			return files[i].IsDirectory
+		}
+		return files[i].Name < files[j].Name
+	})
+}
@@ -0,0 +1,427 @@
+package manager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/source/dockertest"
+	"github.com/local-log-viewer/internal/source/journaltest"
+	"github.com/local-log-viewer/internal/source/s3test"
+	"github.com/local-log-viewer/internal/source/sourcetest"
+	"github.com/local-log-viewer/internal/source/sshtest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	// 作为模拟的 journalctl 运行时处理参数后退出
+	journaltest.Main()
+	os.Exit(m.Run())
+}
+
+// newRemoteTestManager 启动进程内的 SSH 服务器，返回以 ssh:// 路径配置临时目录的日志管理器
+func newRemoteTestManager(t *testing.T) (*LogManager, string, string) {
+	t.Helper()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	signer, privateKey, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	server, err := sshtest.NewServer(signer.PublicKey())
+	if err != nil {
+		t.Fatalf("启动 SSH 服务器失败: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	keyDir := t.TempDir()
+	identityFile := filepath.Join(keyDir, "id_ed25519")
+	if err := os.WriteFile(identityFile, privateKey, 0600); err != nil {
+		t.Fatalf("写入私钥失败: %v", err)
+	}
+	knownHosts, err := server.WriteKnownHosts(keyDir)
+	if err != nil {
+		t.Fatalf("写入 known_hosts 失败: %v", err)
+	}
+
+	logDir := t.TempDir()
+	root := "ssh://tester@" + server.Addr + logDir
+	cfg := createTestConfig([]string{root})
+	cfg.Server.DataDir = t.TempDir()
+	cfg.SSH.KnownHostsFiles = []string{knownHosts}
+	cfg.SSH.IdentityFiles = []string{identityFile}
+	cfg.SSH.PollInterval = 20 * time.Millisecond
+
+	manager := NewLogManager(cfg, manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+	return manager, root, logDir
+}
+
+func writeRemoteTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+}
+
+func TestLogManager_RemoteListAndRead(t *testing.T) {
+	manager, root, logDir := newRemoteTestManager(t)
+	writeRemoteTestFile(t, filepath.Join(logDir, "app.log"),
+		`{"time":"2023-01-01T10:00:00Z","level":"info","msg":"started"}`+"\n"+
+			`{"time":"2023-01-01T10:00:01Z","level":"error","msg":"db timeout"}`+"\n"+
+			`{"time":"2023-01-01T10:00:02Z","level":"info","msg":"recovered"}`+"\n")
+	writeRemoteTestFile(t, filepath.Join(logDir, "nginx", "access.log"), "GET /health 200\n")
+	writeRemoteTestFile(t, filepath.Join(logDir, "nginx", "notes.md"), "not a log\n")
+	ctx := context.Background()
+
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != root || !files[0].IsDirectory {
+		t.Fatalf("远程根节点不正确: %+v", files)
+	}
+	children := files[0].Children
+	if len(children) != 2 || children[0].Name != "nginx" || children[1].Name != "app.log" {
+		t.Fatalf("远程文件树不正确: %+v", children)
+	}
+	if children[1].Format != "JSON" || len(children[0].Children) != 1 || children[0].Children[0].Path != root+"/nginx/access.log" {
+		t.Errorf("远程文件信息不正确: %+v", children)
+	}
+
+	roots, err := manager.GetDirectoryFiles("")
+	if err != nil || len(roots) != 1 || roots[0].Path != root {
+		t.Errorf("根目录列表不正确: %+v, %v", roots, err)
+	}
+	entries, err := manager.GetDirectoryFiles(root + "/nginx")
+	if err != nil || len(entries) != 1 || entries[0].Name != "access.log" {
+		t.Errorf("远程目录列表不正确: %+v, %v", entries, err)
+	}
+
+	content, err := manager.ReadLogFile(ctx, root+"/app.log", 1, 1)
+	if err != nil {
+		t.Fatalf("读取远程文件失败: %v", err)
+	}
+	if content.TotalLines != 3 || !content.HasMore || len(content.Entries) != 1 ||
+		content.Entries[0].Message != "db timeout" || content.Entries[0].ByteOffset != 63 {
+		t.Errorf("远程文件内容不正确: %+v", content)
+	}
+
+	tail, err := manager.ReadLogFileFromTail(ctx, root+"/app.log", 2)
+	if err != nil {
+		t.Fatalf("读取远程文件尾部失败: %v", err)
+	}
+	if len(tail.Entries) != 2 || tail.Offset != 1 || tail.Entries[1].Message != "recovered" || tail.Entries[1].LineNum != 2 {
+		t.Errorf("远程文件尾部内容不正确: %+v", tail)
+	}
+
+	result, err := manager.SearchLogs(ctx, types.SearchQuery{Path: root + "/app.log", Query: "timeout", Limit: 10})
+	if err != nil {
+		t.Fatalf("搜索远程文件失败: %v", err)
+	}
+	if result.TotalCount != 1 || result.Entries[0].LineNum != 2 {
+		t.Errorf("远程搜索结果不正确: %+v", result)
+	}
+
+	// 根目录之外的路径
+	if _, err := manager.ReadLogFile(ctx, root+"/../../etc/passwd", 0, 10); err == nil {
+		t.Error("期望读取根目录外的路径失败")
+	}
+}
+
+func TestLogManager_RemoteFollow(t *testing.T) {
+	manager, root, logDir := newRemoteTestManager(t)
+	localPath := filepath.Join(logDir, "app.log")
+	writeRemoteTestFile(t, localPath, "first\n")
+	path := root + "/app.log"
+
+	updates, err := manager.WatchFile(path)
+	if err != nil {
+		t.Fatalf("监控远程文件失败: %v", err)
+	}
+	defer manager.UnwatchFile(path, updates)
+
+	appendToFile(t, localPath, "second\nthird\n")
+
+	select {
+	case update := <-updates:
+		if update.Type != "append" || update.StartOffset != 6 || update.EndOffset != 19 ||
+			len(update.Entries) != 2 || update.Entries[1].ByteOffset != 13 {
+			t.Errorf("远程文件更新不正确: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待远程文件更新超时")
+	}
+
+	// 断线重连后按字节位置补齐
+	resync, _, err := manager.ReadRange(path, 8, 0)
+	if err != nil {
+		t.Fatalf("读取远程文件范围失败: %v", err)
+	}
+	if len(resync.Entries) != 1 || resync.Entries[0].Raw != "third" || resync.StartOffset != 13 {
+		t.Errorf("远程文件范围内容不正确: %+v", resync)
+	}
+}
+
+func TestLogManager_MountSource(t *testing.T) {
+	manager := newStreamTestManager(t, t.TempDir())
+	fixtures := sourcetest.NewMemory("mem://fixtures")
+	fixtures.Write("app.log", `{"time":"2023-01-01T10:00:00Z","level":"info","msg":"started"}`+"\n")
+	fixtures.Write("workers/queue.log", "job 1 done\n")
+
+	if err := manager.Mount("fixtures", fixtures); err != nil {
+		t.Fatalf("挂载日志源失败: %v", err)
+	}
+	if err := manager.Mount("fixtures", sourcetest.NewMemory("mem://other")); err == nil {
+		t.Error("期望重复的挂载名称失败")
+	}
+
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "fixtures" || files[0].Path != "mem://fixtures" || len(files[0].Children) != 2 {
+		t.Fatalf("挂载的文件树不正确: %+v", files)
+	}
+
+	content, err := manager.ReadLogFile(context.Background(), "mem://fixtures/app.log", 0, 10)
+	if err != nil {
+		t.Fatalf("读取挂载的文件失败: %v", err)
+	}
+	if content.Format != "JSON" || len(content.Entries) != 1 || content.Entries[0].Message != "started" {
+		t.Errorf("挂载的文件内容不正确: %+v", content)
+	}
+
+	updates, err := manager.WatchFile("mem://fixtures/workers/queue.log")
+	if err != nil {
+		t.Fatalf("监控挂载的文件失败: %v", err)
+	}
+	fixtures.Append("workers/queue.log", "job 2 done\n")
+	select {
+	case update := <-updates:
+		if update.StartOffset != 11 || len(update.Entries) != 1 || update.Entries[0].Raw != "job 2 done" {
+			t.Errorf("挂载的文件更新不正确: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待挂载的文件更新超时")
+	}
+	manager.UnwatchFile("mem://fixtures/workers/queue.log", updates)
+	if n := fixtures.Followers("workers/queue.log"); n != 0 {
+		t.Errorf("停止监控后仍有 %d 个跟踪者", n)
+	}
+
+	if err := manager.Unmount("fixtures"); err != nil {
+		t.Fatalf("卸载日志源失败: %v", err)
+	}
+	if files, _ := manager.GetLogFiles(); len(files) != 0 {
+		t.Errorf("卸载后仍然列出文件: %+v", files)
+	}
+	if _, err := manager.ReadLogFile(context.Background(), "mem://fixtures/app.log", 0, 10); err == nil {
+		t.Error("期望读取已卸载的日志源失败")
+	}
+}
+
+func TestLogManager_DockerContainers(t *testing.T) {
+	server, err := dockertest.NewServer(t.TempDir())
+	if err != nil {
+		t.Fatalf("启动 Docker 服务器失败: %v", err)
+	}
+	t.Cleanup(server.Close)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.AddContainer("api", map[string]string{"com.docker.compose.service": "api"}, false)
+	server.Log("api", "stdout", `{"level":"info","msg":"listening"}`, at)
+	server.Log("api", "stderr", "ERROR upstream timeout", at.Add(time.Second))
+
+	root := "docker://" + server.Socket
+	cfg := createTestConfig([]string{root})
+	cfg.Docker.PollInterval = 20 * time.Millisecond
+	manager := NewLogManager(cfg, manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != root || len(files[0].Children) != 1 || files[0].Children[0].Format != "Docker" {
+		t.Fatalf("容器文件树不正确: %+v", files)
+	}
+
+	path := root + "/api.log"
+	content, err := manager.ReadLogFile(context.Background(), path, 0, 10)
+	if err != nil {
+		t.Fatalf("读取容器日志失败: %v", err)
+	}
+	if len(content.Entries) != 2 {
+		t.Fatalf("期望 2 条日志，得到 %+v", content.Entries)
+	}
+	first, second := content.Entries[0], content.Entries[1]
+	if first.Message != "listening" || !first.Timestamp.Equal(at) || first.Fields["label.com.docker.compose.service"] != "api" {
+		t.Errorf("第一条日志不正确: %+v", first)
+	}
+	if second.Level != "ERROR" || second.Fields["docker_stream"] != "stderr" {
+		t.Errorf("第二条日志不正确: %+v", second)
+	}
+
+	updates, err := manager.WatchFile(path)
+	if err != nil {
+		t.Fatalf("监控容器日志失败: %v", err)
+	}
+	defer manager.UnwatchFile(path, updates)
+
+	time.Sleep(100 * time.Millisecond)
+	server.Log("api", "stdout", "request served", at.Add(2*time.Second))
+	select {
+	case update := <-updates:
+		if len(update.Entries) != 1 || update.Entries[0].Message != "request served" || update.Entries[0].Fields["docker_stream"] != "stdout" {
+			t.Errorf("容器日志更新不正确: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待容器日志更新超时")
+	}
+}
+
+func TestLogManager_JournalUnits(t *testing.T) {
+	journal, err := journaltest.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 journal 失败: %v", err)
+	}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	journal.Add("nginx.service", 6, "started", at)
+	journal.Add("nginx.service", 3, "upstream timed out", at.Add(time.Second))
+	journal.Add("sshd.service", 5, "accepted publickey", at)
+
+	root := "journal://" + journal.Dir
+	cfg := createTestConfig([]string{root})
+	cfg.Journal.Command = journal.Command
+	cfg.Journal.PollInterval = 20 * time.Millisecond
+	manager := NewLogManager(cfg, manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != root || len(files[0].Children) != 2 {
+		t.Fatalf("单元文件树不正确: %+v", files)
+	}
+
+	path := root + "/nginx.service.log"
+	content, err := manager.ReadLogFile(context.Background(), path, 0, 10)
+	if err != nil {
+		t.Fatalf("读取单元日志失败: %v", err)
+	}
+	if len(content.Entries) != 2 {
+		t.Fatalf("期望 2 条日志，得到 %+v", content.Entries)
+	}
+	first, second := content.Entries[0], content.Entries[1]
+	if first.LogType != "Journal" || first.Level != "INFO" || !first.Timestamp.Equal(at) || first.Fields["_SYSTEMD_UNIT"] != "nginx.service" {
+		t.Errorf("第一条日志不正确: %+v", first)
+	}
+	if second.Level != "ERROR" || second.Message != "upstream timed out" || second.Fields["_HOSTNAME"] != "testhost" || second.Fields["_PID"] == "" {
+		t.Errorf("第二条日志不正确: %+v", second)
+	}
+
+	updates, err := manager.WatchFile(path)
+	if err != nil {
+		t.Fatalf("监控单元日志失败: %v", err)
+	}
+	defer manager.UnwatchFile(path, updates)
+
+	time.Sleep(300 * time.Millisecond)
+	journal.Add("nginx.service", 4, "worker process exited", at.Add(2*time.Second))
+	select {
+	case update := <-updates:
+		if len(update.Entries) != 1 || update.Entries[0].Message != "worker process exited" || update.Entries[0].Level != "WARN" {
+			t.Errorf("单元日志更新不正确: %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待单元日志更新超时")
+	}
+}
+
+func TestLogManager_S3Prefix(t *testing.T) {
+	server := s3test.NewServer("app-logs")
+	server.PageSize = 3
+	t.Cleanup(server.Close)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(w, "level=info msg=\"batch %d done\" request_id=r%d\n", i, i)
+	}
+	w.Close()
+	server.Put("app-logs", "2024/03/01/api.log.gz", gz.Bytes())
+	server.Put("app-logs", "2024/03/01/worker.log", []byte("level=error msg=\"batch 42 failed\"\nlevel=info msg=idle\n"))
+	server.Put("app-logs", "2024/03/02/api.log", []byte("level=info msg=\"batch 42 retried\"\n"))
+	server.Put("app-logs", "2024/03/02/image.png.gz", []byte("not a log"))
+
+	root := "s3://app-logs/2024"
+	cfg := createTestConfig([]string{root})
+	cfg.S3.Endpoint, cfg.S3.PathStyle = server.URL, true
+	manager := NewLogManager(cfg, manualWatcher{}, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	t.Cleanup(func() { manager.Stop() })
+
+	files, err := manager.GetLogFiles()
+	if err != nil {
+		t.Fatalf("获取日志文件失败: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != root || len(files[0].Children) != 1 || len(files[0].Children[0].Children) != 2 {
+		t.Fatalf("对象存储文件树不正确: %+v", files)
+	}
+
+	// .gz 对象按解压后的内容分页读取；第一次读取尾部时大小未知，先完整读取一遍
+	ctx := context.Background()
+	gzPath := root + "/03/01/api.log.gz"
+	tail, err := manager.ReadLogFileFromTail(ctx, gzPath, 1)
+	if err != nil || len(tail.Entries) != 1 || tail.Entries[0].Message != "batch 199 done" || tail.TotalLines != 200 || tail.Offset != 199 {
+		t.Errorf(".gz 对象的尾部不正确: %+v, %v", tail, err)
+	}
+	content, err := manager.ReadLogFile(ctx, gzPath, 100, 5)
+	if err != nil {
+		t.Fatalf("读取 .gz 对象失败: %v", err)
+	}
+	if len(content.Entries) != 5 || content.TotalLines != 200 || content.Entries[0].Message != "batch 100 done" || content.Format != "Logfmt" {
+		t.Errorf(".gz 对象的内容不正确: %+v", content)
+	}
+
+	// 搜索前缀时同时读取其下的所有对象，结果按文件排列
+	result, err := manager.SearchLogs(ctx, types.SearchQuery{Path: root, Query: "batch 42", Limit: 10})
+	if err != nil {
+		t.Fatalf("搜索前缀失败: %v", err)
+	}
+	if result.TotalCount != 3 || len(result.Entries) != 3 {
+		t.Fatalf("期望 3 条结果，得到 %+v", result)
+	}
+	expected := []string{gzPath, root + "/03/01/worker.log", root + "/03/02/api.log"}
+	for i, entry := range result.Entries {
+		if entry.Path != expected[i] {
+			t.Errorf("第 %d 条结果的文件不正确: %s", i, entry.Path)
+		}
+	}
+
+	page, err := manager.SearchLogs(ctx, types.SearchQuery{Path: root + "/03/01", Query: "batch", Offset: 199, Limit: 10})
+	if err != nil || page.TotalCount != 201 || len(page.Entries) != 2 || page.HasMore || page.Entries[1].Level != "ERROR" {
+		t.Errorf("前缀搜索的分页不正确: %+v, %v", page, err)
+	}
+}
@@ -0,0 +1,319 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+	"go.uber.org/zap"
+)
+
+// maxSubscriberBacklog 每个订阅者最多积压的更新数，超出时丢弃最早的更新并以 gap 更新通知
+const maxSubscriberBacklog = 1000
+
+// fileWatch 单个文件的监控，文件事件产生的更新写入 source，由一个 goroutine 分发给所有订阅者
+type fileWatch struct {
+	path        string
+	source      chan types.LogUpdate
+	subscribers map[<-chan types.LogUpdate]*watchSubscriber
+
+	// stop 停止通过日志源跟踪文件
+	stop func()
+}
+
+// watchSubscriber 文件更新的订阅者，积压的更新由独立的 goroutine 转发，慢速订阅者不影响其他订阅者
+type watchSubscriber struct {
+	id    string
+	since time.Time
+	in    chan types.LogUpdate
+	out   chan types.LogUpdate
+	done  chan struct{}
+
+	mutex     sync.Mutex
+	delivered uint64
+	dropped   uint64
+	backlog   int
+	lastSeq   uint64
+}
+
+func newWatchSubscriber(id string) *watchSubscriber {
+	return &watchSubscriber{
+		id:    id,
+		since: time.Now(),
+		in:    make(chan types.LogUpdate, 100),
+		out:   make(chan types.LogUpdate, 100),
+		done:  make(chan struct{}),
+	}
+}
+
+// run 将更新转发给订阅者，直到订阅被释放或文件不再监控
+func (s *watchSubscriber) run() {
+	defer close(s.out)
+
+	var backlog []types.LogUpdate
+	var gap *types.LogUpdate
+	for {
+		// 有积压时才尝试发送，丢弃的范围在后续更新之前送达
+		var out chan types.LogUpdate
+		var next types.LogUpdate
+		if gap != nil {
+			out, next = s.out, *gap
+		} else if len(backlog) > 0 {
+			out, next = s.out, backlog[0]
+		}
+
+		select {
+		case update, ok := <-s.in:
+			if !ok {
+				return
+			}
+			backlog = append(backlog, update)
+			if len(backlog) > maxSubscriberBacklog {
+				gap = mergeGapUpdate(gap, backlog[0])
+				backlog = backlog[1:]
+				s.mutex.Lock()
+				s.dropped++
+				s.mutex.Unlock()
+				watchUpdatesDropped.Inc()
+			}
+
+		case out <- next:
+			s.mutex.Lock()
+			if gap != nil {
+				gap = nil
+			} else {
+				backlog = backlog[1:]
+				s.delivered++
+				s.lastSeq = next.Seq
+			}
+			s.mutex.Unlock()
+
+		case <-s.done:
+			return
+		}
+
+		s.mutex.Lock()
+		s.backlog = len(backlog)
+		s.mutex.Unlock()
+	}
+}
+
+// stats 返回订阅者的统计
+func (s *watchSubscriber) stats() types.WatchSubscriberStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return types.WatchSubscriberStats{
+		ID:        s.id,
+		Since:     s.since,
+		Delivered: s.delivered,
+		Dropped:   s.dropped,
+		Backlog:   s.backlog,
+		LastSeq:   s.lastSeq,
+	}
+}
+
+// mergeGapUpdate 将丢弃的更新合并到 gap 更新中，gap 更新的序号为丢弃的最后一个更新的序号
+func mergeGapUpdate(gap *types.LogUpdate, dropped types.LogUpdate) *types.LogUpdate {
+	if gap == nil {
+		return &types.LogUpdate{
+			Path:        dropped.Path,
+			Type:        "gap",
+			Seq:         dropped.Seq,
+			StartOffset: dropped.StartOffset,
+			EndOffset:   dropped.EndOffset,
+		}
+	}
+	if dropped.StartOffset < gap.StartOffset {
+		gap.StartOffset = dropped.StartOffset
+	}
+	if dropped.EndOffset > gap.EndOffset {
+		gap.EndOffset = dropped.EndOffset
+	}
+	gap.Seq = dropped.Seq
+	return gap
+}
+
+// WatchFile 监控文件变化，每次调用返回独立的更新通道，使用完后需要通过 UnwatchFile 释放
+func (lm *LogManager) WatchFile(path string) (<-chan types.LogUpdate, error) {
+	return lm.WatchFileAs(path, "")
+}
+
+// WatchFileAs 以指定名称订阅文件更新，第一个订阅者开始监控文件
+func (lm *LogManager) WatchFileAs(path, subscriber string) (<-chan types.LogUpdate, error) {
+	lm.watchLifecycle.Lock()
+	defer lm.watchLifecycle.Unlock()
+
+	// 初始化文件位置为文件末尾，只读取新增内容
+	// 获取远程文件的大小需要一次往返，不在持有 watchMutex 时获取；watchLifecycle 保证期间不会有其他监控建立
+	lm.watchMutex.RLock()
+	_, exists := lm.fileWatches[path]
+	lm.watchMutex.RUnlock()
+	var position int64
+	if !exists {
+		if size, err := lm.fileSize(path); err == nil {
+			position = size
+		}
+	}
+
+	lm.watchMutex.Lock()
+	watch, exists := lm.fileWatches[path]
+	if !exists {
+		lm.filePositions[path] = position
+
+		watch = &fileWatch{
+			path:        path,
+			source:      make(chan types.LogUpdate, 100),
+			subscribers: make(map[<-chan types.LogUpdate]*watchSubscriber),
+		}
+		lm.watchedFiles[path] = watch.source
+		lm.fileWatches[path] = watch
+		lm.watchMutex.Unlock()
+
+		// 设置文件监控回调
+		source := watch.source
+		callback := func(event types.FileEvent) {
+			logger.Debug("File event received",
+				zap.String("path", path),
+				zap.String("event_type", event.Type))
+			lm.handleFileEvent(path, event, source)
+		}
+		stop, err := lm.followFile(path, callback)
+		if err != nil {
+			lm.watchMutex.Lock()
+			delete(lm.watchedFiles, path)
+			delete(lm.fileWatches, path)
+			delete(lm.filePositions, path)
+			delete(lm.readLocks, path)
+			lm.watchMutex.Unlock()
+			close(source)
+			logger.Error("Failed to watch file",
+				zap.String("path", path),
+				zap.Error(err))
+			return nil, fmt.Errorf("监控文件失败: %w", err)
+		}
+
+		// UnwatchFile 同样持有 watchLifecycle，不会在设置之前读取 stop
+		watch.stop = stop
+		go lm.fanOut(watch)
+		logger.Info("Started watching file", zap.String("path", path))
+		lm.watchMutex.Lock()
+	}
+
+	lm.subscriberSeq++
+	if subscriber == "" {
+		subscriber = fmt.Sprintf("subscriber-%d", lm.subscriberSeq)
+	}
+	sub := newWatchSubscriber(subscriber)
+	watch.subscribers[sub.out] = sub
+	lm.watchMutex.Unlock()
+	watchSubscribers.Inc()
+
+	go sub.run()
+	logger.Debug("File watch subscriber added",
+		zap.String("path", path),
+		zap.String("subscriber", subscriber))
+	return sub.out, nil
+}
+
+// UnwatchFile 释放 WatchFile 返回的更新通道，最后一个订阅者释放后停止监控文件
+func (lm *LogManager) UnwatchFile(path string, updates <-chan types.LogUpdate) error {
+	lm.watchLifecycle.Lock()
+	defer lm.watchLifecycle.Unlock()
+
+	lm.watchMutex.Lock()
+	watch, exists := lm.fileWatches[path]
+	if !exists {
+		lm.watchMutex.Unlock()
+		return fmt.Errorf("文件未被监控: %s", path)
+	}
+	sub, exists := watch.subscribers[updates]
+	if !exists {
+		lm.watchMutex.Unlock()
+		return fmt.Errorf("订阅不存在: %s", path)
+	}
+	delete(watch.subscribers, updates)
+	close(sub.done)
+	watchSubscribers.Dec()
+
+	last := len(watch.subscribers) == 0
+	if last {
+		// 关闭前持有 watchMutex，文件事件不会再向 source 发送
+		delete(lm.fileWatches, path)
+		delete(lm.watchedFiles, path)
+		delete(lm.filePositions, path)
+		delete(lm.readLocks, path)
+		close(watch.source)
+	}
+	lm.watchMutex.Unlock()
+
+	if !last {
+		return nil
+	}
+	logger.Info("Stopped watching file", zap.String("path", path))
+	watch.stop()
+	return nil
+}
+
+// followFile 通过文件所属的日志源开始监控文件（本地文件由 FileWatcher 通知），返回停止监控的函数
+func (lm *LogManager) followFile(path string, callback func(types.FileEvent)) (func(), error) {
+	src, resolved, err := lm.sourceFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return src.Follow(resolved, callback)
+}
+
+// fanOut 将文件的更新分发给所有订阅者，source 关闭后关闭剩余订阅者的通道
+func (lm *LogManager) fanOut(watch *fileWatch) {
+	for update := range watch.source {
+		lm.watchMutex.RLock()
+		subscribers := make([]*watchSubscriber, 0, len(watch.subscribers))
+		for _, sub := range watch.subscribers {
+			subscribers = append(subscribers, sub)
+		}
+		lm.watchMutex.RUnlock()
+
+		for _, sub := range subscribers {
+			select {
+			case sub.in <- update:
+			case <-sub.done:
+			}
+		}
+	}
+
+	lm.watchMutex.Lock()
+	for updates, sub := range watch.subscribers {
+		close(sub.in)
+		delete(watch.subscribers, updates)
+		watchSubscribers.Dec()
+	}
+	lm.watchMutex.Unlock()
+}
+
+// GetWatchStats 返回正在监控的文件及每个订阅者的统计
+func (lm *LogManager) GetWatchStats() []types.WatchStats {
+	lm.watchMutex.RLock()
+	defer lm.watchMutex.RUnlock()
+
+	stats := make([]types.WatchStats, 0, len(lm.fileWatches))
+	for path, watch := range lm.fileWatches {
+		entry := types.WatchStats{
+			Path:        path,
+			Seq:         lm.fileSeqs[path],
+			Offset:      lm.filePositions[path],
+			Subscribers: make([]types.WatchSubscriberStats, 0, len(watch.subscribers)),
+		}
+		for _, sub := range watch.subscribers {
+			entry.Subscribers = append(entry.Subscribers, sub.stats())
+		}
+		sort.Slice(entry.Subscribers, func(i, j int) bool {
+			return entry.Subscribers[i].Since.Before(entry.Subscribers[j].Since)
+		})
+		stats = append(stats, entry)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+	return stats
+}
@@ -0,0 +1,240 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/source/sourcetest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// countingWatcher 记录监控和取消监控次数的文件监控器
+type countingWatcher struct {
+	mutex     sync.Mutex
+	watched   map[string]int
+	unwatched map[string]int
+}
+
+func (w *countingWatcher) WatchFile(path string, callback func(types.FileEvent)) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.watched[path]++
+	return nil
+}
+
+func (w *countingWatcher) UnwatchFile(path string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.unwatched[path]++
+	return nil
+}
+
+func (w *countingWatcher) Start() error { return nil }
+func (w *countingWatcher) Stop() error  { return nil }
+
+func receiveUpdate(t *testing.T, updates <-chan types.LogUpdate) types.LogUpdate {
+	t.Helper()
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			t.Fatal("更新通道已关闭")
+		}
+		return update
+	case <-time.After(2 * time.Second):
+		t.Fatal("没有收到更新")
+	}
+	return types.LogUpdate{}
+}
+
+func TestLogManager_WatchFanOut(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "app.log")
+	if err := os.WriteFile(logFilePath, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	watcher := &countingWatcher{watched: map[string]int{}, unwatched: map[string]int{}}
+	manager := NewLogManager(createTestConfig([]string{tempDir}), watcher, cache.NewMemoryCache(10, time.Minute)).(*LogManager)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("启动日志管理器失败: %v", err)
+	}
+	defer manager.Stop()
+
+	first, err := manager.WatchFileAs(logFilePath, "tab-1")
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	second, err := manager.WatchFileAs(logFilePath, "tab-2")
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	if watcher.watched[logFilePath] != 1 {
+		t.Errorf("多个订阅者应共享一个文件监控，实际监控了 %d 次", watcher.watched[logFilePath])
+	}
+
+	// 每个订阅者都收到全部更新
+	for i, line := range []string{"first\n", "second\n"} {
+		appendToFile(t, logFilePath, line)
+		manager.handleFileModify(logFilePath, manager.watchedFiles[logFilePath])
+		for _, updates := range []<-chan types.LogUpdate{first, second} {
+			if update := receiveUpdate(t, updates); update.Seq != uint64(i+1) {
+				t.Errorf("期望序号 %d，得到 %d", i+1, update.Seq)
+			}
+		}
+	}
+
+	stats := manager.GetWatchStats()
+	if len(stats) != 1 || len(stats[0].Subscribers) != 2 {
+		t.Fatalf("期望 1 个文件 2 个订阅者，得到 %+v", stats)
+	}
+	if sub := stats[0].Subscribers[0]; sub.ID != "tab-1" || sub.Delivered != 2 || sub.LastSeq != 2 {
+		t.Errorf("订阅者统计不正确: %+v", sub)
+	}
+
+	// 释放一个订阅者后继续监控，通道被关闭
+	if err := manager.UnwatchFile(logFilePath, first); err != nil {
+		t.Fatalf("释放订阅失败: %v", err)
+	}
+	if _, ok := <-first; ok {
+		t.Error("释放后的通道应被关闭")
+	}
+	if watcher.unwatched[logFilePath] != 0 {
+		t.Error("仍有订阅者时不应停止监控文件")
+	}
+
+	// 最后一个订阅者释放后停止监控文件
+	if err := manager.UnwatchFile(logFilePath, second); err != nil {
+		t.Fatalf("释放订阅失败: %v", err)
+	}
+	if watcher.unwatched[logFilePath] != 1 {
+		t.Errorf("最后一个订阅者释放后应停止监控文件")
+	}
+	if len(manager.GetWatchStats()) != 0 {
+		t.Error("停止监控后不应有统计")
+	}
+	if err := manager.UnwatchFile(logFilePath, second); err == nil {
+		t.Error("重复释放应返回错误")
+	}
+
+	// 重新订阅时从文件末尾开始，序号继续递增
+	third, err := manager.WatchFile(logFilePath)
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	appendToFile(t, logFilePath, "third\n")
+	manager.handleFileModify(logFilePath, manager.watchedFiles[logFilePath])
+	if update := receiveUpdate(t, third); update.Seq != 3 || update.StartOffset != 13 || len(update.Entries) != 1 {
+		t.Errorf("重新订阅后的更新不正确: seq=%d start=%d entries=%d", update.Seq, update.StartOffset, len(update.Entries))
+	}
+}
+
+func TestWatchSubscriber_BacklogOverflow(t *testing.T) {
+	sub := newWatchSubscriber("slow")
+	go sub.run()
+	defer close(sub.done)
+
+	// 订阅者不读取，积压超出上限后丢弃最早的更新
+	total := maxSubscriberBacklog + cap(sub.out) + 5
+	for i := 1; i <= total; i++ {
+		sub.in <- types.LogUpdate{Type: "append", Seq: uint64(i), StartOffset: int64(i * 10), EndOffset: int64(i*10 + 10)}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sub.stats().Dropped < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if dropped := sub.stats().Dropped; dropped != 5 {
+		t.Fatalf("期望丢弃 5 个更新，得到 %d", dropped)
+	}
+
+	// 先收到已进入通道的更新，然后是丢弃范围的 gap 更新，再是之后的更新
+	for i := 1; i <= cap(sub.out); i++ {
+		if update := <-sub.out; update.Seq != uint64(i) {
+			t.Fatalf("期望序号 %d，得到 %d", i, update.Seq)
+		}
+	}
+	gap := <-sub.out
+	first := uint64(cap(sub.out) + 1)
+	if gap.Type != "gap" || gap.StartOffset != int64(first*10) || gap.EndOffset != int64((first+5)*10) || gap.Seq != first+4 {
+		t.Errorf("gap 更新不正确: %+v", gap)
+	}
+	if next := <-sub.out; next.Seq != first+5 {
+		t.Errorf("gap 之后期望序号 %d，得到 %d", first+5, next.Seq)
+	}
+}
+
+// blockingSource 打开 blocked 指定的文件或 blocked 为根路径时列出文件会阻塞，直到 release 被关闭
+type blockingSource struct {
+	*sourcetest.Memory
+	blocked string
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSource) block(p string) {
+	if p == s.blocked {
+		s.opened <- struct{}{}
+		<-s.release
+	}
+}
+
+func (s *blockingSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	s.block(p)
+	return s.Memory.Open(ctx, p, offset, length)
+}
+
+func (s *blockingSource) Walk(ctx context.Context) ([]source.FileInfo, error) {
+	s.block(s.Root())
+	return s.Memory.Walk(ctx)
+}
+
+func TestLogManager_SlowReadDoesNotBlockOtherFiles(t *testing.T) {
+	manager := newStreamTestManager(t, t.TempDir())
+	src := &blockingSource{
+		Memory:  sourcetest.NewMemory("mem://slow"),
+		blocked: "mem://slow/a.log",
+		opened:  make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	src.Write("a.log", "")
+	src.Write("b.log", "")
+	if err := manager.Mount("slow", src); err != nil {
+		t.Fatalf("挂载日志源失败: %v", err)
+	}
+	slow, err := manager.WatchFile("mem://slow/a.log")
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+	fast, err := manager.WatchFile("mem://slow/b.log")
+	if err != nil {
+		t.Fatalf("监控文件失败: %v", err)
+	}
+
+	// a.log 的读取阻塞在日志源中，不应持有 watchMutex
+	go src.Append("a.log", "slow line\n")
+	select {
+	case <-src.opened:
+	case <-time.After(2 * time.Second):
+		t.Fatal("没有读取 a.log")
+	}
+
+	src.Append("b.log", "fast line\n")
+	if update := receiveUpdate(t, fast); len(update.Entries) != 1 || update.Entries[0].Raw != "fast line" {
+		t.Errorf("b.log 的更新不正确: %+v", update)
+	}
+	if stats := manager.GetWatchStats(); len(stats) != 2 {
+		t.Errorf("期望 2 个监控的文件，得到 %+v", stats)
+	}
+
+	close(src.release)
+	if update := receiveUpdate(t, slow); update.StartOffset != 0 || len(update.Entries) != 1 || update.Entries[0].Raw != "slow line" {
+		t.Errorf("a.log 的更新不正确: %+v", update)
+	}
+}
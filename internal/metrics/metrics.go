@@ -0,0 +1,571 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 默认的耗时直方图分桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 默认的指标注册表，各模块在包初始化时注册指标，由 /metrics 输出
+var Default = NewRegistry()
+
+func init() {
+	logError(Default.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	}))
+	logError(Default.NewGaugeFunc("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", func() float64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return float64(m.HeapAlloc)
+	}))
+}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// collector 一个指标族，按名称排序输出
+type collector interface {
+	name() string
+	// sampleNames 输出的样本名称，直方图包含 _bucket、_sum 和 _count
+	sampleNames() []string
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors map[string]collector
+	samples    map[string]string // 样本名称到所属指标族
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector), samples: make(map[string]string)}
+}
+
+// register 注册指标族，名称不合法或与已注册指标的样本名称冲突时返回错误
+func (r *Registry) register(c collector) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		return fmt.Errorf("metrics: duplicate metric %s", c.name())
+	}
+	names := c.sampleNames()
+	for _, sample := range names {
+		if owner, exists := r.samples[sample]; exists {
+			return fmt.Errorf("metrics: %s conflicts with samples of %s", c.name(), owner)
+		}
+	}
+	r.collectors[c.name()] = c
+	for _, sample := range names {
+		r.samples[sample] = c.name()
+	}
+	return nil
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mutex.RUnlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// desc 指标族的名称、说明和标签名
+type desc struct {
+	fqName string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) name() string {
+	return d.fqName
+}
+
+func (d *desc) sampleNames() []string {
+	return []string{d.fqName}
+}
+
+// validate 检查指标名称和标签名是否符合 Prometheus 的命名规则
+func (d *desc) validate(reserved ...string) error {
+	if !metricNameRE.MatchString(d.fqName) {
+		return fmt.Errorf("metrics: invalid metric name %q", d.fqName)
+	}
+	seen := make(map[string]bool, len(d.labels))
+	for _, label := range d.labels {
+		if !labelNameRE.MatchString(label) || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("metrics: %s has invalid label name %q", d.fqName, label)
+		}
+		for _, r := range reserved {
+			if label == r {
+				return fmt.Errorf("metrics: %s uses reserved label name %q", d.fqName, label)
+			}
+		}
+		if seen[label] {
+			return fmt.Errorf("metrics: %s has duplicate label name %q", d.fqName, label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.fqName, escapeHelp(d.help), d.fqName, d.kind)
+}
+
+// labelPairs 将标签名和值格式化为 {a="1",b="2"}，extra 追加在最后（直方图的 le）
+func (d *desc) labelPairs(values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// vec 按标签值保存子指标
+type vec[T any] struct {
+	desc
+	mutex    sync.RWMutex
+	children map[string]*child[T]
+	create   func() *T
+}
+
+type child[T any] struct {
+	values []string
+	metric *T
+}
+
+func newVec[T any](d desc, create func() *T) *vec[T] {
+	return &vec[T]{desc: d, children: make(map[string]*child[T]), create: create}
+}
+
+// with 返回标签值对应的子指标，不存在时创建；标签值数量不匹配时返回错误
+func (v *vec[T]) with(values []string) (*T, error) {
+	if len(values) != len(v.labels) {
+		return nil, fmt.Errorf("metrics: %s expects %d label values, got %d", v.fqName, len(v.labels), len(values))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mutex.RLock()
+	c, exists := v.children[key]
+	v.mutex.RUnlock()
+	if exists {
+		return c.metric, nil
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if c, exists = v.children[key]; !exists {
+		c = &child[T]{values: append([]string(nil), values...), metric: v.create()}
+		v.children[key] = c
+	}
+	return c.metric, nil
+}
+
+// mustWith 返回标签值对应的子指标，出错时记录错误并返回不输出的子指标，调用方不需要处理错误
+func (v *vec[T]) mustWith(values []string) *T {
+	metric, err := v.with(values)
+	if err != nil {
+		log.Printf("%v", err)
+		return v.create()
+	}
+	return metric
+}
+
+// sorted 返回按标签值排序的子指标
+func (v *vec[T]) sorted() []*child[T] {
+	v.mutex.RLock()
+	children := make([]*child[T], 0, len(v.children))
+	for _, c := range v.children {
+		children = append(children, c)
+	}
+	v.mutex.RUnlock()
+	sort.Slice(children, func(i, j int) bool {
+		return strings.Join(children[i].values, "\xff") < strings.Join(children[j].values, "\xff")
+	})
+	return children
+}
+
+// value 可以原子更新的浮点数
+type value struct {
+	bits uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&v.bits, old, next) {
+			return
+		}
+	}
+}
+
+func (v *value) set(f float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(f))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+// Counter 只增不减的计数器
+type Counter struct {
+	v value
+}
+
+// Inc 加 1
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add 增加非负数
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.v.add(delta)
+}
+
+// Value 返回当前值
+func (c *Counter) Value() float64 {
+	return c.v.get()
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// GetMetricWithLabelValues 返回标签值对应的计数器，值的顺序与注册时的标签名相同
+func (c *CounterVec) GetMetricWithLabelValues(values ...string) (*Counter, error) {
+	return c.with(values)
+}
+
+// WithLabelValues 与 GetMetricWithLabelValues 相同，标签值数量不匹配时记录错误并返回不输出的计数器
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.mustWith(values)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, child := range c.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.fqName, c.labelPairs(child.values), formatFloat(child.metric.Value()))
+	}
+}
+
+// Gauge 可增可减的数值
+type Gauge struct {
+	v value
+}
+
+// Set 设置当前值
+func (g *Gauge) Set(f float64) {
+	g.v.set(f)
+}
+
+// Inc 加 1
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+// Dec 减 1
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// Value 返回当前值
+func (g *Gauge) Value() float64 {
+	return g.v.get()
+}
+
+// GaugeVec 带标签的数值
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+// GetMetricWithLabelValues 返回标签值对应的数值
+func (g *GaugeVec) GetMetricWithLabelValues(values ...string) (*Gauge, error) {
+	return g.with(values)
+}
+
+// WithLabelValues 与 GetMetricWithLabelValues 相同，标签值数量不匹配时记录错误并返回不输出的数值
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.mustWith(values)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	for _, child := range g.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", g.fqName, g.labelPairs(child.values), formatFloat(child.metric.Value()))
+	}
+}
+
+// gaugeFunc 输出时调用函数取值的数值
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", g.fqName, formatFloat(g.fn()))
+}
+
+// Histogram 分桶统计的观测值
+type Histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(f float64) {
+	i := sort.SearchFloat64s(h.buckets, f)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += f
+}
+
+// snapshot 返回累计的分桶计数、总数和总和
+func (h *Histogram) snapshot() ([]uint64, uint64, float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i, n := range h.counts {
+		total += n
+		cumulative[i] = total
+	}
+	return cumulative, h.count, h.sum
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// GetMetricWithLabelValues 返回标签值对应的直方图
+func (h *HistogramVec) GetMetricWithLabelValues(values ...string) (*Histogram, error) {
+	return h.with(values)
+}
+
+// WithLabelValues 与 GetMetricWithLabelValues 相同，标签值数量不匹配时记录错误并返回不输出的直方图
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.mustWith(values)
+}
+
+func (h *HistogramVec) sampleNames() []string {
+	return []string{h.fqName, h.fqName + "_bucket", h.fqName + "_sum", h.fqName + "_count"}
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, child := range h.sorted() {
+		cumulative, count, sum := child.metric.snapshot()
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelPairs(child.values, "le", formatFloat(upper)), cumulative[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.fqName, h.labelPairs(child.values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.fqName, h.labelPairs(child.values), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.fqName, h.labelPairs(child.values), count)
+	}
+}
+
+// NewCounterVec 注册带标签的计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) (*CounterVec, error) {
+	c := &CounterVec{newVec(desc{name, help, "counter", labels}, func() *Counter { return &Counter{} })}
+	if err := c.validate(); err != nil {
+		return c, err
+	}
+	return c, r.register(c)
+}
+
+// NewCounter 注册计数器
+func (r *Registry) NewCounter(name, help string) (*Counter, error) {
+	c, err := r.NewCounterVec(name, help)
+	return c.mustWith(nil), err
+}
+
+// NewGaugeVec 注册带标签的数值
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) (*GaugeVec, error) {
+	g := &GaugeVec{newVec(desc{name, help, "gauge", labels}, func() *Gauge { return &Gauge{} })}
+	if err := g.validate(); err != nil {
+		return g, err
+	}
+	return g, r.register(g)
+}
+
+// NewGauge 注册数值
+func (r *Registry) NewGauge(name, help string) (*Gauge, error) {
+	g, err := r.NewGaugeVec(name, help)
+	return g.mustWith(nil), err
+}
+
+// NewGaugeFunc 注册输出时调用 fn 取值的数值
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) error {
+	g := &gaugeFunc{desc: desc{name, help, "gauge", nil}, fn: fn}
+	if err := g.validate(); err != nil {
+		return err
+	}
+	return r.register(g)
+}
+
+// NewHistogramVec 注册带标签的直方图，buckets 为空时使用 DefaultBuckets
+// 分桶上限中的 +Inf 会被忽略（总是输出），NaN 或重复的上限返回错误
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) (*HistogramVec, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, 0, len(buckets))
+	var bucketErr error
+	for _, upper := range buckets {
+		if math.IsNaN(upper) {
+			bucketErr = fmt.Errorf("metrics: %s has NaN bucket", name)
+			continue
+		}
+		if !math.IsInf(upper, 1) {
+			sorted = append(sorted, upper)
+		}
+	}
+	sort.Float64s(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			bucketErr = fmt.Errorf("metrics: %s has duplicate bucket %s", name, formatFloat(sorted[i]))
+		}
+	}
+
+	h := &HistogramVec{buckets: sorted}
+	h.vec = newVec(desc{name, help, "histogram", labels}, func() *Histogram {
+		return &Histogram{buckets: sorted, counts: make([]uint64, len(sorted))}
+	})
+	if bucketErr != nil {
+		return h, bucketErr
+	}
+	if err := h.validate("le"); err != nil {
+		return h, err
+	}
+	return h, r.register(h)
+}
+
+// NewHistogram 注册直方图
+func (r *Registry) NewHistogram(name, help string, buckets []float64) (*Histogram, error) {
+	h, err := r.NewHistogramVec(name, help, buckets)
+	return h.mustWith(nil), err
+}
+
+// 包级函数在包初始化时注册到默认注册表，注册失败时记录错误并返回不输出的指标，不影响调用方
+
+// NewCounterVec 在默认注册表中注册带标签的计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c, err := Default.NewCounterVec(name, help, labels...)
+	logError(err)
+	return c
+}
+
+// NewCounter 在默认注册表中注册计数器
+func NewCounter(name, help string) *Counter {
+	c, err := Default.NewCounter(name, help)
+	logError(err)
+	return c
+}
+
+// NewGaugeVec 在默认注册表中注册带标签的数值
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g, err := Default.NewGaugeVec(name, help, labels...)
+	logError(err)
+	return g
+}
+
+// NewGauge 在默认注册表中注册数值
+func NewGauge(name, help string) *Gauge {
+	g, err := Default.NewGauge(name, help)
+	logError(err)
+	return g
+}
+
+// NewHistogramVec 在默认注册表中注册带标签的直方图
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h, err := Default.NewHistogramVec(name, help, buckets, labels...)
+	logError(err)
+	return h
+}
+
+// NewHistogram 在默认注册表中注册直方图
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h, err := Default.NewHistogram(name, help, buckets)
+	logError(err)
+	return h
+}
+
+func logError(err error) {
+	if err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// escapeLabel 转义标签值，无效的 UTF-8 替换为 U+FFFD，文本格式要求标签值为 UTF-8
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(strings.ToValidUTF8(s, "\uFFFD"))
+}
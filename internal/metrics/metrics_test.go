@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeText(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, r.WriteText(&b))
+	return b.String()
+}
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	requests, err := r.NewCounterVec("app_requests_total", "Requests by route.\nSecond line.", "route", "status")
+	require.NoError(t, err)
+	requests.WithLabelValues("/api/logs", "200").Inc()
+	requests.WithLabelValues("/api/logs", "200").Add(2)
+	requests.WithLabelValues(`/a"b`, "500").Inc()
+	requests.WithLabelValues("/api/logs", "200").Add(-1)
+
+	clients, err := r.NewGauge("app_clients", "Connected clients.")
+	require.NoError(t, err)
+	clients.Inc()
+	clients.Inc()
+	clients.Dec()
+
+	require.NoError(t, r.NewGaugeFunc("app_answer", "Computed on scrape.", func() float64 { return 42 }))
+
+	latency, err := r.NewHistogram("app_latency_seconds", "Latency.", []float64{1, 0.1})
+	require.NoError(t, err)
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(3)
+
+	assert.Equal(t, `# HELP app_answer Computed on scrape.
+# TYPE app_answer gauge
+app_answer 42
+# HELP app_clients Connected clients.
+# TYPE app_clients gauge
+app_clients 1
+# HELP app_latency_seconds Latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{le="0.1"} 2
+app_latency_seconds_bucket{le="1"} 2
+app_latency_seconds_bucket{le="+Inf"} 3
+app_latency_seconds_sum 3.15
+app_latency_seconds_count 3
+# HELP app_requests_total Requests by route.\nSecond line.
+# TYPE app_requests_total counter
+app_requests_total{route="/a\"b",status="500"} 1
+app_requests_total{route="/api/logs",status="200"} 3
+`, writeText(t, r))
+}
+
+func TestRegistry_Labels(t *testing.T) {
+	r := NewRegistry()
+	latency, err := r.NewHistogramVec("op_seconds", "Op latency.", nil, "op")
+	require.NoError(t, err)
+	latency.WithLabelValues("scan").Observe(0.2)
+
+	out := writeText(t, r)
+	assert.Contains(t, out, `op_seconds_bucket{op="scan",le="0.25"} 1`)
+	assert.Contains(t, out, `op_seconds_bucket{op="scan",le="0.1"} 0`)
+	assert.Contains(t, out, `op_seconds_count{op="scan"} 1`)
+
+	// 标签值数量不匹配时返回错误，WithLabelValues 返回不输出的指标
+	_, err = latency.GetMetricWithLabelValues()
+	assert.Error(t, err, "标签值数量不匹配")
+	assert.NotPanics(t, func() { latency.WithLabelValues("scan", "extra").Observe(1) })
+	assert.Equal(t, out, writeText(t, r))
+}
+
+func TestRegistry_RegisterErrors(t *testing.T) {
+	r := NewRegistry()
+	_, err := r.NewHistogramVec("op_seconds", "Op latency.", nil, "op")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		register func() error
+	}{
+		{"duplicate", func() error { _, err := r.NewCounter("op_seconds", "duplicate"); return err }},
+		{"histogram sample conflict", func() error { _, err := r.NewCounter("op_seconds_count", "conflict"); return err }},
+		{"invalid metric name", func() error { _, err := r.NewGauge("op-seconds", "invalid"); return err }},
+		{"metric name starts with digit", func() error { _, err := r.NewGauge("1op", "invalid"); return err }},
+		{"invalid label name", func() error { _, err := r.NewCounterVec("op_total", "invalid", "op.name"); return err }},
+		{"reserved label prefix", func() error { _, err := r.NewCounterVec("op_total", "invalid", "__name"); return err }},
+		{"duplicate label", func() error { _, err := r.NewCounterVec("op_total", "invalid", "op", "op"); return err }},
+		{"histogram le label", func() error { _, err := r.NewHistogramVec("op2_seconds", "invalid", nil, "le"); return err }},
+		{"NaN bucket", func() error { _, err := r.NewHistogram("op3_seconds", "invalid", []float64{math.NaN()}); return err }},
+		{"duplicate bucket", func() error { _, err := r.NewHistogram("op4_seconds", "invalid", []float64{1, 1}); return err }},
+		{"gauge func name", func() error { return r.NewGaugeFunc("op seconds", "invalid", func() float64 { return 0 }) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.register())
+		})
+	}
+
+	// 注册失败的指标不输出，但仍然可以使用
+	counter, err := r.NewCounter("op_seconds_sum", "conflict")
+	require.Error(t, err)
+	assert.NotPanics(t, func() { counter.Inc() })
+	out := writeText(t, r)
+	assert.NotContains(t, out, "op_total")
+	assert.Equal(t, 1, strings.Count(out, "# TYPE "))
+
+	// +Inf 分桶总是输出，不重复
+	_, err = r.NewHistogram("op5_seconds", "Inf bucket.", []float64{1, math.Inf(1)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(writeText(t, r), `op5_seconds_bucket{le="+Inf"}`))
+}
+
+func TestDefaultRegistry_RuntimeMetrics(t *testing.T) {
+	out := writeText(t, Default)
+	assert.Contains(t, out, "# TYPE go_goroutines gauge")
+	assert.Contains(t, out, "go_memstats_heap_alloc_bytes ")
+}
+
+var (
+	helpLineRE   = regexp.MustCompile(`^# HELP ([a-zA-Z_:][a-zA-Z0-9_:]*) (?:[^\\\n]|\\[\\n])*$`)
+	typeLineRE   = regexp.MustCompile(`^# TYPE ([a-zA-Z_:][a-zA-Z0-9_:]*) (counter|gauge|histogram|summary|untyped)$`)
+	sampleLineRE = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{((?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\\n]|\\[\\"n])*",?)*)\})? (\S+)$`)
+	labelPairRE  = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\\n]|\\[\\"n])*)"`)
+)
+
+// checkExposition 按 Prometheus 文本格式 0.0.4 检查输出：
+// 每个指标族只出现一次，HELP 和 TYPE 在样本之前，样本名称属于所在的指标族，
+// 同一指标族内没有重复的序列，直方图的分桶按 le 递增且累计，+Inf 分桶等于 _count
+func checkExposition(t *testing.T, text string) {
+	t.Helper()
+	require.True(t, strings.HasSuffix(text, "\n"), "输出必须以换行结尾")
+
+	type histogramSeries struct {
+		lastLE   float64
+		lastCum  float64
+		infCount string
+		count    string
+	}
+	families := map[string]bool{}
+	var family, kind string
+	series := map[string]bool{}
+	histograms := map[string]*histogramSeries{}
+
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			m := helpLineRE.FindStringSubmatch(line)
+			require.NotNil(t, m, "第 %d 行 HELP 格式错误: %q", i+1, line)
+			require.False(t, families[m[1]], "指标族 %s 重复出现", m[1])
+			families[m[1]], family, kind = true, m[1], ""
+			series = map[string]bool{}
+			histograms = map[string]*histogramSeries{}
+		case strings.HasPrefix(line, "# TYPE "):
+			m := typeLineRE.FindStringSubmatch(line)
+			require.NotNil(t, m, "第 %d 行 TYPE 格式错误: %q", i+1, line)
+			require.Equal(t, family, m[1], "第 %d 行 TYPE 与 HELP 不属于同一指标族", i+1)
+			kind = m[2]
+		default:
+			m := sampleLineRE.FindStringSubmatch(line)
+			require.NotNil(t, m, "第 %d 行样本格式错误: %q", i+1, line)
+			require.NotEmpty(t, kind, "第 %d 行样本之前没有 TYPE", i+1)
+			value, err := strconv.ParseFloat(m[3], 64)
+			require.NoError(t, err, "第 %d 行样本值无效: %q", i+1, m[3])
+
+			var labels []string
+			le := ""
+			for _, pair := range labelPairRE.FindAllStringSubmatch(m[2], -1) {
+				if pair[1] == "le" {
+					le = pair[2]
+					continue
+				}
+				labels = append(labels, pair[1]+"="+pair[2])
+			}
+			key := m[1] + "{" + strings.Join(labels, ",") + "}" + le
+			require.False(t, series[key], "第 %d 行序列重复: %q", i+1, line)
+			series[key] = true
+
+			if kind != "histogram" {
+				require.Equal(t, family, m[1], "第 %d 行样本不属于指标族 %s", i+1, family)
+				require.Empty(t, le, "第 %d 行非直方图不应有 le 标签", i+1)
+				continue
+			}
+			id := strings.Join(labels, ",")
+			h := histograms[id]
+			if h == nil {
+				h = &histogramSeries{lastLE: math.Inf(-1)}
+				histograms[id] = h
+			}
+			switch m[1] {
+			case family + "_bucket":
+				upper, err := strconv.ParseFloat(le, 64)
+				require.NoError(t, err, "第 %d 行 le 无效: %q", i+1, le)
+				require.Greater(t, upper, h.lastLE, "第 %d 行分桶上限没有递增", i+1)
+				require.GreaterOrEqual(t, value, h.lastCum, "第 %d 行分桶计数没有累计", i+1)
+				h.lastLE, h.lastCum = upper, value
+				if math.IsInf(upper, 1) {
+					h.infCount = m[3]
+				}
+			case family + "_sum":
+			case family + "_count":
+				h.count = m[3]
+			default:
+				t.Fatalf("第 %d 行样本 %s 不属于直方图 %s", i+1, m[1], family)
+			}
+		}
+	}
+	for id, h := range histograms {
+		assert.NotEmpty(t, h.infCount, "直方图 %s{%s} 缺少 +Inf 分桶", family, id)
+		assert.Equal(t, h.infCount, h.count, "直方图 %s{%s} 的 +Inf 分桶与 _count 不一致", family, id)
+	}
+}
+
+func TestRegistry_ExpositionFormat(t *testing.T) {
+	r := NewRegistry()
+	requests, err := r.NewCounterVec("app_requests_total", "Requests with \\ backslash\nand newline.", "route", "status")
+	require.NoError(t, err)
+	for _, route := range []string{"/api", `quote"`, `back\slash`, "new\nline", "invalid\xffutf8", ""} {
+		requests.WithLabelValues(route, "200").Inc()
+	}
+
+	latency, err := r.NewHistogramVec("app_latency_seconds", "Latency.", []float64{0.5, 0.1, 1}, "op")
+	require.NoError(t, err)
+	for _, v := range []float64{0.05, 0.3, 0.7, 2, math.Inf(1)} {
+		latency.WithLabelValues("read").Observe(v)
+	}
+	latency.WithLabelValues("write")
+
+	gauge, err := r.NewGauge("app_temperature", "")
+	require.NoError(t, err)
+	gauge.Set(math.Inf(-1))
+	require.NoError(t, r.NewGaugeFunc("app_nan", "Not a number.", func() float64 { return math.NaN() }))
+	require.NoError(t, r.NewGaugeFunc("app:recorded_rule", "Colon in name.", func() float64 { return 1e-9 }))
+
+	out := writeText(t, r)
+	checkExposition(t, out)
+	assert.Contains(t, out, `route="new\nline"`)
+	assert.Contains(t, out, `route="invalid`+"�"+`utf8"`)
+	assert.Contains(t, out, `app_latency_seconds_bucket{op="write",le="+Inf"} 0`)
+
+	checkExposition(t, writeText(t, Default))
+}
@@ -15,11 +15,31 @@ import (
 	"github.com/local-log-viewer/internal/types"
 )
 
+const (
+	// UserContextKey 当前用户名在请求上下文中的键
+	UserContextKey = "user"
+	// RemoteUserHeader 未启用内置认证时，由前置认证代理传入的用户名（只接受来自 TrustedProxies 的请求）
+	RemoteUserHeader = "X-Remote-User"
+	// AnonymousUser 无法识别用户时使用的用户名
+	AnonymousUser = "anonymous"
+)
+
+// CurrentUser 返回当前请求的用户名，用于按用户区分保存的数据
+func CurrentUser(c *gin.Context) string {
+	if user := c.GetString(UserContextKey); user != "" {
+		return user
+	}
+	return AnonymousUser
+}
+
 // BasicAuth 基本认证中间件
 func BasicAuth(cfg *config.SecurityConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 如果未启用认证，直接通过
+		// 如果未启用认证，直接通过（用户名由受信任的前置认证代理提供，否则为匿名用户）
 		if !cfg.EnableAuth {
+			if user := trustedRemoteUser(c, cfg); user != "" {
+				c.Set(UserContextKey, user)
+			}
 			c.Next()
 			return
 		}
@@ -107,10 +127,32 @@ func BasicAuth(cfg *config.SecurityConfig) gin.HandlerFunc {
 			zap.String("username", username))
 
 		// 认证成功，继续处理请求
+		c.Set(UserContextKey, username)
 		c.Next()
 	}
 }
 
+// trustedRemoteUser 返回前置认证代理传入的用户名，请求不是来自 TrustedProxies 时忽略该请求头
+func trustedRemoteUser(c *gin.Context, cfg *config.SecurityConfig) string {
+	user := c.GetHeader(RemoteUserHeader)
+	if user == "" || len(cfg.TrustedProxies) == 0 {
+		return ""
+	}
+
+	// 使用直接连接的地址，X-Forwarded-For 等请求头可以被客户端伪造
+	peer, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		peer = c.Request.RemoteAddr
+	}
+	if !isIPAllowed(peer, cfg.TrustedProxies) {
+		logger.Warn("ignoring remote user header from untrusted address",
+			zap.String("remote_addr", peer),
+			zap.String("user", user))
+		return ""
+	}
+	return user
+}
+
 // IPWhitelist IP白名单中间件
 func IPWhitelist(cfg *config.SecurityConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
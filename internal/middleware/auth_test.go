@@ -365,3 +365,75 @@ func TestCombinedSecurityMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
+
+func TestCurrentUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		config       *config.SecurityConfig
+		authHeader   string
+		remoteUser   string
+		expectedUser string
+	}{
+		{
+			name:         "auth disabled - anonymous",
+			config:       &config.SecurityConfig{EnableAuth: false},
+			expectedUser: AnonymousUser,
+		},
+		{
+			name:         "auth disabled - remote user header ignored by default",
+			config:       &config.SecurityConfig{EnableAuth: false},
+			remoteUser:   "alice",
+			expectedUser: AnonymousUser,
+		},
+		{
+			// httptest.NewRequest 的来源地址为 192.0.2.1
+			name:         "auth disabled - remote user header from trusted proxy",
+			config:       &config.SecurityConfig{EnableAuth: false, TrustedProxies: []string{"192.0.2.0/24"}},
+			remoteUser:   "alice",
+			expectedUser: "alice",
+		},
+		{
+			name:         "auth disabled - remote user header from untrusted address",
+			config:       &config.SecurityConfig{EnableAuth: false, TrustedProxies: []string{"10.0.0.1"}},
+			remoteUser:   "alice",
+			expectedUser: AnonymousUser,
+		},
+		{
+			name: "auth enabled - basic auth user wins over header",
+			config: &config.SecurityConfig{
+				EnableAuth: true,
+				Username:   "admin",
+				Password:   "password123",
+			},
+			authHeader:   "Basic " + base64.StdEncoding.EncodeToString([]byte("admin:password123")),
+			remoteUser:   "alice",
+			expectedUser: "admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(BasicAuth(tt.config))
+			router.GET("/test", func(c *gin.Context) {
+				c.String(http.StatusOK, CurrentUser(c))
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			if tt.remoteUser != "" {
+				req.Header.Set(RemoteUserHeader, tt.remoteUser)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedUser, w.Body.String())
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/local-log-viewer/internal/errors"
 	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/tracing"
 	"github.com/local-log-viewer/internal/types"
 )
 
@@ -178,6 +179,9 @@ func RequestLogger() gin.HandlerFunc {
 			zap.String("user_agent", c.Request.UserAgent()),
 			zap.Int64("body_size", int64(c.Writer.Size())),
 		}
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			fields = append(fields, zap.String("trace_id", traceID))
+		}
 
 		// 根据状态码选择日志级别
 		switch {
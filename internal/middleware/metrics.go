@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/metrics"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// unmatchedRoute 未匹配任何路由的请求（前端页面、静态文件、404）使用的路由标签，避免按路径产生大量时间序列
+const unmatchedRoute = "unmatched"
+
+var requestDuration = metrics.NewHistogramVec("logviewer_http_request_duration_seconds",
+	"HTTP request latency by method, route template and status code. WebSocket and event streams are recorded when they close.",
+	nil, "method", "route", "status")
+
+// RequestMetrics 按路由记录请求耗时
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuth 指标端点的访问控制，与界面的认证相互独立
+// 设置了令牌时要求 Authorization: Bearer <token>，设置了允许列表时要求客户端IP在列表中，都未设置时只允许本机访问
+func MetricsAuth(cfg *config.MetricsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := c.ClientIP()
+
+		allowed := true
+		if len(cfg.AllowedIPs) > 0 {
+			allowed = isIPAllowed(clientIP, cfg.AllowedIPs)
+		} else if cfg.Token == "" {
+			ip := net.ParseIP(clientIP)
+			allowed = ip != nil && ip.IsLoopback()
+		}
+		if !allowed {
+			logger.Warn("metrics access denied", zap.String("client_ip", clientIP))
+			c.JSON(http.StatusForbidden, types.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: "访问被拒绝",
+				Details: "您的IP地址不允许访问指标",
+			})
+			c.Abort()
+			return
+		}
+
+		if cfg.Token != "" {
+			const prefix = "Bearer "
+			auth := c.GetHeader("Authorization")
+			token := strings.TrimPrefix(auth, prefix)
+			if !strings.HasPrefix(auth, prefix) || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+				logger.Warn("metrics authentication failed", zap.String("client_ip", clientIP))
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+					Code:    http.StatusUnauthorized,
+					Message: "需要认证",
+					Details: "请提供指标访问令牌",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
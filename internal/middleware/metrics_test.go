@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/metrics"
+)
+
+func TestMetricsAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		config         config.MetricsConfig
+		remoteAddr     string
+		authHeader     string
+		expectedStatus int
+	}{
+		{name: "default - loopback allowed", remoteAddr: "127.0.0.1:9000", expectedStatus: http.StatusOK},
+		{name: "default - remote denied", remoteAddr: "10.1.2.3:9000", expectedStatus: http.StatusForbidden},
+		{name: "allowed network", config: config.MetricsConfig{AllowedIPs: []string{"10.0.0.0/8"}}, remoteAddr: "10.1.2.3:9000", expectedStatus: http.StatusOK},
+		{name: "outside allowed network", config: config.MetricsConfig{AllowedIPs: []string{"10.0.0.0/8"}}, remoteAddr: "127.0.0.1:9000", expectedStatus: http.StatusForbidden},
+		{name: "token - remote with token", config: config.MetricsConfig{Token: "s3cret"}, remoteAddr: "10.1.2.3:9000", authHeader: "Bearer s3cret", expectedStatus: http.StatusOK},
+		{name: "token - missing", config: config.MetricsConfig{Token: "s3cret"}, remoteAddr: "127.0.0.1:9000", expectedStatus: http.StatusUnauthorized},
+		{name: "token - wrong", config: config.MetricsConfig{Token: "s3cret"}, remoteAddr: "10.1.2.3:9000", authHeader: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "token and network - outside network", config: config.MetricsConfig{Token: "s3cret", AllowedIPs: []string{"10.0.0.0/8"}}, remoteAddr: "192.168.1.1:9000", authHeader: "Bearer s3cret", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/metrics", MetricsAuth(&tt.config), func(c *gin.Context) {
+				c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestMetrics())
+	router.GET("/api/logs/content/*path", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/api/logs/content/a.log", "/api/logs/content/b.log", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var b strings.Builder
+	require.NoError(t, metrics.Default.WriteText(&b))
+	// 按路由模板而不是请求路径统计
+	assert.Contains(t, b.String(), `logviewer_http_request_duration_seconds_count{method="GET",route="/api/logs/content/*path",status="418"} 2`)
+	assert.Contains(t, b.String(), `logviewer_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, b.String(), "a.log")
+}
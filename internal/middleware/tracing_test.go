@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/local-log-viewer/internal/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var handlerTraceID string
+	router := gin.New()
+	router.Use(Tracing())
+	router.GET("/api/logs/*path", func(c *gin.Context) {
+		handlerTraceID = tracing.TraceID(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/logs/app.log", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+
+	if handlerTraceID != traceID {
+		t.Errorf("expected handler to see upstream trace ID, got %q", handlerTraceID)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /api/logs/*path" {
+		t.Errorf("unexpected span name %q", span.Name)
+	}
+	if span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected upstream parent span, got %s", span.Parent.SpanID())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected error status for 500, got %v", span.Status.Code)
+	}
+	hasStatus := false
+	for _, attr := range span.Attributes {
+		if attr == attribute.Int("http.response.status_code", 500) {
+			hasStatus = true
+		}
+	}
+	if !hasStatus {
+		t.Errorf("missing status code attribute: %v", span.Attributes)
+	}
+}
@@ -247,17 +247,24 @@ func (mm *MemoryMonitor) checkMemory() {
 
 	// 检查阈值
 	usagePercent := stats.UsagePercent / 100
+	memoryUsageRatio.Set(usagePercent)
 
 	if usagePercent >= mm.criticalLevel {
+		memoryPressureLevel.Set(2)
+		memoryPressureEvents.WithLabelValues("critical").Inc()
 		if mm.criticalCallback != nil {
 			mm.criticalCallback(stats)
 		}
 		// 自动触发GC
 		runtime.GC()
 	} else if usagePercent >= mm.warningLevel {
+		memoryPressureLevel.Set(1)
+		memoryPressureEvents.WithLabelValues("warning").Inc()
 		if mm.warningCallback != nil {
 			mm.warningCallback(stats)
 		}
+	} else {
+		memoryPressureLevel.Set(0)
 	}
 }
 
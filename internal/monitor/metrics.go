@@ -0,0 +1,12 @@
+package monitor
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	memoryUsageRatio = metrics.NewGauge("logviewer_memory_usage_ratio",
+		"Allocated memory relative to the memory monitor limit, as of the last check.")
+	memoryPressureLevel = metrics.NewGauge("logviewer_memory_pressure_level",
+		"Memory pressure as of the last check: 0 normal, 1 warning, 2 critical.")
+	memoryPressureEvents = metrics.NewCounterVec("logviewer_memory_pressure_events_total",
+		"Memory checks that crossed the warning or critical threshold.", "level")
+)
@@ -66,18 +66,57 @@ func (p *BaseParser) ExtractLogLevel(content string) string {
 	return "INFO" // 默认级别
 }
 
+// NormalizeLevel 标准化日志级别名称
+func NormalizeLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	switch level {
+	case "ERR", "ERROR":
+		return "ERROR"
+	case "WARN", "WARNING":
+		return "WARN"
+	case "INFO", "INFORMATION":
+		return "INFO"
+	case "DEBUG", "DBG":
+		return "DEBUG"
+	case "TRACE", "TRC":
+		return "TRACE"
+	case "FATAL", "CRIT", "CRITICAL":
+		return "FATAL"
+	default:
+		return level
+	}
+}
+
 // AutoDetector 自动检测器
 type AutoDetector struct {
-	parsers []interfaces.LogParser
+	parsers    []interfaces.LogParser
+	sampleSize int
 }
 
+// defaultSampleSize 默认用于检测的样本行数
+const defaultSampleSize = 5
+
 // NewAutoDetector 创建自动检测器
 func NewAutoDetector() *AutoDetector {
+	return NewAutoDetectorWithParsers([]interfaces.LogParser{
+		NewDockerLogParser(), // json-file 和 journal 记录也是合法的 JSON，排在 JSON 之前
+		NewJournalLogParser(),
+		NewJSONLogParser(),
+		NewSyslogParser(),
+		NewLogfmtLogParser(),
+		NewCommonLogParser(),
+	}, defaultSampleSize)
+}
+
+// NewAutoDetectorWithParsers 使用指定的候选解析器创建自动检测器
+// 得分相同时排在前面的解析器优先，sampleSize 为参与检测的最大非空行数
+func NewAutoDetectorWithParsers(parsers []interfaces.LogParser, sampleSize int) *AutoDetector {
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
 	return &AutoDetector{
-		parsers: []interfaces.LogParser{
-			NewJSONLogParser(),
-			NewCommonLogParser(),
-		},
+		parsers:    parsers,
+		sampleSize: sampleSize,
 	}
 }
 
@@ -85,10 +124,10 @@ func NewAutoDetector() *AutoDetector {
 func (d *AutoDetector) DetectFormat(content string) interfaces.LogParser {
 	// 取前几行进行检测
 	lines := strings.Split(content, "\n")
-	sampleLines := make([]string, 0, 5)
+	sampleLines := make([]string, 0, d.sampleSize)
 
-	for i, line := range lines {
-		if i >= 5 {
+	for _, line := range lines {
+		if len(sampleLines) >= d.sampleSize {
 			break
 		}
 		if strings.TrimSpace(line) != "" {
@@ -100,11 +139,17 @@ func (d *AutoDetector) DetectFormat(content string) interfaces.LogParser {
 		return NewCommonLogParser() // 默认使用通用解析器
 	}
 
-	// 检测每个解析器
-	var bestParser interfaces.LogParser = NewCommonLogParser()
+	// 检测每个解析器，通用解析器能解析任何内容，只作为兜底
+	var fallback interfaces.LogParser = NewCommonLogParser()
+	var bestParser interfaces.LogParser
 	bestScore := 0
 
 	for _, parser := range d.parsers {
+		if parser.GetFormat() == "Common" {
+			fallback = parser
+			continue
+		}
+
 		canParseCount := 0
 		for _, line := range sampleLines {
 			if parser.CanParse(line) {
@@ -112,19 +157,33 @@ func (d *AutoDetector) DetectFormat(content string) interfaces.LogParser {
 			}
 		}
 
-		// 如果这个解析器的得分更高，选择它
+		// 如果超过一半的行都能解析，且是JSON（包括Docker json-file、journal）解析器，优先选择
+		if canParseCount > len(sampleLines)/2 && isJSONFormat(parser.GetFormat()) {
+			return parser
+		}
+
+		// 如果这个解析器的得分更高，选择它（得分相同时保留排在前面的）
 		if canParseCount > bestScore {
 			bestScore = canParseCount
 			bestParser = parser
 		}
+	}
 
-		// 如果超过一半的行都能解析，且是JSON解析器，优先选择
-		if canParseCount > len(sampleLines)/2 && parser.GetFormat() == "JSON" {
-			return parser
-		}
+	// 超过一半的行能解析时才使用结构化解析器，避免个别行（如堆栈）导致整个文件退化为通用格式
+	if bestParser != nil && bestScore > len(sampleLines)/2 {
+		return bestParser
 	}
 
-	return bestParser
+	return fallback
+}
+
+// isJSONFormat 判断是否为每行一个 JSON 对象的格式
+func isJSONFormat(format string) bool {
+	switch format {
+	case "JSON", "Docker", "Journal":
+		return true
+	}
+	return false
 }
 
 // ParseJSON 解析JSON的辅助函数
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// dockerLabelPrefix 容器标签字段名的前缀
+	dockerLabelPrefix = "label."
+	// dockerStreamField 输出流（stdout/stderr）的字段名
+	dockerStreamField = "docker_stream"
+)
+
+// DockerLogParser Docker json-file 日志驱动格式的解析器
+// 每行是 {"log":"...\n","stream":"stdout","attrs":{...},"time":"..."}，时间取自 Docker 记录的时间，
+// stream 和 attrs（容器标签）作为带前缀的字段 docker_stream、label.<标签>，不会覆盖应用程序日志中的同名字段；
+// log 本身是 JSON 时继续解析其中的级别、消息和字段
+type DockerLogParser struct {
+	*BaseParser
+	inner *JSONLogParser
+}
+
+// dockerRecord json-file 日志驱动写入的一条记录
+type dockerRecord struct {
+	Log    *string           `json:"log"`
+	Stream string            `json:"stream"`
+	Attrs  map[string]string `json:"attrs"`
+	Time   string            `json:"time"`
+}
+
+// NewDockerLogParser 创建 Docker json-file 日志解析器
+func NewDockerLogParser() *DockerLogParser {
+	return &DockerLogParser{
+		BaseParser: NewBaseParser("Docker"),
+		inner:      NewJSONLogParser(),
+	}
+}
+
+// Parse 解析一条 json-file 记录
+func (p *DockerLogParser) Parse(line string) (*types.LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	record, err := parseDockerRecord(line)
+	if err != nil {
+		return nil, err
+	}
+
+	message := strings.TrimRight(*record.Log, "\r\n")
+	entry := &types.LogEntry{
+		Raw:     line,
+		Message: message,
+		Fields:  make(map[string]interface{}, len(record.Attrs)+1),
+		LogType: "Docker",
+	}
+
+	// 应用程序输出的 JSON 日志
+	if IsValidJSON(message) {
+		if data, err := ParseJSON(message); err == nil {
+			for key, value := range data {
+				entry.Fields[key] = value
+			}
+			entry.Level = p.inner.extractLevel(data)
+			entry.Message = p.inner.extractMessage(data)
+		}
+	}
+	if entry.Level == "" {
+		entry.Level = p.ExtractLogLevel(message)
+	}
+
+	for key, value := range record.Attrs {
+		entry.Fields[dockerLabelPrefix+key] = value
+	}
+	entry.Fields[dockerStreamField] = record.Stream
+
+	if timestamp, err := time.Parse(time.RFC3339Nano, record.Time); err == nil {
+		entry.Timestamp = timestamp
+	} else {
+		entry.Timestamp = time.Now()
+	}
+
+	return entry, nil
+}
+
+// CanParse 检查是否为 json-file 记录：必须有字符串类型的 log、stream 和 time 字段
+func (p *DockerLogParser) CanParse(content string) bool {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "{") || !strings.Contains(content, `"log"`) {
+		return false
+	}
+	_, err := parseDockerRecord(content)
+	return err == nil
+}
+
+func parseDockerRecord(line string) (*dockerRecord, error) {
+	var record dockerRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return nil, fmt.Errorf("failed to parse docker log: %w", err)
+	}
+	if record.Log == nil || record.Stream == "" || record.Time == "" {
+		return nil, fmt.Errorf("not a docker json-file log record")
+	}
+	return &record, nil
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDockerLogParser_Parse(t *testing.T) {
+	parser := NewDockerLogParser()
+
+	entry, err := parser.Parse(`{"log":"ERROR connection refused\n","stream":"stderr","attrs":{"com.docker.compose.service":"api"},"time":"2023-12-07T10:30:45.123456789Z"}`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if entry.Message != "ERROR connection refused" || entry.Level != "ERROR" || entry.LogType != "Docker" {
+		t.Errorf("解析结果不正确: %+v", entry)
+	}
+	if !entry.Timestamp.Equal(time.Date(2023, 12, 7, 10, 30, 45, 123456789, time.UTC)) {
+		t.Errorf("期望使用 Docker 记录的时间，得到 %v", entry.Timestamp)
+	}
+	if entry.Fields["docker_stream"] != "stderr" || entry.Fields["label.com.docker.compose.service"] != "api" {
+		t.Errorf("字段不正确: %v", entry.Fields)
+	}
+
+	// 应用程序输出的 JSON 日志
+	entry, err = parser.Parse(`{"log":"{\"level\":\"warn\",\"msg\":\"slow query\",\"ms\":812}\n","stream":"stdout","time":"2023-12-07T10:30:46Z"}`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if entry.Message != "slow query" || entry.Level != "WARN" || entry.Fields["ms"] != float64(812) || entry.Fields["docker_stream"] != "stdout" {
+		t.Errorf("内嵌 JSON 解析结果不正确: %+v", entry)
+	}
+}
+
+func TestDockerLogParser_FieldCollisions(t *testing.T) {
+	parser := NewDockerLogParser()
+
+	// 应用程序日志中的 service、stream 字段与容器标签、json-file 的 stream 同名
+	entry, err := parser.Parse(`{"log":"{\"level\":\"info\",\"msg\":\"sent\",\"service\":\"billing\",\"stream\":\"orders\"}\n","stream":"stdout","attrs":{"service":"api","stream":"label-stream"},"time":"2023-12-07T10:30:46Z"}`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	expected := map[string]interface{}{
+		"service":       "billing",
+		"stream":        "orders",
+		"label.service": "api",
+		"label.stream":  "label-stream",
+		"docker_stream": "stdout",
+	}
+	for key, value := range expected {
+		if entry.Fields[key] != value {
+			t.Errorf("字段 %s 期望 %v，得到 %v", key, value, entry.Fields[key])
+		}
+	}
+}
+
+func TestDockerLogParser_CanParse(t *testing.T) {
+	parser := NewDockerLogParser()
+
+	tests := map[string]bool{
+		`{"log":"hello\n","stream":"stdout","time":"2023-12-07T10:30:45Z"}`: true,
+		`{"log":"","stream":"stdout","time":"2023-12-07T10:30:45Z"}`:        true,
+		`{"level":"info","msg":"hello","time":"2023-12-07T10:30:45Z"}`:      false,
+		`{"log":"hello"}`:                false,
+		`2023-12-07 10:30:45 INFO hello`: false,
+	}
+	for line, expected := range tests {
+		if got := parser.CanParse(line); got != expected {
+			t.Errorf("CanParse(%s) = %v，期望 %v", line, got, expected)
+		}
+	}
+
+	// json-file 记录本身也是合法的 JSON，需要排在 JSON 解析器之前
+	detector := NewAutoDetector()
+	sample := `{"log":"started\n","stream":"stdout","time":"2023-12-07T10:30:45Z"}` + "\n" +
+		`{"log":"ready\n","stream":"stdout","time":"2023-12-07T10:30:46Z"}`
+	if format := detector.DetectFormat(sample).GetFormat(); format != "Docker" {
+		t.Errorf("期望检测为 Docker，得到 %s", format)
+	}
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"strings"
+	"sync"
 
+	"github.com/local-log-viewer/internal/config"
 	"github.com/local-log-viewer/internal/interfaces"
 )
 
@@ -10,6 +12,10 @@ import (
 type ParserFactory struct {
 	parsers      map[string]interfaces.LogParser
 	autoDetector *AutoDetector
+
+	// 配置定义的自定义解析器（按配置顺序）
+	patternParsers []*PatternLogParser
+	mutex          sync.RWMutex
 }
 
 // NewParserFactory 创建解析器工厂
@@ -21,6 +27,10 @@ func NewParserFactory() *ParserFactory {
 
 	// 注册默认解析器
 	factory.RegisterParser("json", NewJSONLogParser())
+	factory.RegisterParser("docker", NewDockerLogParser())
+	factory.RegisterParser("journal", NewJournalLogParser())
+	factory.RegisterParser("logfmt", NewLogfmtLogParser())
+	factory.RegisterParser("syslog", NewSyslogParser())
 	factory.RegisterParser("common", NewCommonLogParser())
 	factory.RegisterParser("apache", NewCommonLogParser())
 	factory.RegisterParser("nginx", NewCommonLogParser())
@@ -30,17 +40,68 @@ func NewParserFactory() *ParserFactory {
 
 // RegisterParser 注册解析器
 func (f *ParserFactory) RegisterParser(name string, parser interfaces.LogParser) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 	f.parsers[strings.ToLower(name)] = parser
 }
 
+// UnregisterParser 注销解析器
+func (f *ParserFactory) UnregisterParser(name string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.parsers, strings.ToLower(name))
+}
+
+// RegisterPatternParsers 根据配置注册自定义解析器，替换之前注册的自定义解析器
+// 任意一个定义无效时返回错误且不修改已注册的解析器
+func (f *ParserFactory) RegisterPatternParsers(defs []config.ParserConfig) ([]*PatternLogParser, error) {
+	built := make([]*PatternLogParser, 0, len(defs))
+	for _, def := range defs {
+		p, err := NewPatternLogParser(def)
+		if err != nil {
+			return nil, err
+		}
+		built = append(built, p)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, old := range f.patternParsers {
+		delete(f.parsers, strings.ToLower(old.GetFormat()))
+	}
+	for _, p := range built {
+		f.parsers[strings.ToLower(p.GetFormat())] = p
+	}
+	f.patternParsers = built
+
+	return built, nil
+}
+
 // GetParser 根据名称获取解析器
 func (f *ParserFactory) GetParser(name string) interfaces.LogParser {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
 	if parser, exists := f.parsers[strings.ToLower(name)]; exists {
 		return parser
 	}
 	return f.parsers["common"] // 默认返回通用解析器
 }
 
+// GetParserForFile 获取通过文件glob绑定到指定路径的解析器，没有绑定时返回nil
+func (f *ParserFactory) GetParserForFile(path string) interfaces.LogParser {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for _, p := range f.patternParsers {
+		if p.MatchesFile(path) {
+			return p
+		}
+	}
+	return nil
+}
+
 // GetParserByContent 根据内容自动检测并返回合适的解析器
 func (f *ParserFactory) GetParserByContent(content string) interfaces.LogParser {
 	return f.autoDetector.DetectFormat(content)
@@ -48,6 +109,9 @@ func (f *ParserFactory) GetParserByContent(content string) interfaces.LogParser
 
 // GetAvailableParsers 获取所有可用的解析器名称
 func (f *ParserFactory) GetAvailableParsers() []string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
 	var names []string
 	for name := range f.parsers {
 		names = append(names, name)
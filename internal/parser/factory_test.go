@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/local-log-viewer/internal/config"
 	"github.com/local-log-viewer/internal/interfaces"
 )
 
@@ -60,6 +61,16 @@ func TestParserFactory_GetParser(t *testing.T) {
 			parserName:   "json",
 			expectFormat: "JSON",
 		},
+		{
+			name:         "Get logfmt parser",
+			parserName:   "logfmt",
+			expectFormat: "Logfmt",
+		},
+		{
+			name:         "Get syslog parser",
+			parserName:   "syslog",
+			expectFormat: "Syslog",
+		},
 		{
 			name:         "Get common parser",
 			parserName:   "common",
@@ -317,3 +328,53 @@ func TestParsersImplementInterface(t *testing.T) {
 	var _ interfaces.LogParser = NewJSONLogParser()
 	var _ interfaces.LogParser = NewCommonLogParser()
 }
+
+func TestParserFactory_RegisterPatternParsers(t *testing.T) {
+	factory := NewParserFactory()
+
+	_, err := factory.RegisterPatternParsers([]config.ParserConfig{
+		{
+			Name:    "Orders",
+			Type:    "regex",
+			Pattern: `^order=(?P<order>\d+)`,
+			Files:   []string{"orders*.log"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register pattern parsers: %v", err)
+	}
+
+	if parser := factory.GetParser("orders"); parser.GetFormat() != "Orders" {
+		t.Errorf("Expected Orders parser, got %s", parser.GetFormat())
+	}
+
+	if parser := factory.GetParserForFile("/tmp/orders-1.log"); parser == nil || parser.GetFormat() != "Orders" {
+		t.Error("Expected file binding to resolve to Orders parser")
+	}
+
+	if parser := factory.GetParserForFile("/tmp/app.log"); parser != nil {
+		t.Errorf("Expected no binding for unrelated file, got %s", parser.GetFormat())
+	}
+
+	// 无效定义不会替换已注册的解析器
+	_, err = factory.RegisterPatternParsers([]config.ParserConfig{
+		{Name: "broken", Type: "grok", Pattern: `%{MISSING:x}`},
+	})
+	if err == nil {
+		t.Fatal("Expected error for invalid definition")
+	}
+	if parser := factory.GetParserForFile("/tmp/orders-1.log"); parser == nil {
+		t.Error("Expected previous pattern parsers to remain registered")
+	}
+
+	// 重新注册会替换之前的自定义解析器
+	if _, err := factory.RegisterPatternParsers(nil); err != nil {
+		t.Fatalf("Failed to clear pattern parsers: %v", err)
+	}
+	if parser := factory.GetParser("orders"); parser.GetFormat() != "Common" {
+		t.Errorf("Expected Orders parser to be removed, got %s", parser.GetFormat())
+	}
+	if parser := factory.GetParserForFile("/tmp/orders-1.log"); parser != nil {
+		t.Error("Expected file binding to be removed")
+	}
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// JournalLogParser systemd journal 导出格式（journalctl -o json）的解析器
+// 时间取自 __REALTIME_TIMESTAMP（微秒），PRIORITY 按 syslog 严重性映射为级别，
+// _SYSTEMD_UNIT、_PID、_HOSTNAME 等字段放入 Fields；以双下划线开头的内部字段（游标、单调时间）不保留
+type JournalLogParser struct {
+	*BaseParser
+}
+
+// NewJournalLogParser 创建 systemd journal 日志解析器
+func NewJournalLogParser() *JournalLogParser {
+	return &JournalLogParser{
+		BaseParser: NewBaseParser("Journal"),
+	}
+}
+
+// Parse 解析一条 journal 记录
+func (p *JournalLogParser) Parse(line string) (*types.LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	record, err := parseJournalRecord(line)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &types.LogEntry{
+		Raw:     line,
+		Message: journalString(record["MESSAGE"]),
+		Fields:  make(map[string]interface{}, len(record)),
+		LogType: "Journal",
+	}
+
+	for key, value := range record {
+		if key == "MESSAGE" || strings.HasPrefix(key, "__") {
+			continue
+		}
+		entry.Fields[key] = journalString(value)
+	}
+
+	if priority, err := strconv.Atoi(journalString(record["PRIORITY"])); err == nil && priority >= 0 && priority <= 7 {
+		entry.Level = SyslogSeverityToLevel(priority)
+	} else {
+		entry.Level = p.ExtractLogLevel(entry.Message)
+	}
+
+	if usec, err := strconv.ParseInt(journalString(record["__REALTIME_TIMESTAMP"]), 10, 64); err == nil {
+		entry.Timestamp = time.UnixMicro(usec)
+	} else {
+		entry.Timestamp = time.Now()
+	}
+
+	return entry, nil
+}
+
+// CanParse 检查是否为 journal 记录：必须有 __REALTIME_TIMESTAMP 和 MESSAGE 字段
+func (p *JournalLogParser) CanParse(content string) bool {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "{") || !strings.Contains(content, `"__REALTIME_TIMESTAMP"`) {
+		return false
+	}
+	_, err := parseJournalRecord(content)
+	return err == nil
+}
+
+func parseJournalRecord(line string) (map[string]interface{}, error) {
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return nil, fmt.Errorf("failed to parse journal entry: %w", err)
+	}
+	if _, ok := record["__REALTIME_TIMESTAMP"]; !ok {
+		return nil, fmt.Errorf("not a journal entry")
+	}
+	if _, ok := record["MESSAGE"]; !ok {
+		return nil, fmt.Errorf("not a journal entry")
+	}
+	return record, nil
+}
+
+// journalString 返回字段的文本值：不是合法 UTF-8 的值导出为字节数组，
+// 同名字段出现多次时导出为数组（取第一个）
+func journalString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) == 0 {
+			return ""
+		}
+		if _, ok := v[0].(float64); !ok {
+			return journalString(v[0])
+		}
+		data := make([]byte, 0, len(v))
+		for _, b := range v {
+			n, _ := b.(float64)
+			data = append(data, byte(n))
+		}
+		return string(data)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
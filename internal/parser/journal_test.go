@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJournalLogParser_Parse(t *testing.T) {
+	parser := NewJournalLogParser()
+
+	entry, err := parser.Parse(`{"__CURSOR":"s=abc;i=1f","__REALTIME_TIMESTAMP":"1701945045123456","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","_PID":"812","_HOSTNAME":"web1","SYSLOG_IDENTIFIER":"nginx","MESSAGE":"connect() failed"}`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if entry.Message != "connect() failed" || entry.Level != "ERROR" || entry.LogType != "Journal" {
+		t.Errorf("解析结果不正确: %+v", entry)
+	}
+	if !entry.Timestamp.Equal(time.UnixMicro(1701945045123456)) {
+		t.Errorf("期望使用 __REALTIME_TIMESTAMP，得到 %v", entry.Timestamp)
+	}
+	if entry.Fields["_SYSTEMD_UNIT"] != "nginx.service" || entry.Fields["_PID"] != "812" || entry.Fields["_HOSTNAME"] != "web1" {
+		t.Errorf("字段不正确: %v", entry.Fields)
+	}
+	if _, exists := entry.Fields["__CURSOR"]; exists {
+		t.Error("不应保留内部字段")
+	}
+
+	// 不是 UTF-8 的消息导出为字节数组；没有 PRIORITY 时从消息中识别级别
+	entry, err = parser.Parse(`{"__REALTIME_TIMESTAMP":"1701945045000000","MESSAGE":[87,65,82,78,32,255]}`)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if entry.Message != "WARN \xff" || entry.Level != "WARN" {
+		t.Errorf("字节数组消息解析不正确: %q %s", entry.Message, entry.Level)
+	}
+}
+
+func TestJournalLogParser_CanParse(t *testing.T) {
+	parser := NewJournalLogParser()
+
+	tests := map[string]bool{
+		`{"__REALTIME_TIMESTAMP":"1701945045123456","MESSAGE":"hello"}`:           true,
+		`{"__REALTIME_TIMESTAMP":"1701945045123456","_SYSTEMD_UNIT":"a.service"}`: false,
+		`{"level":"info","msg":"hello","time":"2023-12-07T10:30:45Z"}`:            false,
+		`{"log":"hello\n","stream":"stdout","time":"2023-12-07T10:30:45Z"}`:       false,
+		`Dec  7 10:30:45 web1 nginx[812]: connect() failed`:                       false,
+	}
+	for line, expected := range tests {
+		if got := parser.CanParse(line); got != expected {
+			t.Errorf("CanParse(%s) = %v，期望 %v", line, got, expected)
+		}
+	}
+
+	detector := NewAutoDetector()
+	sample := `{"__REALTIME_TIMESTAMP":"1701945045123456","PRIORITY":"6","MESSAGE":"started"}` + "\n" +
+		`{"__REALTIME_TIMESTAMP":"1701945046123456","PRIORITY":"6","MESSAGE":"ready"}`
+	if format := detector.DetectFormat(sample).GetFormat(); format != "Journal" {
+		t.Errorf("期望检测为 Journal，得到 %s", format)
+	}
+}
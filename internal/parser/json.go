@@ -116,25 +116,9 @@ func (p *JSONLogParser) extractLevel(data map[string]interface{}) string {
 	for _, field := range levelFields {
 		if value, exists := data[field]; exists {
 			if levelStr, ok := value.(string); ok {
-				level := strings.ToUpper(strings.TrimSpace(levelStr))
 				// 标准化级别名称
-				switch level {
-				case "ERR", "ERROR":
-					return "ERROR"
-				case "WARN", "WARNING":
-					return "WARN"
-				case "INFO", "INFORMATION":
-					return "INFO"
-				case "DEBUG", "DBG":
-					return "DEBUG"
-				case "TRACE", "TRC":
-					return "TRACE"
-				case "FATAL", "CRIT", "CRITICAL":
-					return "FATAL"
-				default:
-					if level != "" {
-						return level
-					}
+				if level := NormalizeLevel(levelStr); level != "" {
+					return level
 				}
 			}
 		}
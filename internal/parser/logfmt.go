@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// LogfmtLogParser logfmt格式日志解析器（key=value key2="quoted value"）
+type LogfmtLogParser struct {
+	*BaseParser
+	// 复用JSON解析器的时间、级别、消息字段识别规则
+	fieldExtractor *JSONLogParser
+}
+
+// NewLogfmtLogParser 创建logfmt日志解析器
+func NewLogfmtLogParser() *LogfmtLogParser {
+	return &LogfmtLogParser{
+		BaseParser:     NewBaseParser("Logfmt"),
+		fieldExtractor: NewJSONLogParser(),
+	}
+}
+
+// logfmtPair logfmt键值对
+type logfmtPair struct {
+	key      string
+	value    string
+	quoted   bool
+	hasValue bool
+}
+
+// Parse 解析logfmt格式的日志行
+func (p *LogfmtLogParser) Parse(line string) (*types.LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	pairs, err := scanLogfmt(line)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse logfmt: %w", err)
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no key=value pairs found")
+	}
+
+	data := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		data[pair.key] = coerceLogfmtValue(pair)
+	}
+
+	entry := &types.LogEntry{
+		Raw:     line,
+		Fields:  data,
+		LogType: "Logfmt",
+	}
+
+	// 提取时间戳
+	if timestamp := p.fieldExtractor.extractTimestamp(data); !timestamp.IsZero() {
+		entry.Timestamp = timestamp
+	} else {
+		entry.Timestamp = time.Now()
+	}
+
+	// 提取日志级别和消息
+	entry.Level = p.fieldExtractor.extractLevel(data)
+	entry.Message = p.fieldExtractor.extractMessage(data)
+
+	return entry, nil
+}
+
+// CanParse 检查是否能解析指定内容
+// 要求整行都由 key=value 组成且至少有两个键值对，避免把普通文本误判为logfmt
+func (p *LogfmtLogParser) CanParse(content string) bool {
+	content = strings.TrimSpace(content)
+	if content == "" || strings.HasPrefix(content, "{") {
+		return false
+	}
+
+	pairs, err := scanLogfmt(content)
+	if err != nil || len(pairs) < 2 {
+		return false
+	}
+
+	for _, pair := range pairs {
+		if !pair.hasValue {
+			return false
+		}
+	}
+	return true
+}
+
+// scanLogfmt 将一行logfmt拆分为键值对，支持双引号和转义字符
+func scanLogfmt(line string) ([]logfmtPair, error) {
+	var pairs []logfmtPair
+	i, n := 0, len(line)
+
+	for i < n {
+		// 跳过空白
+		for i < n && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i >= n {
+			break
+		}
+
+		// 读取键
+		start := i
+		for i < n && line[i] != '=' && line[i] != ' ' && line[i] != '\t' && line[i] != '"' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected character %q at position %d", line[i], i)
+		}
+		pair := logfmtPair{key: line[start:i]}
+
+		if i >= n || line[i] != '=' {
+			// 没有值的键，例如 "debug"
+			if i < n && line[i] == '"' {
+				return nil, fmt.Errorf("unexpected quote at position %d", i)
+			}
+			pairs = append(pairs, pair)
+			continue
+		}
+
+		// 跳过 '='
+		i++
+		pair.hasValue = true
+
+		if i < n && line[i] == '"' {
+			value, next, err := scanQuotedValue(line, i)
+			if err != nil {
+				return nil, err
+			}
+			pair.value = value
+			pair.quoted = true
+			i = next
+		} else {
+			start = i
+			for i < n && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			pair.value = line[start:i]
+		}
+
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// scanQuotedValue 读取从start处的引号开始的带引号值，返回反转义后的值和结束位置
+func scanQuotedValue(line string, start int) (string, int, error) {
+	var sb strings.Builder
+	i := start + 1
+
+	for i < len(line) {
+		c := line[i]
+		switch c {
+		case '\\':
+			if i+1 >= len(line) {
+				return "", 0, fmt.Errorf("unterminated escape at position %d", i)
+			}
+			switch line[i+1] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(line[i+1])
+			}
+			i += 2
+		case '"':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted value starting at position %d", start)
+}
+
+// coerceLogfmtValue 将未加引号的值转换为数字、时长或布尔类型；带引号的值始终保留为字符串
+func coerceLogfmtValue(pair logfmtPair) interface{} {
+	if !pair.hasValue {
+		return true
+	}
+	if pair.quoted || pair.value == "" {
+		return pair.value
+	}
+
+	if v, err := strconv.ParseInt(pair.value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(pair.value, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+		return v
+	}
+	switch strings.ToLower(pair.value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if v, err := time.ParseDuration(pair.value); err == nil {
+		return v.Seconds()
+	}
+
+	return pair.value
+}
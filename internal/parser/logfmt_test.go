@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestLogfmtLogParser_Parse(t *testing.T) {
+	parser := NewLogfmtLogParser()
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		checkFields func(t *testing.T, entry *types.LogEntry)
+	}{
+		{
+			name:  "Standard logfmt line",
+			input: `time=2023-12-07T10:30:45Z level=info msg="request served" dur=12ms status=200 cached=false ratio=0.75`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "INFO" {
+					t.Errorf("Expected level INFO, got %s", entry.Level)
+				}
+				if entry.Message != "request served" {
+					t.Errorf("Expected message 'request served', got %s", entry.Message)
+				}
+				expectedTime := time.Date(2023, 12, 7, 10, 30, 45, 0, time.UTC)
+				if !entry.Timestamp.Equal(expectedTime) {
+					t.Errorf("Expected timestamp %v, got %v", expectedTime, entry.Timestamp)
+				}
+				if v, ok := entry.Fields["status"].(int64); !ok || v != 200 {
+					t.Errorf("Expected status int64(200), got %#v", entry.Fields["status"])
+				}
+				if v, ok := entry.Fields["dur"].(float64); !ok || v != 0.012 {
+					t.Errorf("Expected dur 0.012, got %#v", entry.Fields["dur"])
+				}
+				if v, ok := entry.Fields["cached"].(bool); !ok || v {
+					t.Errorf("Expected cached false, got %#v", entry.Fields["cached"])
+				}
+				if v, ok := entry.Fields["ratio"].(float64); !ok || v != 0.75 {
+					t.Errorf("Expected ratio 0.75, got %#v", entry.Fields["ratio"])
+				}
+				if entry.LogType != "Logfmt" {
+					t.Errorf("Expected log type Logfmt, got %s", entry.LogType)
+				}
+			},
+		},
+		{
+			name:  "Quoted values with escapes stay strings",
+			input: `level=error msg="failed: \"disk\" full\nretrying" code="500" path=C:\\tmp`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "ERROR" {
+					t.Errorf("Expected level ERROR, got %s", entry.Level)
+				}
+				expected := "failed: \"disk\" full\nretrying"
+				if entry.Message != expected {
+					t.Errorf("Expected message %q, got %q", expected, entry.Message)
+				}
+				if v, ok := entry.Fields["code"].(string); !ok || v != "500" {
+					t.Errorf("Expected quoted code to stay string, got %#v", entry.Fields["code"])
+				}
+				if entry.Fields["path"] != `C:\\tmp` {
+					t.Errorf("Expected unquoted path to be kept verbatim, got %v", entry.Fields["path"])
+				}
+			},
+		},
+		{
+			name:  "Unix timestamp and bare key",
+			input: `ts=1701944445 lvl=warn msg=slow debug`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if !entry.Timestamp.Equal(time.Unix(1701944445, 0)) {
+					t.Errorf("Expected unix timestamp, got %v", entry.Timestamp)
+				}
+				if entry.Fields["debug"] != true {
+					t.Errorf("Expected bare key to be true, got %v", entry.Fields["debug"])
+				}
+			},
+		},
+		{
+			name:        "Unterminated quote",
+			input:       `level=info msg="oops`,
+			expectError: true,
+		},
+		{
+			name:        "Empty line",
+			input:       "   ",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parser.Parse(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.checkFields != nil {
+				tt.checkFields(t, entry)
+			}
+		})
+	}
+}
+
+func TestLogfmtLogParser_CanParse(t *testing.T) {
+	parser := NewLogfmtLogParser()
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`level=info msg="hello world"`, true},
+		{`a=1 b=2 c=3`, true},
+		{`level=info`, false},
+		{`2023-01-01 10:00:00 INFO user=bob logged in`, false},
+		{`{"level": "info", "msg": "x=y"}`, false},
+		{`192.168.1.1 - - [07/Dec/2023:10:30:45 +0000] "GET / HTTP/1.1" 200 1234`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		if got := parser.CanParse(tt.input); got != tt.expected {
+			t.Errorf("CanParse(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestAutoDetector_DetectsLogfmt(t *testing.T) {
+	detector := NewAutoDetector()
+
+	content := `level=info msg="server started" port=8080
+level=debug msg="loading config" path=/etc/app.yaml
+level=error msg="db unreachable" retry=3`
+
+	if format := detector.DetectFormat(content).GetFormat(); format != "Logfmt" {
+		t.Errorf("Expected Logfmt, got %s", format)
+	}
+}
+
+func TestLogfmtLogParserImplementsInterface(t *testing.T) {
+	var _ interfaces.LogParser = NewLogfmtLogParser()
+}
@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// grokPatterns 内置的grok基础模式
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d+)?|\.\d+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:\d{1,3}\.){3}\d{1,3}`,
+	"IPV6":              `[0-9A-Fa-f:]*:[0-9A-Fa-f:.]+`,
+	"IP":                `(?:%{IPV4}|%{IPV6})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z\-_.]*\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"USER":              `[a-zA-Z0-9._-]+`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATHPARAM":      `/[^\s?]*(?:\?\S*)?`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"DURATION":          `[+-]?(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+`,
+	"YEAR":              `\d{4}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0?[1-9]|[12]\d|3[01])`,
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"HOUR":              `(?:[01]?\d|2[0-3])`,
+	"MINUTE":            `[0-5]\d`,
+	"SECOND":            `(?:[0-5]\d|60)(?:[.,]\d+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}:%{SECOND}`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]\d{4}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+}
+
+// grokReferenceRegex 匹配 %{PATTERN}、%{PATTERN:field} 和 %{PATTERN:field:type}
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::(\w+))?(?::(\w+))?\}`)
+
+// maxGrokDepth grok模式展开的最大嵌套深度
+const maxGrokDepth = 10
+
+// PatternLogParser 基于命名分组正则或grok模式的自定义日志解析器
+type PatternLogParser struct {
+	*BaseParser
+	regex           *regexp.Regexp
+	timestampField  string
+	timestampLayout string
+	levelField      string
+	messageField    string
+	fieldTypes      map[string]string
+	files           []string
+}
+
+// NewPatternLogParser 根据配置创建自定义解析器
+func NewPatternLogParser(def config.ParserConfig) (*PatternLogParser, error) {
+	fieldTypes := make(map[string]string, len(def.FieldTypes))
+	for field, fieldType := range def.FieldTypes {
+		fieldTypes[field] = strings.ToLower(fieldType)
+	}
+
+	pattern := def.Pattern
+	if strings.EqualFold(def.Type, "grok") {
+		expanded, err := ExpandGrok(def.Pattern, fieldTypes)
+		if err != nil {
+			return nil, fmt.Errorf("解析器 %s 的grok模式无效: %w", def.Name, err)
+		}
+		pattern = expanded
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("解析器 %s 的正则表达式无效: %w", def.Name, err)
+	}
+
+	if len(regex.SubexpNames()) <= 1 {
+		return nil, fmt.Errorf("解析器 %s 的模式不包含任何命名字段", def.Name)
+	}
+
+	return &PatternLogParser{
+		BaseParser:      NewBaseParser(def.Name),
+		regex:           regex,
+		timestampField:  def.TimestampField,
+		timestampLayout: def.TimestampLayout,
+		levelField:      def.LevelField,
+		messageField:    def.MessageField,
+		fieldTypes:      fieldTypes,
+		files:           def.Files,
+	}, nil
+}
+
+// ExpandGrok 将grok模式展开为Go正则表达式，内联声明的字段类型写入fieldTypes
+func ExpandGrok(pattern string, fieldTypes map[string]string) (string, error) {
+	return expandGrok(pattern, fieldTypes, 0)
+}
+
+// expandGrok 递归展开grok模式引用
+func expandGrok(pattern string, fieldTypes map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok模式嵌套过深")
+	}
+
+	var expandErr error
+	result := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if expandErr != nil {
+			return ref
+		}
+
+		matches := grokReferenceRegex.FindStringSubmatch(ref)
+		name, field, fieldType := matches[1], matches[2], matches[3]
+
+		base, exists := grokPatterns[name]
+		if !exists {
+			expandErr = fmt.Errorf("未知的grok模式: %s", name)
+			return ref
+		}
+
+		expanded, err := expandGrok(base, fieldTypes, depth+1)
+		if err != nil {
+			expandErr = err
+			return ref
+		}
+
+		if field == "" {
+			return "(?:" + expanded + ")"
+		}
+
+		if fieldType != "" && fieldTypes != nil {
+			fieldTypes[field] = strings.ToLower(fieldType)
+		}
+		return "(?P<" + field + ">" + expanded + ")"
+	})
+
+	if expandErr != nil {
+		return "", expandErr
+	}
+
+	return result, nil
+}
+
+// Parse 解析日志行
+func (p *PatternLogParser) Parse(line string) (*types.LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	matches := p.regex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("line does not match pattern %s", p.GetFormat())
+	}
+
+	entry := &types.LogEntry{
+		Raw:     line,
+		Fields:  make(map[string]interface{}),
+		LogType: p.GetFormat(),
+	}
+
+	values := make(map[string]string)
+	for i, name := range p.regex.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		values[name] = matches[i]
+		entry.Fields[name] = p.coerce(name, matches[i])
+	}
+
+	// 提取时间戳
+	entry.Timestamp = time.Now()
+	if p.timestampField != "" {
+		if value, exists := values[p.timestampField]; exists && value != "" {
+			if timestamp, ok := p.parseTimestamp(value); ok {
+				entry.Timestamp = timestamp
+			}
+		}
+	}
+
+	// 提取日志级别
+	if value, exists := values[p.levelField]; exists && value != "" {
+		entry.Level = NormalizeLevel(value)
+	} else {
+		entry.Level = p.ExtractLogLevel(line)
+	}
+
+	// 提取消息
+	if value, exists := values[p.messageField]; exists && value != "" {
+		entry.Message = value
+	} else {
+		entry.Message = line
+	}
+
+	return entry, nil
+}
+
+// CanParse 检查是否能解析指定内容
+func (p *PatternLogParser) CanParse(content string) bool {
+	return p.regex.MatchString(strings.TrimSpace(content))
+}
+
+// Fingerprint 返回解析规则（展开后的正则、时间戳、级别、消息字段和字段类型）的哈希
+func (p *PatternLogParser) Fingerprint() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s", p.regex.String(), p.timestampField, p.timestampLayout, p.levelField, p.messageField)
+	fields := make([]string, 0, len(p.fieldTypes))
+	for field := range p.fieldTypes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Fprintf(h, "\x00%s=%s", field, p.fieldTypes[field])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MatchesFile 检查文件路径是否绑定到该解析器
+func (p *PatternLogParser) MatchesFile(path string) bool {
+	for _, glob := range p.files {
+		if matched, _ := filepath.Match(glob, path); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(glob, filepath.Base(path)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimestamp 按配置的布局解析时间戳
+func (p *PatternLogParser) parseTimestamp(value string) (time.Time, bool) {
+	switch p.timestampLayout {
+	case "":
+		return p.ParseTimestamp(value), true
+	case "unix":
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Unix(0, int64(seconds*float64(time.Second))), true
+		}
+		return time.Time{}, false
+	case "unix_ms":
+		if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.UnixMilli(millis), true
+		}
+		return time.Time{}, false
+	default:
+		if t, err := time.Parse(p.timestampLayout, value); err == nil {
+			return t, true
+		}
+		return time.Time{}, false
+	}
+}
+
+// coerce 按配置的类型转换字段值，转换失败时保留原始字符串
+func (p *PatternLogParser) coerce(field, value string) interface{} {
+	return CoerceValue(p.fieldTypes[field], value)
+}
+
+// CoerceValue 将字符串值转换为指定类型，转换失败时返回原始字符串
+func CoerceValue(fieldType, value string) interface{} {
+	switch fieldType {
+	case "int":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "float":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "bool":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	case "duration":
+		if v, err := time.ParseDuration(value); err == nil {
+			return v.Seconds()
+		}
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+)
+
+func TestPatternLogParser_Regex(t *testing.T) {
+	parser, err := NewPatternLogParser(config.ParserConfig{
+		Name:            "orders",
+		Type:            "regex",
+		Pattern:         `^(?P<ts>\S+) \[(?P<lvl>\w+)\] order=(?P<order>\d+) ok=(?P<ok>\w+) took=(?P<took>\S+) (?P<msg>.*)$`,
+		TimestampField:  "ts",
+		TimestampLayout: time.RFC3339,
+		LevelField:      "lvl",
+		MessageField:    "msg",
+		FieldTypes: map[string]string{
+			"order": "int",
+			"ok":    "bool",
+			"took":  "duration",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	entry, err := parser.Parse("2023-12-07T10:30:45Z [warning] order=42 ok=true took=1500ms payment retried")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if entry.Level != "WARN" {
+		t.Errorf("Expected level WARN, got %s", entry.Level)
+	}
+	if entry.Message != "payment retried" {
+		t.Errorf("Expected message 'payment retried', got %s", entry.Message)
+	}
+	expectedTime := time.Date(2023, 12, 7, 10, 30, 45, 0, time.UTC)
+	if !entry.Timestamp.Equal(expectedTime) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTime, entry.Timestamp)
+	}
+	if entry.LogType != "orders" {
+		t.Errorf("Expected log type orders, got %s", entry.LogType)
+	}
+	if v, ok := entry.Fields["order"].(int64); !ok || v != 42 {
+		t.Errorf("Expected order field int64(42), got %#v", entry.Fields["order"])
+	}
+	if v, ok := entry.Fields["ok"].(bool); !ok || !v {
+		t.Errorf("Expected ok field true, got %#v", entry.Fields["ok"])
+	}
+	if v, ok := entry.Fields["took"].(float64); !ok || v != 1.5 {
+		t.Errorf("Expected took field 1.5, got %#v", entry.Fields["took"])
+	}
+
+	if _, err := parser.Parse("something else entirely"); err == nil {
+		t.Error("Expected error for non-matching line")
+	}
+}
+
+func TestPatternLogParser_Grok(t *testing.T) {
+	parser, err := NewPatternLogParser(config.ParserConfig{
+		Name:           "gateway",
+		Type:           "grok",
+		Pattern:        `%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} %{IP:client} %{NUMBER:latency:float}ms %{GREEDYDATA:message}`,
+		TimestampField: "time",
+		LevelField:     "level",
+		MessageField:   "message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	line := "2023-12-07 10:30:45 ERROR 10.0.0.7 12.5ms upstream timeout"
+	if !parser.CanParse(line) {
+		t.Fatal("Expected grok parser to match line")
+	}
+
+	entry, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if entry.Level != "ERROR" {
+		t.Errorf("Expected level ERROR, got %s", entry.Level)
+	}
+	if entry.Message != "upstream timeout" {
+		t.Errorf("Expected message 'upstream timeout', got %s", entry.Message)
+	}
+	if entry.Fields["client"] != "10.0.0.7" {
+		t.Errorf("Expected client 10.0.0.7, got %v", entry.Fields["client"])
+	}
+	if v, ok := entry.Fields["latency"].(float64); !ok || v != 12.5 {
+		t.Errorf("Expected latency 12.5, got %#v", entry.Fields["latency"])
+	}
+	if entry.Timestamp.Year() != 2023 {
+		t.Errorf("Expected timestamp in 2023, got %v", entry.Timestamp)
+	}
+}
+
+func TestPatternLogParser_InvalidDefinitions(t *testing.T) {
+	tests := []struct {
+		name string
+		def  config.ParserConfig
+	}{
+		{
+			name: "Unknown grok pattern",
+			def:  config.ParserConfig{Name: "bad", Type: "grok", Pattern: `%{NOPE:x}`},
+		},
+		{
+			name: "Invalid regex",
+			def:  config.ParserConfig{Name: "bad", Type: "regex", Pattern: `(?P<x>[`},
+		},
+		{
+			name: "No named fields",
+			def:  config.ParserConfig{Name: "bad", Type: "regex", Pattern: `^\d+ .*$`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPatternLogParser(tt.def); err == nil {
+				t.Error("Expected error for invalid definition")
+			}
+		})
+	}
+}
+
+func TestPatternLogParser_MatchesFile(t *testing.T) {
+	parser, err := NewPatternLogParser(config.ParserConfig{
+		Name:    "app",
+		Type:    "regex",
+		Pattern: `^(?P<message>.*)$`,
+		Files:   []string{"app-*.log", "/var/log/gateway/*.out"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/tmp/logs/app-2023.log", true},
+		{"/var/log/gateway/stdout.out", true},
+		{"/tmp/logs/other.log", false},
+	}
+
+	for _, tt := range tests {
+		if got := parser.MatchesFile(tt.path); got != tt.expected {
+			t.Errorf("MatchesFile(%s) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestPatternLogParserImplementsInterface(t *testing.T) {
+	parser, err := NewPatternLogParser(config.ParserConfig{Name: "x", Type: "regex", Pattern: `(?P<message>.*)`})
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	var _ interfaces.LogParser = parser
+}
@@ -0,0 +1,319 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// syslogFacilities 设施编号对应的名称
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogSeverities 严重性编号对应的名称
+var syslogSeverities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// SyslogParser Syslog格式日志解析器（RFC3164 和 RFC5424）
+type SyslogParser struct {
+	*BaseParser
+	rfc5424Regex *regexp.Regexp
+	rfc3164Regex *regexp.Regexp
+	// now 用于推断RFC3164时间戳缺失的年份
+	now func() time.Time
+}
+
+// NewSyslogParser 创建Syslog日志解析器
+func NewSyslogParser() *SyslogParser {
+	return &SyslogParser{
+		BaseParser: NewBaseParser("Syslog"),
+		// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+		rfc5424Regex: regexp.MustCompile(`^<(\d{1,3})>(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) (.*)$`),
+		// [<PRI>]TIMESTAMP HOSTNAME [TAG[PID]: ]MSG，时间戳为BSD格式或rsyslog的RFC3339格式，其余限制见 match3164
+		rfc3164Regex: regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})) (\S+) (?:([^\s:\[]+)(?:\[([^\]]*)\])?: ?)?(.*)$`),
+		now:          time.Now,
+	}
+}
+
+// Parse 解析Syslog格式的日志行
+func (p *SyslogParser) Parse(line string) (*types.LogEntry, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	entry := &types.LogEntry{
+		Raw:     line,
+		Fields:  make(map[string]interface{}),
+		LogType: "Syslog",
+	}
+
+	if p.parseRFC5424(line, entry) {
+		return entry, nil
+	}
+	if p.parseRFC3164(line, entry) {
+		return entry, nil
+	}
+
+	return nil, fmt.Errorf("line is not in syslog format")
+}
+
+// CanParse 检查是否能解析指定内容
+func (p *SyslogParser) CanParse(content string) bool {
+	content = strings.TrimSpace(content)
+	if matches := p.rfc5424Regex.FindStringSubmatch(content); matches != nil {
+		if _, ok := parsePriority(matches[1]); ok {
+			return true
+		}
+	}
+	return p.match3164(content) != nil
+}
+
+// match3164 匹配RFC3164格式。主机名不能是日志级别，不带PRI的行（本地日志文件，包括rsyslog默认的RFC3339时间戳）
+// 必须带有 TAG:，避免把 "2024-01-01T10:00:00Z ERROR ..." 这类应用日志识别为syslog
+func (p *SyslogParser) match3164(line string) []string {
+	matches := p.rfc3164Regex.FindStringSubmatch(line)
+	if matches == nil || isLevelKeyword(matches[3]) || (matches[1] == "" && matches[4] == "") {
+		return nil
+	}
+	return matches
+}
+
+// isLevelKeyword 判断主机名位置的单词是否为日志级别
+func isLevelKeyword(word string) bool {
+	switch NormalizeLevel(strings.Trim(word, "[]:")) {
+	case "ERROR", "WARN", "INFO", "DEBUG", "TRACE", "FATAL", "NOTICE", "PANIC", "ALERT", "EMERG":
+		return true
+	}
+	return false
+}
+
+// parseRFC5424 解析RFC5424格式
+func (p *SyslogParser) parseRFC5424(line string, entry *types.LogEntry) bool {
+	matches := p.rfc5424Regex.FindStringSubmatch(line)
+	if matches == nil {
+		return false
+	}
+
+	pri, ok := parsePriority(matches[1])
+	if !ok {
+		return false
+	}
+
+	structuredData, message, err := parseStructuredData(matches[8])
+	if err != nil {
+		return false
+	}
+
+	timestamp := time.Now()
+	if matches[3] != "-" {
+		t, err := time.Parse(time.RFC3339Nano, matches[3])
+		if err != nil {
+			return false
+		}
+		timestamp = t
+	}
+
+	p.applyPriority(pri, entry)
+	entry.Timestamp = timestamp
+	entry.Fields["syslog_format"] = "RFC5424"
+	entry.Fields["version"] = matches[2]
+	setSyslogField(entry, "hostname", matches[4])
+	setSyslogField(entry, "app_name", matches[5])
+	setSyslogField(entry, "procid", matches[6])
+	setSyslogField(entry, "msgid", matches[7])
+	if len(structuredData) > 0 {
+		entry.Fields["structured_data"] = structuredData
+	}
+
+	// 去掉可选的UTF-8 BOM
+	entry.Message = strings.TrimPrefix(message, "\ufeff")
+
+	return true
+}
+
+// parseRFC3164 解析RFC3164（BSD）格式，PRI可选以兼容 /var/log/syslog 等本地文件
+func (p *SyslogParser) parseRFC3164(line string, entry *types.LogEntry) bool {
+	matches := p.match3164(line)
+	if matches == nil {
+		return false
+	}
+
+	timestamp, ok := p.parseBSDTimestamp(matches[2])
+	if !ok {
+		return false
+	}
+
+	if matches[1] != "" {
+		pri, ok := parsePriority(matches[1])
+		if !ok {
+			return false
+		}
+		p.applyPriority(pri, entry)
+	} else {
+		// 本地日志文件不带PRI，从消息内容推断级别
+		entry.Level = p.ExtractLogLevel(matches[6])
+	}
+
+	entry.Timestamp = timestamp
+	entry.Fields["syslog_format"] = "RFC3164"
+	setSyslogField(entry, "hostname", matches[3])
+	setSyslogField(entry, "app_name", matches[4])
+	setSyslogField(entry, "procid", matches[5])
+	entry.Message = matches[6]
+
+	return true
+}
+
+// parseBSDTimestamp 解析RFC3164时间戳，缺失的年份根据当前时间推断
+func (p *SyslogParser) parseBSDTimestamp(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+
+	t, err := time.ParseInLocation("Jan _2 15:04:05", value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	now := p.now()
+	t = t.AddDate(now.Year(), 0, 0)
+	// 跨年时（例如1月读取12月的日志）时间会落在未来，归到上一年
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
+
+// applyPriority 根据PRI设置设施、严重性和日志级别
+func (p *SyslogParser) applyPriority(pri int, entry *types.LogEntry) {
+	facility := pri / 8
+	severity := pri % 8
+
+	entry.Fields["facility_code"] = facility
+	entry.Fields["severity_code"] = severity
+	if facility < len(syslogFacilities) {
+		entry.Fields["facility"] = syslogFacilities[facility]
+	}
+	entry.Fields["severity"] = syslogSeverities[severity]
+	entry.Level = SyslogSeverityToLevel(severity)
+}
+
+// SyslogSeverityToLevel 将syslog严重性映射为日志级别
+func SyslogSeverityToLevel(severity int) string {
+	switch {
+	case severity <= 2:
+		return "FATAL"
+	case severity == 3:
+		return "ERROR"
+	case severity == 4:
+		return "WARN"
+	case severity <= 6:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// parsePriority 解析PRI值（0-191）
+func parsePriority(value string) (int, bool) {
+	pri, err := strconv.Atoi(value)
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, false
+	}
+	return pri, true
+}
+
+// setSyslogField 设置字段，"-" 表示空值时跳过
+func setSyslogField(entry *types.LogEntry, key, value string) {
+	if value != "" && value != "-" {
+		entry.Fields[key] = value
+	}
+}
+
+// parseStructuredData 解析RFC5424结构化数据，返回结构化数据和剩余的消息
+func parseStructuredData(rest string) (map[string]map[string]string, string, error) {
+	if rest == "-" || strings.HasPrefix(rest, "- ") {
+		return nil, strings.TrimPrefix(strings.TrimPrefix(rest, "-"), " "), nil
+	}
+
+	data := make(map[string]map[string]string)
+	i := 0
+	for i < len(rest) && rest[i] == '[' {
+		i++
+		// SD-ID
+		start := i
+		for i < len(rest) && rest[i] != ' ' && rest[i] != ']' {
+			i++
+		}
+		if i >= len(rest) {
+			return nil, "", fmt.Errorf("unterminated structured data")
+		}
+		id := rest[start:i]
+		params := make(map[string]string)
+
+		for i < len(rest) && rest[i] == ' ' {
+			i++
+			// PARAM-NAME
+			start = i
+			for i < len(rest) && rest[i] != '=' {
+				i++
+			}
+			if i+1 >= len(rest) || rest[i+1] != '"' {
+				return nil, "", fmt.Errorf("invalid structured data parameter")
+			}
+			name := rest[start:i]
+
+			value, next, err := scanSDValue(rest, i+1)
+			if err != nil {
+				return nil, "", err
+			}
+			params[name] = value
+			i = next
+		}
+
+		if i >= len(rest) || rest[i] != ']' {
+			return nil, "", fmt.Errorf("unterminated structured data element %s", id)
+		}
+		i++
+		data[id] = params
+	}
+
+	if i == 0 {
+		return nil, "", fmt.Errorf("invalid structured data")
+	}
+
+	return data, strings.TrimPrefix(rest[i:], " "), nil
+}
+
+// scanSDValue 读取结构化数据参数值，支持 \" \\ \] 转义
+func scanSDValue(s string, start int) (string, int, error) {
+	var sb strings.Builder
+	i := start + 1
+	for i < len(s) {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				sb.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+			sb.WriteByte('\\')
+			i++
+		case '"':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated structured data value")
+}
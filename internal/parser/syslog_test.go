@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestSyslogParser_Parse(t *testing.T) {
+	parser := NewSyslogParser()
+	parser.now = func() time.Time {
+		return time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		checkFields func(t *testing.T, entry *types.LogEntry)
+	}{
+		{
+			name:  "RFC5424 with structured data",
+			input: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][meta seq="7\]"] An application event`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "INFO" {
+					t.Errorf("Expected level INFO (notice), got %s", entry.Level)
+				}
+				if entry.Fields["facility"] != "local4" || entry.Fields["severity"] != "notice" {
+					t.Errorf("Unexpected facility/severity: %v/%v", entry.Fields["facility"], entry.Fields["severity"])
+				}
+				if entry.Fields["hostname"] != "mymachine.example.com" {
+					t.Errorf("Unexpected hostname: %v", entry.Fields["hostname"])
+				}
+				if entry.Fields["app_name"] != "evntslog" || entry.Fields["msgid"] != "ID47" {
+					t.Errorf("Unexpected app_name/msgid: %v/%v", entry.Fields["app_name"], entry.Fields["msgid"])
+				}
+				if _, exists := entry.Fields["procid"]; exists {
+					t.Error("Expected nil procid to be omitted")
+				}
+				sd, ok := entry.Fields["structured_data"].(map[string]map[string]string)
+				if !ok {
+					t.Fatalf("Expected structured data, got %#v", entry.Fields["structured_data"])
+				}
+				if sd["exampleSDID@32473"]["eventSource"] != "Application" {
+					t.Errorf("Unexpected structured data: %v", sd)
+				}
+				if sd["meta"]["seq"] != "7]" {
+					t.Errorf("Expected escaped bracket in SD value, got %q", sd["meta"]["seq"])
+				}
+				if entry.Message != "An application event" {
+					t.Errorf("Unexpected message: %q", entry.Message)
+				}
+				expected := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+				if !entry.Timestamp.Equal(expected) {
+					t.Errorf("Expected timestamp %v, got %v", expected, entry.Timestamp)
+				}
+			},
+		},
+		{
+			name:  "RFC5424 without structured data",
+			input: `<11>1 2023-12-07T10:30:45+08:00 web01 nginx 4242 - - upstream timed out`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "ERROR" {
+					t.Errorf("Expected level ERROR, got %s", entry.Level)
+				}
+				if entry.Fields["procid"] != "4242" {
+					t.Errorf("Unexpected procid: %v", entry.Fields["procid"])
+				}
+				if entry.Message != "upstream timed out" {
+					t.Errorf("Unexpected message: %q", entry.Message)
+				}
+			},
+		},
+		{
+			name:  "RFC3164 with PRI",
+			input: `<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "FATAL" {
+					t.Errorf("Expected level FATAL (crit), got %s", entry.Level)
+				}
+				if entry.Fields["facility"] != "auth" {
+					t.Errorf("Unexpected facility: %v", entry.Fields["facility"])
+				}
+				if entry.Fields["app_name"] != "su" || entry.Fields["procid"] != "123" {
+					t.Errorf("Unexpected tag: %v[%v]", entry.Fields["app_name"], entry.Fields["procid"])
+				}
+				if entry.Message != "'su root' failed for lonvick on /dev/pts/8" {
+					t.Errorf("Unexpected message: %q", entry.Message)
+				}
+				// 10月的日志在1月读取时应归到上一年
+				if entry.Timestamp.Year() != 2023 || entry.Timestamp.Month() != time.October {
+					t.Errorf("Expected October 2023, got %v", entry.Timestamp)
+				}
+			},
+		},
+		{
+			name:  "Local syslog file without PRI",
+			input: `Jan  5 09:15:01 host01 CRON[2211]: (root) CMD (command -v debian-sa1 > /dev/null)`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Fields["hostname"] != "host01" || entry.Fields["app_name"] != "CRON" {
+					t.Errorf("Unexpected host/app: %v/%v", entry.Fields["hostname"], entry.Fields["app_name"])
+				}
+				if _, exists := entry.Fields["severity"]; exists {
+					t.Error("Expected no severity without PRI")
+				}
+				if entry.Timestamp.Year() != 2024 || entry.Timestamp.Day() != 5 {
+					t.Errorf("Expected Jan 5 2024, got %v", entry.Timestamp)
+				}
+			},
+		},
+		{
+			name:  "rsyslog high precision timestamp",
+			input: `2024-01-05T09:15:01.123456+00:00 host01 sshd[99]: error: Failed password for root`,
+			checkFields: func(t *testing.T, entry *types.LogEntry) {
+				if entry.Level != "ERROR" || entry.Timestamp.Nanosecond() != 123456000 {
+					t.Errorf("Unexpected level/timestamp: %s %v", entry.Level, entry.Timestamp)
+				}
+				if entry.Fields["app_name"] != "sshd" {
+					t.Errorf("Unexpected app_name: %v", entry.Fields["app_name"])
+				}
+			},
+		},
+		{
+			name:        "Not syslog",
+			input:       `{"level": "info"}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := parser.Parse(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if entry.LogType != "Syslog" {
+				t.Errorf("Expected log type Syslog, got %s", entry.LogType)
+			}
+			if tt.checkFields != nil {
+				tt.checkFields(t, entry)
+			}
+		})
+	}
+}
+
+func TestSyslogParser_CanParse(t *testing.T) {
+	parser := NewSyslogParser()
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`<34>Oct 11 22:14:15 mymachine su: 'su root' failed`, true},
+		{`<165>1 2003-10-11T22:14:15.003Z host app - - - msg`, true},
+		{`Oct 11 22:14:15 mymachine kernel: [12.34] usb 1-1: new device`, true},
+		{`<999>1 2003-10-11T22:14:15.003Z host app - - - msg`, false},
+		{`2023-01-01 10:00:00 INFO Application started`, false},
+		{`2024-01-01T10:00:00Z ERROR failed to connect to db`, false},
+		{`2024-01-01T10:00:00Z host01 sshd[99]: no PRI with RFC3339 timestamp`, true},
+		{`2024-01-01T10:00:00Z host01 no tag with RFC3339 timestamp`, false},
+		{`2024-01-01T10:00:00.123Z INFO worker: started`, false},
+		{`Jan  5 09:15:01 WARN disk: almost full`, false},
+		{`Jan  5 09:15:01 host01 no tag without PRI`, false},
+		{`<13>Jan  5 09:15:01 host01 no tag with PRI`, true},
+		{`level=info msg=hello`, false},
+	}
+
+	for _, tt := range tests {
+		if got := parser.CanParse(tt.input); got != tt.expected {
+			t.Errorf("CanParse(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSyslogSeverityToLevel(t *testing.T) {
+	expected := []string{"FATAL", "FATAL", "FATAL", "ERROR", "WARN", "INFO", "INFO", "DEBUG"}
+	for severity, level := range expected {
+		if got := SyslogSeverityToLevel(severity); got != level {
+			t.Errorf("SyslogSeverityToLevel(%d) = %s, expected %s", severity, got, level)
+		}
+	}
+}
+
+func TestAutoDetector_DetectsSyslog(t *testing.T) {
+	detector := NewAutoDetector()
+
+	content := `Jan  5 09:15:01 host01 CRON[2211]: (root) CMD (run-parts /etc/cron.hourly)
+Jan  5 09:17:01 host01 systemd[1]: Started Session 42 of user root.
+Jan  5 09:18:22 host01 sshd[3100]: Accepted publickey for deploy`
+
+	if format := detector.DetectFormat(content).GetFormat(); format != "Syslog" {
+		t.Errorf("Expected Syslog, got %s", format)
+	}
+
+	// 当前 Ubuntu、Debian 的 /var/log/syslog 和 auth.log 使用 rsyslog 默认的高精度时间戳，不带PRI
+	content = `2024-01-05T09:15:01.123456+00:00 host01 CRON[2211]: (root) CMD (run-parts /etc/cron.hourly)
+2024-01-05T09:17:01.000012+00:00 host01 systemd[1]: Started Session 42 of user root.
+2024-01-05T09:18:22.481516+00:00 host01 sshd[3100]: Accepted publickey for deploy`
+
+	if format := detector.DetectFormat(content).GetFormat(); format != "Syslog" {
+		t.Errorf("Expected Syslog for rsyslog high precision timestamps, got %s", format)
+	}
+}
+
+// 带RFC3339时间戳和级别的应用日志不能被识别为syslog（主机名会变成级别）
+func TestAutoDetector_AppLogsAreNotSyslog(t *testing.T) {
+	detector := NewAutoDetector()
+
+	content := `2024-01-01T10:00:00Z ERROR failed to connect to db
+2024-01-01T10:00:01Z INFO retrying in 5s
+2024-01-01T10:00:06Z WARN connection pool exhausted`
+
+	parser := detector.DetectFormat(content)
+	if format := parser.GetFormat(); format == "Syslog" {
+		t.Fatalf("Expected application log format, got %s", format)
+	}
+	entry, err := parser.Parse("2024-01-01T10:00:00Z ERROR failed to connect to db")
+	if err != nil || entry.Level != "ERROR" {
+		t.Errorf("Expected level ERROR, got %+v, %v", entry, err)
+	}
+}
+
+func TestSyslogParserImplementsInterface(t *testing.T) {
+	var _ interfaces.LogParser = NewSyslogParser()
+}
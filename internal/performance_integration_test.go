@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -65,7 +66,7 @@ func TestPerformanceIntegration(t *testing.T) {
 
 		// 读取多个页面
 		for i := 0; i < 10; i++ {
-			content, err := logManager.ReadLogFile(logFile, int64(i*100), 100)
+			content, err := logManager.ReadLogFile(context.Background(), logFile, int64(i*100), 100)
 			if err != nil {
 				t.Errorf("读取文件失败: %v", err)
 				continue
@@ -89,7 +90,7 @@ func TestPerformanceIntegration(t *testing.T) {
 	t.Run("CacheEffectiveness", func(t *testing.T) {
 		// 第一次读取（冷缓存）
 		start := time.Now()
-		_, err := logManager.ReadLogFile(logFile, 0, 100)
+		_, err := logManager.ReadLogFile(context.Background(), logFile, 0, 100)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -97,7 +98,7 @@ func TestPerformanceIntegration(t *testing.T) {
 
 		// 第二次读取（热缓存）
 		start = time.Now()
-		_, err = logManager.ReadLogFile(logFile, 0, 100)
+		_, err = logManager.ReadLogFile(context.Background(), logFile, 0, 100)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -129,7 +130,7 @@ func TestPerformanceIntegration(t *testing.T) {
 
 		// 第一次搜索
 		start := time.Now()
-		result1, err := searchEngine.Search(query)
+		result1, err := searchEngine.Search(context.Background(), query)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -137,7 +138,7 @@ func TestPerformanceIntegration(t *testing.T) {
 
 		// 第二次搜索（应该使用缓存）
 		start = time.Now()
-		result2, err := searchEngine.Search(query)
+		result2, err := searchEngine.Search(context.Background(), query)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -198,7 +199,7 @@ func TestPerformanceIntegration(t *testing.T) {
 				for j := 0; j < numOperations; j++ {
 					// 交替进行读取和搜索操作
 					if j%2 == 0 {
-						_, err = logManager.ReadLogFile(logFile, int64(j*10), 50)
+						_, err = logManager.ReadLogFile(context.Background(), logFile, int64(j*10), 50)
 					} else {
 						query := types.SearchQuery{
 							Path:   logFile,
@@ -206,7 +207,7 @@ func TestPerformanceIntegration(t *testing.T) {
 							Limit:  10,
 							Offset: 0,
 						}
-						_, err = logManager.SearchLogs(query)
+						_, err = logManager.SearchLogs(context.Background(), query)
 					}
 
 					if err != nil {
@@ -354,7 +355,7 @@ func BenchmarkPerformanceOptimizations(b *testing.B) {
 	// 基准测试读取操作
 	b.Run("OptimizedRead", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_, err := logManager.ReadLogFile(tmpFile.Name(), int64(i%10)*10, 50)
+			_, err := logManager.ReadLogFile(context.Background(), tmpFile.Name(), int64(i%10)*10, 50)
 			if err != nil {
 				b.Error(err)
 			}
@@ -371,7 +372,7 @@ func BenchmarkPerformanceOptimizations(b *testing.B) {
 		}
 
 		for i := 0; i < b.N; i++ {
-			_, err := logManager.SearchLogs(query)
+			_, err := logManager.SearchLogs(context.Background(), query)
 			if err != nil {
 				b.Error(err)
 			}
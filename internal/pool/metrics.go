@@ -0,0 +1,14 @@
+package pool
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	poolInUse = metrics.NewGauge("logviewer_pool_resources_in_use",
+		"Pooled resources (open files) currently borrowed.")
+	poolBorrows = metrics.NewCounter("logviewer_pool_borrows_total",
+		"Resources borrowed from pools.")
+	poolCreated = metrics.NewCounter("logviewer_pool_resources_created_total",
+		"Resources created by pools, including when a pool is empty.")
+	poolDestroyed = metrics.NewCounter("logviewer_pool_resources_destroyed_total",
+		"Resources closed because they expired, were invalid or the pool was full.")
+)
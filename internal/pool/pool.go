@@ -120,6 +120,7 @@ func NewResourcePool(factory Factory, config PoolConfig) (Pool, error) {
 		select {
 		case pool.resources <- pooledRes:
 			pool.created++
+			poolCreated.Inc()
 		default:
 			resource.Close()
 		}
@@ -146,11 +147,14 @@ func (p *ResourcePool) Get(ctx context.Context) (Resource, error) {
 		if p.isResourceValid(pooledRes) {
 			pooledRes.lastUsed = time.Now()
 			p.borrowed++
+			poolBorrows.Inc()
+			poolInUse.Inc()
 			return pooledRes.resource, nil
 		}
 		// 资源无效，销毁并创建新的
 		pooledRes.resource.Close()
 		p.destroyed++
+		poolDestroyed.Inc()
 
 		// 创建新资源
 		resource, err := p.factory()
@@ -158,7 +162,10 @@ func (p *ResourcePool) Get(ctx context.Context) (Resource, error) {
 			return nil, err
 		}
 		p.created++
+		poolCreated.Inc()
 		p.borrowed++
+		poolBorrows.Inc()
+		poolInUse.Inc()
 		return resource, nil
 
 	case <-ctx.Done():
@@ -171,7 +178,10 @@ func (p *ResourcePool) Get(ctx context.Context) (Resource, error) {
 			return nil, err
 		}
 		p.created++
+		poolCreated.Inc()
 		p.borrowed++
+		poolBorrows.Inc()
+		poolInUse.Inc()
 		return resource, nil
 	}
 }
@@ -181,6 +191,7 @@ func (p *ResourcePool) Put(resource Resource) error {
 	if resource == nil {
 		return nil
 	}
+	poolInUse.Dec()
 
 	p.mutex.RLock()
 	if p.closed {
@@ -204,6 +215,7 @@ func (p *ResourcePool) Put(resource Resource) error {
 		// 池已满，直接关闭资源
 		resource.Close()
 		p.destroyed++
+		poolDestroyed.Inc()
 		return nil
 	}
 }
@@ -225,6 +237,7 @@ func (p *ResourcePool) Close() error {
 	for pooledRes := range p.resources {
 		pooledRes.resource.Close()
 		p.destroyed++
+		poolDestroyed.Inc()
 	}
 
 	return nil
@@ -302,11 +315,13 @@ func (p *ResourcePool) cleanupExpiredResources() {
 					// 池已满，关闭资源
 					pooledRes.resource.Close()
 					p.destroyed++
+					poolDestroyed.Inc()
 				}
 			} else {
 				// 资源无效，关闭
 				pooledRes.resource.Close()
 				p.destroyed++
+				poolDestroyed.Inc()
 			}
 		default:
 			// 没有更多资源
@@ -0,0 +1,282 @@
+package savedsearch
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// storeFileName 保存的搜索在数据目录中的文件名
+	storeFileName = "saved-searches.json"
+	// idLength 短链接ID长度
+	idLength = 8
+	// idAlphabet 短链接ID字符集
+	idAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	// ErrNotFound 搜索不存在，或不属于当前用户且未共享
+	ErrNotFound = errors.New("saved search not found")
+	// ErrForbidden 只有所有者可以修改或删除
+	ErrForbidden = errors.New("only the owner can modify a saved search")
+	// ErrInvalid 搜索定义无效
+	ErrInvalid = errors.New("invalid saved search")
+)
+
+// Store 保存的搜索，持久化为数据目录下的JSON文件，dataDir 为空时只保存在内存中
+type Store struct {
+	path     string
+	searches map[string]*types.SavedSearch
+	now      func() time.Time
+	mutex    sync.RWMutex
+}
+
+// NewStore 创建存储并加载已保存的搜索
+func NewStore(dataDir string) (*Store, error) {
+	s := &Store{
+		searches: make(map[string]*types.SavedSearch),
+		now:      time.Now,
+	}
+	if dataDir == "" {
+		return s, nil
+	}
+
+	s.path = filepath.Join(dataDir, storeFileName)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+
+	var searches []*types.SavedSearch
+	if err := json.Unmarshal(data, &searches); err != nil {
+		return s, fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	for _, search := range searches {
+		s.searches[search.ID] = search
+	}
+
+	return s, nil
+}
+
+// List 返回用户自己的搜索和其他用户共享的搜索，按名称排序
+func (s *Store) List(user string) []types.SavedSearch {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]types.SavedSearch, 0)
+	for _, search := range s.searches {
+		if search.Owner == user || search.Shared {
+			result = append(result, *search)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Get 获取用户可以访问的搜索
+func (s *Store) Get(id, user string) (*types.SavedSearch, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	search, exists := s.searches[id]
+	if !exists || (search.Owner != user && !search.Shared) {
+		return nil, ErrNotFound
+	}
+	copied := *search
+	return &copied, nil
+}
+
+// Create 保存新的搜索，ID、所有者和时间由存储生成
+func (s *Store) Create(user string, search types.SavedSearch) (*types.SavedSearch, error) {
+	if err := Validate(search); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id, err := s.newIDLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	search.ID = id
+	search.Owner = user
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	s.searches[id] = &search
+
+	if err := s.saveLocked(); err != nil {
+		delete(s.searches, id)
+		return nil, err
+	}
+
+	copied := search
+	return &copied, nil
+}
+
+// Update 修改搜索，只有所有者可以修改
+func (s *Store) Update(id, user string, search types.SavedSearch) (*types.SavedSearch, error) {
+	if err := Validate(search); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, err := s.ownedLocked(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *existing
+	search.ID = existing.ID
+	search.Owner = existing.Owner
+	search.CreatedAt = existing.CreatedAt
+	search.UpdatedAt = s.now()
+	*existing = search
+
+	if err := s.saveLocked(); err != nil {
+		*existing = previous
+		return nil, err
+	}
+
+	copied := search
+	return &copied, nil
+}
+
+// Delete 删除搜索，只有所有者可以删除
+func (s *Store) Delete(id, user string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, err := s.ownedLocked(id, user)
+	if err != nil {
+		return err
+	}
+
+	delete(s.searches, id)
+	if err := s.saveLocked(); err != nil {
+		s.searches[id] = existing
+		return err
+	}
+	return nil
+}
+
+// Validate 检查搜索定义是否有效，返回的错误包装 ErrInvalid
+func Validate(search types.SavedSearch) error {
+	if err := validate(search); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+func validate(search types.SavedSearch) error {
+	if strings.TrimSpace(search.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(search.Paths) == 0 {
+		return fmt.Errorf("at least one path is required")
+	}
+	for _, path := range search.Paths {
+		if path == "" {
+			return fmt.Errorf("path must not be empty")
+		}
+	}
+	if search.IsRegex {
+		if _, err := regexp.Compile(search.Query); err != nil {
+			return fmt.Errorf("invalid regex pattern: %w", err)
+		}
+	}
+	if search.Range != "" {
+		if d, err := time.ParseDuration(search.Range); err != nil || d <= 0 {
+			return fmt.Errorf("invalid range %q, should be a positive duration such as 15m", search.Range)
+		}
+	}
+	if search.StartTime != nil && search.EndTime != nil && search.EndTime.Before(*search.StartTime) {
+		return fmt.Errorf("endTime must not be before startTime")
+	}
+	return nil
+}
+
+// ownedLocked 获取属于用户的搜索（调用方持有锁）
+func (s *Store) ownedLocked(id, user string) (*types.SavedSearch, error) {
+	existing, exists := s.searches[id]
+	if !exists || (existing.Owner != user && !existing.Shared) {
+		return nil, ErrNotFound
+	}
+	if existing.Owner != user {
+		return nil, ErrForbidden
+	}
+	return existing, nil
+}
+
+// newIDLocked 生成未使用的短ID（调用方持有锁）
+func (s *Store) newIDLocked() (string, error) {
+	max := big.NewInt(int64(len(idAlphabet)))
+	for {
+		var builder strings.Builder
+		for i := 0; i < idLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate id: %w", err)
+			}
+			builder.WriteByte(idAlphabet[n.Int64()])
+		}
+		if id := builder.String(); s.searches[id] == nil {
+			return id, nil
+		}
+	}
+}
+
+// saveLocked 写入磁盘（调用方持有锁）
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	searches := make([]*types.SavedSearch, 0, len(s.searches))
+	for _, search := range s.searches {
+		searches = append(searches, search)
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].ID < searches[j].ID })
+
+	data, err := json.MarshalIndent(searches, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal saved searches: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下不完整的文件
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write saved searches: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save saved searches: %w", err)
+	}
+	return nil
+}
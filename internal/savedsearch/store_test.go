@@ -0,0 +1,133 @@
+package savedsearch
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+func newSearch(name string) types.SavedSearch {
+	return types.SavedSearch{
+		Name:   name,
+		Paths:  []string{"/var/log/app.log"},
+		Query:  "timeout",
+		Levels: []string{"ERROR"},
+		Range:  "15m",
+	}
+}
+
+func TestStore_CRUD(t *testing.T) {
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	created, err := store.Create("alice", newSearch("timeouts"))
+	require.NoError(t, err)
+	assert.Len(t, created.ID, idLength)
+	assert.Equal(t, "alice", created.Owner)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	got, err := store.Get(created.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "timeouts", got.Name)
+
+	update := newSearch("slow requests")
+	update.Owner = "mallory" // 所有者不能通过修改请求变更
+	updated, err := store.Update(created.ID, "alice", update)
+	require.NoError(t, err)
+	assert.Equal(t, "slow requests", updated.Name)
+	assert.Equal(t, "alice", updated.Owner)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+	require.NoError(t, store.Delete(created.ID, "alice"))
+	_, err = store.Get(created.ID, "alice")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestStore_Sharing(t *testing.T) {
+	store, err := NewStore("")
+	require.NoError(t, err)
+
+	private, err := store.Create("alice", newSearch("private"))
+	require.NoError(t, err)
+
+	shared := newSearch("shared")
+	shared.Shared = true
+	public, err := store.Create("alice", shared)
+	require.NoError(t, err)
+
+	_, err = store.Create("bob", newSearch("bob's"))
+	require.NoError(t, err)
+
+	// 其他用户只能看到共享的搜索
+	list := store.List("bob")
+	require.Len(t, list, 2)
+	assert.Equal(t, "bob's", list[0].Name)
+	assert.Equal(t, "shared", list[1].Name)
+
+	_, err = store.Get(private.ID, "bob")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = store.Get(public.ID, "bob")
+	assert.NoError(t, err)
+
+	// 共享的搜索只有所有者可以修改和删除
+	_, err = store.Update(public.ID, "bob", newSearch("hijacked"))
+	assert.True(t, errors.Is(err, ErrForbidden))
+	assert.True(t, errors.Is(store.Delete(public.ID, "bob"), ErrForbidden))
+	assert.True(t, errors.Is(store.Delete(private.ID, "bob"), ErrNotFound))
+
+	assert.Len(t, store.List("alice"), 2)
+}
+
+func TestStore_Persistence(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	created, err := store.Create("alice", newSearch("persisted"))
+	require.NoError(t, err)
+
+	reloaded, err := NewStore(dir)
+	require.NoError(t, err)
+	got, err := reloaded.Get(created.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "persisted", got.Name)
+	assert.Equal(t, []string{"/var/log/app.log"}, got.Paths)
+}
+
+func TestValidate(t *testing.T) {
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	end := start.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		modify func(*types.SavedSearch)
+		valid  bool
+	}{
+		{"valid", func(s *types.SavedSearch) {}, true},
+		{"missing name", func(s *types.SavedSearch) { s.Name = " " }, false},
+		{"missing paths", func(s *types.SavedSearch) { s.Paths = nil }, false},
+		{"empty path", func(s *types.SavedSearch) { s.Paths = []string{""} }, false},
+		{"invalid regex", func(s *types.SavedSearch) { s.IsRegex = true; s.Query = "(" }, false},
+		{"invalid range", func(s *types.SavedSearch) { s.Range = "yesterday" }, false},
+		{"negative range", func(s *types.SavedSearch) { s.Range = "-5m" }, false},
+		{"end before start", func(s *types.SavedSearch) { s.Range = ""; s.StartTime = &start; s.EndTime = &end }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := newSearch("test")
+			tt.modify(&search)
+			err := Validate(search)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalid), "expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/local-log-viewer/internal/tracing"
+	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultCorrelateLimit 默认返回的最大条目数
+const defaultCorrelateLimit = 1000
+
+// CorrelationFields 关联查询使用的字段名，每类字段按顺序取第一个存在的值
+type CorrelationFields struct {
+	ID       []string // trace ID 或 request ID
+	SpanID   []string
+	ParentID []string
+}
+
+// Correlate 在多个文件中查找 ID 字段等于 query.ID 的日志条目，合并为按时间排序的时间线
+// 字段值来自解析器提取的 Fields；条目带有父 span 时同时返回 span 树
+func (se *SearchEngine) Correlate(ctx context.Context, paths []string, query types.CorrelateQuery, fields CorrelationFields) (result *types.CorrelateResult, err error) {
+	if query.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if len(fields.ID) == 0 {
+		return nil, fmt.Errorf("no id fields configured")
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultCorrelateLimit
+	}
+
+	ctx, span := tracing.Start(ctx, "search.correlate",
+		attribute.String("correlate.id", query.ID),
+		attribute.Int("correlate.files", len(paths)))
+	defer func() { tracing.End(span, err) }()
+
+	result = &types.CorrelateResult{ID: query.ID}
+	var entries []types.CorrelatedEntry
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// 先按原始文本过滤，只有包含该 ID 的行才检查字段
+		scanQuery := types.SearchQuery{
+			Path:      path,
+			Query:     query.ID,
+			StartTime: query.StartTime,
+			EndTime:   query.EndTime,
+		}
+		err := se.scan(ctx, scanQuery, nil, func(entry *types.LogEntry) {
+			matchedField, ok := firstMatchingField(entry, fields.ID, query.ID)
+			if !ok {
+				return
+			}
+			result.TotalCount++
+			entries = append(entries, types.CorrelatedEntry{
+				LogEntry:     *entry,
+				Path:         path,
+				MatchedField: matchedField,
+				SpanID:       firstFieldValue(entry, fields.SpanID),
+				ParentID:     firstFieldValue(entry, fields.ParentID),
+			})
+			// 只保留时间最早的条目，避免常见 ID 占用过多内存
+			if len(entries) >= 2*query.Limit {
+				sortCorrelatedEntries(entries)
+				entries = entries[:query.Limit]
+			}
+		})
+		if err != nil {
+			// 列出文件后被删除或轮转的文件直接跳过
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		result.FilesScanned++
+	}
+
+	sortCorrelatedEntries(entries)
+	if len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	result.Truncated = result.TotalCount > int64(len(entries))
+	if entries == nil {
+		entries = []types.CorrelatedEntry{}
+	}
+	result.Entries = entries
+	result.Spans = buildSpanTree(entries)
+
+	span.SetAttributes(
+		attribute.Int64("correlate.matched", result.TotalCount),
+		attribute.Int("correlate.spans", len(result.Spans)),
+	)
+	return result, nil
+}
+
+// firstMatchingField 返回第一个值等于 id 的字段名
+func firstMatchingField(entry *types.LogEntry, fields []string, id string) (string, bool) {
+	for _, field := range fields {
+		if value, ok := FieldValue(entry, field); ok && formatFacetValue(value) == id {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// firstFieldValue 返回第一个存在的字段值
+func firstFieldValue(entry *types.LogEntry, fields []string) string {
+	for _, field := range fields {
+		if value, ok := FieldValue(entry, field); ok {
+			if s := formatFacetValue(value); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// sortCorrelatedEntries 按时间排序，时间相同时按文件和行号，没有时间戳的条目排在最后
+func sortCorrelatedEntries(entries []types.CorrelatedEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Timestamp.IsZero() != b.Timestamp.IsZero() {
+			return b.Timestamp.IsZero()
+		}
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.LineNum < b.LineNum
+	})
+}
+
+// buildSpanTree 按 span ID 分组条目并按父 span 组成树，没有任何父 span 时返回 nil
+// 父 span 不在结果中的 span 作为根节点；存在环时环上的 span 也作为根节点
+func buildSpanTree(entries []types.CorrelatedEntry) []types.CorrelationSpan {
+	spans := make(map[string]*types.CorrelationSpan)
+	var order []string
+	hasParent := false
+
+	for i, entry := range entries {
+		if entry.SpanID == "" {
+			continue
+		}
+		node, exists := spans[entry.SpanID]
+		if !exists {
+			node = &types.CorrelationSpan{SpanID: entry.SpanID}
+			spans[entry.SpanID] = node
+			order = append(order, entry.SpanID)
+		}
+		if node.ParentID == "" && entry.ParentID != "" && entry.ParentID != entry.SpanID {
+			node.ParentID = entry.ParentID
+			hasParent = true
+		}
+		if !entry.Timestamp.IsZero() {
+			if node.Start.IsZero() || entry.Timestamp.Before(node.Start) {
+				node.Start = entry.Timestamp
+			}
+			if entry.Timestamp.After(node.End) {
+				node.End = entry.Timestamp
+			}
+		}
+		if !containsString(node.Paths, entry.Path) {
+			node.Paths = append(node.Paths, entry.Path)
+		}
+		node.Entries = append(node.Entries, i)
+	}
+	if !hasParent {
+		return nil
+	}
+
+	children := make(map[string][]string)
+	var roots []string
+	for _, id := range order {
+		parent := spans[id].ParentID
+		if _, exists := spans[parent]; parent != "" && exists {
+			children[parent] = append(children[parent], id)
+		} else {
+			roots = append(roots, id)
+		}
+	}
+
+	visited := make(map[string]bool)
+	var build func(id string) types.CorrelationSpan
+	build = func(id string) types.CorrelationSpan {
+		visited[id] = true
+		node := *spans[id]
+		for _, child := range children[id] {
+			if !visited[child] {
+				node.Children = append(node.Children, build(child))
+			}
+		}
+		return node
+	}
+
+	var tree []types.CorrelationSpan
+	for _, id := range roots {
+		tree = append(tree, build(id))
+	}
+	for _, id := range order {
+		if !visited[id] {
+			tree = append(tree, build(id))
+		}
+	}
+	return tree
+}
+
+// containsString 检查切片中是否包含字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
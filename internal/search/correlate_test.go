@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+var testCorrelationFields = CorrelationFields{
+	ID:       []string{"trace_id", "request_id"},
+	SpanID:   []string{"span_id"},
+	ParentID: []string{"parent_id"},
+}
+
+func TestCorrelate_MergedTimelineAndSpanTree(t *testing.T) {
+	gateway := createTestFile(t, strings.Join([]string{
+		`{"time":"2023-01-01T10:00:00Z","level":"info","msg":"request received","trace_id":"abc","span_id":"root"}`,
+		`{"time":"2023-01-01T10:00:01Z","level":"info","msg":"other request","trace_id":"abcd","span_id":"x"}`,
+		`{"time":"2023-01-01T10:00:05Z","level":"info","msg":"response sent","trace_id":"abc","span_id":"root"}`,
+	}, "\n")+"\n")
+	backend := createTestFile(t, strings.Join([]string{
+		`{"time":"2023-01-01T10:00:02Z","level":"info","msg":"query users","request_id":"abc","span_id":"db","parent_id":"api"}`,
+		`{"time":"2023-01-01T10:00:01Z","level":"error","msg":"handler","trace_id":"abc","span_id":"api","parent_id":"root"}`,
+		`{"time":"2023-01-01T10:00:03Z","level":"info","msg":"mentions abc in text only"}`,
+	}, "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	missing := filepath.Join(t.TempDir(), "rotated.log")
+	result, err := se.Correlate(context.Background(), []string{gateway, backend, missing}, types.CorrelateQuery{ID: "abc"}, testCorrelationFields)
+	if err != nil {
+		t.Fatalf("Correlate failed: %v", err)
+	}
+
+	if result.FilesScanned != 2 || result.TotalCount != 4 || result.Truncated {
+		t.Errorf("Unexpected counts: files=%d total=%d truncated=%v", result.FilesScanned, result.TotalCount, result.Truncated)
+	}
+
+	// 合并后按时间排序，只匹配字段值完全相同的条目
+	var messages []string
+	for _, entry := range result.Entries {
+		messages = append(messages, entry.Message)
+	}
+	expected := "request received,handler,query users,response sent"
+	if got := strings.Join(messages, ","); got != expected {
+		t.Fatalf("Expected timeline %s, got %s", expected, got)
+	}
+	if result.Entries[1].Path != backend || result.Entries[1].MatchedField != "trace_id" {
+		t.Errorf("Unexpected entry source: %+v", result.Entries[1])
+	}
+	if result.Entries[2].MatchedField != "request_id" || result.Entries[2].ParentID != "api" {
+		t.Errorf("Unexpected entry fields: %+v", result.Entries[2])
+	}
+
+	// root -> api -> db
+	if len(result.Spans) != 1 {
+		t.Fatalf("Expected 1 root span, got %+v", result.Spans)
+	}
+	root := result.Spans[0]
+	if root.SpanID != "root" || len(root.Entries) != 2 || root.End.Sub(root.Start).Seconds() != 5 {
+		t.Errorf("Unexpected root span: %+v", root)
+	}
+	if len(root.Children) != 1 || root.Children[0].SpanID != "api" {
+		t.Fatalf("Expected api child span, got %+v", root.Children)
+	}
+	if db := root.Children[0].Children; len(db) != 1 || db[0].SpanID != "db" || db[0].Paths[0] != backend {
+		t.Errorf("Expected db grandchild span, got %+v", db)
+	}
+}
+
+func TestCorrelate_LimitKeepsEarliest(t *testing.T) {
+	filePath := createTestFile(t, strings.Join([]string{
+		`{"time":"2023-01-01T10:00:03Z","msg":"c","trace_id":"t1"}`,
+		`{"time":"2023-01-01T10:00:01Z","msg":"a","trace_id":"t1"}`,
+		`{"msg":"no time","trace_id":"t1"}`,
+		`{"time":"2023-01-01T10:00:02Z","msg":"b","trace_id":"t1"}`,
+	}, "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	result, err := se.Correlate(context.Background(), []string{filePath}, types.CorrelateQuery{ID: "t1", Limit: 2}, testCorrelationFields)
+	if err != nil {
+		t.Fatalf("Correlate failed: %v", err)
+	}
+	if result.TotalCount != 4 || !result.Truncated || len(result.Entries) != 2 {
+		t.Fatalf("Expected 2 of 4 entries, got %d of %d", len(result.Entries), result.TotalCount)
+	}
+	if result.Entries[0].Message != "a" || result.Entries[1].Message != "b" {
+		t.Errorf("Expected earliest entries, got %s, %s", result.Entries[0].Message, result.Entries[1].Message)
+	}
+	// 没有父 span 时不返回 span 树
+	if result.Spans != nil {
+		t.Errorf("Expected no spans, got %+v", result.Spans)
+	}
+}
+
+func TestBuildSpanTree_Cycle(t *testing.T) {
+	spans := buildSpanTree([]types.CorrelatedEntry{
+		{SpanID: "a", ParentID: "b"},
+		{SpanID: "b", ParentID: "a"},
+		{SpanID: "c", ParentID: "missing"},
+	})
+	if len(spans) != 2 || spans[0].SpanID != "c" || spans[1].SpanID != "a" {
+		t.Fatalf("Unexpected roots: %+v", spans)
+	}
+	if len(spans[1].Children) != 1 || spans[1].Children[0].SpanID != "b" || len(spans[1].Children[0].Children) != 0 {
+		t.Errorf("Expected cycle to be broken, got %+v", spans[1])
+	}
+}
+
+func TestCorrelate_MissingID(t *testing.T) {
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	if _, err := se.Correlate(context.Background(), nil, types.CorrelateQuery{}, testCorrelationFields); err == nil {
+		t.Error("Expected error for missing id")
+	}
+}
@@ -0,0 +1,268 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// defaultFacetTopN 默认返回的值数量
+	defaultFacetTopN = 10
+	// maxFacetTopN 最多返回的值数量
+	maxFacetTopN = 100
+	// maxTrackedValues 精确计数的最大不同值数量，超过后新值只计入基数估算
+	maxTrackedValues = 10000
+	// maxNumericSamples 计算分位数时保留的最大样本数（蓄水池抽样）
+	maxNumericSamples = 100000
+)
+
+// Facets 统计字段的取值分布，过滤条件与搜索相同
+func (se *SearchEngine) Facets(ctx context.Context, query types.FacetQuery) (*types.FacetResult, error) {
+	if query.Field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	if query.TopN <= 0 {
+		query.TopN = defaultFacetTopN
+	}
+	if query.TopN > maxFacetTopN {
+		query.TopN = maxFacetTopN
+	}
+
+	// 文件变化后缓存自动失效
+	version, err := se.fileVersion(ctx, query.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", query.Path, err)
+	}
+
+	if result, found := se.searchCache.GetFacets(query, version); found {
+		return result, nil
+	}
+
+	regex, err := compileQuery(query.SearchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregator := newFacetAggregator(query.Field)
+	if err := se.scan(ctx, query.SearchQuery, regex, aggregator.add); err != nil {
+		return nil, err
+	}
+
+	result := aggregator.result(query.TopN)
+	se.searchCache.SetFacets(query, version, result)
+
+	return result, nil
+}
+
+// facetAggregator 单次扫描中累计字段统计
+type facetAggregator struct {
+	field        string
+	matched      int64
+	present      int64
+	counts       map[string]int64
+	approximate  bool
+	cardinality  *HyperLogLog
+	numericCount int64
+	min, max     float64
+	sum          float64
+	samples      []float64
+	rng          *rand.Rand
+}
+
+func newFacetAggregator(field string) *facetAggregator {
+	return &facetAggregator{
+		field:       field,
+		counts:      make(map[string]int64),
+		cardinality: NewHyperLogLog(),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+		// 固定种子，保证相同内容的统计结果一致
+		rng: rand.New(rand.NewSource(1)),
+	}
+}
+
+// add 累计一个日志条目
+func (a *facetAggregator) add(entry *types.LogEntry) {
+	a.matched++
+
+	value, ok := FieldValue(entry, a.field)
+	if !ok {
+		return
+	}
+	a.present++
+
+	key := formatFacetValue(value)
+	a.cardinality.Add(key)
+	if _, exists := a.counts[key]; exists || len(a.counts) < maxTrackedValues {
+		a.counts[key]++
+	} else {
+		a.approximate = true
+	}
+
+	if number, ok := NumericValue(value); ok {
+		a.addNumber(number)
+	}
+}
+
+// addNumber 累计数值统计，超过样本上限后使用蓄水池抽样
+func (a *facetAggregator) addNumber(number float64) {
+	a.numericCount++
+	a.sum += number
+	if number < a.min {
+		a.min = number
+	}
+	if number > a.max {
+		a.max = number
+	}
+
+	if len(a.samples) < maxNumericSamples {
+		a.samples = append(a.samples, number)
+	} else if i := a.rng.Int63n(a.numericCount); i < maxNumericSamples {
+		a.samples[i] = number
+	}
+}
+
+// result 生成聚合结果
+func (a *facetAggregator) result(topN int) *types.FacetResult {
+	values := make([]types.FacetValue, 0, len(a.counts))
+	for value, count := range a.counts {
+		values = append(values, types.FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > topN {
+		values = values[:topN]
+	}
+
+	result := &types.FacetResult{
+		Field:        a.field,
+		MatchedCount: a.matched,
+		FieldCount:   a.present,
+		Cardinality:  a.cardinality.Count(),
+		TopValues:    values,
+		Approximate:  a.approximate,
+	}
+
+	// 只有所有取值都是数值时才给出数值统计
+	if a.numericCount > 0 && a.numericCount == a.present {
+		sort.Float64s(a.samples)
+		result.Numeric = &types.NumericStats{
+			Count: a.numericCount,
+			Min:   a.min,
+			Max:   a.max,
+			Mean:  a.sum / float64(a.numericCount),
+			P50:   percentile(a.samples, 0.50),
+			P95:   percentile(a.samples, 0.95),
+			P99:   percentile(a.samples, 0.99),
+		}
+	}
+
+	return result
+}
+
+// FieldValue 获取日志条目中的字段值
+// level 和 message 使用解析后的标准值，其他字段从 Fields 中读取，支持用点号访问嵌套字段
+func FieldValue(entry *types.LogEntry, field string) (interface{}, bool) {
+	switch field {
+	case "level":
+		if entry.Level != "" {
+			return entry.Level, true
+		}
+	case "message":
+		if entry.Message != "" {
+			return entry.Message, true
+		}
+	}
+
+	if entry.Fields == nil {
+		return nil, false
+	}
+	if value, exists := entry.Fields[field]; exists && value != nil {
+		return value, true
+	}
+
+	// 嵌套字段，例如 http.status
+	var current interface{} = entry.Fields
+	for _, part := range strings.Split(field, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok || current == nil {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// formatFacetValue 将字段值转换为用于分组的字符串
+func formatFacetValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// NumericValue 将字段值转换为数值，数字字符串（如访问日志中的状态码）也视为数值
+func NumericValue(value interface{}) (float64, bool) {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case float32:
+		number = float64(v)
+	case int:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case int32:
+		number = float64(v)
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, false
+		}
+		number = parsed
+	default:
+		return 0, false
+	}
+
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// percentile 计算已排序样本的分位数（最近秩法）
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/cache"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/parser"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func newJSONSearchEngine() *SearchEngine {
+	se := NewSearchEngine(nil, cache.NewMemoryCache(100, time.Hour))
+	jsonParser := parser.NewJSONLogParser()
+	se.SetParserResolver(func(path string) interfaces.LogParser { return jsonParser })
+	return se
+}
+
+func TestFacets_TopValuesAndNumericStats(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 100; i++ {
+		status := 200
+		switch {
+		case i%10 == 0:
+			status = 500
+		case i%4 == 0:
+			status = 404
+		}
+		level := "info"
+		if status == 500 {
+			level = "error"
+		}
+		lines = append(lines, fmt.Sprintf(
+			`{"time":"2023-01-01T10:%02d:00Z","level":"%s","msg":"GET /api","status":%d,"latency":%d,"http":{"method":"GET"}}`,
+			i%60, level, status, i))
+	}
+	filePath := createTestFile(t, strings.Join(lines, "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	result, err := se.Facets(context.Background(), types.FacetQuery{
+		SearchQuery: types.SearchQuery{Path: filePath},
+		Field:       "status",
+		TopN:        2,
+	})
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+
+	if result.MatchedCount != 100 || result.FieldCount != 100 {
+		t.Errorf("Expected 100 matched entries, got %d/%d", result.MatchedCount, result.FieldCount)
+	}
+	if result.Cardinality != 3 {
+		t.Errorf("Expected cardinality 3, got %d", result.Cardinality)
+	}
+	if len(result.TopValues) != 2 {
+		t.Fatalf("Expected 2 top values, got %d", len(result.TopValues))
+	}
+	if result.TopValues[0] != (types.FacetValue{Value: "200", Count: 70}) {
+		t.Errorf("Unexpected first value: %+v", result.TopValues[0])
+	}
+	if result.TopValues[1] != (types.FacetValue{Value: "404", Count: 20}) {
+		t.Errorf("Unexpected second value: %+v", result.TopValues[1])
+	}
+
+	latency, err := se.Facets(context.Background(), types.FacetQuery{
+		SearchQuery: types.SearchQuery{Path: filePath},
+		Field:       "latency",
+	})
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	stats := latency.Numeric
+	if stats == nil {
+		t.Fatal("Expected numeric stats for latency")
+	}
+	if stats.Min != 1 || stats.Max != 100 || stats.Mean != 50.5 {
+		t.Errorf("Unexpected min/max/mean: %+v", stats)
+	}
+	if stats.P50 != 50 || stats.P95 != 95 || stats.P99 != 99 {
+		t.Errorf("Unexpected percentiles: %+v", stats)
+	}
+
+	// 过滤条件与搜索相同；嵌套字段用点号访问
+	filtered, err := se.Facets(context.Background(), types.FacetQuery{
+		SearchQuery: types.SearchQuery{Path: filePath, Levels: []string{"ERROR"}},
+		Field:       "http.method",
+	})
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	if filtered.MatchedCount != 10 || len(filtered.TopValues) != 1 || filtered.TopValues[0].Value != "GET" {
+		t.Errorf("Unexpected filtered facets: %+v", filtered)
+	}
+	if filtered.Numeric != nil {
+		t.Error("Expected no numeric stats for string field")
+	}
+}
+
+func TestFacets_CacheInvalidatedOnFileChange(t *testing.T) {
+	filePath := createTestFile(t, `{"level":"info","msg":"a"}`+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	query := types.FacetQuery{SearchQuery: types.SearchQuery{Path: filePath}, Field: "level"}
+	first, err := se.Facets(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	if first.MatchedCount != 1 {
+		t.Fatalf("Expected 1 entry, got %d", first.MatchedCount)
+	}
+
+	cached, err := se.Facets(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	if cached != first {
+		t.Error("Expected second call to be served from cache")
+	}
+
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	f.WriteString(`{"level":"warn","msg":"b"}` + "\n")
+	f.Close()
+
+	updated, err := se.Facets(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	if updated.MatchedCount != 2 {
+		t.Errorf("Expected cache to be invalidated after append, got %d entries", updated.MatchedCount)
+	}
+}
+
+func TestFacets_MissingField(t *testing.T) {
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	if _, err := se.Facets(context.Background(), types.FacetQuery{SearchQuery: types.SearchQuery{Path: "/tmp/x.log"}}); err == nil {
+		t.Error("Expected error for missing field")
+	}
+}
+
+func TestHyperLogLog_Count(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		hll := NewHyperLogLog()
+		for i := 0; i < n; i++ {
+			hll.Add(fmt.Sprintf("user-%d", i))
+			// 重复值不影响基数
+			hll.Add(fmt.Sprintf("user-%d", i))
+		}
+
+		estimate := float64(hll.Count())
+		if n == 0 {
+			if estimate != 0 {
+				t.Errorf("Expected 0 for empty set, got %v", estimate)
+			}
+			continue
+		}
+		if errRate := math.Abs(estimate-float64(n)) / float64(n); errRate > 0.03 {
+			t.Errorf("Cardinality %d estimated as %v (error %.2f%%)", n, estimate, errRate*100)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+
+	"github.com/local-log-viewer/internal/tracing"
+	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fileMatches 一个文件的搜索结果
+type fileMatches struct {
+	entries []types.LogEntry
+	count   int64
+	err     error
+}
+
+// SearchFiles 搜索多个文件（例如对象存储前缀下的所有对象），最多 concurrency 个文件同时流式读取。
+// 结果按 paths 的顺序和文件中的行号排列，条目的 Path 为所在的文件；列出后被删除的文件直接跳过
+func (se *SearchEngine) SearchFiles(ctx context.Context, paths []string, query types.SearchQuery, concurrency int) (result *types.SearchResult, err error) {
+	ctx, span := tracing.Start(ctx, "search.SearchFiles",
+		attribute.String("file.path", query.Path),
+		attribute.Int("search.files", len(paths)))
+	defer func() { tracing.End(span, err) }()
+
+	regex, err := compileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// 结果按文件顺序分页，每个文件最多保留 offset+limit 个条目
+	keep := query.Offset + query.Limit
+	matches := make([]fileMatches, len(paths))
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fileQuery := query
+				fileQuery.Path = paths[i]
+				m := &matches[i]
+				m.err = se.scan(scanCtx, fileQuery, regex, func(entry *types.LogEntry) {
+					m.count++
+					if len(m.entries) < keep {
+						entry.Path = paths[i]
+						m.entries = append(m.entries, *entry)
+					}
+				})
+				// 一个文件失败时停止其他文件的读取
+				if m.err != nil && !errors.Is(m.err, fs.ErrNotExist) {
+					cancel()
+				}
+			}
+		}()
+	}
+feed:
+	for i := range paths {
+		select {
+		case next <- i:
+		case <-scanCtx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// 被取消的文件只会在其他文件失败时出现，返回失败的原因
+	for _, m := range matches {
+		if m.err != nil && !errors.Is(m.err, fs.ErrNotExist) && !errors.Is(m.err, context.Canceled) {
+			return nil, m.err
+		}
+	}
+
+	result = &types.SearchResult{Entries: []types.LogEntry{}, Offset: query.Offset}
+	var position int
+	for _, m := range matches {
+		if m.err != nil {
+			continue
+		}
+		result.TotalCount += m.count
+		for i := range m.entries {
+			if position >= query.Offset && len(result.Entries) < query.Limit {
+				result.Entries = append(result.Entries, *se.highlightEntry(&m.entries[i], query, regex))
+			}
+			position++
+		}
+	}
+	result.HasMore = result.TotalCount > int64(keep)
+
+	span.SetAttributes(attribute.Int64("search.matched", result.TotalCount))
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestSearchFiles_OrderAndPaging(t *testing.T) {
+	var paths []string
+	for i := 0; i < 5; i++ {
+		var lines []string
+		for j := 0; j < 3; j++ {
+			lines = append(lines, fmt.Sprintf(`{"level":"info","msg":"file %d match %d"}`, i, j), `{"level":"info","msg":"noise"}`)
+		}
+		paths = append(paths, createTestFile(t, strings.Join(lines, "\n")+"\n"))
+	}
+	// 列出后被删除的文件跳过
+	paths = append(paths[:2], append([]string{filepath.Join(t.TempDir(), "rotated.log")}, paths[2:]...)...)
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	result, err := se.SearchFiles(context.Background(), paths, types.SearchQuery{Query: "match", Offset: 4, Limit: 4}, 3)
+	if err != nil {
+		t.Fatalf("SearchFiles failed: %v", err)
+	}
+	if result.TotalCount != 15 || len(result.Entries) != 4 || !result.HasMore {
+		t.Fatalf("Unexpected result: total=%d entries=%d hasMore=%v", result.TotalCount, len(result.Entries), result.HasMore)
+	}
+	// 第 5 条结果是第二个文件的第二条匹配
+	first := result.Entries[0]
+	if first.Path != paths[1] || first.LineNum != 3 || !strings.Contains(first.Message, "<mark>match</mark> 1") {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if last := result.Entries[3]; last.Path != paths[3] || !strings.Contains(last.Message, "file 2 <mark>match</mark> 1") {
+		t.Errorf("Unexpected last entry: %+v", last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := se.SearchFiles(ctx, paths, types.SearchQuery{Query: "match", Limit: 4}, 3); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// 字段条件支持的比较方式
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpContains = "contains"
+	OpRegex    = "regex"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpExists   = "exists"
+)
+
+// NewStreamMatcher 根据订阅过滤条件创建匹配函数，级别和关键词的语义与搜索相同
+func NewStreamMatcher(filter types.StreamFilter) (func(entry *types.LogEntry) bool, error) {
+	matchQuery, err := NewMatcher(types.SearchQuery{
+		Query:   filter.Query,
+		IsRegex: filter.IsRegex,
+		Levels:  filter.Levels,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	predicates := make([]func(entry *types.LogEntry) bool, 0, len(filter.Fields))
+	for _, predicate := range filter.Fields {
+		match, err := newFieldPredicate(predicate)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, match)
+	}
+
+	return func(entry *types.LogEntry) bool {
+		if !matchQuery(entry) {
+			return false
+		}
+		for _, match := range predicates {
+			if !match(entry) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// newFieldPredicate 创建单个字段条件的匹配函数
+func newFieldPredicate(predicate types.FieldPredicate) (func(entry *types.LogEntry) bool, error) {
+	if predicate.Field == "" {
+		return nil, fmt.Errorf("field predicate requires a field name")
+	}
+
+	op := strings.ToLower(predicate.Op)
+	if op == "" {
+		op = OpEq
+	}
+
+	switch op {
+	case OpExists:
+		return func(entry *types.LogEntry) bool {
+			_, ok := FieldValue(entry, predicate.Field)
+			return ok
+		}, nil
+
+	case OpEq, OpNe:
+		return func(entry *types.LogEntry) bool {
+			value, ok := FieldValue(entry, predicate.Field)
+			equal := ok && strings.EqualFold(formatFacetValue(value), predicate.Value)
+			return equal == (op == OpEq)
+		}, nil
+
+	case OpContains:
+		needle := strings.ToLower(predicate.Value)
+		return func(entry *types.LogEntry) bool {
+			value, ok := FieldValue(entry, predicate.Field)
+			return ok && strings.Contains(strings.ToLower(formatFacetValue(value)), needle)
+		}, nil
+
+	case OpRegex:
+		regex, err := regexp.Compile(predicate.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for field %s: %w", predicate.Field, err)
+		}
+		return func(entry *types.LogEntry) bool {
+			value, ok := FieldValue(entry, predicate.Field)
+			return ok && regex.MatchString(formatFacetValue(value))
+		}, nil
+
+	case OpGt, OpGte, OpLt, OpLte:
+		target, ok := NumericValue(predicate.Value)
+		if !ok {
+			return nil, fmt.Errorf("field %s: %s requires a numeric value, got %q", predicate.Field, op, predicate.Value)
+		}
+		return func(entry *types.LogEntry) bool {
+			value, ok := FieldValue(entry, predicate.Field)
+			if !ok {
+				return false
+			}
+			number, ok := NumericValue(value)
+			if !ok {
+				return false
+			}
+			switch op {
+			case OpGt:
+				return number > target
+			case OpGte:
+				return number >= target
+			case OpLt:
+				return number < target
+			default:
+				return number <= target
+			}
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported field operator %q", predicate.Op)
+}
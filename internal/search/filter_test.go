@@ -0,0 +1,72 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestNewStreamMatcher(t *testing.T) {
+	entries := []types.LogEntry{
+		{Level: "ERROR", Message: "upstream timeout", Raw: "upstream timeout", Fields: map[string]interface{}{
+			"status": float64(504), "service": "api", "http": map[string]interface{}{"method": "GET"},
+		}},
+		{Level: "INFO", Message: "request done", Raw: "request done", Fields: map[string]interface{}{
+			"status": "200", "service": "api",
+		}},
+		{Level: "WARN", Message: "slow query", Raw: "slow query", Fields: map[string]interface{}{
+			"status": float64(500), "service": "db",
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		filter   types.StreamFilter
+		expected []bool
+	}{
+		{"empty filter matches all", types.StreamFilter{}, []bool{true, true, true}},
+		{"levels", types.StreamFilter{Levels: []string{"error", "warn"}}, []bool{true, false, true}},
+		{"substring", types.StreamFilter{Query: "TIMEOUT"}, []bool{true, false, false}},
+		{"regex", types.StreamFilter{Query: "^(slow|request)", IsRegex: true}, []bool{false, true, true}},
+		{"numeric gte", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "status", Op: "gte", Value: "500"}}}, []bool{true, false, true}},
+		{"numeric string field", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "status", Op: "lt", Value: "300"}}}, []bool{false, true, false}},
+		{"eq default op", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "service", Value: "API"}}}, []bool{true, true, false}},
+		{"ne", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "service", Op: "ne", Value: "api"}}}, []bool{false, false, true}},
+		{"nested exists", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "http.method", Op: "exists"}}}, []bool{true, false, false}},
+		{"field regex", types.StreamFilter{Fields: []types.FieldPredicate{{Field: "message", Op: "regex", Value: "^slow"}}}, []bool{false, false, true}},
+		{"combined", types.StreamFilter{
+			Levels: []string{"ERROR", "WARN"},
+			Fields: []types.FieldPredicate{{Field: "service", Op: "contains", Value: "ap"}},
+		}, []bool{true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := NewStreamMatcher(tt.filter)
+			if err != nil {
+				t.Fatalf("创建匹配函数失败: %v", err)
+			}
+			for i := range entries {
+				if got := match(&entries[i]); got != tt.expected[i] {
+					t.Errorf("条目 %d: 期望 %v, 得到 %v", i, tt.expected[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStreamMatcher_Invalid(t *testing.T) {
+	filters := []types.StreamFilter{
+		{Query: "(", IsRegex: true},
+		{Fields: []types.FieldPredicate{{Op: "eq", Value: "x"}}},
+		{Fields: []types.FieldPredicate{{Field: "status", Op: "gt", Value: "abc"}}},
+		{Fields: []types.FieldPredicate{{Field: "status", Op: "regex", Value: "["}}},
+		{Fields: []types.FieldPredicate{{Field: "status", Op: "between", Value: "1"}}},
+	}
+
+	for i, filter := range filters {
+		if _, err := NewStreamMatcher(filter); err == nil {
+			t.Errorf("过滤条件 %d 应该无效", i)
+		}
+	}
+}
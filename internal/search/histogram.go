@@ -0,0 +1,223 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// defaultHistogramBucket 默认桶宽度
+	defaultHistogramBucket = time.Minute
+	// maxHistogramBuckets 单次查询最多返回的桶数量
+	maxHistogramBuckets = 10000
+)
+
+// SetSummaryStore 设置文件摘要存储，设置后不带过滤条件的直方图查询使用摘要计算
+func (se *SearchEngine) SetSummaryStore(store *SummaryStore) {
+	se.summaries = store
+}
+
+// Histogram 按时间分桶统计日志条目数量，可按字段分组
+func (se *SearchEngine) Histogram(ctx context.Context, query types.HistogramQuery) (*types.HistogramResult, error) {
+	if query.Bucket <= 0 {
+		query.Bucket = defaultHistogramBucket
+	}
+	if query.Bucket < time.Second {
+		return nil, fmt.Errorf("bucket must be at least 1s")
+	}
+
+	var counts map[int64]map[string]int64
+	var untimed int64
+	var err error
+
+	fromSummary := se.canUseSummary(query)
+	if fromSummary {
+		counts, untimed, err = se.histogramFromSummary(query)
+	} else {
+		counts, untimed, err = se.histogramFromScan(ctx, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, total, err := buildHistogramBuckets(counts, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.HistogramResult{
+		Bucket:      query.Bucket.String(),
+		GroupBy:     query.GroupBy,
+		Buckets:     buckets,
+		Total:       total,
+		Untimed:     untimed,
+		FromSummary: fromSummary,
+	}, nil
+}
+
+// canUseSummary 摘要只记录每分钟各级别的数量，带搜索条件、按其他字段分组、分辨率更细或远程文件时需要扫描文件
+func (se *SearchEngine) canUseSummary(query types.HistogramQuery) bool {
+	if se.summaries == nil || se.isRemote(query.Path) || query.Query != "" || len(query.Levels) > 0 {
+		return false
+	}
+	if query.GroupBy != "" && query.GroupBy != "level" {
+		return false
+	}
+	if query.Bucket%summaryResolution != 0 {
+		return false
+	}
+	return isAligned(query.StartTime) && isAligned(query.EndTime)
+}
+
+// histogramFromSummary 使用增量更新的文件摘要计算直方图，时间范围按 [startTime, endTime) 计算
+func (se *SearchEngine) histogramFromSummary(query types.HistogramQuery) (map[int64]map[string]int64, int64, error) {
+	parser := se.getParserForFile(query.Path)
+	scanStart := time.Now()
+
+	summary, err := se.summaries.Update(query.Path, parser, func(line string) (time.Time, string, bool) {
+		entry, err := se.parseLogEntry(line, 0, parser)
+		if err != nil || !hasTimestamp(entry, scanStart) {
+			return time.Time{}, "", false
+		}
+		return entry.Timestamp, entry.Level, true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[int64]map[string]int64)
+	for minute, levels := range summary.Buckets {
+		t := time.Unix(minute, 0)
+		if !query.StartTime.IsZero() && t.Before(query.StartTime) {
+			continue
+		}
+		if !query.EndTime.IsZero() && !t.Before(query.EndTime) {
+			continue
+		}
+
+		key := t.Truncate(query.Bucket).Unix()
+		for level, count := range levels {
+			group := ""
+			if query.GroupBy != "" {
+				group = level
+			}
+			addHistogramCount(counts, key, group, count)
+		}
+	}
+
+	return counts, summary.Untimed, nil
+}
+
+// histogramFromScan 扫描文件计算直方图，支持与搜索相同的过滤条件
+func (se *SearchEngine) histogramFromScan(ctx context.Context, query types.HistogramQuery) (map[int64]map[string]int64, int64, error) {
+	regex, err := compileQuery(query.SearchQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts := make(map[int64]map[string]int64)
+	var untimed int64
+	scanStart := time.Now()
+
+	err = se.scan(ctx, query.SearchQuery, regex, func(entry *types.LogEntry) {
+		if !hasTimestamp(entry, scanStart) {
+			untimed++
+			return
+		}
+
+		group := ""
+		if query.GroupBy != "" {
+			group = unknownGroup
+			if value, ok := FieldValue(entry, query.GroupBy); ok {
+				group = formatFacetValue(value)
+			}
+		}
+		addHistogramCount(counts, entry.Timestamp.Truncate(query.Bucket).Unix(), group, 1)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return counts, untimed, nil
+}
+
+// buildHistogramBuckets 生成连续的时间桶（没有数据的桶计数为0），便于直接绘制
+func buildHistogramBuckets(counts map[int64]map[string]int64, query types.HistogramQuery) ([]types.HistogramBucket, int64, error) {
+	step := int64(query.Bucket / time.Second)
+
+	var first, last int64
+	hasRange := false
+	for key := range counts {
+		if !hasRange || key < first {
+			first = key
+		}
+		if !hasRange || key > last {
+			last = key
+		}
+		hasRange = true
+	}
+	if !query.StartTime.IsZero() {
+		first = query.StartTime.Truncate(query.Bucket).Unix()
+		if !hasRange {
+			last = first
+		}
+		hasRange = true
+	}
+	if !query.EndTime.IsZero() {
+		last = query.EndTime.Truncate(query.Bucket).Unix()
+		if !hasRange {
+			first = last
+		}
+		hasRange = true
+	}
+
+	if !hasRange || last < first {
+		return []types.HistogramBucket{}, 0, nil
+	}
+	if (last-first)/step+1 > maxHistogramBuckets {
+		return nil, 0, fmt.Errorf("too many buckets (max %d), use a larger bucket or a shorter time range", maxHistogramBuckets)
+	}
+
+	buckets := make([]types.HistogramBucket, 0, (last-first)/step+1)
+	var total int64
+	for key := first; key <= last; key += step {
+		bucket := types.HistogramBucket{Time: time.Unix(key, 0).UTC()}
+		for group, count := range counts[key] {
+			bucket.Count += count
+			if query.GroupBy != "" {
+				if bucket.Groups == nil {
+					bucket.Groups = make(map[string]int64)
+				}
+				bucket.Groups[group] = count
+			}
+		}
+		total += bucket.Count
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, total, nil
+}
+
+// addHistogramCount 累加桶内分组的计数
+func addHistogramCount(counts map[int64]map[string]int64, key int64, group string, n int64) {
+	groups := counts[key]
+	if groups == nil {
+		groups = make(map[string]int64)
+		counts[key] = groups
+	}
+	groups[group] += n
+}
+
+// hasTimestamp 判断条目是否带有时间戳
+// 解析器在日志行中没有时间戳时使用当前时间，因此不早于扫描开始时间的时间戳视为缺失
+func hasTimestamp(entry *types.LogEntry, scanStart time.Time) bool {
+	return !entry.Timestamp.IsZero() && entry.Timestamp.Before(scanStart)
+}
+
+// isAligned 检查时间是否对齐到摘要分辨率（零值视为对齐）
+func isAligned(t time.Time) bool {
+	return t.IsZero() || t.Equal(t.Truncate(summaryResolution))
+}
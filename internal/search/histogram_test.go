@@ -0,0 +1,288 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/parser"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func histogramLines(minute int, level string, n int) []string {
+	var lines []string
+	for i := 0; i < n; i++ {
+		lines = append(lines, fmt.Sprintf(`{"time":"2023-01-01T10:%02d:%02dZ","level":"%s","msg":"request %d","status":%d}`,
+			minute, i, level, i, 200+i%2*300))
+	}
+	return lines
+}
+
+func appendLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		t.Fatalf("Failed to append: %v", err)
+	}
+}
+
+func TestHistogram_SummaryByLevel(t *testing.T) {
+	lines := append(histogramLines(0, "info", 3), histogramLines(0, "error", 1)...)
+	lines = append(lines, histogramLines(2, "info", 2)...)
+	lines = append(lines, "plain line without timestamp")
+	filePath := createTestFile(t, strings.Join(lines, "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+	se.SetSummaryStore(NewSummaryStore(t.TempDir()))
+
+	result, err := se.Histogram(context.Background(), types.HistogramQuery{
+		SearchQuery: types.SearchQuery{Path: filePath},
+		Bucket:      time.Minute,
+		GroupBy:     "level",
+	})
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+
+	if !result.FromSummary {
+		t.Error("Expected histogram to be served from summary")
+	}
+	if result.Total != 6 || result.Untimed != 1 {
+		t.Errorf("Expected 6 timed and 1 untimed entries, got %d/%d", result.Total, result.Untimed)
+	}
+	// 中间没有数据的分钟也返回空桶
+	if len(result.Buckets) != 3 {
+		t.Fatalf("Expected 3 dense buckets, got %d", len(result.Buckets))
+	}
+	first := result.Buckets[0]
+	if !first.Time.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected first bucket time: %v", first.Time)
+	}
+	if first.Count != 4 || first.Groups["INFO"] != 3 || first.Groups["ERROR"] != 1 {
+		t.Errorf("Unexpected first bucket: %+v", first)
+	}
+	if result.Buckets[1].Count != 0 || result.Buckets[2].Count != 2 {
+		t.Errorf("Unexpected bucket counts: %+v", result.Buckets)
+	}
+
+	// 增量追加后只处理新内容
+	appendLines(t, filePath, histogramLines(2, "warn", 1))
+	updated, err := se.Histogram(context.Background(), types.HistogramQuery{
+		SearchQuery: types.SearchQuery{Path: filePath},
+		Bucket:      5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+	if len(updated.Buckets) != 1 || updated.Buckets[0].Count != 7 || updated.Buckets[0].Groups != nil {
+		t.Errorf("Unexpected buckets after append: %+v", updated.Buckets)
+	}
+}
+
+func TestHistogram_ScanWithFilter(t *testing.T) {
+	lines := append(histogramLines(0, "info", 4), histogramLines(1, "error", 2)...)
+	filePath := createTestFile(t, strings.Join(lines, "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+	se.SetSummaryStore(NewSummaryStore(""))
+
+	result, err := se.Histogram(context.Background(), types.HistogramQuery{
+		SearchQuery: types.SearchQuery{Path: filePath, Levels: []string{"INFO"}},
+		Bucket:      30 * time.Second,
+		GroupBy:     "status",
+	})
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+
+	if result.FromSummary {
+		t.Error("Filtered histogram should be computed by scanning")
+	}
+	if result.Total != 4 || len(result.Buckets) != 1 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if groups := result.Buckets[0].Groups; groups["200"] != 2 || groups["500"] != 2 {
+		t.Errorf("Unexpected groups: %+v", groups)
+	}
+
+	// 时间范围决定首尾桶
+	ranged, err := se.Histogram(context.Background(), types.HistogramQuery{
+		SearchQuery: types.SearchQuery{
+			Path:      filePath,
+			StartTime: time.Date(2023, 1, 1, 10, 1, 0, 0, time.UTC),
+			EndTime:   time.Date(2023, 1, 1, 10, 3, 0, 0, time.UTC),
+		},
+		Bucket: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Histogram failed: %v", err)
+	}
+	if len(ranged.Buckets) != 3 || ranged.Total != 2 || ranged.Buckets[0].Count != 2 {
+		t.Errorf("Unexpected ranged buckets: %+v", ranged.Buckets)
+	}
+}
+
+func TestHistogram_TooManyBuckets(t *testing.T) {
+	filePath := createTestFile(t, strings.Join(histogramLines(0, "info", 1), "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	_, err := se.Histogram(context.Background(), types.HistogramQuery{
+		SearchQuery: types.SearchQuery{
+			Path:      filePath,
+			StartTime: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Bucket: time.Second,
+	})
+	if err == nil {
+		t.Error("Expected error for too many buckets")
+	}
+}
+
+func TestSummaryStore_PersistAndRebuild(t *testing.T) {
+	dir := t.TempDir()
+	filePath := createTestFile(t, strings.Join(histogramLines(0, "info", 3), "\n")+"\n")
+
+	se := newJSONSearchEngine()
+	defer se.Close()
+	parser := se.getParserForFile(filePath)
+	parse := func(line string) (time.Time, string, bool) {
+		entry, err := se.parseLogEntry(line, 0, parser)
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		return entry.Timestamp, entry.Level, true
+	}
+
+	first, err := NewSummaryStore(dir).Update(filePath, parser, parse)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// 新的存储实例从磁盘加载摘要，不再读取已处理的内容
+	calls := 0
+	counting := func(line string) (time.Time, string, bool) {
+		calls++
+		return parse(line)
+	}
+	reloaded, err := NewSummaryStore(dir).Update(filePath, parser, counting)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if calls != 0 || reloaded.Offset != first.Offset {
+		t.Errorf("Expected persisted summary to be reused, parsed %d lines", calls)
+	}
+
+	// 文件被替换后重新生成
+	if err := os.WriteFile(filePath, []byte(strings.Join(histogramLines(5, "error", 2), "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite file: %v", err)
+	}
+	rebuilt, err := NewSummaryStore(dir).Update(filePath, parser, counting)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	minute := time.Date(2023, 1, 1, 10, 5, 0, 0, time.UTC).Unix()
+	if len(rebuilt.Buckets) != 1 || rebuilt.Buckets[minute]["ERROR"] != 2 {
+		t.Errorf("Expected summary to be rebuilt, got %+v", rebuilt.Buckets)
+	}
+}
+
+func TestSummaryStore_ParserFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	filePath := createTestFile(t, "2023-01-01T10:00:00Z info one\n2023-01-01T10:00:01Z error two\n")
+
+	newParser := func(pattern string) *parser.PatternLogParser {
+		p, err := parser.NewPatternLogParser(config.ParserConfig{Name: "custom", Pattern: pattern, LevelField: "level"})
+		if err != nil {
+			t.Fatalf("Failed to create parser: %v", err)
+		}
+		return p
+	}
+	levelFirst := newParser(`^\S+ (?P<level>\w+) (?P<msg>.*)$`)
+	messageOnly := newParser(`^\S+ \w+ (?P<msg>.*)$`)
+	if levelFirst.Fingerprint() == messageOnly.Fingerprint() {
+		t.Fatal("Expected different patterns to have different fingerprints")
+	}
+
+	calls := 0
+	parse := func(line string) (time.Time, string, bool) {
+		calls++
+		return time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), "", true
+	}
+	if _, err := NewSummaryStore(dir).Update(filePath, levelFirst, parse); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// 同名解析器的模式变化后不能使用旧摘要
+	calls = 0
+	if _, err := NewSummaryStore(dir).Update(filePath, messageOnly, parse); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected summary to be rebuilt for the changed pattern, parsed %d lines", calls)
+	}
+
+	// 规则不变时复用持久化的摘要
+	calls = 0
+	if _, err := NewSummaryStore(dir).Update(filePath, newParser(`^\S+ (?P<level>\w+) (?P<msg>.*)$`), parse); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected persisted summary to be reused, parsed %d lines", calls)
+	}
+}
+
+func TestSummaryStore_LocksPerFile(t *testing.T) {
+	store := NewSummaryStore("")
+	slowPath := createTestFile(t, strings.Join(histogramLines(0, "info", 3), "\n")+"\n")
+	fastPath := createTestFile(t, strings.Join(histogramLines(1, "info", 3), "\n")+"\n")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	slowDone := make(chan error)
+	go func() {
+		_, err := store.Update(slowPath, nil, func(line string) (time.Time, string, bool) {
+			once.Do(func() { close(started) })
+			<-release
+			return time.Time{}, "", false
+		})
+		slowDone <- err
+	}()
+	<-started
+
+	// 扫描一个文件时，其他文件的更新不需要等待
+	fastDone := make(chan error)
+	go func() {
+		_, err := store.Update(fastPath, nil, func(line string) (time.Time, string, bool) {
+			return time.Time{}, "", false
+		})
+		fastDone <- err
+	}()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Errorf("Update failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Update of another file was blocked by a running scan")
+	}
+
+	close(release)
+	if err := <-slowDone; err != nil {
+		t.Errorf("Update failed: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision HyperLogLog精度，2^14个寄存器，标准误差约0.8%
+const hllPrecision = 14
+
+// HyperLogLog 基数估算器，占用固定内存（16KB）
+type HyperLogLog struct {
+	registers []uint8
+}
+
+// NewHyperLogLog 创建基数估算器
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{
+		registers: make([]uint8, 1<<hllPrecision),
+	}
+}
+
+// Add 添加一个值
+func (h *HyperLogLog) Add(value string) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	x := mix64(hasher.Sum64())
+
+	index := x >> (64 - hllPrecision)
+	// 剩余位中第一个1出现的位置，末尾补1保证结果有界
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Count 返回不同值数量的估算
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha * m * m / sum
+
+	// 小基数时使用线性计数修正
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// mix64 对FNV哈希做二次混合，使高位分布更均匀
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
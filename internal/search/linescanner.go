@@ -0,0 +1,33 @@
+package search
+
+import (
+	"bufio"
+	"io"
+)
+
+// LineScanner 按行扫描，同时记录当前行起始的字节位置
+type LineScanner struct {
+	*bufio.Scanner
+	offset int64
+	next   int64
+}
+
+// NewLineScanner 创建按行扫描器，maxLineSize 为允许的最大行长度
+func NewLineScanner(r io.Reader, bufferSize, maxLineSize int) *LineScanner {
+	ls := &LineScanner{Scanner: bufio.NewScanner(r)}
+	ls.Buffer(make([]byte, 0, bufferSize), maxLineSize)
+	ls.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			ls.offset = ls.next
+		}
+		ls.next += int64(advance)
+		return advance, token, err
+	})
+	return ls
+}
+
+// Offset 返回最近一次 Scan 读到的行起始的字节位置
+func (ls *LineScanner) Offset() int64 {
+	return ls.offset
+}
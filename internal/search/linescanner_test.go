@@ -0,0 +1,27 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineScanner_Offset(t *testing.T) {
+	input := "first\r\n\nthird line\nlast"
+	scanner := NewLineScanner(strings.NewReader(input), 4, 1024)
+
+	var lines []string
+	var offsets []int64
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		offsets = append(offsets, scanner.Offset())
+	}
+
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"first", "", "third line", "last"}, lines)
+	assert.Equal(t, []int64{0, 7, 8, 19}, offsets)
+	for i, offset := range offsets {
+		assert.True(t, strings.HasPrefix(input[offset:], lines[i]))
+	}
+}
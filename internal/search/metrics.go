@@ -0,0 +1,12 @@
+package search
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	searchDuration = metrics.NewHistogramVec("logviewer_search_duration_seconds",
+		"Search request duration, by whether the result came from the search cache.", nil, "cache")
+	scanDuration = metrics.NewHistogram("logviewer_search_scan_duration_seconds",
+		"Duration of full file scans for searches, facets and histograms.", nil)
+	bytesScanned = metrics.NewCounter("logviewer_search_bytes_scanned_total",
+		"Bytes read from log files by searches, facets and histograms.")
+)
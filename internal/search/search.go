@@ -2,7 +2,9 @@ package search
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -11,7 +13,9 @@ import (
 	"github.com/local-log-viewer/internal/cache"
 	"github.com/local-log-viewer/internal/interfaces"
 	"github.com/local-log-viewer/internal/pool"
+	"github.com/local-log-viewer/internal/tracing"
 	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // SearchEngine 搜索引擎实现
@@ -20,6 +24,22 @@ type SearchEngine struct {
 	cache       interfaces.LogCache
 	searchCache *cache.SearchCache
 	filePool    *pool.FilePool
+
+	// parserResolver 按文件获取解析器（由日志管理器提供按文件检测的格式）
+	parserResolver func(path string) interfaces.LogParser
+	// summaries 文件摘要，用于快速计算直方图
+	summaries *SummaryStore
+	// remoteFiles 不在本地文件系统上的文件（例如 SSH 日志源）
+	remoteFiles RemoteFiles
+}
+
+// RemoteFiles 提供不在本地文件系统上的文件，Handles 返回 false 的路径仍从本地读取
+type RemoteFiles interface {
+	Handles(path string) bool
+	// Open 读取整个文件
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
+	// Version 文件的版本标识，文件变化后改变
+	Version(ctx context.Context, path string) (string, error)
 }
 
 // NewSearchEngine 创建新的搜索引擎
@@ -45,52 +65,156 @@ func NewSearchEngine(parsers map[string]interfaces.LogParser, logCache interface
 	}
 }
 
+// SetParserResolver 设置按文件获取解析器的方法，未设置时根据文件开头的内容自动选择
+func (se *SearchEngine) SetParserResolver(resolver func(path string) interfaces.LogParser) {
+	se.parserResolver = resolver
+}
+
+// SetRemoteFiles 设置读取远程文件的方法
+func (se *SearchEngine) SetRemoteFiles(remote RemoteFiles) {
+	se.remoteFiles = remote
+}
+
+// isRemote 判断文件是否由 RemoteFiles 提供
+func (se *SearchEngine) isRemote(path string) bool {
+	return se.remoteFiles != nil && se.remoteFiles.Handles(path)
+}
+
+// fileVersion 文件的版本标识，用于缓存失效
+func (se *SearchEngine) fileVersion(ctx context.Context, path string) (string, error) {
+	if se.isRemote(path) {
+		return se.remoteFiles.Version(ctx, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
 // Search 搜索日志
-func (se *SearchEngine) Search(query types.SearchQuery) (*types.SearchResult, error) {
+func (se *SearchEngine) Search(ctx context.Context, query types.SearchQuery) (result *types.SearchResult, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "search.Search", attribute.String("file.path", query.Path))
+	defer func() { tracing.End(span, err) }()
+
 	// 尝试从缓存获取结果
 	if result, found := se.searchCache.Get(query); found {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		searchDuration.WithLabelValues("hit").Observe(time.Since(start).Seconds())
 		return result, nil
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
-	// 使用文件池获取文件资源
-	fileResource, err := se.filePool.GetFileResource(query.Path)
+	// 编译正则表达式（如果需要）
+	regex, err := compileQuery(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file resource %s: %w", query.Path, err)
-	}
-	defer se.filePool.PutFileResource(query.Path, fileResource)
-
-	// 重置文件位置
-	if err := fileResource.Reset(); err != nil {
-		return nil, fmt.Errorf("failed to reset file position: %w", err)
+		return nil, err
 	}
 
 	var results []types.LogEntry
 	var totalCount int64
-	var lineNum int64
 	var processedCount int
 
-	// 编译正则表达式（如果需要）
-	var regex *regexp.Regexp
-	if query.IsRegex {
-		regex, err = regexp.Compile(query.Query)
+	err = se.scan(ctx, query, regex, func(entry *types.LogEntry) {
+		totalCount++
+
+		// 应用分页
+		if processedCount >= query.Offset && len(results) < query.Limit {
+			// 高亮搜索结果
+			highlightedEntry := se.highlightEntry(entry, query, regex)
+			results = append(results, *highlightedEntry)
+		}
+		processedCount++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := totalCount > int64(query.Offset+query.Limit)
+
+	result = &types.SearchResult{
+		Entries:    results,
+		TotalCount: totalCount,
+		HasMore:    hasMore,
+		Offset:     query.Offset,
+	}
+
+	// 缓存搜索结果
+	se.searchCache.Set(query, result)
+	searchDuration.WithLabelValues("miss").Observe(time.Since(start).Seconds())
+
+	return result, nil
+}
+
+// compileQuery 编译正则查询，非正则查询返回nil
+func compileQuery(query types.SearchQuery) (*regexp.Regexp, error) {
+	if !query.IsRegex {
+		return nil, nil
+	}
+	regex, err := regexp.Compile(query.Query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return regex, nil
+}
+
+// scan 逐行扫描文件，对每个符合查询条件的日志条目调用 fn
+func (se *SearchEngine) scan(ctx context.Context, query types.SearchQuery, regex *regexp.Regexp, fn func(entry *types.LogEntry)) (err error) {
+	ctx, span := tracing.Start(ctx, "search.scan",
+		attribute.String("file.path", query.Path),
+		attribute.Bool("search.regex", regex != nil))
+	defer func() { tracing.End(span, err) }()
+
+	var reader io.Reader
+	if se.isRemote(query.Path) {
+		// 远程文件不经过文件池，流式读取整个文件
+		remote, err := se.remoteFiles.Open(ctx, query.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", query.Path, err)
+		}
+		defer remote.Close()
+		reader = remote
+	} else {
+		// 使用文件池获取文件资源
+		_, poolSpan := tracing.Start(ctx, "pool.GetFileResource", attribute.String("file.path", query.Path))
+		fileResource, err := se.filePool.GetFileResource(query.Path)
+		tracing.End(poolSpan, err)
 		if err != nil {
-			return nil, fmt.Errorf("invalid regex pattern: %w", err)
+			return fmt.Errorf("failed to get file resource %s: %w", query.Path, err)
 		}
+		defer se.filePool.PutFileResource(query.Path, fileResource)
+
+		// 重置文件位置
+		if err := fileResource.Reset(); err != nil {
+			return fmt.Errorf("failed to reset file position: %w", err)
+		}
+		reader = fileResource.GetReader()
 	}
 
 	// 获取适合的解析器
 	parser := se.getParserForFile(query.Path)
 
+	start := time.Now()
+
+	// 逐行计时有额外开销，只在 span 被采样时分别统计解析和匹配（含正则）的耗时
+	timed := span.IsRecording()
+	var parseTime, matchTime time.Duration
+	var matched int64
+
 	// 使用优化的扫描器
-	reader := fileResource.GetReader()
-	scanner := bufio.NewScanner(reader)
-	buf := make([]byte, 0, 64*1024) // 64KB 缓冲区
-	scanner.Buffer(buf, 1024*1024)  // 最大1MB行长度
+	scanner := NewLineScanner(reader, 64*1024, 1024*1024) // 64KB 缓冲区，最大1MB行长度
 
+	var lineNum int64
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
 
+		var stepStart time.Time
+		if timed {
+			stepStart = time.Now()
+		}
+
 		// 解析日志条目
 		entry, err := se.parseLogEntry(line, lineNum, parser)
 		if err != nil {
@@ -101,43 +225,49 @@ func (se *SearchEngine) Search(query types.SearchQuery) (*types.SearchResult, er
 				Message: line,
 			}
 		}
+		entry.ByteOffset = scanner.Offset()
 
-		// 应用过滤条件
-		if se.matchesQuery(entry, query, regex) {
-			totalCount++
-
-			// 应用分页
-			if processedCount >= query.Offset && len(results) < query.Limit {
-				// 高亮搜索结果
-				highlightedEntry := se.highlightEntry(entry, query, regex)
-				results = append(results, *highlightedEntry)
-			}
-			processedCount++
+		if timed {
+			now := time.Now()
+			parseTime += now.Sub(stepStart)
+			stepStart = now
+		}
 
-			// 如果已经收集足够的结果，可以继续计数但不添加到结果中
-			if len(results) >= query.Limit {
-				// 继续扫描以获取总数，但不添加到结果中
-			}
+		// 应用过滤条件
+		matches := se.matchesQuery(entry, query, regex)
+		if timed {
+			matchTime += time.Since(stepStart)
+		}
+		if matches {
+			matched++
+			fn(entry)
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+	bytesScanned.Add(float64(scanner.next))
+	scanDuration.Observe(time.Since(start).Seconds())
+
+	span.SetAttributes(
+		attribute.Int64("search.lines", lineNum),
+		attribute.Int64("search.matched", matched),
+		attribute.Int64("search.bytes_scanned", scanner.next),
+	)
+	if timed {
+		span.SetAttributes(
+			attribute.Float64("search.parse_ms", float64(parseTime)/float64(time.Millisecond)),
+			attribute.Float64("search.match_ms", float64(matchTime)/float64(time.Millisecond)),
+		)
 	}
 
-	hasMore := totalCount > int64(query.Offset+query.Limit)
-
-	result := &types.SearchResult{
-		Entries:    results,
-		TotalCount: totalCount,
-		HasMore:    hasMore,
-		Offset:     query.Offset,
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading file: %w", err)
 	}
 
-	// 缓存搜索结果
-	se.searchCache.Set(query, result)
+	return nil
+}
 
-	return result, nil
+// Close 释放搜索引擎持有的文件资源
+func (se *SearchEngine) Close() error {
+	return se.filePool.Close()
 }
 
 // IndexFile 索引文件（当前实现为空，可以后续扩展）
@@ -155,6 +285,10 @@ func (se *SearchEngine) RemoveIndex(path string) error {
 
 // getParserForFile 获取文件对应的解析器
 func (se *SearchEngine) getParserForFile(path string) interfaces.LogParser {
+	if se.parserResolver != nil {
+		return se.parserResolver(path)
+	}
+
 	// 尝试从缓存获取文件内容片段来确定格式
 	cacheKey := fmt.Sprintf("parser_%s", path)
 	if cachedParser, exists := se.cache.Get(cacheKey); exists {
@@ -207,8 +341,24 @@ func (se *SearchEngine) parseLogEntry(line string, lineNum int64, parser interfa
 	}, nil
 }
 
+// NewMatcher 根据查询条件创建日志条目匹配函数，用于在实时日志流上应用与搜索相同的过滤条件
+func NewMatcher(query types.SearchQuery) (func(entry *types.LogEntry) bool, error) {
+	regex, err := compileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return func(entry *types.LogEntry) bool {
+		return matchEntry(entry, query, regex)
+	}, nil
+}
+
 // matchesQuery 检查日志条目是否匹配查询条件
 func (se *SearchEngine) matchesQuery(entry *types.LogEntry, query types.SearchQuery, regex *regexp.Regexp) bool {
+	return matchEntry(entry, query, regex)
+}
+
+// matchEntry 检查日志条目是否匹配查询条件
+func matchEntry(entry *types.LogEntry, query types.SearchQuery, regex *regexp.Regexp) bool {
 	// 检查关键词匹配
 	if query.Query != "" {
 		var matched bool
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -100,7 +101,7 @@ func TestSearch_BasicKeywordSearch(t *testing.T) {
 		Limit:  10,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
@@ -143,7 +144,7 @@ func TestSearch_RegexSearch(t *testing.T) {
 		Limit:   10,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Regex search failed: %v", err)
 	}
@@ -179,7 +180,7 @@ func TestSearch_InvalidRegex(t *testing.T) {
 		Limit:   10,
 	}
 
-	_, err := se.Search(query)
+	_, err := se.Search(context.Background(), query)
 	if err == nil {
 		t.Fatal("Expected error for invalid regex")
 	}
@@ -215,7 +216,7 @@ func TestSearch_TimeRangeFilter(t *testing.T) {
 		Limit:     10,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Time range search failed: %v", err)
 	}
@@ -256,7 +257,7 @@ func TestSearch_LevelFilter(t *testing.T) {
 		Limit:  10,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Level filter search failed: %v", err)
 	}
@@ -296,7 +297,7 @@ func TestSearch_Pagination(t *testing.T) {
 		Limit:  2,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Pagination search failed: %v", err)
 	}
@@ -315,7 +316,7 @@ func TestSearch_Pagination(t *testing.T) {
 
 	// 第二页
 	query.Offset = 2
-	result, err = se.Search(query)
+	result, err = se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Second page search failed: %v", err)
 	}
@@ -326,7 +327,7 @@ func TestSearch_Pagination(t *testing.T) {
 
 	// 第三页
 	query.Offset = 4
-	result, err = se.Search(query)
+	result, err = se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Third page search failed: %v", err)
 	}
@@ -354,7 +355,7 @@ func TestSearch_FileNotFound(t *testing.T) {
 		Limit:  10,
 	}
 
-	_, err := se.Search(query)
+	_, err := se.Search(context.Background(), query)
 	if err == nil {
 		t.Fatal("Expected error for nonexistent file")
 	}
@@ -382,7 +383,7 @@ func TestSearch_EmptyQuery(t *testing.T) {
 		Limit:  10,
 	}
 
-	result, err := se.Search(query)
+	result, err := se.Search(context.Background(), query)
 	if err != nil {
 		t.Fatalf("Empty query search failed: %v", err)
 	}
@@ -0,0 +1,302 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// summaryResolution 文件摘要的时间分辨率，桶宽度为其整数倍时可直接使用摘要
+	summaryResolution = time.Minute
+	// summaryHeadSize 用于识别文件是否被替换的文件头长度
+	summaryHeadSize = 1024
+	// unknownGroup 没有分组值（例如无法识别级别）时使用的分组名
+	unknownGroup = "UNKNOWN"
+)
+
+// FileSummary 文件的按分钟、按级别计数摘要，随文件追加增量扩展
+type FileSummary struct {
+	Path     string `json:"path"`
+	Offset   int64  `json:"offset"`   // 已处理到的字节位置（只处理完整的行）
+	HeadLen  int    `json:"headLen"`  // 计算文件头哈希使用的长度
+	HeadHash string `json:"headHash"` // 文件头哈希，变化说明文件被替换或截断
+	Format   string `json:"format"`   // 生成摘要时使用的解析器（名称和规则指纹，见 parserFingerprint）
+	Untimed  int64  `json:"untimed"`  // 没有时间戳的条目数
+	// Buckets 按分钟起始的Unix时间 -> 级别 -> 条目数
+	Buckets map[int64]map[string]int64 `json:"buckets"`
+}
+
+// SummaryStore 文件摘要存储，dir 为空时只保存在内存中。
+// 摘要按文件和解析器区分，每个摘要一把锁：同一摘要的更新串行，不同文件的扫描互不阻塞
+type SummaryStore struct {
+	dir string
+
+	mutex     sync.Mutex // 保护 summaries 和 locks，不在读取文件时持有
+	summaries map[string]*FileSummary
+	locks     map[string]*sync.Mutex
+}
+
+// NewSummaryStore 创建文件摘要存储，摘要保存在 dir/summaries 目录下
+func NewSummaryStore(dir string) *SummaryStore {
+	if dir != "" {
+		dir = filepath.Join(dir, "summaries")
+	}
+	return &SummaryStore{
+		dir:       dir,
+		summaries: make(map[string]*FileSummary),
+		locks:     make(map[string]*sync.Mutex),
+	}
+}
+
+// Update 将文件新追加的内容计入摘要并返回摘要的副本
+// 文件被替换、截断或解析格式变化时重新生成
+func (s *SummaryStore) Update(path string, parser interfaces.LogParser, parse func(line string) (time.Time, string, bool)) (*FileSummary, error) {
+	format := parserFingerprint(parser)
+	key := path + "\x00" + format
+
+	lock := s.lockFor(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+
+	summary := s.load(key, path)
+	if summary != nil && !summary.valid(file, info.Size(), format) {
+		logger.Debug("文件摘要失效，重新生成", zap.String("path", path))
+		summary = nil
+	}
+	if summary == nil {
+		summary = &FileSummary{
+			Path:    path,
+			Format:  format,
+			Buckets: make(map[int64]map[string]int64),
+		}
+	}
+
+	if info.Size() > summary.Offset {
+		if err := summary.extend(file, parse); err != nil {
+			// 部分扩展的摘要不可信，下次重新生成
+			s.mutex.Lock()
+			delete(s.summaries, key)
+			s.mutex.Unlock()
+			return nil, err
+		}
+		if summary.HeadLen < summaryHeadSize && summary.Offset > int64(summary.HeadLen) {
+			if err := summary.updateHead(file); err != nil {
+				return nil, err
+			}
+		}
+		s.save(key, summary)
+	}
+
+	return summary.clone(), nil
+}
+
+// lockFor 返回摘要的锁，锁随摘要保留（数量与查询过的文件数相同）
+func (s *SummaryStore) lockFor(key string) *sync.Mutex {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	lock, exists := s.locks[key]
+	if !exists {
+		lock = &sync.Mutex{}
+		s.locks[key] = lock
+	}
+	return lock
+}
+
+// parserFingerprint 摘要对应的解析器：格式名称，规则可修改的解析器再加上规则的哈希，
+// 修改自定义解析器的模式后旧摘要不再使用
+func parserFingerprint(parser interfaces.LogParser) string {
+	if parser == nil {
+		return ""
+	}
+	if p, ok := parser.(interfaces.ParserFingerprinter); ok {
+		return parser.GetFormat() + ":" + p.Fingerprint()
+	}
+	return parser.GetFormat()
+}
+
+// valid 检查摘要是否仍对应当前文件
+func (fs *FileSummary) valid(file *os.File, size int64, format string) bool {
+	if fs.Format != format || size < fs.Offset {
+		return false
+	}
+	if fs.HeadLen == 0 {
+		return true
+	}
+	hash, err := headHash(file, fs.HeadLen)
+	return err == nil && hash == fs.HeadHash
+}
+
+// extend 从 Offset 开始读取完整的行并计入摘要
+func (fs *FileSummary) extend(file *os.File, parse func(line string) (time.Time, string, bool)) error {
+	if _, err := file.Seek(fs.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// 最后一行还没写完，下次再处理
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading file: %w", err)
+		}
+		fs.Offset += int64(len(line))
+
+		text := string(bytes.TrimRight(line, "\r\n"))
+		if text == "" {
+			continue
+		}
+
+		timestamp, level, ok := parse(text)
+		if !ok {
+			fs.Untimed++
+			continue
+		}
+		if level == "" {
+			level = unknownGroup
+		}
+
+		minute := timestamp.Truncate(summaryResolution).Unix()
+		levels := fs.Buckets[minute]
+		if levels == nil {
+			levels = make(map[string]int64)
+			fs.Buckets[minute] = levels
+		}
+		levels[level]++
+	}
+}
+
+// updateHead 文件头不足 summaryHeadSize 时随文件增长重新计算
+func (fs *FileSummary) updateHead(file *os.File) error {
+	headLen := summaryHeadSize
+	if fs.Offset < int64(headLen) {
+		headLen = int(fs.Offset)
+	}
+	hash, err := headHash(file, headLen)
+	if err != nil {
+		return err
+	}
+	fs.HeadLen = headLen
+	fs.HeadHash = hash
+	return nil
+}
+
+// clone 复制摘要，避免调用方与后续的增量更新并发访问
+func (fs *FileSummary) clone() *FileSummary {
+	copied := *fs
+	copied.Buckets = make(map[int64]map[string]int64, len(fs.Buckets))
+	for minute, levels := range fs.Buckets {
+		copiedLevels := make(map[string]int64, len(levels))
+		for level, count := range levels {
+			copiedLevels[level] = count
+		}
+		copied.Buckets[minute] = copiedLevels
+	}
+	return &copied
+}
+
+// headHash 计算文件前 n 个字节的哈希
+func headHash(file *os.File, n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := file.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file head: %w", err)
+	}
+	sum := sha1.Sum(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// load 从内存或磁盘加载摘要，调用方持有摘要的锁
+func (s *SummaryStore) load(key, path string) *FileSummary {
+	s.mutex.Lock()
+	summary, exists := s.summaries[key]
+	s.mutex.Unlock()
+	if exists {
+		return summary
+	}
+	if s.dir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.summaryPath(key))
+	if err != nil {
+		return nil
+	}
+
+	summary = &FileSummary{}
+	if err := json.Unmarshal(data, summary); err != nil || summary.Path != path {
+		logger.Warn("忽略无效的文件摘要", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	if summary.Buckets == nil {
+		summary.Buckets = make(map[int64]map[string]int64)
+	}
+
+	s.mutex.Lock()
+	s.summaries[key] = summary
+	s.mutex.Unlock()
+	return summary
+}
+
+// save 保存摘要，写入磁盘失败时只记录日志（摘要可以重新生成）；调用方持有摘要的锁
+func (s *SummaryStore) save(key string, summary *FileSummary) {
+	s.mutex.Lock()
+	s.summaries[key] = summary
+	s.mutex.Unlock()
+	if s.dir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		logger.Warn("创建摘要目录失败", zap.String("dir", s.dir), zap.Error(err))
+		return
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		logger.Warn("序列化文件摘要失败", zap.String("path", summary.Path), zap.Error(err))
+		return
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下不完整的摘要
+	target := s.summaryPath(key)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger.Warn("写入文件摘要失败", zap.String("path", summary.Path), zap.Error(err))
+		return
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		logger.Warn("保存文件摘要失败", zap.String("path", summary.Path), zap.Error(err))
+	}
+}
+
+// summaryPath 摘要文件路径，key 为文件路径和解析器指纹
+func (s *SummaryStore) summaryPath(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/local-log-viewer/internal/tracing"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestScan_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	filePath := createTestFile(t, `{"level":"error","msg":"timeout after 30s"}
+{"level":"info","msg":"ok"}
+{"level":"error","msg":"timeout after 5s"}
+`)
+	se := newJSONSearchEngine()
+	defer se.Close()
+
+	ctx, request := tracing.StartServer(context.Background(), "GET /api/facets")
+	_, err := se.Facets(ctx, types.FacetQuery{
+		SearchQuery: types.SearchQuery{Path: filePath, Query: `timeout after \d+s`, IsRegex: true},
+		Field:       "level",
+	})
+	request.End()
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	scan, ok := spans["search.scan"]
+	if !ok {
+		t.Fatalf("missing search.scan span, got %v", spans)
+	}
+	if scan.Parent.SpanID() != request.SpanContext().SpanID() {
+		t.Error("expected scan span to be a child of the request span")
+	}
+	if pool, ok := spans["pool.GetFileResource"]; !ok || pool.Parent.SpanID() != scan.SpanContext.SpanID() {
+		t.Error("expected pool span to be a child of the scan span")
+	}
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range scan.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs["search.lines"].AsInt64() != 3 || attrs["search.matched"].AsInt64() != 2 || !attrs["search.regex"].AsBool() {
+		t.Errorf("unexpected scan attributes: %v", scan.Attributes)
+	}
+	for _, key := range []attribute.Key{"search.parse_ms", "search.match_ms", "search.bytes_scanned"} {
+		if _, ok := attrs[key]; !ok {
+			t.Errorf("missing attribute %s", key)
+		}
+	}
+}
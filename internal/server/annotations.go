@@ -0,0 +1,144 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/annotation"
+	"github.com/local-log-viewer/internal/errors"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/middleware"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// annotationProvider 获取支持标注的日志管理器
+func (s *HTTPServer) annotationProvider(c *gin.Context) (interfaces.AnnotationProvider, bool) {
+	provider, ok := s.logManager.(interfaces.AnnotationProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support annotations"), errors.ErrorTypeServiceUnavailable, "annotations are not supported"))
+	}
+	return provider, ok
+}
+
+// listAnnotations 列出文件上的标注 API
+func (s *HTTPServer) listAnnotations(c *gin.Context) {
+	provider, ok := s.annotationProvider(c)
+	if !ok {
+		return
+	}
+
+	path := c.Query("path")
+	if path == "" {
+		c.Error(errors.NewSearchError("path", fmt.Errorf("missing path parameter")))
+		return
+	}
+
+	annotations, err := provider.GetAnnotations(path)
+	if err != nil {
+		c.Error(annotationError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    annotations,
+	})
+}
+
+// createAnnotation 创建标注 API
+func (s *HTTPServer) createAnnotation(c *gin.Context) {
+	provider, ok := s.annotationProvider(c)
+	if !ok {
+		return
+	}
+
+	var request types.Annotation
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, "invalid request body"))
+		return
+	}
+
+	created, err := provider.CreateAnnotation(middleware.CurrentUser(c), request)
+	if err != nil {
+		c.Error(annotationError(err))
+		return
+	}
+	s.broadcastAnnotation("created", created)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// updateAnnotation 修改标注 API，只有作者可以修改
+func (s *HTTPServer) updateAnnotation(c *gin.Context) {
+	provider, ok := s.annotationProvider(c)
+	if !ok {
+		return
+	}
+
+	var request types.Annotation
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, "invalid request body"))
+		return
+	}
+
+	updated, err := provider.UpdateAnnotation(c.Param("id"), middleware.CurrentUser(c), request)
+	if err != nil {
+		c.Error(annotationError(err))
+		return
+	}
+	s.broadcastAnnotation("updated", updated)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// deleteAnnotation 删除标注 API，只有作者可以删除
+func (s *HTTPServer) deleteAnnotation(c *gin.Context) {
+	provider, ok := s.annotationProvider(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := provider.DeleteAnnotation(c.Param("id"), middleware.CurrentUser(c))
+	if err != nil {
+		c.Error(annotationError(err))
+		return
+	}
+	s.broadcastAnnotation("deleted", deleted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// broadcastAnnotation 通知正在查看该文件的客户端
+func (s *HTTPServer) broadcastAnnotation(action string, a *types.Annotation) {
+	s.wsHub.BroadcastToSubscribers(a.Path, types.WSMessage{
+		Type: "annotation",
+		Data: types.AnnotationEvent{
+			Action:     action,
+			Annotation: *a,
+		},
+	})
+}
+
+// annotationError 将存储错误转换为对应的 API 错误
+func annotationError(err error) error {
+	switch {
+	case stderrors.Is(err, annotation.ErrNotFound):
+		return errors.WrapError(err, errors.ErrorTypeNotFound, "annotation not found")
+	case stderrors.Is(err, annotation.ErrForbidden):
+		return errors.WrapError(err, errors.ErrorTypeAccessDenied, "only the author can modify an annotation")
+	case stderrors.Is(err, annotation.ErrInvalid):
+		return errors.WrapError(err, errors.ErrorTypeInvalidQuery, err.Error())
+	default:
+		return errors.WrapError(err, errors.ErrorTypeInternalError, "failed to access annotations")
+	}
+}
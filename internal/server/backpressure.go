@@ -0,0 +1,327 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// streamRetryInterval 客户端发送缓冲区满时重新发送积压更新的间隔
+const streamRetryInterval = 100 * time.Millisecond
+
+var (
+	errClientClosed   = errors.New("client connection is closed")
+	errSendBufferFull = errors.New("send channel is full")
+	errSlowConsumer   = errors.New("pending updates exceed the limit")
+)
+
+// updateQueue 订阅中等待发送的更新，客户端发送缓冲区满时积压在这里
+// 积压超出上限时按慢速客户端策略处理，丢弃的范围合并为一个 gap 通知客户端
+type updateQueue struct {
+	config  config.StreamingConfig
+	pending []types.LogUpdate
+	gap     *types.StreamGap
+}
+
+// pushResult 加入更新后的处理结果
+type pushResult struct {
+	dropped    int  // 丢弃的更新数
+	coalesced  int  // 合并掉的更新数
+	disconnect bool // 需要断开连接
+}
+
+func newUpdateQueue(cfg config.StreamingConfig) *updateQueue {
+	return &updateQueue{config: cfg.WithDefaults()}
+}
+
+// push 加入更新，超出积压上限时按策略处理
+func (q *updateQueue) push(update types.LogUpdate) pushResult {
+	q.pending = append(q.pending, update)
+	if len(q.pending) <= q.config.MaxPendingUpdates {
+		return pushResult{}
+	}
+
+	var result pushResult
+	switch q.config.SlowConsumerPolicy {
+	case config.SlowConsumerDisconnect:
+		result.disconnect = true
+		return result
+	case config.SlowConsumerCoalesce:
+		result.coalesced = q.coalesce()
+	}
+
+	// 仍然超出上限（例如无法合并的截断、删除事件）时丢弃最早的更新
+	for len(q.pending) > q.config.MaxPendingUpdates {
+		oldest := q.pending[0]
+		q.pending = q.pending[1:]
+		q.addGap(oldest, oldest.StartOffset, oldest.EndOffset, len(oldest.Entries), config.SlowConsumerDropOldest)
+		result.dropped++
+	}
+	return result
+}
+
+// coalesce 合并连续的追加更新，合并后的更新使用最后一个更新的序号
+// 条目超出上限时丢弃最早的条目并记录到 gap，返回被合并掉的更新数
+func (q *updateQueue) coalesce() int {
+	merged := make([]types.LogUpdate, 0, len(q.pending))
+	coalesced := 0
+	for _, update := range q.pending {
+		if n := len(merged); n > 0 && update.Type == "append" && merged[n-1].Type == "append" {
+			last := &merged[n-1]
+			// 先截断容量，避免写入其他更新共享的底层数组
+			last.Entries = append(last.Entries[:len(last.Entries):len(last.Entries)], update.Entries...)
+			last.Seq = update.Seq
+			last.EndOffset = update.EndOffset
+			coalesced++
+			continue
+		}
+		merged = append(merged, update)
+	}
+
+	for i := range merged {
+		update := &merged[i]
+		excess := len(update.Entries) - q.config.MaxCoalescedEntries
+		if excess <= 0 {
+			continue
+		}
+		keepFrom := update.Entries[excess].ByteOffset
+		q.addGap(*update, update.StartOffset, keepFrom, excess, config.SlowConsumerCoalesce)
+		update.Entries = update.Entries[excess:]
+		update.StartOffset = keepFrom
+	}
+
+	q.pending = merged
+	return coalesced
+}
+
+// addGap 将丢弃的范围合并到待发送的 gap 中
+func (q *updateQueue) addGap(update types.LogUpdate, start, end int64, entries int, reason string) {
+	if q.gap == nil {
+		q.gap = &types.StreamGap{
+			Path:        update.Path,
+			FromSeq:     update.Seq,
+			ToSeq:       update.Seq,
+			StartOffset: start,
+			EndOffset:   end,
+			Entries:     entries,
+			Reason:      reason,
+		}
+		return
+	}
+
+	if update.Seq < q.gap.FromSeq {
+		q.gap.FromSeq = update.Seq
+	}
+	if update.Seq > q.gap.ToSeq {
+		q.gap.ToSeq = update.Seq
+	}
+	if start < q.gap.StartOffset {
+		q.gap.StartOffset = start
+	}
+	if end > q.gap.EndOffset {
+		q.gap.EndOffset = end
+	}
+	q.gap.Entries += entries
+	q.gap.Reason = reason
+}
+
+// streamingConfig 返回 WebSocket 中心的实时推送配置
+func (c *WebSocketClient) streamingConfig() config.StreamingConfig {
+	if hub, ok := c.hub.(*WebSocketHub); ok {
+		return hub.streamingConfig()
+	}
+	return config.StreamingConfig{}.WithDefaults()
+}
+
+// flushUpdates 依次发送缺失范围和积压的更新，客户端发送缓冲区满时停止并返回错误
+func (c *WebSocketClient) flushUpdates(q *updateQueue) error {
+	if q.gap != nil {
+		if err := c.Send(types.WSMessage{Type: "gap", Data: *q.gap}); err != nil {
+			return err
+		}
+		if hub, ok := c.hub.(*WebSocketHub); ok {
+			hub.recordStreamMetrics(0, 0, 0, 1)
+		}
+		q.gap = nil
+	}
+
+	for len(q.pending) > 0 {
+		if err := c.Send(types.WSMessage{Type: "log_update", Data: q.pending[0]}); err != nil {
+			return err
+		}
+		q.pending = q.pending[1:]
+	}
+	return nil
+}
+
+// streamUpdates 将文件更新推送给客户端，直到订阅被取消或更新通道关闭
+// 客户端跟不上时更新积压在队列中，监听不会阻塞，日志管理器的更新通道不会被占满
+// replayEnd 为续传回放结束的位置，已被回放覆盖的追加更新不再发送，小于 0 表示没有回放
+func (c *WebSocketClient) streamUpdates(ctx context.Context, path string, sub *subscription, updateCh <-chan types.LogUpdate, queue *updateQueue, replayEnd int64) {
+	var retry <-chan time.Time
+	if len(queue.pending) > 0 || queue.gap != nil {
+		retry = time.After(0)
+	}
+
+	for {
+		select {
+		case update, ok := <-updateCh:
+			if !ok {
+				log.Printf("Update channel closed for: %s", path)
+				return
+			}
+
+			// 日志管理器中积压超出上限而丢弃的范围，与本地丢弃的范围一起通知客户端
+			if update.Type == "gap" {
+				gap := gapFromUpdate(update)
+				queue.addGap(update, gap.StartOffset, gap.EndOffset, 0, gap.Reason)
+				if retry == nil {
+					retry = time.After(0)
+				}
+				continue
+			}
+
+			if update, ok = trimReplayed(update, &replayEnd); !ok {
+				continue
+			}
+
+			// 应用当前的过滤条件，全部被过滤时不发送
+			update, ok = sub.filter.Load().apply(update)
+			if !ok {
+				continue
+			}
+
+			result := queue.push(update)
+			if hub, ok := c.hub.(*WebSocketHub); ok {
+				disconnects := 0
+				if result.disconnect {
+					disconnects = 1
+				}
+				hub.recordStreamMetrics(result.dropped, result.coalesced, disconnects, 0)
+			}
+			if result.disconnect {
+				c.disconnectSlowConsumer(path)
+				return
+			}
+
+		case <-retry:
+
+		case <-ctx.Done():
+			log.Printf("Subscription cancelled for: %s", path)
+			return
+		}
+
+		retry = nil
+		if err := c.flushUpdates(queue); err != nil {
+			if errors.Is(err, errClientClosed) {
+				return
+			}
+			retry = time.After(streamRetryInterval)
+		}
+	}
+}
+
+// trimReplayed 去掉实时更新中已被续传回放覆盖的部分，全部被覆盖时返回 false，replayEnd 小于 0 表示没有回放
+// 跨越回放结束位置的追加更新只保留从该位置开始的条目；截断等事件之后字节位置重新开始，不再与回放范围比较
+func trimReplayed(update types.LogUpdate, replayEnd *int64) (types.LogUpdate, bool) {
+	end := *replayEnd
+	if end < 0 {
+		return update, true
+	}
+	*replayEnd = -1
+	if update.Type != "append" || update.StartOffset >= end {
+		return update, true
+	}
+	if update.EndOffset <= end {
+		*replayEnd = end
+		return update, false
+	}
+
+	entries := make([]types.LogEntry, 0, len(update.Entries))
+	for _, entry := range update.Entries {
+		if entry.ByteOffset >= end {
+			entries = append(entries, entry)
+		}
+	}
+	update.Entries = entries
+	update.StartOffset = end
+	return update, len(entries) > 0
+}
+
+// gapFromUpdate 将日志管理器因订阅者积压丢弃更新产生的 gap 更新转换为缺失范围
+func gapFromUpdate(update types.LogUpdate) types.StreamGap {
+	return types.StreamGap{
+		Path:        update.Path,
+		ToSeq:       update.Seq,
+		StartOffset: update.StartOffset,
+		EndOffset:   update.EndOffset,
+		Reason:      "subscriber_backlog",
+	}
+}
+
+// disconnectSlowConsumer 断开跟不上推送速度的客户端
+func (c *WebSocketClient) disconnectSlowConsumer(path string) {
+	log.Printf("Disconnecting slow WebSocket client %s (subscription: %s)", c.GetID(), path)
+	// 发送缓冲区已满，直接发送关闭帧说明原因
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "slow consumer"),
+		time.Now().Add(writeWait))
+	c.hub.UnregisterClient(c)
+	c.Close()
+}
+
+// handleResync 从指定字节位置重新读取文件内容，用于补齐 gap 通知的缺失范围
+// 单次最多读取 maxResyncBytes 字节，剩余部分以 gap 通知，客户端可以继续 resync
+func (c *WebSocketClient) handleResync(path string, offset int64) {
+	if path == "" {
+		c.sendError("INVALID_PATH", "Path is required for resync")
+		return
+	}
+
+	reader, ok := c.logManager.(interfaces.RangeReader)
+	if !ok {
+		c.sendError("RESYNC_UNSUPPORTED", "Log manager does not support resync")
+		return
+	}
+
+	normalizedPath, err := c.normalizePath(path)
+	if err != nil {
+		normalizedPath = path
+	}
+
+	c.subMutex.Lock()
+	sub, exists := c.subscriptions[normalizedPath]
+	c.subMutex.Unlock()
+	if !exists {
+		c.sendError("NOT_SUBSCRIBED", "No active subscription for: "+normalizedPath)
+		return
+	}
+
+	update, liveOffset, err := reader.ReadRange(normalizedPath, offset, c.streamingConfig().MaxResyncBytes)
+	if err != nil {
+		c.sendError("RESYNC_FAILED", err.Error())
+		return
+	}
+
+	// 补齐的内容同样应用订阅的过滤条件，即使没有匹配的条目也发送，告知客户端范围已补齐
+	filtered, _ := sub.filter.Load().apply(*update)
+	if err := c.Send(types.WSMessage{Type: "log_update", Data: filtered}); err != nil {
+		log.Printf("Failed to send resync update: %v", err)
+		return
+	}
+
+	if update.EndOffset < liveOffset {
+		c.Send(types.WSMessage{Type: "gap", Data: types.StreamGap{
+			Path:        normalizedPath,
+			StartOffset: update.EndOffset,
+			EndOffset:   liveOffset,
+			Reason:      "resync_limit",
+		}})
+	}
+}
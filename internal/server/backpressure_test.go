@@ -0,0 +1,138 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// appendUpdate 构造 [start, start+10*lines) 范围内每行 10 字节的追加更新
+func appendUpdate(seq uint64, start int64, lines int) types.LogUpdate {
+	update := types.LogUpdate{Path: "/var/log/app.log", Type: "append", Seq: seq, StartOffset: start}
+	for i := 0; i < lines; i++ {
+		update.Entries = append(update.Entries, types.LogEntry{ByteOffset: start + int64(i*10)})
+	}
+	update.EndOffset = start + int64(lines*10)
+	return update
+}
+
+func TestUpdateQueue_DropOldest(t *testing.T) {
+	q := newUpdateQueue(config.StreamingConfig{MaxPendingUpdates: 2})
+
+	assert.Zero(t, q.push(appendUpdate(1, 0, 1)).dropped)
+	assert.Zero(t, q.push(appendUpdate(2, 10, 2)).dropped)
+	assert.Equal(t, 1, q.push(appendUpdate(3, 30, 1)).dropped)
+	assert.Equal(t, 1, q.push(appendUpdate(4, 40, 1)).dropped)
+
+	require.Len(t, q.pending, 2)
+	assert.Equal(t, uint64(3), q.pending[0].Seq)
+	require.NotNil(t, q.gap)
+	assert.Equal(t, types.StreamGap{
+		Path:        "/var/log/app.log",
+		FromSeq:     1,
+		ToSeq:       2,
+		StartOffset: 0,
+		EndOffset:   30,
+		Entries:     3,
+		Reason:      config.SlowConsumerDropOldest,
+	}, *q.gap)
+}
+
+func TestUpdateQueue_Coalesce(t *testing.T) {
+	q := newUpdateQueue(config.StreamingConfig{
+		SlowConsumerPolicy:  config.SlowConsumerCoalesce,
+		MaxPendingUpdates:   2,
+		MaxCoalescedEntries: 3,
+	})
+
+	first := appendUpdate(1, 0, 2)
+	q.push(first)
+	q.push(appendUpdate(2, 20, 1))
+	result := q.push(appendUpdate(3, 30, 2))
+
+	assert.Equal(t, 2, result.coalesced)
+	assert.Zero(t, result.dropped)
+	require.Len(t, q.pending, 1)
+
+	merged := q.pending[0]
+	assert.Equal(t, uint64(3), merged.Seq)
+	assert.Equal(t, int64(20), merged.StartOffset)
+	assert.Equal(t, int64(50), merged.EndOffset)
+	assert.Len(t, merged.Entries, 3)
+	assert.Len(t, first.Entries, 2, "合并不应修改原有更新")
+
+	// 超出条目上限的最早条目记录为缺失范围
+	require.NotNil(t, q.gap)
+	assert.Equal(t, int64(0), q.gap.StartOffset)
+	assert.Equal(t, int64(20), q.gap.EndOffset)
+	assert.Equal(t, 2, q.gap.Entries)
+	assert.Equal(t, config.SlowConsumerCoalesce, q.gap.Reason)
+}
+
+func TestUpdateQueue_Disconnect(t *testing.T) {
+	q := newUpdateQueue(config.StreamingConfig{SlowConsumerPolicy: config.SlowConsumerDisconnect, MaxPendingUpdates: 1})
+
+	assert.False(t, q.push(appendUpdate(1, 0, 1)).disconnect)
+	assert.True(t, q.push(appendUpdate(2, 10, 1)).disconnect)
+	assert.Nil(t, q.gap)
+}
+
+func TestStreamingConfig_Validate(t *testing.T) {
+	assert.NoError(t, config.StreamingConfig{}.Validate())
+	assert.NoError(t, config.StreamingConfig{SlowConsumerPolicy: config.SlowConsumerCoalesce}.Validate())
+	assert.Error(t, config.StreamingConfig{SlowConsumerPolicy: "block"}.Validate())
+	assert.Error(t, config.StreamingConfig{MaxPendingUpdates: -1}.Validate())
+}
+
+func TestTrimReplayed(t *testing.T) {
+	// 完全被回放覆盖的更新不发送，之后仍然与回放范围比较
+	replayEnd := int64(30)
+	_, ok := trimReplayed(appendUpdate(1, 0, 2), &replayEnd)
+	assert.False(t, ok)
+	assert.Equal(t, int64(30), replayEnd)
+
+	// 跨越回放结束位置的更新只保留之后的条目
+	update, ok := trimReplayed(appendUpdate(2, 20, 3), &replayEnd)
+	require.True(t, ok)
+	assert.Equal(t, int64(30), update.StartOffset)
+	assert.Equal(t, int64(50), update.EndOffset)
+	require.Len(t, update.Entries, 2)
+	assert.Equal(t, int64(30), update.Entries[0].ByteOffset)
+	assert.Equal(t, int64(-1), replayEnd)
+
+	// 回放结束后不再裁剪
+	update, ok = trimReplayed(appendUpdate(3, 0, 1), &replayEnd)
+	assert.True(t, ok)
+	assert.Len(t, update.Entries, 1)
+
+	// 截断之后字节位置重新开始
+	replayEnd = 30
+	update, ok = trimReplayed(types.LogUpdate{Type: "truncate"}, &replayEnd)
+	assert.True(t, ok)
+	assert.Equal(t, "truncate", update.Type)
+	assert.Equal(t, int64(-1), replayEnd)
+}
+
+func TestReplayRange_Disconnect(t *testing.T) {
+	manager := newResyncLogManager()
+	cfg := config.StreamingConfig{SlowConsumerPolicy: config.SlowConsumerDisconnect, MaxPendingUpdates: 1, MaxResyncBytes: 13}
+	queue := newUpdateQueue(cfg)
+
+	// 每次读取一行，第二行超出积压上限
+	_, err := replayRange(manager, cfg, "/var/log/app.log", 0, nil, queue)
+	assert.ErrorIs(t, err, errSlowConsumer)
+
+	// 其他策略下继续回放，超出的部分记录为缺失范围
+	cfg.SlowConsumerPolicy = config.SlowConsumerDropOldest
+	queue = newUpdateQueue(cfg)
+	end, err := replayRange(manager, cfg, "/var/log/app.log", 0, nil, queue)
+	require.NoError(t, err)
+	assert.Equal(t, int64(39), end)
+	require.Len(t, queue.pending, 1)
+	require.NotNil(t, queue.gap)
+	assert.Equal(t, int64(26), queue.gap.EndOffset)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/errors"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// correlate 跨文件关联日志 API，返回带有同一 trace ID 或 request ID 的合并时间线
+func (s *HTTPServer) correlate(c *gin.Context) {
+	provider, ok := s.logManager.(interfaces.CorrelationProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support correlation"), errors.ErrorTypeServiceUnavailable, "correlation is not supported"))
+		return
+	}
+
+	query := types.CorrelateQuery{ID: c.Query("id")}
+	if query.ID == "" {
+		c.Error(errors.NewSearchError("id", fmt.Errorf("missing id parameter")))
+		return
+	}
+
+	var err error
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
+		if query.StartTime, err = time.Parse(time.RFC3339, startTimeStr); err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid startTime format, should use RFC3339"))
+			return
+		}
+	}
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
+		if query.EndTime, err = time.Parse(time.RFC3339, endTimeStr); err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid endTime format, should use RFC3339"))
+			return
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if query.Limit, err = strconv.Atoi(limitStr); err != nil || query.Limit <= 0 {
+			c.Error(errors.NewSearchError("limit", fmt.Errorf("invalid limit parameter, should be a positive integer")))
+			return
+		}
+	}
+
+	result, err := provider.Correlate(c.Request.Context(), query)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to correlate logs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
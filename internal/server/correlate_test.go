@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// correlationLogManager 支持关联查询的日志管理器，记录收到的查询
+type correlationLogManager struct {
+	MockLogManager
+	query types.CorrelateQuery
+}
+
+func (m *correlationLogManager) Correlate(ctx context.Context, query types.CorrelateQuery) (*types.CorrelateResult, error) {
+	m.query = query
+	return &types.CorrelateResult{
+		ID:           query.ID,
+		Entries:      []types.CorrelatedEntry{{Path: "/tmp/logs/api.log", MatchedField: "trace_id", SpanID: "s1"}},
+		TotalCount:   1,
+		FilesScanned: 2,
+	}, nil
+}
+
+func TestCorrelateAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &correlationLogManager{}
+	server := New(&config.Config{}, manager, NewWebSocketHub())
+	server.setupRoutes()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/correlate?id=abc&startTime=2026-01-02T09:00:00Z&endTime=2026-01-02T10:00:00Z&limit=50")
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if manager.query.ID != "abc" || manager.query.Limit != 50 ||
+		!manager.query.StartTime.Equal(time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("查询参数解析错误: %+v", manager.query)
+	}
+
+	var response struct {
+		Success bool                  `json:"success"`
+		Data    types.CorrelateResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if !response.Success || response.Data.FilesScanned != 2 || response.Data.Entries[0].SpanID != "s1" {
+		t.Errorf("响应不正确: %+v", response)
+	}
+
+	for _, url := range []string{
+		"/api/correlate",
+		"/api/correlate?id=abc&limit=0",
+		"/api/correlate?id=abc&startTime=yesterday",
+	} {
+		if w := get(url); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", url, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestCorrelateAPI_Unsupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := New(&config.Config{}, &MockLogManager{}, NewWebSocketHub())
+	server.setupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/correlate?id=abc", nil)
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+type formatContextKey struct{}
+
+// formatLogManager 支持按请求和持久指定格式的日志管理器，记录读取时的格式和保存的格式
+type formatLogManager struct {
+	MockLogManager
+	readFormat  string
+	savedFormat map[string]string
+}
+
+func (m *formatLogManager) WithFormat(ctx context.Context, format string) (context.Context, error) {
+	if format == "nope" {
+		return ctx, fmt.Errorf("未知的日志格式: %s", format)
+	}
+	return context.WithValue(ctx, formatContextKey{}, format), nil
+}
+
+func (m *formatLogManager) SetFileFormat(path, format string) error {
+	switch {
+	case format == "nope":
+		return fmt.Errorf("未知的日志格式: %s", format)
+	case strings.HasPrefix(path, "/etc/"):
+		return fmt.Errorf("路径不在配置的日志路径中: %s: %w", path, fs.ErrPermission)
+	case strings.HasSuffix(path, "missing.log"):
+		return fmt.Errorf("文件不存在: %w", fs.ErrNotExist)
+	}
+	m.savedFormat[path] = format
+	return nil
+}
+
+func (m *formatLogManager) ReadLogFile(ctx context.Context, path string, offset int64, limit int) (*types.LogContent, error) {
+	m.readFormat, _ = ctx.Value(formatContextKey{}).(string)
+	return &types.LogContent{Entries: []types.LogEntry{}}, nil
+}
+
+func (m *formatLogManager) ReadLogFileFromTail(ctx context.Context, path string, lines int) (*types.LogContent, error) {
+	return m.ReadLogFile(ctx, path, 0, lines)
+}
+
+func TestLogFormatAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &formatLogManager{savedFormat: make(map[string]string)}
+	server := New(&config.Config{}, manager, NewWebSocketHub())
+	server.setupRoutes()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// GET 的 format 参数只对本次读取生效，不保存
+	for _, url := range []string{"/api/logs/content/%2Ftmp%2Fapp.log?format=logfmt", "/api/logs/tail/%2Ftmp%2Fapp.log?format=logfmt"} {
+		if w := do("GET", url, ""); w.Code != http.StatusOK || manager.readFormat != "logfmt" {
+			t.Errorf("%s: 期望按 logfmt 读取，状态码 %d，格式 %q", url, w.Code, manager.readFormat)
+		}
+	}
+	if len(manager.savedFormat) != 0 {
+		t.Errorf("GET 请求不应保存格式: %v", manager.savedFormat)
+	}
+	if w := do("GET", "/api/logs/content/%2Ftmp%2Fapp.log", ""); w.Code != http.StatusOK || manager.readFormat != "" {
+		t.Errorf("没有 format 参数时不应指定格式: %q", manager.readFormat)
+	}
+	if w := do("GET", "/api/logs/content/%2Ftmp%2Fapp.log?format=nope", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("未知格式期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 持久指定格式使用 PUT
+	if w := do("PUT", "/api/logs/format/%2Ftmp%2Fapp.log", `{"format":"logfmt"}`); w.Code != http.StatusOK || manager.savedFormat["/tmp/app.log"] != "logfmt" {
+		t.Errorf("PUT 期望保存格式，状态码 %d: %s, %v", w.Code, w.Body.String(), manager.savedFormat)
+	}
+	if w := do("PUT", "/api/logs/format/%2Ftmp%2Fapp.log", `{"format":"nope"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("未知格式期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+	if w := do("PUT", "/api/logs/format/%2Ftmp%2Fapp.log", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("缺少格式期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+	if w := do("PUT", "/api/logs/format/%2Ftmp%2Fmissing.log", `{"format":"logfmt"}`); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的文件期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+	if w := do("PUT", "/api/logs/format/%2Fetc%2Fpasswd", `{"format":"logfmt"}`); w.Code != http.StatusForbidden {
+		t.Errorf("日志路径之外的文件期望状态码 %d, 得到 %d", http.StatusForbidden, w.Code)
+	}
+}
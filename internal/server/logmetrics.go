@@ -0,0 +1,104 @@
+package server
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/errors"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logmetric"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// logMetricProvider 获取支持日志指标的日志管理器
+func (s *HTTPServer) logMetricProvider(c *gin.Context) (interfaces.LogMetricProvider, bool) {
+	provider, ok := s.logManager.(interfaces.LogMetricProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support log metrics"), errors.ErrorTypeServiceUnavailable, "log metrics are not supported"))
+	}
+	return provider, ok
+}
+
+// getLogMetrics 列出日志指标 API
+func (s *HTTPServer) getLogMetrics(c *gin.Context) {
+	provider, ok := s.logMetricProvider(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    provider.GetLogMetrics(),
+	})
+}
+
+// queryLogMetric 按时间范围和步长查询日志指标 API
+func (s *HTTPServer) queryLogMetric(c *gin.Context) {
+	provider, ok := s.logMetricProvider(c)
+	if !ok {
+		return
+	}
+
+	query := types.LogMetricQuery{
+		Name:  c.Query("name"),
+		Func:  c.Query("func"),
+		Group: c.Query("group"),
+	}
+	if query.Name == "" {
+		c.Error(errors.NewSearchError("name", fmt.Errorf("missing name parameter")))
+		return
+	}
+
+	var err error
+	if q := c.Query("q"); q != "" {
+		if query.Quantile, err = strconv.ParseFloat(q, 64); err != nil {
+			c.Error(errors.NewSearchError("q", fmt.Errorf("invalid q parameter, should be a number such as 0.99")))
+			return
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		if query.Start, err = time.Parse(time.RFC3339, from); err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid from format, should use RFC3339"))
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.End, err = time.Parse(time.RFC3339, to); err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid to format, should use RFC3339"))
+			return
+		}
+	}
+	if step := c.Query("step"); step != "" {
+		if query.Step, err = time.ParseDuration(step); err != nil {
+			c.Error(errors.NewSearchError("step", fmt.Errorf("invalid step parameter, should be a duration such as 1m")))
+			return
+		}
+	}
+
+	result, err := provider.QueryLogMetric(query)
+	if err != nil {
+		c.Error(logMetricError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// logMetricError 将日志指标引擎的错误转换为 API 错误
+func logMetricError(err error) error {
+	switch {
+	case stderrors.Is(err, logmetric.ErrNotFound):
+		return errors.WrapError(err, errors.ErrorTypeNotFound, "log metric not found")
+	case stderrors.Is(err, logmetric.ErrInvalid):
+		return errors.WrapError(err, errors.ErrorTypeInvalidQuery, err.Error())
+	default:
+		return errors.WrapError(err, errors.ErrorTypeInternalError, "failed to query log metric")
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logmetric"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// logMetricLogManager 支持日志指标的日志管理器，记录收到的查询
+type logMetricLogManager struct {
+	MockLogManager
+	query types.LogMetricQuery
+}
+
+func (m *logMetricLogManager) GetLogMetrics() []types.LogMetricInfo {
+	return []types.LogMetricInfo{{Name: "latency", Type: "gauge", Path: "/tmp/logs/api.log", Groups: []string{"GET"}}}
+}
+
+func (m *logMetricLogManager) QueryLogMetric(query types.LogMetricQuery) (*types.LogMetricResult, error) {
+	m.query = query
+	switch query.Name {
+	case "latency":
+		return &types.LogMetricResult{Name: query.Name, Func: query.Func, Step: "1m0s", Series: []types.LogMetricSeries{
+			{Group: "GET", Points: []types.LogMetricPoint{{Time: query.Start, Value: 120}}},
+		}}, nil
+	case "invalid":
+		return nil, fmt.Errorf("%w: quantile must be in (0, 1]", logmetric.ErrInvalid)
+	default:
+		return nil, logmetric.ErrNotFound
+	}
+}
+
+func TestLogMetricsAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := &logMetricLogManager{}
+	server := New(&config.Config{}, manager, NewWebSocketHub())
+	server.setupRoutes()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	w = get("/api/metrics/query?name=latency&func=quantile&q=0.99&from=2026-01-02T09:00:00Z&to=2026-01-02T10:00:00Z&step=1m&group=GET")
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	want := types.LogMetricQuery{
+		Name:     "latency",
+		Func:     "quantile",
+		Quantile: 0.99,
+		Start:    time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC),
+		Step:     time.Minute,
+		Group:    "GET",
+	}
+	if manager.query != want {
+		t.Errorf("查询参数不正确: %+v", manager.query)
+	}
+	var response struct {
+		Success bool                  `json:"success"`
+		Data    types.LogMetricResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if !response.Success || len(response.Data.Series) != 1 || response.Data.Series[0].Points[0].Value != 120 {
+		t.Errorf("响应不正确: %s", w.Body.String())
+	}
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/api/metrics/query", http.StatusBadRequest},
+		{"/api/metrics/query?name=latency&step=abc", http.StatusBadRequest},
+		{"/api/metrics/query?name=latency&from=yesterday", http.StatusBadRequest},
+		{"/api/metrics/query?name=latency&q=high", http.StatusBadRequest},
+		{"/api/metrics/query?name=invalid", http.StatusBadRequest},
+		{"/api/metrics/query?name=missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := get(tt.url); w.Code != tt.code {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", tt.url, tt.code, w.Code)
+		}
+	}
+}
+
+func TestLogMetricsAPIUnsupported(t *testing.T) {
+	server := setupTestServer()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/metrics/query?name=latency", nil)
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	wsClients = metrics.NewGauge("logviewer_websocket_clients",
+		"Connected WebSocket clients.")
+	wsConnections = metrics.NewCounter("logviewer_websocket_connections_total",
+		"WebSocket clients registered since start.")
+	wsBroadcastDropped = metrics.NewCounter("logviewer_websocket_broadcast_dropped_total",
+		"Broadcast messages dropped because the hub's broadcast queue was full.")
+	sseStreams = metrics.NewGauge("logviewer_sse_streams",
+		"Open Server-Sent Events log streams.")
+
+	streamUpdatesDropped = metrics.NewCounter("logviewer_stream_updates_dropped_total",
+		"Log updates dropped for slow WebSocket subscribers.")
+	streamUpdatesCoalesced = metrics.NewCounter("logviewer_stream_updates_coalesced_total",
+		"Log updates merged into a pending update for slow WebSocket subscribers.")
+	streamSlowConsumerDisconnects = metrics.NewCounter("logviewer_stream_slow_consumer_disconnects_total",
+		"WebSocket clients disconnected by the disconnect slow-consumer policy.")
+	streamGaps = metrics.NewCounter("logviewer_stream_gaps_total",
+		"Gap messages sent to clients for ranges they did not receive.")
+)
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// resumePoint 订阅时指定的断线重连续传位置
+type resumePoint struct {
+	offset *int64
+	seq    *uint64
+}
+
+// requested 是否指定了续传位置
+func (r resumePoint) requested() bool {
+	return r.offset != nil || r.seq != nil
+}
+
+// replay 将续传位置到实时推送位置之间的内容放入队列，返回回放结束的位置
+// 无法续传时发送错误并直接切换到实时推送；回放超出积压上限且策略为断开连接时断开客户端，返回 false
+func (c *WebSocketClient) replay(reader interfaces.RangeReader, path string, resume resumePoint, sub *subscription, queue *updateQueue) (int64, bool) {
+	var from int64
+	if resume.offset != nil {
+		from = *resume.offset
+	} else {
+		offset, ok := reader.OffsetForSeq(path, *resume.seq)
+		if !ok {
+			c.sendError("RESUME_FAILED", fmt.Sprintf("Sequence %d is no longer available for: %s", *resume.seq, path))
+			return -1, true
+		}
+		from = offset
+	}
+
+	end, err := replayRange(reader, c.streamingConfig(), path, from, sub.filter.Load(), queue)
+	if errors.Is(err, errSlowConsumer) {
+		if hub, ok := c.hub.(*WebSocketHub); ok {
+			hub.recordStreamMetrics(0, 0, 1, 0)
+		}
+		c.disconnectSlowConsumer(path)
+		return end, false
+	}
+	if err != nil {
+		c.sendError("RESUME_FAILED", err.Error())
+	}
+	return end, true
+}
+
+// replayRange 将 from 到实时推送位置之间的内容放入队列，返回回放结束的位置
+// 超出回放上限时只回放最近的部分，跳过的范围以 gap 通知；出错时已回放的部分仍然有效，没有回放时返回 -1
+// 队列按慢速客户端策略处理积压，策略要求断开连接时返回 errSlowConsumer
+func replayRange(reader interfaces.RangeReader, cfg config.StreamingConfig, path string, from int64, filter *streamFilter, queue *updateQueue) (int64, error) {
+	cfg = cfg.WithDefaults()
+
+	update, liveOffset, err := reader.ReadRange(path, from, cfg.MaxResyncBytes)
+	if err != nil {
+		return -1, err
+	}
+
+	// 只回放最近 maxReplayBytes 字节，之前的部分由客户端按需 resync
+	if liveOffset-from > cfg.MaxReplayBytes {
+		if update, _, err = reader.ReadRange(path, liveOffset-cfg.MaxReplayBytes, cfg.MaxResyncBytes); err != nil {
+			return -1, err
+		}
+		queue.addGap(types.LogUpdate{Path: path}, from, update.StartOffset, 0, "replay_limit")
+	}
+
+	for {
+		if filtered, _ := filter.apply(*update); len(filtered.Entries) > 0 {
+			if queue.push(filtered).disconnect {
+				return update.StartOffset, errSlowConsumer
+			}
+		}
+		if update.EndOffset >= liveOffset || update.EndOffset == update.StartOffset {
+			return update.EndOffset, nil
+		}
+		end := update.EndOffset
+		if update, liveOffset, err = reader.ReadRange(path, end, cfg.MaxResyncBytes); err != nil {
+			return end, err
+		}
+	}
+}
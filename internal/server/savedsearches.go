@@ -0,0 +1,144 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/errors"
+	"github.com/local-log-viewer/internal/middleware"
+	"github.com/local-log-viewer/internal/savedsearch"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// listSavedSearches 列出当前用户的搜索和共享的搜索 API
+func (s *HTTPServer) listSavedSearches(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.savedSearches.List(middleware.CurrentUser(c)),
+	})
+}
+
+// getSavedSearch 获取单个保存的搜索 API
+func (s *HTTPServer) getSavedSearch(c *gin.Context) {
+	search, err := s.savedSearches.Get(c.Param("id"), middleware.CurrentUser(c))
+	if err != nil {
+		c.Error(savedSearchError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    search,
+	})
+}
+
+// createSavedSearch 保存新的搜索 API
+func (s *HTTPServer) createSavedSearch(c *gin.Context) {
+	var search types.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, "invalid request body"))
+		return
+	}
+
+	created, err := s.savedSearches.Create(middleware.CurrentUser(c), search)
+	if err != nil {
+		c.Error(savedSearchError(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// updateSavedSearch 修改保存的搜索 API，只有所有者可以修改
+func (s *HTTPServer) updateSavedSearch(c *gin.Context) {
+	var search types.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, "invalid request body"))
+		return
+	}
+
+	updated, err := s.savedSearches.Update(c.Param("id"), middleware.CurrentUser(c), search)
+	if err != nil {
+		c.Error(savedSearchError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// deleteSavedSearch 删除保存的搜索 API，只有所有者可以删除
+func (s *HTTPServer) deleteSavedSearch(c *gin.Context) {
+	if err := s.savedSearches.Delete(c.Param("id"), middleware.CurrentUser(c)); err != nil {
+		c.Error(savedSearchError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// resolveShortLink 将 /s/<id> 跳转到带有搜索条件的前端页面
+func (s *HTTPServer) resolveShortLink(c *gin.Context) {
+	search, err := s.savedSearches.Get(c.Param("id"), middleware.CurrentUser(c))
+	if err != nil {
+		c.Error(savedSearchError(err))
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/?"+shortLinkQuery(search).Encode())
+}
+
+// shortLinkQuery 生成前端恢复搜索所需的查询参数，参数名与 /api/search 一致
+func shortLinkQuery(search *types.SavedSearch) url.Values {
+	values := url.Values{}
+	for _, path := range search.Paths {
+		values.Add("path", path)
+	}
+	if search.Query != "" {
+		values.Set("query", search.Query)
+	}
+	if search.IsRegex {
+		values.Set("isRegex", strconv.FormatBool(search.IsRegex))
+	}
+	if len(search.Levels) > 0 {
+		values.Set("levels", strings.Join(search.Levels, ","))
+	}
+	if search.Range != "" {
+		values.Set("range", search.Range)
+	} else {
+		if search.StartTime != nil {
+			values.Set("startTime", search.StartTime.Format(time.RFC3339))
+		}
+		if search.EndTime != nil {
+			values.Set("endTime", search.EndTime.Format(time.RFC3339))
+		}
+	}
+	values.Set("savedSearch", search.ID)
+	return values
+}
+
+// savedSearchError 将存储错误转换为对应的 API 错误
+func savedSearchError(err error) error {
+	switch {
+	case stderrors.Is(err, savedsearch.ErrNotFound):
+		return errors.WrapError(err, errors.ErrorTypeNotFound, "saved search not found")
+	case stderrors.Is(err, savedsearch.ErrForbidden):
+		return errors.WrapError(err, errors.ErrorTypeAccessDenied, "only the owner can modify a saved search")
+	case stderrors.Is(err, savedsearch.ErrInvalid):
+		return errors.WrapError(err, errors.ErrorTypeInvalidQuery, err.Error())
+	default:
+		return errors.WrapError(err, errors.ErrorTypeInternalError, "failed to save search")
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"embed"
+	stderrors "errors"
 	"fmt"
 	"io/fs"
 	"net"
@@ -22,7 +23,9 @@ import (
 	"github.com/local-log-viewer/internal/health"
 	"github.com/local-log-viewer/internal/interfaces"
 	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/metrics"
 	"github.com/local-log-viewer/internal/middleware"
+	"github.com/local-log-viewer/internal/savedsearch"
 	"github.com/local-log-viewer/internal/shutdown"
 	"github.com/local-log-viewer/internal/types"
 )
@@ -119,6 +122,7 @@ type HTTPServer struct {
 	wsHub           interfaces.WebSocketHub
 	healthService   *health.HealthService
 	shutdownManager *shutdown.Manager
+	savedSearches   *savedsearch.Store
 }
 
 // New 创建新的HTTP服务器
@@ -133,6 +137,27 @@ func New(cfg *config.Config, logManager interfaces.LogManager, wsHub interfaces.
 	// 创建关闭管理器
 	shutdownManager := shutdown.NewManager(30 * time.Second)
 
+	// 慢速客户端处理策略
+	if hub, ok := wsHub.(*WebSocketHub); ok {
+		hub.SetStreamingConfig(cfg.Streaming)
+	}
+
+	// 告警状态变化推送给所有 WebSocket 客户端
+	if provider, ok := logManager.(interfaces.AlertProvider); ok && wsHub != nil {
+		provider.OnAlert(func(event types.AlertEvent) {
+			wsHub.Broadcast(types.WSMessage{
+				Type: "alert",
+				Data: event,
+			})
+		})
+	}
+
+	// 保存的搜索，加载失败时仍可使用（已有数据在修复文件前不可见）
+	savedSearches, err := savedsearch.NewStore(cfg.Server.DataDir)
+	if err != nil {
+		logger.Error("加载保存的搜索失败", zap.Error(err))
+	}
+
 	return &HTTPServer{
 		config:          cfg,
 		router:          gin.New(), // 使用gin.New()而不是gin.Default()以便自定义中间件
@@ -140,6 +165,7 @@ func New(cfg *config.Config, logManager interfaces.LogManager, wsHub interfaces.
 		wsHub:           wsHub,
 		healthService:   healthService,
 		shutdownManager: shutdownManager,
+		savedSearches:   savedSearches,
 	}
 }
 
@@ -230,7 +256,9 @@ func (s *HTTPServer) shutdownHook(ctx context.Context) error {
 // setupRoutes 设置路由
 func (s *HTTPServer) setupRoutes() {
 	// 添加中间件
+	s.router.Use(middleware.Tracing())
 	s.router.Use(middleware.RequestLogger())
+	s.router.Use(middleware.RequestMetrics())
 	s.router.Use(middleware.ErrorHandler())
 	s.router.Use(middleware.SecurityHeaders())
 	s.router.Use(middleware.IPWhitelist(&s.config.Security))
@@ -260,12 +288,42 @@ func (s *HTTPServer) setupRoutes() {
 		api.GET("/logs/directory", s.getDirectoryFiles)
 		api.GET("/logs/content/*path", s.getLogContent)
 		api.GET("/logs/tail/*path", s.getLogContentFromTail)
+		api.GET("/logs/stream/*path", s.streamLogs)
+		api.PUT("/logs/format/*path", s.setLogFormat)
 		api.GET("/search", s.searchLogs)
+		api.GET("/facets", s.getFacets)
+		api.GET("/histogram", s.getHistogram)
+		api.GET("/correlate", s.correlate)
+		api.GET("/alerts", s.getAlerts)
+		api.GET("/metrics", s.getLogMetrics)
+		api.GET("/metrics/query", s.queryLogMetric)
+		api.GET("/watches", s.getWatchStats)
+		api.GET("/saved-searches", s.listSavedSearches)
+		api.POST("/saved-searches", s.createSavedSearch)
+		api.GET("/saved-searches/:id", s.getSavedSearch)
+		api.PUT("/saved-searches/:id", s.updateSavedSearch)
+		api.DELETE("/saved-searches/:id", s.deleteSavedSearch)
+		api.GET("/annotations", s.listAnnotations)
+		api.POST("/annotations", s.createAnnotation)
+		api.PUT("/annotations/:id", s.updateAnnotation)
+		api.DELETE("/annotations/:id", s.deleteAnnotation)
 		api.GET("/health", s.healthCheck)
 		api.GET("/health/detailed", s.detailedHealthCheck)
 		api.GET("/version", s.getBuildInfo)
 	}
 
+	// 保存的搜索的短链接，跳转到前端并恢复搜索条件
+	s.router.GET("/s/:id", middleware.BasicAuth(&s.config.Security), s.resolveShortLink)
+
+	// Prometheus 指标，使用独立的访问控制
+	if s.config.Metrics.Enabled {
+		path := s.config.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		s.router.GET(path, middleware.MetricsAuth(&s.config.Metrics), s.getMetrics)
+	}
+
 	// WebSocket 路由 - 应用认证中间件
 	ws := s.router.Group("/ws")
 	ws.Use(middleware.BasicAuth(&s.config.Security))
@@ -333,6 +391,12 @@ func (s *HTTPServer) getLogContent(c *gin.Context) {
 		return
 	}
 
+	// 本次请求指定的解析格式
+	ctx, ok := s.readContext(c)
+	if !ok {
+		return
+	}
+
 	// 获取查询参数
 	offsetStr := c.DefaultQuery("offset", "0")
 	limitStr := c.DefaultQuery("limit", "100")
@@ -358,7 +422,7 @@ func (s *HTTPServer) getLogContent(c *gin.Context) {
 	}
 
 	// 读取日志文件内容
-	content, err := s.logManager.ReadLogFile(decodedPath, offset, limit)
+	content, err := s.logManager.ReadLogFile(ctx, decodedPath, offset, limit)
 	if err != nil {
 		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to read log file"))
 		return
@@ -377,6 +441,72 @@ func (s *HTTPServer) getLogContent(c *gin.Context) {
 	})
 }
 
+// readContext 处理 format 查询参数，返回按该格式解析的读取上下文，只对本次请求生效
+// 返回 false 表示已写入错误响应
+func (s *HTTPServer) readContext(c *gin.Context) (context.Context, bool) {
+	ctx := c.Request.Context()
+	format := c.Query("format")
+	if format == "" {
+		return ctx, true
+	}
+
+	reader, ok := s.logManager.(interfaces.FormatReader)
+	if !ok {
+		return ctx, true
+	}
+
+	ctx, err := reader.WithFormat(ctx, format)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid format parameter"))
+		return nil, false
+	}
+	return ctx, true
+}
+
+// setLogFormat 指定文件的日志格式 API，之后对该文件的读取和实时更新都使用此格式，"auto" 恢复自动检测
+func (s *HTTPServer) setLogFormat(c *gin.Context) {
+	path := strings.TrimPrefix(c.Param("path"), "/")
+	if path == "" {
+		c.Error(errors.NewConfigError("path", fmt.Errorf("missing file path parameter")))
+		return
+	}
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid path parameter format"))
+		return
+	}
+
+	var request struct {
+		Format string `json:"format" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, "invalid request body"))
+		return
+	}
+
+	setter, ok := s.logManager.(interfaces.FileFormatSetter)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support format overrides"), errors.ErrorTypeServiceUnavailable, "format overrides are not supported"))
+		return
+	}
+	if err := setter.SetFileFormat(decodedPath, request.Format); err != nil {
+		switch {
+		case stderrors.Is(err, fs.ErrNotExist):
+			c.Error(errors.NewFileNotFoundError(decodedPath, err))
+		case stderrors.Is(err, fs.ErrPermission):
+			c.Error(errors.WrapError(err, errors.ErrorTypeAccessDenied, "path is outside the configured log paths"))
+		default:
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid format"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"path": decodedPath, "format": request.Format},
+	})
+}
+
 // getLogContentFromTail 从文件尾部获取日志内容 API
 func (s *HTTPServer) getLogContentFromTail(c *gin.Context) {
 	// 获取路径参数
@@ -397,6 +527,12 @@ func (s *HTTPServer) getLogContentFromTail(c *gin.Context) {
 		return
 	}
 
+	// 本次请求指定的解析格式
+	ctx, ok := s.readContext(c)
+	if !ok {
+		return
+	}
+
 	// 获取查询参数
 	linesStr := c.DefaultQuery("lines", "100")
 
@@ -415,7 +551,7 @@ func (s *HTTPServer) getLogContentFromTail(c *gin.Context) {
 	}
 
 	// 从文件尾部读取日志内容
-	content, err := s.logManager.ReadLogFileFromTail(decodedPath, lines)
+	content, err := s.logManager.ReadLogFileFromTail(ctx, decodedPath, lines)
 	if err != nil {
 		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to read log file from tail"))
 		return
@@ -436,53 +572,21 @@ func (s *HTTPServer) getLogContentFromTail(c *gin.Context) {
 // searchLogs 搜索日志 API
 func (s *HTTPServer) searchLogs(c *gin.Context) {
 	// 获取查询参数
-	path := c.Query("path")
-	query := c.Query("query")
-	isRegexStr := c.DefaultQuery("isRegex", "false")
-	startTimeStr := c.Query("startTime")
-	endTimeStr := c.Query("endTime")
-	levelsStr := c.Query("levels")
 	offsetStr := c.DefaultQuery("offset", "0")
 	limitStr := c.DefaultQuery("limit", "100")
 
-	// 验证必需参数
-	if path == "" {
-		c.Error(errors.NewSearchError("path", fmt.Errorf("missing path parameter")))
+	searchQuery, err := parseSearchFilters(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if query == "" {
+	// 验证必需参数
+	if searchQuery.Query == "" {
 		c.Error(errors.NewSearchError("query", fmt.Errorf("missing query parameter")))
 		return
 	}
 
-	// 解析参数
-	isRegex := isRegexStr == "true"
-
-	var startTime, endTime time.Time
-	var err error
-
-	if startTimeStr != "" {
-		startTime, err = time.Parse(time.RFC3339, startTimeStr)
-		if err != nil {
-			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid startTime format, should use RFC3339"))
-			return
-		}
-	}
-
-	if endTimeStr != "" {
-		endTime, err = time.Parse(time.RFC3339, endTimeStr)
-		if err != nil {
-			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid endTime format, should use RFC3339"))
-			return
-		}
-	}
-
-	var levels []string
-	if levelsStr != "" {
-		levels = strings.Split(levelsStr, ",")
-	}
-
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil {
 		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid offset parameter"))
@@ -503,29 +607,20 @@ func (s *HTTPServer) searchLogs(c *gin.Context) {
 		limit = 100
 	}
 
-	// 构建搜索查询
-	searchQuery := types.SearchQuery{
-		Path:      path,
-		Query:     query,
-		IsRegex:   isRegex,
-		StartTime: startTime,
-		EndTime:   endTime,
-		Levels:    levels,
-		Offset:    offset,
-		Limit:     limit,
-	}
+	searchQuery.Offset = offset
+	searchQuery.Limit = limit
 
 	// 执行搜索
-	result, err := s.logManager.SearchLogs(searchQuery)
+	result, err := s.logManager.SearchLogs(c.Request.Context(), searchQuery)
 	if err != nil {
 		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to search logs"))
 		return
 	}
 
 	logger.Debug("search completed",
-		zap.String("path", path),
-		zap.String("query", query),
-		zap.Bool("is_regex", isRegex),
+		zap.String("path", searchQuery.Path),
+		zap.String("query", searchQuery.Query),
+		zap.Bool("is_regex", searchQuery.IsRegex),
 		zap.Int64("total_count", result.TotalCount),
 		zap.Int("returned_count", len(result.Entries)),
 	)
@@ -536,6 +631,186 @@ func (s *HTTPServer) searchLogs(c *gin.Context) {
 	})
 }
 
+// getFacets 字段聚合统计 API
+func (s *HTTPServer) getFacets(c *gin.Context) {
+	provider, ok := s.logManager.(interfaces.FacetProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support facets"), errors.ErrorTypeServiceUnavailable, "facets are not supported"))
+		return
+	}
+
+	filters, err := parseSearchFilters(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	field := c.Query("field")
+	if field == "" {
+		c.Error(errors.NewSearchError("field", fmt.Errorf("missing field parameter")))
+		return
+	}
+
+	topN, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid top parameter"))
+		return
+	}
+
+	result, err := provider.GetFacets(c.Request.Context(), types.FacetQuery{
+		SearchQuery: filters,
+		Field:       field,
+		TopN:        topN,
+	})
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to compute facets"))
+		return
+	}
+
+	logger.Debug("facets computed",
+		zap.String("path", filters.Path),
+		zap.String("field", field),
+		zap.Int64("matched_count", result.MatchedCount),
+		zap.Uint64("cardinality", result.Cardinality),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// getHistogram 日志量直方图 API
+func (s *HTTPServer) getHistogram(c *gin.Context) {
+	provider, ok := s.logManager.(interfaces.HistogramProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support histograms"), errors.ErrorTypeServiceUnavailable, "histograms are not supported"))
+		return
+	}
+
+	filters, err := parseSearchFilters(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// from/to 是 startTime/endTime 的简写
+	if from := c.Query("from"); from != "" {
+		filters.StartTime, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid from format, should use RFC3339"))
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		filters.EndTime, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid to format, should use RFC3339"))
+			return
+		}
+	}
+
+	bucket, err := time.ParseDuration(c.DefaultQuery("bucket", "1m"))
+	if err != nil || bucket <= 0 {
+		c.Error(errors.NewSearchError("bucket", fmt.Errorf("invalid bucket parameter, should be a duration such as 1m")))
+		return
+	}
+
+	result, err := provider.GetHistogram(c.Request.Context(), types.HistogramQuery{
+		SearchQuery: filters,
+		Bucket:      bucket,
+		GroupBy:     c.Query("groupBy"),
+	})
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to compute histogram"))
+		return
+	}
+
+	logger.Debug("histogram computed",
+		zap.String("path", filters.Path),
+		zap.String("bucket", result.Bucket),
+		zap.Int("bucket_count", len(result.Buckets)),
+		zap.Bool("from_summary", result.FromSummary),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// getAlerts 告警规则状态 API
+func (s *HTTPServer) getAlerts(c *gin.Context) {
+	provider, ok := s.logManager.(interfaces.AlertProvider)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support alerts"), errors.ErrorTypeServiceUnavailable, "alerts are not supported"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    provider.GetAlerts(),
+	})
+}
+
+// getWatchStats 获取正在监控的文件及每个订阅者的统计 API
+func (s *HTTPServer) getWatchStats(c *gin.Context) {
+	broker, ok := s.logManager.(interfaces.WatchBroker)
+	if !ok {
+		c.Error(errors.WrapError(fmt.Errorf("log manager does not support watch stats"), errors.ErrorTypeServiceUnavailable, "watch stats are not supported"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    broker.GetWatchStats(),
+	})
+}
+
+// getMetrics 以 Prometheus 文本格式输出指标
+func (s *HTTPServer) getMetrics(c *gin.Context) {
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if err := metrics.Default.WriteText(c.Writer); err != nil {
+		logger.Warn("failed to write metrics", zap.Error(err))
+	}
+}
+
+// parseSearchFilters 解析搜索类接口共用的过滤参数: path, query, isRegex, startTime, endTime, levels
+func parseSearchFilters(c *gin.Context) (types.SearchQuery, error) {
+	path := c.Query("path")
+	if path == "" {
+		return types.SearchQuery{}, errors.NewSearchError("path", fmt.Errorf("missing path parameter"))
+	}
+
+	query := types.SearchQuery{
+		Path:    path,
+		Query:   c.Query("query"),
+		IsRegex: c.DefaultQuery("isRegex", "false") == "true",
+	}
+
+	var err error
+	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
+		query.StartTime, err = time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return query, errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid startTime format, should use RFC3339")
+		}
+	}
+
+	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
+		query.EndTime, err = time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return query, errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid endTime format, should use RFC3339")
+		}
+	}
+
+	if levelsStr := c.Query("levels"); levelsStr != "" {
+		query.Levels = strings.Split(levelsStr, ",")
+	}
+
+	return query, nil
+}
+
 // healthCheck 简单健康检查 API
 func (s *HTTPServer) healthCheck(c *gin.Context) {
 	status, _ := s.healthService.GetOverallStatus(c.Request.Context())
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,7 +36,7 @@ func (m *MockLogManager) GetLogPaths() []string {
 }
 
 // ReadLogFileFromTail implements interfaces.LogManager.
-func (m *MockLogManager) ReadLogFileFromTail(path string, lines int) (*types.LogContent, error) {
+func (m *MockLogManager) ReadLogFileFromTail(ctx context.Context, path string, lines int) (*types.LogContent, error) {
 	panic("unimplemented")
 }
 
@@ -42,11 +44,11 @@ func (m *MockLogManager) GetLogFiles() ([]types.LogFile, error) {
 	return m.files, m.err
 }
 
-func (m *MockLogManager) ReadLogFile(path string, offset int64, limit int) (*types.LogContent, error) {
+func (m *MockLogManager) ReadLogFile(ctx context.Context, path string, offset int64, limit int) (*types.LogContent, error) {
 	return m.content, m.err
 }
 
-func (m *MockLogManager) SearchLogs(query types.SearchQuery) (*types.SearchResult, error) {
+func (m *MockLogManager) SearchLogs(ctx context.Context, query types.SearchQuery) (*types.SearchResult, error) {
 	return m.result, m.err
 }
 
@@ -576,3 +578,183 @@ func TestIntegrationAPIFlow(t *testing.T) {
 
 	t.Log("集成测试通过：所有API端点正常工作")
 }
+
+func TestSavedSearchesAPI(t *testing.T) {
+	server := setupTestServer()
+	// 用户名由本机的认证代理传入
+	server.config.Security.TrustedProxies = []string{"127.0.0.1"}
+
+	request := func(method, path, body, user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:50000"
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Remote-User", user)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 创建共享的搜索
+	w := request("POST", "/api/saved-searches",
+		`{"name":"errors","shared":true,"paths":["/tmp/logs/a.log","/tmp/logs/b.log"],"query":"timeout","levels":["ERROR","WARN"],"range":"15m"}`, "alice")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Data types.SavedSearch `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if created.Data.ID == "" || created.Data.Owner != "alice" {
+		t.Fatalf("期望生成ID且所有者为 alice, 得到 %+v", created.Data)
+	}
+	id := created.Data.ID
+
+	// 无效的搜索定义
+	if w := request("POST", "/api/saved-searches", `{"name":"","paths":[]}`, "alice"); w.Code != http.StatusBadRequest {
+		t.Errorf("无效定义期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 其他用户可以读取共享的搜索，但不能修改
+	if w := request("GET", "/api/saved-searches/"+id, "", "bob"); w.Code != http.StatusOK {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if w := request("PUT", "/api/saved-searches/"+id, `{"name":"mine","paths":["/tmp/logs/a.log"]}`, "bob"); w.Code != http.StatusForbidden {
+		t.Errorf("非所有者修改期望状态码 %d, 得到 %d", http.StatusForbidden, w.Code)
+	}
+	if w := request("GET", "/api/saved-searches/unknown", "", "bob"); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的搜索期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+
+	// 短链接跳转到带搜索条件的前端页面
+	w = request("GET", "/s/"+id, "", "bob")
+	if w.Code != http.StatusFound {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusFound, w.Code)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("解析跳转地址失败: %v", err)
+	}
+	query := location.Query()
+	if location.Path != "/" || len(query["path"]) != 2 || query.Get("query") != "timeout" ||
+		query.Get("levels") != "ERROR,WARN" || query.Get("range") != "15m" || query.Get("savedSearch") != id {
+		t.Errorf("跳转地址不正确: %s", location)
+	}
+
+	// 所有者删除
+	if w := request("DELETE", "/api/saved-searches/"+id, "", "alice"); w.Code != http.StatusOK {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if w := request("GET", "/s/"+id, "", "alice"); w.Code != http.StatusNotFound {
+		t.Errorf("删除后期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetWatchStatsUnsupported(t *testing.T) {
+	server := setupTestServer()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/watches", nil)
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestGetMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Security: config.SecurityConfig{EnableAuth: true, Username: "admin", Password: "password123"},
+		Metrics:  config.MetricsConfig{Enabled: true, Path: "/metrics"},
+	}
+	server := New(cfg, &MockLogManager{}, NewWebSocketHub())
+	server.setupRoutes()
+
+	// 本机抓取不需要界面的认证
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:9000"
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type 不正确: %s", contentType)
+	}
+	for _, name := range []string{
+		"# TYPE logviewer_http_request_duration_seconds histogram",
+		"# TYPE logviewer_websocket_clients gauge",
+		"# TYPE logviewer_stream_updates_dropped_total counter",
+		"# TYPE logviewer_search_bytes_scanned_total counter",
+		"# TYPE logviewer_cache_requests_total counter",
+		"# TYPE go_goroutines gauge",
+	} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("指标输出缺少 %q", name)
+		}
+	}
+
+	// 其他地址不能抓取
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	req.RemoteAddr = "10.1.2.3:9000"
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusForbidden, w.Code)
+	}
+}
+
+// recordingClient 记录收到的消息的 WebSocket 客户端
+type recordingClient struct {
+	id       string
+	path     string
+	received chan types.WSMessage
+}
+
+func (c *recordingClient) Send(message types.WSMessage) error {
+	c.received <- message
+	return nil
+}
+func (c *recordingClient) Close() error                  { return nil }
+func (c *recordingClient) GetID() string                 { return c.id }
+func (c *recordingClient) IsSubscribed(path string) bool { return c.path == path }
+
+func TestBroadcastToSubscribers(t *testing.T) {
+	hub := NewWebSocketHub().(*WebSocketHub)
+	if err := hub.Start(); err != nil {
+		t.Fatalf("启动 WebSocket 中心失败: %v", err)
+	}
+	defer hub.Stop()
+
+	viewer := &recordingClient{id: "viewer", path: "/var/log/app.log", received: make(chan types.WSMessage, 1)}
+	other := &recordingClient{id: "other", path: "/var/log/other.log", received: make(chan types.WSMessage, 1)}
+	hub.RegisterClient(viewer)
+	hub.RegisterClient(other)
+
+	// 等待注册完成，注册和广播由同一个循环处理，顺序不确定
+	deadline := time.Now().Add(time.Second)
+	for hub.GetMetrics()["total_connections"].(int64) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.BroadcastToSubscribers("/var/log/app.log", types.WSMessage{Type: "annotation"})
+
+	select {
+	case message := <-viewer.received:
+		if message.Type != "annotation" {
+			t.Errorf("期望消息类型 annotation, 得到 %s", message.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("订阅了该文件的客户端没有收到消息")
+	}
+
+	select {
+	case message := <-other.received:
+		t.Errorf("未订阅该文件的客户端不应收到消息: %+v", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
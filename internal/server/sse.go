@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/errors"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// sseHeartbeatInterval 没有更新时发送心跳注释的间隔，避免代理断开空闲连接
+var sseHeartbeatInterval = 15 * time.Second
+
+// streamLogs 以 Server-Sent Events 推送文件的实时更新 API，事件内容与 WebSocket 的 log_update 相同
+// 支持与 WebSocket 订阅相同的过滤条件，以及通过 Last-Event-ID 断线续传
+func (s *HTTPServer) streamLogs(c *gin.Context) {
+	path := strings.TrimPrefix(c.Param("path"), "/")
+	if path == "" {
+		c.Error(errors.NewConfigError("path", fmt.Errorf("missing file path parameter")))
+		return
+	}
+
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidFormat, "invalid path parameter format"))
+		return
+	}
+
+	spec, err := parseStreamFilter(c)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, err.Error()))
+		return
+	}
+	filter, err := newStreamFilter(spec)
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInvalidQuery, err.Error()))
+		return
+	}
+
+	// 事件ID为更新结束的字节位置，EventSource 重连时通过 Last-Event-ID 请求头带回，curl 可以使用 lastEventId 参数
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	var reader interfaces.RangeReader
+	var resumeFrom int64
+	if lastEventID != "" {
+		if resumeFrom, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || resumeFrom < 0 {
+			c.Error(errors.WrapError(fmt.Errorf("invalid Last-Event-ID: %s", lastEventID), errors.ErrorTypeInvalidFormat, "invalid Last-Event-ID"))
+			return
+		}
+		var ok bool
+		if reader, ok = s.logManager.(interfaces.RangeReader); !ok {
+			c.Error(errors.WrapError(fmt.Errorf("log manager does not support resume"), errors.ErrorTypeServiceUnavailable, "resuming streams is not supported"))
+			return
+		}
+	}
+
+	fullPath, err := resolveLogPath(s.logManager, decodedPath)
+	if err != nil {
+		c.Error(errors.NewFileNotFoundError(decodedPath, err))
+		return
+	}
+
+	updateCh, err := watchLogFile(s.logManager, fullPath, "sse-"+c.ClientIP())
+	if err != nil {
+		c.Error(errors.WrapError(err, errors.ErrorTypeInternalError, "failed to watch log file"))
+		return
+	}
+	defer releaseLogWatch(s.logManager, fullPath, updateCh)
+
+	// 长连接不受服务器写超时限制
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear write deadline for event stream: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用 nginx 缓冲
+	c.Status(http.StatusOK)
+
+	sseStreams.Inc()
+	defer sseStreams.Dec()
+
+	stream := &sseStream{w: c.Writer}
+	if err := stream.comment("connected " + fullPath); err != nil {
+		return
+	}
+
+	// 先回放续传位置之后的内容，再切换到实时推送
+	replayEnd := int64(-1)
+	if reader != nil {
+		cfg := s.config.Streaming.WithDefaults()
+		queue := newUpdateQueue(cfg)
+		if replayEnd, err = replayRange(reader, cfg, fullPath, resumeFrom, filter, queue); err != nil {
+			if err == errSlowConsumer {
+				// 回放超出积压上限且策略为断开连接，通知客户端后结束流
+				stream.event("error", "", map[string]string{"code": "SLOW_CONSUMER", "message": err.Error()})
+				return
+			}
+			stream.event("error", "", map[string]string{"code": "RESUME_FAILED", "message": err.Error()})
+		}
+		if queue.gap != nil {
+			stream.event("gap", "", *queue.gap)
+		}
+		for _, update := range queue.pending {
+			if err := stream.logUpdate(update); err != nil {
+				return
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		var err error
+		select {
+		case update, ok := <-updateCh:
+			if !ok {
+				return
+			}
+			if update.Type == "gap" {
+				err = stream.event("gap", "", gapFromUpdate(update))
+				break
+			}
+			if update, ok = trimReplayed(update, &replayEnd); !ok {
+				continue
+			}
+			if update, ok = filter.apply(update); !ok {
+				continue
+			}
+			err = stream.logUpdate(update)
+
+		case <-heartbeat.C:
+			err = stream.comment("heartbeat")
+
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			log.Printf("Event stream for %s closed: %v", fullPath, err)
+			return
+		}
+	}
+}
+
+// parseStreamFilter 解析实时推送的过滤参数: levels, query, isRegex, field
+// field 可以重复，格式为 字段:操作:值 或 字段:exists，没有任何过滤参数时返回 nil
+func parseStreamFilter(c *gin.Context) (*types.StreamFilter, error) {
+	filter := types.StreamFilter{
+		Query:   c.Query("query"),
+		IsRegex: c.Query("isRegex") == "true",
+	}
+	if levels := c.Query("levels"); levels != "" {
+		for _, level := range strings.Split(levels, ",") {
+			if level = strings.TrimSpace(level); level != "" {
+				filter.Levels = append(filter.Levels, level)
+			}
+		}
+	}
+	for _, field := range c.QueryArray("field") {
+		parts := strings.SplitN(field, ":", 3)
+		switch {
+		case len(parts) == 2 && parts[1] == "exists":
+			filter.Fields = append(filter.Fields, types.FieldPredicate{Field: parts[0], Op: parts[1]})
+		case len(parts) == 3:
+			filter.Fields = append(filter.Fields, types.FieldPredicate{Field: parts[0], Op: parts[1], Value: parts[2]})
+		default:
+			return nil, fmt.Errorf("invalid field filter %q, expected field:op:value", field)
+		}
+	}
+
+	if len(filter.Levels) == 0 && filter.Query == "" && len(filter.Fields) == 0 {
+		return nil, nil
+	}
+	return &filter, nil
+}
+
+// sseStream 写入 Server-Sent Events，每个事件写入后立即刷新
+type sseStream struct {
+	w gin.ResponseWriter
+}
+
+// logUpdate 写入日志更新事件，事件ID为更新结束的字节位置
+func (s *sseStream) logUpdate(update types.LogUpdate) error {
+	return s.event("log_update", strconv.FormatInt(update.EndOffset, 10), update)
+}
+
+// event 写入事件，data 编码为单行 JSON
+func (s *sseStream) event(name, id string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("event: " + name + "\n")
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(payload)
+	b.WriteString("\n\n")
+	return s.write(b.String())
+}
+
+// comment 写入注释行，客户端会忽略，用作心跳
+func (s *sseStream) comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *sseStream) write(text string) error {
+	if _, err := io.WriteString(s.w, text); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
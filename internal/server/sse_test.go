@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// sseEvent 解析后的事件
+type sseEvent struct {
+	name string
+	id   string
+	data map[string]interface{}
+}
+
+// readSSEEvent 读取下一个事件，跳过注释行
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取事件失败: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.name != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatalf("解析事件数据失败: %v", err)
+			}
+		}
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	lm := newResyncLogManager()
+	lm.entries[1].Fields = map[string]interface{}{"status": "503"}
+	server := New(&config.Config{}, lm, NewWebSocketHub())
+	server.setupRoutes()
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET",
+		httpServer.URL+"/api/logs/stream/"+url.PathEscape(logFile)+"?levels=ERROR&field=status:gte:500", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("请求事件流失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("期望 200 text/event-stream, 得到 %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	// 从 Last-Event-ID 回放，内容经过过滤，事件ID为结束位置
+	replayed := readSSEEvent(t, reader)
+	if replayed.name != "log_update" || replayed.id != "39" || replayed.data["type"] != "resync" {
+		t.Errorf("回放事件不正确: %+v", replayed)
+	}
+	if entries := replayed.data["entries"].([]interface{}); len(entries) != 1 {
+		t.Errorf("期望回放 1 条匹配的日志，得到 %d", len(entries))
+	}
+
+	// 已回放的实时更新跳过，之后的更新按过滤条件推送
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Seq: 3, StartOffset: 26, EndOffset: 39,
+		Entries: []types.LogEntry{{Level: "ERROR", Message: "failed", Fields: map[string]interface{}{"status": "503"}}}}
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Seq: 4, StartOffset: 39, EndOffset: 52,
+		Entries: []types.LogEntry{{Level: "ERROR", Message: "not found", Fields: map[string]interface{}{"status": "404"}}}}
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Seq: 5, StartOffset: 52, EndOffset: 70,
+		Entries: []types.LogEntry{{Level: "ERROR", Message: "bad gateway", Fields: map[string]interface{}{"status": "502"}}}}
+	live := readSSEEvent(t, reader)
+	if live.id != "70" || live.data["seq"].(float64) != 5 {
+		t.Errorf("期望序号 5 的实时更新，得到 %+v", live)
+	}
+
+	// 日志管理器丢弃的范围以 gap 事件通知
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "gap", Seq: 9, StartOffset: 70, EndOffset: 120}
+	if gap := readSSEEvent(t, reader); gap.name != "gap" || gap.data["reason"] != "subscriber_backlog" || gap.id != "" {
+		t.Errorf("gap 事件不正确: %+v", gap)
+	}
+}
+
+func TestStreamLogsHeartbeatAndErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	interval := sseHeartbeatInterval
+	sseHeartbeatInterval = 20 * time.Millisecond
+	defer func() { sseHeartbeatInterval = interval }()
+
+	server := New(&config.Config{}, &watchLogManager{updates: make(chan types.LogUpdate)}, NewWebSocketHub())
+	server.setupRoutes()
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	// 参数错误在开始推送前以普通错误响应返回
+	for _, query := range []string{"?field=status", "?query=(&isRegex=true"} {
+		resp, err := http.Get(httpServer.URL + "/api/logs/stream/" + url.PathEscape(logFile) + query)
+		if err != nil {
+			t.Fatalf("请求事件流失败: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: 期望状态码 400, 得到 %d", query, resp.StatusCode)
+		}
+	}
+
+	// 日志管理器不支持续传
+	req, _ := http.NewRequest("GET", httpServer.URL+"/api/logs/stream/"+url.PathEscape(logFile), nil)
+	req.Header.Set("Last-Event-ID", "10")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("请求事件流失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("期望状态码 503, 得到 %d", resp.StatusCode)
+	}
+
+	// 没有更新时发送心跳注释
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, "GET", httpServer.URL+"/api/logs/stream/"+url.PathEscape(logFile), nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("请求事件流失败: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("没有收到心跳: %v", err)
+		}
+		if line == ": heartbeat\n" {
+			break
+		}
+	}
+}
@@ -9,12 +9,18 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/local-log-viewer/internal/config"
 	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/search"
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/tracing"
 	"github.com/local-log-viewer/internal/types"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -36,11 +42,11 @@ var upgrader = websocket.Upgrader{
 
 // WebSocketHub WebSocket中心实现
 type WebSocketHub struct {
-	// 注册的客户端
+	// 注册的客户端，只在持有 mutex 时访问
 	clients map[interfaces.WebSocketClient]bool
 
 	// 广播消息通道
-	broadcast chan types.WSMessage
+	broadcast chan hubMessage
 
 	// 注册客户端通道
 	register chan interfaces.WebSocketClient
@@ -60,19 +66,59 @@ type WebSocketHub struct {
 	messagesSent     int64
 	messagesDropped  int64
 	metricsMutex     sync.RWMutex
+
+	// 慢速客户端处理配置和指标
+	streaming               config.StreamingConfig
+	updatesDropped          int64
+	updatesCoalesced        int64
+	slowConsumerDisconnects int64
+	gapsReported            int64
 }
 
 // NewWebSocketHub 创建新的WebSocket中心
 func NewWebSocketHub() interfaces.WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[interfaces.WebSocketClient]bool),
-		broadcast:  make(chan types.WSMessage, 256),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan interfaces.WebSocketClient, 100),
 		unregister: make(chan interfaces.WebSocketClient, 100),
 		stopCh:     make(chan struct{}),
+		streaming:  config.StreamingConfig{}.WithDefaults(),
 	}
 }
 
+// SetStreamingConfig 设置实时推送的慢速客户端处理策略，只影响之后建立的订阅
+func (h *WebSocketHub) SetStreamingConfig(cfg config.StreamingConfig) {
+	h.metricsMutex.Lock()
+	defer h.metricsMutex.Unlock()
+	h.streaming = cfg.WithDefaults()
+}
+
+// streamingConfig 返回实时推送配置
+func (h *WebSocketHub) streamingConfig() config.StreamingConfig {
+	h.metricsMutex.RLock()
+	defer h.metricsMutex.RUnlock()
+	return h.streaming
+}
+
+// recordStreamMetrics 记录慢速客户端处理的指标
+func (h *WebSocketHub) recordStreamMetrics(dropped, coalesced, disconnects, gaps int) {
+	if dropped == 0 && coalesced == 0 && disconnects == 0 && gaps == 0 {
+		return
+	}
+	h.metricsMutex.Lock()
+	defer h.metricsMutex.Unlock()
+	h.updatesDropped += int64(dropped)
+	h.updatesCoalesced += int64(coalesced)
+	h.slowConsumerDisconnects += int64(disconnects)
+	h.gapsReported += int64(gaps)
+
+	streamUpdatesDropped.Add(float64(dropped))
+	streamUpdatesCoalesced.Add(float64(coalesced))
+	streamSlowConsumerDisconnects.Add(float64(disconnects))
+	streamGaps.Add(float64(gaps))
+}
+
 // Start 启动WebSocket中心
 func (h *WebSocketHub) Start() error {
 	h.mutex.Lock()
@@ -85,7 +131,7 @@ func (h *WebSocketHub) Start() error {
 	// 重新创建通道（如果之前被关闭）
 	h.stopCh = make(chan struct{})
 	h.clients = make(map[interfaces.WebSocketClient]bool)
-	h.broadcast = make(chan types.WSMessage, 256)
+	h.broadcast = make(chan hubMessage, 256)
 	h.register = make(chan interfaces.WebSocketClient, 100)
 	h.unregister = make(chan interfaces.WebSocketClient, 100)
 
@@ -112,6 +158,7 @@ func (h *WebSocketHub) Stop() error {
 
 	// 清空客户端映射
 	h.clients = make(map[interfaces.WebSocketClient]bool)
+	wsClients.Set(0)
 
 	// 关闭停止通道
 	select {
@@ -124,32 +171,43 @@ func (h *WebSocketHub) Stop() error {
 	return nil
 }
 
-// Run 运行WebSocket中心
+// Run 运行WebSocket中心。clients 只在持有 mutex 时访问：注册、注销和发送失败的移除由这里完成，
+// 广播时复制一份客户端列表后在锁外发送，Stop 和 GetMetrics 也通过 mutex 访问
 func (h *WebSocketHub) Run() {
+	// 使用本次启动创建的通道，Stop 之后再次 Start 会替换这些字段
+	h.mutex.RLock()
+	register, unregister, broadcast, stopCh := h.register, h.unregister, h.broadcast, h.stopCh
+	h.mutex.RUnlock()
+
 	for {
 		select {
-		case client := <-h.register:
+		case client := <-register:
+			h.mutex.Lock()
 			h.clients[client] = true
+			total := len(h.clients)
+			h.mutex.Unlock()
 			h.metricsMutex.Lock()
 			h.totalConnections++
 			h.metricsMutex.Unlock()
-			log.Printf("WebSocket client registered: %s (total: %d)", client.GetID(), len(h.clients))
+			wsConnections.Inc()
+			wsClients.Set(float64(total))
+			log.Printf("WebSocket client registered: %s (total: %d)", client.GetID(), total)
 
-		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
-				log.Printf("WebSocket client unregistered: %s (remaining: %d)", client.GetID(), len(h.clients))
+		case client := <-unregister:
+			if remaining, ok := h.removeClient(client); ok {
+				log.Printf("WebSocket client unregistered: %s (remaining: %d)", client.GetID(), remaining)
 			}
 
-		case message := <-h.broadcast:
-			// 广播消息给所有客户端
-			for client := range h.clients {
-				if err := client.Send(message); err != nil {
+		case message := <-broadcast:
+			// 广播消息给所有客户端，指定了文件时只发给订阅了该文件的客户端
+			for _, client := range h.clientList() {
+				if message.path != "" && !client.IsSubscribed(message.path) {
+					continue
+				}
+				if err := client.Send(message.message); err != nil {
 					log.Printf("Error sending message to client %s: %v", client.GetID(), err)
 					// 发送失败，移除客户端
-					delete(h.clients, client)
-					client.Close()
+					h.removeClient(client)
 				} else {
 					h.metricsMutex.Lock()
 					h.messagesSent++
@@ -157,52 +215,110 @@ func (h *WebSocketHub) Run() {
 				}
 			}
 
-		case <-h.stopCh:
+		case <-stopCh:
 			return
 		}
 	}
 }
 
+// clientList 复制当前的客户端列表，发送消息时不持有锁
+func (h *WebSocketHub) clientList() []interfaces.WebSocketClient {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	clients := make([]interfaces.WebSocketClient, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// removeClient 移除并关闭客户端，返回剩余的客户端数；客户端已被移除（例如 Stop 之后）时返回 false
+func (h *WebSocketHub) removeClient(client interfaces.WebSocketClient) (int, bool) {
+	h.mutex.Lock()
+	_, ok := h.clients[client]
+	delete(h.clients, client)
+	remaining := len(h.clients)
+	h.mutex.Unlock()
+	if !ok {
+		return remaining, false
+	}
+	client.Close()
+	wsClients.Set(float64(remaining))
+	return remaining, true
+}
+
 // BroadcastLogUpdate 广播日志更新
 func (h *WebSocketHub) BroadcastLogUpdate(update types.LogUpdate) {
-	message := types.WSMessage{
+	h.Broadcast(types.WSMessage{
 		Type: "log_update",
 		Data: update,
-	}
+	})
+}
+
+// Broadcast 向所有客户端广播消息
+func (h *WebSocketHub) Broadcast(message types.WSMessage) {
+	h.enqueue(hubMessage{message: message})
+}
+
+// BroadcastToSubscribers 向订阅了指定文件的客户端广播消息
+func (h *WebSocketHub) BroadcastToSubscribers(path string, message types.WSMessage) {
+	h.enqueue(hubMessage{path: path, message: message})
+}
+
+// enqueue 将消息放入广播通道，通道满时丢弃
+func (h *WebSocketHub) enqueue(message hubMessage) {
+	h.mutex.RLock()
+	broadcast := h.broadcast
+	h.mutex.RUnlock()
 
 	select {
-	case h.broadcast <- message:
+	case broadcast <- message:
 		// 发送成功
 	default:
 		// 通道满，记录指标并发出警告
 		h.metricsMutex.Lock()
 		h.messagesDropped++
 		h.metricsMutex.Unlock()
+		wsBroadcastDropped.Inc()
 		log.Printf("Warning: Broadcast channel is full (size: %d/%d), message dropped. Consider increasing buffer size or clients are too slow.",
-			len(h.broadcast), cap(h.broadcast))
+			len(broadcast), cap(broadcast))
 	}
 }
 
 // GetMetrics 获取性能指标
 func (h *WebSocketHub) GetMetrics() map[string]interface{} {
+	h.mutex.RLock()
+	activeConnections := len(h.clients)
+	broadcast := h.broadcast
+	h.mutex.RUnlock()
+
 	h.metricsMutex.RLock()
 	defer h.metricsMutex.RUnlock()
 
 	return map[string]interface{}{
-		"total_connections":     h.totalConnections,
-		"active_connections":    len(h.clients),
-		"messages_sent":         h.messagesSent,
-		"messages_dropped":      h.messagesDropped,
-		"broadcast_capacity":    cap(h.broadcast),
-		"broadcast_queue_size":  len(h.broadcast),
-		"broadcast_utilization": float64(len(h.broadcast)) / float64(cap(h.broadcast)) * 100,
+		"total_connections":         h.totalConnections,
+		"active_connections":        activeConnections,
+		"messages_sent":             h.messagesSent,
+		"messages_dropped":          h.messagesDropped,
+		"broadcast_capacity":        cap(broadcast),
+		"broadcast_queue_size":      len(broadcast),
+		"broadcast_utilization":     float64(len(broadcast)) / float64(cap(broadcast)) * 100,
+		"slow_consumer_policy":      h.streaming.SlowConsumerPolicy,
+		"updates_dropped":           h.updatesDropped,
+		"updates_coalesced":         h.updatesCoalesced,
+		"slow_consumer_disconnects": h.slowConsumerDisconnects,
+		"gaps_reported":             h.gapsReported,
 	}
 }
 
 // RegisterClient 注册客户端
 func (h *WebSocketHub) RegisterClient(client interfaces.WebSocketClient) {
+	h.mutex.RLock()
+	register := h.register
+	h.mutex.RUnlock()
+
 	select {
-	case h.register <- client:
+	case register <- client:
 	default:
 		log.Printf("Register channel is full, dropping client registration")
 	}
@@ -210,18 +326,87 @@ func (h *WebSocketHub) RegisterClient(client interfaces.WebSocketClient) {
 
 // UnregisterClient 注销客户端
 func (h *WebSocketHub) UnregisterClient(client interfaces.WebSocketClient) {
+	h.mutex.RLock()
+	unregister := h.unregister
+	h.mutex.RUnlock()
+
 	select {
-	case h.unregister <- client:
+	case unregister <- client:
 	default:
 		log.Printf("Unregister channel is full, dropping client unregistration")
 	}
 }
 
+// hubMessage 广播通道中的消息，path 非空时只发给订阅了该文件的客户端
+type hubMessage struct {
+	path    string
+	message types.WSMessage
+}
+
 // WebSocketMessage WebSocket消息
 type WebSocketMessage struct {
-	Type string      `json:"type"`
-	Path string      `json:"path,omitempty"`
-	Data interface{} `json:"data,omitempty"`
+	Type   string              `json:"type"`
+	Path   string              `json:"path,omitempty"`
+	Filter *types.StreamFilter `json:"filter,omitempty"` // subscribe 和 update_filter 使用的过滤条件
+	Offset int64               `json:"offset,omitempty"` // resync 开始的字节位置
+
+	// subscribe 断线重连续传的位置，fromOffset 优先于 lastSeq
+	FromOffset *int64      `json:"fromOffset,omitempty"`
+	LastSeq    *uint64     `json:"lastSeq,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// subscription 单个文件的订阅，过滤条件可以在监听过程中替换
+type subscription struct {
+	cancel context.CancelFunc
+	filter atomic.Pointer[streamFilter]
+}
+
+// streamFilter 订阅使用的过滤条件
+type streamFilter struct {
+	spec  types.StreamFilter
+	match func(entry *types.LogEntry) bool
+}
+
+// newStreamFilter 编译过滤条件，spec 为 nil 时不过滤
+func newStreamFilter(spec *types.StreamFilter) (*streamFilter, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	match, err := search.NewStreamMatcher(*spec)
+	if err != nil {
+		return nil, err
+	}
+	return &streamFilter{spec: *spec, match: match}, nil
+}
+
+// apply 过滤追加和补齐的日志条目，没有条目需要发送时返回 false
+// 补齐的更新即使没有匹配的条目也需要发送，告知客户端范围已补齐
+func (f *streamFilter) apply(update types.LogUpdate) (types.LogUpdate, bool) {
+	if f == nil || (update.Type != "append" && update.Type != "resync") {
+		return update, true
+	}
+
+	entries := make([]types.LogEntry, 0, len(update.Entries))
+	for i := range update.Entries {
+		if f.match(&update.Entries[i]) {
+			entries = append(entries, update.Entries[i])
+		}
+	}
+	update.Entries = entries
+	if len(entries) == 0 && update.Type == "append" {
+		return update, false
+	}
+	return update, true
+}
+
+// filterSpec 返回当前的过滤条件，用于确认消息
+func (f *streamFilter) filterSpec() *types.StreamFilter {
+	if f == nil {
+		return nil
+	}
+	spec := f.spec
+	return &spec
 }
 
 // WebSocketClient WebSocket客户端实现
@@ -242,7 +427,7 @@ type WebSocketClient struct {
 	logManager interfaces.LogManager
 
 	// 订阅管理
-	subscriptions map[string]context.CancelFunc
+	subscriptions map[string]*subscription
 	subMutex      sync.Mutex
 
 	// 关闭状态
@@ -258,7 +443,7 @@ func NewWebSocketClient(conn *websocket.Conn, hub interfaces.WebSocketHub, id st
 		hub:           hub,
 		id:            id,
 		logManager:    logManager,
-		subscriptions: make(map[string]context.CancelFunc),
+		subscriptions: make(map[string]*subscription),
 	}
 }
 
@@ -267,20 +452,38 @@ func (c *WebSocketClient) GetID() string {
 	return c.id
 }
 
+// IsSubscribed 是否订阅了指定文件
+func (c *WebSocketClient) IsSubscribed(path string) bool {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	for subscribed := range c.subscriptions {
+		if abs, err := filepath.Abs(subscribed); err == nil && abs == target {
+			return true
+		}
+	}
+	return false
+}
+
 // Send 发送消息
 func (c *WebSocketClient) Send(message types.WSMessage) error {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
 	if c.closed {
-		return fmt.Errorf("client connection is closed")
+		return errClientClosed
 	}
 
 	select {
 	case c.send <- message:
 		return nil
 	default:
-		return fmt.Errorf("send channel is full")
+		return errSendBufferFull
 	}
 }
 
@@ -297,11 +500,11 @@ func (c *WebSocketClient) Close() error {
 
 	// 取消所有订阅
 	c.subMutex.Lock()
-	for path, cancel := range c.subscriptions {
-		cancel()
+	for path, sub := range c.subscriptions {
+		sub.cancel()
 		log.Printf("Cancelled subscription for: %s", path)
 	}
-	c.subscriptions = make(map[string]context.CancelFunc)
+	c.subscriptions = make(map[string]*subscription)
 	c.subMutex.Unlock()
 
 	close(c.send)
@@ -353,11 +556,17 @@ func (c *WebSocketClient) handleMessage(message []byte) {
 
 	switch msg.Type {
 	case "subscribe":
-		c.handleSubscribe(msg.Path)
+		c.handleSubscribe(msg.Path, msg.Filter, resumePoint{offset: msg.FromOffset, seq: msg.LastSeq})
+
+	case "update_filter":
+		c.handleUpdateFilter(msg.Path, msg.Filter)
 
 	case "unsubscribe":
 		c.handleUnsubscribe(msg.Path)
 
+	case "resync":
+		c.handleResync(msg.Path, msg.Offset)
+
 	case "ping":
 		// 响应 ping 消息，发送 pong 回复
 		log.Printf("Received ping from client %s", c.GetID())
@@ -376,6 +585,19 @@ func (c *WebSocketClient) handleMessage(message []byte) {
 
 // normalizePath 规范化路径
 func (c *WebSocketClient) normalizePath(path string) (string, error) {
+	return resolveLogPath(c.logManager, path)
+}
+
+// resolveLogPath 将路径解析为存在的文件，相对路径在配置的日志目录中查找
+func resolveLogPath(logManager interfaces.LogManager, path string) (string, error) {
+	// 远程路径和本地归档中的路径由日志管理器所属的日志源检查
+	if source.IsRemote(path) {
+		return path, nil
+	}
+	if _, _, ok := source.SplitArchivePath(path); ok {
+		return path, nil
+	}
+
 	// 如果是绝对路径，直接返回
 	if filepath.IsAbs(path) {
 		if _, err := os.Stat(path); err != nil {
@@ -385,7 +607,7 @@ func (c *WebSocketClient) normalizePath(path string) (string, error) {
 	}
 
 	// 如果是相对路径，尝试从配置的日志目录中查找
-	logPaths := c.logManager.GetLogPaths()
+	logPaths := logManager.GetLogPaths()
 	for _, logDir := range logPaths {
 		fullPath := filepath.Join(logDir, path)
 		if _, err := os.Stat(fullPath); err == nil {
@@ -397,13 +619,34 @@ func (c *WebSocketClient) normalizePath(path string) (string, error) {
 	return "", fmt.Errorf("file not found in any log directory: %s", path)
 }
 
-// handleSubscribe 处理订阅请求
-func (c *WebSocketClient) handleSubscribe(path string) {
+// handleSubscribe 处理订阅请求，filter 为 nil 时推送所有追加的日志
+// 指定了续传位置时先回放该位置之后的内容，再切换到实时推送
+func (c *WebSocketClient) handleSubscribe(path string, filter *types.StreamFilter, resume resumePoint) {
 	if path == "" {
 		c.sendError("INVALID_PATH", "Path is required for subscribe")
 		return
 	}
 
+	compiled, err := newStreamFilter(filter)
+	if err != nil {
+		c.sendError("INVALID_FILTER", err.Error())
+		return
+	}
+
+	reader, canResume := c.logManager.(interfaces.RangeReader)
+	if resume.requested() && !canResume {
+		c.sendError("RESUME_UNSUPPORTED", "Log manager does not support resuming subscriptions")
+		return
+	}
+
+	// 客户端已关闭时不再订阅
+	c.mutex.RLock()
+	closed := c.closed
+	c.mutex.RUnlock()
+	if closed {
+		return
+	}
+
 	log.Printf("Starting file watch for: %s", path)
 
 	// 规范化路径
@@ -415,7 +658,7 @@ func (c *WebSocketClient) handleSubscribe(path string) {
 	}
 
 	// 开始监控指定文件
-	updateCh, err := c.logManager.WatchFile(normalizedPath)
+	updateCh, err := watchLogFile(c.logManager, normalizedPath, c.id)
 	if err != nil {
 		log.Printf("Failed to watch file %s: %v", normalizedPath, err)
 		c.sendError("WATCH_FAILED", fmt.Sprintf("Failed to watch file: %v", err))
@@ -426,58 +669,69 @@ func (c *WebSocketClient) handleSubscribe(path string) {
 
 	// 创建可取消的 context
 	ctx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{cancel: cancel}
+	sub.filter.Store(compiled)
 
+	// 与 Close 持有同一把锁：监控期间客户端已关闭时立即释放更新通道，
+	// 否则登记订阅，之后的 Close 会取消它
+	c.mutex.Lock()
+	if c.closed {
+		releaseLogWatch(c.logManager, normalizedPath, updateCh)
+		c.mutex.Unlock()
+		cancel()
+		return
+	}
 	c.subMutex.Lock()
 	// 取消旧订阅（如果存在）
-	if oldCancel, exists := c.subscriptions[normalizedPath]; exists {
+	if old, exists := c.subscriptions[normalizedPath]; exists {
 		log.Printf("Cancelling existing subscription for: %s", normalizedPath)
-		oldCancel()
+		old.cancel()
 	}
-	c.subscriptions[normalizedPath] = cancel
+	c.subscriptions[normalizedPath] = sub
 	c.subMutex.Unlock()
+	c.mutex.Unlock()
 
 	// 发送订阅成功确认
 	c.Send(types.WSMessage{
 		Type: "subscribed",
-		Data: map[string]string{"path": normalizedPath},
+		Data: map[string]interface{}{"path": normalizedPath, "filter": compiled.filterSpec()},
 	})
 
 	// 在新的 goroutine 中监听文件更新
 	go func() {
 		log.Printf("Starting update listener goroutine for: %s", normalizedPath)
 		defer log.Printf("Update listener goroutine ended for: %s", normalizedPath)
+		defer releaseLogWatch(c.logManager, normalizedPath, updateCh)
 
-		for {
-			select {
-			case update, ok := <-updateCh:
-				if !ok {
-					log.Printf("Update channel closed for: %s", normalizedPath)
-					return
-				}
-
-				log.Printf("Received file update for %s: %d entries", update.Path, len(update.Entries))
-
-				// 将更新发送给这个客户端
-				wsMessage := types.WSMessage{
-					Type: "log_update",
-					Data: update,
-				}
-
-				if err := c.Send(wsMessage); err != nil {
-					log.Printf("Failed to send log update: %v", err)
-					return
-				}
-
-				log.Printf("Successfully sent log update to client")
-
-			case <-ctx.Done():
-				log.Printf("Subscription cancelled for: %s", normalizedPath)
+		queue := newUpdateQueue(c.streamingConfig())
+		var replayEnd int64 = -1
+		if resume.requested() {
+			var ok bool
+			if replayEnd, ok = c.replay(reader, normalizedPath, resume, sub, queue); !ok {
 				return
 			}
 		}
+		c.streamUpdates(ctx, normalizedPath, sub, updateCh, queue, replayEnd)
 	}()
 }
 
+// watchLogFile 订阅文件更新，日志管理器支持时使用订阅者名称，用于统计
+func watchLogFile(logManager interfaces.LogManager, path, subscriber string) (<-chan types.LogUpdate, error) {
+	if broker, ok := logManager.(interfaces.WatchBroker); ok {
+		return broker.WatchFileAs(path, subscriber)
+	}
+	return logManager.WatchFile(path)
+}
+
+// releaseLogWatch 订阅结束时释放更新通道，最后一个订阅者释放后日志管理器停止监控文件
+func releaseLogWatch(logManager interfaces.LogManager, path string, updateCh <-chan types.LogUpdate) {
+	if broker, ok := logManager.(interfaces.WatchBroker); ok {
+		if err := broker.UnwatchFile(path, updateCh); err != nil {
+			log.Printf("Failed to release watch for %s: %v", path, err)
+		}
+	}
+}
+
 // handleUnsubscribe 处理取消订阅请求
 func (c *WebSocketClient) handleUnsubscribe(path string) {
 	if path == "" {
@@ -493,8 +747,8 @@ func (c *WebSocketClient) handleUnsubscribe(path string) {
 	}
 
 	c.subMutex.Lock()
-	if cancel, exists := c.subscriptions[normalizedPath]; exists {
-		cancel()
+	if sub, exists := c.subscriptions[normalizedPath]; exists {
+		sub.cancel()
 		delete(c.subscriptions, normalizedPath)
 		log.Printf("Unsubscribed from file: %s", normalizedPath)
 		c.subMutex.Unlock()
@@ -511,6 +765,42 @@ func (c *WebSocketClient) handleUnsubscribe(path string) {
 	}
 }
 
+// handleUpdateFilter 替换已有订阅的过滤条件，不重新订阅，filter 为 nil 时清除过滤
+func (c *WebSocketClient) handleUpdateFilter(path string, filter *types.StreamFilter) {
+	if path == "" {
+		c.sendError("INVALID_PATH", "Path is required for update_filter")
+		return
+	}
+
+	compiled, err := newStreamFilter(filter)
+	if err != nil {
+		c.sendError("INVALID_FILTER", err.Error())
+		return
+	}
+
+	normalizedPath, err := c.normalizePath(path)
+	if err != nil {
+		normalizedPath = path
+	}
+
+	c.subMutex.Lock()
+	sub, exists := c.subscriptions[normalizedPath]
+	if exists {
+		sub.filter.Store(compiled)
+	}
+	c.subMutex.Unlock()
+
+	if !exists {
+		c.sendError("NOT_SUBSCRIBED", fmt.Sprintf("No active subscription for: %s", normalizedPath))
+		return
+	}
+
+	c.Send(types.WSMessage{
+		Type: "filter_updated",
+		Data: map[string]interface{}{"path": normalizedPath, "filter": compiled.filterSpec()},
+	})
+}
+
 // sendError 发送错误消息
 func (c *WebSocketClient) sendError(code, message string) {
 	c.Send(types.WSMessage{
@@ -540,8 +830,13 @@ func (c *WebSocketClient) writePump() {
 				return
 			}
 
-			// 发送JSON消息
-			if err := c.conn.WriteJSON(message); err != nil {
+			// 发送JSON消息，每条消息一个 span，慢速网络或大消息在追踪中可见
+			_, span := tracing.Start(context.Background(), "websocket.send",
+				attribute.String("websocket.client_id", c.id),
+				attribute.String("websocket.message_type", message.Type))
+			err := c.conn.WriteJSON(message)
+			tracing.End(span, err)
+			if err != nil {
 				log.Printf("Error writing JSON message: %v", err)
 				return
 			}
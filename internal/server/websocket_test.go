@@ -0,0 +1,397 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// watchLogManager 返回可控更新通道的日志管理器
+type watchLogManager struct {
+	MockLogManager
+	updates chan types.LogUpdate
+}
+
+func (m *watchLogManager) WatchFile(path string) (<-chan types.LogUpdate, error) {
+	return m.updates, nil
+}
+
+// resyncLogManager 支持按范围重新读取的日志管理器
+type resyncLogManager struct {
+	watchLogManager
+	entries    []types.LogEntry
+	liveOffset int64
+	seqOffsets map[uint64]int64
+}
+
+func (m *resyncLogManager) ReadRange(path string, offset, maxBytes int64) (*types.LogUpdate, int64, error) {
+	update := &types.LogUpdate{Path: path, Type: "resync", Seq: 7, StartOffset: offset, EndOffset: offset}
+	for _, entry := range m.entries {
+		if entry.ByteOffset >= offset && entry.ByteOffset < offset+maxBytes {
+			if len(update.Entries) == 0 {
+				update.StartOffset = entry.ByteOffset
+			}
+			update.Entries = append(update.Entries, entry)
+			update.EndOffset = entry.ByteOffset + int64(len(entry.Raw)) + 1
+		}
+	}
+	return update, m.liveOffset, nil
+}
+
+func (m *resyncLogManager) OffsetForSeq(path string, seq uint64) (int64, bool) {
+	offset, ok := m.seqOffsets[seq]
+	return offset, ok
+}
+
+// newResyncLogManager 返回包含三行日志的日志管理器
+func newResyncLogManager() *resyncLogManager {
+	return &resyncLogManager{
+		watchLogManager: watchLogManager{updates: make(chan types.LogUpdate, 10)},
+		entries: []types.LogEntry{
+			{ByteOffset: 0, Raw: "INFO started", Level: "INFO", Message: "started"},
+			{ByteOffset: 13, Raw: "ERROR failed", Level: "ERROR", Message: "failed"},
+			{ByteOffset: 26, Raw: "INFO retried", Level: "INFO", Message: "retried"},
+		},
+		liveOffset: 39,
+		seqOffsets: map[uint64]int64{3: 26},
+	}
+}
+
+// dialTestWebSocket 启动测试服务器并建立 WebSocket 连接
+func dialTestWebSocket(t *testing.T, lm interfaces.LogManager) *websocket.Conn {
+	gin.SetMode(gin.TestMode)
+	hub := NewWebSocketHub().(*WebSocketHub)
+	hub.SetStreamingConfig(config.StreamingConfig{MaxResyncBytes: 20, MaxReplayBytes: 30})
+	if err := hub.Start(); err != nil {
+		t.Fatalf("启动 WebSocket 中心失败: %v", err)
+	}
+	t.Cleanup(func() { hub.Stop() })
+
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) { HandleWebSocketConnection(c, hub, lm) })
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("连接 WebSocket 失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readMessageOfType 读取下一条指定类型的消息
+func readMessageOfType(t *testing.T, conn *websocket.Conn, messageType string) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var message map[string]interface{}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("等待 %s 消息失败: %v", messageType, err)
+		}
+		if message["type"] == messageType {
+			return message
+		}
+	}
+}
+
+// updateMessages 返回日志更新消息中的日志内容
+func updateMessages(message map[string]interface{}) []string {
+	var messages []string
+	data := message["data"].(map[string]interface{})
+	for _, entry := range data["entries"].([]interface{}) {
+		messages = append(messages, entry.(map[string]interface{})["message"].(string))
+	}
+	return messages
+}
+
+func TestWebSocket_SubscribeWithFilter(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	lm := &watchLogManager{updates: make(chan types.LogUpdate, 10)}
+	conn := dialTestWebSocket(t, lm)
+
+	update := types.LogUpdate{Path: logFile, Type: "append", Entries: []types.LogEntry{
+		{Level: "INFO", Message: "request done", Fields: map[string]interface{}{"status": "200"}},
+		{Level: "ERROR", Message: "upstream timeout", Fields: map[string]interface{}{"status": "504"}},
+		{Level: "WARN", Message: "slow request", Fields: map[string]interface{}{"status": "200"}},
+	}}
+
+	// 按级别过滤
+	conn.WriteJSON(map[string]interface{}{
+		"type":   "subscribe",
+		"path":   logFile,
+		"filter": map[string]interface{}{"levels": []string{"ERROR", "WARN"}},
+	})
+	readMessageOfType(t, conn, "subscribed")
+
+	lm.updates <- update
+	got := updateMessages(readMessageOfType(t, conn, "log_update"))
+	if len(got) != 2 || got[0] != "upstream timeout" || got[1] != "slow request" {
+		t.Errorf("级别过滤结果不正确: %v", got)
+	}
+
+	// 修改过滤条件，不需要重新订阅
+	conn.WriteJSON(map[string]interface{}{
+		"type": "update_filter",
+		"path": logFile,
+		"filter": map[string]interface{}{
+			"fields": []map[string]string{{"field": "status", "op": "gte", "value": "500"}},
+		},
+	})
+	readMessageOfType(t, conn, "filter_updated")
+
+	// 全部被过滤的更新不发送，下一条消息应来自后续的更新
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Entries: update.Entries[:1]}
+	lm.updates <- update
+	got = updateMessages(readMessageOfType(t, conn, "log_update"))
+	if len(got) != 1 || got[0] != "upstream timeout" {
+		t.Errorf("字段过滤结果不正确: %v", got)
+	}
+
+	// 无效的过滤条件不影响当前订阅
+	conn.WriteJSON(map[string]interface{}{
+		"type":   "update_filter",
+		"path":   logFile,
+		"filter": map[string]interface{}{"query": "(", "isRegex": true},
+	})
+	errorMessage := readMessageOfType(t, conn, "error")
+	if code := errorMessage["data"].(map[string]interface{})["code"]; code != "INVALID_FILTER" {
+		t.Errorf("期望错误码 INVALID_FILTER, 得到 %v", code)
+	}
+
+	// 清除过滤条件
+	conn.WriteJSON(map[string]interface{}{"type": "update_filter", "path": logFile})
+	readMessageOfType(t, conn, "filter_updated")
+	lm.updates <- update
+	if got := updateMessages(readMessageOfType(t, conn, "log_update")); len(got) != 3 {
+		t.Errorf("清除过滤后期望 3 条日志, 得到 %v", got)
+	}
+}
+
+func TestWebSocket_Resync(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	lm := newResyncLogManager()
+	conn := dialTestWebSocket(t, lm)
+
+	// 未订阅时不能补齐
+	conn.WriteJSON(map[string]interface{}{"type": "resync", "path": logFile, "offset": 0})
+	if code := readMessageOfType(t, conn, "error")["data"].(map[string]interface{})["code"]; code != "NOT_SUBSCRIBED" {
+		t.Errorf("期望错误码 NOT_SUBSCRIBED, 得到 %v", code)
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"type":   "subscribe",
+		"path":   logFile,
+		"filter": map[string]interface{}{"levels": []string{"ERROR"}},
+	})
+	readMessageOfType(t, conn, "subscribed")
+
+	// 补齐的内容应用订阅的过滤条件，超出单次上限的部分以 gap 通知
+	conn.WriteJSON(map[string]interface{}{"type": "resync", "path": logFile, "offset": 0})
+	message := readMessageOfType(t, conn, "log_update")
+	data := message["data"].(map[string]interface{})
+	if data["type"] != "resync" || data["seq"].(float64) != 7 || data["endOffset"].(float64) != 26 {
+		t.Errorf("补齐更新不正确: %v", data)
+	}
+	if got := updateMessages(message); len(got) != 1 || got[0] != "failed" {
+		t.Errorf("补齐内容过滤结果不正确: %v", got)
+	}
+
+	gap := readMessageOfType(t, conn, "gap")["data"].(map[string]interface{})
+	if gap["startOffset"].(float64) != 26 || gap["endOffset"].(float64) != 39 || gap["reason"] != "resync_limit" {
+		t.Errorf("剩余范围通知不正确: %v", gap)
+	}
+}
+
+func TestWebSocket_ResumeSubscription(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	lm := newResyncLogManager()
+	conn := dialTestWebSocket(t, lm)
+
+	// 从字节位置续传，超出回放上限的部分以 gap 通知
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "path": logFile, "fromOffset": 0})
+	readMessageOfType(t, conn, "subscribed")
+
+	gap := readMessageOfType(t, conn, "gap")["data"].(map[string]interface{})
+	if gap["startOffset"].(float64) != 0 || gap["endOffset"].(float64) != 13 || gap["reason"] != "replay_limit" {
+		t.Errorf("跳过的范围通知不正确: %v", gap)
+	}
+	if got := updateMessages(readMessageOfType(t, conn, "log_update")); len(got) != 2 || got[0] != "failed" || got[1] != "retried" {
+		t.Errorf("回放内容不正确: %v", got)
+	}
+
+	// 已被回放覆盖的实时更新不重复发送
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Seq: 3, StartOffset: 26, EndOffset: 39,
+		Entries: []types.LogEntry{{Message: "retried"}}}
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "append", Seq: 4, StartOffset: 39, EndOffset: 52,
+		Entries: []types.LogEntry{{Message: "recovered"}}}
+	if got := updateMessages(readMessageOfType(t, conn, "log_update")); len(got) != 1 || got[0] != "recovered" {
+		t.Errorf("期望回放之后的实时更新，得到 %v", got)
+	}
+
+	// 按最后收到的序号续传
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "path": logFile, "lastSeq": 3})
+	readMessageOfType(t, conn, "subscribed")
+	if got := updateMessages(readMessageOfType(t, conn, "log_update")); len(got) != 1 || got[0] != "retried" {
+		t.Errorf("按序号续传的内容不正确: %v", got)
+	}
+
+	// 序号不可用时报告错误，订阅仍然切换到实时推送
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "path": logFile, "lastSeq": 99})
+	readMessageOfType(t, conn, "subscribed")
+	if code := readMessageOfType(t, conn, "error")["data"].(map[string]interface{})["code"]; code != "RESUME_FAILED" {
+		t.Errorf("期望错误码 RESUME_FAILED, 得到 %v", code)
+	}
+}
+
+// brokerLogManager 为每个订阅者返回独立通道并记录释放的日志管理器
+type brokerLogManager struct {
+	watchLogManager
+	mutex       sync.Mutex
+	subscribers []string
+	released    []<-chan types.LogUpdate
+	onWatch     func() // 订阅建立时调用，用于模拟订阅期间客户端关闭
+}
+
+func (m *brokerLogManager) WatchFileAs(path, subscriber string) (<-chan types.LogUpdate, error) {
+	if m.onWatch != nil {
+		m.onWatch()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.subscribers = append(m.subscribers, subscriber)
+	return m.updates, nil
+}
+
+func (m *brokerLogManager) UnwatchFile(path string, updates <-chan types.LogUpdate) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.released = append(m.released, updates)
+	return nil
+}
+
+func (m *brokerLogManager) GetWatchStats() []types.WatchStats {
+	return nil
+}
+
+func TestWebSocket_WatchBroker(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	lm := &brokerLogManager{watchLogManager: watchLogManager{updates: make(chan types.LogUpdate, 10)}}
+	conn := dialTestWebSocket(t, lm)
+
+	conn.WriteJSON(map[string]interface{}{"type": "subscribe", "path": logFile})
+	readMessageOfType(t, conn, "subscribed")
+
+	lm.mutex.Lock()
+	if len(lm.subscribers) != 1 || !strings.HasPrefix(lm.subscribers[0], "client_") {
+		t.Errorf("期望以客户端ID订阅，得到 %v", lm.subscribers)
+	}
+	lm.mutex.Unlock()
+
+	// 日志管理器丢弃的范围转换为 gap 消息
+	lm.updates <- types.LogUpdate{Path: logFile, Type: "gap", Seq: 9, StartOffset: 100, EndOffset: 250}
+	gap := readMessageOfType(t, conn, "gap")["data"].(map[string]interface{})
+	if gap["startOffset"].(float64) != 100 || gap["endOffset"].(float64) != 250 || gap["reason"] != "subscriber_backlog" {
+		t.Errorf("gap 消息不正确: %v", gap)
+	}
+
+	// 取消订阅后释放更新通道
+	conn.WriteJSON(map[string]interface{}{"type": "unsubscribe", "path": logFile})
+	readMessageOfType(t, conn, "unsubscribed")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		lm.mutex.Lock()
+		released := len(lm.released)
+		lm.mutex.Unlock()
+		if released == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("取消订阅后没有释放更新通道")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newServerWebSocketClient 建立 WebSocket 连接，返回服务端的客户端对象（不启动读写泵）
+func newServerWebSocketClient(t *testing.T, lm interfaces.LogManager) *WebSocketClient {
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("升级 WebSocket 连接失败: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接 WebSocket 失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewWebSocketClient(<-conns, NewWebSocketHub(), "client_test", lm).(*WebSocketClient)
+}
+
+func TestWebSocket_SubscribeAfterClose(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	// 关闭后的订阅请求不会建立监控
+	lm := &brokerLogManager{watchLogManager: watchLogManager{updates: make(chan types.LogUpdate, 10)}}
+	client := newServerWebSocketClient(t, lm)
+	client.Close()
+	client.handleSubscribe(logFile, nil, resumePoint{})
+	if len(lm.subscribers) != 0 {
+		t.Errorf("客户端关闭后不应订阅: %v", lm.subscribers)
+	}
+
+	// 建立监控期间客户端关闭：立即释放更新通道，不登记订阅
+	lm = &brokerLogManager{watchLogManager: watchLogManager{updates: make(chan types.LogUpdate, 10)}}
+	client = newServerWebSocketClient(t, lm)
+	lm.onWatch = func() { client.Close() }
+	client.handleSubscribe(logFile, nil, resumePoint{})
+
+	lm.mutex.Lock()
+	subscribed, released := len(lm.subscribers), len(lm.released)
+	lm.mutex.Unlock()
+	if subscribed != 1 || released != 1 {
+		t.Errorf("期望释放关闭期间建立的监控，订阅 %d 次，释放 %d 次", subscribed, released)
+	}
+	client.subMutex.Lock()
+	remaining := len(client.subscriptions)
+	client.subMutex.Unlock()
+	if remaining != 0 {
+		t.Errorf("客户端关闭后不应保留订阅: %d", remaining)
+	}
+}
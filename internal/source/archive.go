@@ -0,0 +1,684 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// archivePollInterval 实时跟踪归档成员时检查归档文件变化的间隔
+	archivePollInterval = 2 * time.Second
+	// maxArchiveDecompressors 每个归档最多保留的停在上次读取结束位置的解压器数量，每个解压器持有一个打开的文件
+	maxArchiveDecompressors = 8
+	// skipChunkSize 解压跳过内容时每次读取的字节数，两次读取之间检查 ctx 是否取消
+	skipChunkSize = 1 << 20
+)
+
+// archiveKind 归档格式
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveZip
+	archiveTar
+	archiveTarGz
+)
+
+// archiveKindOf 按扩展名识别归档格式
+func archiveKindOf(name string) archiveKind {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz
+	default:
+		return archiveNone
+	}
+}
+
+// IsArchive 按扩展名判断是否为支持的归档（.zip、.tar、.tar.gz、.tgz）
+func IsArchive(p string) bool {
+	return archiveKindOf(p) != archiveNone
+}
+
+// SplitArchivePath 把本地路径拆分为归档文件的路径和归档中的成员路径；
+// 路径中没有归档文件时返回 false，路径就是归档文件时成员路径为空
+func SplitArchivePath(p string) (archive, member string, ok bool) {
+	if p == "" || IsRemote(p) {
+		return "", "", false
+	}
+	p = filepath.Clean(p)
+	for i := 1; i <= len(p); i++ {
+		if i < len(p) && p[i] != filepath.Separator {
+			continue
+		}
+		prefix := p[:i]
+		if !IsArchive(prefix) {
+			continue
+		}
+		if info, err := os.Stat(prefix); err == nil && info.Mode().IsRegular() {
+			return prefix, filepath.ToSlash(strings.TrimPrefix(p[i:], string(filepath.Separator))), true
+		}
+	}
+	return "", "", false
+}
+
+// ArchiveSource 本地的 .zip、.tar、.tar.gz 归档中的日志，归档作为目录，成员路径为 <归档路径>/<成员路径>
+// 成员不解压到磁盘：第一次访问时建立成员索引（zip 读取中央目录，tar 记录每个成员数据的位置），
+// zip 中未压缩的成员和 .tar 的成员直接按位置读取；需要解压的内容（zip 中压缩的成员、.tar.gz）通过解压索引读取：
+// 解压时每隔 span 记录恢复点（.tar.gz 在建立成员索引时解压整个流，zip 的成员在第一次读取时记录），
+// 之后从不晚于目标位置的最近恢复点或保留的解压器继续解压，读取尾部、向前翻页最多解压 span 的内容
+type ArchiveSource struct {
+	root         string
+	kind         archiveKind
+	pollInterval time.Duration
+	span         int64 // 解压索引中恢复点的间隔
+
+	mutex         sync.Mutex
+	index         *archiveIndex
+	generation    int // 每次重建索引加一，旧的解压器不再使用
+	decompressors []*decompressor
+	closed        bool
+	stopCh        chan struct{}
+}
+
+// archiveIndex 归档的成员索引，归档文件的大小或修改时间变化后重建
+type archiveIndex struct {
+	size    int64
+	modTime time.Time
+	members map[string]*archiveMember
+	dirs    map[string]time.Time // 目录的修改时间，包括只出现在成员路径中的上级目录
+	// inflates 需要解压的内容的解压索引，键为 zip 成员路径（.tar.gz 整个 tar 流为空），由 ArchiveSource.mutex 保护
+	inflates map[string]*deflateIndex
+}
+
+// archiveMember 归档中的文件
+type archiveMember struct {
+	size    int64
+	modTime time.Time
+	// offset zip：压缩数据在归档文件中的位置；tar：数据在 tar 流（.tar.gz 为解压后的流）中的位置
+	offset         int64
+	compressedSize int64
+	deflated       bool
+}
+
+// decompressor 停在某个位置的解压器，key 为 zip 成员路径（.tar.gz 整个 tar 流为空）
+type decompressor struct {
+	key        string
+	r          *inflateReader
+	closer     io.Closer
+	start      int64 // 开始解压的位置，即使用的恢复点的解压后位置
+	generation int
+	used       time.Time
+	broken     bool // 读取出错或已经读完，不再保留
+}
+
+// NewArchiveSource 创建本地归档的日志源
+func NewArchiveSource(archive string) (*ArchiveSource, error) {
+	kind := archiveKindOf(archive)
+	if kind == archiveNone {
+		return nil, fmt.Errorf("不支持的归档格式: %s", archive)
+	}
+	abs, err := filepath.Abs(archive)
+	if err != nil {
+		return nil, fmt.Errorf("无效的归档路径 %s: %w", archive, err)
+	}
+	return &ArchiveSource{
+		root:         abs,
+		kind:         kind,
+		pollInterval: archivePollInterval,
+		span:         deflateSpan,
+		stopCh:       make(chan struct{}),
+	}, nil
+}
+
+// Root 源的根路径，即归档文件的路径
+func (s *ArchiveSource) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否为归档本身或归档中的成员路径，拒绝 .. 等不规范的成员路径
+func (s *ArchiveSource) Resolve(p string) (string, bool) {
+	if p == "" || IsRemote(p) {
+		return "", false
+	}
+	p = filepath.Clean(p)
+	if p == s.root {
+		return p, true
+	}
+	member, ok := strings.CutPrefix(filepath.ToSlash(p), filepath.ToSlash(s.root)+"/")
+	if !ok || cleanMemberName(member) != member {
+		return "", false
+	}
+	return p, true
+}
+
+// memberName 返回路径对应的成员路径，归档本身为空
+func (s *ArchiveSource) memberName(p string) (string, error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return "", fmt.Errorf("路径不在归档 %s 中: %s", s.root, p)
+	}
+	return strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(resolved, s.root)), "/"), nil
+}
+
+// memberPath 返回成员的完整路径
+func (s *ArchiveSource) memberPath(name string) string {
+	if name == "" {
+		return s.root
+	}
+	return s.root + string(filepath.Separator) + filepath.FromSlash(name)
+}
+
+// List 列出归档中目录的直接子节点
+func (s *ArchiveSource) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	name, err := s.memberName(dir)
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := index.dirs[name]; !ok && name != "" {
+		return nil, fmt.Errorf("路径不是目录: %s", dir)
+	}
+
+	var files []FileInfo
+	for _, info := range s.infos(index) {
+		if s.parentName(info.Path) == name {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+// Walk 列出归档中的所有文件和目录，跳过隐藏文件和目录
+func (s *ArchiveSource) Walk(ctx context.Context) ([]FileInfo, error) {
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	for _, info := range s.infos(index) {
+		name, _ := s.memberName(info.Path)
+		if !hiddenMember(name) {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+// Stat 获取归档或成员的信息，成员的文件标识由归档的大小和修改时间组成
+func (s *ArchiveSource) Stat(ctx context.Context, p string) (FileInfo, error) {
+	name, err := s.memberName(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	index, err := s.loadIndex()
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if name == "" {
+		return FileInfo{Path: s.root, Name: filepath.Base(s.root), ModTime: index.modTime, IsDir: true, ID: index.id()}, nil
+	}
+	if modTime, ok := index.dirs[name]; ok {
+		return FileInfo{Path: s.memberPath(name), Name: path.Base(name), ModTime: modTime, IsDir: true, ID: index.id()}, nil
+	}
+	member, ok := index.members[name]
+	if !ok {
+		return FileInfo{}, fmt.Errorf("归档 %s 中没有 %s: %w", s.root, name, fs.ErrNotExist)
+	}
+	return FileInfo{Path: s.memberPath(name), Name: path.Base(name), Size: member.size, ModTime: member.modTime, ID: index.id()}, nil
+}
+
+// Open 从 offset 开始读取成员，length 为负数时读取到末尾
+func (s *ArchiveSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	name, err := s.memberName(p)
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	member, ok := index.members[name]
+	if !ok {
+		if _, isDir := index.dirs[name]; isDir || name == "" {
+			return nil, fmt.Errorf("路径是目录: %s", p)
+		}
+		return nil, fmt.Errorf("归档 %s 中没有 %s: %w", s.root, name, fs.ErrNotExist)
+	}
+
+	if offset > member.size {
+		offset = member.size
+	}
+	if remaining := member.size - offset; length < 0 || length > remaining {
+		length = remaining
+	}
+
+	switch {
+	case s.kind == archiveTar || (s.kind == archiveZip && !member.deflated):
+		// 数据在归档文件中连续存放，直接按位置读取
+		file, err := os.Open(s.root)
+		if err != nil {
+			return nil, err
+		}
+		return &limitedReadCloser{Reader: io.NewSectionReader(file, member.offset+offset, length), Closer: file}, nil
+
+	case s.kind == archiveZip:
+		// 压缩数据位于 member.offset 开始的 compressedSize 字节
+		dec, err := s.acquire(ctx, index, name, offset, func(start int64) (io.Reader, io.Closer, error) {
+			file, err := os.Open(s.root)
+			if err != nil {
+				return nil, nil, err
+			}
+			return io.NewSectionReader(file, member.offset+start, member.compressedSize-start), file, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &decompressorReader{s: s, dec: dec, remaining: length}, nil
+
+	default:
+		dec, err := s.acquire(ctx, index, "", member.offset+offset, func(start int64) (io.Reader, io.Closer, error) {
+			file, err := os.Open(s.root)
+			if err != nil {
+				return nil, nil, err
+			}
+			return io.NewSectionReader(file, start, index.size-start), file, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &decompressorReader{s: s, dec: dec, remaining: length}, nil
+	}
+}
+
+// Follow 定期检查归档文件，归档被替换或修改时通知 modify，被删除、重新出现时通知 delete、create
+func (s *ArchiveSource) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	if _, err := s.memberName(p); err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	go func() {
+		last, lastErr := os.Stat(s.root)
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			case <-s.stopCh:
+				return
+			}
+
+			current, err := os.Stat(s.root)
+			var event string
+			switch {
+			case err != nil && lastErr == nil:
+				event = "delete"
+			case err == nil && lastErr != nil:
+				event = "create"
+			case err == nil && (current.Size() != last.Size() || !current.ModTime().Equal(last.ModTime())):
+				event = "modify"
+			}
+			last, lastErr = current, err
+			if event != "" {
+				callback(types.FileEvent{Path: p, Type: event})
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(stop) }) }, nil
+}
+
+// Close 关闭保留的解压器，停止所有跟踪
+func (s *ArchiveSource) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stopCh)
+	s.dropDecompressorsLocked()
+	return nil
+}
+
+// loadIndex 返回成员索引，归档文件变化后重建
+func (s *ArchiveSource) loadIndex() (*archiveIndex, error) {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.index != nil && s.index.size == info.Size() && s.index.modTime.Equal(info.ModTime()) {
+		return s.index, nil
+	}
+
+	index := &archiveIndex{
+		size:     info.Size(),
+		modTime:  info.ModTime(),
+		members:  make(map[string]*archiveMember),
+		dirs:     make(map[string]time.Time),
+		inflates: make(map[string]*deflateIndex),
+	}
+	if s.kind == archiveZip {
+		err = index.readZip(s.root)
+	} else {
+		err = index.readTar(s.root, s.kind == archiveTarGz, s.span)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取归档 %s 失败: %w", s.root, err)
+	}
+
+	s.index = index
+	s.generation++
+	s.dropDecompressorsLocked()
+	return index, nil
+}
+
+// readZip 通过中央目录建立索引，记录每个成员压缩数据的位置
+func (idx *archiveIndex) readZip(archive string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := zip.NewReader(file, idx.size)
+	if err != nil {
+		return err
+	}
+	for _, f := range reader.File {
+		name := cleanMemberName(f.Name)
+		if name == "" {
+			continue
+		}
+		if f.FileInfo().IsDir() {
+			idx.addDir(name, f.Modified)
+			continue
+		}
+		// 只支持未压缩和 deflate 压缩的普通文件
+		if !f.Mode().IsRegular() || (f.Method != zip.Store && f.Method != zip.Deflate) {
+			continue
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			continue
+		}
+		idx.addMember(name, &archiveMember{
+			size:           int64(f.UncompressedSize64),
+			modTime:        f.Modified,
+			offset:         offset,
+			compressedSize: int64(f.CompressedSize64),
+			deflated:       f.Method == zip.Deflate,
+		})
+	}
+	return nil
+}
+
+// readTar 顺序读取 tar 流建立索引，记录每个成员数据在 tar 流中的位置；.tar 可以跳过成员的数据，
+// .tar.gz 需要解压整个流，同时建立解压索引
+func (idx *archiveIndex) readTar(archive string, gzipped bool, span int64) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// tar.Reader 每次只读取需要的块，读取头部后的位置就是成员数据的位置
+	var position func() int64
+	var tr *tar.Reader
+	if gzipped {
+		inflate := newDeflateIndex(true, span)
+		idx.inflates[""] = inflate
+		zr, err := newInflateReader(inflate, deflatePoint{}, file)
+		if err != nil {
+			return err
+		}
+		tr = tar.NewReader(zr)
+		position = zr.position
+	} else {
+		tr = tar.NewReader(file)
+		position = func() int64 {
+			offset, _ := file.Seek(0, io.SeekCurrent)
+			return offset
+		}
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanMemberName(header.Name)
+		if name == "" {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			idx.addDir(name, header.ModTime)
+		case tar.TypeReg:
+			idx.addMember(name, &archiveMember{
+				size:    header.Size,
+				modTime: header.ModTime,
+				offset:  position(),
+			})
+		}
+	}
+}
+
+func (idx *archiveIndex) addMember(name string, member *archiveMember) {
+	idx.members[name] = member
+	idx.addDir(path.Dir(name), member.modTime)
+}
+
+// addDir 记录目录及其上级目录，显式的目录条目的修改时间优先
+func (idx *archiveIndex) addDir(name string, modTime time.Time) {
+	for ; name != "." && name != ""; name = path.Dir(name) {
+		if existing, ok := idx.dirs[name]; ok && !existing.IsZero() {
+			return
+		}
+		idx.dirs[name] = modTime
+	}
+}
+
+// id 成员的文件标识，归档被替换后变化
+func (idx *archiveIndex) id() string {
+	return fmt.Sprintf("%d:%d", idx.size, idx.modTime.UnixNano())
+}
+
+// infos 按路径排序的所有目录和成员
+func (s *ArchiveSource) infos(index *archiveIndex) []FileInfo {
+	files := make([]FileInfo, 0, len(index.dirs)+len(index.members))
+	for name, modTime := range index.dirs {
+		files = append(files, FileInfo{Path: s.memberPath(name), Name: path.Base(name), ModTime: modTime, IsDir: true, ID: index.id()})
+	}
+	for name, member := range index.members {
+		files = append(files, FileInfo{Path: s.memberPath(name), Name: path.Base(name), Size: member.size, ModTime: member.modTime, ID: index.id()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// parentName 返回路径的上级目录的成员路径，归档根目录为空
+func (s *ArchiveSource) parentName(p string) string {
+	name, _ := s.memberName(p)
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// cleanMemberName 规范化归档中的成员路径，去掉开头的 / 和 ./；包含 .. 的路径返回空，不会指向归档之外
+func cleanMemberName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.Contains("/"+name+"/", "/../") {
+		return ""
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// hiddenMember 成员路径的某一级以 . 开头
+func hiddenMember(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// acquire 取得停在 target 位置的解压器：从保留的解压器和解压索引的恢复点中选择不晚于目标位置的最近的一个，
+// 恢复点更近时调用 open 打开从压缩数据的指定字节开始的读取器，从恢复点继续解压
+func (s *ArchiveSource) acquire(ctx context.Context, index *archiveIndex, key string, target int64, open func(start int64) (io.Reader, io.Closer, error)) (*decompressor, error) {
+	s.mutex.Lock()
+	inflate := index.inflates[key]
+	if inflate == nil {
+		inflate = newDeflateIndex(s.kind == archiveTarGz, s.span)
+		index.inflates[key] = inflate
+	}
+	point := inflate.point(target)
+	best := -1
+	for i, dec := range s.decompressors {
+		pos := dec.r.position()
+		if dec.key == key && pos <= target && pos >= point.out && (best < 0 || pos > s.decompressors[best].r.position()) {
+			best = i
+		}
+	}
+	var dec *decompressor
+	if best >= 0 {
+		dec = s.decompressors[best]
+		s.decompressors = append(s.decompressors[:best], s.decompressors[best+1:]...)
+	}
+	generation := s.generation
+	s.mutex.Unlock()
+
+	if dec == nil {
+		r, closer, err := open(point.bit / 8)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := newInflateReader(inflate, point, r)
+		if err != nil {
+			closer.Close()
+			return nil, fmt.Errorf("解压归档 %s 失败: %w", s.root, err)
+		}
+		dec = &decompressor{key: key, r: zr, closer: closer, start: point.out, generation: generation}
+	}
+
+	for pos := dec.r.position(); pos < target; pos = dec.r.position() {
+		if err := ctx.Err(); err != nil {
+			dec.closer.Close()
+			return nil, err
+		}
+		_, err := io.CopyN(io.Discard, dec.r, min(target-pos, skipChunkSize))
+		if err != nil {
+			dec.closer.Close()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("解压归档 %s 失败: %w", s.root, err)
+		}
+	}
+	return dec, nil
+}
+
+// release 读取结束后保留解压器，超过数量上限时关闭最久未使用的解压器
+func (s *ArchiveSource) release(dec *decompressor) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if dec.broken || s.closed || dec.generation != s.generation {
+		dec.closer.Close()
+		return
+	}
+	dec.used = time.Now()
+	s.decompressors = append(s.decompressors, dec)
+	if len(s.decompressors) > maxArchiveDecompressors {
+		oldest := 0
+		for i, c := range s.decompressors {
+			if c.used.Before(s.decompressors[oldest].used) {
+				oldest = i
+			}
+		}
+		s.decompressors[oldest].closer.Close()
+		s.decompressors = append(s.decompressors[:oldest], s.decompressors[oldest+1:]...)
+	}
+}
+
+func (s *ArchiveSource) dropDecompressorsLocked() {
+	for _, dec := range s.decompressors {
+		dec.closer.Close()
+	}
+	s.decompressors = nil
+}
+
+// decompressorReader 从解压器读取 remaining 字节，关闭时把解压器交还归档
+type decompressorReader struct {
+	s         *ArchiveSource
+	dec       *decompressor
+	remaining int64
+	closed    bool
+}
+
+func (r *decompressorReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.dec.r.Read(p)
+	r.remaining -= int64(n)
+	if err != nil {
+		r.dec.broken = true
+		if err == io.EOF && r.remaining > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+func (r *decompressorReader) Close() error {
+	if !r.closed {
+		r.closed = true
+		r.s.release(r.dec)
+	}
+	return nil
+}
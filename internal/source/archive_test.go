@@ -0,0 +1,304 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testArchiveMembers 测试归档中的成员，包括会被忽略的隐藏文件和越界路径
+var testArchiveMembers = map[string]string{
+	"var/log/app.log":     numberedLines("app", 2000),
+	"var/log/nginx/a.log": "GET /health 200\n",
+	"README.txt":          "support bundle\n",
+	".hidden/x.log":       "hidden\n",
+	"../escape.log":       "escape\n",
+}
+
+func numberedLines(prefix string, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%s line %04d\n", prefix, i)
+	}
+	return b.String()
+}
+
+// writeTestArchive 按扩展名创建归档，zip 中 README.txt 不压缩，其他成员使用 deflate
+func writeTestArchive(t *testing.T, name string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("创建归档失败: %v", err)
+	}
+	defer file.Close()
+
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		zw := zip.NewWriter(file)
+		for member, content := range testArchiveMembers {
+			method := zip.Deflate
+			if member == "README.txt" {
+				method = zip.Store
+			}
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: member, Method: method, Modified: modTime})
+			if err != nil {
+				t.Fatalf("写入 zip 失败: %v", err)
+			}
+			w.Write([]byte(content))
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("写入 zip 失败: %v", err)
+		}
+	default:
+		var w io.Writer = file
+		var gz *gzip.Writer
+		if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+			gz = gzip.NewWriter(file)
+			w = gz
+		}
+		tw := tar.NewWriter(w)
+		tw.WriteHeader(&tar.Header{Name: "var/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime})
+		for member, content := range testArchiveMembers {
+			tw.WriteHeader(&tar.Header{Name: member, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), ModTime: modTime})
+			tw.Write([]byte(content))
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("写入 tar 失败: %v", err)
+		}
+		if gz != nil {
+			gz.Close()
+		}
+	}
+	return archivePath
+}
+
+func readArchiveRange(t *testing.T, src *ArchiveSource, p string, offset, length int64) string {
+	t.Helper()
+	r, err := src.Open(context.Background(), p, offset, length)
+	if err != nil {
+		t.Fatalf("Open(%s, %d, %d) 失败: %v", p, offset, length, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestArchiveSource_ListStatOpen(t *testing.T) {
+	for _, name := range []string{"bundle.zip", "bundle.tar", "bundle.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := writeTestArchive(t, name)
+			src, err := NewArchiveSource(archivePath)
+			if err != nil {
+				t.Fatalf("创建归档日志源失败: %v", err)
+			}
+			defer src.Close()
+			ctx := context.Background()
+
+			files, err := src.Walk(ctx)
+			if err != nil {
+				t.Fatalf("Walk 失败: %v", err)
+			}
+			var paths []string
+			for _, f := range files {
+				paths = append(paths, strings.TrimPrefix(f.Path, archivePath))
+			}
+			expected := "/README.txt,/var,/var/log,/var/log/app.log,/var/log/nginx,/var/log/nginx/a.log"
+			if strings.Join(paths, ",") != expected {
+				t.Errorf("期望成员 %s，得到 %s", expected, strings.Join(paths, ","))
+			}
+
+			children, err := src.List(ctx, archivePath+"/var/log")
+			if err != nil || len(children) != 2 || children[0].Name != "app.log" || !children[1].IsDir {
+				t.Errorf("List 结果不正确: %+v, %v", children, err)
+			}
+
+			app := archivePath + "/var/log/app.log"
+			content := testArchiveMembers["var/log/app.log"]
+			info, err := src.Stat(ctx, app)
+			if err != nil || info.Size != int64(len(content)) || info.IsDir || info.ID == "" {
+				t.Errorf("Stat 结果不正确: %+v, %v", info, err)
+			}
+			if got := readArchiveRange(t, src, app, 0, -1); got != content {
+				t.Errorf("成员内容不正确，长度 %d", len(got))
+			}
+			// 按位置分页读取，包括倒序读取
+			for _, offset := range []int64{15000, 300, 27000} {
+				if got := readArchiveRange(t, src, app, offset, 100); got != content[offset:offset+100] {
+					t.Errorf("offset %d 的内容不正确: %q", offset, got)
+				}
+			}
+			if got := readArchiveRange(t, src, archivePath+"/README.txt", 8, -1); got != "bundle\n" {
+				t.Errorf("README.txt 的内容不正确: %q", got)
+			}
+
+			if _, err := src.Stat(ctx, archivePath+"/var/log/missing.log"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("期望成员不存在错误，得到 %v", err)
+			}
+			if _, ok := src.Resolve(archivePath + "/../escape.log"); ok {
+				t.Error("期望拒绝归档之外的路径")
+			}
+			if root, err := src.Stat(ctx, archivePath); err != nil || !root.IsDir {
+				t.Errorf("归档本身应作为目录: %+v, %v", root, err)
+			}
+		})
+	}
+}
+
+func TestArchiveSource_Decompressors(t *testing.T) {
+	archivePath := writeTestArchive(t, "bundle.tgz")
+	src, err := NewArchiveSource(archivePath)
+	if err != nil {
+		t.Fatalf("创建归档日志源失败: %v", err)
+	}
+	defer src.Close()
+
+	app := archivePath + "/var/log/app.log"
+	content := testArchiveMembers["var/log/app.log"]
+	readArchiveRange(t, src, app, 1000, 100)
+
+	src.mutex.Lock()
+	if len(src.decompressors) != 1 {
+		t.Fatalf("期望保留 1 个解压器，得到 %d", len(src.decompressors))
+	}
+	first := src.decompressors[0]
+	src.mutex.Unlock()
+
+	// 向后翻页从保留的解压器继续解压
+	if got := readArchiveRange(t, src, app, 2000, 100); got != content[2000:2100] {
+		t.Errorf("从保留的解压器读取的内容不正确: %q", got)
+	}
+	src.mutex.Lock()
+	if len(src.decompressors) != 1 || src.decompressors[0] != first {
+		t.Errorf("期望复用解压器，得到 %d 个", len(src.decompressors))
+	}
+	src.mutex.Unlock()
+
+	// 向前翻页从不晚于目标位置的最近恢复点解压（成员小于恢复点的间隔时就是开头），两个位置的解压器都保留
+	if got := readArchiveRange(t, src, app, 500, 100); got != content[500:600] {
+		t.Errorf("向前读取的内容不正确: %q", got)
+	}
+	src.mutex.Lock()
+	if len(src.decompressors) != 2 {
+		t.Errorf("期望 2 个解压器，得到 %d", len(src.decompressors))
+	}
+	src.mutex.Unlock()
+
+	// 归档被替换后重建索引，旧的解压器被丢弃
+	if err := os.WriteFile(archivePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Stat(context.Background(), app); err == nil {
+		t.Error("期望读取损坏的归档失败")
+	}
+}
+
+// writeLargeTestArchive 创建只有一个 deflate 压缩的成员的 .tgz 或 .zip
+func writeLargeTestArchive(t *testing.T, name, member, content string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("创建归档失败: %v", err)
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(name, ".zip") {
+		zw := zip.NewWriter(file)
+		w, _ := zw.CreateHeader(&zip.FileHeader{Name: member, Method: zip.Deflate})
+		w.Write([]byte(content))
+		if err := zw.Close(); err != nil {
+			t.Fatalf("写入 zip 失败: %v", err)
+		}
+		return archivePath
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: member, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	if err := gz.Close(); err != nil {
+		t.Fatalf("写入 tar.gz 失败: %v", err)
+	}
+	return archivePath
+}
+
+func TestArchiveSource_DeflateIndex(t *testing.T) {
+	content := numberedLines("big", 100000)
+	size := int64(len(content))
+	for _, name := range []string{"big.tgz", "big.zip"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := writeLargeTestArchive(t, name, "var/log/big.log", content)
+			src, err := NewArchiveSource(archivePath)
+			if err != nil {
+				t.Fatalf("创建归档日志源失败: %v", err)
+			}
+			defer src.Close()
+			src.span = 64 << 10
+			big := archivePath + "/var/log/big.log"
+
+			// 先读取最后一页，再读取更早的一页
+			if got := readArchiveRange(t, src, big, size-100, 100); got != content[size-100:] {
+				t.Errorf("最后一页的内容不正确: %q", got)
+			}
+			offset := size / 2
+			if got := readArchiveRange(t, src, big, offset, 100); got != content[offset:offset+100] {
+				t.Errorf("更早一页的内容不正确: %q", got)
+			}
+
+			src.mutex.Lock()
+			defer src.mutex.Unlock()
+			if len(src.decompressors) != 2 {
+				t.Fatalf("期望保留 2 个解压器，得到 %d", len(src.decompressors))
+			}
+			last, earlier := src.decompressors[0], src.decompressors[1]
+			// .tar.gz 建立成员索引时已经记录了恢复点，zip 的成员在第一次读取时从头解压并记录恢复点
+			if name == "big.tgz" && last.start == 0 {
+				t.Error("期望最后一页从恢复点开始解压")
+			}
+			// 更早的一页不能从保留的解压器向前读取，应从它之前的最近恢复点开始解压，而不是从头解压
+			end := earlier.r.position()
+			if earlier.start == 0 || earlier.start > end-100 || end-100-earlier.start > 2*src.span {
+				t.Errorf("期望从目标位置 %d 之前的恢复点开始解压，得到 %d", end-100, earlier.start)
+			}
+			if n := len(src.index.inflates[earlier.key].points); int64(n) < size/src.span/2 {
+				t.Errorf("恢复点太少: %d", n)
+			}
+		})
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	archivePath := writeTestArchive(t, "bundle.zip")
+
+	tests := map[string][2]string{
+		archivePath:                      {archivePath, ""},
+		archivePath + "/var/log/app.log": {archivePath, "var/log/app.log"},
+		archivePath + "/var/log/":        {archivePath, "var/log"},
+	}
+	for p, want := range tests {
+		archive, member, ok := SplitArchivePath(p)
+		if !ok || archive != want[0] || member != want[1] {
+			t.Errorf("SplitArchivePath(%s) = %s, %s, %v", p, archive, member, ok)
+		}
+	}
+	for _, p := range []string{filepath.Dir(archivePath), filepath.Join(filepath.Dir(archivePath), "other.zip", "a.log"), "ssh://h/x.zip/a.log"} {
+		if _, _, ok := SplitArchivePath(p); ok {
+			t.Errorf("%s 不在归档中", p)
+		}
+	}
+}
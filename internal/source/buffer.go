@@ -0,0 +1,68 @@
+package source
+
+import (
+	"bytes"
+	"io"
+)
+
+// lineBuffer 保存在内存中的只追加的日志内容，超过上限时丢弃最早的整行
+// 字节位置按包括已丢弃内容在内的完整内容计算，丢弃后已有的字节位置仍然指向同一行
+type lineBuffer struct {
+	data  []byte
+	base  int64 // data[0] 的字节位置，即已丢弃的字节数
+	lines int64 // 行数，包括已丢弃的行
+}
+
+// size 完整内容的大小，包括已丢弃的部分
+func (b *lineBuffer) size() int64 {
+	return b.base + int64(len(b.data))
+}
+
+// reset 清空内容，字节位置从 0 重新开始
+func (b *lineBuffer) reset() {
+	*b = lineBuffer{}
+}
+
+// appendLine 追加一行（不含换行符），内容超过 maxBytes 时丢弃最早的行，只保留约 3/4，
+// 避免每次追加都复制；maxBytes 小于等于 0 时不限制
+func (b *lineBuffer) appendLine(line []byte, maxBytes int64) {
+	// 只在已有内容之后写入，reader 取得的切片不受影响
+	b.data = append(append(b.data, line...), '\n')
+	b.lines++
+
+	if maxBytes <= 0 || int64(len(b.data)) <= maxBytes {
+		return
+	}
+	drop := len(b.data) - int(maxBytes/4*3)
+	if i := bytes.IndexByte(b.data[drop:], '\n'); i >= 0 {
+		drop += i + 1
+	} else {
+		drop = len(b.data)
+	}
+	// 复制到新的切片，释放被丢弃的内容；已取得的 reader 仍然读取旧的切片
+	b.data = append([]byte(nil), b.data[drop:]...)
+	b.base += int64(drop)
+}
+
+// reader 从 offset 开始读取，length 为负数时读取到末尾；offset 早于保留的内容时从保留的第一行开始
+func (b *lineBuffer) reader(offset, length int64) io.Reader {
+	data := b.data
+	offset -= b.base
+	if offset < 0 {
+		if length >= 0 {
+			length += offset
+			if length < 0 {
+				length = 0
+			}
+		}
+		offset = 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return bytes.NewReader(data)
+}
@@ -0,0 +1,616 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// dockerAPIVersion 使用的 Docker Engine API 版本（Docker 20.10 及以上支持）
+const dockerAPIVersion = "v1.41"
+
+// containerNamePattern Docker 允许的容器名称
+var containerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// DockerSource 通过 Docker Engine API 的 Unix socket 读取容器的标准输出和标准错误
+// 每个容器是根路径下的一个文件 <容器名称>.log，内容按 json-file 日志驱动的格式生成：
+// 时间取自 Docker，容器标签放在 attrs 中。读取过的内容缓存在内存中，之后只读取新增的部分，
+// 每个容器最多保留 docker.maxBytes 字节，超过时丢弃最早的行
+type DockerSource struct {
+	root   string // docker:///var/run/docker.sock
+	socket string
+	cfg    config.DockerConfig
+	client *http.Client
+
+	mutex  sync.Mutex
+	logs   map[string]*containerLog // 键为容器名称
+	closed bool
+	stopCh chan struct{}
+}
+
+// containerLog 容器日志按 json-file 格式生成的内容
+type containerLog struct {
+	mutex   sync.Mutex
+	id      string
+	labels  map[string]string
+	tty     bool
+	running bool
+	modTime time.Time
+	buf     lineBuffer
+	// attrs 序列化后的容器标签，每个容器只序列化一次
+	attrs []byte
+
+	// 最后一行的时间和该时间的行数。Docker 的 since 参数包含该时间本身，
+	// 重新读取时跳过已有的行；时间早于 last 的行（标准输出和标准错误交错时可能出现）同样被跳过
+	last      time.Time
+	lastCount int
+	fetched   bool
+	// streaming 正在 follow 的日志流数量，大于 0 时内容由日志流更新，不需要再次读取
+	streaming int
+}
+
+// dockerContainer GET /containers/{name}/json 的响应中用到的字段
+type dockerContainer struct {
+	ID      string `json:"Id"`
+	Name    string `json:"Name"`
+	Created string `json:"Created"`
+	Config  struct {
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+}
+
+// dockerRecord json-file 日志驱动的一条记录
+type dockerRecord struct {
+	Log    string          `json:"log"`
+	Stream string          `json:"stream"`
+	Attrs  json.RawMessage `json:"attrs,omitempty"`
+	Time   string          `json:"time"`
+}
+
+// NewDockerSource 创建容器日志源，root 例如 docker:///var/run/docker.sock
+// 第一次访问时才连接 Docker
+func NewDockerSource(root string, cfg config.DockerConfig) (*DockerSource, error) {
+	cfg = cfg.WithDefaults()
+
+	socket := strings.TrimPrefix(root, config.DockerPathPrefix)
+	if !strings.HasPrefix(root, config.DockerPathPrefix) || !strings.HasPrefix(socket, "/") {
+		return nil, fmt.Errorf("无效的容器日志路径: %s", root)
+	}
+	socket = path.Clean(socket)
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	return &DockerSource{
+		root:   config.DockerPathPrefix + socket,
+		socket: socket,
+		cfg:    cfg,
+		client: &http.Client{
+			// 不设置总超时，follow 的日志流会一直保持
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+		logs:   make(map[string]*containerLog),
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// Root 源的根路径
+func (s *DockerSource) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否为根路径或根路径下的 <容器名称>.log
+func (s *DockerSource) Resolve(p string) (string, bool) {
+	if p == s.root {
+		return p, true
+	}
+	name, ok := strings.CutPrefix(p, s.root+"/")
+	if !ok || !strings.HasSuffix(name, ".log") || !containerNamePattern.MatchString(strings.TrimSuffix(name, ".log")) {
+		return "", false
+	}
+	return p, true
+}
+
+// containerName 返回路径对应的容器名称
+func (s *DockerSource) containerName(p string) (string, error) {
+	if _, ok := s.Resolve(p); !ok || p == s.root {
+		return "", fmt.Errorf("路径不是 %s 下的容器日志: %s", s.root, p)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(p, s.root+"/"), ".log"), nil
+}
+
+// List 列出正在运行的容器，容器日志没有子目录
+func (s *DockerSource) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	if dir != s.root {
+		return nil, fmt.Errorf("路径不是目录: %s", dir)
+	}
+	return s.Walk(ctx)
+}
+
+// Walk 列出正在运行的容器，已经读取过日志的容器带有当前大小
+func (s *DockerSource) Walk(ctx context.Context) ([]FileInfo, error) {
+	resp, err := s.get(ctx, "/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var containers []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Created int64    `json:"Created"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("解析容器列表失败: %w", err)
+	}
+
+	files := make([]FileInfo, 0, len(containers))
+	for _, c := range containers {
+		if len(c.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(c.Names[0], "/")
+		info := FileInfo{
+			Path:    s.root + "/" + name + ".log",
+			Name:    name + ".log",
+			ModTime: time.Unix(c.Created, 0),
+			ID:      c.ID,
+		}
+		s.mutex.Lock()
+		log := s.logs[name]
+		s.mutex.Unlock()
+		if log != nil {
+			log.mutex.Lock()
+			if log.id == c.ID {
+				info.Size = log.buf.size()
+				info.ModTime = log.modTime
+			}
+			log.mutex.Unlock()
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// Stat 获取容器日志的信息，会读取容器新增的日志；根路径检查 Docker 是否可以访问
+func (s *DockerSource) Stat(ctx context.Context, p string) (FileInfo, error) {
+	if p == s.root {
+		resp, err := s.get(ctx, "/_ping", nil)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		resp.Body.Close()
+		return FileInfo{Path: s.root, Name: path.Base(s.socket), IsDir: true}, nil
+	}
+
+	name, err := s.containerName(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	log, err := s.refresh(ctx, name)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return FileInfo{
+		Path:    p,
+		Name:    name + ".log",
+		Size:    log.buf.size(),
+		ModTime: log.modTime,
+		ID:      log.id,
+	}, nil
+}
+
+// CountLines 统计容器日志的行数
+func (s *DockerSource) CountLines(ctx context.Context, p string) (int64, error) {
+	name, err := s.containerName(p)
+	if err != nil {
+		return 0, err
+	}
+	log, err := s.refresh(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.buf.lines, nil
+}
+
+// Open 从 offset 开始读取容器日志，length 为负数时读取到末尾
+// 超过 docker.maxBytes 被丢弃的内容不能再读取，offset 早于保留的内容时从保留的第一行开始
+func (s *DockerSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	name, err := s.containerName(p)
+	if err != nil {
+		return nil, err
+	}
+	log, err := s.refresh(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	log.mutex.Lock()
+	r := log.buf.reader(offset, length)
+	log.mutex.Unlock()
+	return io.NopCloser(r), nil
+}
+
+// Follow 通过 follow 模式的日志流跟踪正在运行的容器，新的输出以 modify 事件通知；
+// 容器停止后定期检查，容器被删除、重新创建（容器 ID 变化）时通知 delete、create 事件
+func (s *DockerSource) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	name, err := s.containerName(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go s.follow(ctx, p, name, callback)
+	return cancel, nil
+}
+
+func (s *DockerSource) follow(ctx context.Context, p, name string, callback func(types.FileEvent)) {
+	log, err := s.refresh(ctx, name)
+	exists := err == nil
+	var id string
+	var size int64
+	if exists {
+		id, size = log.state()
+	}
+
+	for {
+		if exists && log.isRunning() {
+			s.stream(ctx, log, func() {
+				callback(types.FileEvent{Path: p, Type: "modify"})
+			})
+			_, size = log.state()
+		}
+
+		select {
+		case <-time.After(s.cfg.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		current, err := s.refresh(ctx, name)
+		var event string
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if exists {
+				event = "delete"
+			}
+			exists = false
+		case err != nil:
+			// 连接失败时在下一次检查时重试
+			if ctx.Err() == nil {
+				logger.Debug("检查容器失败", zap.String("container", name), zap.Error(err))
+			}
+			continue
+		default:
+			currentID, currentSize := current.state()
+			switch {
+			case !exists || currentID != id:
+				event = "create"
+			case currentSize != size:
+				event = "modify"
+			}
+			log, exists, id, size = current, true, currentID, currentSize
+		}
+		if event != "" {
+			callback(types.FileEvent{Path: p, Type: event})
+		}
+	}
+}
+
+// stream 读取 follow 模式的日志流，直到容器停止或 ctx 取消
+func (s *DockerSource) stream(ctx context.Context, log *containerLog, onAppend func()) {
+	log.mutex.Lock()
+	log.streaming++
+	log.mutex.Unlock()
+	defer func() {
+		log.mutex.Lock()
+		log.streaming--
+		log.mutex.Unlock()
+	}()
+
+	if err := s.fetch(ctx, log, true, onAppend); err != nil && ctx.Err() == nil {
+		logger.Debug("容器日志流中断", zap.String("container", log.id), zap.Error(err))
+	}
+}
+
+// Close 停止所有跟踪
+func (s *DockerSource) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stopCh)
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// refresh 获取容器的当前状态并读取新增的日志，容器被替换时重新读取
+func (s *DockerSource) refresh(ctx context.Context, name string) (*containerLog, error) {
+	resp, err := s.get(ctx, "/containers/"+name+"/json", nil)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			s.mutex.Lock()
+			delete(s.logs, name)
+			s.mutex.Unlock()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var container dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return nil, fmt.Errorf("解析容器信息失败: %w", err)
+	}
+
+	s.mutex.Lock()
+	log := s.logs[name]
+	if log == nil {
+		log = &containerLog{}
+		s.logs[name] = log
+	}
+	s.mutex.Unlock()
+
+	log.mutex.Lock()
+	if log.id != container.ID {
+		// 同名的新容器，重新读取（旧容器的日志流追加的内容会因为 ID 不同被忽略）
+		created, _ := time.Parse(time.RFC3339Nano, container.Created)
+		log.id, log.tty, log.modTime = container.ID, container.Config.Tty, created
+		log.attrs = nil
+		if len(container.Config.Labels) > 0 {
+			log.attrs, _ = json.Marshal(container.Config.Labels)
+		}
+		log.buf.reset()
+		log.last, log.lastCount, log.fetched = time.Time{}, 0, false
+	}
+	log.running = container.State.Running
+	upToDate := log.fetched && log.streaming > 0
+	log.mutex.Unlock()
+
+	if upToDate {
+		return log, nil
+	}
+	if err := s.fetch(ctx, log, false, nil); err != nil {
+		return nil, fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	return log, nil
+}
+
+// fetch 读取容器的日志并追加到内容末尾，follow 为 true 时一直读取到日志流结束
+func (s *DockerSource) fetch(ctx context.Context, log *containerLog, follow bool, onAppend func()) error {
+	query := url.Values{}
+	query.Set("stdout", "1")
+	query.Set("stderr", "1")
+	query.Set("timestamps", "1")
+	if follow {
+		query.Set("follow", "1")
+	}
+
+	log.mutex.Lock()
+	id, tty := log.id, log.tty
+	if log.fetched {
+		query.Set("since", fmt.Sprintf("%d.%09d", log.last.Unix(), log.last.Nanosecond()))
+	} else if s.cfg.Tail >= 0 {
+		query.Set("tail", strconv.Itoa(s.cfg.Tail))
+	}
+	log.mutex.Unlock()
+
+	resp, err := s.get(ctx, "/containers/"+id+"/logs", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	appender := &logAppender{log: log, id: id, maxBytes: s.cfg.MaxBytes}
+	err = demuxLogs(resp.Body, tty, func(stream, line string) {
+		if appender.append(stream, line) && onAppend != nil {
+			onAppend()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.mutex.Lock()
+	if log.id == id {
+		log.fetched = true
+	}
+	log.mutex.Unlock()
+	return nil
+}
+
+// state 返回容器 ID 和内容大小
+func (l *containerLog) state() (string, int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.id, l.buf.size()
+}
+
+func (l *containerLog) isRunning() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.running
+}
+
+// logAppender 把一次请求读取到的行追加到容器日志，跳过已有的行
+type logAppender struct {
+	log      *containerLog
+	id       string
+	maxBytes int64
+	ts       time.Time
+	count    int // 本次请求中时间为 ts 的行数
+}
+
+// append 追加一行 "<RFC3339Nano 时间> <内容>"，返回是否为新的行
+func (a *logAppender) append(stream, line string) bool {
+	ts := time.Now().UTC()
+	text := line
+	if tsText, rest, ok := strings.Cut(line, " "); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, tsText); err == nil {
+			ts, text = parsed, rest
+		}
+	}
+	if ts.Equal(a.ts) {
+		a.count++
+	} else {
+		a.ts, a.count = ts, 1
+	}
+
+	l := a.log
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.id != a.id || ts.Before(l.last) || (ts.Equal(l.last) && a.count <= l.lastCount) {
+		return false
+	}
+	if ts.Equal(l.last) {
+		l.lastCount++
+	} else {
+		l.last, l.lastCount = ts, 1
+	}
+
+	record, err := json.Marshal(dockerRecord{
+		Log:    text + "\n",
+		Stream: stream,
+		Attrs:  l.attrs,
+		Time:   ts.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return false
+	}
+	l.buf.appendLine(record, a.maxBytes)
+	l.modTime = ts
+	return true
+}
+
+// demuxLogs 按行读取容器日志：TTY 容器是原始输出（都作为标准输出），
+// 否则是多路复用格式，每帧为 8 字节头（流类型、大端序长度）加内容，一行可能跨多帧
+func demuxLogs(r io.Reader, tty bool, emit func(stream, line string)) error {
+	if tty {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				emit("stdout", line)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	streams := map[byte]string{1: "stdout", 2: "stderr"}
+	pending := make(map[byte][]byte)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			// 最后没有换行的内容
+			for streamType, rest := range pending {
+				if len(rest) > 0 {
+					emit(streams[streamType], string(rest))
+				}
+			}
+			return nil
+		}
+
+		frame := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return err
+		}
+		stream, ok := streams[header[0]]
+		if !ok {
+			continue
+		}
+
+		buf := append(pending[header[0]], frame...)
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			emit(stream, string(buf[:i]))
+			buf = buf[i+1:]
+		}
+		pending[header[0]] = append([]byte(nil), buf...)
+	}
+}
+
+// get 发送 GET 请求，非 2xx 的响应转换为错误，404 满足 errors.Is(err, fs.ErrNotExist)
+func (s *DockerSource) get(ctx context.Context, apiPath string, query url.Values) (*http.Response, error) {
+	u := "http://docker/" + dockerAPIVersion + apiPath
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("连接 Docker (%s) 失败: %w", s.socket, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	if body.Message == "" {
+		body.Message = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", body.Message, fs.ErrNotExist)
+	}
+	return nil, fmt.Errorf("Docker 返回错误: %s", body.Message)
+}
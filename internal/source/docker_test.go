@@ -0,0 +1,217 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/source/dockertest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// newTestDockerSource 启动模拟的 Docker Engine API，返回连接它的日志源
+func newTestDockerSource(t *testing.T) (*DockerSource, *dockertest.Server) {
+	t.Helper()
+	return newTestDockerSourceWith(t, config.DockerConfig{})
+}
+
+// newTestDockerSourceWith 与 newTestDockerSource 相同，使用指定的配置
+func newTestDockerSourceWith(t *testing.T, cfg config.DockerConfig) (*DockerSource, *dockertest.Server) {
+	t.Helper()
+
+	server, err := dockertest.NewServer(t.TempDir())
+	if err != nil {
+		t.Fatalf("启动 Docker 服务器失败: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	cfg.PollInterval = 20 * time.Millisecond
+	src, err := NewDockerSource("docker://"+server.Socket, cfg)
+	if err != nil {
+		t.Fatalf("创建容器日志源失败: %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+	return src, server
+}
+
+// readDockerRecords 读取容器日志并解析为 json-file 记录
+func readDockerRecords(t *testing.T, src *DockerSource, p string) []dockerRecord {
+	t.Helper()
+	r, err := src.Open(context.Background(), p, 0, -1)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+
+	var records []dockerRecord
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var record dockerRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("不是 json-file 记录: %q", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestDockerSource_ListStatOpen(t *testing.T) {
+	src, server := newTestDockerSource(t)
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	server.AddContainer("api", map[string]string{"com.example.team": "payments"}, false)
+	server.Log("api", "stdout", "started", at)
+	server.Log("api", "stderr", "ERROR boom", at.Add(time.Second))
+	server.AddContainer("shell", nil, true)
+	server.Log("shell", "stdout", "prompt", at)
+	server.AddContainer("old", nil, false)
+	server.Stop("old")
+
+	files, err := src.Walk(ctx)
+	if err != nil {
+		t.Fatalf("Walk 失败: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if len(names) != 2 || !strings.Contains(strings.Join(names, ","), "api.log") || !strings.Contains(strings.Join(names, ","), "shell.log") {
+		t.Errorf("期望只列出正在运行的容器，得到 %v", names)
+	}
+
+	apiPath := src.Root() + "/api.log"
+	info, err := src.Stat(ctx, apiPath)
+	if err != nil || info.Size == 0 || info.ID == "" || !info.ModTime.Equal(at.Add(time.Second)) {
+		t.Errorf("Stat 结果不正确: %+v, %v", info, err)
+	}
+
+	records := readDockerRecords(t, src, apiPath)
+	if len(records) != 2 || records[0].Log != "started\n" || records[0].Stream != "stdout" ||
+		records[1].Stream != "stderr" || records[1].Time != "2024-03-01T12:00:01Z" ||
+		string(records[1].Attrs) != `{"com.example.team":"payments"}` {
+		t.Errorf("容器日志内容不正确: %+v", records)
+	}
+	if records := readDockerRecords(t, src, src.Root()+"/shell.log"); len(records) != 1 || records[0].Log != "prompt\n" {
+		t.Errorf("TTY 容器的日志内容不正确: %+v", records)
+	}
+
+	// 时间相同的行在重新读取时不重复也不丢失
+	server.Log("api", "stdout", "same second", at.Add(time.Second))
+	if lines, err := src.CountLines(ctx, apiPath); err != nil || lines != 3 {
+		t.Errorf("期望 3 行，得到 %d, %v", lines, err)
+	}
+
+	if _, err := src.Stat(ctx, src.Root()+"/missing.log"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望容器不存在错误，得到 %v", err)
+	}
+	for _, p := range []string{src.Root() + "/api", src.Root() + "/../etc/passwd.log", src.Root() + "/a/b.log"} {
+		if _, ok := src.Resolve(p); ok {
+			t.Errorf("期望拒绝路径: %s", p)
+		}
+	}
+	if info, err := src.Stat(ctx, src.Root()); err != nil || !info.IsDir {
+		t.Errorf("根路径信息不正确: %+v, %v", info, err)
+	}
+}
+
+func TestDockerSource_MaxBytes(t *testing.T) {
+	src, server := newTestDockerSourceWith(t, config.DockerConfig{MaxBytes: 1000})
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	apiPath := src.Root() + "/api.log"
+
+	server.AddContainer("api", nil, false)
+	server.Log("api", "stdout", "line 00", at)
+	first, err := src.Stat(ctx, apiPath)
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	for i := 1; i < 50; i++ {
+		server.Log("api", "stdout", fmt.Sprintf("line %02d", i), at.Add(time.Duration(i)*time.Second))
+	}
+
+	// 大小和行数包括丢弃的内容，已有的字节位置不变
+	info, err := src.Stat(ctx, apiPath)
+	if err != nil || info.Size != first.Size*50 {
+		t.Fatalf("期望大小为 %d，得到 %+v, %v", first.Size*50, info, err)
+	}
+	if lines, err := src.CountLines(ctx, apiPath); err != nil || lines != 50 {
+		t.Errorf("期望 50 行，得到 %d, %v", lines, err)
+	}
+
+	records := readDockerRecords(t, src, apiPath)
+	if len(records) == 0 || len(records) >= 50 || int64(len(records))*first.Size > 1000 || records[len(records)-1].Log != "line 49\n" {
+		t.Errorf("期望只保留最近的行，得到 %d 行: %+v", len(records), records)
+	}
+
+	r, err := src.Open(ctx, apiPath, first.Size*48, first.Size)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	var record dockerRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Log != "line 48\n" {
+		t.Errorf("按字节位置读取不正确: %q, %v", data, err)
+	}
+
+	// 已丢弃的范围没有内容
+	r, err = src.Open(ctx, apiPath, 0, first.Size)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	if data, _ := io.ReadAll(r); len(data) != 0 {
+		t.Errorf("期望已丢弃的范围为空，得到 %q", data)
+	}
+	r.Close()
+}
+
+func TestDockerSource_Follow(t *testing.T) {
+	src, server := newTestDockerSource(t)
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server.AddContainer("api", nil, false)
+	server.Log("api", "stdout", "first", at)
+
+	events := make(chan string, 10)
+	stop, err := src.Follow(src.Root()+"/api.log", func(event types.FileEvent) {
+		events <- event.Type
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	defer stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("期望 %s 事件，得到 %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待 %s 事件超时", want)
+		}
+	}
+
+	// 等待日志流建立后再写入
+	time.Sleep(100 * time.Millisecond)
+	server.Log("api", "stderr", "second", at.Add(time.Second))
+	expect("modify")
+	if records := readDockerRecords(t, src, src.Root()+"/api.log"); len(records) != 2 || records[1].Log != "second\n" {
+		t.Errorf("跟踪到的内容不正确: %+v", records)
+	}
+
+	server.Remove("api")
+	expect("delete")
+
+	server.AddContainer("api", nil, false)
+	expect("create")
+}
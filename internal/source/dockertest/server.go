@@ -0,0 +1,297 @@
+// Package dockertest 提供测试用的 Docker Engine API 服务器，监听 Unix socket
+package dockertest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 模拟 Docker Engine API 中与容器日志相关的接口：
+// GET /_ping、/containers/json、/containers/{name}/json、/containers/{id}/logs
+type Server struct {
+	// Socket Unix socket 的路径
+	Socket string
+
+	listener net.Listener
+	server   *http.Server
+
+	mutex      sync.Mutex
+	containers map[string]*container // 键为容器名称
+	seq        int
+	changed    chan struct{} // 日志或容器变化时关闭并替换，唤醒 follow 请求
+}
+
+type container struct {
+	id      string
+	name    string
+	labels  map[string]string
+	tty     bool
+	running bool
+	created time.Time
+	logs    []logLine
+}
+
+type logLine struct {
+	stream string
+	text   string
+	time   time.Time
+}
+
+// NewServer 在 dir 下的 docker.sock 启动服务器
+func NewServer(dir string) (*Server, error) {
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Socket:     socket,
+		listener:   listener,
+		containers: make(map[string]*container),
+		changed:    make(chan struct{}),
+	}
+	s.server = &http.Server{Handler: http.HandlerFunc(s.handle)}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// AddContainer 添加正在运行的容器，同名的容器被替换（容器 ID 变化），返回容器 ID
+func (s *Server) AddContainer(name string, labels map[string]string, tty bool) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seq++
+	id := fmt.Sprintf("%064x", s.seq)
+	s.containers[name] = &container{
+		id:      id,
+		name:    name,
+		labels:  labels,
+		tty:     tty,
+		running: true,
+		created: time.Now(),
+	}
+	s.notifyLocked()
+	return id
+}
+
+// Log 写入一行容器输出，stream 为 stdout 或 stderr
+func (s *Server) Log(name, stream, text string, at time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c, ok := s.containers[name]; ok {
+		c.logs = append(c.logs, logLine{stream: stream, text: text, time: at.UTC()})
+		s.notifyLocked()
+	}
+}
+
+// Stop 停止容器，正在 follow 的日志请求随之结束
+func (s *Server) Stop(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c, ok := s.containers[name]; ok {
+		c.running = false
+		s.notifyLocked()
+	}
+}
+
+// Remove 删除容器
+func (s *Server) Remove(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.containers, name)
+	s.notifyLocked()
+}
+
+// Close 关闭服务器
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	// 去掉 API 版本前缀，例如 /v1.41
+	p := r.URL.Path
+	if strings.HasPrefix(p, "/v1.") {
+		if i := strings.Index(p[1:], "/"); i >= 0 {
+			p = p[i+1:]
+		}
+	}
+
+	switch {
+	case p == "/_ping":
+		w.Write([]byte("OK"))
+	case p == "/containers/json":
+		s.handleList(w)
+	case strings.HasPrefix(p, "/containers/") && strings.HasSuffix(p, "/json"):
+		s.handleInspect(w, strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/json"))
+	case strings.HasPrefix(p, "/containers/") && strings.HasSuffix(p, "/logs"):
+		s.handleLogs(w, r, strings.TrimSuffix(strings.TrimPrefix(p, "/containers/"), "/logs"))
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func (s *Server) handleList(w http.ResponseWriter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := []map[string]interface{}{}
+	for _, c := range s.containers {
+		if !c.running {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"Id":      c.id,
+			"Names":   []string{"/" + c.name},
+			"Labels":  c.labels,
+			"Created": c.created.Unix(),
+			"State":   "running",
+		})
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// lookupLocked 按名称或 ID 查找容器
+func (s *Server) lookupLocked(ref string) *container {
+	if c, ok := s.containers[ref]; ok {
+		return c
+	}
+	for _, c := range s.containers {
+		if c.id == ref {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleInspect(w http.ResponseWriter, ref string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c := s.lookupLocked(ref)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "No such container: "+ref)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"Id":      c.id,
+		"Name":    "/" + c.name,
+		"Created": c.created.Format(time.RFC3339Nano),
+		"Config":  map[string]interface{}{"Labels": c.labels, "Tty": c.tty},
+		"State":   map[string]interface{}{"Running": c.running},
+	})
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, ref string) {
+	query := r.URL.Query()
+	follow := query.Get("follow") == "1"
+	timestamps := query.Get("timestamps") == "1"
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		secPart, fracPart, _ := strings.Cut(value, ".")
+		sec, _ := strconv.ParseInt(secPart, 10, 64)
+		nsec, _ := strconv.ParseInt((fracPart + "000000000")[:9], 10, 64)
+		since = time.Unix(sec, nsec)
+	}
+	tail := -1
+	if value := query.Get("tail"); value != "" && value != "all" {
+		tail, _ = strconv.Atoi(value)
+	}
+
+	s.mutex.Lock()
+	c := s.lookupLocked(ref)
+	if c == nil {
+		s.mutex.Unlock()
+		writeError(w, http.StatusNotFound, "No such container: "+ref)
+		return
+	}
+	id, tty := c.id, c.tty
+	lines := c.logs
+	if tail >= 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	sent := len(c.logs)
+	changed := s.changed
+	s.mutex.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	write := func(lines []logLine) {
+		for _, line := range lines {
+			if line.time.Before(since) {
+				continue
+			}
+			text := line.text + "\n"
+			if timestamps {
+				text = line.time.Format(time.RFC3339Nano) + " " + text
+			}
+			writeFrame(w, line.stream, text, tty)
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	write(lines)
+
+	for follow {
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+
+		s.mutex.Lock()
+		c := s.lookupLocked(ref)
+		if c == nil || c.id != id {
+			s.mutex.Unlock()
+			return
+		}
+		lines := c.logs[sent:]
+		sent = len(c.logs)
+		running := c.running
+		changed = s.changed
+		s.mutex.Unlock()
+
+		write(lines)
+		if !running {
+			return
+		}
+	}
+}
+
+// writeFrame 写入一帧输出：TTY 容器是原始输出，否则为 8 字节头（流类型、长度）加内容的多路复用格式
+func writeFrame(w http.ResponseWriter, stream, text string, tty bool) {
+	if tty {
+		w.Write([]byte(text))
+		return
+	}
+	header := make([]byte, 8)
+	header[0] = 1
+	if stream == "stderr" {
+		header[0] = 2
+	}
+	binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+	w.Write(header)
+	w.Write([]byte(text))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
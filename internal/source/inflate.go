@@ -0,0 +1,717 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"hash/crc32"
+	"io"
+	"sort"
+	"sync"
+)
+
+const (
+	// deflateSpan 解压索引中相邻恢复点之间最少间隔的解压后字节数，从恢复点解压到目标位置最多解压这么多内容
+	deflateSpan = 4 << 20
+	// deflateWindowSize deflate 的回溯窗口大小，恢复解压需要恢复点之前这么多的解压后内容
+	deflateWindowSize = 1 << 15
+	// huffmanFastBits 不超过这个长度的 Huffman 编码直接查表解码
+	huffmanFastBits = 9
+)
+
+// deflateIndex deflate 数据（raw deflate 或 gzip）的随机访问索引，原理与 zlib 示例中的 zran 相同：
+// 解压经过 deflate 块的边界时，每隔 span 记录一个恢复点（解压后的位置、下一个块在压缩数据中的位位置和之前 32KiB 的解压后内容），
+// 之后读取某个位置时从不晚于它的最近恢复点继续解压，不必从头解压。任何一次解压都会补充经过的恢复点，
+// 完整解压到末尾后记录解压后的大小
+type deflateIndex struct {
+	gzip bool
+	span int64
+
+	mutex  sync.Mutex
+	points []deflatePoint // 按解压后的位置排序，不包括数据开头
+	size   int64          // 解压后的大小，还没有解压到末尾时为 -1
+}
+
+// deflatePoint 恢复点，零值表示数据的开头
+type deflatePoint struct {
+	out    int64  // 解压后的位置
+	bit    int64  // 下一个 deflate 块在压缩数据中的位置，单位为位
+	window []byte // 恢复点之前最多 32KiB 的解压后内容，使用 flate 压缩保存
+}
+
+// newDeflateIndex 创建空的解压索引，gzipped 为 true 时数据是（可能由多个成员拼接的）gzip，否则是 raw deflate
+func newDeflateIndex(gzipped bool, span int64) *deflateIndex {
+	return &deflateIndex{gzip: gzipped, span: span, size: -1}
+}
+
+// point 返回不晚于 target 的最近恢复点
+func (idx *deflateIndex) point(target int64) deflatePoint {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	i := sort.Search(len(idx.points), func(i int) bool { return idx.points[i].out > target })
+	if i == 0 {
+		return deflatePoint{}
+	}
+	return idx.points[i-1]
+}
+
+// Size 返回解压后的大小，还没有完整解压过时返回 false
+func (idx *deflateIndex) Size() (int64, bool) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.size, idx.size >= 0
+}
+
+// wants 判断是否需要在 out 记录恢复点：与不晚于它的最近恢复点相距至少 span
+func (idx *deflateIndex) wants(out int64) bool {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return idx.wantsLocked(out)
+}
+
+func (idx *deflateIndex) wantsLocked(out int64) bool {
+	i := sort.Search(len(idx.points), func(i int) bool { return idx.points[i].out > out })
+	var previous int64
+	if i > 0 {
+		previous = idx.points[i-1].out
+	}
+	return out-previous >= idx.span
+}
+
+// add 记录恢复点，其他解压已经在附近记录过时忽略
+func (idx *deflateIndex) add(p deflatePoint) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if !idx.wantsLocked(p.out) {
+		return
+	}
+	i := sort.Search(len(idx.points), func(i int) bool { return idx.points[i].out > p.out })
+	idx.points = append(idx.points, deflatePoint{})
+	copy(idx.points[i+1:], idx.points[i:])
+	idx.points[i] = p
+}
+
+func (idx *deflateIndex) setSize(size int64) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.size = size
+}
+
+// inflateReader 的状态
+const (
+	inflateGzipHeader = iota
+	inflateBlockHeader
+	inflateStored
+	inflateHuffman
+	inflateGzipTrailer
+	inflateDone
+)
+
+// inflateReader 从恢复点开始解压的 deflate 解码器。标准库的 compress/flate 不能从块的边界和已知的窗口继续解压，
+// 也不能取得块边界的位位置，因此单独实现。解压后的内容写入 32KiB 的环形窗口，读取后才覆盖
+type inflateReader struct {
+	idx *deflateIndex
+	r   *bufio.Reader
+
+	in    int64 // 已经读取的压缩数据的字节数，从压缩数据的开头计算
+	bits  uint64
+	nbits uint
+
+	hist    []byte // 环形窗口，hist[rd:wr] 是已解压但还没有被读取的内容
+	rd, wr  int
+	histLen int   // 窗口中可以回溯的内容的长度
+	out     int64 // 已解压的字节数，即 hist[wr] 的解压后位置
+
+	state    int
+	final    bool
+	lit      *huffmanDecoder
+	dist     *huffmanDecoder
+	copyLen  int
+	copyDist int
+	stored   int
+	tables   [3]huffmanDecoder // 动态 Huffman 块的字面量/长度表、距离表和码长表
+
+	member   int64 // 当前 gzip 成员开始的解压后位置
+	checksum bool  // 从 gzip 成员的开头解压，可以校验 CRC 和长度
+	crc      uint32
+	err      error
+}
+
+// newInflateReader 从恢复点 p 开始解压，r 从压缩数据的第 p.bit/8 个字节开始读取
+func newInflateReader(idx *deflateIndex, p deflatePoint, r io.Reader) (*inflateReader, error) {
+	z := &inflateReader{
+		idx:   idx,
+		r:     bufio.NewReaderSize(r, 64<<10),
+		in:    p.bit / 8,
+		hist:  make([]byte, deflateWindowSize),
+		out:   p.out,
+		state: inflateBlockHeader,
+	}
+	if idx.gzip && p.bit == 0 {
+		z.state = inflateGzipHeader
+	}
+	if p.window != nil {
+		window, err := io.ReadAll(flate.NewReader(bytes.NewReader(p.window)))
+		if err != nil {
+			return nil, err
+		}
+		z.wr = copy(z.hist, window)
+		z.rd, z.histLen = z.wr, z.wr
+	}
+	if skip := uint(p.bit % 8); skip != 0 {
+		b, err := z.readByte()
+		if err != nil {
+			return nil, err
+		}
+		z.bits, z.nbits = uint64(b)>>skip, 8-skip
+	}
+	return z, nil
+}
+
+// position 下一次读取的内容的解压后位置
+func (z *inflateReader) position() int64 {
+	return z.out - int64(z.wr-z.rd)
+}
+
+func (z *inflateReader) Read(p []byte) (int, error) {
+	for {
+		if z.rd < z.wr {
+			n := copy(p, z.hist[z.rd:z.wr])
+			if z.checksum {
+				z.crc = crc32.Update(z.crc, crc32.IEEETable, z.hist[z.rd:z.rd+n])
+			}
+			z.rd += n
+			return n, nil
+		}
+		if z.err != nil {
+			return 0, z.err
+		}
+		if z.wr == len(z.hist) {
+			z.rd, z.wr = 0, 0
+		}
+		z.err = z.step()
+	}
+}
+
+// step 解压一部分内容，最多写满环形窗口的剩余部分
+func (z *inflateReader) step() error {
+	switch z.state {
+	case inflateGzipHeader:
+		b, err := z.readByte()
+		if err != nil {
+			return err
+		}
+		return z.gzipHeader(b)
+	case inflateBlockHeader:
+		return z.blockHeader()
+	case inflateStored:
+		return z.storedBlock()
+	case inflateHuffman:
+		return z.huffmanBlock()
+	case inflateGzipTrailer:
+		return z.gzipTrailer()
+	default:
+		return io.EOF
+	}
+}
+
+// gzipHeader 读取 gzip 成员的头部，first 为已经读取的第一个字节
+func (z *inflateReader) gzipHeader(first byte) error {
+	var header [10]byte
+	header[0] = first
+	for i := 1; i < len(header); i++ {
+		b, err := z.readByte()
+		if err != nil {
+			return err
+		}
+		header[i] = b
+	}
+	flags := header[3]
+	if header[0] != 0x1f || header[1] != 0x8b || header[2] != 8 || flags&0xe0 != 0 {
+		return gzip.ErrHeader
+	}
+	if flags&0x04 != 0 { // FEXTRA
+		lo, err := z.readByte()
+		if err != nil {
+			return err
+		}
+		hi, err := z.readByte()
+		if err != nil {
+			return err
+		}
+		for n := int(lo) | int(hi)<<8; n > 0; n-- {
+			if _, err := z.readByte(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, flag := range []byte{0x08, 0x10} { // FNAME、FCOMMENT，以 0 结尾
+		if flags&flag == 0 {
+			continue
+		}
+		for {
+			b, err := z.readByte()
+			if err != nil {
+				return err
+			}
+			if b == 0 {
+				break
+			}
+		}
+	}
+	if flags&0x02 != 0 { // FHCRC
+		for i := 0; i < 2; i++ {
+			if _, err := z.readByte(); err != nil {
+				return err
+			}
+		}
+	}
+
+	z.member, z.checksum, z.crc = z.out, true, 0
+	z.histLen, z.final = 0, false
+	z.state = inflateBlockHeader
+	return nil
+}
+
+// gzipTrailer 校验 gzip 成员的 CRC 和长度，之后还有数据时作为下一个成员读取
+func (z *inflateReader) gzipTrailer() error {
+	z.alignToByte()
+	crc, err := z.getBits(32)
+	if err != nil {
+		return err
+	}
+	size, err := z.getBits(32)
+	if err != nil {
+		return err
+	}
+	if z.checksum && (crc != z.crc || size != uint32(z.out-z.member)) {
+		return gzip.ErrChecksum
+	}
+
+	b, err := z.r.ReadByte()
+	if err == io.EOF {
+		z.state = inflateDone
+		z.idx.setSize(z.out)
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	z.in++
+	return z.gzipHeader(b)
+}
+
+// blockHeader 读取 deflate 块的头部，按索引的间隔在块的边界记录恢复点
+func (z *inflateReader) blockHeader() error {
+	if z.final {
+		if z.idx.gzip {
+			z.state = inflateGzipTrailer
+			return nil
+		}
+		z.state = inflateDone
+		z.idx.setSize(z.out)
+		return io.EOF
+	}
+	if z.idx.wants(z.out) {
+		z.idx.add(deflatePoint{out: z.out, bit: z.bitPosition(), window: z.window()})
+	}
+
+	header, err := z.getBits(3)
+	if err != nil {
+		return err
+	}
+	z.final = header&1 == 1
+	switch header >> 1 {
+	case 0:
+		z.alignToByte()
+		v, err := z.getBits(32)
+		if err != nil {
+			return err
+		}
+		if v&0xffff != ^v>>16 {
+			return z.corrupt()
+		}
+		z.stored = int(v & 0xffff)
+		z.state = inflateStored
+	case 1:
+		fixedHuffmanOnce.Do(initFixedHuffman)
+		z.lit, z.dist = &fixedLiteral, &fixedDistance
+		z.state = inflateHuffman
+	case 2:
+		if err := z.dynamicTables(); err != nil {
+			return err
+		}
+		z.state = inflateHuffman
+	default:
+		return z.corrupt()
+	}
+	return nil
+}
+
+// storedBlock 复制未压缩块的内容
+func (z *inflateReader) storedBlock() error {
+	for z.stored > 0 && z.nbits >= 8 && z.wr < len(z.hist) {
+		z.hist[z.wr] = byte(z.bits)
+		z.bits >>= 8
+		z.nbits -= 8
+		z.stored--
+		z.advance(1)
+	}
+	if n := min(z.stored, len(z.hist)-z.wr); n > 0 && z.nbits == 0 {
+		if _, err := io.ReadFull(z.r, z.hist[z.wr:z.wr+n]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		z.in += int64(n)
+		z.stored -= n
+		z.advance(n)
+	}
+	if z.stored == 0 {
+		z.state = inflateBlockHeader
+	}
+	return nil
+}
+
+var (
+	lengthBase  = [29]int{3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 15, 17, 19, 23, 27, 31, 35, 43, 51, 59, 67, 83, 99, 115, 131, 163, 195, 227, 258}
+	lengthExtra = [29]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5, 0}
+	distBase    = [30]int{1, 2, 3, 4, 5, 7, 9, 13, 17, 25, 33, 49, 65, 97, 129, 193, 257, 385, 513, 769, 1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577}
+	distExtra   = [30]uint{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13}
+	// codeLengthOrder 动态 Huffman 块中码长表的码长的顺序
+	codeLengthOrder = [19]int{16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15}
+)
+
+// huffmanBlock 解码压缩块，直到块结束或环形窗口写满；写满时未完成的复制在下一次继续
+func (z *inflateReader) huffmanBlock() error {
+	for z.wr < len(z.hist) {
+		if z.copyLen > 0 {
+			z.copyMatch()
+			continue
+		}
+		sym, err := z.decode(z.lit)
+		if err != nil {
+			return err
+		}
+		switch {
+		case sym < 256:
+			z.hist[z.wr] = byte(sym)
+			z.advance(1)
+		case sym == 256:
+			z.state = inflateBlockHeader
+			return nil
+		case sym <= 285:
+			extra, err := z.getBits(lengthExtra[sym-257])
+			if err != nil {
+				return err
+			}
+			length := lengthBase[sym-257] + int(extra)
+			dsym, err := z.decode(z.dist)
+			if err != nil {
+				return err
+			}
+			if dsym >= len(distBase) {
+				return z.corrupt()
+			}
+			extra, err = z.getBits(distExtra[dsym])
+			if err != nil {
+				return err
+			}
+			dist := distBase[dsym] + int(extra)
+			if dist > z.histLen {
+				return z.corrupt()
+			}
+			z.copyLen, z.copyDist = length, dist
+		default:
+			return z.corrupt()
+		}
+	}
+	return nil
+}
+
+// copyMatch 复制之前 copyDist 处的内容，每次复制不超过 copyDist，重叠的复制按字节重复之前的内容
+func (z *inflateReader) copyMatch() {
+	n := min(z.copyLen, len(z.hist)-z.wr)
+	src := z.wr - z.copyDist
+	if src < 0 {
+		src += len(z.hist)
+	}
+	for done := 0; done < n; {
+		k := min(n-done, z.copyDist, len(z.hist)-src)
+		copy(z.hist[z.wr+done:z.wr+done+k], z.hist[src:src+k])
+		done += k
+		if src += k; src == len(z.hist) {
+			src = 0
+		}
+	}
+	z.copyLen -= n
+	z.advance(n)
+}
+
+// dynamicTables 读取动态 Huffman 块的编码表
+func (z *inflateReader) dynamicTables() error {
+	v, err := z.getBits(14)
+	if err != nil {
+		return err
+	}
+	nlit, ndist, nclen := int(v&31)+257, int(v>>5&31)+1, int(v>>10)+4
+	if nlit > 286 || ndist > 30 {
+		return z.corrupt()
+	}
+
+	var clens [19]uint8
+	for i := 0; i < nclen; i++ {
+		v, err := z.getBits(3)
+		if err != nil {
+			return err
+		}
+		clens[codeLengthOrder[i]] = uint8(v)
+	}
+	codeLengths := &z.tables[2]
+	if !codeLengths.init(clens[:]) {
+		return z.corrupt()
+	}
+
+	var lengths [286 + 30]uint8
+	for i := 0; i < nlit+ndist; {
+		sym, err := z.decode(codeLengths)
+		if err != nil {
+			return err
+		}
+		if sym < 16 {
+			lengths[i] = uint8(sym)
+			i++
+			continue
+		}
+		var repeat uint32
+		var value uint8
+		switch sym {
+		case 16:
+			if i == 0 {
+				return z.corrupt()
+			}
+			repeat, err = z.getBits(2)
+			repeat += 3
+			value = lengths[i-1]
+		case 17:
+			repeat, err = z.getBits(3)
+			repeat += 3
+		default:
+			repeat, err = z.getBits(7)
+			repeat += 11
+		}
+		if err != nil {
+			return err
+		}
+		if i+int(repeat) > nlit+ndist {
+			return z.corrupt()
+		}
+		for ; repeat > 0; repeat-- {
+			lengths[i] = value
+			i++
+		}
+	}
+	if lengths[256] == 0 {
+		return z.corrupt()
+	}
+
+	z.lit, z.dist = &z.tables[0], &z.tables[1]
+	if !z.lit.init(lengths[:nlit]) || !z.dist.init(lengths[nlit:nlit+ndist]) {
+		return z.corrupt()
+	}
+	return nil
+}
+
+// decode 解码一个 Huffman 符号：先按窗口中的位查表，编码更长或剩余的数据不够查表时逐位解码
+func (z *inflateReader) decode(h *huffmanDecoder) (int, error) {
+	for z.nbits < huffmanFastBits {
+		b, err := z.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		z.in++
+		z.bits |= uint64(b) << z.nbits
+		z.nbits += 8
+	}
+	if z.nbits >= huffmanFastBits {
+		if e := h.fast[z.bits&(1<<huffmanFastBits-1)]; e != 0 {
+			n := uint(e & 15)
+			z.bits >>= n
+			z.nbits -= n
+			return int(e >> 4), nil
+		}
+	}
+
+	code, first, index := 0, 0, 0
+	for length := 1; length <= 15; length++ {
+		bit, err := z.getBits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= int(bit)
+		count := int(h.count[length])
+		if code-count < first {
+			return int(h.symbol[index+code-first]), nil
+		}
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+	return 0, z.corrupt()
+}
+
+// advance 已经在 hist[wr:] 写入 n 个字节
+func (z *inflateReader) advance(n int) {
+	z.wr += n
+	z.out += int64(n)
+	z.histLen = min(z.histLen+n, len(z.hist))
+}
+
+// window 返回最后 histLen 个解压后的字节，使用 flate 压缩
+func (z *inflateReader) window() []byte {
+	window := make([]byte, 0, z.histLen)
+	if z.histLen > z.wr {
+		window = append(window, z.hist[len(z.hist)-(z.histLen-z.wr):]...)
+	}
+	window = append(window, z.hist[max(0, z.wr-z.histLen):z.wr]...)
+
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	w.Write(window)
+	w.Close()
+	return buf.Bytes()
+}
+
+// bitPosition 下一个未读取的位在压缩数据中的位置
+func (z *inflateReader) bitPosition() int64 {
+	return z.in*8 - int64(z.nbits)
+}
+
+func (z *inflateReader) readByte() (byte, error) {
+	b, err := z.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	z.in++
+	return b, nil
+}
+
+// getBits 读取 n 个位（n 不超过 32），先读取的位在低位
+func (z *inflateReader) getBits(n uint) (uint32, error) {
+	for z.nbits < n {
+		b, err := z.readByte()
+		if err != nil {
+			return 0, err
+		}
+		z.bits |= uint64(b) << z.nbits
+		z.nbits += 8
+	}
+	v := uint32(z.bits & (1<<n - 1))
+	z.bits >>= n
+	z.nbits -= n
+	return v, nil
+}
+
+func (z *inflateReader) alignToByte() {
+	z.bits >>= z.nbits % 8
+	z.nbits -= z.nbits % 8
+}
+
+func (z *inflateReader) corrupt() error {
+	return flate.CorruptInputError(z.bitPosition() / 8)
+}
+
+// huffmanDecoder 范式 Huffman 编码的解码表：不超过 huffmanFastBits 位的编码直接查表，更长的编码逐位解码
+type huffmanDecoder struct {
+	count  [16]uint16                   // 每种长度的编码数量
+	symbol []uint16                     // 按编码排序的符号
+	fast   [1 << huffmanFastBits]uint16 // 符号<<4 | 编码长度，0 表示需要逐位解码
+}
+
+// init 按每个符号的编码长度建立解码表，编码超额时返回 false；允许不完整的编码，解码到未使用的编码时报错
+func (h *huffmanDecoder) init(lengths []uint8) bool {
+	h.count = [16]uint16{}
+	h.fast = [1 << huffmanFastBits]uint16{}
+	for _, length := range lengths {
+		h.count[length]++
+	}
+	h.count[0] = 0
+	left := 1
+	for length := 1; length <= 15; length++ {
+		left = left<<1 - int(h.count[length])
+		if left < 0 {
+			return false
+		}
+	}
+
+	var offsets [16]int
+	for length := 1; length < 15; length++ {
+		offsets[length+1] = offsets[length] + int(h.count[length])
+	}
+	h.symbol = h.symbol[:0]
+	h.symbol = append(h.symbol, make([]uint16, offsets[15]+int(h.count[15]))...)
+	for sym, length := range lengths {
+		if length != 0 {
+			h.symbol[offsets[length]] = uint16(sym)
+			offsets[length]++
+		}
+	}
+
+	// 编码按先读取的位在高位排列，查表的索引是按读取顺序排列的位，需要反转
+	code, index := 0, 0
+	for length := 1; length <= huffmanFastBits; length++ {
+		for i := 0; i < int(h.count[length]); i++ {
+			reversed := 0
+			for b := 0; b < length; b++ {
+				reversed |= (code >> b & 1) << (length - 1 - b)
+			}
+			entry := h.symbol[index]<<4 | uint16(length)
+			for j := reversed; j < len(h.fast); j += 1 << length {
+				h.fast[j] = entry
+			}
+			code++
+			index++
+		}
+		code <<= 1
+	}
+	return true
+}
+
+var (
+	fixedHuffmanOnce sync.Once
+	fixedLiteral     huffmanDecoder
+	fixedDistance    huffmanDecoder
+)
+
+// initFixedHuffman 建立固定 Huffman 块的编码表
+func initFixedHuffman() {
+	var lengths [288]uint8
+	for i := range lengths {
+		switch {
+		case i < 144:
+			lengths[i] = 8
+		case i < 256:
+			lengths[i] = 9
+		case i < 280:
+			lengths[i] = 7
+		default:
+			lengths[i] = 8
+		}
+	}
+	fixedLiteral.init(lengths[:])
+	var distances [30]uint8
+	for i := range distances {
+		distances[i] = 5
+	}
+	fixedDistance.init(distances[:])
+}
@@ -0,0 +1,124 @@
+package source
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// testInflateData 测试解压使用的内容：重复的日志行（长距离复制）、随机字节（未压缩块）和两者混合
+func testInflateData() map[string][]byte {
+	random := make([]byte, 96<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	logs := []byte(numberedLines("app", 12000))
+	mixed := append(append(append([]byte(nil), logs[:64<<10]...), random[:32<<10]...), logs[64<<10:]...)
+	return map[string][]byte{"logs": logs, "random": random, "mixed": mixed}
+}
+
+func deflateBytes(t *testing.T, data []byte, level int, flushEvery int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(data) > 0 {
+		n := len(data)
+		if flushEvery > 0 && n > flushEvery {
+			n = flushEvery
+		}
+		w.Write(data[:n])
+		data = data[n:]
+		if flushEvery > 0 {
+			w.Flush()
+		}
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// checkInflate 从头解压并比较内容，然后从记录的每个恢复点继续解压，内容应与原始内容的对应部分相同
+func checkInflate(t *testing.T, compressed, data []byte, gzipped bool) *deflateIndex {
+	t.Helper()
+	idx := newDeflateIndex(gzipped, 16<<10)
+	zr, err := newInflateReader(idx, deflatePoint{}, bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("解压的内容不正确: 长度 %d/%d, %v", len(got), len(data), err)
+	}
+	if size, ok := idx.Size(); !ok || size != int64(len(data)) {
+		t.Errorf("解压后的大小不正确: %d, %v", size, ok)
+	}
+
+	for _, p := range idx.points {
+		zr, err := newInflateReader(idx, p, bytes.NewReader(compressed[p.bit/8:]))
+		if err != nil {
+			t.Fatalf("从恢复点 %d 解压失败: %v", p.out, err)
+		}
+		if zr.position() != p.out {
+			t.Errorf("恢复点的位置不正确: %d != %d", zr.position(), p.out)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil || !bytes.Equal(got, data[p.out:]) {
+			t.Fatalf("从恢复点 %d 解压的内容不正确: 长度 %d, %v", p.out, len(got), err)
+		}
+	}
+	return idx
+}
+
+func TestInflateReader_Deflate(t *testing.T) {
+	levels := []int{flate.NoCompression, flate.BestSpeed, flate.DefaultCompression, flate.BestCompression, flate.HuffmanOnly}
+	for name, data := range testInflateData() {
+		for _, level := range levels {
+			idx := checkInflate(t, deflateBytes(t, data, level, 0), data, false)
+			if len(idx.points) == 0 {
+				t.Errorf("%s/%d: 期望记录恢复点", name, level)
+			}
+		}
+		// Flush 写入的空的未压缩块
+		checkInflate(t, deflateBytes(t, data, flate.DefaultCompression, 10000), data, false)
+	}
+}
+
+func TestInflateReader_GzipMembers(t *testing.T) {
+	data := testInflateData()
+	first, second := data["logs"], data["mixed"]
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Name, gz.Comment, gz.Extra = "app.log", "rotated", []byte("extra")
+	gz.Write(first)
+	gz.Close()
+	gz = gzip.NewWriter(&buf)
+	gz.Write(second)
+	gz.Close()
+	compressed := buf.Bytes()
+
+	idx := checkInflate(t, compressed, append(append([]byte(nil), first...), second...), true)
+	if last := idx.points[len(idx.points)-1]; last.out <= int64(len(first)) {
+		t.Errorf("期望第二个成员中也有恢复点，最后一个在 %d", last.out)
+	}
+
+	// CRC 不一致、数据被截断
+	corrupted := append([]byte(nil), compressed...)
+	corrupted[len(corrupted)-5] ^= 0xff
+	zr, _ := newInflateReader(newDeflateIndex(true, deflateSpan), deflatePoint{}, bytes.NewReader(corrupted))
+	if _, err := io.ReadAll(zr); !errors.Is(err, gzip.ErrChecksum) {
+		t.Errorf("期望校验和错误，得到 %v", err)
+	}
+	zr, _ = newInflateReader(newDeflateIndex(true, deflateSpan), deflatePoint{}, bytes.NewReader(compressed[:len(compressed)/2]))
+	if _, err := io.ReadAll(zr); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("期望数据不完整错误，得到 %v", err)
+	}
+	zr, _ = newInflateReader(newDeflateIndex(true, deflateSpan), deflatePoint{}, bytes.NewReader([]byte("not gzip data")))
+	if _, err := io.ReadAll(zr); !errors.Is(err, gzip.ErrHeader) {
+		t.Errorf("期望 gzip 头部错误，得到 %v", err)
+	}
+}
@@ -0,0 +1,697 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// unitNamePattern systemd 单元名称（实例名中的特殊字符被转义为 \xNN）
+var unitNamePattern = regexp.MustCompile(`^[a-zA-Z0-9:_.@\\-]+$`)
+
+// JournalSource 读取 systemd journal 目录（例如 /var/log/journal）中的日志
+// 每个 systemd 单元是根路径下的一个文件 <单元名称>.log，内容是 journalctl -o json 格式的记录，每行一条。
+// 直接读取目录和机器 ID 子目录中的 journal 文件（见 journal_file.go），记录的字段被压缩、
+// 文件格式不支持或配置了 journal.exec 时通过 journalctl 读取，两种方式使用相同的游标。
+// 读取过的内容缓存在内存中，之后从最后一条记录之后只读取新增的记录，每个单元最多保留 journal.maxBytes 字节
+type JournalSource struct {
+	root string // journal:///var/log/journal
+	dir  string
+	cfg  config.JournalConfig
+
+	mutex      sync.Mutex
+	units      map[string]*unitLog // 键为单元名称
+	useCommand bool                // 只通过 journalctl 读取
+	closed     bool
+	stopCh     chan struct{}
+
+	filesMutex sync.Mutex
+	files      map[string]*journalFile // 已打开的 journal 文件，键为路径
+}
+
+// unitLog 单元的日志内容
+type unitLog struct {
+	mutex   sync.Mutex
+	buf     lineBuffer
+	modTime time.Time
+	cursor  string // 最后一条记录的游标
+	fetched bool
+	// streaming 正在 follow 的 journalctl 数量，大于 0 时内容由它更新，不需要再次读取
+	streaming int
+
+	// readMutex 保证同一个单元同时只有一次直接读取，positions 只在持有它时访问
+	readMutex sync.Mutex
+	positions map[[16]byte]journalPos // 键为 journal 文件的 ID
+}
+
+// journalCursor journal 记录中用于续读的字段
+type journalCursor struct {
+	Cursor   string `json:"__CURSOR"`
+	Realtime string `json:"__REALTIME_TIMESTAMP"`
+}
+
+// NewJournalSource 创建 systemd journal 日志源，root 例如 journal:///var/log/journal
+func NewJournalSource(root string, cfg config.JournalConfig) (*JournalSource, error) {
+	cfg = cfg.WithDefaults()
+
+	dir := strings.TrimPrefix(root, config.JournalPathPrefix)
+	if !strings.HasPrefix(root, config.JournalPathPrefix) || !strings.HasPrefix(dir, "/") {
+		return nil, fmt.Errorf("无效的 journal 路径: %s", root)
+	}
+	dir = path.Clean(dir)
+
+	return &JournalSource{
+		root:       config.JournalPathPrefix + dir,
+		dir:        dir,
+		cfg:        cfg,
+		units:      make(map[string]*unitLog),
+		useCommand: cfg.Exec,
+		stopCh:     make(chan struct{}),
+		files:      make(map[string]*journalFile),
+	}, nil
+}
+
+// Root 源的根路径
+func (s *JournalSource) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否为根路径或根路径下的 <单元名称>.log
+func (s *JournalSource) Resolve(p string) (string, bool) {
+	if p == s.root {
+		return p, true
+	}
+	name, ok := strings.CutPrefix(p, s.root+"/")
+	if !ok || !strings.HasSuffix(name, ".log") {
+		return "", false
+	}
+	unit := strings.TrimSuffix(name, ".log")
+	if !unitNamePattern.MatchString(unit) || unit == "." || unit == ".." {
+		return "", false
+	}
+	return p, true
+}
+
+// unitName 返回路径对应的单元名称
+func (s *JournalSource) unitName(p string) (string, error) {
+	if _, ok := s.Resolve(p); !ok || p == s.root {
+		return "", fmt.Errorf("路径不是 %s 下的单元日志: %s", s.root, p)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(p, s.root+"/"), ".log"), nil
+}
+
+// List 列出有日志的单元，单元日志没有子目录
+func (s *JournalSource) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	if dir != s.root {
+		return nil, fmt.Errorf("路径不是目录: %s", dir)
+	}
+	return s.Walk(ctx)
+}
+
+// Walk 列出有日志的单元，已经读取过日志的单元带有当前大小
+func (s *JournalSource) Walk(ctx context.Context) ([]FileInfo, error) {
+	units, err := s.listUnits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(units))
+	for _, unit := range units {
+		info := FileInfo{
+			Path: s.root + "/" + unit + ".log",
+			Name: unit + ".log",
+			ID:   unit,
+		}
+		s.mutex.Lock()
+		log := s.units[unit]
+		s.mutex.Unlock()
+		if log != nil {
+			log.mutex.Lock()
+			info.Size = log.buf.size()
+			info.ModTime = log.modTime
+			log.mutex.Unlock()
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// Stat 获取单元日志的信息，会读取新增的记录；根路径检查 journal 目录是否存在
+func (s *JournalSource) Stat(ctx context.Context, p string) (FileInfo, error) {
+	if p == s.root {
+		info, err := os.Stat(s.dir)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		if !info.IsDir() {
+			return FileInfo{}, fmt.Errorf("journal 路径不是目录: %s", s.dir)
+		}
+		return FileInfo{Path: s.root, Name: path.Base(s.dir), ModTime: info.ModTime(), IsDir: true}, nil
+	}
+
+	unit, err := s.unitName(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	log, err := s.refresh(ctx, unit)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return FileInfo{
+		Path:    p,
+		Name:    unit + ".log",
+		Size:    log.buf.size(),
+		ModTime: log.modTime,
+		ID:      unit,
+	}, nil
+}
+
+// CountLines 统计单元日志的条数
+func (s *JournalSource) CountLines(ctx context.Context, p string) (int64, error) {
+	unit, err := s.unitName(p)
+	if err != nil {
+		return 0, err
+	}
+	log, err := s.refresh(ctx, unit)
+	if err != nil {
+		return 0, err
+	}
+
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.buf.lines, nil
+}
+
+// Open 从 offset 开始读取单元日志，length 为负数时读取到末尾
+// 超过 journal.maxBytes 被丢弃的内容不能再读取，offset 早于保留的内容时从保留的第一条记录开始
+func (s *JournalSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	unit, err := s.unitName(p)
+	if err != nil {
+		return nil, err
+	}
+	log, err := s.refresh(ctx, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	log.mutex.Lock()
+	r := log.buf.reader(offset, length)
+	log.mutex.Unlock()
+	return io.NopCloser(r), nil
+}
+
+// Follow 跟踪单元日志，新的记录以 modify 事件通知：直接读取 journal 文件时按 journal.pollInterval 检查，
+// 通过 journalctl 读取时运行 journalctl -f 从最后一条记录的游标开始跟踪，退出后定期重试；
+// 单元出现日志时通知 create 事件
+func (s *JournalSource) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	unit, err := s.unitName(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go s.follow(ctx, p, unit, callback)
+	return cancel, nil
+}
+
+func (s *JournalSource) follow(ctx context.Context, p, unit string, callback func(types.FileEvent)) {
+	log, err := s.refresh(ctx, unit)
+	exists := err == nil
+	var size int64
+	if exists {
+		size = log.size()
+	}
+
+	for {
+		if exists && !s.direct() {
+			s.stream(ctx, unit, log, func() {
+				callback(types.FileEvent{Path: p, Type: "modify"})
+			})
+			size = log.size()
+		}
+
+		select {
+		case <-time.After(s.cfg.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		current, err := s.refresh(ctx, unit)
+		var event string
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			if exists {
+				event = "delete"
+			}
+			exists = false
+		case err != nil:
+			if ctx.Err() == nil {
+				logger.Debug("读取单元日志失败", zap.String("unit", unit), zap.Error(err))
+			}
+			continue
+		default:
+			currentSize := current.size()
+			switch {
+			case !exists:
+				event = "create"
+			case currentSize != size:
+				event = "modify"
+			}
+			log, exists, size = current, true, currentSize
+		}
+		if event != "" {
+			callback(types.FileEvent{Path: p, Type: event})
+		}
+	}
+}
+
+// stream 运行 journalctl -f，直到它退出或 ctx 取消
+func (s *JournalSource) stream(ctx context.Context, unit string, log *unitLog, onAppend func()) {
+	log.mutex.Lock()
+	log.streaming++
+	log.mutex.Unlock()
+	defer func() {
+		log.mutex.Lock()
+		log.streaming--
+		log.mutex.Unlock()
+	}()
+
+	if err := s.fetch(ctx, unit, log, true, onAppend); err != nil && ctx.Err() == nil {
+		logger.Debug("journalctl 跟踪中断", zap.String("unit", unit), zap.Error(err))
+	}
+}
+
+// Close 停止所有跟踪
+func (s *JournalSource) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stopCh)
+
+	s.filesMutex.Lock()
+	for _, f := range s.files {
+		f.file.Close()
+	}
+	s.files = make(map[string]*journalFile)
+	s.filesMutex.Unlock()
+	return nil
+}
+
+// direct 是否直接读取 journal 文件
+func (s *JournalSource) direct() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !s.useCommand
+}
+
+// fallback journal 文件格式不支持时改为只通过 journalctl 读取
+func (s *JournalSource) fallback(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.useCommand {
+		s.useCommand = true
+		logger.Warn("无法直接读取 journal 文件，改用 journalctl", zap.String("dir", s.dir), zap.Error(err))
+	}
+}
+
+// journalFiles 返回 journal 目录和机器 ID 子目录中的 journal 文件，已打开的文件（包括被归档改名的）继续使用
+func (s *JournalSource) journalFiles() ([]*journalFile, error) {
+	if _, err := os.Stat(s.dir); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, pattern := range []string{"*.journal", "*.journal~", "*/*.journal", "*/*.journal~"} {
+		matches, err := filepath.Glob(filepath.Join(s.dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	s.filesMutex.Lock()
+	defer s.filesMutex.Unlock()
+
+	files := make(map[string]*journalFile, len(paths))
+	list := make([]*journalFile, 0, len(paths))
+	var lastErr error
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		var f *journalFile
+		for _, opened := range s.files {
+			if os.SameFile(opened.info, info) {
+				f = opened
+				break
+			}
+		}
+		if f == nil {
+			if f, err = openJournalFile(p); err != nil {
+				if errors.Is(err, errJournalUnsupported) {
+					return nil, err
+				}
+				// 正在创建或已损坏的文件，journalctl 同样跳过
+				logger.Debug("跳过无法读取的 journal 文件", zap.String("path", p), zap.Error(err))
+				lastErr = err
+				continue
+			}
+		}
+		files[p] = f
+		list = append(list, f)
+	}
+	if len(list) == 0 && lastErr != nil && !errors.Is(lastErr, errJournalInvalid) {
+		return nil, lastErr
+	}
+
+	used := make(map[*journalFile]bool, len(files))
+	for _, f := range files {
+		used[f] = true
+	}
+	for _, f := range s.files {
+		if !used[f] {
+			f.file.Close()
+		}
+	}
+	s.files = files
+	return list, nil
+}
+
+// listUnits 列出 journal 中出现过的单元
+func (s *JournalSource) listUnits(ctx context.Context) ([]string, error) {
+	if s.direct() {
+		units, err := s.listFileUnits()
+		if !errors.Is(err, errJournalUnsupported) {
+			return units, err
+		}
+		s.fallback(err)
+	}
+
+	var units []string
+	err := s.run(ctx, []string{"--field=_SYSTEMD_UNIT"}, func(line []byte) {
+		if unit := string(line); unitNamePattern.MatchString(unit) {
+			units = append(units, unit)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(units)
+	return units, nil
+}
+
+// listFileUnits 从 journal 文件中列出出现过的单元
+func (s *JournalSource) listFileUnits() ([]string, error) {
+	files, err := s.journalFiles()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, f := range files {
+		names, err := f.unitNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+	units := make([]string, 0, len(seen))
+	for unit := range seen {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units, nil
+}
+
+// refresh 读取单元新增的记录；第一次读取时检查单元是否存在
+func (s *JournalSource) refresh(ctx context.Context, unit string) (*unitLog, error) {
+	s.mutex.Lock()
+	log := s.units[unit]
+	s.mutex.Unlock()
+
+	if log == nil {
+		units, err := s.listUnits(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if i := sort.SearchStrings(units, unit); i == len(units) || units[i] != unit {
+			return nil, fmt.Errorf("单元 %s 没有日志: %w", unit, fs.ErrNotExist)
+		}
+
+		s.mutex.Lock()
+		if log = s.units[unit]; log == nil {
+			log = &unitLog{}
+			s.units[unit] = log
+		}
+		s.mutex.Unlock()
+	}
+
+	log.mutex.Lock()
+	upToDate := log.fetched && log.streaming > 0
+	log.mutex.Unlock()
+	if upToDate {
+		return log, nil
+	}
+
+	if err := s.fetch(ctx, unit, log, false, nil); err != nil {
+		return nil, fmt.Errorf("读取单元日志失败: %w", err)
+	}
+	return log, nil
+}
+
+// fetch 读取最后一条记录之后的记录并追加到内容末尾，follow 为 true 时运行 journalctl -f 一直读取到它退出
+func (s *JournalSource) fetch(ctx context.Context, unit string, log *unitLog, follow bool, onAppend func()) error {
+	if !follow && s.direct() {
+		err := s.fetchFiles(ctx, unit, log, onAppend)
+		if !errors.Is(err, errJournalUnsupported) {
+			return err
+		}
+		s.fallback(err)
+	}
+	return s.fetchCommand(ctx, unit, log, follow, onAppend)
+}
+
+// fetchFiles 直接从 journal 文件读取单元新增的记录，多个文件中的记录按时间排序
+// 读到字段被压缩的记录时，从这条记录开始改用 journalctl 读取；journalctl 读取失败时不保存读取位置，下一次重新读取
+func (s *JournalSource) fetchFiles(ctx context.Context, unit string, log *unitLog, onAppend func()) error {
+	log.readMutex.Lock()
+	defer log.readMutex.Unlock()
+
+	files, err := s.journalFiles()
+	if err != nil {
+		return err
+	}
+
+	log.mutex.Lock()
+	cursor, fetched := log.cursor, log.fetched
+	log.mutex.Unlock()
+
+	positions := make(map[[16]byte]journalPos, len(files))
+	var entries []journalEntry
+	for _, f := range files {
+		data, ok, err := f.unitData(unit)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		added, pos, err := f.entries(data, log.positions[f.fileID])
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", f.file.Name(), err)
+		}
+		positions[f.fileID] = pos
+		entries = append(entries, added...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].realtime != entries[j].realtime {
+			return entries[i].realtime < entries[j].realtime
+		}
+		return entries[i].seqnum < entries[j].seqnum
+	})
+
+	switch {
+	case cursor != "":
+		// 之前通过 journalctl 读取过的记录
+		for len(entries) > 0 && !entries[0].after(cursor) {
+			entries = entries[1:]
+		}
+	case !fetched && s.cfg.Tail >= 0 && len(entries) > s.cfg.Tail:
+		entries = entries[len(entries)-s.cfg.Tail:]
+	}
+
+	appender := &journalAppender{log: log, prev: cursor, maxBytes: s.cfg.MaxBytes}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := entry.record()
+		if errors.Is(err, errJournalCompressed) {
+			logger.Debug("journal 记录的字段已压缩，通过 journalctl 读取", zap.String("unit", unit))
+			if err := s.fetchCommand(ctx, unit, log, false, onAppend); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %w", entry.file.file.Name(), err)
+		}
+		if appender.append(record) && onAppend != nil {
+			onAppend()
+		}
+	}
+
+	log.positions = positions
+	log.mutex.Lock()
+	log.fetched = true
+	log.mutex.Unlock()
+	return nil
+}
+
+// fetchCommand 通过 journalctl 读取最后一条记录之后的记录
+func (s *JournalSource) fetchCommand(ctx context.Context, unit string, log *unitLog, follow bool, onAppend func()) error {
+	args := []string{"-o", "json", "-u", unit}
+
+	log.mutex.Lock()
+	appender := &journalAppender{log: log, prev: log.cursor, maxBytes: s.cfg.MaxBytes}
+	switch {
+	case log.cursor != "":
+		args = append(args, "--after-cursor="+log.cursor)
+	case !log.fetched && s.cfg.Tail >= 0:
+		args = append(args, "--lines="+strconv.Itoa(s.cfg.Tail))
+	default:
+		// 单元还没有记录，或者配置为读取全部（journalctl -f 默认只输出最近 10 条）
+		args = append(args, "--lines=all")
+	}
+	log.mutex.Unlock()
+
+	if follow {
+		args = append(args, "--follow")
+	}
+
+	err := s.run(ctx, args, func(line []byte) {
+		if appender.append(line) && onAppend != nil {
+			onAppend()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.mutex.Lock()
+	log.fetched = true
+	log.mutex.Unlock()
+	return nil
+}
+
+// run 以 -D 指定 journal 目录运行 journalctl，逐行处理标准输出
+func (s *JournalSource) run(ctx context.Context, args []string, each func(line []byte)) error {
+	args = append([]string{"-D", s.dir, "--no-pager", "-q"}, args...)
+	cmd := exec.CommandContext(ctx, s.cfg.Command, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("运行 %s 失败: %w", s.cfg.Command, err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			each(line)
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("journalctl 返回错误: %s", message)
+		}
+		return fmt.Errorf("journalctl 返回错误: %w", err)
+	}
+	return nil
+}
+
+func (l *unitLog) size() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.buf.size()
+}
+
+// journalAppender 把一次 journalctl 输出的记录追加到单元日志
+// prev 是本次输出中上一条记录的游标，只有内容恰好停在这条记录时才追加，
+// 同时运行的读取和跟踪输出相同的记录时不会重复
+type journalAppender struct {
+	log      *unitLog
+	prev     string
+	maxBytes int64
+}
+
+// append 追加一条记录，返回是否为新的记录
+func (a *journalAppender) append(line []byte) bool {
+	var record journalCursor
+	if err := json.Unmarshal(line, &record); err != nil || record.Cursor == "" {
+		return false
+	}
+	prev := a.prev
+	a.prev = record.Cursor
+
+	l := a.log
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.cursor != prev {
+		return false
+	}
+	l.buf.appendLine(line, a.maxBytes)
+	l.cursor = record.Cursor
+	if usec, err := strconv.ParseInt(record.Realtime, 10, 64); err == nil {
+		l.modTime = time.UnixMicro(usec)
+	}
+	return true
+}
@@ -0,0 +1,469 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// 直接读取 journal 文件，格式见 https://systemd.io/JOURNAL_FILE_FORMAT/
+// 只读取不需要解压的字段：journald 把超过阈值（默认 512 字节）的字段压缩保存，
+// 读到压缩的字段时返回 errJournalCompressed，由 JournalSource 改用 journalctl 读取
+const (
+	journalSignature  = "LPKSHHRH"
+	journalHeaderSize = 208 // 读取到 tail_entry_monotonic 为止
+
+	journalIncompatibleCompact = 1 << 4
+	// 压缩（XZ、LZ4、ZSTD）、带密钥的哈希、紧凑格式，其他的不兼容标志说明文件格式更新，无法读取
+	journalIncompatibleSupported = 1<<5 - 1
+
+	journalObjectData       = 1
+	journalObjectEntry      = 3
+	journalObjectEntryArray = 6
+	journalObjectCompressed = 1<<3 - 1 // XZ、LZ4、ZSTD
+
+	journalObjectHeaderSize = 16
+	journalEntryHeaderSize  = 64
+	journalArrayHeaderSize  = 24
+)
+
+var (
+	// errJournalCompressed 记录的字段被压缩，需要通过 journalctl 读取
+	errJournalCompressed = errors.New("journal 记录的字段已压缩")
+	// errJournalUnsupported journal 文件使用了不支持的格式
+	errJournalUnsupported = errors.New("不支持的 journal 文件格式")
+	// errJournalInvalid 不是 journal 文件或者文件已损坏
+	errJournalInvalid = errors.New("无效的 journal 文件")
+)
+
+// unitDataPrefix 单元名称字段，journal 文件按它的 DATA 对象找到单元的所有记录
+const unitDataPrefix = "_SYSTEMD_UNIT="
+
+// journalFile 打开的 journal 文件，记录已扫描过的对象中每个单元的 DATA 对象位置
+type journalFile struct {
+	file     *os.File
+	info     os.FileInfo
+	fileID   [16]byte
+	seqnumID [16]byte
+	compact  bool
+
+	mutex   sync.Mutex
+	scanned uint64            // 下一个未扫描的对象位置
+	units   map[string]uint64 // 单元名称 -> DATA 对象位置
+}
+
+// journalPos 单元在一个 journal 文件中已读取到的位置
+type journalPos struct {
+	n     uint64 // 已读取的记录数
+	array uint64 // 下一条记录所在的 ENTRY_ARRAY 对象，0 表示 DATA 对象还没有 ENTRY_ARRAY
+	index uint64 // 下一条记录在 ENTRY_ARRAY 中的位置
+}
+
+// journalEntry ENTRY 对象的头部
+type journalEntry struct {
+	file      *journalFile
+	offset    uint64
+	seqnum    uint64
+	realtime  uint64
+	monotonic uint64
+	bootID    [16]byte
+	xorHash   uint64
+}
+
+// openJournalFile 打开 journal 文件并读取头部
+func openJournalFile(path string) (*journalFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	header := make([]byte, journalHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil || string(header[:8]) != journalSignature {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, errJournalInvalid)
+	}
+	incompatible := binary.LittleEndian.Uint32(header[12:])
+	if incompatible&^journalIncompatibleSupported != 0 {
+		file.Close()
+		return nil, fmt.Errorf("%s: 不兼容标志 %#x: %w", path, incompatible, errJournalUnsupported)
+	}
+
+	f := &journalFile{
+		file:    file,
+		info:    info,
+		compact: incompatible&journalIncompatibleCompact != 0,
+		scanned: binary.LittleEndian.Uint64(header[88:]),
+		units:   make(map[string]uint64),
+	}
+	copy(f.fileID[:], header[24:40])
+	copy(f.seqnumID[:], header[72:88])
+	return f, nil
+}
+
+// unitData 扫描新写入的对象，返回单元的 DATA 对象位置
+func (f *journalFile) unitData(unit string) (uint64, bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.scanLocked(); err != nil {
+		return 0, false, err
+	}
+	offset, ok := f.units[unit]
+	return offset, ok, nil
+}
+
+// unitNames 扫描新写入的对象，返回文件中出现过的单元
+func (f *journalFile) unitNames() ([]string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.scanLocked(); err != nil {
+		return nil, err
+	}
+	units := make([]string, 0, len(f.units))
+	for unit := range f.units {
+		units = append(units, unit)
+	}
+	return units, nil
+}
+
+// scanLocked 顺序读取上次扫描之后写入的对象，记录单元名称字段的 DATA 对象
+func (f *journalFile) scanLocked() error {
+	header := make([]byte, journalHeaderSize)
+	if _, err := f.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	tail := binary.LittleEndian.Uint64(header[136:])
+	if tail < f.scanned {
+		return nil
+	}
+
+	reader := bufio.NewReaderSize(io.NewSectionReader(f.file, int64(f.scanned), 1<<62), 256*1024)
+	objectHeader := make([]byte, journalObjectHeaderSize)
+	payloadOffset := f.dataPayloadOffset()
+	for f.scanned <= tail {
+		if _, err := io.ReadFull(reader, objectHeader); err != nil {
+			// 对象还没有写完，下一次继续
+			return nil
+		}
+		objectType, flags := objectHeader[0], objectHeader[1]
+		size := binary.LittleEndian.Uint64(objectHeader[8:])
+		if size < journalObjectHeaderSize {
+			return nil
+		}
+		body := size - journalObjectHeaderSize
+		skip := align8(size) - journalObjectHeaderSize
+
+		// 单元名称很短，不会被压缩
+		if objectType == journalObjectData && flags&journalObjectCompressed == 0 && size > payloadOffset && size-payloadOffset <= 1024 {
+			data := make([]byte, body)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return nil
+			}
+			payload := data[payloadOffset-journalObjectHeaderSize:]
+			if unit, ok := bytes.CutPrefix(payload, []byte(unitDataPrefix)); ok && unitNamePattern.Match(unit) {
+				f.units[string(unit)] = f.scanned
+			}
+			skip -= body
+		}
+		if _, err := reader.Discard(int(skip)); err != nil {
+			return nil
+		}
+		f.scanned += align8(size)
+	}
+	return nil
+}
+
+// entries 从 pos 开始返回 DATA 对象关联的记录，返回读取之后的位置
+// 记录按写入顺序排列：第一条记录在 DATA 对象中，其余的在 ENTRY_ARRAY 链表中
+func (f *journalFile) entries(data uint64, pos journalPos) ([]journalEntry, journalPos, error) {
+	header, err := f.readAt(data, 64)
+	if err != nil {
+		return nil, pos, err
+	}
+	if header[0] != journalObjectData {
+		return nil, pos, errJournalInvalid
+	}
+	first := binary.LittleEndian.Uint64(header[40:])
+	array := binary.LittleEndian.Uint64(header[48:])
+	n := binary.LittleEndian.Uint64(header[56:])
+
+	var offsets []uint64
+	if pos.n == 0 && n > 0 && first != 0 {
+		offsets = append(offsets, first)
+		pos = journalPos{n: 1}
+	}
+	if pos.n > 0 && pos.array == 0 {
+		pos.array = array
+	}
+
+	itemSize := uint64(8)
+	if f.compact {
+		itemSize = 4
+	}
+	for pos.n < n {
+		if pos.array == 0 {
+			// 还没有写入 ENTRY_ARRAY
+			break
+		}
+		arrayHeader, err := f.readAt(pos.array, journalArrayHeaderSize)
+		if err != nil {
+			return nil, pos, err
+		}
+		if arrayHeader[0] != journalObjectEntryArray {
+			return nil, pos, errJournalInvalid
+		}
+		size := binary.LittleEndian.Uint64(arrayHeader[8:])
+		next := binary.LittleEndian.Uint64(arrayHeader[16:])
+		capacity := (size - journalArrayHeaderSize) / itemSize
+		if pos.index >= capacity {
+			if next == 0 {
+				break
+			}
+			pos.array, pos.index = next, 0
+			continue
+		}
+
+		count := capacity - pos.index
+		if remaining := n - pos.n; count > remaining {
+			count = remaining
+		}
+		items, err := f.readAt(pos.array+journalArrayHeaderSize+pos.index*itemSize, int(count*itemSize))
+		if err != nil {
+			return nil, pos, err
+		}
+		for i := uint64(0); i < count; i++ {
+			var offset uint64
+			if f.compact {
+				offset = uint64(binary.LittleEndian.Uint32(items[i*4:]))
+			} else {
+				offset = binary.LittleEndian.Uint64(items[i*8:])
+			}
+			if offset == 0 {
+				// 还没有写入
+				break
+			}
+			offsets = append(offsets, offset)
+			pos.n++
+			pos.index++
+		}
+		if pos.index < capacity {
+			break
+		}
+	}
+
+	entries := make([]journalEntry, 0, len(offsets))
+	for _, offset := range offsets {
+		entry, err := f.entry(offset)
+		if err != nil {
+			return nil, pos, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, pos, nil
+}
+
+// entry 读取 ENTRY 对象的头部
+func (f *journalFile) entry(offset uint64) (journalEntry, error) {
+	header, err := f.readAt(offset, journalEntryHeaderSize)
+	if err != nil {
+		return journalEntry{}, err
+	}
+	if header[0] != journalObjectEntry {
+		return journalEntry{}, errJournalInvalid
+	}
+	entry := journalEntry{
+		file:      f,
+		offset:    offset,
+		seqnum:    binary.LittleEndian.Uint64(header[16:]),
+		realtime:  binary.LittleEndian.Uint64(header[24:]),
+		monotonic: binary.LittleEndian.Uint64(header[32:]),
+		xorHash:   binary.LittleEndian.Uint64(header[56:]),
+	}
+	copy(entry.bootID[:], header[40:56])
+	return entry, nil
+}
+
+// cursor 与 journalctl 相同格式的游标，两种读取方式可以互相续读
+func (e journalEntry) cursor() string {
+	return fmt.Sprintf("s=%x;i=%x;b=%x;m=%x;t=%x;x=%x", e.file.seqnumID, e.seqnum, e.bootID, e.monotonic, e.realtime, e.xorHash)
+}
+
+// after 判断记录是否在游标指向的记录之后：序列号来自同一个 journal 时比较序列号，否则比较时间
+func (e journalEntry) after(cursor string) bool {
+	var seqnumID, seqnum, realtime string
+	for _, part := range bytes.Split([]byte(cursor), []byte(";")) {
+		key, value, _ := bytes.Cut(part, []byte("="))
+		switch string(key) {
+		case "s":
+			seqnumID = string(value)
+		case "i":
+			seqnum = string(value)
+		case "t":
+			realtime = string(value)
+		}
+	}
+	if seqnumID == fmt.Sprintf("%x", e.file.seqnumID) {
+		if n, err := strconv.ParseUint(seqnum, 16, 64); err == nil {
+			return e.seqnum > n
+		}
+	}
+	t, err := strconv.ParseUint(realtime, 16, 64)
+	return err != nil || e.realtime > t
+}
+
+// record 读取记录的字段，按 journalctl -o json 的格式输出：
+// 同名的多个值输出为数组，不是可打印 UTF-8 文本的值输出为字节数组
+func (e journalEntry) record() ([]byte, error) {
+	f := e.file
+	objectHeader, err := f.readAt(e.offset, journalObjectHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint64(objectHeader[8:])
+	if size < journalEntryHeaderSize {
+		return nil, errJournalInvalid
+	}
+	items, err := f.readAt(e.offset+journalEntryHeaderSize, int(size-journalEntryHeaderSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	values := make(map[string][]json.RawMessage)
+	itemSize := 16
+	if f.compact {
+		itemSize = 4
+	}
+	for i := 0; i+itemSize <= len(items); i += itemSize {
+		var offset uint64
+		if f.compact {
+			offset = uint64(binary.LittleEndian.Uint32(items[i:]))
+		} else {
+			offset = binary.LittleEndian.Uint64(items[i:])
+		}
+		payload, err := f.dataPayload(offset)
+		if err != nil {
+			return nil, err
+		}
+		name, value, ok := bytes.Cut(payload, []byte("="))
+		if !ok {
+			continue
+		}
+		if _, seen := values[string(name)]; !seen {
+			names = append(names, string(name))
+		}
+		values[string(name)] = append(values[string(name)], journalValue(value))
+	}
+
+	var buf bytes.Buffer
+	field := func(name string, value json.RawMessage) {
+		if buf.Len() == 0 {
+			buf.WriteByte('{')
+		} else {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	quoted := func(s string) json.RawMessage {
+		return json.RawMessage(strconv.Quote(s))
+	}
+	field("__CURSOR", quoted(e.cursor()))
+	field("__REALTIME_TIMESTAMP", quoted(strconv.FormatUint(e.realtime, 10)))
+	field("__MONOTONIC_TIMESTAMP", quoted(strconv.FormatUint(e.monotonic, 10)))
+	field("__SEQNUM", quoted(strconv.FormatUint(e.seqnum, 10)))
+	field("__SEQNUM_ID", quoted(fmt.Sprintf("%x", f.seqnumID)))
+	field("_BOOT_ID", quoted(fmt.Sprintf("%x", e.bootID)))
+	for _, name := range names {
+		if list := values[name]; len(list) == 1 {
+			field(name, list[0])
+		} else {
+			array, _ := json.Marshal(list)
+			field(name, array)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// dataPayload 读取 DATA 对象中 "字段名=值" 格式的内容
+func (f *journalFile) dataPayload(offset uint64) ([]byte, error) {
+	header, err := f.readAt(offset, journalObjectHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != journalObjectData {
+		return nil, errJournalInvalid
+	}
+	if header[1]&journalObjectCompressed != 0 {
+		return nil, errJournalCompressed
+	}
+	size := binary.LittleEndian.Uint64(header[8:])
+	payloadOffset := f.dataPayloadOffset()
+	if size < payloadOffset {
+		return nil, errJournalInvalid
+	}
+	return f.readAt(offset+payloadOffset, int(size-payloadOffset))
+}
+
+// dataPayloadOffset DATA 对象中内容的位置，紧凑格式多出 ENTRY_ARRAY 尾部的位置和数量
+func (f *journalFile) dataPayloadOffset() uint64 {
+	if f.compact {
+		return 72
+	}
+	return 64
+}
+
+func (f *journalFile) readAt(offset uint64, size int) ([]byte, error) {
+	if size < 0 || size > 64*1024*1024 {
+		return nil, errJournalInvalid
+	}
+	buf := make([]byte, size)
+	if _, err := f.file.ReadAt(buf, int64(offset)); err != nil {
+		if err == io.EOF {
+			return nil, errJournalInvalid
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// journalValue 可打印的 UTF-8 文本输出为字符串，否则输出为字节数组（与 journalctl 相同）
+func journalValue(value []byte) json.RawMessage {
+	printable := utf8.Valid(value)
+	for _, b := range value {
+		if b < 0x20 && b != '\n' && b != '\t' || b == 0x7f {
+			printable = false
+			break
+		}
+	}
+	var data []byte
+	if printable {
+		data, _ = json.Marshal(string(value))
+	} else {
+		numbers := make([]int, len(value))
+		for i, b := range value {
+			numbers[i] = int(b)
+		}
+		data, _ = json.Marshal(numbers)
+	}
+	return data
+}
+
+func align8(n uint64) uint64 {
+	return (n + 7) &^ 7
+}
@@ -0,0 +1,325 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/source/journaltest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	journaltest.Main()
+	os.Exit(m.Run())
+}
+
+// newTestJournalSource 创建模拟的 journal 目录，返回读取它的日志源
+// exec 为 false 时直接读取 journal 文件，journalctl 只在需要时使用
+func newTestJournalSource(t *testing.T, tail int, exec bool) (*JournalSource, *journaltest.Journal) {
+	t.Helper()
+	journal, err := journaltest.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 journal 失败: %v", err)
+	}
+	return newTestJournalSourceWith(t, journal, config.JournalConfig{Tail: tail, Exec: exec}), journal
+}
+
+// newTestJournalSourceWith 使用指定的配置读取 journal，没有指定命令时使用模拟的 journalctl
+func newTestJournalSourceWith(t *testing.T, journal *journaltest.Journal, cfg config.JournalConfig) *JournalSource {
+	t.Helper()
+	if cfg.Command == "" {
+		cfg.Command = journal.Command
+	}
+	cfg.PollInterval = 20 * time.Millisecond
+	src, err := NewJournalSource("journal://"+journal.Dir, cfg)
+	if err != nil {
+		t.Fatalf("创建 journal 日志源失败: %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+	return src
+}
+
+// readJournalMessages 读取单元日志中每条记录的 MESSAGE
+func readJournalMessages(t *testing.T, src *JournalSource, p string) []string {
+	t.Helper()
+	r, err := src.Open(context.Background(), p, 0, -1)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var record map[string]string
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("不是 journal 记录: %q", line)
+		}
+		messages = append(messages, record["MESSAGE"])
+	}
+	return messages
+}
+
+// waitJournalFollowing 等待单元的跟踪开始：直接读取时等待第一次读取完成，否则等待 journalctl -f 开始运行
+func waitJournalFollowing(t *testing.T, src *JournalSource, unit string, exec bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		src.mutex.Lock()
+		log := src.units[unit]
+		src.mutex.Unlock()
+		if log != nil {
+			log.mutex.Lock()
+			fetched, streaming := log.fetched, log.streaming
+			log.mutex.Unlock()
+			if !exec && fetched {
+				return
+			}
+			if exec && streaming > 0 {
+				// journalctl 进程读取现有记录需要一点时间
+				time.Sleep(100 * time.Millisecond)
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("等待 %s 的跟踪启动超时", unit)
+}
+
+func TestJournalSource_ListStatOpen(t *testing.T) {
+	t.Run("files", func(t *testing.T) {
+		journal, err := journaltest.New(t.TempDir())
+		if err != nil {
+			t.Fatalf("创建 journal 失败: %v", err)
+		}
+		// 直接读取 journal 文件时不运行 journalctl
+		src := newTestJournalSourceWith(t, journal, config.JournalConfig{Tail: 2, Command: "/nonexistent/journalctl"})
+		testJournalListStatOpen(t, src, journal)
+	})
+	t.Run("journalctl", func(t *testing.T) {
+		src, journal := newTestJournalSource(t, 2, true)
+		testJournalListStatOpen(t, src, journal)
+	})
+}
+
+func testJournalListStatOpen(t *testing.T, src *JournalSource, journal *journaltest.Journal) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	journal.Add("nginx.service", 6, "starting", at)
+	journal.Add("sshd.service", 6, "listening", at)
+	journal.Add("nginx.service", 6, "started", at.Add(time.Second))
+	journal.Add("nginx.service", 3, "upstream failed", at.Add(2*time.Second))
+
+	files, err := src.Walk(ctx)
+	if err != nil {
+		t.Fatalf("Walk 失败: %v", err)
+	}
+	if len(files) != 2 || files[0].Name != "nginx.service.log" || files[1].Name != "sshd.service.log" {
+		t.Errorf("期望按单元列出日志，得到 %+v", files)
+	}
+
+	nginx := src.Root() + "/nginx.service.log"
+	info, err := src.Stat(ctx, nginx)
+	if err != nil || info.Size == 0 || !info.ModTime.Equal(at.Add(2*time.Second)) {
+		t.Errorf("Stat 结果不正确: %+v, %v", info, err)
+	}
+	// 第一次只读取最近 tail 条
+	if messages := readJournalMessages(t, src, nginx); strings.Join(messages, ",") != "started,upstream failed" {
+		t.Errorf("单元日志内容不正确: %v", messages)
+	}
+
+	// 之后从游标开始读取新增的记录
+	journal.Add("nginx.service", 6, "reloaded", at.Add(3*time.Second))
+	if lines, err := src.CountLines(ctx, nginx); err != nil || lines != 3 {
+		t.Errorf("期望 3 条，得到 %d, %v", lines, err)
+	}
+	if messages := readJournalMessages(t, src, nginx); messages[2] != "reloaded" {
+		t.Errorf("新增的记录不正确: %v", messages)
+	}
+
+	if _, err := src.Stat(ctx, src.Root()+"/missing.service.log"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望单元不存在错误，得到 %v", err)
+	}
+	for _, p := range []string{src.Root() + "/nginx.service", src.Root() + "/../etc/passwd.log", src.Root() + "/a/b.log", src.Root() + "/x*.log"} {
+		if _, ok := src.Resolve(p); ok {
+			t.Errorf("期望拒绝路径: %s", p)
+		}
+	}
+	if info, err := src.Stat(ctx, src.Root()); err != nil || !info.IsDir {
+		t.Errorf("根路径信息不正确: %+v, %v", info, err)
+	}
+}
+
+func TestJournalSource_Follow(t *testing.T) {
+	for _, exec := range []bool{false, true} {
+		name := "files"
+		if exec {
+			name = "journalctl"
+		}
+		t.Run(name, func(t *testing.T) {
+			src, journal := newTestJournalSource(t, 0, exec)
+			testJournalFollow(t, src, journal, exec)
+		})
+	}
+}
+
+func testJournalFollow(t *testing.T, src *JournalSource, journal *journaltest.Journal, exec bool) {
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	journal.Add("api.service", 6, "first", at)
+
+	events := make(chan string, 10)
+	stop, err := src.Follow(src.Root()+"/api.service.log", func(event types.FileEvent) {
+		events <- event.Type
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	defer stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("期望 %s 事件，得到 %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待 %s 事件超时", want)
+		}
+	}
+
+	// 等待跟踪开始后再写入
+	waitJournalFollowing(t, src, "api.service", exec)
+	journal.Add("api.service", 4, "second", at.Add(time.Second))
+	expect("modify")
+	if messages := readJournalMessages(t, src, src.Root()+"/api.service.log"); strings.Join(messages, ",") != "first,second" {
+		t.Errorf("跟踪到的内容不正确: %v", messages)
+	}
+
+	// 跟踪还没有日志的单元，出现日志时通知 create
+	created := make(chan string, 10)
+	stopWorker, err := src.Follow(src.Root()+"/worker.service.log", func(event types.FileEvent) {
+		created <- event.Type
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	defer stopWorker()
+	time.Sleep(300 * time.Millisecond)
+	journal.Add("worker.service", 6, "hello", at)
+	select {
+	case got := <-created:
+		if got != "create" {
+			t.Errorf("期望 create 事件，得到 %s", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待 create 事件超时")
+	}
+}
+
+// 字段被压缩的记录通过 journalctl 读取，之后继续直接读取 journal 文件
+func TestJournalSource_CompressedFallback(t *testing.T) {
+	src, journal := newTestJournalSource(t, -1, false)
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	nginx := src.Root() + "/nginx.service.log"
+
+	journal.Add("nginx.service", 6, "starting", at)
+	journal.AddCompressed("nginx.service", 3, strings.Repeat("long ", 200), at.Add(time.Second))
+	journal.Add("nginx.service", 6, "started", at.Add(2*time.Second))
+	if messages := readJournalMessages(t, src, nginx); len(messages) != 3 || messages[0] != "starting" || messages[2] != "started" {
+		t.Errorf("单元日志内容不正确: %v", messages)
+	}
+
+	journal.Add("nginx.service", 6, "reloaded", at.Add(3*time.Second))
+	if lines, err := src.CountLines(ctx, nginx); err != nil || lines != 4 {
+		t.Errorf("期望 4 条，得到 %d, %v", lines, err)
+	}
+	if !src.direct() {
+		t.Error("记录被压缩时不应停止直接读取 journal 文件")
+	}
+
+	// journalctl 不可用时返回错误，之后仍然可以重新读取
+	broken := newTestJournalSourceWith(t, journal, config.JournalConfig{Tail: -1, Command: "/nonexistent/journalctl"})
+	if _, err := broken.Stat(ctx, nginx); err == nil {
+		t.Error("期望没有 journalctl 时读取压缩的记录失败")
+	}
+}
+
+// journal 文件被归档改名后继续读取，紧凑格式的文件同样可以读取
+func TestJournalSource_Rotate(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		journal, err := journaltest.New(t.TempDir())
+		if err != nil {
+			t.Fatalf("创建 journal 失败: %v", err)
+		}
+		journal.Compact = compact
+		src := newTestJournalSourceWith(t, journal, config.JournalConfig{Tail: -1, Command: "/nonexistent/journalctl"})
+		at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		api := src.Root() + "/api.service.log"
+
+		// 超过 ENTRY_ARRAY 的容量，记录分布在多个 ENTRY_ARRAY 中
+		var want []string
+		for i := 0; i < 10; i++ {
+			message := fmt.Sprintf("before %d", i)
+			journal.Add("api.service", 6, message, at.Add(time.Duration(i)*time.Second))
+			want = append(want, message)
+		}
+		if messages := readJournalMessages(t, src, api); strings.Join(messages, ",") != strings.Join(want, ",") {
+			t.Errorf("compact=%v: 单元日志内容不正确: %v", compact, messages)
+		}
+
+		journal.Rotate()
+		for i := 0; i < 3; i++ {
+			message := fmt.Sprintf("after %d", i)
+			journal.Add("api.service", 6, message, at.Add(time.Duration(10+i)*time.Second))
+			want = append(want, message)
+		}
+		journal.Add("worker.service", 6, "hello", at)
+		if messages := readJournalMessages(t, src, api); strings.Join(messages, ",") != strings.Join(want, ",") {
+			t.Errorf("compact=%v: 归档后的内容不正确: %v", compact, messages)
+		}
+		if files, err := src.Walk(context.Background()); err != nil || len(files) != 2 {
+			t.Errorf("compact=%v: 期望列出 2 个单元，得到 %+v, %v", compact, files, err)
+		}
+	}
+}
+
+func TestJournalSource_MaxBytes(t *testing.T) {
+	journal, err := journaltest.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 journal 失败: %v", err)
+	}
+	src := newTestJournalSourceWith(t, journal, config.JournalConfig{Tail: -1, MaxBytes: 2000})
+	ctx := context.Background()
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	api := src.Root() + "/api.service.log"
+
+	for i := 0; i < 30; i++ {
+		journal.Add("api.service", 6, fmt.Sprintf("message %02d", i), at.Add(time.Duration(i)*time.Second))
+	}
+	if lines, err := src.CountLines(ctx, api); err != nil || lines != 30 {
+		t.Errorf("期望 30 条，得到 %d, %v", lines, err)
+	}
+	info, err := src.Stat(ctx, api)
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	messages := readJournalMessages(t, src, api)
+	if len(messages) == 0 || len(messages) >= 30 || messages[len(messages)-1] != "message 29" {
+		t.Errorf("期望只保留最近的记录，得到 %v", messages)
+	}
+	if info.Size <= 2000 {
+		t.Errorf("大小应包括丢弃的内容，得到 %d", info.Size)
+	}
+}
@@ -0,0 +1,256 @@
+package journaltest
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"os"
+)
+
+// journal 文件格式的常量，见 https://systemd.io/JOURNAL_FILE_FORMAT/
+const (
+	headerSize = 256
+
+	incompatibleZstd    = 1 << 3
+	incompatibleCompact = 1 << 4
+
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+
+	objectCompressedZstd = 1 << 2
+)
+
+// fileWriter 按 journal 文件格式追加记录，只写入按字段查找记录需要的 DATA、ENTRY 和 ENTRY_ARRAY 对象，
+// 不写入哈希表和全局的 ENTRY_ARRAY（模拟的 journalctl 读取 entries.jsonl）
+type fileWriter struct {
+	file    *os.File
+	compact bool
+	end     uint64
+	fileID  [16]byte
+
+	data         map[string]*dataObject
+	nObjects     uint64
+	nEntries     uint64
+	tailObject   uint64
+	headSeqnum   uint64
+	headRealtime uint64
+}
+
+// dataObject 已写入的 DATA 对象和它最后一个 ENTRY_ARRAY 的使用情况
+type dataObject struct {
+	offset    uint64
+	n         uint64
+	array     uint64
+	arrayCap  uint64
+	arrayUsed uint64
+}
+
+// field 记录的一个字段，compressed 为 true 时 DATA 对象带有 ZSTD 压缩标志（内容不压缩，读取方不应解析）
+type field struct {
+	name, value string
+	compressed  bool
+}
+
+func newFileWriter(path string, compact bool) (*fileWriter, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWriter{
+		file:    file,
+		compact: compact,
+		end:     headerSize,
+		data:    make(map[string]*dataObject),
+	}
+	if _, err := rand.Read(w.fileID[:]); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// appendEntry 写入一条记录：先写入新的 DATA 对象和 ENTRY 对象，再把记录加入每个 DATA 对象的记录列表，最后更新头部
+func (w *fileWriter) appendEntry(j *Journal, seqnum, realtime, monotonic uint64, fields []field) (uint64, error) {
+	var xorHash uint64
+	offsets := make([]uint64, len(fields))
+	objects := make([]*dataObject, len(fields))
+	for i, f := range fields {
+		payload := f.name + "=" + f.value
+		h := fnv.New64a()
+		h.Write([]byte(payload))
+		xorHash ^= h.Sum64()
+
+		d := w.data[payload]
+		if d == nil || f.compressed {
+			body := make([]byte, w.dataPayloadOffset()-16, int(w.dataPayloadOffset()-16)+len(payload))
+			body = append(body, payload...)
+			var flags byte
+			if f.compressed {
+				flags = objectCompressedZstd
+			}
+			offset, err := w.appendObject(objectData, flags, body)
+			if err != nil {
+				return 0, err
+			}
+			d = &dataObject{offset: offset}
+			if !f.compressed {
+				w.data[payload] = d
+			}
+		}
+		offsets[i], objects[i] = d.offset, d
+	}
+
+	body := make([]byte, 48)
+	binary.LittleEndian.PutUint64(body[0:], seqnum)
+	binary.LittleEndian.PutUint64(body[8:], realtime)
+	binary.LittleEndian.PutUint64(body[16:], monotonic)
+	copy(body[24:40], j.bootID[:])
+	binary.LittleEndian.PutUint64(body[40:], xorHash)
+	for _, offset := range offsets {
+		if w.compact {
+			body = binary.LittleEndian.AppendUint32(body, uint32(offset))
+		} else {
+			body = binary.LittleEndian.AppendUint64(body, offset)
+			body = binary.LittleEndian.AppendUint64(body, 0)
+		}
+	}
+	entry, err := w.appendObject(objectEntry, 0, body)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range objects {
+		if err := w.link(d, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	w.nEntries++
+	if w.headSeqnum == 0 {
+		w.headSeqnum, w.headRealtime = seqnum, realtime
+	}
+	return xorHash, w.writeHeader(j, seqnum, realtime, monotonic)
+}
+
+// link 把记录加入 DATA 对象的记录列表：第一条记录保存在 DATA 对象中，其余的保存在 ENTRY_ARRAY 链表中，
+// 每个 ENTRY_ARRAY 的容量是上一个的两倍
+func (w *fileWriter) link(d *dataObject, entry uint64) error {
+	if d.n == 0 {
+		if err := w.writeUint64(d.offset+40, entry); err != nil {
+			return err
+		}
+	} else {
+		if d.array == 0 || d.arrayUsed == d.arrayCap {
+			capacity := uint64(4)
+			if d.array != 0 {
+				capacity = d.arrayCap * 2
+			}
+			array, err := w.appendObject(objectEntryArray, 0, make([]byte, 8+capacity*w.itemSize()))
+			if err != nil {
+				return err
+			}
+			previous := d.offset + 48 // DATA 对象的 entry_array_offset
+			if d.array != 0 {
+				previous = d.array + 16 // 上一个 ENTRY_ARRAY 的 next_entry_array_offset
+			}
+			if err := w.writeUint64(previous, array); err != nil {
+				return err
+			}
+			d.array, d.arrayCap, d.arrayUsed = array, capacity, 0
+		}
+
+		item := d.array + 24 + d.arrayUsed*w.itemSize()
+		var err error
+		if w.compact {
+			buf := binary.LittleEndian.AppendUint32(nil, uint32(entry))
+			_, err = w.file.WriteAt(buf, int64(item))
+		} else {
+			err = w.writeUint64(item, entry)
+		}
+		if err != nil {
+			return err
+		}
+		d.arrayUsed++
+	}
+	d.n++
+	return w.writeUint64(d.offset+56, d.n)
+}
+
+// appendObject 在文件末尾写入对象，返回对象的位置；对象按 8 字节对齐
+func (w *fileWriter) appendObject(objectType, flags byte, body []byte) (uint64, error) {
+	size := uint64(16 + len(body))
+	buf := make([]byte, (size+7)&^7)
+	buf[0], buf[1] = objectType, flags
+	binary.LittleEndian.PutUint64(buf[8:], size)
+	copy(buf[16:], body)
+
+	offset := w.end
+	if _, err := w.file.WriteAt(buf, int64(offset)); err != nil {
+		return 0, err
+	}
+	w.end += uint64(len(buf))
+	w.nObjects++
+	w.tailObject = offset
+	return offset, nil
+}
+
+func (w *fileWriter) writeHeader(j *Journal, tailSeqnum, tailRealtime, tailMonotonic uint64) error {
+	header := make([]byte, headerSize)
+	copy(header, "LPKSHHRH")
+	incompatible := uint32(incompatibleZstd)
+	if w.compact {
+		incompatible |= incompatibleCompact
+	}
+	binary.LittleEndian.PutUint32(header[12:], incompatible)
+	header[16] = 1 // STATE_ONLINE
+	copy(header[24:40], w.fileID[:])
+	copy(header[40:56], j.machineID[:])
+	copy(header[56:72], j.bootID[:])
+	copy(header[72:88], j.seqnumID[:])
+	values := []uint64{
+		headerSize,         // header_size
+		w.end - headerSize, // arena_size
+		0, 0, 0, 0,         // 哈希表的位置和大小
+		w.tailObject,   // tail_object_offset
+		w.nObjects,     // n_objects
+		w.nEntries,     // n_entries
+		tailSeqnum,     // tail_entry_seqnum
+		w.headSeqnum,   // head_entry_seqnum
+		0,              // entry_array_offset
+		w.headRealtime, // head_entry_realtime
+		tailRealtime,   // tail_entry_realtime
+		tailMonotonic,  // tail_entry_monotonic
+	}
+	for i, value := range values {
+		binary.LittleEndian.PutUint64(header[88+i*8:], value)
+	}
+	_, err := w.file.WriteAt(header, 0)
+	return err
+}
+
+func (w *fileWriter) writeUint64(offset, value uint64) error {
+	_, err := w.file.WriteAt(binary.LittleEndian.AppendUint64(nil, value), int64(offset))
+	return err
+}
+
+func (w *fileWriter) itemSize() uint64 {
+	if w.compact {
+		return 4
+	}
+	return 8
+}
+
+// dataPayloadOffset DATA 对象中内容的位置，紧凑格式多出 8 字节
+func (w *fileWriter) dataPayloadOffset() uint64 {
+	if w.compact {
+		return 72
+	}
+	return 64
+}
+
+// cursor 与 journalctl 相同格式的游标
+func cursor(seqnumID [16]byte, seqnum uint64, bootID [16]byte, monotonic, realtime, xorHash uint64) string {
+	return fmt.Sprintf("s=%x;i=%x;b=%x;m=%x;t=%x;x=%x", seqnumID, seqnum, bootID, monotonic, realtime, xorHash)
+}
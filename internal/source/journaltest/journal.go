@@ -0,0 +1,335 @@
+// Package journaltest 提供测试用的 journal 目录：记录按 journal 文件格式写入 <机器 ID>/system.journal，
+// 同时写入 entries.jsonl 供测试用的 journalctl 读取。journalctl 是以 journalctl 的参数重新执行测试二进制文件的包装脚本，
+// 测试包的 TestMain 调用 Main 处理这些参数，从 -D 指定的目录下的 entries.jsonl 读取记录
+package journaltest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	helperEnv   = "JOURNALTEST_HELPER"
+	entriesFile = "entries.jsonl"
+)
+
+// Journal 模拟的 journal 目录
+type Journal struct {
+	// Dir journal 目录，对应 journal:// 路径
+	Dir string
+	// Command 模拟的 journalctl 命令
+	Command string
+	// Compact 使用紧凑格式写入 journal 文件，在第一次写入前设置
+	Compact bool
+
+	mutex     sync.Mutex
+	seq       int
+	machineID [16]byte
+	bootID    [16]byte
+	seqnumID  [16]byte
+	writer    *fileWriter
+	rotated   int
+}
+
+// New 在 dir 下创建 journal 目录和 journalctl 包装脚本
+func New(dir string) (*Journal, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{
+		Dir:     filepath.Join(dir, "journal"),
+		Command: filepath.Join(dir, "journalctl"),
+	}
+	for _, id := range [][]byte{j.machineID[:], j.bootID[:], j.seqnumID[:]} {
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(j.Dir, fmt.Sprintf("%x", j.machineID)), 0755); err != nil {
+		return nil, err
+	}
+	script := fmt.Sprintf("#!/bin/sh\n%s=1 exec %s \"$@\"\n", helperEnv, strconv.Quote(executable))
+	if err := os.WriteFile(j.Command, []byte(script), 0755); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Add 写入一条单元日志，priority 为 syslog 严重性（0~7）
+func (j *Journal) Add(unit string, priority int, message string, at time.Time) error {
+	return j.add(unit, priority, message, at, false)
+}
+
+// AddCompressed 与 Add 相同，journal 文件中 MESSAGE 字段的 DATA 对象标记为已压缩，只能通过 journalctl 读取
+func (j *Journal) AddCompressed(unit string, priority int, message string, at time.Time) error {
+	return j.add(unit, priority, message, at, true)
+}
+
+// Rotate 像 journald 一样把当前的 system.journal 归档改名，之后的记录写入新的文件
+func (j *Journal) Rotate() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.writer == nil {
+		return nil
+	}
+	j.rotated++
+	dir := filepath.Join(j.Dir, fmt.Sprintf("%x", j.machineID))
+	archived := filepath.Join(dir, fmt.Sprintf("system@%x-%016x-%016x.journal", j.seqnumID, j.rotated, time.Now().UnixMicro()))
+	j.writer.file.Close()
+	j.writer = nil
+	return os.Rename(filepath.Join(dir, "system.journal"), archived)
+}
+
+func (j *Journal) add(unit string, priority int, message string, at time.Time, compressed bool) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.writer == nil {
+		w, err := newFileWriter(filepath.Join(j.Dir, fmt.Sprintf("%x", j.machineID), "system.journal"), j.Compact)
+		if err != nil {
+			return err
+		}
+		j.writer = w
+	}
+
+	j.seq++
+	fields := []field{
+		{name: "PRIORITY", value: strconv.Itoa(priority)},
+		{name: "_SYSTEMD_UNIT", value: unit},
+		{name: "_PID", value: strconv.Itoa(1000 + j.seq)},
+		{name: "_HOSTNAME", value: "testhost"},
+		{name: "SYSLOG_IDENTIFIER", value: strings.TrimSuffix(unit, filepath.Ext(unit))},
+		{name: "MESSAGE", value: message, compressed: compressed},
+	}
+	seqnum, realtime, monotonic := uint64(j.seq), uint64(at.UnixMicro()), uint64(j.seq)*1000
+	xorHash, err := j.writer.appendEntry(j, seqnum, realtime, monotonic, fields)
+	if err != nil {
+		return err
+	}
+
+	record := map[string]string{
+		"__CURSOR":              cursor(j.seqnumID, seqnum, j.bootID, monotonic, realtime, xorHash),
+		"__REALTIME_TIMESTAMP":  strconv.FormatUint(realtime, 10),
+		"__MONOTONIC_TIMESTAMP": strconv.FormatUint(monotonic, 10),
+		"_BOOT_ID":              fmt.Sprintf("%x", j.bootID),
+	}
+	for _, f := range fields {
+		record[f.name] = f.value
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(j.Dir, entriesFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Main 由测试包的 TestMain 在 m.Run 之前调用；作为 journalctl 执行时处理参数后退出
+func Main() {
+	if os.Getenv(helperEnv) == "" {
+		return
+	}
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// query 支持的 journalctl 参数
+type query struct {
+	dir         string
+	unit        string
+	field       string
+	afterCursor string
+	lines       int // -1 表示全部
+	linesSet    bool
+	follow      bool
+}
+
+func run(args []string) error {
+	q := query{lines: -1}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		next := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("option %s requires an argument", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		var value string
+		switch {
+		case arg == "-D":
+			q.dir, err = next()
+		case strings.HasPrefix(arg, "--directory="):
+			q.dir = strings.TrimPrefix(arg, "--directory=")
+		case arg == "-u":
+			q.unit, err = next()
+		case strings.HasPrefix(arg, "--unit="):
+			q.unit = strings.TrimPrefix(arg, "--unit=")
+		case arg == "-o":
+			if value, err = next(); err == nil && value != "json" {
+				err = fmt.Errorf("unsupported output mode: %s", value)
+			}
+		case arg == "-n":
+			if value, err = next(); err == nil {
+				err = q.setLines(value)
+			}
+		case strings.HasPrefix(arg, "--lines="):
+			err = q.setLines(strings.TrimPrefix(arg, "--lines="))
+		case strings.HasPrefix(arg, "--after-cursor="):
+			q.afterCursor = strings.TrimPrefix(arg, "--after-cursor=")
+		case strings.HasPrefix(arg, "--field="):
+			q.field = strings.TrimPrefix(arg, "--field=")
+		case arg == "-f" || arg == "--follow":
+			q.follow = true
+		case arg == "--no-pager" || arg == "-q" || arg == "--quiet":
+		default:
+			err = fmt.Errorf("unrecognized option '%s'", arg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if q.dir == "" {
+		return fmt.Errorf("journaltest: -D is required")
+	}
+	if info, err := os.Stat(q.dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("Failed to open journal directory %s", q.dir)
+	}
+
+	lines, offset := readEntries(q.dir, 0)
+
+	if q.field != "" {
+		values := make(map[string]bool)
+		for _, entry := range lines {
+			if value, ok := entry.fields[q.field]; ok {
+				values[value] = true
+			}
+		}
+		sorted := make([]string, 0, len(values))
+		for value := range values {
+			sorted = append(sorted, value)
+		}
+		sort.Strings(sorted)
+		for _, value := range sorted {
+			fmt.Println(value)
+		}
+		return nil
+	}
+
+	var matched []entry
+	for _, entry := range lines {
+		if q.unit == "" || entry.fields["_SYSTEMD_UNIT"] == q.unit {
+			matched = append(matched, entry)
+		}
+	}
+
+	if q.afterCursor != "" {
+		found := false
+		for i, entry := range matched {
+			if entry.fields["__CURSOR"] == q.afterCursor {
+				matched, found = matched[i+1:], true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Failed to seek to cursor: Invalid argument")
+		}
+	} else {
+		n := q.lines
+		if q.follow && !q.linesSet {
+			n = 10
+		}
+		if n >= 0 && len(matched) > n {
+			matched = matched[len(matched)-n:]
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	for _, entry := range matched {
+		out.Write(append(entry.raw, '\n'))
+	}
+	if err := out.Flush(); err != nil || !q.follow {
+		return err
+	}
+
+	// 跟踪新写入的记录，直到进程被结束
+	for {
+		time.Sleep(10 * time.Millisecond)
+		var added []entry
+		added, offset = readEntries(q.dir, offset)
+		for _, entry := range added {
+			if q.unit == "" || entry.fields["_SYSTEMD_UNIT"] == q.unit {
+				out.Write(append(entry.raw, '\n'))
+			}
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func (q *query) setLines(value string) error {
+	q.linesSet = true
+	if value == "all" {
+		q.lines = -1
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fmt.Errorf("failed to parse lines '%s'", value)
+	}
+	q.lines = n
+	return nil
+}
+
+type entry struct {
+	raw    []byte
+	fields map[string]string
+}
+
+// readEntries 从 offset 开始读取完整的记录，返回记录和下一次读取的位置
+func readEntries(dir string, offset int64) ([]entry, int64) {
+	data, err := os.ReadFile(filepath.Join(dir, entriesFile))
+	if err != nil || int64(len(data)) <= offset {
+		return nil, offset
+	}
+	data = data[offset:]
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, offset
+	}
+
+	var entries []entry
+	for _, line := range bytes.Split(data[:end], []byte("\n")) {
+		var fields map[string]string
+		if json.Unmarshal(line, &fields) == nil {
+			entries = append(entries, entry{raw: line, fields: fields})
+		}
+	}
+	return entries, offset + int64(end) + 1
+}
@@ -0,0 +1,192 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/local-log-viewer/internal/interfaces"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// LocalSource 本地文件系统上的日志，文件变化由 FileWatcher（fsnotify）通知
+type LocalSource struct {
+	root    string // 绝对路径，可以是目录或单个文件；为空时接受任意本地路径
+	watcher interfaces.FileWatcher
+}
+
+// NewLocalSource 创建本地日志源，root 为空时接受任意本地路径（用于不在配置的日志目录下的文件）
+func NewLocalSource(root string, watcher interfaces.FileWatcher) (*LocalSource, error) {
+	if root != "" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("无效的日志路径 %s: %w", root, err)
+		}
+		root = abs
+	}
+	return &LocalSource{root: root, watcher: watcher}, nil
+}
+
+// Root 源的根路径
+func (s *LocalSource) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否位于根路径下，返回绝对路径
+func (s *LocalSource) Resolve(p string) (string, bool) {
+	if p == "" || IsRemote(p) {
+		return "", false
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	if s.root == "" || abs == s.root {
+		return abs, true
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return abs, true
+}
+
+// LocalPath 本地日志源的路径就是本地路径
+func (s *LocalSource) LocalPath(p string) (string, bool) {
+	return s.Resolve(p)
+}
+
+// List 列出目录的直接子节点
+func (s *LocalSource) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	resolved, ok := s.Resolve(dir)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", s.root, dir)
+	}
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, localFileInfo(filepath.Join(resolved, entry.Name()), info))
+	}
+	return files, nil
+}
+
+// Walk 递归列出根路径下的所有文件和目录，跳过隐藏文件和目录；根路径是文件时只返回该文件
+func (s *LocalSource) Walk(ctx context.Context) ([]FileInfo, error) {
+	if s.root == "" {
+		return nil, fmt.Errorf("没有根路径的本地日志源不能遍历")
+	}
+
+	var files []FileInfo
+	err := filepath.Walk(s.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == s.root {
+				return err
+			}
+			return nil // 跳过无法访问的文件/目录
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if p == s.root {
+			if !info.IsDir() {
+				files = append(files, localFileInfo(p, info))
+			}
+			return nil
+		}
+
+		// 跳过隐藏文件和目录
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		files = append(files, localFileInfo(p, info))
+		return nil
+	})
+	return files, err
+}
+
+// Stat 获取文件信息
+func (s *LocalSource) Stat(ctx context.Context, p string) (FileInfo, error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return FileInfo{}, fmt.Errorf("路径不在 %s 下: %s", s.root, p)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return localFileInfo(resolved, info), nil
+}
+
+// Open 从 offset 开始读取文件，length 为负数时读取到文件末尾
+func (s *LocalSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", s.root, p)
+	}
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+// Follow 通过 FileWatcher 跟踪文件的变化
+func (s *LocalSource) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", s.root, p)
+	}
+	if s.watcher == nil {
+		return nil, fmt.Errorf("本地日志源没有文件监控器")
+	}
+	if err := s.watcher.WatchFile(resolved, callback); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := s.watcher.UnwatchFile(resolved); err != nil {
+			logger.Warn("停止监控文件失败", zap.String("path", resolved), zap.Error(err))
+		}
+	}, nil
+}
+
+// Close 本地日志源不持有资源，文件监控器由调用方管理
+func (s *LocalSource) Close() error {
+	return nil
+}
+
+func localFileInfo(p string, info os.FileInfo) FileInfo {
+	return FileInfo{
+		Path:    p,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+		OSInfo:  info,
+	}
+}
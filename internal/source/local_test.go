@@ -0,0 +1,126 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/local-log-viewer/internal/types"
+)
+
+// recordingWatcher 记录 WatchFile/UnwatchFile 调用的文件监控器
+type recordingWatcher struct {
+	callbacks map[string]func(types.FileEvent)
+	unwatched []string
+}
+
+func (w *recordingWatcher) WatchFile(path string, callback func(types.FileEvent)) error {
+	w.callbacks[path] = callback
+	return nil
+}
+
+func (w *recordingWatcher) UnwatchFile(path string) error {
+	w.unwatched = append(w.unwatched, path)
+	return nil
+}
+
+func (w *recordingWatcher) Start() error { return nil }
+func (w *recordingWatcher) Stop() error  { return nil }
+
+func TestLocalSource_ListStatOpen(t *testing.T) {
+	logDir := t.TempDir()
+	writeFile(t, filepath.Join(logDir, "app.log"), "line 1\nline 2\nline 3")
+	writeFile(t, filepath.Join(logDir, "nginx", "access.log"), "GET /\n")
+	writeFile(t, filepath.Join(logDir, ".cache", "hidden.log"), "hidden\n")
+	src, err := NewLocalSource(logDir, nil)
+	if err != nil {
+		t.Fatalf("创建本地日志源失败: %v", err)
+	}
+	ctx := context.Background()
+
+	files, err := src.Walk(ctx)
+	if err != nil {
+		t.Fatalf("Walk 失败: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, filepath.ToSlash(strings.TrimPrefix(f.Path, logDir)))
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "/app.log,/nginx,/nginx/access.log" {
+		t.Errorf("Walk 结果不正确: %v", paths)
+	}
+
+	children, err := src.List(ctx, filepath.Join(logDir, "nginx"))
+	if err != nil || len(children) != 1 || children[0].Name != "access.log" || children[0].IsDir {
+		t.Errorf("List 结果不正确: %+v, %v", children, err)
+	}
+
+	info, err := src.Stat(ctx, filepath.Join(logDir, "app.log"))
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	local, _ := os.Stat(filepath.Join(logDir, "app.log"))
+	if info.Size != 20 || info.OSInfo == nil || !os.SameFile(info.OSInfo, local) {
+		t.Errorf("Stat 结果不正确: %+v", info)
+	}
+	if _, err := src.Stat(ctx, filepath.Join(logDir, "missing.log")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望文件不存在错误，得到 %v", err)
+	}
+
+	r, err := src.Open(ctx, filepath.Join(logDir, "app.log"), 7, 6)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "line 2" {
+		t.Errorf("读取范围不正确: %q", data)
+	}
+
+	if _, ok := src.Resolve(filepath.Join(logDir, "..", "etc", "passwd")); ok {
+		t.Error("期望拒绝根目录外的路径")
+	}
+	if _, ok := src.Resolve("ssh://host" + logDir + "/app.log"); ok {
+		t.Error("期望拒绝非本地路径")
+	}
+}
+
+func TestLocalSource_Follow(t *testing.T) {
+	logDir := t.TempDir()
+	path := filepath.Join(logDir, "app.log")
+	writeFile(t, path, "first\n")
+
+	watcher := &recordingWatcher{callbacks: make(map[string]func(types.FileEvent))}
+	src, err := NewLocalSource("", watcher)
+	if err != nil {
+		t.Fatalf("创建本地日志源失败: %v", err)
+	}
+
+	var events []string
+	stop, err := src.Follow(path, func(event types.FileEvent) {
+		events = append(events, event.Type)
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	callback, ok := watcher.callbacks[path]
+	if !ok {
+		t.Fatalf("文件没有交给文件监控器: %v", watcher.callbacks)
+	}
+	callback(types.FileEvent{Path: path, Type: "modify"})
+	if len(events) != 1 || events[0] != "modify" {
+		t.Errorf("文件事件不正确: %v", events)
+	}
+
+	stop()
+	if len(watcher.unwatched) != 1 || watcher.unwatched[0] != path {
+		t.Errorf("停止跟踪时没有停止监控文件: %v", watcher.unwatched)
+	}
+}
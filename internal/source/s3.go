@@ -0,0 +1,766 @@
+package source
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+)
+
+const (
+	// emptyPayloadHash 空请求体的 SHA-256，GET、HEAD 请求签名时使用
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// maxS3GzipIndexes 最多保留解压索引的 .gz 对象数量，超过时丢弃最久未使用的
+	maxS3GzipIndexes = 64
+)
+
+// S3Source 通过 S3 兼容的 API 读取对象存储中的日志，root 为 s3://bucket 或 s3://bucket/prefix，
+// 对象的 key 按 / 分隔显示为目录。列出使用 ListObjectsV2，分页读取使用带 Range 的 GET；
+// .gz 对象读取时流式解压，读取的位置是解压后的；读取时建立解压索引（见 deflateIndex），之后的分页从最近的恢复点
+// 用带 Range 的 GET 继续读取。解压后的大小在第一次完整读取后才知道，之前显示压缩后的大小并标记为 SizeUnknown
+type S3Source struct {
+	root     string // s3://bucket/prefix
+	bucket   string
+	prefix   string // 根路径对应的 key 前缀，不带首尾的 /，可以为空
+	cfg      config.S3Config
+	endpoint *url.URL
+	region   string
+	creds    s3Credentials
+	client   *http.Client
+
+	span int64 // 解压索引中恢复点的间隔
+
+	mutex  sync.Mutex
+	gzips  map[string]*gzipObject // .gz 对象的解压索引，键为对象的 key
+	closed bool
+	stopCh chan struct{}
+}
+
+// s3Credentials 请求签名使用的凭证，AccessKeyID 为空时发送匿名请求
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// gzipObject .gz 对象一个版本（ETag）的解压索引，包括恢复点和完整读取后得到的解压后大小
+type gzipObject struct {
+	etag  string
+	index *deflateIndex
+	used  time.Time
+}
+
+// s3Object 对象的信息
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+}
+
+// listBucketResult ListObjectsV2 的响应中用到的字段
+type listBucketResult struct {
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+	Contents              []s3Object `xml:"Contents"`
+	CommonPrefixes        []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// NewS3Source 创建对象存储日志源，root 例如 s3://app-logs/archive/2024
+// 未配置的区域、服务地址和凭证从 AWS_REGION、AWS_ENDPOINT_URL、AWS_ACCESS_KEY_ID 等环境变量读取
+func NewS3Source(root string, cfg config.S3Config) (*S3Source, error) {
+	cfg = cfg.WithDefaults()
+
+	rest, ok := strings.CutPrefix(root, config.S3PathPrefix)
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if !ok || bucket == "" {
+		return nil, fmt.Errorf("无效的对象存储路径: %s", root)
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		if cleaned, ok := cleanObjectPath(prefix); ok {
+			prefix = cleaned
+		} else {
+			return nil, fmt.Errorf("无效的对象存储路径: %s", root)
+		}
+	}
+
+	region := firstNonEmpty(cfg.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	endpoint, err := url.Parse(firstNonEmpty(cfg.Endpoint, os.Getenv("AWS_ENDPOINT_URL"), "https://s3."+region+".amazonaws.com"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的对象存储服务地址: %s", cfg.Endpoint)
+	}
+
+	creds := s3Credentials{AccessKeyID: cfg.AccessKeyID, SecretAccessKey: cfg.SecretAccessKey, SessionToken: cfg.SessionToken}
+	if creds.AccessKeyID == "" {
+		creds = s3Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+
+	root = config.S3PathPrefix + bucket
+	if prefix != "" {
+		root += "/" + prefix
+	}
+	return &S3Source{
+		root:     root,
+		bucket:   bucket,
+		prefix:   prefix,
+		cfg:      cfg,
+		endpoint: endpoint,
+		region:   region,
+		creds:    creds,
+		client: &http.Client{
+			// 不设置总超时，读取大对象的时间不确定；等待响应头的时间受 Timeout 限制
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: cfg.Timeout,
+			},
+		},
+		span:   deflateSpan,
+		gzips:  make(map[string]*gzipObject),
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// cleanObjectPath 检查 / 分隔的相对路径，拒绝空的部分和 .、..
+func cleanObjectPath(rel string) (string, bool) {
+	rel = strings.TrimSuffix(rel, "/")
+	if rel == "" {
+		return "", false
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", false
+		}
+	}
+	return rel, true
+}
+
+// Root 源的根路径
+func (s *S3Source) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否位于根路径下，去掉末尾的 /
+func (s *S3Source) Resolve(p string) (string, bool) {
+	if p == s.root || p == s.root+"/" {
+		return s.root, true
+	}
+	rel, ok := strings.CutPrefix(p, s.root+"/")
+	if !ok {
+		return "", false
+	}
+	rel, ok = cleanObjectPath(rel)
+	if !ok {
+		return "", false
+	}
+	return s.root + "/" + rel, true
+}
+
+// objectKey 返回路径对应的对象 key，根路径返回前缀本身
+func (s *S3Source) objectKey(p string) (string, error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return "", fmt.Errorf("路径不在对象存储 %s 下: %s", s.root, p)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(resolved, s.root), "/")
+	switch {
+	case s.prefix == "":
+		return rel, nil
+	case rel == "":
+		return s.prefix, nil
+	default:
+		return s.prefix + "/" + rel, nil
+	}
+}
+
+// dirPrefix 目录中对象的 key 前缀
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// objectPath 返回对象 key 对应的路径
+func (s *S3Source) objectPath(key string) string {
+	rel := strings.TrimPrefix(key, dirPrefix(s.prefix))
+	return s.root + "/" + rel
+}
+
+// isGzipObject .gz 对象读取时解压
+func isGzipObject(key string) bool {
+	return strings.HasSuffix(strings.ToLower(key), ".gz")
+}
+
+// objectInfo 将对象转换为文件信息，.gz 对象已经完整读取过时使用解压后的大小，否则为压缩后的大小并标记为 SizeUnknown
+func (s *S3Source) objectInfo(obj s3Object) FileInfo {
+	etag := strings.Trim(obj.ETag, `"`)
+	info := FileInfo{
+		Path:    s.objectPath(obj.Key),
+		Name:    path.Base(obj.Key),
+		Size:    obj.Size,
+		ModTime: obj.LastModified,
+		ID:      etag,
+	}
+	if isGzipObject(obj.Key) {
+		info.Decompressed, info.SizeUnknown = true, true
+		s.mutex.Lock()
+		g := s.gzips[obj.Key]
+		s.mutex.Unlock()
+		if g != nil && g.etag == etag {
+			if size, ok := g.index.Size(); ok {
+				info.Size, info.SizeUnknown = size, false
+			}
+		}
+	}
+	return info
+}
+
+// List 列出目录的直接子节点，key 中的 / 作为目录分隔符
+func (s *S3Source) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	key, err := s.objectKey(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := dirPrefix(key)
+
+	var files []FileInfo
+	err = s.listObjects(ctx, prefix, "/", 0, func(result *listBucketResult) {
+		for _, p := range result.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/")
+			if name == "" {
+				continue
+			}
+			files = append(files, FileInfo{
+				Path:  s.objectPath(strings.TrimSuffix(p.Prefix, "/")),
+				Name:  name,
+				IsDir: true,
+			})
+		}
+		for _, obj := range result.Contents {
+			// 以 / 结尾的对象是控制台创建的目录占位
+			if obj.Key == prefix || strings.HasSuffix(obj.Key, "/") {
+				continue
+			}
+			files = append(files, s.objectInfo(obj))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && key != s.prefix {
+		// 前缀下没有对象时目录不存在
+		return nil, fmt.Errorf("目录不存在: %s: %w", dir, fs.ErrNotExist)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// Walk 列出根路径下的所有对象，目录由 key 中的 / 生成，跳过隐藏的文件和目录
+func (s *S3Source) Walk(ctx context.Context) ([]FileInfo, error) {
+	prefix := dirPrefix(s.prefix)
+
+	var files []FileInfo
+	dirs := make(map[string]bool)
+	err := s.listObjects(ctx, prefix, "", 0, func(result *listBucketResult) {
+		for _, obj := range result.Contents {
+			rel := strings.TrimPrefix(obj.Key, prefix)
+			if strings.HasSuffix(rel, "/") {
+				continue
+			}
+			rel, ok := cleanObjectPath(rel)
+			if !ok || isHiddenPath(rel) {
+				continue
+			}
+			for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+				if dirs[dir] {
+					break
+				}
+				dirs[dir] = true
+				files = append(files, FileInfo{Path: s.root + "/" + dir, Name: path.Base(dir), IsDir: true})
+			}
+			files = append(files, s.objectInfo(obj))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// isHiddenPath 路径中是否有以 . 开头的部分
+func isHiddenPath(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Stat 获取对象的信息，.gz 对象的大小与 List 相同；没有对应的对象但有以它为前缀的对象时作为目录
+func (s *S3Source) Stat(ctx context.Context, p string) (FileInfo, error) {
+	key, err := s.objectKey(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	resolved, _ := s.Resolve(p)
+
+	if resolved != s.root {
+		obj, err := s.headObject(ctx, key)
+		if err == nil {
+			return s.objectInfo(obj), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return FileInfo{}, err
+		}
+	}
+
+	// 根路径检查桶是否可以访问，其他路径检查前缀下是否有对象
+	found := false
+	err = s.listObjects(ctx, dirPrefix(key), "/", 1, func(result *listBucketResult) {
+		found = found || len(result.Contents) > 0 || len(result.CommonPrefixes) > 0
+	})
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if !found && resolved != s.root {
+		return FileInfo{}, fmt.Errorf("对象不存在: %s: %w", p, fs.ErrNotExist)
+	}
+	name := path.Base(key)
+	if key == "" {
+		name = s.bucket
+	}
+	return FileInfo{Path: resolved, Name: name, IsDir: true}, nil
+}
+
+// Open 从 offset 开始读取对象，普通对象使用带 Range 的 GET 只读取需要的范围，
+// .gz 对象从不晚于 offset 的最近恢复点开始解压并跳过 offset 之前的内容
+func (s *S3Source) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	key, err := s.objectKey(p)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, _ := s.Resolve(p); resolved == s.root {
+		return nil, fmt.Errorf("路径是目录: %s", p)
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	if !isGzipObject(key) {
+		byteRange := fmt.Sprintf("bytes=%d-", offset)
+		if length > 0 {
+			byteRange += strconv.FormatInt(offset+length-1, 10)
+		}
+		resp, err := s.getObject(ctx, key, byteRange)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			// offset 超过对象的大小
+			resp.Body.Close()
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return resp.Body, nil
+	}
+
+	zr, body, err := s.openGzip(ctx, key, offset)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, zr, offset-zr.position()); err != nil && err != io.EOF {
+		body.Close()
+		return nil, fmt.Errorf("解压 %s 失败: %w", key, err)
+	}
+	var r io.Reader = zr
+	if length > 0 {
+		r = io.LimitReader(zr, length)
+	}
+	return limitedReadCloser{Reader: r, Closer: body}, nil
+}
+
+// openGzip 从解压索引中不晚于 offset 的最近恢复点读取 .gz 对象；
+// 对象的 ETag 与索引不同（对象被覆盖）或服务端不支持 Range 时从头读取，使用对应 ETag 的新索引
+func (s *S3Source) openGzip(ctx context.Context, key string, offset int64) (*inflateReader, io.Closer, error) {
+	s.mutex.Lock()
+	g := s.gzips[key]
+	s.mutex.Unlock()
+
+	var point deflatePoint
+	byteRange := ""
+	if g != nil {
+		if point = g.index.point(offset); point.bit > 0 {
+			byteRange = fmt.Sprintf("bytes=%d-", point.bit/8)
+		}
+	}
+	resp, err := s.getObject(ctx, key, byteRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	etag := strings.Trim(resp.Header.Get("ETag"), `"`)
+	if g == nil || g.etag != etag || (byteRange != "" && resp.StatusCode != http.StatusPartialContent) {
+		if byteRange != "" {
+			resp.Body.Close()
+			if resp, err = s.getObject(ctx, key, ""); err != nil {
+				return nil, nil, err
+			}
+			etag = strings.Trim(resp.Header.Get("ETag"), `"`)
+		}
+		point = deflatePoint{}
+	}
+	g = s.gzipObject(key, etag)
+
+	zr, err := newInflateReader(g.index, point, resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("解压 %s 失败: %w", key, err)
+	}
+	return zr, resp.Body, nil
+}
+
+// gzipObject 返回 .gz 对象指定版本的解压索引，没有时创建，替换旧版本的索引；超过数量上限时丢弃最久未使用的索引
+func (s *S3Source) gzipObject(key, etag string) *gzipObject {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if g := s.gzips[key]; g != nil && g.etag == etag {
+		g.used = time.Now()
+		return g
+	}
+	g := &gzipObject{etag: etag, index: newDeflateIndex(true, s.span), used: time.Now()}
+	s.gzips[key] = g
+	if len(s.gzips) > maxS3GzipIndexes {
+		oldest := ""
+		for k, other := range s.gzips {
+			if oldest == "" || other.used.Before(s.gzips[oldest].used) {
+				oldest = k
+			}
+		}
+		delete(s.gzips, oldest)
+	}
+	return g
+}
+
+// Follow 定期检查对象的 ETag：对象出现时通知 create，被删除时通知 delete，
+// 被覆盖时变大通知 modify（例如追加后重新上传），否则通知 create
+func (s *S3Source) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	key, err := s.objectKey(p)
+	if err != nil {
+		return nil, err
+	}
+	resolved, _ := s.Resolve(p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		obj, err := s.headObject(ctx, key)
+		exists := err == nil
+		for {
+			select {
+			case <-time.After(s.cfg.PollInterval):
+			case <-ctx.Done():
+				return
+			}
+
+			current, err := s.headObject(ctx, key)
+			var event string
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				if exists {
+					event = "delete"
+				}
+				exists = false
+			case err != nil:
+				// 请求失败时在下一次检查时重试
+				if ctx.Err() == nil {
+					logger.Debug("检查对象失败", zap.String("key", key), zap.Error(err))
+				}
+				continue
+			default:
+				switch {
+				case !exists:
+					event = "create"
+				case current.ETag != obj.ETag && current.Size > obj.Size:
+					event = "modify"
+				case current.ETag != obj.ETag:
+					event = "create"
+				}
+				obj, exists = current, true
+			}
+			if event != "" {
+				callback(types.FileEvent{Path: resolved, Type: event})
+			}
+		}
+	}()
+	return cancel, nil
+}
+
+// Close 停止所有跟踪
+func (s *S3Source) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stopCh)
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// listObjects 使用 ListObjectsV2 列出前缀下的对象，按页调用 fn；maxKeys 大于 0 时只读取第一页
+func (s *S3Source) listObjects(ctx context.Context, prefix, delimiter string, maxKeys int, fn func(*listBucketResult)) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if maxKeys > 0 {
+			query.Set("max-keys", strconv.Itoa(maxKeys))
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, "")
+		if err != nil {
+			return err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("解析对象列表失败: %w", err)
+		}
+		fn(&result)
+
+		if maxKeys > 0 || !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// headObject 获取对象的大小、修改时间和 ETag
+func (s *S3Source) headObject(ctx context.Context, key string) (s3Object, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, "")
+	if err != nil {
+		return s3Object{}, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return s3Object{
+		Key:          key,
+		LastModified: modTime,
+		ETag:         resp.Header.Get("ETag"),
+		Size:         resp.ContentLength,
+	}, nil
+}
+
+// getObject 读取对象，byteRange 不为空时只读取该范围；范围超出对象大小时返回 416 响应而不是错误
+func (s *S3Source) getObject(ctx context.Context, key, byteRange string) (*http.Response, error) {
+	return s.do(ctx, http.MethodGet, key, nil, byteRange)
+}
+
+// do 发送签名后的请求，非 2xx 的响应转换为错误，404 满足 errors.Is(err, fs.ErrNotExist)
+func (s *S3Source) do(ctx context.Context, method, key string, query url.Values, byteRange string) (*http.Response, error) {
+	u := *s.endpoint
+	objectPath := "/" + key
+	if s.cfg.PathStyle {
+		objectPath = "/" + s.bucket
+		if key != "" {
+			objectPath += "/" + key
+		}
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	signS3Request(req, s.creds, s.region, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("连接对象存储 (%s) 失败: %w", s.endpoint.Host, err)
+	}
+	if (resp.StatusCode >= 200 && resp.StatusCode < 300) ||
+		(byteRange != "" && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable) {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	message := strings.TrimSpace(body.Code + " " + body.Message)
+	if message == "" {
+		message = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", message, fs.ErrNotExist)
+	}
+	return nil, fmt.Errorf("对象存储返回错误: %s", message)
+}
+
+// signS3Request 按 AWS Signature Version 4 签名请求，没有凭证时不签名（匿名访问公开的桶）
+func signS3Request(req *http.Request, creds s3Credentials, region string, now time.Time) {
+	if creds.AccessKeyID == "" {
+		return
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": emptyPayloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if creds.SessionToken != "" {
+		headers["x-amz-security-token"] = creds.SessionToken
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery 按参数名排序并编码查询参数，请求使用的查询字符串与签名时的相同
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape 按 SigV4 的规则编码：只保留字母、数字和 -_.~，encodeSlash 为 false 时保留 /
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,296 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/source/s3test"
+	"github.com/local-log-viewer/internal/types"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(data))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestS3Server 启动带凭证的测试服务器，每页最多返回 2 个对象以覆盖分页
+func newTestS3Server(t *testing.T) (*s3test.Server, config.S3Config) {
+	t.Helper()
+	server := s3test.NewServer("app-logs")
+	server.AccessKeyID, server.SecretAccessKey = "AKIDTEST", "secret/key+1"
+	server.PageSize = 2
+	t.Cleanup(server.Close)
+
+	return server, config.S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKIDTEST",
+		SecretAccessKey: "secret/key+1",
+		PathStyle:       true,
+	}
+}
+
+func TestS3Source_ListStatOpen(t *testing.T) {
+	server, cfg := newTestS3Server(t)
+	api := numberedLines("api", 1000)
+	worker := numberedLines("worker", 500)
+	server.Put("app-logs", "archive/app/api.log", []byte(api))
+	gzWorker := gzipBytes(t, worker)
+	server.Put("app-logs", "archive/app/worker.log.gz", gzWorker)
+	server.Put("app-logs", "archive/app/odd name+1.log", []byte("odd\n"))
+	server.Put("app-logs", "archive/.tmp/upload.log", []byte("hidden\n"))
+	server.Put("app-logs", "archive/README.txt", []byte("readme\n"))
+	server.Put("app-logs", "other/outside.log", []byte("outside\n"))
+
+	src, err := NewS3Source("s3://app-logs/archive/", cfg)
+	if err != nil {
+		t.Fatalf("创建对象存储日志源失败: %v", err)
+	}
+	defer src.Close()
+	ctx := context.Background()
+	root := "s3://app-logs/archive"
+	if src.Root() != root {
+		t.Fatalf("根路径不正确: %s", src.Root())
+	}
+
+	files, err := src.Walk(ctx)
+	if err != nil {
+		t.Fatalf("Walk 失败: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, strings.TrimPrefix(f.Path, root))
+	}
+	expected := "/README.txt,/app,/app/api.log,/app/odd name+1.log,/app/worker.log.gz"
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("期望文件 %s，得到 %s", expected, strings.Join(paths, ","))
+	}
+
+	children, err := src.List(ctx, root)
+	if err != nil || len(children) != 3 || !children[0].IsDir || children[0].Path != root+"/.tmp" || children[1].Name != "README.txt" || !children[2].IsDir {
+		t.Errorf("List 结果不正确: %+v, %v", children, err)
+	}
+	if _, err := src.List(ctx, root+"/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望目录不存在错误，得到 %v", err)
+	}
+
+	info, err := src.Stat(ctx, root+"/app/api.log")
+	if err != nil || info.Size != int64(len(api)) || info.IsDir || info.ID == "" || info.Decompressed {
+		t.Errorf("Stat 结果不正确: %+v, %v", info, err)
+	}
+
+	// 分页读取只请求需要的范围
+	server.Requests()
+	r, err := src.Open(ctx, root+"/app/api.log", 100, 50)
+	if err != nil {
+		t.Fatalf("Open 失败: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != api[100:150] {
+		t.Errorf("读取的内容不正确: %q", data)
+	}
+	if requests := server.Requests(); len(requests) != 1 || requests[0] != "GET /app-logs/archive/app/api.log bytes=100-149" {
+		t.Errorf("期望一次带 Range 的 GET，得到 %v", requests)
+	}
+	if got := readAll(t, src, root+"/app/api.log", int64(len(api))+10, -1); got != "" {
+		t.Errorf("超过对象大小的读取应为空: %q", got)
+	}
+	if got := readAll(t, src, root+"/app/odd name+1.log", 0, -1); got != "odd\n" {
+		t.Errorf("特殊字符 key 的内容不正确: %q", got)
+	}
+
+	// .gz 对象按解压后的位置读取；完整读取之前 Stat 与 List 一样显示压缩后的大小，不下载对象
+	server.Requests()
+	gzInfo, err := src.Stat(ctx, root+"/app/worker.log.gz")
+	if err != nil || gzInfo.Size != int64(len(gzWorker)) || !gzInfo.SizeUnknown || !gzInfo.Decompressed {
+		t.Errorf(".gz 对象的信息不正确: %+v, %v", gzInfo, err)
+	}
+	if requests := server.Requests(); len(requests) != 1 || !strings.HasPrefix(requests[0], "HEAD ") {
+		t.Errorf("Stat 不应读取对象的内容，得到请求 %v", requests)
+	}
+	if got := readAll(t, src, root+"/app/worker.log.gz", 3000, 100); got != worker[3000:3100] {
+		t.Errorf(".gz 对象的内容不正确: %q", got)
+	}
+
+	// 完整读取后记录解压后的大小，Stat 与 List 一致
+	if got := readAll(t, src, root+"/app/worker.log.gz", 0, -1); got != worker {
+		t.Errorf(".gz 对象的内容不正确，长度 %d", len(got))
+	}
+	gzInfo, err = src.Stat(ctx, root+"/app/worker.log.gz")
+	if err != nil || gzInfo.Size != int64(len(worker)) || gzInfo.SizeUnknown {
+		t.Errorf("完整读取后 .gz 对象的大小不正确: %+v, %v", gzInfo, err)
+	}
+	children, err = src.List(ctx, root+"/app")
+	if err != nil || len(children) != 3 || children[2].Size != gzInfo.Size || children[2].SizeUnknown {
+		t.Errorf("List 中 .gz 对象的大小应与 Stat 相同: %+v, %v", children, err)
+	}
+
+	// 多个 gzip 成员拼接的对象（例如拼接的轮转日志）：大小是所有成员解压后的总和，不是最后一个成员的 ISIZE
+	rotated := numberedLines("rotated", 300)
+	half := len(rotated) / 2
+	server.Put("app-logs", "archive/app/rotated.log.gz", append(gzipBytes(t, rotated[:half]), gzipBytes(t, rotated[half:])...))
+	if got := readAll(t, src, root+"/app/rotated.log.gz", 0, -1); got != rotated {
+		t.Errorf("多成员 .gz 对象的内容不正确，长度 %d", len(got))
+	}
+	if multiInfo, err := src.Stat(ctx, root+"/app/rotated.log.gz"); err != nil || multiInfo.Size != int64(len(rotated)) || multiInfo.SizeUnknown {
+		t.Errorf("多成员 .gz 对象的大小不正确: %+v, %v", multiInfo, err)
+	}
+	if got := readAll(t, src, root+"/app/rotated.log.gz", int64(half-50), 100); got != rotated[half-50:half+50] {
+		t.Errorf("跨成员读取的内容不正确: %q", got)
+	}
+
+	if dir, err := src.Stat(ctx, root+"/app"); err != nil || !dir.IsDir || dir.Name != "app" {
+		t.Errorf("前缀应作为目录: %+v, %v", dir, err)
+	}
+	if _, err := src.Stat(ctx, root+"/app/missing.log"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望对象不存在错误，得到 %v", err)
+	}
+	for _, p := range []string{"s3://app-logs/other/outside.log", root + "/../other/outside.log", root + "//app/api.log"} {
+		if _, ok := src.Resolve(p); ok {
+			t.Errorf("期望拒绝路径 %s", p)
+		}
+	}
+}
+
+func readAll(t *testing.T, src LogSource, p string, offset, length int64) string {
+	t.Helper()
+	r, err := src.Open(context.Background(), p, offset, length)
+	if err != nil {
+		t.Fatalf("Open(%s) 失败: %v", p, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestS3Source_GzipIndex(t *testing.T) {
+	server, cfg := newTestS3Server(t)
+	content := numberedLines("app", 50000)
+	server.Put("app-logs", "app.log.gz", gzipBytes(t, content))
+
+	src, err := NewS3Source("s3://app-logs", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	src.span = 16 << 10
+	p := "s3://app-logs/app.log.gz"
+	size := int64(len(content))
+
+	// 第一次完整读取建立解压索引
+	if got := readAll(t, src, p, 0, -1); got != content {
+		t.Fatalf("内容不正确，长度 %d", len(got))
+	}
+
+	// 之后的分页（包括尾部和向前翻页）从最近的恢复点用带 Range 的 GET 读取，不从头下载
+	for _, offset := range []int64{size - 100, size / 2, 1000} {
+		server.Requests()
+		if got := readAll(t, src, p, offset, 100); got != content[offset:offset+100] {
+			t.Errorf("offset %d 的内容不正确: %q", offset, got)
+		}
+		requests := server.Requests()
+		if offset < src.span {
+			continue
+		}
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "GET /app-logs/app.log.gz bytes=") || strings.HasSuffix(requests[0], "bytes=0-") {
+			t.Errorf("offset %d 期望从恢复点读取，得到请求 %v", offset, requests)
+		}
+	}
+
+	// 对象被覆盖后旧的恢复点不再适用，从头读取
+	replaced := numberedLines("new", 50000)
+	server.Put("app-logs", "app.log.gz", gzipBytes(t, replaced))
+	if info, err := src.Stat(context.Background(), p); err != nil || !info.SizeUnknown {
+		t.Errorf("对象被覆盖后大小应未知: %+v, %v", info, err)
+	}
+	if got := readAll(t, src, p, size/2, 100); got != replaced[size/2:size/2+100] {
+		t.Errorf("对象被覆盖后的内容不正确: %q", got)
+	}
+}
+
+func TestS3Source_Credentials(t *testing.T) {
+	server, cfg := newTestS3Server(t)
+	server.Put("app-logs", "api.log", []byte("hello\n"))
+
+	// 配置中没有凭证时从环境变量读取
+	t.Setenv("AWS_ACCESS_KEY_ID", cfg.AccessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", cfg.SecretAccessKey)
+	t.Setenv("AWS_REGION", "eu-west-1")
+	src, err := NewS3Source("s3://app-logs", config.S3Config{Endpoint: cfg.Endpoint, PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if got := readAll(t, src, "s3://app-logs/api.log", 0, -1); got != "hello\n" {
+		t.Errorf("内容不正确: %q", got)
+	}
+
+	cfg.SecretAccessKey = "wrong"
+	wrong, err := NewS3Source("s3://app-logs", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wrong.Close()
+	if _, err := wrong.Stat(context.Background(), "s3://app-logs"); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("期望签名错误，得到 %v", err)
+	}
+}
+
+func TestS3Source_Follow(t *testing.T) {
+	server, cfg := newTestS3Server(t)
+	cfg.PollInterval = 20 * time.Millisecond
+	server.Put("app-logs", "api.log", []byte("one\n"))
+
+	src, err := NewS3Source("s3://app-logs", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	events := make(chan types.FileEvent, 10)
+	stop, err := src.Follow("s3://app-logs/api.log", func(event types.FileEvent) {
+		events <- event
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	defer stop()
+
+	expect := func(eventType string) {
+		t.Helper()
+		select {
+		case event := <-events:
+			if event.Type != eventType || event.Path != "s3://app-logs/api.log" {
+				t.Errorf("期望 %s 事件，得到 %+v", eventType, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("没有收到 %s 事件", eventType)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	server.Put("app-logs", "api.log", []byte("one\ntwo\n"))
+	expect("modify")
+	server.Delete("app-logs", "api.log")
+	expect("delete")
+	server.Put("app-logs", "api.log", []byte("new\n"))
+	expect("create")
+}
@@ -0,0 +1,341 @@
+// Package s3test 提供测试用的 S3 兼容服务器（类似本地的 MinIO），只支持路径风格的地址
+package s3test
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 模拟 S3 API 中读取日志用到的部分：ListObjectsV2、HEAD 对象、带 Range 的 GET 对象。
+// 设置了凭证时校验 Signature Version 4 签名
+type Server struct {
+	// URL 服务地址，作为 S3Config.Endpoint
+	URL string
+	// AccessKeyID、SecretAccessKey 为空时接受匿名请求
+	AccessKeyID     string
+	SecretAccessKey string
+	// PageSize ListObjectsV2 每页的最大对象数，默认 1000
+	PageSize int
+
+	server *httptest.Server
+
+	mutex    sync.Mutex
+	buckets  map[string]map[string]object
+	requests []string
+}
+
+type object struct {
+	data    []byte
+	etag    string
+	modTime time.Time
+}
+
+// NewServer 启动服务器，buckets 为预先创建的桶
+func NewServer(buckets ...string) *Server {
+	s := &Server{buckets: make(map[string]map[string]object)}
+	for _, bucket := range buckets {
+		s.buckets[bucket] = make(map[string]object)
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close 关闭服务器
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Put 写入对象，已有的对象被覆盖
+func (s *Server) Put(bucket, key string, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sum := md5.Sum(data)
+	s.buckets[bucket][key] = object{
+		data:    append([]byte(nil), data...),
+		etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+		modTime: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Delete 删除对象
+func (s *Server) Delete(bucket, key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.buckets[bucket], key)
+}
+
+// Requests 返回收到的请求，每个为 "<方法> <路径> <Range>"，之后清空记录
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+r.Header.Get("Range")))
+	s.mutex.Unlock()
+
+	if code, message := s.checkSignature(r); code != "" {
+		writeError(w, http.StatusForbidden, code, message)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	s.mutex.Lock()
+	objects, ok := s.buckets[bucket]
+	var obj object
+	var found bool
+	if ok && key != "" {
+		obj, found = objects[key]
+	}
+	s.mutex.Unlock()
+
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r, bucket)
+	case key == "":
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "only ListObjectsV2 is supported")
+	case !found:
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		serveObject(w, r, obj)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// list 实现 ListObjectsV2：prefix、delimiter、max-keys、continuation-token
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	maxKeys := s.PageSize
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+	if n, err := strconv.Atoi(query.Get("max-keys")); err == nil && n < maxKeys {
+		maxKeys = n
+	}
+
+	s.mutex.Lock()
+	var keys []string
+	for key := range s.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	objects := s.buckets[bucket]
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+	type commonPrefix struct {
+		Prefix string `xml:"Prefix"`
+	}
+	var result struct {
+		XMLName               xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name                  string         `xml:"Name"`
+		Prefix                string         `xml:"Prefix"`
+		KeyCount              int            `xml:"KeyCount"`
+		MaxKeys               int            `xml:"MaxKeys"`
+		IsTruncated           bool           `xml:"IsTruncated"`
+		NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+		Contents              []content      `xml:"Contents"`
+		CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+	}
+	result.Name, result.Prefix, result.MaxKeys = bucket, prefix, maxKeys
+
+	// 续传令牌是上一页最后一个 key 或公共前缀（base64 编码），公共前缀下的 key 都已返回
+	token, _ := base64.URLEncoding.DecodeString(query.Get("continuation-token"))
+	after := string(token)
+	seenPrefixes := make(map[string]bool)
+	for _, key := range keys {
+		if key <= after || (delimiter != "" && strings.HasSuffix(after, delimiter) && strings.HasPrefix(key, after)) {
+			continue
+		}
+		entry := key
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+				if seenPrefixes[entry] || entry <= after {
+					continue
+				}
+			}
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		result.KeyCount++
+		result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(entry))
+		if entry != key {
+			seenPrefixes[entry] = true
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry})
+			continue
+		}
+		obj := objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: obj.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         obj.etag,
+			Size:         len(obj.data),
+		})
+	}
+	s.mutex.Unlock()
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(result)
+}
+
+// serveObject 返回对象的内容，支持 bytes=a-b、bytes=a-、bytes=-n 形式的 Range
+func serveObject(w http.ResponseWriter, r *http.Request, obj object) {
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	data := obj.data
+	status := http.StatusOK
+	if byteRange := r.Header.Get("Range"); byteRange != "" {
+		size := int64(len(data))
+		spec := strings.TrimPrefix(byteRange, "bytes=")
+		startText, endText, _ := strings.Cut(spec, "-")
+		start, end := int64(0), size-1
+		var err error
+		switch {
+		case startText == "":
+			var n int64
+			n, err = strconv.ParseInt(endText, 10, 64)
+			start = max(size-n, 0)
+		default:
+			start, err = strconv.ParseInt(startText, 10, 64)
+			if endText != "" && err == nil {
+				end, err = strconv.ParseInt(endText, 10, 64)
+			}
+		}
+		if err != nil || start >= size {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+			return
+		}
+		end = min(end, size-1)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+// checkSignature 按 Signature Version 4 重新计算签名，返回错误代码和信息
+func (s *Server) checkSignature(r *http.Request) (string, string) {
+	if s.AccessKeyID == "" {
+		return "", ""
+	}
+
+	auth := r.Header.Get("Authorization")
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[k] = v
+		}
+	}
+	credential := strings.Split(fields["Credential"], "/")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") || len(credential) != 5 {
+		return "AccessDenied", "missing or malformed Authorization header"
+	}
+	if credential[0] != s.AccessKeyID {
+		return "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records."
+	}
+	date, region := credential[1], credential[2]
+
+	var headers []string
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers = append(headers, name+":"+strings.TrimSpace(value)+"\n")
+	}
+
+	params := r.URL.Query()
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var query []string
+	for _, name := range names {
+		for _, value := range params[name] {
+			query = append(query, escape(name)+"="+escape(value))
+		}
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.Join(query, "&"),
+		strings.Join(headers, ""),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	scope := strings.Join(credential[1:], "/")
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = sign(key, part)
+	}
+	if hex.EncodeToString(sign(key, stringToSign)) != fields["Signature"] {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."
+	}
+	return "", ""
+}
+
+func sign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escape 按 RFC 3986 编码查询参数，空格编码为 %20
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, message)
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// FileInfo 日志源中文件或目录的信息
+type FileInfo struct {
+	Path    string // 完整路径，非本地的源带有前缀，例如 ssh://user@host/var/log/app/api.log
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	ID      string // 文件标识（例如设备号和 inode），文件被替换后变化
+
+	// Decompressed 读取的是解压后的内容（例如对象存储中的 .gz 对象），按去掉压缩扩展名的名称判断是否为日志文件
+	Decompressed bool
+	// SizeUnknown 解压后的大小还不知道（例如还没有完整读取过的 .gz 对象），Size 是压缩后的大小；
+	// 需要实际大小时完整读取一遍，日志源随之记录大小
+	SizeUnknown bool
+
+	// OSInfo 本地文件的 os.FileInfo，通过 os.SameFile 判断文件是否被替换；其他源为 nil
+	OSInfo os.FileInfo
+}
+
+// LogSource 日志的来源，LogManager 通过它列出、读取和跟踪文件
+// 本地文件系统是其中一种实现，其他源（远程主机、容器、归档、对象存储等）的路径使用 scheme:// 前缀
+type LogSource interface {
+	// Root 源的根路径，也是源的标识，在文件树中作为挂载点
+	Root() string
+
+	// Resolve 检查路径是否位于源的根路径下，返回规范化后的路径
+	Resolve(path string) (string, bool)
+
+	// List 列出目录的直接子节点
+	List(ctx context.Context, dir string) ([]FileInfo, error)
+
+	// Walk 递归列出根路径下的所有文件和目录（不包括根路径本身），跳过隐藏文件
+	Walk(ctx context.Context) ([]FileInfo, error)
+
+	// Stat 获取文件信息（包括文件标识），文件不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)
+	Stat(ctx context.Context, path string) (FileInfo, error)
+
+	// Open 从 offset 开始读取文件的一个范围，length 为负数时读取到文件末尾
+	Open(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+
+	// Follow 跟踪文件的变化，通过 callback 通知 create、modify、delete 事件，返回停止跟踪的函数
+	Follow(path string, callback func(types.FileEvent)) (func(), error)
+
+	// Close 关闭连接，停止所有跟踪
+	Close() error
+}
+
+// LineCounter 可以在源端统计行数的日志源，避免为了统计行数传输整个文件
+type LineCounter interface {
+	CountLines(ctx context.Context, path string) (int64, error)
+}
+
+// LocalFiles 文件就在本地文件系统上的日志源，LogManager 对这些文件使用文件池等本地优化
+type LocalFiles interface {
+	// LocalPath 返回文件在本地文件系统上的路径
+	LocalPath(path string) (string, bool)
+}
+
+// limitedReadCloser 只读取指定长度，关闭时关闭底层的读取器
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// IsRemote 判断路径是否为远程路径（带有 scheme://）
+func IsRemote(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// New 按根路径的 scheme 创建日志源
+func New(root string, cfg *config.Config) (LogSource, error) {
+	switch {
+	case strings.HasPrefix(root, config.SSHPathPrefix):
+		return NewSSHSource(root, cfg.SSH)
+	case strings.HasPrefix(root, config.DockerPathPrefix):
+		return NewDockerSource(root, cfg.Docker)
+	case strings.HasPrefix(root, config.JournalPathPrefix):
+		return NewJournalSource(root, cfg.Journal)
+	case strings.HasPrefix(root, config.S3PathPrefix):
+		return NewS3Source(root, cfg.S3)
+	default:
+		return nil, fmt.Errorf("不支持的日志源: %s", root)
+	}
+}
@@ -0,0 +1,293 @@
+// Package sourcetest 提供测试用的日志源
+package sourcetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local-log-viewer/internal/source"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// Memory 内存中的日志源，文件内容由测试写入，写入、追加和删除时通知跟踪者
+type Memory struct {
+	root string
+
+	mu        sync.Mutex
+	files     map[string]*memoryFile // 键为完整路径
+	followers map[string]map[int]func(types.FileEvent)
+	seq       int
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+	id      string
+}
+
+// NewMemory 创建根路径为 root（例如 mem://fixtures）的内存日志源
+func NewMemory(root string) *Memory {
+	return &Memory{
+		root:      strings.TrimSuffix(root, "/"),
+		files:     make(map[string]*memoryFile),
+		followers: make(map[string]map[int]func(types.FileEvent)),
+	}
+}
+
+// Write 创建或替换文件，name 为相对根路径的路径；替换后文件标识变化
+func (m *Memory) Write(name, content string) {
+	p := m.fullPath(name)
+
+	m.mu.Lock()
+	m.seq++
+	m.files[p] = &memoryFile{data: []byte(content), modTime: time.Now(), id: fmt.Sprintf("mem-%d", m.seq)}
+	callbacks := m.callbacksLocked(p)
+	m.mu.Unlock()
+
+	notify(callbacks, types.FileEvent{Path: p, Type: "create"})
+}
+
+// Append 向文件末尾追加内容，文件不存在时创建
+func (m *Memory) Append(name, content string) {
+	p := m.fullPath(name)
+
+	m.mu.Lock()
+	file, ok := m.files[p]
+	if !ok {
+		m.mu.Unlock()
+		m.Write(name, content)
+		return
+	}
+	file.data = append(file.data, content...)
+	file.modTime = time.Now()
+	callbacks := m.callbacksLocked(p)
+	m.mu.Unlock()
+
+	notify(callbacks, types.FileEvent{Path: p, Type: "modify"})
+}
+
+// Remove 删除文件
+func (m *Memory) Remove(name string) {
+	p := m.fullPath(name)
+
+	m.mu.Lock()
+	delete(m.files, p)
+	callbacks := m.callbacksLocked(p)
+	m.mu.Unlock()
+
+	notify(callbacks, types.FileEvent{Path: p, Type: "delete"})
+}
+
+// fullPath 相对根路径的名称对应的完整路径
+func (m *Memory) fullPath(name string) string {
+	return m.root + path.Clean("/"+name)
+}
+
+func (m *Memory) callbacksLocked(p string) []func(types.FileEvent) {
+	var callbacks []func(types.FileEvent)
+	for _, callback := range m.followers[p] {
+		callbacks = append(callbacks, callback)
+	}
+	return callbacks
+}
+
+func notify(callbacks []func(types.FileEvent), event types.FileEvent) {
+	for _, callback := range callbacks {
+		callback(event)
+	}
+}
+
+// Root 源的根路径
+func (m *Memory) Root() string {
+	return m.root
+}
+
+// Resolve 检查路径是否位于根路径下，返回规范化后的路径
+func (m *Memory) Resolve(p string) (string, bool) {
+	if p == m.root {
+		return p, true
+	}
+	if !strings.HasPrefix(p, m.root+"/") {
+		return "", false
+	}
+	// 不允许通过 .. 离开根路径
+	rel := strings.TrimPrefix(p, m.root)
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	if cleaned := path.Clean(rel); cleaned != "/" {
+		return m.root + cleaned, true
+	}
+	return m.root, true
+}
+
+// List 列出目录的直接子节点
+func (m *Memory) List(ctx context.Context, dir string) ([]source.FileInfo, error) {
+	resolved, ok := m.Resolve(dir)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", m.root, dir)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, isFile := m.files[resolved]; isFile {
+		return nil, fmt.Errorf("路径不是目录: %s", dir)
+	}
+	children := make(map[string]source.FileInfo)
+	for p, file := range m.files {
+		if !strings.HasPrefix(p, resolved+"/") {
+			continue
+		}
+		name, _, nested := strings.Cut(strings.TrimPrefix(p, resolved+"/"), "/")
+		if nested {
+			children[name] = source.FileInfo{Path: resolved + "/" + name, Name: name, IsDir: true, ModTime: file.modTime}
+			continue
+		}
+		children[name] = file.info(p)
+	}
+	if len(children) == 0 && resolved != m.root {
+		return nil, fmt.Errorf("目录不存在 %s: %w", dir, fs.ErrNotExist)
+	}
+	return sortedInfos(children), nil
+}
+
+// Walk 递归列出所有文件和目录，跳过隐藏文件
+func (m *Memory) Walk(ctx context.Context) ([]source.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make(map[string]source.FileInfo)
+	for p, file := range m.files {
+		rel := strings.TrimPrefix(p, m.root+"/")
+		if strings.HasPrefix(rel, ".") || strings.Contains(rel, "/.") {
+			continue
+		}
+		entries[p] = file.info(p)
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			full := m.root + "/" + dir
+			entries[full] = source.FileInfo{Path: full, Name: path.Base(dir), IsDir: true, ModTime: file.modTime}
+		}
+	}
+	return sortedInfos(entries), nil
+}
+
+// Stat 获取文件信息，目录由其下的文件推断
+func (m *Memory) Stat(ctx context.Context, p string) (source.FileInfo, error) {
+	resolved, ok := m.Resolve(p)
+	if !ok {
+		return source.FileInfo{}, fmt.Errorf("路径不在 %s 下: %s", m.root, p)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if file, ok := m.files[resolved]; ok {
+		return file.info(resolved), nil
+	}
+	if resolved == m.root {
+		return source.FileInfo{Path: m.root, Name: path.Base(m.root), IsDir: true}, nil
+	}
+	for p := range m.files {
+		if strings.HasPrefix(p, resolved+"/") {
+			return source.FileInfo{Path: resolved, Name: path.Base(resolved), IsDir: true}, nil
+		}
+	}
+	return source.FileInfo{}, fmt.Errorf("文件不存在 %s: %w", p, fs.ErrNotExist)
+}
+
+// Open 读取文件内容的快照
+func (m *Memory) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	resolved, ok := m.Resolve(p)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", m.root, p)
+	}
+
+	m.mu.Lock()
+	file, ok := m.files[resolved]
+	var data []byte
+	if ok {
+		data = append([]byte(nil), file.data...)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("文件不存在 %s: %w", p, fs.ErrNotExist)
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Follow 注册文件变化的回调，Write、Append、Remove 时同步调用
+func (m *Memory) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	resolved, ok := m.Resolve(p)
+	if !ok {
+		return nil, fmt.Errorf("路径不在 %s 下: %s", m.root, p)
+	}
+
+	m.mu.Lock()
+	m.seq++
+	id := m.seq
+	if m.followers[resolved] == nil {
+		m.followers[resolved] = make(map[int]func(types.FileEvent))
+	}
+	m.followers[resolved][id] = callback
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.followers[resolved], id)
+		m.mu.Unlock()
+	}, nil
+}
+
+// Followers 正在跟踪文件的回调数量
+func (m *Memory) Followers(name string) int {
+	p := m.fullPath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.followers[p])
+}
+
+// Close 停止所有跟踪
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	m.followers = make(map[string]map[int]func(types.FileEvent))
+	m.mu.Unlock()
+	return nil
+}
+
+func (f *memoryFile) info(p string) source.FileInfo {
+	return source.FileInfo{
+		Path:    p,
+		Name:    path.Base(p),
+		Size:    int64(len(f.data)),
+		ModTime: f.modTime,
+		ID:      f.id,
+	}
+}
+
+func sortedInfos(m map[string]source.FileInfo) []source.FileInfo {
+	infos := make([]source.FileInfo, 0, len(m))
+	for _, info := range m {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos
+}
@@ -0,0 +1,638 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/logger"
+	"github.com/local-log-viewer/internal/types"
+)
+
+var (
+	// errSourceClosed 日志源已关闭
+	errSourceClosed = errors.New("日志源已关闭")
+	// errNoSFTP 远程主机不支持 SFTP 子系统或配置了 ssh.exec，改为执行远程命令
+	errNoSFTP = errors.New("SFTP 不可用")
+)
+
+// SSHSource 通过 SSH 读取远程主机上的日志
+// 通过 SFTP 子系统列出和读取文件，与远程主机的操作系统和命令行工具无关；
+// 远程主机不支持 SFTP 或配置了 ssh.exec 时改为执行 find、tail 等 GNU 命令（见 ssh_exec.go）
+type SSHSource struct {
+	root   string // ssh://user@host:port/var/log/app
+	prefix string // ssh://user@host:port，远程文件的路径为 prefix + 远程绝对路径
+	dir    string // 远程主机上的根目录
+	addr   string
+	cfg    config.SSHConfig
+
+	clientConfig *ssh.ClientConfig
+	signers      []ssh.Signer
+
+	// sessions 限制执行远程命令时同时打开的会话数，SFTP 的请求共用一个会话
+	sessions chan struct{}
+
+	mutex      sync.Mutex
+	client     *ssh.Client
+	sftpClient *sftp.Client
+	useExec    bool // 使用远程命令代替 SFTP
+	closed     bool
+	stopCh     chan struct{}
+}
+
+// NewSSHSource 创建 SSH 日志源，root 例如 ssh://user@host:22/var/log/app
+// 只加载密钥和 known_hosts，第一次访问时才建立连接
+func NewSSHSource(root string, cfg config.SSHConfig) (*SSHSource, error) {
+	cfg = cfg.WithDefaults()
+
+	u, err := url.Parse(root)
+	if err != nil || u.Scheme != "ssh" || u.User == nil || u.Hostname() == "" || !strings.HasPrefix(u.Path, "/") {
+		return nil, fmt.Errorf("无效的远程日志路径: %s", root)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "22"
+	}
+
+	configured := cfg.KnownHostsFiles
+	if len(configured) == 0 {
+		configured = []string{"~/.ssh/known_hosts"}
+	}
+	knownHostsFiles := make([]string, len(configured))
+	for i, file := range configured {
+		knownHostsFiles[i] = expandHome(file)
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("读取 known_hosts 失败: %w", err)
+	}
+
+	signers, err := loadSigners(cfg.IdentityFiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) == 0 && (cfg.DisableAgent || os.Getenv("SSH_AUTH_SOCK") == "") {
+		return nil, fmt.Errorf("没有可用的 SSH 私钥或 ssh-agent")
+	}
+
+	addr := net.JoinHostPort(u.Hostname(), port)
+	prefix := config.SSHPathPrefix + u.User.Username() + "@" + u.Host
+	dir := path.Clean(u.Path)
+
+	return &SSHSource{
+		root:   prefix + dir,
+		prefix: prefix,
+		dir:    dir,
+		addr:   addr,
+		cfg:    cfg,
+		clientConfig: &ssh.ClientConfig{
+			User:              u.User.Username(),
+			HostKeyCallback:   hostKeyCallback,
+			HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeyCallback, addr),
+			Timeout:           cfg.Timeout,
+		},
+		signers:  signers,
+		sessions: make(chan struct{}, cfg.MaxSessions),
+		useExec:  cfg.Exec,
+		stopCh:   make(chan struct{}),
+	}, nil
+}
+
+// Root 源的根路径
+func (s *SSHSource) Root() string {
+	return s.root
+}
+
+// Resolve 检查路径是否位于根目录下，返回规范化后的路径
+func (s *SSHSource) Resolve(p string) (string, bool) {
+	if !strings.HasPrefix(p, s.prefix+"/") {
+		return "", false
+	}
+	remote := path.Clean(p[len(s.prefix):])
+	if remote != s.dir && !strings.HasPrefix(remote, strings.TrimSuffix(s.dir, "/")+"/") {
+		return "", false
+	}
+	return s.prefix + remote, true
+}
+
+// remotePath 返回文件在远程主机上的路径
+func (s *SSHSource) remotePath(p string) (string, error) {
+	resolved, ok := s.Resolve(p)
+	if !ok {
+		return "", fmt.Errorf("路径不在 %s 下: %s", s.root, p)
+	}
+	return resolved[len(s.prefix):], nil
+}
+
+// List 列出目录的直接子节点，符号链接按指向的文件或目录显示
+func (s *SSHSource) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	remote, err := s.remotePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.sftp()
+	if errors.Is(err, errNoSFTP) {
+		return s.listExec(ctx, remote)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := client.ReadDir(remote)
+	if err != nil {
+		return nil, fmt.Errorf("列出 %s 失败: %w", s.prefix+remote, err)
+	}
+	var files []FileInfo
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if info, ok := s.followEntry(client, path.Join(remote, entry.Name()), entry); ok {
+			files = append(files, info)
+		}
+	}
+	return files, nil
+}
+
+// Walk 递归列出根目录下的所有文件和目录，跳过隐藏文件和没有权限的子目录
+// 与本地日志源相同，指向目录的符号链接会列出但不进入，避免链接形成环时无限递归
+func (s *SSHSource) Walk(ctx context.Context) ([]FileInfo, error) {
+	client, err := s.sftp()
+	if errors.Is(err, errNoSFTP) {
+		return s.walkExec(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := client.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("列出 %s 失败: %w", s.root, err)
+	}
+
+	var files []FileInfo
+	var walk func(dir string, entries []os.FileInfo) error
+	walk = func(dir string, entries []os.FileInfo) error {
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			remote := path.Join(dir, entry.Name())
+			info, ok := s.followEntry(client, remote, entry)
+			if !ok {
+				continue
+			}
+			files = append(files, info)
+			if !entry.IsDir() {
+				continue
+			}
+
+			children, err := client.ReadDir(remote)
+			if err != nil {
+				logger.Debug("列出远程目录失败", zap.String("path", s.prefix+remote), zap.Error(err))
+				continue
+			}
+			if err := walk(remote, children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(s.dir, entries); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Stat 获取文件信息，跟随符号链接
+// SFTP 不提供 inode，文件标识为空，轮转通过文件变小检测
+func (s *SSHSource) Stat(ctx context.Context, p string) (FileInfo, error) {
+	remote, err := s.remotePath(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	client, err := s.sftp()
+	if errors.Is(err, errNoSFTP) {
+		return s.statExec(ctx, remote)
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	info, err := client.Stat(remote)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("获取 %s 的文件信息失败: %w", s.prefix+remote, err)
+	}
+	return s.fileInfo(remote, info), nil
+}
+
+// CountLines 统计行数，最后一行没有换行符时也计入
+// 执行远程命令时在远程主机上统计，使用 SFTP 时读取整个文件统计
+func (s *SSHSource) CountLines(ctx context.Context, p string) (int64, error) {
+	remote, err := s.remotePath(p)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.sftp(); errors.Is(err, errNoSFTP) {
+		return s.countLinesExec(ctx, remote)
+	}
+
+	r, err := s.Open(ctx, p, 0, -1)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var lines int64
+	last := byte('\n')
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
+
+// Open 从 offset 开始读取文件，length 为负数时读取到文件末尾
+// 关闭返回的读取器会结束读取，不需要读取完整个范围
+func (s *SSHSource) Open(ctx context.Context, p string, offset, length int64) (io.ReadCloser, error) {
+	remote, err := s.remotePath(p)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("无效的字节位置: %d", offset)
+	}
+	client, err := s.sftp()
+	if errors.Is(err, errNoSFTP) {
+		return s.openExec(ctx, remote, offset, length)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := client.Open(remote)
+	if err != nil {
+		return nil, fmt.Errorf("打开 %s 失败: %w", s.prefix+remote, err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	var reader io.Reader = &contextReader{ctx: ctx, r: file}
+	if length >= 0 {
+		reader = io.LimitReader(reader, length)
+	}
+	return &limitedReadCloser{Reader: reader, Closer: file}, nil
+}
+
+// followEntry 将目录项转换为文件信息，符号链接按指向的文件或目录转换，只返回普通文件和目录
+func (s *SSHSource) followEntry(client *sftp.Client, remote string, entry os.FileInfo) (FileInfo, bool) {
+	if entry.Mode()&os.ModeSymlink != 0 {
+		target, err := client.Stat(remote)
+		if err != nil {
+			return FileInfo{}, false
+		}
+		entry = target
+	}
+	if !entry.Mode().IsRegular() && !entry.IsDir() {
+		return FileInfo{}, false
+	}
+	return s.fileInfo(remote, entry), true
+}
+
+// fileInfo 将 SFTP 返回的文件信息转换为 FileInfo
+func (s *SSHSource) fileInfo(remote string, info os.FileInfo) FileInfo {
+	return FileInfo{
+		Path:    s.prefix + remote,
+		Name:    path.Base(remote),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+}
+
+// Follow 定期检查文件的大小、修改时间和文件标识，变化时通过 callback 通知
+func (s *SSHSource) Follow(p string, callback func(types.FileEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	previous, err := s.Stat(ctx, p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		cancel()
+		return nil, err
+	}
+	exists := err == nil
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			}
+
+			current, err := s.Stat(ctx, p)
+			var event string
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				if exists {
+					event = "delete"
+				}
+				exists = false
+			case err != nil:
+				// 连接失败时在下一次检查时重试
+				if ctx.Err() == nil {
+					logger.Debug("检查远程文件失败", zap.String("path", p), zap.Error(err))
+				}
+				continue
+			case !exists:
+				event = "create"
+				exists = true
+			case current.ID != previous.ID || current.Size != previous.Size || !current.ModTime.Equal(previous.ModTime):
+				event = "modify"
+			}
+			if err == nil {
+				previous = current
+			}
+			if event != "" {
+				callback(types.FileEvent{Path: p, Type: event})
+			}
+		}
+	}()
+
+	return cancel, nil
+}
+
+// Close 关闭连接，停止所有跟踪
+func (s *SSHSource) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.stopCh)
+	if s.sftpClient != nil {
+		s.sftpClient.Close()
+		s.sftpClient = nil
+	}
+	if s.client != nil {
+		err := s.client.Close()
+		s.client = nil
+		return err
+	}
+	return nil
+}
+
+// session 打开一个会话，连接断开时重新连接，返回的 release 需要在会话结束后调用
+func (s *SSHSource) session(ctx context.Context) (*ssh.Session, func(), error) {
+	select {
+	case s.sessions <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	release := func() { <-s.sessions }
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, err := s.newSessionLocked()
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return session, release, nil
+}
+
+// sftp 返回 SFTP 客户端，第一次使用或连接断开后建立连接
+// 配置了 ssh.exec 或远程主机不支持 SFTP 子系统时返回 errNoSFTP
+func (s *SSHSource) sftp() (*sftp.Client, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.useExec {
+		return nil, errNoSFTP
+	}
+	if s.sftpClient != nil {
+		return s.sftpClient, nil
+	}
+
+	session, err := s.newSessionLocked()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		logger.Warn("远程主机不支持 SFTP，改为执行远程命令读取文件", zap.String("addr", s.addr), zap.Error(err))
+		s.useExec = true
+		return nil, errNoSFTP
+	}
+	client, err := sftp.NewClientPipe(stdout, stdin)
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("初始化 SFTP 失败: %w", err)
+	}
+	s.sftpClient = client
+
+	// SFTP 会话结束（例如连接断开）后，下一次使用时重新建立
+	go func() {
+		client.Wait()
+		session.Close()
+		s.mutex.Lock()
+		if s.sftpClient == client {
+			s.sftpClient = nil
+		}
+		s.mutex.Unlock()
+	}()
+	return client, nil
+}
+
+// newSessionLocked 在当前连接上打开会话，连接断开时重新连接，调用方需持有 mutex
+func (s *SSHSource) newSessionLocked() (*ssh.Session, error) {
+	if s.closed {
+		return nil, errSourceClosed
+	}
+
+	if s.client != nil {
+		session, err := s.client.NewSession()
+		if err == nil {
+			return session, nil
+		}
+		// 服务器拒绝打开会话时连接仍然可用，其他错误说明连接已经断开
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			return nil, fmt.Errorf("打开 SSH 会话失败: %w", err)
+		}
+		s.client.Close()
+		s.client = nil
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %w", s.addr, err)
+	}
+	s.client = client
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("打开 SSH 会话失败: %w", err)
+	}
+	return session, nil
+}
+
+// dial 建立连接，ssh-agent 中的密钥优先于私钥文件
+func (s *SSHSource) dial() (*ssh.Client, error) {
+	signers := s.signers
+	if socket := os.Getenv("SSH_AUTH_SOCK"); !s.cfg.DisableAgent && socket != "" {
+		conn, err := net.DialTimeout("unix", socket, s.cfg.Timeout)
+		if err != nil {
+			logger.Warn("连接 ssh-agent 失败", zap.String("socket", socket), zap.Error(err))
+		} else {
+			// 签名在握手期间完成，连接建立后即可关闭
+			defer conn.Close()
+			agentSigners, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				logger.Warn("读取 ssh-agent 密钥失败", zap.Error(err))
+			}
+			signers = append(agentSigners, signers...)
+		}
+	}
+
+	clientConfig := *s.clientConfig
+	clientConfig.Auth = []ssh.AuthMethod{ssh.PublicKeys(signers...)}
+	return ssh.Dial("tcp", s.addr, &clientConfig)
+}
+
+// loadSigners 加载私钥文件，未配置时使用 ~/.ssh 下存在的默认私钥
+// 有密码保护的私钥需要通过 ssh-agent 使用
+func loadSigners(files []string) ([]ssh.Signer, error) {
+	explicit := len(files) > 0
+	if !explicit {
+		files = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+	}
+
+	var signers []ssh.Signer
+	for _, file := range files {
+		data, err := os.ReadFile(expandHome(file))
+		if err != nil {
+			if !explicit && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("读取私钥 %s 失败: %w", file, err)
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				logger.Warn("私钥有密码保护，已跳过，请通过 ssh-agent 使用", zap.String("file", file))
+				continue
+			}
+			return nil, fmt.Errorf("解析私钥 %s 失败: %w", file, err)
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// knownHostKeyAlgorithms 返回 known_hosts 中该主机已有密钥的算法
+// 握手时只协商这些算法，避免服务器选择 known_hosts 中没有的密钥类型导致校验失败
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, addr string) []string {
+	// 用不存在的密钥查询，KeyError.Want 中是已知的密钥
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if err := callback(addr, &net.TCPAddr{IP: net.IPv4zero}, probe); !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, known := range keyErr.Want {
+		candidates := []string{known.Key.Type()}
+		if known.Key.Type() == ssh.KeyAlgoRSA {
+			candidates = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+		}
+		for _, algorithm := range candidates {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
+
+// contextReader 在 ctx 取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,217 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 远程主机不支持 SFTP 或配置了 ssh.exec 时，通过执行 GNU find、tail、awk 等命令读取文件
+
+// findFormat find -printf 的输出格式：类型、大小、修改时间、文件标识、路径
+const findFormat = `%y\t%s\t%T@\t%D:%i\t%p\n`
+
+// listExec 通过 find 列出目录的直接子节点
+func (s *SSHSource) listExec(ctx context.Context, remote string) ([]FileInfo, error) {
+	out, err := s.run(ctx, fmt.Sprintf(`find -L %s -mindepth 1 -maxdepth 1 \( -type f -o -type d \) -printf '%s'`, quote(remote), findFormat))
+	// 部分子目录没有权限时 find 以非零状态退出，已列出的内容仍然可用
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return s.parseFind(out), nil
+}
+
+// walkExec 通过 find 递归列出根目录下的所有文件和目录，跳过隐藏文件
+func (s *SSHSource) walkExec(ctx context.Context) ([]FileInfo, error) {
+	out, err := s.run(ctx, fmt.Sprintf(`find -L %s -mindepth 1 -name '.*' -prune -o \( -type f -o -type d \) -printf '%s'`, quote(s.dir), findFormat))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return s.parseFind(out), nil
+}
+
+// statExec 通过 find 获取文件信息
+func (s *SSHSource) statExec(ctx context.Context, remote string) (FileInfo, error) {
+	out, err := s.run(ctx, fmt.Sprintf(`find -L %s -maxdepth 0 -printf '%s'`, quote(remote), findFormat))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	files := s.parseFind(out)
+	if len(files) != 1 {
+		return FileInfo{}, fmt.Errorf("无法解析 %s 的文件信息", s.prefix+remote)
+	}
+	return files[0], nil
+}
+
+// countLinesExec 在远程主机上通过 awk 统计行数，最后一行没有换行符时也计入
+func (s *SSHSource) countLinesExec(ctx context.Context, remote string) (int64, error) {
+	out, err := s.run(ctx, "awk 'END { print NR }' "+quote(remote))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+}
+
+// openExec 通过 cat 或 tail 从 offset 开始读取文件，关闭返回的读取器会结束远程命令
+func (s *SSHSource) openExec(ctx context.Context, remote string, offset, length int64) (io.ReadCloser, error) {
+	cmd := "cat " + quote(remote)
+	if offset > 0 {
+		cmd = fmt.Sprintf("tail -c +%d %s", offset+1, quote(remote))
+	}
+	reader, err := s.start(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if length >= 0 {
+		return &limitedReadCloser{Reader: io.LimitReader(reader, length), Closer: reader}, nil
+	}
+	return reader, nil
+}
+
+// run 执行远程命令并返回标准输出，命令失败时同时返回已经输出的内容
+func (s *SSHSource) run(ctx context.Context, cmd string) ([]byte, error) {
+	session, release, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	if err := session.Run(cmd); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return stdout.Bytes(), commandError(err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// start 启动远程命令，返回读取标准输出的读取器
+func (s *SSHSource) start(ctx context.Context, cmd string) (*commandReader, error) {
+	session, release, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &commandReader{session: session, release: release}
+	session.Stderr = &reader.stderr
+	stdout, err := session.StdoutPipe()
+	if err == nil {
+		err = session.Start(cmd)
+	}
+	if err != nil {
+		session.Close()
+		release()
+		return nil, fmt.Errorf("启动远程命令失败: %w", err)
+	}
+	reader.stdout = stdout
+	reader.stop = context.AfterFunc(ctx, func() { session.Close() })
+	return reader, nil
+}
+
+// parseFind 解析 find -printf findFormat 的输出
+func (s *SSHSource) parseFind(out []byte) []FileInfo {
+	var files []FileInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(line, "\t", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		files = append(files, FileInfo{
+			Path:    s.prefix + fields[4],
+			Name:    path.Base(fields[4]),
+			Size:    size,
+			ModTime: parseFindTime(fields[2]),
+			IsDir:   fields[0] == "d",
+			ID:      fields[3],
+		})
+	}
+	return files
+}
+
+// parseFindTime 解析 %T@ 输出的时间，例如 1700000000.1234567890
+func parseFindTime(value string) time.Time {
+	secPart, fracPart, _ := strings.Cut(value, ".")
+	sec, err := strconv.ParseInt(secPart, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	var nsec int64
+	if fracPart != "" {
+		nsec, _ = strconv.ParseInt((fracPart + "000000000")[:9], 10, 64)
+	}
+	return time.Unix(sec, nsec)
+}
+
+// commandReader 远程命令的标准输出，读取到末尾时检查命令的退出状态
+type commandReader struct {
+	session *ssh.Session
+	stdout  io.Reader
+	stderr  bytes.Buffer
+	release func()
+	stop    func() bool
+
+	waited  bool
+	waitErr error
+	once    sync.Once
+}
+
+func (r *commandReader) Read(p []byte) (int, error) {
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		if !r.waited {
+			r.waited = true
+			if waitErr := r.session.Wait(); waitErr != nil {
+				r.waitErr = commandError(waitErr, r.stderr.String())
+			}
+		}
+		if r.waitErr != nil {
+			return n, r.waitErr
+		}
+	}
+	return n, err
+}
+
+// Close 结束远程命令并释放会话
+func (r *commandReader) Close() error {
+	r.once.Do(func() {
+		r.stop()
+		r.session.Close()
+		r.release()
+	})
+	return nil
+}
+
+// commandError 将远程命令的错误转换为包含标准错误输出的错误，文件不存在时满足 errors.Is(err, fs.ErrNotExist)
+func commandError(err error, stderr string) error {
+	msg := strings.TrimSpace(stderr)
+	if strings.Contains(msg, "No such file") {
+		return fmt.Errorf("%s: %w", msg, fs.ErrNotExist)
+	}
+	if msg != "" {
+		return fmt.Errorf("远程命令失败: %s: %w", msg, err)
+	}
+	return fmt.Errorf("远程命令失败: %w", err)
+}
+
+// quote 将参数转义为 shell 单引号字符串
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
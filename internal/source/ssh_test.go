@@ -0,0 +1,352 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/local-log-viewer/internal/config"
+	"github.com/local-log-viewer/internal/source/sshtest"
+	"github.com/local-log-viewer/internal/types"
+)
+
+// newTestSSHSource 启动进程内的 SSH 服务器，返回以临时目录为根目录、使用私钥文件认证的日志源
+func newTestSSHSource(t *testing.T) (*SSHSource, string) {
+	t.Helper()
+	src, _, logDir := startTestSSHSource(t, nil)
+	return src, logDir
+}
+
+// startTestSSHSource 与 newTestSSHSource 相同，创建日志源前先用 setup 配置服务器
+func startTestSSHSource(t *testing.T, setup func(*sshtest.Server)) (*SSHSource, *sshtest.Server, string) {
+	t.Helper()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	signer, privateKey, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	server, err := sshtest.NewServer(signer.PublicKey())
+	if err != nil {
+		t.Fatalf("启动 SSH 服务器失败: %v", err)
+	}
+	t.Cleanup(server.Close)
+	if setup != nil {
+		setup(server)
+	}
+
+	keyDir := t.TempDir()
+	identityFile := filepath.Join(keyDir, "id_ed25519")
+	if err := os.WriteFile(identityFile, privateKey, 0600); err != nil {
+		t.Fatalf("写入私钥失败: %v", err)
+	}
+	knownHosts, err := server.WriteKnownHosts(keyDir)
+	if err != nil {
+		t.Fatalf("写入 known_hosts 失败: %v", err)
+	}
+
+	logDir := t.TempDir()
+	src, err := NewSSHSource("ssh://tester@"+server.Addr+logDir, config.SSHConfig{
+		KnownHostsFiles: []string{knownHosts},
+		IdentityFiles:   []string{identityFile},
+		PollInterval:    20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("创建 SSH 日志源失败: %v", err)
+	}
+	t.Cleanup(func() { src.Close() })
+	return src, server, logDir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+}
+
+func TestSSHSource_ListStatOpen(t *testing.T) {
+	src, server, logDir := startTestSSHSource(t, nil)
+	// 指向上级目录的符号链接不会让 Walk 无限递归
+	writeFile(t, filepath.Join(logDir, "nginx", "access.log"), "GET /\n")
+	if err := os.Symlink(logDir, filepath.Join(logDir, "nginx", "root")); err != nil {
+		t.Fatalf("创建符号链接失败: %v", err)
+	}
+	testSSHSourceFiles(t, src, logDir, "/app.log,/nginx,/nginx/access.log,/nginx/root")
+
+	if commands := server.Commands(); len(commands) != 0 {
+		t.Errorf("SFTP 可用时不应执行远程命令: %v", commands)
+	}
+}
+
+// 远程主机不支持 SFTP 时改为执行 find、tail 等命令
+func TestSSHSource_ExecFallback(t *testing.T) {
+	src, server, logDir := startTestSSHSource(t, (*sshtest.Server).DisableSFTP)
+	testSSHSourceFiles(t, src, logDir, "/app.log,/nginx,/nginx/access.log")
+
+	if len(server.Commands()) == 0 {
+		t.Error("期望通过远程命令读取文件")
+	}
+}
+
+// testSSHSourceFiles 检查 Walk、List、Stat、Open、CountLines 的结果，walked 是 Walk 期望列出的相对路径
+func testSSHSourceFiles(t *testing.T, src *SSHSource, logDir, walked string) {
+	t.Helper()
+	writeFile(t, filepath.Join(logDir, "app.log"), "line 1\nline 2\nline 3")
+	writeFile(t, filepath.Join(logDir, "nginx", "access.log"), "GET /\n")
+	writeFile(t, filepath.Join(logDir, ".cache", "hidden.log"), "hidden\n")
+	ctx := context.Background()
+	root := src.Root()
+
+	files, err := src.Walk(ctx)
+	if err != nil {
+		t.Fatalf("Walk 失败: %v", err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, strings.TrimPrefix(f.Path, root))
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != walked {
+		t.Errorf("Walk 结果不正确: %v", paths)
+	}
+
+	children, err := src.List(ctx, root)
+	var names []string
+	for _, child := range children {
+		names = append(names, child.Name)
+	}
+	sort.Strings(names)
+	if err != nil || strings.Join(names, ",") != ".cache,app.log,nginx" {
+		t.Errorf("List 结果不正确: %+v, %v", children, err)
+	}
+
+	info, err := src.Stat(ctx, root+"/app.log")
+	if err != nil {
+		t.Fatalf("Stat 失败: %v", err)
+	}
+	local, _ := os.Stat(filepath.Join(logDir, "app.log"))
+	if info.Size != 20 || info.IsDir || !info.ModTime.Truncate(time.Second).Equal(local.ModTime().Truncate(time.Second)) {
+		t.Errorf("Stat 结果不正确: %+v", info)
+	}
+
+	if _, err := src.Stat(ctx, root+"/missing.log"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望文件不存在错误，得到 %v", err)
+	}
+
+	read := func(offset, length int64) string {
+		r, err := src.Open(ctx, root+"/app.log", offset, length)
+		if err != nil {
+			t.Fatalf("Open 失败: %v", err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("读取失败: %v", err)
+		}
+		return string(data)
+	}
+	if got := read(0, -1); got != "line 1\nline 2\nline 3" {
+		t.Errorf("读取整个文件不正确: %q", got)
+	}
+	if got := read(7, 6); got != "line 2" {
+		t.Errorf("读取范围不正确: %q", got)
+	}
+
+	r, err := src.Open(ctx, root+"/missing.log", 0, -1)
+	if err == nil {
+		_, err = io.ReadAll(r)
+		r.Close()
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("期望读取不存在的文件失败，得到 %v", err)
+	}
+
+	lines, err := src.CountLines(ctx, root+"/app.log")
+	if err != nil || lines != 3 {
+		t.Errorf("期望 3 行，得到 %d, %v", lines, err)
+	}
+}
+
+func TestSSHSource_Resolve(t *testing.T) {
+	src, _ := newTestSSHSource(t)
+	root := src.Root()
+
+	if resolved, ok := src.Resolve(root + "/nginx/./access.log"); !ok || resolved != root+"/nginx/access.log" {
+		t.Errorf("路径规范化不正确: %s, %v", resolved, ok)
+	}
+	for _, p := range []string{
+		root + "/../../etc/passwd",
+		root + "-other/app.log",
+		"/var/log/app.log",
+	} {
+		if _, ok := src.Resolve(p); ok {
+			t.Errorf("期望拒绝根目录外的路径: %s", p)
+		}
+	}
+	if _, err := src.Stat(context.Background(), root+"/../../etc/passwd"); err == nil {
+		t.Error("期望读取根目录外的路径失败")
+	}
+}
+
+func TestSSHSource_Follow(t *testing.T) {
+	src, logDir := newTestSSHSource(t)
+	localPath := filepath.Join(logDir, "app.log")
+	writeFile(t, localPath, "first\n")
+
+	events := make(chan string, 10)
+	stop, err := src.Follow(src.Root()+"/app.log", func(event types.FileEvent) {
+		events <- event.Type
+	})
+	if err != nil {
+		t.Fatalf("Follow 失败: %v", err)
+	}
+	defer stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("期望 %s 事件，得到 %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待 %s 事件超时", want)
+		}
+	}
+
+	f, err := os.OpenFile(localPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	f.WriteString("second\n")
+	f.Close()
+	expect("modify")
+
+	os.Remove(localPath)
+	expect("delete")
+
+	writeFile(t, localPath, "recreated\n")
+	expect("create")
+}
+
+func TestSSHSource_AgentAuth(t *testing.T) {
+	// 没有私钥文件，只有 ssh-agent 中的密钥
+	t.Setenv("HOME", t.TempDir())
+
+	signer, privateKey, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	server, err := sshtest.NewServer(signer.PublicKey())
+	if err != nil {
+		t.Fatalf("启动 SSH 服务器失败: %v", err)
+	}
+	defer server.Close()
+
+	rawKey, err := ssh.ParseRawPrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("解析私钥失败: %v", err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: rawKey}); err != nil {
+		t.Fatalf("添加 agent 密钥失败: %v", err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("监听 agent socket 失败: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	knownHosts, err := server.WriteKnownHosts(t.TempDir())
+	if err != nil {
+		t.Fatalf("写入 known_hosts 失败: %v", err)
+	}
+	logDir := t.TempDir()
+	writeFile(t, filepath.Join(logDir, "app.log"), "hello\n")
+
+	src, err := NewSSHSource("ssh://tester@"+server.Addr+logDir, config.SSHConfig{KnownHostsFiles: []string{knownHosts}})
+	if err != nil {
+		t.Fatalf("创建 SSH 日志源失败: %v", err)
+	}
+	defer src.Close()
+
+	if info, err := src.Stat(context.Background(), src.Root()+"/app.log"); err != nil || info.Size != 6 {
+		t.Errorf("通过 ssh-agent 认证读取失败: %+v, %v", info, err)
+	}
+
+	// 禁用 agent 后没有可用的密钥
+	if _, err := NewSSHSource("ssh://tester@"+server.Addr+logDir, config.SSHConfig{KnownHostsFiles: []string{knownHosts}, DisableAgent: true}); err == nil {
+		t.Error("期望没有可用密钥时创建失败")
+	}
+}
+
+func TestSSHSource_HostKeyMismatch(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	signer, privateKey, err := sshtest.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	server, err := sshtest.NewServer(signer.PublicKey())
+	if err != nil {
+		t.Fatalf("启动 SSH 服务器失败: %v", err)
+	}
+	defer server.Close()
+
+	// known_hosts 中记录的是另一台主机的密钥
+	impostor, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("启动 SSH 服务器失败: %v", err)
+	}
+	impostor.Close()
+	impostor.Addr = server.Addr
+
+	keyDir := t.TempDir()
+	knownHosts, err := impostor.WriteKnownHosts(keyDir)
+	if err != nil {
+		t.Fatalf("写入 known_hosts 失败: %v", err)
+	}
+	identityFile := filepath.Join(keyDir, "id_ed25519")
+	if err := os.WriteFile(identityFile, privateKey, 0600); err != nil {
+		t.Fatalf("写入私钥失败: %v", err)
+	}
+
+	src, err := NewSSHSource("ssh://tester@"+server.Addr+"/var/log", config.SSHConfig{
+		KnownHostsFiles: []string{knownHosts},
+		IdentityFiles:   []string{identityFile},
+	})
+	if err != nil {
+		t.Fatalf("创建 SSH 日志源失败: %v", err)
+	}
+	defer src.Close()
+
+	if _, err := src.Walk(context.Background()); err == nil || !strings.Contains(err.Error(), "knownhosts") {
+		t.Errorf("期望主机密钥校验失败，得到 %v", err)
+	}
+}
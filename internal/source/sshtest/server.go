@@ -0,0 +1,225 @@
+// Package sshtest 提供在进程内运行的 SSH 服务器，用于测试远程日志源
+package sshtest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Server 只接受指定公钥认证的 SSH 服务器，exec 请求在本机通过 sh -c 执行，sftp 子系统读取本机文件
+type Server struct {
+	Addr    string
+	HostKey ssh.PublicKey
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mutex    sync.Mutex
+	conns    map[net.Conn]bool
+	commands []string
+	noSFTP   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer 在 127.0.0.1 的随机端口上启动服务器
+func NewServer(authorizedKeys ...ssh.PublicKey) (*Server, error) {
+	hostSigner, _, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := make(map[string]bool)
+	for _, key := range authorizedKeys {
+		authorized[string(key.Marshal())] = true
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorized[string(key.Marshal())] {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		HostKey:  hostSigner.PublicKey(),
+		listener: listener,
+		config:   config,
+		conns:    make(map[net.Conn]bool),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// WriteKnownHosts 将服务器的主机密钥写入 dir 下的 known_hosts 文件，返回文件路径
+func (s *Server) WriteKnownHosts(dir string) (string, error) {
+	path := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.Addr)}, s.HostKey) + "\n"
+	return path, os.WriteFile(path, []byte(line), 0600)
+}
+
+// Commands 返回已执行的命令
+func (s *Server) Commands() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// DisableSFTP 拒绝之后的 sftp 子系统请求，模拟不支持 SFTP 的远程主机
+func (s *Server) DisableSFTP() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.noSFTP = true
+}
+
+// Close 停止服务器并断开所有连接
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mutex.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns[conn] = true
+		s.mutex.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(channel, requests)
+	}
+}
+
+// handleSession 处理会话中的 exec 和 sftp 子系统请求，客户端关闭会话时结束正在执行的命令
+func (s *Server) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for req := range requests {
+		if req.Type == "subsystem" {
+			var payload struct{ Name string }
+			s.mutex.Lock()
+			noSFTP := s.noSFTP
+			s.mutex.Unlock()
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" || noSFTP {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			go func() {
+				server, err := sftp.NewServer(channel, sftp.ReadOnly())
+				if err == nil {
+					server.Serve()
+					server.Close()
+				}
+				channel.Close()
+			}()
+			continue
+		}
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		s.mutex.Lock()
+		s.commands = append(s.commands, payload.Command)
+		s.mutex.Unlock()
+
+		go func() {
+			cmd := exec.CommandContext(ctx, "sh", "-c", payload.Command)
+			cmd.Stdout = channel
+			cmd.Stderr = channel.Stderr()
+
+			var status uint32
+			if err := cmd.Run(); err != nil {
+				status = 255
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+					status = uint32(exitErr.ExitCode())
+				}
+			}
+			channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+			channel.Close()
+		}()
+	}
+}
+
+// GenerateKey 生成 ed25519 密钥，返回签名器和 OpenSSH 格式的私钥
+func GenerateKey() (ssh.Signer, []byte, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, pem.EncodeToMemory(block), nil
+}
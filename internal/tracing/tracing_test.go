@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/local-log-viewer/internal/config"
+)
+
+func TestStartEnd(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	if id := TraceID(context.Background()); id != "" {
+		t.Errorf("expected empty trace ID without span, got %s", id)
+	}
+
+	ctx, parent := StartServer(context.Background(), "GET /api/logs")
+	_, child := Start(ctx, "manager.ReadLogFile", attribute.String("file.path", "/logs/app.log"))
+	End(child, errors.New("file not found"))
+	End(parent, nil)
+
+	if id := TraceID(ctx); id != parent.SpanContext().TraceID().String() {
+		t.Errorf("unexpected trace ID %s", id)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	readSpan, requestSpan := spans[0], spans[1]
+	if readSpan.Parent.SpanID() != requestSpan.SpanContext.SpanID() {
+		t.Error("expected read span to be a child of the request span")
+	}
+	if readSpan.Status.Code != codes.Error || len(readSpan.Events) != 1 {
+		t.Errorf("expected failed span with error event, got %+v", readSpan.Status)
+	}
+	if requestSpan.Status.Code != codes.Unset {
+		t.Errorf("expected request span status unset, got %v", requestSpan.Status.Code)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	shutdown, err := Setup(config.TracingConfig{}, "dev")
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+
+	// 未启用时仍然传播上游的 traceparent
+	fields := otel.GetTextMapPropagator().Fields()
+	found := false
+	for _, field := range fields {
+		if field == "traceparent" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected traceparent propagation, got fields %v", fields)
+	}
+}
@@ -0,0 +1,38 @@
+package types
+
+import "time"
+
+// AlertState 告警规则状态
+type AlertState string
+
+const (
+	// AlertInactive 未超过阈值
+	AlertInactive AlertState = "inactive"
+	// AlertPending 已超过阈值，等待持续时间或冷却结束
+	AlertPending AlertState = "pending"
+	// AlertFiring 告警中
+	AlertFiring AlertState = "firing"
+	// AlertResolved 告警已恢复，再次超过阈值前保持该状态
+	AlertResolved AlertState = "resolved"
+)
+
+// AlertStatus 告警规则的当前状态
+type AlertStatus struct {
+	Rule      string     `json:"rule"`
+	Path      string     `json:"path"`
+	Severity  string     `json:"severity"`
+	State     AlertState `json:"state"`
+	Count     int        `json:"count"`     // 当前窗口内的匹配数
+	Threshold int        `json:"threshold"` // 超过该值时触发
+	Window    string     `json:"window"`
+	Since     time.Time  `json:"since"`               // 进入当前状态的时间
+	LastFired *time.Time `json:"lastFired,omitempty"` // 最近一次触发时间
+	Samples   []LogEntry `json:"samples,omitempty"`   // 最近匹配的日志条目
+}
+
+// AlertEvent 告警状态变化事件
+type AlertEvent struct {
+	AlertStatus
+	Previous AlertState `json:"previous"`
+	Time     time.Time  `json:"time"`
+}
@@ -0,0 +1,24 @@
+package types
+
+import "time"
+
+// Annotation 日志行上的标注（书签、标签和评论）
+// 按文件和行起始的字节位置定位，文件追加内容后仍然有效
+type Annotation struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Offset    int64     `json:"offset"`         // 所在行起始的字节位置，对应 LogEntry.ByteOffset
+	Line      string    `json:"line,omitempty"` // 创建时的行内容（可能被截断），用于确认定位
+	Pinned    bool      `json:"pinned"`         // 书签
+	Tags      []string  `json:"tags,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AnnotationEvent 标注变化，通过 WebSocket 推送给查看同一文件的客户端
+type AnnotationEvent struct {
+	Action     string     `json:"action"` // created, updated, deleted
+	Annotation Annotation `json:"annotation"`
+}
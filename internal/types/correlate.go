@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// CorrelateQuery 按 trace ID 或 request ID 跨文件查找日志
+type CorrelateQuery struct {
+	ID        string    `json:"id"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Limit     int       `json:"limit"` // 最多返回的条目数，超出时 Truncated 为 true
+}
+
+// CorrelatedEntry 合并时间线中的日志条目
+type CorrelatedEntry struct {
+	LogEntry
+	Path         string `json:"path"`
+	MatchedField string `json:"matchedField"`       // 包含该 ID 的字段
+	SpanID       string `json:"spanId,omitempty"`   // 条目所属的 span
+	ParentID     string `json:"parentId,omitempty"` // 父 span
+}
+
+// CorrelationSpan span 树的节点，由同一 span ID 的条目组成
+type CorrelationSpan struct {
+	SpanID   string            `json:"spanId"`
+	ParentID string            `json:"parentId,omitempty"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Paths    []string          `json:"paths"`   // 条目所在的文件
+	Entries  []int             `json:"entries"` // 条目在 CorrelateResult.Entries 中的下标
+	Children []CorrelationSpan `json:"children,omitempty"`
+}
+
+// CorrelateResult 关联查询结果
+type CorrelateResult struct {
+	ID           string            `json:"id"`
+	Entries      []CorrelatedEntry `json:"entries"` // 按时间排序的合并时间线，没有时间戳的条目排在最后
+	Spans        []CorrelationSpan `json:"spans,omitempty"`
+	TotalCount   int64             `json:"totalCount"`
+	FilesScanned int               `json:"filesScanned"`
+	Truncated    bool              `json:"truncated"`
+}
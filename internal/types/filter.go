@@ -0,0 +1,16 @@
+package types
+
+// StreamFilter 实时日志订阅的过滤条件，在服务端对追加的日志逐条评估
+type StreamFilter struct {
+	Levels  []string         `json:"levels,omitempty"`
+	Query   string           `json:"query,omitempty"` // 关键词，IsRegex 为 true 时为正则表达式
+	IsRegex bool             `json:"isRegex,omitempty"`
+	Fields  []FieldPredicate `json:"fields,omitempty"` // 字段条件，全部满足才匹配
+}
+
+// FieldPredicate 字段条件
+type FieldPredicate struct {
+	Field string `json:"field"` // 字段名，支持 level、message 及用点号访问嵌套字段
+	Op    string `json:"op"`    // eq, ne, contains, regex, gt, gte, lt, lte, exists
+	Value string `json:"value,omitempty"`
+}
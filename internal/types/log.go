@@ -9,18 +9,22 @@ type LogFile struct {
 	Size        int64     `json:"size"`
 	ModTime     time.Time `json:"modTime"`
 	IsDirectory bool      `json:"isDirectory"`
+	Format      string    `json:"format,omitempty"` // 检测到的日志格式
 	Children    []LogFile `json:"children,omitempty"`
 }
 
 // LogEntry 日志条目
 type LogEntry struct {
-	Timestamp time.Time              `json:"timestamp"`
-	Level     string                 `json:"level"`
-	Message   string                 `json:"message"`
-	Fields    map[string]interface{} `json:"fields"`
-	Raw       string                 `json:"raw"`
-	LineNum   int64                  `json:"lineNum"`
-	LogType   string                 `json:"logType"` // JSON, WebServer, Generic
+	Timestamp   time.Time              `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Fields      map[string]interface{} `json:"fields"`
+	Raw         string                 `json:"raw"`
+	LineNum     int64                  `json:"lineNum"`
+	LogType     string                 `json:"logType"`        // JSON, WebServer, Generic
+	ByteOffset  int64                  `json:"byteOffset"`     // 行起始的字节位置，文件追加内容后保持不变
+	Path        string                 `json:"path,omitempty"` // 所在的文件，只在搜索整个目录时设置
+	Annotations []Annotation           `json:"annotations,omitempty"`
 }
 
 // LogContent 日志内容响应
@@ -29,6 +33,7 @@ type LogContent struct {
 	TotalLines int64      `json:"totalLines"`
 	HasMore    bool       `json:"hasMore"`
 	Offset     int64      `json:"offset"`
+	Format     string     `json:"format,omitempty"` // 解析使用的日志格式
 }
 
 // SearchQuery 搜索查询
@@ -51,11 +56,84 @@ type SearchResult struct {
 	Offset     int        `json:"offset"`
 }
 
+// FacetQuery 字段聚合查询，过滤条件与搜索相同
+type FacetQuery struct {
+	SearchQuery
+	Field string `json:"field"` // 字段名，支持 level、message 及用点号访问嵌套字段
+	TopN  int    `json:"topN"`
+}
+
+// FacetResult 字段聚合结果
+type FacetResult struct {
+	Field        string        `json:"field"`
+	MatchedCount int64         `json:"matchedCount"` // 符合过滤条件的条目数
+	FieldCount   int64         `json:"fieldCount"`   // 包含该字段的条目数
+	Cardinality  uint64        `json:"cardinality"`  // 不同值数量的估算（HyperLogLog）
+	TopValues    []FacetValue  `json:"topValues"`
+	Approximate  bool          `json:"approximate"` // 不同值过多时计数为近似值
+	Numeric      *NumericStats `json:"numeric,omitempty"`
+}
+
+// FacetValue 字段值及出现次数
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// NumericStats 数值字段统计
+type NumericStats struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+}
+
+// HistogramQuery 按时间分桶统计查询，过滤条件与搜索相同
+type HistogramQuery struct {
+	SearchQuery
+	Bucket  time.Duration `json:"bucket"`  // 桶宽度
+	GroupBy string        `json:"groupBy"` // 分组字段，例如 level；为空时不分组
+}
+
+// HistogramResult 按时间分桶统计结果
+type HistogramResult struct {
+	Bucket      string            `json:"bucket"`
+	GroupBy     string            `json:"groupBy,omitempty"`
+	Buckets     []HistogramBucket `json:"buckets"`
+	Total       int64             `json:"total"`       // 计入时间桶的条目数
+	Untimed     int64             `json:"untimed"`     // 没有时间戳的条目数
+	FromSummary bool              `json:"fromSummary"` // 是否由持久化的文件摘要得出
+}
+
+// HistogramBucket 单个时间桶
+type HistogramBucket struct {
+	Time   time.Time        `json:"time"`
+	Count  int64            `json:"count"`
+	Groups map[string]int64 `json:"groups,omitempty"`
+}
+
 // LogUpdate 日志更新事件
 type LogUpdate struct {
-	Path    string     `json:"path"`
-	Entries []LogEntry `json:"entries"`
-	Type    string     `json:"type"` // "append", "truncate", "delete"
+	Path        string     `json:"path"`
+	Entries     []LogEntry `json:"entries"`
+	Type        string     `json:"type"`        // "append", "truncate", "delete", "create", "resync"
+	Seq         uint64     `json:"seq"`         // 每个文件递增的序号，resync 为读取时最新的序号
+	StartOffset int64      `json:"startOffset"` // 本次更新覆盖的字节范围 [startOffset, endOffset)
+	EndOffset   int64      `json:"endOffset"`
+}
+
+// StreamGap 推送给客户端时被丢弃的更新范围，客户端可以从 StartOffset 开始 resync 补齐
+type StreamGap struct {
+	Path        string `json:"path"`
+	FromSeq     uint64 `json:"fromSeq,omitempty"`
+	ToSeq       uint64 `json:"toSeq,omitempty"`
+	StartOffset int64  `json:"startOffset"`
+	EndOffset   int64  `json:"endOffset"`
+	Entries     int    `json:"entries"` // 丢弃的日志条数
+	Reason      string `json:"reason"`  // drop_oldest, coalesce, resync_limit
 }
 
 // FileEvent 文件事件
@@ -0,0 +1,58 @@
+package types
+
+import "time"
+
+// 日志指标查询的聚合函数
+const (
+	LogMetricRate     = "rate"     // 每秒增量（值之和除以步长）
+	LogMetricSum      = "sum"      // 值之和，未设置数值字段时等于匹配条数
+	LogMetricCount    = "count"    // 匹配条数
+	LogMetricAvg      = "avg"      // 平均值
+	LogMetricMin      = "min"      // 最小值
+	LogMetricMax      = "max"      // 最大值
+	LogMetricQuantile = "quantile" // 分位数（基于抽样）
+)
+
+// LogMetricInfo 日志指标的定义和当前状态
+type LogMetricInfo struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Path       string   `json:"path"`
+	Value      string   `json:"value,omitempty"`
+	GroupBy    string   `json:"groupBy,omitempty"`
+	Groups     []string `json:"groups"`     // 已有数据的分组
+	Retention  string   `json:"retention"`  // 保留时长
+	Resolution string   `json:"resolution"` // 时间分辨率
+	Backfilled bool     `json:"backfilled"` // 是否已完成回填
+}
+
+// LogMetricQuery 日志指标查询
+type LogMetricQuery struct {
+	Name     string        `json:"name"`
+	Func     string        `json:"func"`               // 聚合函数，默认 rate
+	Quantile float64       `json:"quantile,omitempty"` // func 为 quantile 时的分位数，0~1
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Step     time.Duration `json:"step"`            // 每个数据点覆盖的时长，至少为指标的分辨率
+	Group    string        `json:"group,omitempty"` // 只返回该分组，为空时返回所有分组
+}
+
+// LogMetricResult 日志指标查询结果，每个分组一条时间序列
+type LogMetricResult struct {
+	Name   string            `json:"name"`
+	Func   string            `json:"func"`
+	Step   string            `json:"step"`
+	Series []LogMetricSeries `json:"series"`
+}
+
+// LogMetricSeries 单个分组的时间序列
+type LogMetricSeries struct {
+	Group  string           `json:"group,omitempty"`
+	Points []LogMetricPoint `json:"points"`
+}
+
+// LogMetricPoint 数据点，Time 为所覆盖时间段的开始
+type LogMetricPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
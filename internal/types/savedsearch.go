@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// SavedSearch 保存的搜索
+type SavedSearch struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Owner     string     `json:"owner"`
+	Shared    bool       `json:"shared"` // 共享后其他用户可以查看和使用，但只有所有者可以修改
+	Paths     []string   `json:"paths"`
+	Query     string     `json:"query"`
+	IsRegex   bool       `json:"isRegex"`
+	Levels    []string   `json:"levels,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+	Range     string     `json:"range,omitempty"` // 相对时间范围，例如 15m 表示最近15分钟，优先于 startTime/endTime
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
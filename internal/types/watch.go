@@ -0,0 +1,21 @@
+package types
+
+import "time"
+
+// WatchStats 正在监控的文件及其订阅者
+type WatchStats struct {
+	Path        string                 `json:"path"`
+	Seq         uint64                 `json:"seq"`    // 最近一次发送的更新序号
+	Offset      int64                  `json:"offset"` // 实时推送的位置
+	Subscribers []WatchSubscriberStats `json:"subscribers"`
+}
+
+// WatchSubscriberStats 单个订阅者的统计
+type WatchSubscriberStats struct {
+	ID        string    `json:"id"`
+	Since     time.Time `json:"since"`
+	Delivered uint64    `json:"delivered"` // 已送达的更新数
+	Dropped   uint64    `json:"dropped"`   // 积压超出上限被丢弃的更新数
+	Backlog   int       `json:"backlog"`   // 当前积压的更新数
+	LastSeq   uint64    `json:"lastSeq"`   // 最近送达的更新序号
+}
@@ -135,6 +135,7 @@ func (fw *FileWatcher) eventLoop() {
 				return
 			}
 			// 记录错误但不停止监控
+			watcherErrors.Inc()
 			fmt.Printf("File watcher error: %v\n", err)
 
 		case <-fw.stopCh:
@@ -155,6 +156,7 @@ func (fw *FileWatcher) handleEvent(event fsnotify.Event) {
 
 	// 将 fsnotify 事件转换为我们的 FileEvent
 	fileEvent := fw.convertEvent(event)
+	watcherEvents.WithLabelValues(fileEvent.Type).Inc()
 
 	// 立即串行执行所有回调,不启动新的 goroutine
 	// 这保证了事件处理的顺序性和原子性,类似 tail -f 的行为
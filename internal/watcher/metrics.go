@@ -0,0 +1,10 @@
+package watcher
+
+import "github.com/local-log-viewer/internal/metrics"
+
+var (
+	watcherEvents = metrics.NewCounterVec("logviewer_watcher_events_total",
+		"File system events delivered for watched files, by type.", "type")
+	watcherErrors = metrics.NewCounter("logviewer_watcher_errors_total",
+		"Errors reported by the file system watcher.")
+)
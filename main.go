@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"github.com/local-log-viewer/internal/logger"
 	"github.com/local-log-viewer/internal/manager"
 	"github.com/local-log-viewer/internal/server"
+	"github.com/local-log-viewer/internal/tracing"
 	"github.com/local-log-viewer/internal/watcher"
 	"go.uber.org/zap"
 )
@@ -49,6 +51,7 @@ func main() {
 		logPaths       = flag.String("logs", "", "日志文件路径，多个路径用逗号分隔 (默认: ./logs)")
 		maxFileSize    = flag.String("max-file-size", "", "最大文件大小，支持单位 K/M/G (默认: 100M)")
 		cacheSize      = flag.Int("cache-size", 0, "文件缓存数量 (默认: 50)")
+		dataDir        = flag.String("data-dir", "", "持久化数据目录 (文件摘要等，默认仅保存在内存中)")
 		enableAuth     = flag.Bool("enable-auth", false, "启用基本认证")
 		username       = flag.String("username", "", "认证用户名")
 		password       = flag.String("password", "", "认证密码")
@@ -114,6 +117,7 @@ func main() {
 		LogPaths:    *logPaths,
 		MaxFileSize: *maxFileSize,
 		CacheSize:   *cacheSize,
+		DataDir:     *dataDir,
 		EnableAuth:  *enableAuth,
 		Username:    *username,
 		Password:    *password,
@@ -144,6 +148,19 @@ func main() {
 		zap.String("go_version", runtime.Version()),
 	)
 
+	// 初始化链路追踪，退出时导出剩余的 span
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, version)
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("failed to flush traces", zap.Error(err))
+		}
+	}()
+
 	// 创建依赖组件
 
 	// 创建缓存 (最大缓存数量, TTL时间)